@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"os"
+	"time"
+	"webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/domain/services"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+)
+
+// checkTimeout bounds the scan of every bank account and journal entry
+const checkTimeout = 5 * time.Minute
+
+// ledgercheck verifies the invariant that the stored balances of every bank account equal the totals of their postings
+// in the journal. It reports every bank account that diverges and exits with a non-zero status if there are any.
+func main() {
+	logger, err := logging.FromEnv(os.Stderr, os.LookupEnv)
+	if err != nil {
+		fatal(slog.Default(), "Error when creating logger", logging.Err(err))
+	}
+	slog.SetDefault(logger)
+	mongoURL, urlPresent := os.LookupEnv("MONGO_URL")
+	if !urlPresent {
+		mongoURL = "mongodb://localhost:30001"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURL))
+	if err != nil {
+		fatal(logger, "Error in connecting to database", logging.Err(err))
+	}
+	defer func(client *mongo.Client, ctx context.Context) {
+		err := client.Disconnect(ctx)
+		if err != nil {
+			logger.Error("Error encountered when closing database connection", logging.Err(err))
+		}
+	}(client, ctx)
+
+	db := client.Database("wallet")
+	// The check runs once, so the metrics of its repositories are never exported
+	m := metrics.New()
+	ar := repositories.CreateNewAccountRepositoryMongodb(db.Collection("account"), m, logger)
+	jr := repositories.CreateNewJournalRepositoryMongodb(db.Collection("journal_entry"), m, logger)
+	ls := services.CreateNewLedgerServiceImpl(ar, jr, logger)
+
+	discrepancies, err := ls.FindBalanceDiscrepancies(ctx)
+	if err != nil {
+		fatal(logger, "Error when checking balances against the journal", logging.Err(err))
+	}
+	for _, elem := range discrepancies {
+		logger.Warn("Bank account diverges from the journal",
+			logging.BankAccountId(elem.BankAccountId),
+			logging.AccountNumber(elem.AccountNumber),
+			slog.String("stored_available", elem.StoredAvailable.String()),
+			slog.String("posted_available", elem.PostedAvailable.String()),
+			slog.String("stored_pending", elem.StoredPending.String()),
+			slog.String("posted_pending", elem.PostedPending.String()))
+	}
+	if len(discrepancies) > 0 {
+		logger.Error("Bank accounts diverge from the journal", slog.Int("count", len(discrepancies)))
+		// Deferred calls do not run on os.Exit, so the connection is closed before exiting
+		_ = client.Disconnect(ctx)
+		cancel()
+		os.Exit(1)
+	}
+	logger.Info("All bank account balances match the journal")
+}
+
+// fatal logs the error that stops the check and exits
+func fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
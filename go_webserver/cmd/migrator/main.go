@@ -2,79 +2,247 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"log"
+	"log/slog"
 	"os"
 	"strconv"
+	"text/tabwriter"
+	"time"
+	"webserver/internal/pkg/logging"
 	"webserver/migrations/service"
 	"webserver/migrations/versions"
 	"webserver/migrations/versions/data"
 	"webserver/migrations/versions/schema"
 )
 
+const usage = `Usage: migrator [command] [flags]
+
+Commands:
+  up      [--track schema|data] [--to N]   apply migrations up to and including N (default: all)
+  down    --track schema|data [--to N]     roll back migrations above N (default: the latest applied one)
+  redo    --track schema|data              roll back and re-apply the latest applied migration
+  status  [--track schema|data]            list migrations and when they were applied
+
+Running the migrator without a command is the same as running up. Migrations that changed since they were applied
+stop up, down and redo unless --warn-on-changed is given. With STORAGE_BACKEND=sqlite the commands run on the SQLite
+database at SQLITE_PATH, which only has a schema track. Otherwise they run on the MongoDB database named by
+MONGO_DATABASE (default: wallet) at MONGO_URL. Progress is logged at LOG_LEVEL (default: info) in LOG_FORMAT, text
+or json.`
+
+// noTarget marks a --to flag that was not given
+const noTarget = -1
+
 func main() {
+	logger, err := logging.FromEnv(os.Stderr, os.LookupEnv)
+	if err != nil {
+		fatal(slog.Default(), "Error when creating logger", logging.Err(err))
+	}
+	slog.SetDefault(logger)
+	command, args := "up", os.Args[1:]
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		command, args = args[0], args[1:]
+	}
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	trackName := flags.String("track", "", "the migration track to run the command on, schema or data")
+	target := flags.Int("to", noTarget, "the migration number to migrate up or down to")
+	warnOnChanged := flags.Bool("warn-on-changed", false, "only warn about applied migrations that have changed")
+	if err = flags.Parse(args); err != nil {
+		fatal(logger, "Error when parsing flags", logging.Err(err))
+	}
+	opts := commandOptions{command: command, trackName: *trackName, target: *target, warnOnChanged: *warnOnChanged}
+	backend := os.Getenv("STORAGE_BACKEND")
+	switch backend {
+	case "", "mongodb":
+		runOnMongodb(opts, logger)
+	case "sqlite":
+		runOnSQLite(opts, logger)
+	default:
+		fatal(logger, "Invalid STORAGE_BACKEND, expected one of mongodb or sqlite", slog.String("backend", backend))
+	}
+}
+
+// fatal logs the error that stops the migrator and exits
+func fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+type commandOptions struct {
+	command       string
+	trackName     string
+	target        int
+	warnOnChanged bool
+}
+
+// checkTrackSelection refuses commands that need a single track when more than one is selected
+func checkTrackSelection(opts commandOptions, selected int, logger *slog.Logger) {
+	// Rolling back both tracks at once is never what was meant, and targets are numbered per track
+	if (opts.command == "down" || opts.command == "redo") && selected != 1 {
+		fatal(logger, opts.command+" requires --track to be given")
+	}
+	if opts.target != noTarget && selected != 1 {
+		fatal(logger, "--to requires --track to be given")
+	}
+}
+
+func runOnMongodb(opts commandOptions, logger *slog.Logger) {
 	mainDatabaseName, migrationDatabaseName, migrationCollectionName := "wallet", "migrations", "migrations"
+	// The webserver reads the wallet from the database given in MONGO_DATABASE, so migrations must be applied there
+	if databaseName := os.Getenv("MONGO_DATABASE"); databaseName != "" {
+		mainDatabaseName = databaseName
+	}
+	tracks, err := selectTracks(opts.trackName)
+	if err != nil {
+		fatal(logger, "Error when selecting migration tracks", logging.Err(err))
+	}
+	checkTrackSelection(opts, len(tracks), logger)
+
+	// The URL is not logged, since it may hold credentials
 	mongoURL := os.Getenv("MONGO_URL")
-	log.Printf("Attempting to connect to Mongo URL %s", mongoURL)
+	logger.Info("Attempting to connect to MongoDB")
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURL))
 	if err != nil {
-		log.Fatalf("Error in connecting to database: %v", err)
+		fatal(logger, "Error in connecting to database", logging.Err(err))
 	}
 	defer func(client *mongo.Client, ctx context.Context) {
 		err := client.Disconnect(ctx)
 		if err != nil {
-			log.Printf("Error encountered when closing database connection: %v", err)
+			logger.Error("Error encountered when closing database connection", logging.Err(err))
 		}
 	}(client, ctx)
 
-	schemaStartVer := parseEnvAsInt("SCHEMA_START_VER", 1)
-	schemaEndVer := parseEnvAsInt("SCHEMA_END_VER", 2)
+	logger = logger.With(slog.String("database", mainDatabaseName))
+	ms := service.NewMigrationService(client, ctx, migrationDatabaseName, migrationCollectionName, logger)
+	ms.SetWarnOnChangedMigrations(opts.warnOnChanged)
+
+	switch opts.command {
+	case "up":
+		for _, track := range tracks {
+			trackTarget := opts.target
+			if trackTarget == noTarget {
+				trackTarget = defaultUpTarget(track.Name, len(track.Migrations), logger)
+			}
+			logger.Info("Applying migrations", slog.String("track", track.Name), slog.Int("target", trackTarget))
+			if err = ms.MigrateUp(mainDatabaseName, track, trackTarget); err != nil {
+				fatal(logger, "Error when applying migrations", slog.String("track", track.Name), logging.Err(err))
+			}
+		}
+		logger.Info("Migrations completed successfully")
+	case "down":
+		track := tracks[0]
+		target := opts.target
+		if target == noTarget {
+			target = latestApplied(ms, track, logger) - 1
+		}
+		logger.Info("Rolling back migrations", slog.String("track", track.Name), slog.Int("target", target))
+		if err = ms.MigrateDown(mainDatabaseName, track, max(target, 0)); err != nil {
+			fatal(logger, "Error when rolling back migrations", slog.String("track", track.Name), logging.Err(err))
+		}
+		logger.Info("Rollback completed successfully")
+	case "redo":
+		track := tracks[0]
+		latest := latestApplied(ms, track, logger)
+		if latest == 0 {
+			fatal(logger, "No migration has been applied", slog.String("track", track.Name))
+		}
+		version := slog.String("version", track.Migrations[latest-1].Version)
+		if err = ms.MigrateDown(mainDatabaseName, track, latest-1); err != nil {
+			fatal(logger, "Error when rolling back migration", version, logging.Err(err))
+		}
+		if err = ms.MigrateUp(mainDatabaseName, track, latest); err != nil {
+			fatal(logger, "Error when re-applying migration", version, logging.Err(err))
+		}
+		logger.Info("Migration redone successfully", version)
+	case "status":
+		var statuses []service.MigrationStatus
+		for _, track := range tracks {
+			trackStatuses, err := ms.Status(track)
+			if err != nil {
+				fatal(logger, "Error when getting status of migrations", slog.String("track", track.Name),
+					logging.Err(err))
+			}
+			statuses = append(statuses, trackStatuses...)
+		}
+		printStatus(statuses, logger)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
 
-	dataStartVer := parseEnvAsInt("DATA_START_VER", 1)
-	dataEndVer := parseEnvAsInt("DATA_END_VER", 2)
+func selectTracks(name string) ([]versions.Track, error) {
+	switch name {
+	case "":
+		return []versions.Track{schema.Track, data.Track}, nil
+	case schema.Track.Name:
+		return []versions.Track{schema.Track}, nil
+	case data.Track.Name:
+		return []versions.Track{data.Track}, nil
+	default:
+		return nil, fmt.Errorf("unknown migration track %s, expected %s or %s", name, schema.Track.Name,
+			data.Track.Name)
+	}
+}
 
-	ms := service.NewMigrationService(client, ctx, migrationDatabaseName, migrationCollectionName)
+// defaultUpTarget applies every migration of the named track, unless SCHEMA_END_VER or DATA_END_VER hold it back
+func defaultUpTarget(trackName string, migrationCount int, logger *slog.Logger) int {
+	switch trackName {
+	case schema.Track.Name:
+		return parseEnvAsInt("SCHEMA_END_VER", migrationCount, logger)
+	case data.Track.Name:
+		return parseEnvAsInt("DATA_END_VER", migrationCount, logger)
+	default:
+		return migrationCount
+	}
+}
 
-	log.Printf("Applying schema migrations to database %s", mainDatabaseName)
-	applyMigrations(ms, mainDatabaseName, schema.SchemaMigrations, schemaStartVer, schemaEndVer)
-	log.Printf("Applying data migrations to database %s", mainDatabaseName)
-	applyMigrations(ms, mainDatabaseName, data.Migrations, dataStartVer, dataEndVer)
-	log.Println("Migrations completed successfully")
+func latestApplied(ms *service.MigrationServiceImpl, track versions.Track, logger *slog.Logger) int {
+	latest, err := ms.LatestApplied(track)
+	if err != nil {
+		fatal(logger, "Error when getting the latest applied migration", slog.String("track", track.Name),
+			logging.Err(err))
+	}
+	return latest
 }
 
-func applyMigrations(
-	ms *service.MigrationServiceImpl,
-	mainDatabaseName string,
-	migrationsToRun []versions.Migration,
-	startVer int,
-	endVer int,
-) {
-	migrationsToRun = migrationsToRun[startVer-1 : endVer]
-	for _, elem := range migrationsToRun {
-		log.Printf("Applying migration %s", elem.Version)
-		err, hasBeenApplied := ms.ApplyMigration(mainDatabaseName, elem)
-		if err != nil {
-			log.Fatalf("Error when applying migration %s: %v", elem.Version, err)
+func printStatus(statuses []service.MigrationStatus, logger *slog.Logger) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TRACK\tNUMBER\tVERSION\tDESCRIPTION\tAPPLIED AT\tDURATION\t")
+	for _, elem := range statuses {
+		appliedAt, duration := "pending", "-"
+		if elem.Applied && elem.AppliedAt.IsZero() {
+			appliedAt = "applied"
+		} else if elem.Applied {
+			appliedAt = elem.AppliedAt.Format(time.RFC3339)
+			duration = elem.Duration.String()
 		}
-		if hasBeenApplied {
-			log.Printf("Migration %s has been applied", elem.Version)
-		} else {
-			log.Printf("Migration %s has not been applied by this run", elem.Version)
+		changed := ""
+		if elem.Changed {
+			changed = "changed since applied"
 		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", elem.Track, elem.Number, elem.Version,
+			elem.Description, appliedAt, duration, changed)
+	}
+	if err := w.Flush(); err != nil {
+		logger.Error("Error when printing migration status", logging.Err(err))
 	}
 }
 
-func parseEnvAsInt(envVar string, defaultValue int) int {
+func parseEnvAsInt(envVar string, defaultValue int, logger *slog.Logger) int {
 	val := os.Getenv(envVar)
 	if val == "" {
 		return defaultValue
 	}
 	ret, err := strconv.Atoi(val)
 	if err != nil {
-		log.Fatalf("Error when parsing %s as int: %v", envVar, err)
+		fatal(logger, "Error when parsing environment variable as int", slog.String("variable", envVar),
+			logging.Err(err))
 	}
 	return ret
 }
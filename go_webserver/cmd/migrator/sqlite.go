@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/logging"
+	"webserver/migrations/service"
+	"webserver/migrations/versions/sqlschema"
+)
+
+// runOnSQLite runs the command on the SQLite database at SQLITE_PATH. SQLite only has a schema track, since the seed
+// data of the data track is only ever loaded into MongoDB.
+func runOnSQLite(opts commandOptions, logger *slog.Logger) {
+	track := sqlschema.Track
+	if opts.trackName != "" && opts.trackName != track.Name {
+		fatal(logger, "Unknown migration track of the sqlite backend, expected "+track.Name,
+			slog.String("track", opts.trackName))
+	}
+	checkTrackSelection(opts, 1, logger)
+
+	path, pathPresent := os.LookupEnv("SQLITE_PATH")
+	if !pathPresent {
+		path = "wallet.db"
+	}
+	logger = logger.With(slog.String("path", path))
+	logger.Info("Attempting to open SQLite database")
+	db, err := sqlite.Open(path)
+	if err != nil {
+		fatal(logger, "Error in opening database", logging.Err(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Error("Error encountered when closing database", logging.Err(err))
+		}
+	}()
+
+	ms := service.NewSQLMigrationService(db, context.Background(), logger)
+	ms.SetWarnOnChangedMigrations(opts.warnOnChanged)
+
+	switch opts.command {
+	case "up":
+		target := opts.target
+		if target == noTarget {
+			target = defaultUpTarget(track.Name, len(track.Migrations), logger)
+		}
+		logger.Info("Applying migrations", slog.String("track", track.Name), slog.Int("target", target))
+		if err = ms.MigrateUp(track, target); err != nil {
+			fatal(logger, "Error when applying migrations", slog.String("track", track.Name), logging.Err(err))
+		}
+		logger.Info("Migrations completed successfully")
+	case "down":
+		target := opts.target
+		if target == noTarget {
+			target = latestAppliedSQL(ms, logger) - 1
+		}
+		logger.Info("Rolling back migrations", slog.String("track", track.Name), slog.Int("target", target))
+		if err = ms.MigrateDown(track, max(target, 0)); err != nil {
+			fatal(logger, "Error when rolling back migrations", slog.String("track", track.Name), logging.Err(err))
+		}
+		logger.Info("Rollback completed successfully")
+	case "redo":
+		latest := latestAppliedSQL(ms, logger)
+		if latest == 0 {
+			fatal(logger, "No migration has been applied", slog.String("track", track.Name))
+		}
+		version := slog.String("version", track.Migrations[latest-1].Version)
+		if err = ms.MigrateDown(track, latest-1); err != nil {
+			fatal(logger, "Error when rolling back migration", version, logging.Err(err))
+		}
+		if err = ms.MigrateUp(track, latest); err != nil {
+			fatal(logger, "Error when re-applying migration", version, logging.Err(err))
+		}
+		logger.Info("Migration redone successfully", version)
+	case "status":
+		statuses, err := ms.Status(track)
+		if err != nil {
+			fatal(logger, "Error when getting status of migrations", slog.String("track", track.Name),
+				logging.Err(err))
+		}
+		printStatus(statuses, logger)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func latestAppliedSQL(ms *service.SQLMigrationServiceImpl, logger *slog.Logger) int {
+	latest, err := ms.LatestApplied(sqlschema.Track)
+	if err != nil {
+		fatal(logger, "Error when getting the latest applied migration", slog.String("track", sqlschema.Track.Name),
+			logging.Err(err))
+	}
+	return latest
+}
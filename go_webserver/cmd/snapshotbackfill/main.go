@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"os"
+	"time"
+	"webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/domain/services"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+	"webserver/migrations/versions/schema"
+)
+
+const usage = `Usage: snapshotbackfill [--from YYYY-MM-DD]
+
+Computes the end-of-day balance snapshots of every bank account from its transactions, for every day from the one
+given by --from, or from the day of the first transaction of the bank account, to the last day that ended. Days that
+were snapshotted already are left as they are, so the backfill can be run again after it was interrupted. With
+STORAGE_BACKEND=sqlite it runs on the SQLite database at SQLITE_PATH. Otherwise it runs on the MongoDB database named
+by MONGO_DATABASE (default: wallet) at MONGO_URL, which the migrator must have brought up to date first.`
+
+// backfillTimeout bounds the backfill of every bank account
+const backfillTimeout = 30 * time.Minute
+
+func main() {
+	logger, err := logging.FromEnv(os.Stderr, os.LookupEnv)
+	if err != nil {
+		fatal(slog.Default(), "Error when creating logger", logging.Err(err))
+	}
+	slog.SetDefault(logger)
+	flags := flag.NewFlagSet("snapshotbackfill", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	from := flags.String("from", "", "the first day to snapshot, in UTC")
+	if err = flags.Parse(os.Args[1:]); err != nil {
+		fatal(logger, "Error when parsing flags", logging.Err(err))
+	}
+	var fromTime time.Time
+	if *from != "" {
+		if fromTime, err = time.Parse(time.DateOnly, *from); err != nil {
+			fatal(logger, "Error when parsing --from, expected a day such as 2024-01-31", logging.Err(err))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backfillTimeout)
+	defer cancel()
+	backend := os.Getenv("STORAGE_BACKEND")
+	switch backend {
+	case "", "mongodb":
+		backfillOnMongodb(fromTime, logger, ctx)
+	case "sqlite":
+		backfillOnSQLite(fromTime, logger, ctx)
+	default:
+		fatal(logger, "Invalid STORAGE_BACKEND, expected one of mongodb or sqlite", slog.String("backend", backend))
+	}
+}
+
+// fatal logs the error that stops the backfill and exits
+func fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+func backfillOnMongodb(fromTime time.Time, logger *slog.Logger, ctx context.Context) {
+	databaseName := "wallet"
+	if name := os.Getenv("MONGO_DATABASE"); name != "" {
+		databaseName = name
+	}
+	// The URL is not logged, since it may hold credentials
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("MONGO_URL")))
+	if err != nil {
+		fatal(logger, "Error in connecting to database", logging.Err(err))
+	}
+	defer func(client *mongo.Client, ctx context.Context) {
+		err := client.Disconnect(ctx)
+		if err != nil {
+			logger.Error("Error encountered when closing database connection", logging.Err(err))
+		}
+	}(client, ctx)
+
+	logger = logger.With(slog.String("database", databaseName))
+	db := client.Database(databaseName)
+	// The backfill runs once, so the metrics of its repositories are never exported
+	m := metrics.New()
+	ss := services.CreateNewBalanceSnapshotServiceImpl(
+		repositories.CreateNewAccountRepositoryMongodb(db.Collection(schema.AccountCollectionName), m, logger),
+		repositories.CreateNewTransactionRepositoryMongodb(db.Collection(schema.TransactionCollectionName), m, logger),
+		repositories.CreateNewBalanceSnapshotRepositoryMongodb(db.Collection(schema.BalanceSnapshotCollectionName), m,
+			logger),
+		transactional.NewMongoTransactional(client, m, logger),
+		logger,
+	)
+	backfill(ss, fromTime, logger, ctx)
+}
+
+func backfillOnSQLite(fromTime time.Time, logger *slog.Logger, ctx context.Context) {
+	path, pathPresent := os.LookupEnv("SQLITE_PATH")
+	if !pathPresent {
+		path = "wallet.db"
+	}
+	logger = logger.With(slog.String("path", path))
+	db, err := sqlite.Open(path)
+	if err != nil {
+		fatal(logger, "Error in opening database", logging.Err(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Error("Error encountered when closing database", logging.Err(err))
+		}
+	}()
+
+	ss := services.CreateNewBalanceSnapshotServiceImpl(
+		repositories.CreateNewAccountRepositorySQLite(db, logger),
+		repositories.CreateNewTransactionRepositorySQLite(db, logger),
+		repositories.CreateNewBalanceSnapshotRepositorySQLite(db, logger),
+		transactional.NewSQLiteTransactional(db, logger),
+		logger,
+	)
+	backfill(ss, fromTime, logger, ctx)
+}
+
+// backfill logs the bank accounts that cannot be snapshotted rather than stopping at them, so the snapshots that
+// could be computed are stored either way
+func backfill(ss services.BalanceSnapshotService, fromTime time.Time, logger *slog.Logger, ctx context.Context) {
+	logger.Info("Backfilling balance snapshots", slog.Time("from", fromTime))
+	added, err := ss.BackfillBalanceSnapshots(fromTime, time.Now(), ctx)
+	if err != nil {
+		fatal(logger, "Error when backfilling balance snapshots", logging.Err(err))
+	}
+	logger.Info("Backfilled balance snapshots", slog.Int("count", added))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"webserver/internal/pkg/domain/services"
+	"webserver/internal/pkg/logging"
+)
+
+// runPendingTransactionExpirer periodically revokes active pending transactions that are past their expiration date
+// and deletes expired idempotency keys until the given context is cancelled.
+func runPendingTransactionExpirer(
+	ts services.TransactionService,
+	interval time.Duration,
+	logger *slog.Logger,
+	ctx context.Context,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping pending transaction expirer")
+			return
+		case <-ticker.C:
+			revoked, err := ts.RevokeExpiredPendingTransactions(time.Now(), ctx)
+			if err != nil {
+				logger.ErrorContext(ctx, "Error revoking expired pending transactions", logging.Err(err))
+			} else if revoked > 0 {
+				logger.InfoContext(ctx, "Revoked expired pending transactions", slog.Int("count", revoked))
+			}
+			deleted, err := ts.DeleteExpiredIdempotencyKeys(time.Now(), ctx)
+			if err != nil {
+				logger.ErrorContext(ctx, "Error deleting expired idempotency keys", logging.Err(err))
+			} else if deleted > 0 {
+				logger.InfoContext(ctx, "Deleted expired idempotency keys", slog.Int("count", deleted))
+			}
+		}
+	}
+}
@@ -2,15 +2,27 @@ package main
 
 import (
 	"context"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+	// The image the server runs in has no time zone database, which balance histories need
+	_ "time/tzdata"
+	"webserver/internal/app/config"
 	"webserver/internal/app/server/router"
-	repositories2 "webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/auth"
 	"webserver/internal/pkg/domain/services"
-	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+	"webserver/internal/pkg/statement"
+	"webserver/internal/pkg/tracing"
 )
 
 // @title Wallet API
@@ -28,41 +40,192 @@ import (
 
 // @BasePath /backendAPI
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token issued by /accounts/login, given as "Bearer <accessToken>"
+
 func main() {
-	mongoURL, urlPresent := os.LookupEnv("MONGO_URL")
-	if !urlPresent {
-		mongoURL = "mongodb://localhost:30001"
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fatal(slog.Default(), "Error when loading configuration", logging.Err(err))
+	}
+	logger, err := logging.New(os.Stderr, logging.Options{Level: cfg.Logging.Level, Format: cfg.Logging.Format})
+	if err != nil {
+		fatal(slog.Default(), "Error when creating logger", logging.Err(err))
+	}
+	// Libraries logging through the default logger, and the log package, share the configured output and format
+	slog.SetDefault(logger)
+	if err = run(cfg, logger); err != nil {
+		fatal(logger, "Webserver failed", logging.Err(err))
+	}
+}
+
+// fatal logs the error that keeps the webserver from running and exits
+func fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+// run serves the API until the webserver fails or receives SIGINT or SIGTERM. On a signal it stops accepting
+// connections, lets requests in flight finish within the shutdown timeout, stops the background workers and only
+// then closes the storage and flushes the spans that have not been exported yet.
+func run(cfg *config.Config, logger *slog.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	m := metrics.New()
+	tp, err := tracing.NewProvider(tracing.Options{
+		Exporter:     cfg.Tracing.Exporter,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+		Output:       os.Stdout,
+	}, ctx)
+	if err != nil {
+		return fmt.Errorf("error when setting up tracing: %w", err)
+	}
+	defer shutdownTracing(tp, cfg.Server.ShutdownTimeout, logger)
+	tracer := tracing.Tracer(tp)
+	st := traceStorage(loadStorage(cfg.Storage, m, tp, logger, ctx), tracer)
+	defer st.cleanup()
+
+	as := services.CreateNewAccountServiceTraced(services.CreateNewAccountServiceImpl(st.ar, st.tr, st.sr, st.tra,
+		logger), tracer)
+	ts := services.CreateNewTransactionServiceTraced(services.CreateNewTransactionServiceImpl(st.tr, st.ar, st.ir,
+		st.jr, st.cr, st.tra, cfg.Features.UnknownPayeePolicy, m, logger), tracer)
+	ss := services.CreateNewStatementServiceTraced(services.CreateNewStatementServiceImpl(st.ar, st.tr, st.tra, logger),
+		tracer)
+	bs := services.CreateNewBalanceSnapshotServiceTraced(services.CreateNewBalanceSnapshotServiceImpl(st.ar, st.tr,
+		st.sr, st.tra, logger), tracer)
+	cs := services.CreateNewCategoryServiceTraced(services.CreateNewCategoryServiceImpl(st.ar, st.tr, st.cr, st.tra,
+		logger), tracer)
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	if cfg.Features.PendingTransactionExpirer {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			runPendingTransactionExpirer(ts, cfg.Features.PendingTransactionExpiryInterval, logger, workersCtx)
+		}()
+	}
+	if cfg.Features.BalanceSnapshots {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			runBalanceSnapshotter(bs, cfg.Features.BalanceSnapshotInterval, logger, workersCtx)
+		}()
+	}
+
+	tm := auth.NewTokenManager(sessionTokenSecret(cfg.Session.TokenSecret, logger), cfg.Session.AccessTokenTTL,
+		cfg.Session.RefreshTokenTTL)
+	statementOptions := statement.Options{Currency: cfg.Statement.Currency, BankId: cfg.Statement.BankId}
+	server := createServer(cfg.Server, router.CreateRouter(as, ts, ss, cs, statementOptions, tm,
+		cfg.ParsedRouteBudgets(), m, tp, logger), logger)
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- serve(server, cfg.Server, logger)
+	}()
+	var metricsServer *http.Server
+	if cfg.Server.MetricsListenAddress != "" {
+		metricsServer = createMetricsServer(cfg.Server, router.CreateMetricsRouter(m), logger)
+		go func() {
+			serveErr <- serveMetrics(metricsServer, logger)
+		}()
 	}
-	cli, cleanup, ctx := createDatabase(mongoURL)
-	accountCollection := cli.Database("wallet").Collection("account")
-	transactionCollection := cli.Database("wallet").Collection("transaction")
-	defer cleanup()
 
-	ar := repositories2.CreateNewAccountRepositoryMongodb(accountCollection)
-	tr := repositories2.CreateNewTransactionRepositoryMongodb(transactionCollection)
+	select {
+	case err := <-serveErr:
+		closeServers(server, metricsServer, logger)
+		stopWorkers()
+		workers.Wait()
+		return fmt.Errorf("error when serving: %w", err)
+	case <-ctx.Done():
+		stop()
+		logger.Info("Shutting down webserver")
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	shutdownErr := server.Shutdown(shutdownCtx)
+	if shutdownErr != nil {
+		shutdownErr = fmt.Errorf("error when waiting for requests in flight to finish: %w", shutdownErr)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("Error encountered when stopping the metrics server", logging.Err(err))
+		}
+	}
+	stopWorkers()
+	workers.Wait()
+	logger.Info("Webserver stopped")
+	return shutdownErr
+}
 
-	tra := transactional.NewMongoTransactional(cli)
+// shutdownTracing exports the spans still batched, giving up on them after the timeout
+func shutdownTracing(tp *sdktrace.TracerProvider, timeout time.Duration, logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := tp.Shutdown(ctx); err != nil {
+		logger.Error("Error encountered when flushing spans", logging.Err(err))
+	}
+}
 
-	as := services.CreateNewAccountServiceImpl(ar, tr, tra)
-	ts := services.CreateNewTransactionServiceImpl(tr, ar, tra)
-	r := router.CreateRouter(as, ts, ctx)
-	log.Printf("Starting webserver")
-	log.Fatal(http.ListenAndServe(":8080", r))
+func createServer(cfg config.ServerConfig, handler http.Handler, logger *slog.Logger) *http.Server {
+	return &http.Server{
+		// Errors of connections that never reach a handler, like failed TLS handshakes
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		Addr:              cfg.ListenAddress,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
 }
 
-func createDatabase(mongoURL string) (*mongo.Client, func(), context.Context) {
-	// TODO: Do not pass this context down, figure out another way
-	ctx, cancel := context.WithCancel(context.Background())
-	// Connect to MongoDB
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURL))
-	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+// createMetricsServer creates the server of the metrics at their own address, with the timeouts of the API server
+func createMetricsServer(cfg config.ServerConfig, handler http.Handler, logger *slog.Logger) *http.Server {
+	cfg.ListenAddress = cfg.MetricsListenAddress
+	return createServer(cfg, handler, logger)
+}
+
+// serveMetrics serves the metrics over plain HTTP, as they are meant to be scraped from inside the deployment
+func serveMetrics(server *http.Server, logger *slog.Logger) error {
+	logger.Info("Starting metrics server", slog.String("address", server.Addr))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error when serving metrics: %w", err)
 	}
-	cleanup := func() {
-		cancel()
-		if err = client.Disconnect(ctx); err != nil {
-			log.Fatalf("Failed to disconnect from MongoDB: %v", err)
+	return nil
+}
+
+// closeServers closes the servers at once, when one of them failed and the webserver stops without a graceful
+// shutdown
+func closeServers(server *http.Server, metricsServer *http.Server, logger *slog.Logger) {
+	for _, s := range []*http.Server{server, metricsServer} {
+		if s == nil {
+			continue
+		}
+		if err := s.Close(); err != nil {
+			logger.Warn("Error encountered when closing server", slog.String("address", s.Addr), logging.Err(err))
 		}
 	}
-	return client, cleanup, ctx
+}
+
+// serve blocks until the server fails or is shut down, which is not reported as an error
+func serve(server *http.Server, cfg config.ServerConfig, logger *slog.Logger) error {
+	var err error
+	if cfg.TLSCertFile != "" {
+		logger.Info("Starting webserver with TLS", slog.String("address", server.Addr))
+		err = server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	} else {
+		logger.Info("Starting webserver", slog.String("address", server.Addr))
+		err = server.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"crypto/rand"
+	"log/slog"
+	"webserver/internal/pkg/logging"
+)
+
+// sessionTokenSecret returns the key session tokens are signed with. Without a configured one a random key is
+// generated, which invalidates all issued tokens whenever the webserver restarts.
+func sessionTokenSecret(configured string, logger *slog.Logger) []byte {
+	if configured != "" {
+		return []byte(configured)
+	}
+	logger.Warn("SESSION_TOKEN_SECRET is not set, generating a random session token secret")
+	randomSecret := make([]byte, 32)
+	if _, err := rand.Read(randomSecret); err != nil {
+		fatal(logger, "Failed to generate session token secret", logging.Err(err))
+	}
+	return randomSecret
+}
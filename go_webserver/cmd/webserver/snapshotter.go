@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"webserver/internal/pkg/domain/services"
+	"webserver/internal/pkg/logging"
+)
+
+// runBalanceSnapshotter snapshots the balances of the days that ended once at start and then periodically, until the
+// given context is cancelled. Days are only snapshotted once, so replicas running it at the same time store the same
+// snapshots.
+func runBalanceSnapshotter(
+	ss services.BalanceSnapshotService,
+	interval time.Duration,
+	logger *slog.Logger,
+	ctx context.Context,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		added, err := ss.TakeBalanceSnapshots(time.Now(), ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "Error taking balance snapshots", logging.Err(err))
+		} else if added > 0 {
+			logger.InfoContext(ctx, "Took balance snapshots", slog.Int("count", added))
+		}
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping balance snapshotter")
+			return
+		case <-ticker.C:
+		}
+	}
+}
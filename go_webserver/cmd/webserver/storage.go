@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"webserver/internal/app/config"
+	"webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/infrastructure/memory"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+	"webserver/migrations/service"
+	"webserver/migrations/versions/schema"
+	"webserver/migrations/versions/sqlschema"
+)
+
+type storage struct {
+	ar      repositories.AccountRepository
+	tr      repositories.TransactionRepository
+	ir      repositories.IdempotencyRepository
+	jr      repositories.JournalRepository
+	sr      repositories.BalanceSnapshotRepository
+	cr      repositories.CategoryRuleRepository
+	tra     transactional.Transactional
+	cleanup func()
+}
+
+// loadStorage sets up the repositories on the configured backend, which is one of mongodb (the default), sqlite or
+// memory. The memory backend keeps everything in the webserver process, so all data is lost when it stops; it is
+// meant for local development and demos.
+func loadStorage(
+	cfg config.StorageConfig,
+	m *metrics.Metrics,
+	tp trace.TracerProvider,
+	logger *slog.Logger,
+	ctx context.Context,
+) storage {
+	switch cfg.Backend {
+	case config.MongodbBackend:
+		return createMongodbStorage(cfg.Mongo, m, tp, logger, ctx)
+	case config.MemoryBackend:
+		logger.Warn("Using the in-memory storage backend, data will be lost when the webserver stops")
+		store := memory.NewWalletStore()
+		return storage{
+			ar:      repositories.CreateNewAccountRepositoryMemory(store, logger),
+			tr:      repositories.CreateNewTransactionRepositoryMemory(store, logger),
+			ir:      repositories.CreateNewIdempotencyRepositoryMemory(store, logger),
+			jr:      repositories.CreateNewJournalRepositoryMemory(store, logger),
+			sr:      repositories.CreateNewBalanceSnapshotRepositoryMemory(store, logger),
+			cr:      repositories.CreateNewCategoryRuleRepositoryMemory(store, logger),
+			tra:     transactional.NewMemoryTransactional(store, logger),
+			cleanup: func() {},
+		}
+	case config.SQLiteBackend:
+		return createSQLiteStorage(cfg.SQLite.Path, logger, ctx)
+	default:
+		fatal(logger, "Invalid storage backend, expected one of mongodb, sqlite or memory",
+			slog.String("backend", cfg.Backend))
+		return storage{}
+	}
+}
+
+// createMongodbStorage uses the collections created by the schema migrations in the configured database, and is the
+// only backend whose operations are observed in the metrics and whose driver commands are traced
+func createMongodbStorage(
+	cfg config.MongoConfig,
+	m *metrics.Metrics,
+	tp trace.TracerProvider,
+	logger *slog.Logger,
+	ctx context.Context,
+) storage {
+	cli, cleanup := createDatabase(cfg, tp, logger, ctx)
+	db := cli.Database(cfg.Database)
+	accountCollection := db.Collection(schema.AccountCollectionName)
+	transactionCollection := db.Collection(schema.TransactionCollectionName)
+	idempotencyKeyCollection := db.Collection(schema.IdempotencyKeyCollectionName)
+	journalEntryCollection := db.Collection(schema.JournalEntryCollectionName)
+	balanceSnapshotCollection := db.Collection(schema.BalanceSnapshotCollectionName)
+	categoryRuleCollection := db.Collection(schema.CategoryRuleCollectionName)
+	return storage{
+		ar:      repositories.CreateNewAccountRepositoryMongodb(accountCollection, m, logger),
+		tr:      repositories.CreateNewTransactionRepositoryMongodb(transactionCollection, m, logger),
+		ir:      repositories.CreateNewIdempotencyRepositoryMongodb(idempotencyKeyCollection, m, logger),
+		jr:      repositories.CreateNewJournalRepositoryMongodb(journalEntryCollection, m, logger),
+		sr:      repositories.CreateNewBalanceSnapshotRepositoryMongodb(balanceSnapshotCollection, m, logger),
+		cr:      repositories.CreateNewCategoryRuleRepositoryMongodb(categoryRuleCollection, m, logger),
+		tra:     transactional.NewMongoTransactional(cli, m, logger),
+		cleanup: cleanup,
+	}
+}
+
+// createSQLiteStorage opens the database file at the given path, which the migrator must have brought up to date
+func createSQLiteStorage(path string, logger *slog.Logger, ctx context.Context) storage {
+	logger = logger.With(slog.String("path", path))
+	db, err := sqlite.Open(path)
+	if err != nil {
+		fatal(logger, "Error in opening SQLite database", logging.Err(err))
+	}
+	latest, err := service.NewSQLMigrationService(db, ctx, logger).LatestApplied(sqlschema.Track)
+	if err != nil {
+		fatal(logger, "Error when checking the migrations of SQLite database", logging.Err(err))
+	}
+	if latest < len(sqlschema.Track.Migrations) {
+		fatal(logger,
+			"SQLite database is behind its schema migrations, run the migrator with STORAGE_BACKEND=sqlite first",
+			slog.Int("applied", latest), slog.Int("available", len(sqlschema.Track.Migrations)))
+	}
+	logger.Info("Using the SQLite storage backend")
+	return storage{
+		ar:  repositories.CreateNewAccountRepositorySQLite(db, logger),
+		tr:  repositories.CreateNewTransactionRepositorySQLite(db, logger),
+		ir:  repositories.CreateNewIdempotencyRepositorySQLite(db, logger),
+		jr:  repositories.CreateNewJournalRepositorySQLite(db, logger),
+		sr:  repositories.CreateNewBalanceSnapshotRepositorySQLite(db, logger),
+		cr:  repositories.CreateNewCategoryRuleRepositorySQLite(db, logger),
+		tra: transactional.NewSQLiteTransactional(db, logger),
+		cleanup: func() {
+			if err := db.Close(); err != nil {
+				logger.Error("Error encountered when closing SQLite database", logging.Err(err))
+			}
+		},
+	}
+}
+
+// createDatabase connects to MongoDB with the configured pool settings, which take precedence over the same options
+// given in the URI, and checks that the deployment can be reached. Every command sent to the deployment is traced,
+// without its documents.
+func createDatabase(
+	cfg config.MongoConfig,
+	tp trace.TracerProvider,
+	logger *slog.Logger,
+	ctx context.Context,
+) (*mongo.Client, func()) {
+	opts := options.Client().ApplyURI(cfg.URI).
+		SetMaxPoolSize(cfg.MaxPoolSize).
+		SetMinPoolSize(cfg.MinPoolSize).
+		SetMaxConnIdleTime(cfg.MaxConnIdleTime).
+		SetConnectTimeout(cfg.ConnectTimeout).
+		SetMonitor(otelmongo.NewMonitor(otelmongo.WithTracerProvider(tp)))
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		fatal(logger, "Failed to connect to MongoDB", logging.Err(err))
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+	defer cancel()
+	if err = client.Ping(pingCtx, nil); err != nil {
+		fatal(logger, "Failed to reach MongoDB", logging.Err(err))
+	}
+	cleanup := func() {
+		// Operations still in progress get a while to finish before their connections are closed
+		disconnectCtx, cancel := context.WithTimeout(context.Background(), cfg.DisconnectTimeout)
+		defer cancel()
+		if err := client.Disconnect(disconnectCtx); err != nil {
+			logger.Error("Error encountered when disconnecting from MongoDB", logging.Err(err))
+			return
+		}
+		logger.Info("Disconnected from MongoDB")
+	}
+	return client, cleanup
+}
+
+// traceStorage records a span for every call to the repositories and every transaction of the storage
+func traceStorage(st storage, tracer trace.Tracer) storage {
+	return storage{
+		ar:      repositories.CreateNewAccountRepositoryTraced(st.ar, tracer),
+		tr:      repositories.CreateNewTransactionRepositoryTraced(st.tr, tracer),
+		ir:      repositories.CreateNewIdempotencyRepositoryTraced(st.ir, tracer),
+		jr:      repositories.CreateNewJournalRepositoryTraced(st.jr, tracer),
+		sr:      repositories.CreateNewBalanceSnapshotRepositoryTraced(st.sr, tracer),
+		cr:      repositories.CreateNewCategoryRuleRepositoryTraced(st.cr, tracer),
+		tra:     transactional.NewTracedTransactional(st.tra, tracer),
+		cleanup: st.cleanup,
+	}
+}
@@ -15,9 +15,1007 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/accounts": {
+            "post": {
+                "description": "Registers a new account without any bank accounts. Log in to obtain session tokens for it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Register",
+                "parameters": [
+                    {
+                        "description": "Registration payload",
+                        "name": "register",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AccountRegisterRequestDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successful registration",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AccountDetailsResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or account details",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "409": {
+                        "description": "Username is already taken",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/bank-accounts": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Opens an empty bank account of the given type for the authenticated account.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Open a bank account",
+                "parameters": [
+                    {
+                        "description": "Bank account payload",
+                        "name": "bankAccount",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.BankAccountOpenRequestDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successful opening of the bank account",
+                        "schema": {
+                            "$ref": "#/definitions/dto.BankAccountDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or account type",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/category-rules": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the rules categorizing the transactions of the authenticated account, in the order they are\ntried.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "categories"
+                ],
+                "summary": "List category rules",
+                "responses": {
+                    "200": {
+                        "description": "Successful retrieval of category rules",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.CategoryRuleResponseDTO"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds a rule categorizing the transactions of the authenticated account that match all of its\nconditions. Rules are tried in the order they were added, the first matching one categorizes the\ntransaction. The rule applies to new transactions; existing ones are categorized again on request.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "categories"
+                ],
+                "summary": "Add a category rule",
+                "parameters": [
+                    {
+                        "description": "Category rule payload",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CategoryRuleRequestDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successful addition of the category rule",
+                        "schema": {
+                            "$ref": "#/definitions/dto.CategoryRuleResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "409": {
+                        "description": "Account has too many category rules",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/category-rules/apply": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Categorizes the transactions of a bank account of the authenticated account again with the current\ncategory rules, oldest first. Transactions categorized by hand are left alone, and transactions no rule\nmatches lose their category. A request categorizes a bounded number of transactions and returns a\nnextCursor while some are left, which the next request passes as its cursor to resume from.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "categories"
+                ],
+                "summary": "Apply category rules",
+                "parameters": [
+                    {
+                        "description": "Bank account payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ApplyCategoryRulesRequestDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful categorization of the transactions",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ApplyCategoryRulesResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or cursor",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "403": {
+                        "description": "BankAccount does not belong to the authenticated account",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "BankAccount not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/category-rules/{ruleId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a category rule of the authenticated account. Transactions it categorized keep their\ncategory until the rules are applied again.",
+                "tags": [
+                    "categories"
+                ],
+                "summary": "Remove a category rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the category rule",
+                        "name": "ruleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Successful removal of the category rule"
+                    },
+                    "400": {
+                        "description": "Invalid category rule ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "Category rule not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
         "/accounts/details/{accountId}": {
             "get": {
-                "description": "Retrieves the details of a specific account by its ID.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves the details of a specific account by its ID.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Get account details",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "BankAccount ID",
+                        "name": "accountId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful retrieval of account details",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AccountDetailsResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid BankAccount ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "403": {
+                        "description": "BankAccount does not belong to the authenticated account",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "BankAccount not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves the balance history of a specific account by its ID, split into day, week, month or quarter\nbuckets starting at midnight in the requested time zone. Every bucket holds the opening, closing,\nlowest and highest available balances along with the totals of the transactions realized in it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Get account history",
+                "parameters": [
+                    {
+                        "description": "Account history payload",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AccountBalanceHistoryRequestDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful retrieval of account history",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AccountBalanceHistoryResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or fields",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "403": {
+                        "description": "BankAccount does not belong to the authenticated account",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "BankAccount not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/login": {
+            "post": {
+                "description": "Logs in a user with the provided username and password, issuing an access and a refresh token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Login",
+                "parameters": [
+                    {
+                        "description": "Login payload",
+                        "name": "login",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AccountLoginRequestDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful login",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AccountLoginResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or fields",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/payees": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the bank accounts known to the authenticated account.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payees"
+                ],
+                "summary": "List payees",
+                "responses": {
+                    "200": {
+                        "description": "Successful retrieval of payees",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.KnownBankAccountDTO"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds the bank account with the given account number as a payee of the authenticated account.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payees"
+                ],
+                "summary": "Add a payee",
+                "parameters": [
+                    {
+                        "description": "Payee payload",
+                        "name": "payee",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PayeeRequestDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successful addition of the payee",
+                        "schema": {
+                            "$ref": "#/definitions/dto.KnownBankAccountDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or account number, or BankAccount is the caller's own",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "No BankAccount has the given account number",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "409": {
+                        "description": "BankAccount is already a payee",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/payees/{payeeId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a payee of the authenticated account.",
+                "tags": [
+                    "payees"
+                ],
+                "summary": "Remove a payee",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "BankAccount ID of the payee",
+                        "name": "payeeId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Successful removal of the payee"
+                    },
+                    "400": {
+                        "description": "Invalid payee ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "Payee not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Changes the nickname of a payee of the authenticated account.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payees"
+                ],
+                "summary": "Rename a payee",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "BankAccount ID of the payee",
+                        "name": "payeeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Nickname payload",
+                        "name": "payee",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PayeeNicknameRequestDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Successful renaming of the payee"
+                    },
+                    "400": {
+                        "description": "Invalid request payload or payee ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "Payee not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/statement": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Exports the realized transactions of a bank account over a period of at most 12 months, along with its\nopening and closing available balance, as CSV, OFX 2.2 or ISO 20022 camt.053. The format is picked\nby the format parameter, or else by the Accept header, and defaults to CSV.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ofx",
+                    "application/xml"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Export a bank account statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "BankAccount ID",
+                        "name": "bankAccountId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the period in an RFC3339 compliant format",
+                        "name": "fromTime",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the period in an RFC3339 compliant format",
+                        "name": "toTime",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "ofx",
+                            "camt053"
+                        ],
+                        "type": "string",
+                        "description": "Format of the statement",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Statement of the bank account",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "403": {
+                        "description": "BankAccount does not belong to the authenticated account",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "BankAccount not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "406": {
+                        "description": "None of the accepted media types is supported",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/statement/monthly": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Downloads the statement of a bank account over a calendar month in UTC as a PDF, listing its realized\ntransactions with the running available balance and the totals of the money that came in and went out.",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Download a monthly statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "BankAccount ID",
+                        "name": "bankAccountId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Month of the statement, e.g. 2024-03",
+                        "name": "month",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Statement of the bank account",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "403": {
+                        "description": "BankAccount does not belong to the authenticated account",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "BankAccount not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/token/refresh": {
+            "post": {
+                "description": "Exchanges a valid refresh token for a new access and refresh token.",
                 "consumes": [
                     "application/json"
                 ],
@@ -27,35 +1025,208 @@ const docTemplate = `{
                 "tags": [
                     "accounts"
                 ],
-                "summary": "Get account details",
+                "summary": "Refresh session tokens",
+                "parameters": [
+                    {
+                        "description": "Refresh payload",
+                        "name": "refresh",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.TokenRefreshRequestDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful refresh",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TokenResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or fields",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired refresh token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the transactions of a bank account one page at a time, newest first by default. Pass the\nnextCursor of a page as the cursor of the next request, keeping the other parameters unchanged.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get account transactions",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "BankAccount ID",
-                        "name": "accountId",
-                        "in": "path",
+                        "name": "bankAccountId",
+                        "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest creation time in an RFC3339 compliant format",
+                        "name": "fromTime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest creation time in an RFC3339 compliant format",
+                        "name": "toTime",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Order of creation",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Number of transactions per page, at most 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "credit",
+                            "debit"
+                        ],
+                        "type": "string",
+                        "description": "Nature of the transactions",
+                        "name": "nature",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "realized",
+                            "pending"
+                        ],
+                        "type": "string",
+                        "description": "Type of the transactions",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "active",
+                            "applied",
+                            "revoked"
+                        ],
+                        "type": "string",
+                        "description": "Status of pending transactions",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Smallest amount",
+                        "name": "minAmount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Largest amount",
+                        "name": "maxAmount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "BankAccount ID of the other side of the transactions",
+                        "name": "counterparty",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successful retrieval of account details",
+                        "description": "Successful retrieval of account transactions",
                         "schema": {
-                            "$ref": "#/definitions/dto.AccountDetailsResponseDTO"
+                            "$ref": "#/definitions/dto.AccountTransactionPageResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "403": {
+                        "description": "BankAccount does not belong to the authenticated account",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "BankAccount not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorMessage"
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
                         }
                     }
                 }
             }
         },
-        "/accounts/history": {
-            "get": {
-                "description": "Retrieves the account month-balance history for a specific account by its ID.",
+        "/transactions": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds a new transaction to the system. Requests with an Idempotency-Key header take effect at most\nonce, with retries returning the originally created transaction for 24 hours. Depending on the\nserver's policy, transfers to BankAccounts that are not known payees are rejected or must be confirmed.",
                 "consumes": [
                     "application/json"
                 ],
@@ -63,126 +1234,325 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "accounts"
+                    "transactions"
                 ],
-                "summary": "Get account history",
+                "summary": "Add a new transaction",
+                "parameters": [
+                    {
+                        "description": "Transaction request",
+                        "name": "transaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.TransactionRequestDTO"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client-generated key identifying the request across retries",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TransactionResponseDTO"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true if the transaction was created by an earlier request"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or fields",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "403": {
+                        "description": "Source BankAccount is not owned, or destination is not a known payee",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "BankAccount not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency key is in use, or the unknown payee must be confirmed",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "422": {
+                        "description": "Insufficient funds, or idempotency key was used for a different request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/pending": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Holds the amount in the pending balances of both accounts until it is applied, revoked or expires.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Add a new pending transaction",
                 "parameters": [
                     {
-                        "description": "Account history payload",
-                        "name": "input",
+                        "description": "Pending transaction request",
+                        "name": "transaction",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.AccountBalanceHistoryRequestDTO"
+                            "$ref": "#/definitions/dto.PendingTransactionRequestDTO"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Successful retrieval of account history",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/dto.AccountBalanceHistoryResponseDTO"
+                            "$ref": "#/definitions/dto.PendingTransactionResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload, fields or expiration date",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "403": {
+                        "description": "Source BankAccount is not owned, or destination is not a known payee",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "BankAccount not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "409": {
+                        "description": "The unknown payee must be confirmed",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "422": {
+                        "description": "Insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorMessage"
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
                         }
                     }
                 }
             }
         },
-        "/accounts/login": {
+        "/transactions/pending/{transactionId}/apply": {
             "post": {
-                "description": "Logs in a user with the provided username and password.",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Releases the held amount and transfers it as a realized transaction. Only the holder of the bank\naccount the amount is taken from may apply it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "accounts"
+                    "transactions"
                 ],
-                "summary": "Login",
+                "summary": "Apply a pending transaction",
                 "parameters": [
                     {
-                        "description": "Login payload",
-                        "name": "login",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.AccountLoginRequestDTO"
-                        }
+                        "type": "string",
+                        "description": "Pending transaction ID",
+                        "name": "transactionId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Successful login",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/dto.AccountDetailsResponseDTO"
+                            "type": "string"
                         }
                     },
                     "401": {
-                        "description": "Invalid credentials",
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "403": {
+                        "description": "Pending transaction is not taken from the authenticated account",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "Pending transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "409": {
+                        "description": "Pending transaction is no longer active",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorMessage"
+                            "$ref": "#/definitions/problem.Details"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorMessage"
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
                         }
                     }
                 }
             }
         },
-        "/accounts/{accountId}/transactions": {
-            "get": {
-                "description": "Retrieves a list of transactions for a specific account by its ID.",
-                "consumes": [
-                    "application/json"
+        "/transactions/pending/{transactionId}/revoke": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Releases the held amount back to the pending balances of both accounts without transferring it.\nThe holders of either bank account may revoke it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "transactions"
                 ],
-                "summary": "Get account transactions",
+                "summary": "Revoke a pending transaction",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "BankAccount ID",
-                        "name": "accountId",
+                        "description": "Pending transaction ID",
+                        "name": "transactionId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Successful retrieval of account transactions",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/dto.AccountTransactionResponseDTO"
-                            }
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "403": {
+                        "description": "Pending transaction does not belong to the authenticated account",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "Pending transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "409": {
+                        "description": "Pending transaction is no longer active",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorMessage"
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
                         }
                     }
                 }
             }
         },
-        "/transactions": {
-            "post": {
-                "description": "Adds a new transaction to the system.",
+        "/transactions/{transactionId}/category": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Sets the category a bank account of the authenticated account gives a transaction, which category\nrules no longer change. An empty category lets the category rules categorize the transaction again.",
                 "consumes": [
                     "application/json"
                 ],
@@ -190,37 +1560,68 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "categories"
                 ],
-                "summary": "Add a new transaction",
+                "summary": "Categorize a transaction",
                 "parameters": [
                     {
-                        "description": "Transaction request",
-                        "name": "transaction",
+                        "type": "string",
+                        "description": "ID of the transaction",
+                        "name": "transactionId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Category payload",
+                        "name": "category",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.TransactionRequestDTO"
+                            "$ref": "#/definitions/dto.TransactionCategoryRequestDTO"
                         }
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Accepted",
+                    "200": {
+                        "description": "Successful categorization of the transaction",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/dto.TransactionCategoryResponseDTO"
                         }
                     },
                     "400": {
-                        "description": "Invalid request payload",
+                        "description": "Invalid request payload or transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "403": {
+                        "description": "BankAccount does not belong to the authenticated account",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found, or BankAccount is not one of its sides",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorMessage"
+                            "$ref": "#/definitions/problem.Details"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorMessage"
+                            "$ref": "#/definitions/problem.Details"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage is unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Details"
                         }
                     }
                 }
@@ -228,6 +1629,58 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "dto.AccountBalanceBucketDTO": {
+            "type": "object",
+            "required": [
+                "closingAvailableBalance",
+                "closingPendingBalance",
+                "endTime",
+                "maxAvailableBalance",
+                "minAvailableBalance",
+                "openingAvailableBalance",
+                "startTime",
+                "totalCredits",
+                "totalDebits"
+            ],
+            "properties": {
+                "closingAvailableBalance": {
+                    "description": "The available balance of the account at the end of the bucket. Valid to two decimal places.",
+                    "type": "string"
+                },
+                "closingPendingBalance": {
+                    "description": "The pending balance of the account at the end of the bucket. Valid to two decimal places.",
+                    "type": "string"
+                },
+                "endTime": {
+                    "description": "The end of the bucket, which is the start of the next one, in an RFC3339 compliant format",
+                    "type": "string"
+                },
+                "maxAvailableBalance": {
+                    "description": "The highest available balance of the account during the bucket. Valid to two decimal places.",
+                    "type": "string"
+                },
+                "minAvailableBalance": {
+                    "description": "The lowest available balance of the account during the bucket. Valid to two decimal places.",
+                    "type": "string"
+                },
+                "openingAvailableBalance": {
+                    "description": "The available balance of the account at the start of the bucket. Valid to two decimal places.",
+                    "type": "string"
+                },
+                "startTime": {
+                    "description": "The start of the bucket in an RFC3339 compliant format, offset to the requested time zone",
+                    "type": "string"
+                },
+                "totalCredits": {
+                    "description": "The total amount of the credit transactions realized during the bucket, which took from the balance",
+                    "type": "string"
+                },
+                "totalDebits": {
+                    "description": "The total amount of the debit transactions realized during the bucket, which added to the balance",
+                    "type": "string"
+                }
+            }
+        },
         "dto.AccountBalanceHistoryRequestDTO": {
             "type": "object",
             "required": [
@@ -240,12 +1693,26 @@ const docTemplate = `{
                     "description": "The bank account ID of the account associated with the transactions",
                     "type": "string"
                 },
+                "bucketSize": {
+                    "description": "The size of the buckets, month by default. Weeks start on Mondays and quarters in January, April, July and\nOctober.",
+                    "type": "string",
+                    "enum": [
+                        "day",
+                        "week",
+                        "month",
+                        "quarter"
+                    ]
+                },
                 "fromTime": {
                     "description": "The start time of the transactions in an RFC3339 compliant format",
                     "type": "string"
                 },
+                "timeZone": {
+                    "description": "The IANA time zone the buckets start at midnight in, e.g. Europe/Paris. UTC by default.",
+                    "type": "string"
+                },
                 "toTime": {
-                    "description": "The end time of the transactions in an RFC3339 compliant format",
+                    "description": "The end time of the transactions in an RFC3339 compliant format. At most 60 months after the start time.",
                     "type": "string"
                 }
             }
@@ -254,60 +1721,124 @@ const docTemplate = `{
             "type": "object",
             "required": [
                 "bankAccountId",
-                "months"
+                "bucketSize",
+                "buckets",
+                "timeZone"
             ],
             "properties": {
                 "bankAccountId": {
                     "description": "The bank account ID of the account associated with the transactions",
                     "type": "string"
                 },
-                "months": {
-                    "description": "The list of account balance months",
+                "bucketSize": {
+                    "description": "The size of the buckets",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.BalanceBucketSize"
+                        }
+                    ]
+                },
+                "buckets": {
+                    "description": "The buckets covering the requested time range, newest first",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/dto.AccountBalanceMonthDTO"
+                        "$ref": "#/definitions/dto.AccountBalanceBucketDTO"
                     }
+                },
+                "timeZone": {
+                    "description": "The IANA time zone the buckets start at midnight in",
+                    "type": "string"
                 }
             }
         },
-        "dto.AccountBalanceMonthDTO": {
+        "dto.AccountDetailsResponseDTO": {
             "type": "object",
             "required": [
-                "availableBalance",
-                "month",
-                "pendingBalance",
-                "year"
+                "bankAccounts",
+                "createdAt",
+                "id",
+                "knownBankAccounts",
+                "person",
+                "username"
             ],
             "properties": {
-                "availableBalance": {
-                    "description": "The available balance of the account at the end of the given month. Valid to two decimal places.",
+                "bankAccounts": {
+                    "description": "The list of bank accounts associated with the account holder",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.BankAccountDTO"
+                    }
+                },
+                "createdAt": {
+                    "description": "The creation timestamp of the account",
                     "type": "string"
                 },
-                "month": {
-                    "description": "The month of the account balance",
-                    "type": "integer"
+                "id": {
+                    "description": "The unique identifier of the account",
+                    "type": "string"
                 },
-                "pendingBalance": {
-                    "description": "The pending balance of the account at the end of the given month. Valid to two decimal places.",
+                "knownBankAccounts": {
+                    "description": "The list of bank accounts known to and recognized by the account holder",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.KnownBankAccountDTO"
+                    }
+                },
+                "person": {
+                    "description": "The account holder associated with the account",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.PersonDTO"
+                        }
+                    ]
+                },
+                "username": {
+                    "description": "The username associated with the account",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AccountLoginRequestDTO": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "description": "The password for the login",
                     "type": "string"
                 },
-                "year": {
-                    "description": "The year of the account balance",
-                    "type": "integer"
+                "username": {
+                    "description": "The username for the login",
+                    "type": "string"
                 }
             }
         },
-        "dto.AccountDetailsResponseDTO": {
+        "dto.AccountLoginResponseDTO": {
             "type": "object",
             "required": [
+                "accessToken",
+                "accessTokenExpiresAt",
                 "bankAccounts",
                 "createdAt",
                 "id",
                 "knownBankAccounts",
                 "person",
+                "refreshToken",
+                "refreshTokenExpiresAt",
+                "tokenType",
                 "username"
             ],
             "properties": {
+                "accessToken": {
+                    "description": "The short-lived token authenticating requests to protected routes",
+                    "type": "string"
+                },
+                "accessTokenExpiresAt": {
+                    "description": "The expiry time of the access token in an RFC3339 compliant format",
+                    "type": "string"
+                },
                 "bankAccounts": {
                     "description": "The list of bank accounts associated with the account holder",
                     "type": "array",
@@ -331,7 +1862,45 @@ const docTemplate = `{
                     }
                 },
                 "person": {
-                    "description": "The account holder associated with the account",
+                    "description": "The account holder associated with the account",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.PersonDTO"
+                        }
+                    ]
+                },
+                "refreshToken": {
+                    "description": "The long-lived token used to obtain a new pair of tokens",
+                    "type": "string"
+                },
+                "refreshTokenExpiresAt": {
+                    "description": "The expiry time of the refresh token in an RFC3339 compliant format",
+                    "type": "string"
+                },
+                "tokenType": {
+                    "description": "The type of the tokens, to be given in the Authorization header as \"Bearer \u003caccessToken\u003e\"",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "The username associated with the account",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AccountRegisterRequestDTO": {
+            "type": "object",
+            "required": [
+                "password",
+                "person",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "description": "The password of the new account. At least 8 characters long.",
+                    "type": "string"
+                },
+                "person": {
+                    "description": "The account holder of the new account",
                     "allOf": [
                         {
                             "$ref": "#/definitions/dto.PersonDTO"
@@ -339,25 +1908,27 @@ const docTemplate = `{
                     ]
                 },
                 "username": {
-                    "description": "The username associated with the account",
+                    "description": "The username of the new account. 3 to 32 letters, digits, '.', '_' or '-'.",
                     "type": "string"
                 }
             }
         },
-        "dto.AccountLoginRequestDTO": {
+        "dto.AccountTransactionPageResponseDTO": {
             "type": "object",
             "required": [
-                "password",
-                "username"
+                "transactions"
             ],
             "properties": {
-                "password": {
-                    "description": "The password for the login",
+                "nextCursor": {
+                    "description": "The cursor to pass to fetch the next page. Omitted on the last page.",
                     "type": "string"
                 },
-                "username": {
-                    "description": "The username for the login",
-                    "type": "string"
+                "transactions": {
+                    "description": "The transactions of the page",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AccountTransactionResponseDTO"
+                    }
                 }
             }
         },
@@ -381,6 +1952,18 @@ const docTemplate = `{
                     "description": "The primary bank account ID associated with the transaction",
                     "type": "string"
                 },
+                "category": {
+                    "description": "The category the bank account gives the transaction. Omitted when the transaction is uncategorized.",
+                    "type": "string"
+                },
+                "categorySource": {
+                    "description": "Whether the category was given by a rule or by the account holder (rule or manual). Omitted when the\ntransaction is uncategorized.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.CategorySource"
+                        }
+                    ]
+                },
                 "createdAt": {
                     "description": "The timestamp of when the transaction was created",
                     "type": "string"
@@ -393,6 +1976,10 @@ const docTemplate = `{
                     "description": "The unique identifier of the transaction",
                     "type": "string"
                 },
+                "memo": {
+                    "description": "The note the sender gave the transaction. Omitted when there is none.",
+                    "type": "string"
+                },
                 "otherBankAccountId": {
                     "description": "The other bank account ID involved in the transaction",
                     "type": "string"
@@ -423,6 +2010,35 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.ApplyCategoryRulesRequestDTO": {
+            "type": "object",
+            "required": [
+                "bankAccountId"
+            ],
+            "properties": {
+                "bankAccountId": {
+                    "description": "The bank account ID whose transactions are categorized",
+                    "type": "string"
+                },
+                "cursor": {
+                    "description": "The nextCursor of the previous response, to resume from. Omitted to start from the first transaction.",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ApplyCategoryRulesResponseDTO": {
+            "type": "object",
+            "properties": {
+                "changed": {
+                    "description": "The number of transactions whose category changed",
+                    "type": "integer"
+                },
+                "nextCursor": {
+                    "description": "The cursor to resume from in the next request. Omitted once every transaction was categorized.",
+                    "type": "string"
+                }
+            }
+        },
         "dto.BankAccountDTO": {
             "type": "object",
             "required": [
@@ -459,6 +2075,104 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.BankAccountOpenRequestDTO": {
+            "type": "object",
+            "required": [
+                "accountType"
+            ],
+            "properties": {
+                "accountType": {
+                    "description": "The type of the bank account to open (savings, checking or investment)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.BankAccountType"
+                        }
+                    ]
+                }
+            }
+        },
+        "dto.CategoryRuleRequestDTO": {
+            "type": "object",
+            "required": [
+                "category"
+            ],
+            "properties": {
+                "category": {
+                    "description": "The category given to the matching transactions. At most 64 characters.",
+                    "type": "string"
+                },
+                "counterpartyBankAccountId": {
+                    "description": "The bank account ID of the other side of the matching transactions",
+                    "type": "string"
+                },
+                "maxAmount": {
+                    "description": "The largest amount of the matching transactions",
+                    "type": "string"
+                },
+                "memoPattern": {
+                    "description": "A regular expression in RE2 syntax the memo of the matching transactions contains a match of. At most 256\ncharacters.",
+                    "type": "string"
+                },
+                "minAmount": {
+                    "description": "The smallest amount of the matching transactions",
+                    "type": "string"
+                },
+                "transactionNature": {
+                    "description": "The nature of the matching transactions (debit or credit)",
+                    "type": "string",
+                    "enum": [
+                        "debit",
+                        "credit"
+                    ]
+                }
+            }
+        },
+        "dto.CategoryRuleResponseDTO": {
+            "type": "object",
+            "required": [
+                "category",
+                "createdAt",
+                "id"
+            ],
+            "properties": {
+                "category": {
+                    "description": "The category given to the matching transactions",
+                    "type": "string"
+                },
+                "counterpartyBankAccountId": {
+                    "description": "The bank account ID of the other side of the matching transactions. Omitted when any bank account matches.",
+                    "type": "string"
+                },
+                "createdAt": {
+                    "description": "The timestamp of when the rule was added",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "The unique identifier of the rule",
+                    "type": "string"
+                },
+                "maxAmount": {
+                    "description": "The largest amount of the matching transactions. Omitted when unbounded.",
+                    "type": "string"
+                },
+                "memoPattern": {
+                    "description": "The regular expression matched against the memo of the transactions. Omitted when any memo matches.",
+                    "type": "string"
+                },
+                "minAmount": {
+                    "description": "The smallest amount of the matching transactions. Omitted when unbounded.",
+                    "type": "string"
+                },
+                "transactionNature": {
+                    "description": "The nature of the matching transactions. Omitted when both natures match.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.TransactionNature"
+                        }
+                    ]
+                }
+            }
+        },
         "dto.KnownBankAccountDTO": {
             "type": "object",
             "required": [
@@ -483,6 +2197,82 @@ const docTemplate = `{
                 "id": {
                     "description": "The account ID of the known account",
                     "type": "string"
+                },
+                "nickname": {
+                    "description": "The nickname given to the known account by the account holder. Empty if none was given.",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PayeeNicknameRequestDTO": {
+            "type": "object",
+            "properties": {
+                "nickname": {
+                    "description": "The new nickname of the payee. At most 64 characters, empty to remove the nickname.",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PayeeRequestDTO": {
+            "type": "object",
+            "required": [
+                "accountNumber"
+            ],
+            "properties": {
+                "accountNumber": {
+                    "description": "The account number of the bank account to add",
+                    "type": "string"
+                },
+                "nickname": {
+                    "description": "The nickname to give the payee. At most 64 characters.",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PendingTransactionRequestDTO": {
+            "type": "object",
+            "required": [
+                "amount",
+                "expirationDate",
+                "fromBankAccountId",
+                "toBankAccountId"
+            ],
+            "properties": {
+                "amount": {
+                    "description": "The amount to be transferred. Positive and valid to two decimal places.",
+                    "type": "string"
+                },
+                "confirmUnknownPayee": {
+                    "description": "Confirms a transfer to a bank account that is not a known payee, when such transfers require confirmation",
+                    "type": "boolean"
+                },
+                "expirationDate": {
+                    "description": "The moment the pending transaction expires and is revoked, in an RFC3339 compliant format",
+                    "type": "string"
+                },
+                "fromBankAccountId": {
+                    "description": "The bank account ID of the account from which the amount is to be transferred",
+                    "type": "string"
+                },
+                "memo": {
+                    "description": "A note shown to both bank accounts along with the transaction. At most 140 characters.",
+                    "type": "string"
+                },
+                "toBankAccountId": {
+                    "description": "The bank account ID of the account to which the amount is to be transferred",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PendingTransactionResponseDTO": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "description": "The unique identifier of the pending transaction",
+                    "type": "string"
                 }
             }
         },
@@ -503,6 +2293,83 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.TokenRefreshRequestDTO": {
+            "type": "object",
+            "required": [
+                "refreshToken"
+            ],
+            "properties": {
+                "refreshToken": {
+                    "description": "The refresh token issued at login or by a previous refresh",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TokenResponseDTO": {
+            "type": "object",
+            "required": [
+                "accessToken",
+                "accessTokenExpiresAt",
+                "refreshToken",
+                "refreshTokenExpiresAt",
+                "tokenType"
+            ],
+            "properties": {
+                "accessToken": {
+                    "description": "The short-lived token authenticating requests to protected routes",
+                    "type": "string"
+                },
+                "accessTokenExpiresAt": {
+                    "description": "The expiry time of the access token in an RFC3339 compliant format",
+                    "type": "string"
+                },
+                "refreshToken": {
+                    "description": "The long-lived token used to obtain a new pair of tokens",
+                    "type": "string"
+                },
+                "refreshTokenExpiresAt": {
+                    "description": "The expiry time of the refresh token in an RFC3339 compliant format",
+                    "type": "string"
+                },
+                "tokenType": {
+                    "description": "The type of the tokens, to be given in the Authorization header as \"Bearer \u003caccessToken\u003e\"",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TransactionCategoryRequestDTO": {
+            "type": "object",
+            "required": [
+                "bankAccountId"
+            ],
+            "properties": {
+                "bankAccountId": {
+                    "description": "The bank account ID of the side of the transaction to categorize",
+                    "type": "string"
+                },
+                "category": {
+                    "description": "The category of the transaction. At most 64 characters, empty to let the category rules categorize it.",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TransactionCategoryResponseDTO": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "description": "The category of the transaction. Omitted when the transaction is uncategorized.",
+                    "type": "string"
+                },
+                "categorySource": {
+                    "description": "Whether the category was given by a rule or by the account holder (rule or manual). Omitted when the\ntransaction is uncategorized.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.CategorySource"
+                        }
+                    ]
+                }
+            }
+        },
         "dto.TransactionRequestDTO": {
             "type": "object",
             "required": [
@@ -512,19 +2379,54 @@ const docTemplate = `{
             ],
             "properties": {
                 "amount": {
-                    "description": "The amount to be transferred. Valid to two decimal places.",
+                    "description": "The amount to be transferred. Positive and valid to two decimal places.",
                     "type": "string"
                 },
+                "confirmUnknownPayee": {
+                    "description": "Confirms a transfer to a bank account that is not a known payee, when such transfers require confirmation",
+                    "type": "boolean"
+                },
                 "fromBankAccountId": {
                     "description": "The bank account ID of the account from which the amount is to be transferred",
                     "type": "string"
                 },
+                "memo": {
+                    "description": "A note shown to both bank accounts along with the transaction. At most 140 characters.",
+                    "type": "string"
+                },
                 "toBankAccountId": {
                     "description": "The bank account ID of the account to which the amount is to be transferred",
                     "type": "string"
                 }
             }
         },
+        "dto.TransactionResponseDTO": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "description": "The unique identifier of the transaction",
+                    "type": "string"
+                }
+            }
+        },
+        "model.BalanceBucketSize": {
+            "type": "string",
+            "enum": [
+                "day",
+                "week",
+                "month",
+                "quarter"
+            ],
+            "x-enum-varnames": [
+                "DailyBuckets",
+                "WeeklyBuckets",
+                "MonthlyBuckets",
+                "QuarterlyBuckets"
+            ]
+        },
         "model.BankAccountType": {
             "type": "string",
             "enum": [
@@ -538,6 +2440,17 @@ const docTemplate = `{
                 "Investment"
             ]
         },
+        "model.CategorySource": {
+            "type": "string",
+            "enum": [
+                "rule",
+                "manual"
+            ],
+            "x-enum-varnames": [
+                "RuleCategory",
+                "ManualCategory"
+            ]
+        },
         "model.PendingTransactionStatus": {
             "type": "string",
             "enum": [
@@ -573,14 +2486,65 @@ const docTemplate = `{
                 "Pending"
             ]
         },
-        "utils.ErrorMessage": {
+        "problem.Details": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "insufficient_funds"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "insufficient balance in BankAccount 65f1c0e4b4a1f0a9c8d7e6f5"
+                },
+                "errors": {
+                    "description": "The fields of the request that failed validation, only given with the invalid_fields code",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/validation.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/transactions"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 422
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Unprocessable Entity"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "urn:wallet:problem:insufficient_funds"
+                }
+            }
+        },
+        "validation.FieldError": {
             "type": "object",
             "properties": {
+                "field": {
+                    "description": "The path of the field in the request body, e.g. person.firstName",
+                    "type": "string",
+                    "example": "amount"
+                },
                 "message": {
-                    "type": "string"
+                    "description": "Why the value of the field was rejected",
+                    "type": "string",
+                    "example": "must have at most 2 decimal places"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "Access token issued by /accounts/login, given as \"Bearer \u003caccessToken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
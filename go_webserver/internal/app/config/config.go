@@ -0,0 +1,254 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"time"
+	"webserver/internal/app/server/middleware"
+	"webserver/internal/app/server/router"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/tracing"
+)
+
+const (
+	MongodbBackend = "mongodb"
+	MemoryBackend  = "memory"
+	SQLiteBackend  = "sqlite"
+)
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Config holds everything the webserver can be configured with. Every setting has a default, which a config file, the
+// environment and command line flags override in that order; see settings for their names.
+type Config struct {
+	Server    ServerConfig
+	Storage   StorageConfig
+	Session   SessionConfig
+	Features  FeatureConfig
+	Logging   LoggingConfig
+	Tracing   TracingConfig
+	Statement StatementConfig
+}
+
+type ServerConfig struct {
+	ListenAddress string
+	// TLSCertFile and TLSKeyFile serve HTTPS when both are given, and plain HTTP when neither is
+	TLSCertFile       string
+	TLSKeyFile        string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout is how long requests in flight are given to finish once the webserver is asked to stop
+	ShutdownTimeout    time.Duration
+	RouteBudgetDefault time.Duration
+	RouteBudgets       string
+	// MetricsListenAddress is where the metrics are served, apart from the API, and turns them off when empty
+	MetricsListenAddress string
+}
+
+type StorageConfig struct {
+	Backend string
+	Mongo   MongoConfig
+	SQLite  SQLiteConfig
+}
+
+type MongoConfig struct {
+	URI             string
+	Database        string
+	MaxPoolSize     uint64
+	MinPoolSize     uint64
+	MaxConnIdleTime time.Duration
+	ConnectTimeout  time.Duration
+	// DisconnectTimeout is how long operations in progress are given to finish when the webserver disconnects
+	DisconnectTimeout time.Duration
+}
+
+type SQLiteConfig struct {
+	Path string
+}
+
+type SessionConfig struct {
+	// TokenSecret signs session tokens, a random one is generated when it is empty
+	TokenSecret     string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+type FeatureConfig struct {
+	PendingTransactionExpirer        bool
+	PendingTransactionExpiryInterval time.Duration
+	UnknownPayeePolicy               model.UnknownPayeePolicy
+	BalanceSnapshots                 bool
+	BalanceSnapshotInterval          time.Duration
+}
+
+type LoggingConfig struct {
+	Level  slog.Level
+	Format string
+}
+
+type TracingConfig struct {
+	Exporter     string
+	OTLPEndpoint string
+	SampleRatio  float64
+}
+
+type StatementConfig struct {
+	// Currency is the ISO 4217 code of the currency statements give amounts in
+	Currency string
+	// BankId identifies the wallet in OFX and camt.053 statements
+	BankId string
+}
+
+// Default returns the configuration the webserver runs with when nothing is configured
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			ListenAddress:        ":8080",
+			ReadTimeout:          10 * time.Second,
+			ReadHeaderTimeout:    5 * time.Second,
+			WriteTimeout:         15 * time.Second,
+			IdleTimeout:          2 * time.Minute,
+			ShutdownTimeout:      20 * time.Second,
+			RouteBudgetDefault:   3 * time.Second,
+			MetricsListenAddress: "localhost:9090",
+		},
+		Storage: StorageConfig{
+			Backend: MongodbBackend,
+			Mongo: MongoConfig{
+				URI:               "mongodb://localhost:30001",
+				Database:          "wallet",
+				MaxPoolSize:       100,
+				ConnectTimeout:    30 * time.Second,
+				DisconnectTimeout: 10 * time.Second,
+			},
+			SQLite: SQLiteConfig{
+				Path: "wallet.db",
+			},
+		},
+		Session: SessionConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 24 * time.Hour,
+		},
+		Features: FeatureConfig{
+			PendingTransactionExpirer:        true,
+			PendingTransactionExpiryInterval: time.Minute,
+			UnknownPayeePolicy:               model.AllowUnknownPayees,
+			BalanceSnapshots:                 true,
+			BalanceSnapshotInterval:          time.Hour,
+		},
+		Logging: LoggingConfig{
+			Level:  slog.LevelInfo,
+			Format: logging.TextFormat,
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracing.NoExporter,
+			SampleRatio: 1,
+		},
+		Statement: StatementConfig{
+			Currency: "EUR",
+			BankId:   "WALLET",
+		},
+	}
+}
+
+// ParsedRouteBudgets returns the route budgets, which Validate has checked to parse
+func (c *Config) ParsedRouteBudgets() middleware.RouteBudgets {
+	budgets, _ := middleware.ParseRouteBudgets(c.Server.RouteBudgets, c.Server.RouteBudgetDefault,
+		router.RouteNames)
+	return budgets
+}
+
+// Validate reports every setting that the webserver cannot start with
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	s := c.Server
+	check(s.ListenAddress != "", "listen-address must not be empty")
+	check(s.MetricsListenAddress != s.ListenAddress, "metrics-listen-address must differ from listen-address")
+	check((s.TLSCertFile == "") == (s.TLSKeyFile == ""), "tls-cert-file and tls-key-file must be given together")
+	check(s.ReadTimeout >= 0, "http-read-timeout must not be negative")
+	check(s.ReadHeaderTimeout >= 0, "http-read-header-timeout must not be negative")
+	check(s.WriteTimeout >= 0, "http-write-timeout must not be negative")
+	check(s.IdleTimeout >= 0, "http-idle-timeout must not be negative")
+	check(s.ShutdownTimeout > 0, "shutdown-timeout must be positive")
+	check(s.RouteBudgetDefault > 0, "route-budget-default must be positive")
+	if budgets, err := middleware.ParseRouteBudgets(s.RouteBudgets, s.RouteBudgetDefault, router.RouteNames); err != nil {
+		errs = append(errs, fmt.Errorf("route-budgets is invalid: %w", err))
+	} else if s.WriteTimeout > 0 {
+		// A write timeout shorter than a route budget cuts the response off before the route gives up on the request
+		longest := budgets.Default
+		for _, budget := range budgets.Routes {
+			longest = max(longest, budget)
+		}
+		check(s.WriteTimeout > longest, "http-write-timeout must be longer than the longest route budget %s", longest)
+	}
+
+	st := c.Storage
+	switch st.Backend {
+	case MongodbBackend:
+		check(st.Mongo.URI != "", "mongo-url must not be empty")
+		check(st.Mongo.Database != "", "mongo-database must not be empty")
+		check(st.Mongo.MaxPoolSize == 0 || st.Mongo.MinPoolSize <= st.Mongo.MaxPoolSize,
+			"mongo-min-pool-size must not be larger than mongo-max-pool-size")
+		check(st.Mongo.MaxConnIdleTime >= 0, "mongo-max-conn-idle-time must not be negative")
+		check(st.Mongo.ConnectTimeout > 0, "mongo-connect-timeout must be positive")
+		check(st.Mongo.DisconnectTimeout > 0, "mongo-disconnect-timeout must be positive")
+	case SQLiteBackend:
+		check(st.SQLite.Path != "", "sqlite-path must not be empty")
+	case MemoryBackend:
+	default:
+		errs = append(errs, fmt.Errorf("storage-backend %q is invalid, expected one of mongodb, sqlite or memory",
+			st.Backend))
+	}
+
+	check(c.Session.AccessTokenTTL > 0, "access-token-ttl must be positive")
+	check(c.Session.RefreshTokenTTL > c.Session.AccessTokenTTL,
+		"refresh-token-ttl must be longer than access-token-ttl")
+
+	f := c.Features
+	check(!f.PendingTransactionExpirer || f.PendingTransactionExpiryInterval > 0,
+		"pending-transaction-expiry-interval must be positive")
+	check(!f.BalanceSnapshots || f.BalanceSnapshotInterval > 0, "balance-snapshot-interval must be positive")
+	switch f.UnknownPayeePolicy {
+	case model.AllowUnknownPayees, model.RejectUnknownPayees, model.ConfirmUnknownPayees:
+	default:
+		errs = append(errs, fmt.Errorf("unknown-payee-policy %q is invalid, expected one of allow, reject or confirm",
+			f.UnknownPayeePolicy))
+	}
+
+	switch c.Logging.Format {
+	case logging.TextFormat, logging.JSONFormat:
+	default:
+		errs = append(errs, fmt.Errorf("log-format %q is invalid, expected one of text or json", c.Logging.Format))
+	}
+
+	tr := c.Tracing
+	switch tr.Exporter {
+	case tracing.NoExporter, tracing.StdoutExporter, tracing.OTLPExporter:
+	default:
+		errs = append(errs, fmt.Errorf("tracing-exporter %q is invalid, expected one of none, stdout or otlp",
+			tr.Exporter))
+	}
+	if tr.OTLPEndpoint != "" {
+		endpoint, err := url.Parse(tr.OTLPEndpoint)
+		check(err == nil && (endpoint.Scheme == "http" || endpoint.Scheme == "https") && endpoint.Host != "",
+			"tracing-otlp-endpoint must be an http or https URL")
+	}
+	check(tr.SampleRatio >= 0 && tr.SampleRatio <= 1, "tracing-sample-ratio must be between 0 and 1")
+
+	check(currencyCode.MatchString(c.Statement.Currency), "statement-currency %q is invalid, expected an ISO 4217 code",
+		c.Statement.Currency)
+	check(c.Statement.BankId != "", "statement-bank-id must not be empty")
+	return errors.Join(errs...)
+}
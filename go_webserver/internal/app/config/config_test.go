@@ -0,0 +1,209 @@
+package config
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/tracing"
+)
+
+func envOf(values map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, present := values[name]
+		return value, present
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("Uses the defaults when nothing is configured", func(t *testing.T) {
+		cfg, err := Load(nil, envOf(nil), io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, Default(), cfg)
+	})
+
+	t.Run("Lets the environment override the file and flags override the environment", func(t *testing.T) {
+		path := writeConfigFile(t, `{
+			"listen-address": ":9000",
+			"mongo-database": "from-file",
+			"mongo-max-pool-size": 20,
+			"http-write-timeout": "30s",
+			"pending-transaction-expirer": false
+		}`)
+		env := envOf(map[string]string{
+			"CONFIG_FILE":    path,
+			"MONGO_DATABASE": "from-env",
+			"LISTEN_ADDRESS": ":9001",
+		})
+		cfg, err := Load([]string{"--listen-address", ":9002", "--unknown-payee-policy=confirm"}, env, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, ":9002", cfg.Server.ListenAddress)
+		assert.Equal(t, "from-env", cfg.Storage.Mongo.Database)
+		assert.Equal(t, uint64(20), cfg.Storage.Mongo.MaxPoolSize)
+		assert.Equal(t, 30*time.Second, cfg.Server.WriteTimeout)
+		assert.False(t, cfg.Features.PendingTransactionExpirer)
+		assert.Equal(t, model.ConfirmUnknownPayees, cfg.Features.UnknownPayeePolicy)
+	})
+
+	t.Run("Reads the config file given by flag over the one in the environment", func(t *testing.T) {
+		path := writeConfigFile(t, `{"storage-backend": "memory"}`)
+		env := envOf(map[string]string{"CONFIG_FILE": filepath.Join(t.TempDir(), "missing.json")})
+		cfg, err := Load([]string{"--config", path}, env, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, MemoryBackend, cfg.Storage.Backend)
+	})
+
+	t.Run("Ignores environment variables that are set but empty", func(t *testing.T) {
+		env := envOf(map[string]string{"STORAGE_BACKEND": "", "UNKNOWN_PAYEE_POLICY": ""})
+		cfg, err := Load(nil, env, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, MongodbBackend, cfg.Storage.Backend)
+		assert.Equal(t, model.AllowUnknownPayees, cfg.Features.UnknownPayeePolicy)
+	})
+
+	t.Run("Accepts boolean flags without a value", func(t *testing.T) {
+		env := envOf(map[string]string{"PENDING_TRANSACTION_EXPIRER": "false"})
+		cfg, err := Load([]string{"--pending-transaction-expirer"}, env, io.Discard)
+		assert.Nil(t, err)
+		assert.True(t, cfg.Features.PendingTransactionExpirer)
+	})
+
+	t.Run("Rejects values that do not parse", func(t *testing.T) {
+		_, err := Load(nil, envOf(map[string]string{"HTTP_READ_TIMEOUT": "soon"}), io.Discard)
+		assert.ErrorContains(t, err, "HTTP_READ_TIMEOUT")
+		_, err = Load([]string{"--mongo-max-pool-size=-1"}, envOf(nil), io.Discard)
+		assert.ErrorContains(t, err, "mongo-max-pool-size")
+		_, err = Load(nil, envOf(map[string]string{"LOG_LEVEL": "loud"}), io.Discard)
+		assert.ErrorContains(t, err, "LOG_LEVEL")
+	})
+
+	t.Run("Reads the log level and format", func(t *testing.T) {
+		env := envOf(map[string]string{"LOG_LEVEL": "debug", "LOG_FORMAT": "json"})
+		cfg, err := Load(nil, env, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, slog.LevelDebug, cfg.Logging.Level)
+		assert.Equal(t, logging.JSONFormat, cfg.Logging.Format)
+	})
+
+	t.Run("Reads the tracing settings", func(t *testing.T) {
+		path := writeConfigFile(t, `{"tracing-exporter": "otlp", "tracing-sample-ratio": 0.25}`)
+		env := envOf(map[string]string{"TRACING_OTLP_ENDPOINT": "http://collector:4318"})
+		cfg, err := Load([]string{"--config", path}, env, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, TracingConfig{
+			Exporter:     tracing.OTLPExporter,
+			OTLPEndpoint: "http://collector:4318",
+			SampleRatio:  0.25,
+		}, cfg.Tracing)
+	})
+
+	t.Run("Reads the balance snapshot settings", func(t *testing.T) {
+		env := envOf(map[string]string{"BALANCE_SNAPSHOTS": "false", "BALANCE_SNAPSHOT_INTERVAL": "15m"})
+		cfg, err := Load(nil, env, io.Discard)
+		assert.Nil(t, err)
+		assert.False(t, cfg.Features.BalanceSnapshots)
+		assert.Equal(t, 15*time.Minute, cfg.Features.BalanceSnapshotInterval)
+	})
+
+	t.Run("Reads the statement settings", func(t *testing.T) {
+		env := envOf(map[string]string{"STATEMENT_CURRENCY": "CHF", "STATEMENT_BANK_ID": "WALLETCH"})
+		cfg, err := Load(nil, env, io.Discard)
+		assert.Nil(t, err)
+		assert.Equal(t, StatementConfig{Currency: "CHF", BankId: "WALLETCH"}, cfg.Statement)
+	})
+
+	t.Run("Rejects unknown settings in the config file", func(t *testing.T) {
+		path := writeConfigFile(t, `{"listen-adress": ":9000"}`)
+		_, err := Load([]string{"--config", path}, envOf(nil), io.Discard)
+		assert.ErrorContains(t, err, "unknown setting listen-adress")
+	})
+
+	t.Run("Reports help requests", func(t *testing.T) {
+		_, err := Load([]string{"--help"}, envOf(nil), io.Discard)
+		assert.ErrorIs(t, err, flag.ErrHelp)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("Accepts the defaults", func(t *testing.T) {
+		assert.Nil(t, Default().Validate())
+	})
+
+	t.Run("Reports every invalid setting", func(t *testing.T) {
+		cfg := Default()
+		cfg.Server.TLSCertFile = "cert.pem"
+		cfg.Storage.Mongo.MinPoolSize = 200
+		cfg.Features.UnknownPayeePolicy = "sometimes"
+		cfg.Logging.Format = "xml"
+		cfg.Tracing.Exporter = "jaeger"
+		cfg.Tracing.OTLPEndpoint = "collector:4318"
+		cfg.Tracing.SampleRatio = 1.5
+		cfg.Statement.Currency = "eur"
+		cfg.Statement.BankId = ""
+		cfg.Features.BalanceSnapshotInterval = 0
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "tls-cert-file and tls-key-file must be given together")
+		assert.ErrorContains(t, err, "mongo-min-pool-size must not be larger than mongo-max-pool-size")
+		assert.ErrorContains(t, err, `unknown-payee-policy "sometimes" is invalid`)
+		assert.ErrorContains(t, err, `log-format "xml" is invalid`)
+		assert.ErrorContains(t, err, `tracing-exporter "jaeger" is invalid`)
+		assert.ErrorContains(t, err, "tracing-otlp-endpoint must be an http or https URL")
+		assert.ErrorContains(t, err, "tracing-sample-ratio must be between 0 and 1")
+		assert.ErrorContains(t, err, `statement-currency "eur" is invalid`)
+		assert.ErrorContains(t, err, "statement-bank-id must not be empty")
+		assert.ErrorContains(t, err, "balance-snapshot-interval must be positive")
+	})
+
+	t.Run("Requires the write timeout to outlast every route budget", func(t *testing.T) {
+		cfg := Default()
+		cfg.Server.RouteBudgets = "login=20s"
+		assert.ErrorContains(t, cfg.Validate(), "http-write-timeout must be longer than the longest route budget 20s")
+		cfg.Server.WriteTimeout = 0
+		assert.Nil(t, cfg.Validate())
+	})
+
+	t.Run("Keeps the metrics off the listener of the API", func(t *testing.T) {
+		cfg := Default()
+		cfg.Server.MetricsListenAddress = cfg.Server.ListenAddress
+		assert.ErrorContains(t, cfg.Validate(), "metrics-listen-address must differ from listen-address")
+		cfg.Server.MetricsListenAddress = ""
+		assert.Nil(t, cfg.Validate())
+	})
+
+	t.Run("Rejects budgets of routes the router does not have", func(t *testing.T) {
+		cfg := Default()
+		cfg.Server.RouteBudgets = "transactionInsert=2s,transfer=2s"
+		assert.ErrorContains(t, cfg.Validate(), "route-budgets is invalid: unknown route transfer")
+	})
+
+	t.Run("Only checks the settings of the chosen storage backend", func(t *testing.T) {
+		cfg := Default()
+		cfg.Storage.Backend = SQLiteBackend
+		cfg.Storage.Mongo.URI = ""
+		assert.Nil(t, cfg.Validate())
+		cfg.Storage.Backend = "postgres"
+		assert.ErrorContains(t, cfg.Validate(), `storage-backend "postgres" is invalid`)
+	})
+
+	t.Run("Builds the route budgets", func(t *testing.T) {
+		cfg := Default()
+		cfg.Server.RouteBudgets = "login=5s"
+		budgets := cfg.ParsedRouteBudgets()
+		assert.Equal(t, 5*time.Second, budgets.For("login"))
+		assert.Equal(t, 3*time.Second, budgets.For("accountDetails"))
+	})
+}
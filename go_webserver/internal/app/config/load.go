@@ -0,0 +1,275 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+	"webserver/internal/pkg/logging"
+)
+
+// setting is a single configuration value, named the same way in config files and command line flags
+type setting struct {
+	name  string
+	env   string
+	usage string
+	// isBool lets the command line flag be given without a value
+	isBool bool
+	set    func(c *Config, raw string) error
+}
+
+var settings = []setting{
+	stringSetting("listen-address", "LISTEN_ADDRESS", "the address the webserver listens on",
+		func(c *Config) *string { return &c.Server.ListenAddress }),
+	stringSetting("metrics-listen-address", "METRICS_LISTEN_ADDRESS",
+		"the address the metrics are served on, apart from the API, or empty to not serve them",
+		func(c *Config) *string { return &c.Server.MetricsListenAddress }),
+	stringSetting("tls-cert-file", "TLS_CERT_FILE", "the certificate file to serve HTTPS with",
+		func(c *Config) *string { return &c.Server.TLSCertFile }),
+	stringSetting("tls-key-file", "TLS_KEY_FILE", "the private key file of the HTTPS certificate",
+		func(c *Config) *string { return &c.Server.TLSKeyFile }),
+	durationSetting("http-read-timeout", "HTTP_READ_TIMEOUT", "the time limit for reading a whole request",
+		func(c *Config) *time.Duration { return &c.Server.ReadTimeout }),
+	durationSetting("http-read-header-timeout", "HTTP_READ_HEADER_TIMEOUT",
+		"the time limit for reading request headers",
+		func(c *Config) *time.Duration { return &c.Server.ReadHeaderTimeout }),
+	durationSetting("http-write-timeout", "HTTP_WRITE_TIMEOUT", "the time limit for writing a response",
+		func(c *Config) *time.Duration { return &c.Server.WriteTimeout }),
+	durationSetting("http-idle-timeout", "HTTP_IDLE_TIMEOUT", "how long idle keep-alive connections are kept open",
+		func(c *Config) *time.Duration { return &c.Server.IdleTimeout }),
+	durationSetting("shutdown-timeout", "SHUTDOWN_TIMEOUT",
+		"how long requests in flight may take to finish on shutdown",
+		func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout }),
+	durationSetting("route-budget-default", "ROUTE_BUDGET_DEFAULT", "the time limit for serving a request",
+		func(c *Config) *time.Duration { return &c.Server.RouteBudgetDefault }),
+	stringSetting("route-budgets", "ROUTE_BUDGETS",
+		"time limits of individual routes, e.g. login=5s,transactionInsert=2s",
+		func(c *Config) *string { return &c.Server.RouteBudgets }),
+	stringSetting("storage-backend", "STORAGE_BACKEND", "where data is stored, one of mongodb, sqlite or memory",
+		func(c *Config) *string { return &c.Storage.Backend }),
+	stringSetting("mongo-url", "MONGO_URL", "the connection string of the MongoDB deployment",
+		func(c *Config) *string { return &c.Storage.Mongo.URI }),
+	stringSetting("mongo-database", "MONGO_DATABASE", "the MongoDB database the wallet is stored in",
+		func(c *Config) *string { return &c.Storage.Mongo.Database }),
+	uintSetting("mongo-max-pool-size", "MONGO_MAX_POOL_SIZE",
+		"the most connections kept per MongoDB server, 0 for no limit",
+		func(c *Config) *uint64 { return &c.Storage.Mongo.MaxPoolSize }),
+	uintSetting("mongo-min-pool-size", "MONGO_MIN_POOL_SIZE", "the fewest connections kept per MongoDB server",
+		func(c *Config) *uint64 { return &c.Storage.Mongo.MinPoolSize }),
+	durationSetting("mongo-max-conn-idle-time", "MONGO_MAX_CONN_IDLE_TIME",
+		"how long MongoDB connections may stay idle, 0 for no limit",
+		func(c *Config) *time.Duration { return &c.Storage.Mongo.MaxConnIdleTime }),
+	durationSetting("mongo-connect-timeout", "MONGO_CONNECT_TIMEOUT", "the time limit for opening a MongoDB connection",
+		func(c *Config) *time.Duration { return &c.Storage.Mongo.ConnectTimeout }),
+	durationSetting("mongo-disconnect-timeout", "MONGO_DISCONNECT_TIMEOUT",
+		"how long MongoDB operations in progress may take to finish on shutdown",
+		func(c *Config) *time.Duration { return &c.Storage.Mongo.DisconnectTimeout }),
+	stringSetting("sqlite-path", "SQLITE_PATH", "the SQLite database file",
+		func(c *Config) *string { return &c.Storage.SQLite.Path }),
+	stringSetting("session-token-secret", "SESSION_TOKEN_SECRET", "the key session tokens are signed with",
+		func(c *Config) *string { return &c.Session.TokenSecret }),
+	durationSetting("access-token-ttl", "ACCESS_TOKEN_TTL", "how long access tokens are valid",
+		func(c *Config) *time.Duration { return &c.Session.AccessTokenTTL }),
+	durationSetting("refresh-token-ttl", "REFRESH_TOKEN_TTL", "how long refresh tokens are valid",
+		func(c *Config) *time.Duration { return &c.Session.RefreshTokenTTL }),
+	boolSetting("pending-transaction-expirer", "PENDING_TRANSACTION_EXPIRER",
+		"whether expired pending transactions are revoked and expired idempotency keys deleted in the background",
+		func(c *Config) *bool { return &c.Features.PendingTransactionExpirer }),
+	durationSetting("pending-transaction-expiry-interval", "PENDING_TRANSACTION_EXPIRY_INTERVAL",
+		"how often expired pending transactions are revoked and expired idempotency keys deleted",
+		func(c *Config) *time.Duration { return &c.Features.PendingTransactionExpiryInterval }),
+	stringSetting("unknown-payee-policy", "UNKNOWN_PAYEE_POLICY",
+		"what happens to transfers to unknown payees, one of allow, reject or confirm",
+		func(c *Config) *string { return (*string)(&c.Features.UnknownPayeePolicy) }),
+	boolSetting("balance-snapshots", "BALANCE_SNAPSHOTS",
+		"whether end-of-day balance snapshots are taken in the background",
+		func(c *Config) *bool { return &c.Features.BalanceSnapshots }),
+	durationSetting("balance-snapshot-interval", "BALANCE_SNAPSHOT_INTERVAL",
+		"how often bank accounts are checked for days to snapshot",
+		func(c *Config) *time.Duration { return &c.Features.BalanceSnapshotInterval }),
+	levelSetting("log-level", "LOG_LEVEL", "the least severe messages logged, one of debug, info, warn or error",
+		func(c *Config) *slog.Level { return &c.Logging.Level }),
+	stringSetting("log-format", "LOG_FORMAT", "how messages are logged, one of text or json",
+		func(c *Config) *string { return &c.Logging.Format }),
+	stringSetting("tracing-exporter", "TRACING_EXPORTER", "where spans are exported, one of none, stdout or otlp",
+		func(c *Config) *string { return &c.Tracing.Exporter }),
+	stringSetting("tracing-otlp-endpoint", "TRACING_OTLP_ENDPOINT",
+		"the URL of the OTLP/HTTP collector spans are exported to, e.g. http://localhost:4318",
+		func(c *Config) *string { return &c.Tracing.OTLPEndpoint }),
+	floatSetting("tracing-sample-ratio", "TRACING_SAMPLE_RATIO", "the fraction of requests that are traced",
+		func(c *Config) *float64 { return &c.Tracing.SampleRatio }),
+	stringSetting("statement-currency", "STATEMENT_CURRENCY", "the ISO 4217 code of the currency of statements",
+		func(c *Config) *string { return &c.Statement.Currency }),
+	stringSetting("statement-bank-id", "STATEMENT_BANK_ID", "the bank identifier OFX and camt.053 statements carry",
+		func(c *Config) *string { return &c.Statement.BankId }),
+}
+
+// Load builds the configuration from the defaults, then the JSON config file given by --config or CONFIG_FILE, then
+// the environment and then the command line flags, and validates it. Environment variables that are set but empty
+// are ignored, so that deployments can pass through variables that may not be set.
+func Load(args []string, lookupEnv func(string) (string, bool), output io.Writer) (*Config, error) {
+	flags := flag.NewFlagSet("webserver", flag.ContinueOnError)
+	flags.SetOutput(output)
+	configFile := flags.String("config", "", "the JSON config file to read settings from, also read from CONFIG_FILE")
+	var given []flagValue
+	for i := range settings {
+		usage := fmt.Sprintf("%s, also read from %s", settings[i].usage, settings[i].env)
+		flags.Var(&recordedFlag{setting: &settings[i], given: &given}, settings[i].name, usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments %v", flags.Args())
+	}
+
+	cfg := Default()
+	if *configFile == "" {
+		*configFile, _ = lookupEnv("CONFIG_FILE")
+	}
+	if *configFile != "" {
+		if err := applyFile(cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range settings {
+		if raw, present := lookupEnv(s.env); present && raw != "" {
+			if err := s.set(cfg, raw); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", s.env, raw, err)
+			}
+		}
+	}
+	for _, value := range given {
+		if err := value.setting.set(cfg, value.raw); err != nil {
+			return nil, fmt.Errorf("invalid --%s %q: %w", value.setting.name, value.raw, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// applyFile reads a JSON object of settings keyed by their flag names, e.g. {"listen-address": ":8443"}
+func applyFile(cfg *Config, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error when reading config file %s: %w", path, err)
+	}
+	var values map[string]json.RawMessage
+	if err = json.Unmarshal(content, &values); err != nil {
+		return fmt.Errorf("error when parsing config file %s: %w", path, err)
+	}
+	byName := make(map[string]*setting, len(settings))
+	for i := range settings {
+		byName[settings[i].name] = &settings[i]
+	}
+	for name, value := range values {
+		s, known := byName[name]
+		if !known {
+			return fmt.Errorf("unknown setting %s in config file %s", name, path)
+		}
+		// Strings are unquoted, while numbers and booleans are parsed from their JSON text
+		raw := string(value)
+		var unquoted string
+		if json.Unmarshal(value, &unquoted) == nil {
+			raw = unquoted
+		}
+		if err = s.set(cfg, raw); err != nil {
+			return fmt.Errorf("invalid %s %s in config file %s: %w", name, value, path, err)
+		}
+	}
+	return nil
+}
+
+type flagValue struct {
+	setting *setting
+	raw     string
+}
+
+// recordedFlag keeps the command line flags in the order they were given, to be applied after the config file and
+// the environment
+type recordedFlag struct {
+	setting *setting
+	given   *[]flagValue
+}
+
+func (f *recordedFlag) String() string {
+	return ""
+}
+
+func (f *recordedFlag) Set(raw string) error {
+	*f.given = append(*f.given, flagValue{setting: f.setting, raw: raw})
+	return nil
+}
+
+func (f *recordedFlag) IsBoolFlag() bool {
+	return f.setting.isBool
+}
+
+func stringSetting(name string, env string, usage string, field func(c *Config) *string) setting {
+	return setting{name: name, env: env, usage: usage, set: func(c *Config, raw string) error {
+		*field(c) = raw
+		return nil
+	}}
+}
+
+func durationSetting(name string, env string, usage string, field func(c *Config) *time.Duration) setting {
+	return setting{name: name, env: env, usage: usage, set: func(c *Config, raw string) error {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		*field(c) = duration
+		return nil
+	}}
+}
+
+func uintSetting(name string, env string, usage string, field func(c *Config) *uint64) setting {
+	return setting{name: name, env: env, usage: usage, set: func(c *Config, raw string) error {
+		value, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = value
+		return nil
+	}}
+}
+
+func floatSetting(name string, env string, usage string, field func(c *Config) *float64) setting {
+	return setting{name: name, env: env, usage: usage, set: func(c *Config, raw string) error {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = value
+		return nil
+	}}
+}
+
+func boolSetting(name string, env string, usage string, field func(c *Config) *bool) setting {
+	return setting{name: name, env: env, usage: usage, isBool: true, set: func(c *Config, raw string) error {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		*field(c) = value
+		return nil
+	}}
+}
+
+func levelSetting(name string, env string, usage string, field func(c *Config) *slog.Level) setting {
+	return setting{name: name, env: env, usage: usage, set: func(c *Config, raw string) error {
+		level, err := logging.ParseLevel(raw)
+		if err != nil {
+			return err
+		}
+		*field(c) = level
+		return nil
+	}}
+}
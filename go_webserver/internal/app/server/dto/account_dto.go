@@ -16,6 +16,8 @@ type KnownBankAccountDTO struct {
 	AccountHolder string `json:"accountHolder" validate:"required"`
 	// The type of the account (e.g., savings, checking)
 	AccountType string `json:"accountType" validate:"required"`
+	// The nickname given to the known account by the account holder. Empty if none was given.
+	Nickname string `json:"nickname"`
 }
 
 // AccountDetailsResponseDTO represents the confidential details of an account belonging to a customer
@@ -35,19 +37,6 @@ type AccountDetailsResponseDTO struct {
 	CreatedAt time.Time `json:"createdAt" validate:"required"`
 }
 
-// AccountTransactionRequestDTO represents a request to retrieve transactions for a specific account
-// between a given time range.
-// @swagger:model AccountTransactionRequestDTO
-
-type AccountTransactionRequestDTO struct {
-	// The bank account ID of the account associated with the transactions
-	BankAccountId string `json:"bankAccountId" validate:"required"`
-	// The start time of the transactions in an RFC3339 compliant format
-	FromTime time.Time `json:"fromTime" validate:"required"`
-	// The end time of the transactions in an RFC3339 compliant format
-	ToTime time.Time `json:"toTime" validate:"required"`
-}
-
 // AccountTransactionResponseDTO represents a transaction between the given account and another account
 // @swagger:model AccountTransactionResponseDTO
 type AccountTransactionResponseDTO struct {
@@ -69,6 +58,22 @@ type AccountTransactionResponseDTO struct {
 	Amount string `json:"amount" validate:"required"`
 	// The timestamp of when the transaction was created
 	CreatedAt time.Time `json:"createdAt" validate:"required"`
+	// The note the sender gave the transaction. Omitted when there is none.
+	Memo string `json:"memo,omitempty"`
+	// The category the bank account gives the transaction. Omitted when the transaction is uncategorized.
+	Category string `json:"category,omitempty"`
+	// Whether the category was given by a rule or by the account holder (rule or manual). Omitted when the
+	// transaction is uncategorized.
+	CategorySource model.CategorySource `json:"categorySource,omitempty"`
+}
+
+// AccountTransactionPageResponseDTO represents a page of the transactions of a bank account
+// @swagger:model AccountTransactionPageResponseDTO
+type AccountTransactionPageResponseDTO struct {
+	// The transactions of the page
+	Transactions []AccountTransactionResponseDTO `json:"transactions" validate:"required"`
+	// The cursor to pass to fetch the next page. Omitted on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 // AccountLoginRequestDTO represents the login credentials for an account
@@ -80,24 +85,105 @@ type AccountLoginRequestDTO struct {
 	Password string `json:"password" validate:"required"`
 }
 
-// AccountBalanceHistoryRequestDTO represents a request to retrieve the account history in months for a specific account
+// AccountRegisterRequestDTO represents the details of a new account
+// @swagger:model AccountRegisterRequestDTO
+type AccountRegisterRequestDTO struct {
+	// The username of the new account. 3 to 32 letters, digits, '.', '_' or '-'.
+	Username string `json:"username" validate:"required"`
+	// The password of the new account. At least 8 characters long.
+	Password string `json:"password" validate:"required"`
+	// The account holder of the new account
+	Person PersonDTO `json:"person" validate:"required"`
+}
+
+// BankAccountOpenRequestDTO represents a request to open a new bank account for the authenticated account
+// @swagger:model BankAccountOpenRequestDTO
+type BankAccountOpenRequestDTO struct {
+	// The type of the bank account to open (savings, checking or investment)
+	AccountType model.BankAccountType `json:"accountType" validate:"required"`
+}
+
+// AccountLoginResponseDTO represents the details of the account that logged in along with its session tokens
+// @swagger:model AccountLoginResponseDTO
+type AccountLoginResponseDTO struct {
+	AccountDetailsResponseDTO
+	TokenResponseDTO
+}
+
+// TokenRefreshRequestDTO represents a request to exchange a refresh token for a new pair of session tokens
+// @swagger:model TokenRefreshRequestDTO
+type TokenRefreshRequestDTO struct {
+	// The refresh token issued at login or by a previous refresh
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// TokenResponseDTO represents a pair of session tokens
+// @swagger:model TokenResponseDTO
+type TokenResponseDTO struct {
+	// The type of the tokens, to be given in the Authorization header as "Bearer <accessToken>"
+	TokenType string `json:"tokenType" validate:"required"`
+	// The short-lived token authenticating requests to protected routes
+	AccessToken string `json:"accessToken" validate:"required"`
+	// The expiry time of the access token in an RFC3339 compliant format
+	AccessTokenExpiresAt time.Time `json:"accessTokenExpiresAt" validate:"required"`
+	// The long-lived token used to obtain a new pair of tokens
+	RefreshToken string `json:"refreshToken" validate:"required"`
+	// The expiry time of the refresh token in an RFC3339 compliant format
+	RefreshTokenExpiresAt time.Time `json:"refreshTokenExpiresAt" validate:"required"`
+}
+
+// AccountBalanceHistoryRequestDTO represents a request to retrieve the balance history of a specific account, split
+// into buckets of the given size
 // @swagger:model AccountBalanceHistoryRequestDTO
 type AccountBalanceHistoryRequestDTO struct {
 	// The bank account ID of the account associated with the transactions
-	BankAccountId string `json:"bankAccountId" validate:"required"`
+	BankAccountId string `json:"bankAccountId" validate:"required,objectid"`
 	// The start time of the transactions in an RFC3339 compliant format
 	FromTime time.Time `json:"fromTime" validate:"required"`
-	// The end time of the transactions in an RFC3339 compliant format
-	ToTime time.Time `json:"toTime" validate:"required"`
+	// The end time of the transactions in an RFC3339 compliant format. At most 60 months after the start time.
+	ToTime time.Time `json:"toTime" validate:"required,gtefield=FromTime,maxmonths=60"`
+	// The size of the buckets, month by default. Weeks start on Mondays and quarters in January, April, July and
+	// October.
+	BucketSize string `json:"bucketSize" validate:"omitempty,oneof=day week month quarter"`
+	// The IANA time zone the buckets start at midnight in, e.g. Europe/Paris. UTC by default.
+	TimeZone string `json:"timeZone" validate:"omitempty,timezone"`
 }
 
-// AccountBalanceHistoryResponseDTO represents the account balance history in months for a specific account
+// StatementRequestDTO represents a request for the statement of a bank account over a period, read from the query
+// parameters of the same names
+// @swagger:model StatementRequestDTO
+type StatementRequestDTO struct {
+	// The bank account ID of the account the statement is for
+	BankAccountId string `json:"bankAccountId" validate:"required,objectid"`
+	// The start of the period in an RFC3339 compliant format
+	FromTime time.Time `json:"fromTime" validate:"required"`
+	// The end of the period in an RFC3339 compliant format. At most 12 months after the start time.
+	ToTime time.Time `json:"toTime" validate:"required,gtefield=FromTime,maxmonths=12"`
+	// The format of the statement, which overrides the Accept header when given
+	Format string `json:"format" validate:"omitempty,oneof=csv ofx camt053"`
+}
+
+// MonthlyStatementRequestDTO represents a request for the printable statement of a bank account over a calendar
+// month, read from the query parameters of the same names
+// @swagger:model MonthlyStatementRequestDTO
+type MonthlyStatementRequestDTO struct {
+	// The bank account ID of the account the statement is for
+	BankAccountId string `json:"bankAccountId" validate:"required,objectid"`
+	// The month of the statement in UTC, e.g. 2024-03
+	Month string `json:"month" validate:"required,datetime=2006-01"`
+}
+
+// AccountBalanceHistoryResponseDTO represents the balance history of a specific account, split into buckets
 // @swagger:model AccountBalanceHistoryResponseDTO
 type AccountBalanceHistoryResponseDTO struct {
 	// The bank account ID of the account associated with the transactions
 	BankAccountId string `json:"bankAccountId" validate:"required"`
-	// The list of account balance months
-	Months []AccountBalanceMonthDTO `json:"months" validate:"required"`
+	// The size of the buckets
+	BucketSize model.BalanceBucketSize `json:"bucketSize" validate:"required"`
+	// The IANA time zone the buckets start at midnight in
+	TimeZone string `json:"timeZone" validate:"required"`
+	// The buckets covering the requested time range, newest first
+	Buckets []AccountBalanceBucketDTO `json:"buckets" validate:"required"`
 }
 
 // BankAccountDTO represents a bank account associated with an account holder
@@ -124,15 +210,25 @@ type PersonDTO struct {
 	LastName string `json:"lastName" validate:"required"`
 }
 
-// AccountBalanceMonthDTO represents the series of account balances at the end of each month in the requested time period
-// @swagger:model AccountBalanceMonthDTO
-type AccountBalanceMonthDTO struct {
-	// The month of the account balance
-	Month int `json:"month" validate:"required"`
-	// The year of the account balance
-	Year int `json:"year" validate:"required"`
-	// The available balance of the account at the end of the given month. Valid to two decimal places.
-	AvailableBalance string `json:"availableBalance" validate:"required"`
-	// The pending balance of the account at the end of the given month. Valid to two decimal places.
-	PendingBalance string `json:"pendingBalance" validate:"required"`
+// AccountBalanceBucketDTO represents the balances of an account over a bucket of its balance history
+// @swagger:model AccountBalanceBucketDTO
+type AccountBalanceBucketDTO struct {
+	// The start of the bucket in an RFC3339 compliant format, offset to the requested time zone
+	StartTime time.Time `json:"startTime" validate:"required"`
+	// The end of the bucket, which is the start of the next one, in an RFC3339 compliant format
+	EndTime time.Time `json:"endTime" validate:"required"`
+	// The available balance of the account at the start of the bucket. Valid to two decimal places.
+	OpeningAvailableBalance string `json:"openingAvailableBalance" validate:"required"`
+	// The available balance of the account at the end of the bucket. Valid to two decimal places.
+	ClosingAvailableBalance string `json:"closingAvailableBalance" validate:"required"`
+	// The lowest available balance of the account during the bucket. Valid to two decimal places.
+	MinAvailableBalance string `json:"minAvailableBalance" validate:"required"`
+	// The highest available balance of the account during the bucket. Valid to two decimal places.
+	MaxAvailableBalance string `json:"maxAvailableBalance" validate:"required"`
+	// The pending balance of the account at the end of the bucket. Valid to two decimal places.
+	ClosingPendingBalance string `json:"closingPendingBalance" validate:"required"`
+	// The total amount of the debit transactions realized during the bucket, which added to the balance
+	TotalDebits string `json:"totalDebits" validate:"required"`
+	// The total amount of the credit transactions realized during the bucket, which took from the balance
+	TotalCredits string `json:"totalCredits" validate:"required"`
 }
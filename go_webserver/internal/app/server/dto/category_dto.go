@@ -0,0 +1,84 @@
+package dto
+
+import (
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+// CategoryRuleRequestDTO represents a request to add a rule categorizing the transactions of the bank accounts of the
+// authenticated account. Conditions that are omitted match every transaction.
+// @swagger:model CategoryRuleRequestDTO
+type CategoryRuleRequestDTO struct {
+	// The category given to the matching transactions. At most 64 characters.
+	Category string `json:"category" validate:"required"`
+	// The bank account ID of the other side of the matching transactions
+	CounterpartyBankAccountId string `json:"counterpartyBankAccountId" validate:"omitempty,objectid"`
+	// The nature of the matching transactions (debit or credit)
+	TransactionNature string `json:"transactionNature" validate:"omitempty,oneof=debit credit"`
+	// The smallest amount of the matching transactions
+	MinAmount string `json:"minAmount" validate:"omitempty,decimal,scale=2"`
+	// The largest amount of the matching transactions
+	MaxAmount string `json:"maxAmount" validate:"omitempty,decimal,scale=2"`
+	// A regular expression in RE2 syntax the memo of the matching transactions contains a match of. At most 256
+	// characters.
+	MemoPattern string `json:"memoPattern"`
+}
+
+// CategoryRuleResponseDTO represents a rule categorizing the transactions of the bank accounts of an account
+// @swagger:model CategoryRuleResponseDTO
+type CategoryRuleResponseDTO struct {
+	// The unique identifier of the rule
+	Id string `json:"id" validate:"required"`
+	// The category given to the matching transactions
+	Category string `json:"category" validate:"required"`
+	// The bank account ID of the other side of the matching transactions. Omitted when any bank account matches.
+	CounterpartyBankAccountId string `json:"counterpartyBankAccountId,omitempty"`
+	// The nature of the matching transactions. Omitted when both natures match.
+	TransactionNature model.TransactionNature `json:"transactionNature,omitempty"`
+	// The smallest amount of the matching transactions. Omitted when unbounded.
+	MinAmount string `json:"minAmount,omitempty"`
+	// The largest amount of the matching transactions. Omitted when unbounded.
+	MaxAmount string `json:"maxAmount,omitempty"`
+	// The regular expression matched against the memo of the transactions. Omitted when any memo matches.
+	MemoPattern string `json:"memoPattern,omitempty"`
+	// The timestamp of when the rule was added
+	CreatedAt time.Time `json:"createdAt" validate:"required"`
+}
+
+// ApplyCategoryRulesRequestDTO represents a request to categorize the transactions of a bank account again with the
+// current rules
+// @swagger:model ApplyCategoryRulesRequestDTO
+type ApplyCategoryRulesRequestDTO struct {
+	// The bank account ID whose transactions are categorized
+	BankAccountId string `json:"bankAccountId" validate:"required,objectid"`
+	// The nextCursor of the previous response, to resume from. Omitted to start from the first transaction.
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// ApplyCategoryRulesResponseDTO represents the outcome of categorizing the transactions of a bank account again
+// @swagger:model ApplyCategoryRulesResponseDTO
+type ApplyCategoryRulesResponseDTO struct {
+	// The number of transactions whose category changed
+	Changed int `json:"changed"`
+	// The cursor to resume from in the next request. Omitted once every transaction was categorized.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// TransactionCategoryRequestDTO represents a request to set the category a bank account gives a transaction
+// @swagger:model TransactionCategoryRequestDTO
+type TransactionCategoryRequestDTO struct {
+	// The bank account ID of the side of the transaction to categorize
+	BankAccountId string `json:"bankAccountId" validate:"required,objectid"`
+	// The category of the transaction. At most 64 characters, empty to let the category rules categorize it.
+	Category string `json:"category"`
+}
+
+// TransactionCategoryResponseDTO represents the category a bank account gives a transaction
+// @swagger:model TransactionCategoryResponseDTO
+type TransactionCategoryResponseDTO struct {
+	// The category of the transaction. Omitted when the transaction is uncategorized.
+	Category string `json:"category,omitempty"`
+	// Whether the category was given by a rule or by the account holder (rule or manual). Omitted when the
+	// transaction is uncategorized.
+	CategorySource model.CategorySource `json:"categorySource,omitempty"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+// PayeeRequestDTO represents a request to add a bank account of another account holder as a payee
+// @swagger:model PayeeRequestDTO
+type PayeeRequestDTO struct {
+	// The account number of the bank account to add
+	AccountNumber string `json:"accountNumber" validate:"required"`
+	// The nickname to give the payee. At most 64 characters.
+	Nickname string `json:"nickname"`
+}
+
+// PayeeNicknameRequestDTO represents a request to change the nickname of a payee
+// @swagger:model PayeeNicknameRequestDTO
+type PayeeNicknameRequestDTO struct {
+	// The new nickname of the payee. At most 64 characters, empty to remove the nickname.
+	Nickname string `json:"nickname"`
+}
@@ -1,12 +1,51 @@
 package dto
 
+import "time"
+
 // TransactionRequestDTO represents a request to add a new transaction from an account to another account.
 // @swagger:model TransactionRequestDTO
 type TransactionRequestDTO struct {
 	// The bank account ID of the account to which the amount is to be transferred
-	ToBankAccountId string `json:"toBankAccountId" validate:"required"`
+	ToBankAccountId string `json:"toBankAccountId" validate:"required,objectid,nefield=FromBankAccountId"`
 	// The bank account ID of the account from which the amount is to be transferred
-	FromBankAccountId string `json:"fromBankAccountId" validate:"required"`
-	// The amount to be transferred. Valid to two decimal places.
-	Amount string `json:"amount" validate:"required"`
+	FromBankAccountId string `json:"fromBankAccountId" validate:"required,objectid"`
+	// The amount to be transferred. Positive and valid to two decimal places.
+	Amount string `json:"amount" validate:"required,decimal,positive,scale=2"`
+	// Confirms a transfer to a bank account that is not a known payee, when such transfers require confirmation
+	ConfirmUnknownPayee bool `json:"confirmUnknownPayee"`
+	// A note shown to both bank accounts along with the transaction. At most 140 characters.
+	Memo string `json:"memo"`
+}
+
+// TransactionResponseDTO represents a newly created transaction, or the transaction created by an earlier request
+// with the same idempotency key
+// @swagger:model TransactionResponseDTO
+type TransactionResponseDTO struct {
+	// The unique identifier of the transaction
+	Id string `json:"id" validate:"required"`
+}
+
+// PendingTransactionRequestDTO represents a request to add a new pending transaction from an account to another
+// account that is held until it is applied, revoked, or expires.
+// @swagger:model PendingTransactionRequestDTO
+type PendingTransactionRequestDTO struct {
+	// The bank account ID of the account to which the amount is to be transferred
+	ToBankAccountId string `json:"toBankAccountId" validate:"required,objectid,nefield=FromBankAccountId"`
+	// The bank account ID of the account from which the amount is to be transferred
+	FromBankAccountId string `json:"fromBankAccountId" validate:"required,objectid"`
+	// The amount to be transferred. Positive and valid to two decimal places.
+	Amount string `json:"amount" validate:"required,decimal,positive,scale=2"`
+	// The moment the pending transaction expires and is revoked, in an RFC3339 compliant format
+	ExpirationDate time.Time `json:"expirationDate" validate:"required"`
+	// Confirms a transfer to a bank account that is not a known payee, when such transfers require confirmation
+	ConfirmUnknownPayee bool `json:"confirmUnknownPayee"`
+	// A note shown to both bank accounts along with the transaction. At most 140 characters.
+	Memo string `json:"memo"`
+}
+
+// PendingTransactionResponseDTO represents a newly created pending transaction
+// @swagger:model PendingTransactionResponseDTO
+type PendingTransactionResponseDTO struct {
+	// The unique identifier of the pending transaction
+	Id string `json:"id" validate:"required"`
 }
@@ -1,17 +1,18 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/gorilla/mux"
 	"io"
-	"log"
 	"net/http"
 	"webserver/internal/app/server/dto"
+	"webserver/internal/app/server/problem"
+	"webserver/internal/pkg/auth"
 	"webserver/internal/pkg/domain/model"
 	"webserver/internal/pkg/domain/services"
-	"webserver/internal/pkg/utils"
+	"webserver/internal/pkg/logging"
 )
 
 // AccountDetailsHandler creates a handler for fetching account details.
@@ -21,141 +22,295 @@ import (
 // @Accept json
 // @Produce json
 // @Param accountId path string true "BankAccount ID"
+// @Security BearerAuth
 // @Success 200 {object} dto.AccountDetailsResponseDTO "Successful retrieval of account details"
-// @Failure 500 {object} utils.ErrorMessage "Internal server error"
+// @Failure 400 {object} problem.Details "Invalid BankAccount ID"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 403 {object} problem.Details "BankAccount does not belong to the authenticated account"
+// @Failure 404 {object} problem.Details "BankAccount not found"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
 // @Router /accounts/details/{accountId} [get]
-func AccountDetailsHandler(s services.AccountService, ctx context.Context) http.HandlerFunc {
+func AccountDetailsHandler(s services.AccountService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		accountID := mux.Vars(r)["accountId"]
-		accountDetails, err := s.GetAccountDetailsFromBankAccountId(accountID, ctx)
+		if !authorizeBankAccount(w, r, s, accountID) {
+			return
+		}
+		accountDetails, err := s.GetAccountDetailsFromBankAccountId(accountID, r.Context())
 		if err != nil {
-			utils.HttpError(w, "Failed to get BankAccount Details", http.StatusInternalServerError)
+			problem.Error(w, r, err, "failed to get BankAccount details")
 			return
 		}
 		jsonAccountDetails := accountDetailsToDTO(accountDetails)
 		err = json.NewEncoder(w).Encode(jsonAccountDetails)
 		if err != nil {
-			utils.HttpError(w, "Error encountered during response payload construction",
-				http.StatusInternalServerError)
-			return
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode response", logging.Err(err))
 		}
 	}
 }
 
-// AccountTransactionsHandler creates a handler for fetching account transactions.
+// AccountTransactionsHandler creates a handler for listing account transactions.
 // @Summary Get account transactions
-// @Description Retrieves a list of transactions for a specific account by its ID.
+// @Description Lists the transactions of a bank account one page at a time, newest first by default. Pass the
+// @Description nextCursor of a page as the cursor of the next request, keeping the other parameters unchanged.
 // @Tags transactions
-// @Accept json
 // @Produce json
-// @Param accountId path string true "BankAccount ID"
-// @Success 200 {object} []dto.AccountTransactionResponseDTO "Successful retrieval of account transactions"
-// @Failure 500 {object} utils.ErrorMessage "Internal server error"
-// @Router /accounts/{accountId}/transactions [get]
-func AccountTransactionsHandler(s services.AccountService, ctx context.Context) http.HandlerFunc {
+// @Param bankAccountId query string true "BankAccount ID"
+// @Param fromTime query string false "Earliest creation time in an RFC3339 compliant format"
+// @Param toTime query string false "Latest creation time in an RFC3339 compliant format"
+// @Param order query string false "Order of creation" Enums(asc, desc)
+// @Param limit query int false "Number of transactions per page, at most 200" default(50)
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Param nature query string false "Nature of the transactions" Enums(credit, debit)
+// @Param type query string false "Type of the transactions" Enums(realized, pending)
+// @Param status query string false "Status of pending transactions" Enums(active, applied, revoked)
+// @Param minAmount query string false "Smallest amount"
+// @Param maxAmount query string false "Largest amount"
+// @Param counterparty query string false "BankAccount ID of the other side of the transactions"
+// @Security BearerAuth
+// @Success 200 {object} dto.AccountTransactionPageResponseDTO "Successful retrieval of account transactions"
+// @Failure 400 {object} problem.Details "Invalid query parameters"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 403 {object} problem.Details "BankAccount does not belong to the authenticated account"
+// @Failure 404 {object} problem.Details "BankAccount not found"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /accounts/transactions [get]
+func AccountTransactionsHandler(s services.AccountService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var req dto.AccountTransactionRequestDTO
-		err := json.NewDecoder(r.Body).Decode(&req)
+		input, err := transactionPageInputFromQuery(r.URL.Query())
 		if err != nil {
-			utils.HttpError(w, "Invalid request payload", http.StatusBadRequest)
+			problem.Write(w, r, http.StatusBadRequest, problem.InvalidQuery, err.Error())
 			return
 		}
-		accountTransactionsInput := accountTransactionRequestToInput(&req)
-		accountTransactions, err := s.GetBankAccountTransactions(&accountTransactionsInput, ctx)
+		if !authorizeBankAccount(w, r, s, input.BankAccountId) {
+			return
+		}
+		page, err := s.GetBankAccountTransactionsPage(&input, r.Context())
 		if err != nil {
-			utils.HttpError(w, "Failed to get BankAccount Transactions", http.StatusInternalServerError)
+			problem.Error(w, r, err, "failed to get BankAccount transactions")
 			return
 		}
-		jsonAccountTransactions := accountTransactionToDTO(accountTransactions)
 
-		err = json.NewEncoder(w).Encode(jsonAccountTransactions)
+		err = json.NewEncoder(w).Encode(transactionPageToDTO(page))
 		if err != nil {
-			utils.HttpError(w, "Error encountered during response payload construction",
-				http.StatusInternalServerError)
-			return
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode response", logging.Err(err))
 		}
 	}
 }
 
 // AccountLoginHandler creates a handler for logging in a user.
 // @Summary Login
-// @Description Logs in a user with the provided username and password.
+// @Description Logs in a user with the provided username and password, issuing an access and a refresh token.
 // @Tags accounts
 // @Accept json
 // @Produce json
 // @Param login body dto.AccountLoginRequestDTO true "Login payload"
-// @Success 200 {object} dto.AccountDetailsResponseDTO "Successful login"
-// @Failure 401 {object} utils.ErrorMessage "Invalid credentials"
-// @Failure 500 {object} utils.ErrorMessage "Internal server error"
+// @Success 200 {object} dto.AccountLoginResponseDTO "Successful login"
+// @Failure 400 {object} problem.Details "Invalid request payload or fields"
+// @Failure 401 {object} problem.Details "Invalid credentials"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
 // @Router /accounts/login [post]
-func AccountLoginHandler(s services.AccountService, ctx context.Context) http.HandlerFunc {
+func AccountLoginHandler(s services.AccountService, tm *auth.TokenManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req dto.AccountLoginRequestDTO
-		err := json.NewDecoder(r.Body).Decode(&req)
-		if err != nil {
-			utils.HttpError(w, "Invalid request payload", http.StatusBadRequest)
+		if !decodeRequest(w, r, &req) {
 			return
 		}
 
 		defer func(Body io.ReadCloser) {
 			err := Body.Close()
 			if err != nil {
-				log.Printf("Failed to close request body: %v", err)
+				logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to close request body",
+					logging.Err(err))
 			}
 		}(r.Body)
 
-		accountDetails, err := s.Login(req.Username, req.Password, ctx)
+		accountDetails, err := s.Login(req.Username, req.Password, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "error encountered during login")
+			return
+		}
+
+		tokenPair, err := tm.IssueTokenPair(accountDetails.Id)
+		if err != nil {
+			problem.Error(w, r, fmt.Errorf("error when issuing session tokens: %w", err),
+				"error encountered during login")
+			return
+		}
+
+		jsonLoginResponse := dto.AccountLoginResponseDTO{
+			AccountDetailsResponseDTO: accountDetailsToDTO(accountDetails),
+			TokenResponseDTO:          tokenPairToDTO(&tokenPair),
+		}
+		err = json.NewEncoder(w).Encode(jsonLoginResponse)
+		if err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode response", logging.Err(err))
+		}
+
+	}
+}
+
+// AccountRegisterHandler creates a handler for registering a new account.
+// @Summary Register
+// @Description Registers a new account without any bank accounts. Log in to obtain session tokens for it.
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param register body dto.AccountRegisterRequestDTO true "Registration payload"
+// @Success 201 {object} dto.AccountDetailsResponseDTO "Successful registration"
+// @Failure 400 {object} problem.Details "Invalid request payload or account details"
+// @Failure 409 {object} problem.Details "Username is already taken"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /accounts [post]
+func AccountRegisterHandler(s services.AccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.AccountRegisterRequestDTO
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+
+		input := accountRegisterRequestToInput(&req)
+		accountDetails, err := s.Register(&input, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "error encountered during registration")
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(accountDetailsToDTO(accountDetails))
+		if err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode response", logging.Err(err))
+		}
+	}
+}
+
+// BankAccountOpenHandler creates a handler for opening a bank account.
+// @Summary Open a bank account
+// @Description Opens an empty bank account of the given type for the authenticated account.
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param bankAccount body dto.BankAccountOpenRequestDTO true "Bank account payload"
+// @Security BearerAuth
+// @Success 201 {object} dto.BankAccountDTO "Successful opening of the bank account"
+// @Failure 400 {object} problem.Details "Invalid request payload or account type"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /accounts/bank-accounts [post]
+func BankAccountOpenHandler(s services.AccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.BankAccountOpenRequestDTO
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		accountId, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Write(w, r, http.StatusUnauthorized, problem.MissingToken, "missing access token")
+			return
+		}
+
+		bankAccount, err := s.OpenBankAccount(accountId, req.AccountType, r.Context())
 		if err != nil {
-			if errors.Is(err, model.ErrInvalidCredentials) {
-				utils.HttpError(w, "Invalid credentials", http.StatusUnauthorized)
+			if errors.Is(err, model.ErrNoMatchingAccount) {
+				problem.Write(w, r, http.StatusUnauthorized, problem.InvalidToken,
+					"authenticated account no longer exists")
 				return
 			}
-			utils.HttpError(w, "Error encountered during login", http.StatusInternalServerError)
+			problem.Error(w, r, err, "error encountered when opening BankAccount")
 			return
 		}
 
-		jsonAccountDetails := accountDetailsToDTO(accountDetails)
-		err = json.NewEncoder(w).Encode(jsonAccountDetails)
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(accountsToDTO([]model.BankAccount{*bankAccount})[0])
+		if err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode response", logging.Err(err))
+		}
+	}
+}
+
+// TokenRefreshHandler creates a handler for refreshing session tokens.
+// @Summary Refresh session tokens
+// @Description Exchanges a valid refresh token for a new access and refresh token.
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param refresh body dto.TokenRefreshRequestDTO true "Refresh payload"
+// @Success 200 {object} dto.TokenResponseDTO "Successful refresh"
+// @Failure 400 {object} problem.Details "Invalid request payload or fields"
+// @Failure 401 {object} problem.Details "Invalid or expired refresh token"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Router /accounts/token/refresh [post]
+func TokenRefreshHandler(tm *auth.TokenManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.TokenRefreshRequestDTO
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+
+		tokenPair, err := tm.Refresh(req.RefreshToken)
 		if err != nil {
-			utils.HttpError(w, "Error encountered during JSON Encoding of Response",
-				http.StatusInternalServerError)
+			if errors.Is(err, auth.ErrInvalidToken) {
+				problem.Write(w, r, http.StatusUnauthorized, problem.InvalidToken, "invalid or expired refresh token")
+				return
+			}
+			problem.Error(w, r, err, "error encountered during token refresh")
 			return
 		}
 
+		err = json.NewEncoder(w).Encode(tokenPairToDTO(&tokenPair))
+		if err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode response", logging.Err(err))
+		}
 	}
 }
 
-// AccountBalanceHistoryInMonthsHandler creates a handler for fetching account history.
+// AccountBalanceHistoryHandler creates a handler for fetching account history.
 // @Summary Get account history
-// @Description Retrieves the account month-balance history for a specific account by its ID.
+// @Description Retrieves the balance history of a specific account by its ID, split into day, week, month or quarter
+// @Description buckets starting at midnight in the requested time zone. Every bucket holds the opening, closing,
+// @Description lowest and highest available balances along with the totals of the transactions realized in it.
 // @Tags accounts
 // @Accept json
 // @Produce json
 // @Param input body dto.AccountBalanceHistoryRequestDTO true "Account history payload"
+// @Security BearerAuth
 // @Success 200 {object} dto.AccountBalanceHistoryResponseDTO "Successful retrieval of account history"
-// @Failure 500 {object} utils.ErrorMessage "Internal server error"
+// @Failure 400 {object} problem.Details "Invalid request payload or fields"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 403 {object} problem.Details "BankAccount does not belong to the authenticated account"
+// @Failure 404 {object} problem.Details "BankAccount not found"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
 // @Router /accounts/history [get]
-func AccountBalanceHistoryInMonthsHandler(s services.AccountService, ctx context.Context) http.HandlerFunc {
+func AccountBalanceHistoryHandler(s services.AccountService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req dto.AccountBalanceHistoryRequestDTO
-		err := json.NewDecoder(r.Body).Decode(&req)
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		if !authorizeBankAccount(w, r, s, req.BankAccountId) {
+			return
+		}
+		accountHistoryInput, err := accountHistoryRequestToInput(&req)
 		if err != nil {
-			utils.HttpError(w, "Invalid request payload", http.StatusBadRequest)
+			problem.Error(w, r, err, "failed to get BankAccount history")
 			return
 		}
-		accountHistoryInput := accountHistoryRequestToInput(&req)
-		accountHistory, err := s.GetAccountBalanceHistoryInMonths(&accountHistoryInput, ctx)
+		accountHistory, err := s.GetAccountBalanceHistory(&accountHistoryInput, r.Context())
 		if err != nil {
-			utils.HttpError(w, "Failed to get BankAccount History", http.StatusInternalServerError)
+			problem.Error(w, r, err, "failed to get BankAccount history")
 			return
 		}
-		jsonAccountHistory := accountHistoryToDTO(&accountHistory)
+		jsonAccountHistory := accountHistoryToDTO(accountHistory)
 
 		err = json.NewEncoder(w).Encode(jsonAccountHistory)
 		if err != nil {
-			utils.HttpError(w, "Error encountered during response payload construction",
-				http.StatusInternalServerError)
-			return
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode response", logging.Err(err))
 		}
 	}
 }
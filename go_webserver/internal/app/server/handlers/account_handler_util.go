@@ -1,8 +1,16 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"net/url"
+	"strconv"
+	"time"
 	"webserver/internal/app/server/dto"
+	"webserver/internal/pkg/auth"
 	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/utils"
 )
 
 func knownAccountToDTO(tx []model.KnownBankAccount) []dto.KnownBankAccountDTO {
@@ -14,6 +22,7 @@ func knownAccountToDTO(tx []model.KnownBankAccount) []dto.KnownBankAccountDTO {
 			AccountNumber: element.AccountNumber,
 			AccountHolder: element.AccountHolder,
 			AccountType:   accountType,
+			Nickname:      element.Nickname,
 		}
 	}
 	return knownAccountDTOList
@@ -33,6 +42,17 @@ func accountsToDTO(tx []model.BankAccount) []dto.BankAccountDTO {
 	return accountDTOList
 }
 
+func accountRegisterRequestToInput(tx *dto.AccountRegisterRequestDTO) model.RegisterAccountInput {
+	return model.RegisterAccountInput{
+		Username: tx.Username,
+		Password: tx.Password,
+		Person: model.Person{
+			FirstName: tx.Person.FirstName,
+			LastName:  tx.Person.LastName,
+		},
+	}
+}
+
 func accountDetailsToDTO(tx *model.AccountDetailsOutput) dto.AccountDetailsResponseDTO {
 	return dto.AccountDetailsResponseDTO{
 		Id:       tx.Id,
@@ -60,39 +80,121 @@ func accountTransactionToDTO(tx []model.BankAccountTransactionOutput) []dto.Acco
 			Status:             element.Status,
 			Amount:             element.Amount.String(),
 			CreatedAt:          element.CreatedAt,
+			Memo:               element.Memo,
+			Category:           element.Category.Name,
+			CategorySource:     element.Category.Source,
 		}
 	}
 	return accountTransactionDTOList
 }
 
-func accountTransactionRequestToInput(tx *dto.AccountTransactionRequestDTO) model.TransactionsForBankAccountInput {
-	return model.TransactionsForBankAccountInput{
-		BankAccountId: tx.BankAccountId,
-		FromTime:      tx.FromTime,
-		ToTime:        tx.ToTime,
+// transactionPageInputFromQuery reads the listing of a bank account's transactions from the query parameters
+func transactionPageInputFromQuery(query url.Values) (model.TransactionPageInput, error) {
+	input := model.TransactionPageInput{
+		BankAccountId: query.Get("bankAccountId"),
+		Order:         model.SortOrder(query.Get("order")),
+		Filter: model.TransactionFilter{
+			TransactionNature:         model.TransactionNature(query.Get("nature")),
+			TransactionType:           model.TransactionType(query.Get("type")),
+			Status:                    model.PendingTransactionStatus(query.Get("status")),
+			CounterpartyBankAccountId: query.Get("counterparty"),
+		},
+	}
+	if input.BankAccountId == "" {
+		return input, errors.New("bankAccountId is required")
+	}
+	var err error
+	for name, target := range map[string]*time.Time{"fromTime": &input.FromTime, "toTime": &input.ToTime} {
+		if raw := query.Get(name); raw != "" {
+			if *target, err = time.Parse(time.RFC3339, raw); err != nil {
+				return input, fmt.Errorf("%s must be an RFC3339 timestamp", name)
+			}
+		}
+	}
+	for name, target := range map[string]*decimal.NullDecimal{
+		"minAmount": &input.Filter.MinAmount,
+		"maxAmount": &input.Filter.MaxAmount,
+	} {
+		if raw := query.Get(name); raw != "" {
+			if target.Decimal, err = decimal.NewFromString(raw); err != nil {
+				return input, fmt.Errorf("%s must be a decimal amount", name)
+			}
+			target.Valid = true
+		}
 	}
+	if raw := query.Get("limit"); raw != "" {
+		if input.PageSize, err = strconv.Atoi(raw); err != nil || input.PageSize < 1 {
+			return input, errors.New("limit must be a positive integer")
+		}
+	}
+	if raw := query.Get("cursor"); raw != "" {
+		if input.After, err = utils.DecodeTransactionCursor(raw); err != nil {
+			return input, err
+		}
+	}
+	return input, nil
 }
 
-func accountHistoryRequestToInput(tx *dto.AccountBalanceHistoryRequestDTO) model.AccountHistoryInMonthsInput {
-	return model.AccountHistoryInMonthsInput{
+func transactionPageToDTO(page *model.TransactionPageOutput) dto.AccountTransactionPageResponseDTO {
+	res := dto.AccountTransactionPageResponseDTO{Transactions: accountTransactionToDTO(page.Transactions)}
+	if page.Next != nil {
+		res.NextCursor = utils.EncodeTransactionCursor(page.Next)
+	}
+	return res
+}
+
+// accountHistoryRequestToInput defaults to monthly buckets in UTC, which is what clients that pick neither a bucket size
+// nor a time zone have always been given
+func accountHistoryRequestToInput(tx *dto.AccountBalanceHistoryRequestDTO) (model.AccountBalanceHistoryInput, error) {
+	input := model.AccountBalanceHistoryInput{
 		BankAccountId: tx.BankAccountId,
 		FromTime:      tx.FromTime,
 		ToTime:        tx.ToTime,
+		BucketSize:    model.BalanceBucketSize(tx.BucketSize),
+		Location:      time.UTC,
+	}
+	if input.BucketSize == "" {
+		input.BucketSize = model.MonthlyBuckets
 	}
+	if tx.TimeZone != "" {
+		location, err := time.LoadLocation(tx.TimeZone)
+		if err != nil {
+			return input, fmt.Errorf("error when loading time zone %s: %w", tx.TimeZone, err)
+		}
+		input.Location = location
+	}
+	return input, nil
 }
 
-func accountHistoryToDTO(tx *model.AccountBalanceMonthsOutput) dto.AccountBalanceHistoryResponseDTO {
-	months := make([]dto.AccountBalanceMonthDTO, len(tx.Months))
-	for i, element := range tx.Months {
-		months[i] = dto.AccountBalanceMonthDTO{
-			Month:            int(element.Month),
-			Year:             element.Year,
-			AvailableBalance: element.AvailableBalance.String(),
-			PendingBalance:   element.PendingBalance.String(),
+func accountHistoryToDTO(tx *model.AccountBalanceHistoryOutput) dto.AccountBalanceHistoryResponseDTO {
+	buckets := make([]dto.AccountBalanceBucketDTO, len(tx.Buckets))
+	for i, element := range tx.Buckets {
+		buckets[i] = dto.AccountBalanceBucketDTO{
+			StartTime:               element.StartTime,
+			EndTime:                 element.EndTime,
+			OpeningAvailableBalance: element.OpeningAvailableBalance.String(),
+			ClosingAvailableBalance: element.ClosingAvailableBalance.String(),
+			MinAvailableBalance:     element.MinAvailableBalance.String(),
+			MaxAvailableBalance:     element.MaxAvailableBalance.String(),
+			ClosingPendingBalance:   element.ClosingPendingBalance.String(),
+			TotalDebits:             element.TotalDebits.String(),
+			TotalCredits:            element.TotalCredits.String(),
 		}
 	}
 	return dto.AccountBalanceHistoryResponseDTO{
 		BankAccountId: tx.BankAccountId,
-		Months:        months,
+		BucketSize:    tx.BucketSize,
+		TimeZone:      tx.Location.String(),
+		Buckets:       buckets,
+	}
+}
+
+func tokenPairToDTO(tp *auth.TokenPair) dto.TokenResponseDTO {
+	return dto.TokenResponseDTO{
+		TokenType:             "Bearer",
+		AccessToken:           tp.AccessToken,
+		AccessTokenExpiresAt:  tp.AccessTokenExpiresAt,
+		RefreshToken:          tp.RefreshToken,
+		RefreshTokenExpiresAt: tp.RefreshTokenExpiresAt,
 	}
 }
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"webserver/internal/app/server/problem"
+	"webserver/internal/pkg/auth"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/domain/services"
+	"webserver/internal/pkg/logging"
+)
+
+// authorizeBankAccount writes an error response and returns false unless the bank account belongs to the
+// authenticated account
+func authorizeBankAccount(
+	w http.ResponseWriter,
+	r *http.Request,
+	s services.AccountService,
+	bankAccountId string,
+) bool {
+	accountId, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		problem.Write(w, r, http.StatusUnauthorized, problem.MissingToken, "missing access token")
+		return false
+	}
+	owned, err := s.IsBankAccountOwner(accountId, bankAccountId, r.Context())
+	if err != nil {
+		problem.Error(w, r, err, "failed to verify BankAccount ownership")
+		return false
+	}
+	if !owned {
+		logging.FromContext(r.Context()).WarnContext(r.Context(), "Denied access to bank account",
+			logging.AccountId(accountId),
+			logging.BankAccountId(bankAccountId))
+		problem.Error(w, r, model.ErrBankAccountNotOwned, "")
+		return false
+	}
+	return true
+}
+
+// pendingTransactionSender lists the bank account a pending transaction takes its amount from, which alone may apply
+// it
+func pendingTransactionSender(transaction *model.TransactionDetailsOutput) []string {
+	return []string{transaction.FromBankAccountId}
+}
+
+// pendingTransactionParties lists both bank accounts of a pending transaction, either of which may revoke it since
+// revoking only hands the held amount back to the sender
+func pendingTransactionParties(transaction *model.TransactionDetailsOutput) []string {
+	return []string{transaction.FromBankAccountId, transaction.ToBankAccountId}
+}
+
+// authorizePendingTransaction writes an error response and returns false unless one of the bank accounts that
+// allowed lists for the pending transaction belongs to the authenticated account
+func authorizePendingTransaction(
+	w http.ResponseWriter,
+	r *http.Request,
+	as services.AccountService,
+	ts services.TransactionService,
+	transactionId string,
+	allowed func(*model.TransactionDetailsOutput) []string,
+) bool {
+	accountId, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		problem.Write(w, r, http.StatusUnauthorized, problem.MissingToken, "missing access token")
+		return false
+	}
+	transaction, err := ts.GetTransactionDetails(transactionId, r.Context())
+	if err != nil {
+		problem.Error(w, r, err, "failed to get pending transaction")
+		return false
+	}
+	if transaction.Type != model.Pending {
+		problem.Error(w, r, model.ErrNoMatchingTransaction, "")
+		return false
+	}
+	for _, bankAccountId := range allowed(transaction) {
+		owned, err := as.IsBankAccountOwner(accountId, bankAccountId, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to verify BankAccount ownership")
+			return false
+		}
+		if owned {
+			return true
+		}
+	}
+	logging.FromContext(r.Context()).WarnContext(r.Context(), "Denied access to pending transaction",
+		logging.AccountId(accountId),
+		logging.TransactionId(transactionId))
+	problem.Error(w, r, model.ErrPendingTransactionNotOwned, "")
+	return false
+}
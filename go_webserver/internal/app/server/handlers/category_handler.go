@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"webserver/internal/app/server/dto"
+	"webserver/internal/app/server/problem"
+	"webserver/internal/pkg/auth"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/domain/services"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
+)
+
+// CategoryRuleListHandler creates a handler for listing category rules.
+// @Summary List category rules
+// @Description Lists the rules categorizing the transactions of the authenticated account, in the order they are
+// @Description tried.
+// @Tags categories
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} []dto.CategoryRuleResponseDTO "Successful retrieval of category rules"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /accounts/category-rules [get]
+func CategoryRuleListHandler(s services.CategoryService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountId, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Write(w, r, http.StatusUnauthorized, problem.MissingToken, "missing access token")
+			return
+		}
+		rules, err := s.GetCategoryRules(accountId, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to get category rules")
+			return
+		}
+		err = json.NewEncoder(w).Encode(categoryRulesToDTO(rules))
+		if err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode category rules response",
+				logging.Err(err))
+		}
+	}
+}
+
+// CategoryRuleAddHandler creates a handler for adding a category rule.
+// @Summary Add a category rule
+// @Description Adds a rule categorizing the transactions of the authenticated account that match all of its
+// @Description conditions. Rules are tried in the order they were added, the first matching one categorizes the
+// @Description transaction. The rule applies to new transactions; existing ones are categorized again on request.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param rule body dto.CategoryRuleRequestDTO true "Category rule payload"
+// @Security BearerAuth
+// @Success 201 {object} dto.CategoryRuleResponseDTO "Successful addition of the category rule"
+// @Failure 400 {object} problem.Details "Invalid request payload"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 409 {object} problem.Details "Account has too many category rules"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /accounts/category-rules [post]
+func CategoryRuleAddHandler(s services.CategoryService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.CategoryRuleRequestDTO
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		accountId, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Write(w, r, http.StatusUnauthorized, problem.MissingToken, "missing access token")
+			return
+		}
+
+		input, err := categoryRuleToModel(&req)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, problem.InvalidPayload, "invalid amount given")
+			return
+		}
+		rule, err := s.AddCategoryRule(accountId, &input, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to add category rule")
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(categoryRulesToDTO([]model.CategoryRule{*rule})[0])
+		if err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode category rule response",
+				logging.Err(err))
+		}
+	}
+}
+
+// CategoryRuleRemoveHandler creates a handler for removing a category rule.
+// @Summary Remove a category rule
+// @Description Removes a category rule of the authenticated account. Transactions it categorized keep their
+// @Description category until the rules are applied again.
+// @Tags categories
+// @Param ruleId path string true "ID of the category rule"
+// @Security BearerAuth
+// @Success 204 "Successful removal of the category rule"
+// @Failure 400 {object} problem.Details "Invalid category rule ID"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 404 {object} problem.Details "Category rule not found"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /accounts/category-rules/{ruleId} [delete]
+func CategoryRuleRemoveHandler(s services.CategoryService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountId, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Write(w, r, http.StatusUnauthorized, problem.MissingToken, "missing access token")
+			return
+		}
+
+		err := s.RemoveCategoryRule(accountId, mux.Vars(r)["ruleId"], r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to remove category rule")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CategoryRulesApplyHandler creates a handler for categorizing the transactions of a bank account again.
+// @Summary Apply category rules
+// @Description Categorizes the transactions of a bank account of the authenticated account again with the current
+// @Description category rules, oldest first. Transactions categorized by hand are left alone, and transactions no rule
+// @Description matches lose their category. A request categorizes a bounded number of transactions and returns a
+// @Description nextCursor while some are left, which the next request passes as its cursor to resume from.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param request body dto.ApplyCategoryRulesRequestDTO true "Bank account payload"
+// @Security BearerAuth
+// @Success 200 {object} dto.ApplyCategoryRulesResponseDTO "Successful categorization of the transactions"
+// @Failure 400 {object} problem.Details "Invalid request payload or cursor"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 403 {object} problem.Details "BankAccount does not belong to the authenticated account"
+// @Failure 404 {object} problem.Details "BankAccount not found"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /accounts/category-rules/apply [post]
+func CategoryRulesApplyHandler(cs services.CategoryService, as services.AccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.ApplyCategoryRulesRequestDTO
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		if !authorizeBankAccount(w, r, as, req.BankAccountId) {
+			return
+		}
+
+		var after *model.TransactionCursor
+		if req.Cursor != "" {
+			var err error
+			if after, err = utils.DecodeTransactionCursor(req.Cursor); err != nil {
+				problem.Write(w, r, http.StatusBadRequest, problem.InvalidPayload, "invalid cursor given")
+				return
+			}
+		}
+
+		applied, err := cs.ApplyCategoryRules(req.BankAccountId, after, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to apply category rules")
+			return
+		}
+		res := dto.ApplyCategoryRulesResponseDTO{Changed: applied.Changed}
+		if applied.Next != nil {
+			res.NextCursor = utils.EncodeTransactionCursor(applied.Next)
+		}
+		err = json.NewEncoder(w).Encode(res)
+		if err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode apply category rules response",
+				logging.Err(err))
+		}
+	}
+}
+
+// TransactionCategorizeHandler creates a handler for categorizing a transaction by hand.
+// @Summary Categorize a transaction
+// @Description Sets the category a bank account of the authenticated account gives a transaction, which category
+// @Description rules no longer change. An empty category lets the category rules categorize the transaction again.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param transactionId path string true "ID of the transaction"
+// @Param category body dto.TransactionCategoryRequestDTO true "Category payload"
+// @Security BearerAuth
+// @Success 200 {object} dto.TransactionCategoryResponseDTO "Successful categorization of the transaction"
+// @Failure 400 {object} problem.Details "Invalid request payload or transaction ID"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 403 {object} problem.Details "BankAccount does not belong to the authenticated account"
+// @Failure 404 {object} problem.Details "Transaction not found, or BankAccount is not one of its sides"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /transactions/{transactionId}/category [put]
+func TransactionCategorizeHandler(cs services.CategoryService, as services.AccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.TransactionCategoryRequestDTO
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		if !authorizeBankAccount(w, r, as, req.BankAccountId) {
+			return
+		}
+
+		category, err := cs.CategorizeTransaction(mux.Vars(r)["transactionId"], req.BankAccountId, req.Category,
+			r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to categorize transaction")
+			return
+		}
+		err = json.NewEncoder(w).Encode(dto.TransactionCategoryResponseDTO{
+			Category:       category.Name,
+			CategorySource: category.Source,
+		})
+		if err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode transaction category response",
+				logging.Err(err))
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"webserver/internal/app/server/dto"
+	"webserver/internal/pkg/domain/model"
+)
+
+func categoryRuleToModel(req *dto.CategoryRuleRequestDTO) (model.CategoryRuleInput, error) {
+	input := model.CategoryRuleInput{
+		Category:                  req.Category,
+		CounterpartyBankAccountId: req.CounterpartyBankAccountId,
+		TransactionNature:         model.TransactionNature(req.TransactionNature),
+		MemoPattern:               req.MemoPattern,
+	}
+	var err error
+	if input.MinAmount, err = optionalAmount(req.MinAmount); err != nil {
+		return input, fmt.Errorf("minAmount must be a decimal amount: %w", err)
+	}
+	if input.MaxAmount, err = optionalAmount(req.MaxAmount); err != nil {
+		return input, fmt.Errorf("maxAmount must be a decimal amount: %w", err)
+	}
+	return input, nil
+}
+
+// optionalAmount parses an amount that is left unbounded when empty
+func optionalAmount(raw string) (decimal.NullDecimal, error) {
+	if raw == "" {
+		return decimal.NullDecimal{}, nil
+	}
+	amount, err := decimal.NewFromString(raw)
+	if err != nil {
+		return decimal.NullDecimal{}, err
+	}
+	return decimal.NewNullDecimal(amount), nil
+}
+
+func categoryRulesToDTO(rules []model.CategoryRule) []dto.CategoryRuleResponseDTO {
+	ruleDTOList := make([]dto.CategoryRuleResponseDTO, len(rules))
+	for i, rule := range rules {
+		ruleDTOList[i] = dto.CategoryRuleResponseDTO{
+			Id:                        rule.Id,
+			Category:                  rule.Category,
+			CounterpartyBankAccountId: rule.CounterpartyBankAccountId,
+			TransactionNature:         rule.TransactionNature,
+			MemoPattern:               rule.MemoPattern,
+			CreatedAt:                 rule.CreatedAt,
+		}
+		if rule.MinAmount.Valid {
+			ruleDTOList[i].MinAmount = rule.MinAmount.Decimal.String()
+		}
+		if rule.MaxAmount.Valid {
+			ruleDTOList[i].MaxAmount = rule.MaxAmount.Decimal.String()
+		}
+	}
+	return ruleDTOList
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"webserver/internal/app/server/dto"
+	"webserver/internal/app/server/problem"
+	"webserver/internal/pkg/auth"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/domain/services"
+	"webserver/internal/pkg/logging"
+)
+
+// PayeeListHandler creates a handler for listing payees.
+// @Summary List payees
+// @Description Lists the bank accounts known to the authenticated account.
+// @Tags payees
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} []dto.KnownBankAccountDTO "Successful retrieval of payees"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /accounts/payees [get]
+func PayeeListHandler(s services.AccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountId, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Write(w, r, http.StatusUnauthorized, problem.MissingToken, "missing access token")
+			return
+		}
+		payees, err := s.GetPayees(accountId, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to get payees")
+			return
+		}
+		err = json.NewEncoder(w).Encode(knownAccountToDTO(payees))
+		if err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode payees response",
+				logging.Err(err))
+		}
+	}
+}
+
+// PayeeAddHandler creates a handler for adding a payee.
+// @Summary Add a payee
+// @Description Adds the bank account with the given account number as a payee of the authenticated account.
+// @Tags payees
+// @Accept json
+// @Produce json
+// @Param payee body dto.PayeeRequestDTO true "Payee payload"
+// @Security BearerAuth
+// @Success 201 {object} dto.KnownBankAccountDTO "Successful addition of the payee"
+// @Failure 400 {object} problem.Details "Invalid request payload or account number, or BankAccount is the caller's own"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 404 {object} problem.Details "No BankAccount has the given account number"
+// @Failure 409 {object} problem.Details "BankAccount is already a payee"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /accounts/payees [post]
+func PayeeAddHandler(s services.AccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.PayeeRequestDTO
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		accountId, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Write(w, r, http.StatusUnauthorized, problem.MissingToken, "missing access token")
+			return
+		}
+
+		input := model.PayeeInput{AccountNumber: req.AccountNumber, Nickname: req.Nickname}
+		payee, err := s.AddPayee(accountId, &input, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to add payee")
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(knownAccountToDTO([]model.KnownBankAccount{*payee})[0])
+		if err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode payee response",
+				logging.Err(err))
+		}
+	}
+}
+
+// PayeeRenameHandler creates a handler for changing the nickname of a payee.
+// @Summary Rename a payee
+// @Description Changes the nickname of a payee of the authenticated account.
+// @Tags payees
+// @Accept json
+// @Param payeeId path string true "BankAccount ID of the payee"
+// @Param payee body dto.PayeeNicknameRequestDTO true "Nickname payload"
+// @Security BearerAuth
+// @Success 204 "Successful renaming of the payee"
+// @Failure 400 {object} problem.Details "Invalid request payload or payee ID"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 404 {object} problem.Details "Payee not found"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /accounts/payees/{payeeId} [patch]
+func PayeeRenameHandler(s services.AccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.PayeeNicknameRequestDTO
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		accountId, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Write(w, r, http.StatusUnauthorized, problem.MissingToken, "missing access token")
+			return
+		}
+
+		err := s.RenamePayee(accountId, mux.Vars(r)["payeeId"], req.Nickname, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to rename payee")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// PayeeRemoveHandler creates a handler for removing a payee.
+// @Summary Remove a payee
+// @Description Removes a payee of the authenticated account.
+// @Tags payees
+// @Param payeeId path string true "BankAccount ID of the payee"
+// @Security BearerAuth
+// @Success 204 "Successful removal of the payee"
+// @Failure 400 {object} problem.Details "Invalid payee ID"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 404 {object} problem.Details "Payee not found"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /accounts/payees/{payeeId} [delete]
+func PayeeRemoveHandler(s services.AccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountId, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Write(w, r, http.StatusUnauthorized, problem.MissingToken, "missing access token")
+			return
+		}
+
+		err := s.RemovePayee(accountId, mux.Vars(r)["payeeId"], r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to remove payee")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"webserver/internal/app/server/problem"
+	"webserver/internal/app/server/validation"
+)
+
+// decodeRequest decodes the JSON body of the request into req and validates it, writing a problem and returning false
+// when either fails
+func decodeRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.InvalidPayload, "invalid request payload")
+		return false
+	}
+	return validateRequest(w, r, req)
+}
+
+// validateRequest validates a request read from the body or the query, writing a problem and returning false when it
+// is invalid
+func validateRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := validation.Struct(req); err != nil {
+		var validationErr *validation.Error
+		if errors.As(err, &validationErr) {
+			problem.Invalid(w, r, validationErr)
+			return false
+		}
+		problem.Error(w, r, err, "failed to validate request")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"webserver/internal/app/server/dto"
+	"webserver/internal/app/server/problem"
+	"webserver/internal/pkg/domain/services"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/statement"
+)
+
+// StatementHandler creates a handler for exporting the statement of a bank account.
+// @Summary Export a bank account statement
+// @Description Exports the realized transactions of a bank account over a period of at most 12 months, along with its
+// @Description opening and closing available balance, as CSV, OFX 2.2 or ISO 20022 camt.053. The format is picked
+// @Description by the format parameter, or else by the Accept header, and defaults to CSV.
+// @Tags accounts
+// @Produce text/csv
+// @Produce application/x-ofx
+// @Produce application/xml
+// @Param bankAccountId query string true "BankAccount ID"
+// @Param fromTime query string true "Start of the period in an RFC3339 compliant format"
+// @Param toTime query string true "End of the period in an RFC3339 compliant format"
+// @Param format query string false "Format of the statement" Enums(csv, ofx, camt053)
+// @Security BearerAuth
+// @Success 200 {file} file "Statement of the bank account"
+// @Failure 400 {object} problem.Details "Invalid query parameters"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 403 {object} problem.Details "BankAccount does not belong to the authenticated account"
+// @Failure 404 {object} problem.Details "BankAccount not found"
+// @Failure 406 {object} problem.Details "None of the accepted media types is supported"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /accounts/statement [get]
+func StatementHandler(
+	ss services.StatementService,
+	as services.AccountService,
+	opts statement.Options,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := statementRequestFromQuery(r.URL.Query())
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, problem.InvalidQuery, err.Error())
+			return
+		}
+		if !validateRequest(w, r, &req) {
+			return
+		}
+		w.Header().Add("Vary", "Accept")
+		format, ok := statement.FormatByName(req.Format)
+		if req.Format == "" {
+			format, ok = statement.Negotiate(r.Header.Get("Accept"))
+		}
+		if !ok {
+			problem.Write(w, r, http.StatusNotAcceptable, problem.NotAcceptable,
+				"none of the accepted media types is supported, expected text/csv, application/x-ofx or "+
+					"application/xml")
+			return
+		}
+		if !authorizeBankAccount(w, r, as, req.BankAccountId) {
+			return
+		}
+		input := statementRequestToInput(&req)
+		s, err := ss.GetStatement(&input, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to get BankAccount statement")
+			return
+		}
+
+		// The statement is written in full before the response, so that failures can still be reported as problems
+		var body bytes.Buffer
+		if err := format.Write(&body, s, opts); err != nil {
+			problem.Error(w, r, err, "failed to write BankAccount statement")
+			return
+		}
+		w.Header().Set("Content-Type", format.ContentType)
+		w.Header().Set("Content-Disposition",
+			mime.FormatMediaType("attachment", map[string]string{"filename": statement.FileName(s, format)}))
+		if _, err := body.WriteTo(w); err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to write response", logging.Err(err))
+		}
+	}
+}
+
+// MonthlyStatementHandler creates a handler for downloading the printable statement of a bank account.
+// @Summary Download a monthly statement
+// @Description Downloads the statement of a bank account over a calendar month in UTC as a PDF, listing its realized
+// @Description transactions with the running available balance and the totals of the money that came in and went out.
+// @Tags accounts
+// @Produce application/pdf
+// @Param bankAccountId query string true "BankAccount ID"
+// @Param month query string true "Month of the statement, e.g. 2024-03"
+// @Security BearerAuth
+// @Success 200 {file} file "Statement of the bank account"
+// @Failure 400 {object} problem.Details "Invalid query parameters"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 403 {object} problem.Details "BankAccount does not belong to the authenticated account"
+// @Failure 404 {object} problem.Details "BankAccount not found"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /accounts/statement/monthly [get]
+func MonthlyStatementHandler(
+	ss services.StatementService,
+	as services.AccountService,
+	opts statement.Options,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		req := dto.MonthlyStatementRequestDTO{BankAccountId: query.Get("bankAccountId"), Month: query.Get("month")}
+		if !validateRequest(w, r, &req) {
+			return
+		}
+		if !authorizeBankAccount(w, r, as, req.BankAccountId) {
+			return
+		}
+		input := monthlyStatementRequestToInput(&req)
+		s, err := ss.GetMonthlyStatement(&input, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to get BankAccount monthly statement")
+			return
+		}
+
+		var body bytes.Buffer
+		if err := statement.WritePDF(&body, s, opts); err != nil {
+			problem.Error(w, r, err, "failed to write BankAccount monthly statement")
+			return
+		}
+		w.Header().Set("Content-Type", statement.PDFContentType)
+		w.Header().Set("Content-Disposition",
+			mime.FormatMediaType("attachment", map[string]string{"filename": statement.MonthlyFileName(s)}))
+		if _, err := body.WriteTo(w); err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to write response", logging.Err(err))
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+	"webserver/internal/app/server/dto"
+	"webserver/internal/pkg/domain/model"
+)
+
+// statementRequestFromQuery reads the request for a statement from the query parameters, leaving the checks of their
+// values to validation
+func statementRequestFromQuery(query url.Values) (dto.StatementRequestDTO, error) {
+	req := dto.StatementRequestDTO{
+		BankAccountId: query.Get("bankAccountId"),
+		Format:        query.Get("format"),
+	}
+	var err error
+	for name, target := range map[string]*time.Time{"fromTime": &req.FromTime, "toTime": &req.ToTime} {
+		if raw := query.Get(name); raw != "" {
+			if *target, err = time.Parse(time.RFC3339, raw); err != nil {
+				return req, fmt.Errorf("%s must be an RFC3339 timestamp", name)
+			}
+		}
+	}
+	return req, nil
+}
+
+// monthlyStatementRequestToInput reads the month of a request that validation has checked to be formatted like
+// 2006-01
+func monthlyStatementRequestToInput(req *dto.MonthlyStatementRequestDTO) model.MonthlyStatementInput {
+	month, _ := time.Parse("2006-01", req.Month)
+	return model.MonthlyStatementInput{
+		BankAccountId: req.BankAccountId,
+		Year:          month.Year(),
+		Month:         month.Month(),
+	}
+}
+
+func statementRequestToInput(req *dto.StatementRequestDTO) model.StatementInput {
+	return model.StatementInput{
+		BankAccountId: req.BankAccountId,
+		FromTime:      req.FromTime,
+		ToTime:        req.ToTime,
+	}
+}
@@ -1,52 +1,215 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
+	"github.com/gorilla/mux"
 	"io"
-	"log"
 	"net/http"
 	"webserver/internal/app/server/dto"
+	"webserver/internal/app/server/problem"
 	"webserver/internal/pkg/domain/services"
-	"webserver/internal/pkg/utils"
+	"webserver/internal/pkg/logging"
 )
 
 // TransactionInsertHandler creates a handler for adding a new transaction.
 // @Summary Add a new transaction
-// @Description Adds a new transaction to the system.
+// @Description Adds a new transaction to the system. Requests with an Idempotency-Key header take effect at most
+// @Description once, with retries returning the originally created transaction for 24 hours. Depending on the
+// @Description server's policy, transfers to BankAccounts that are not known payees are rejected or must be confirmed.
 // @Tags transactions
 // @Accept json
 // @Produce json
 // @Param transaction body dto.TransactionRequestDTO true "Transaction request"
-// @Success 202 {string} string "Accepted"
-// @Failure 400 {object} utils.ErrorMessage "Invalid request payload"
-// @Failure 500 {object} utils.ErrorMessage "Internal server error"
+// @Param Idempotency-Key header string false "Client-generated key identifying the request across retries"
+// @Security BearerAuth
+// @Success 202 {object} dto.TransactionResponseDTO "Accepted"
+// @Header 202 {string} Idempotent-Replayed "true if the transaction was created by an earlier request"
+// @Failure 400 {object} problem.Details "Invalid request payload or fields"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 403 {object} problem.Details "Source BankAccount is not owned, or destination is not a known payee"
+// @Failure 404 {object} problem.Details "BankAccount not found"
+// @Failure 409 {object} problem.Details "Idempotency key is in use, or the unknown payee must be confirmed"
+// @Failure 422 {object} problem.Details "Insufficient funds, or idempotency key was used for a different request"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
 // @Router /transactions [post]
-func TransactionInsertHandler(s services.TransactionService, ctx context.Context) http.HandlerFunc {
+func TransactionInsertHandler(
+	s services.TransactionService,
+	as services.AccountService,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req dto.TransactionRequestDTO
-		err := json.NewDecoder(r.Body).Decode(&req)
-		if err != nil {
-			utils.HttpError(w, "Invalid request payload", http.StatusBadRequest)
+		if !decodeRequest(w, r, &req) {
 			return
 		}
 
 		defer func(Body io.ReadCloser) {
 			err := Body.Close()
 			if err != nil {
-				log.Printf("Failed to close request body: %v", err)
+				logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to close request body",
+					logging.Err(err))
 			}
 		}(r.Body)
 
+		if !authorizeBankAccount(w, r, as, req.FromBankAccountId) {
+			return
+		}
+
 		transactionInput, err := transactionDetailsToModel(&req)
 		if err != nil {
-			utils.HttpError(w, "Invalid amount given", http.StatusBadRequest)
+			problem.Write(w, r, http.StatusBadRequest, problem.InvalidPayload, "invalid amount given")
+			return
+		}
+
+		idempotencyKey, err := idempotencyKeyFromRequest(r)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, problem.InvalidHeader, err.Error())
+			return
+		}
+
+		transaction, err := s.AddTransaction(transactionInput, idempotencyKey, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to add transaction")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if transaction.Replayed {
+			w.Header().Set(idempotentReplayedHeader, "true")
+		}
+		w.WriteHeader(http.StatusAccepted)
+		err = json.NewEncoder(w).Encode(dto.TransactionResponseDTO{Id: transaction.Id})
+		if err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode transaction response",
+				logging.Err(err))
+		}
+	}
+}
+
+// PendingTransactionInsertHandler creates a handler for adding a new pending transaction.
+// @Summary Add a new pending transaction
+// @Description Holds the amount in the pending balances of both accounts until it is applied, revoked or expires.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param transaction body dto.PendingTransactionRequestDTO true "Pending transaction request"
+// @Security BearerAuth
+// @Success 201 {object} dto.PendingTransactionResponseDTO "Created"
+// @Failure 400 {object} problem.Details "Invalid request payload, fields or expiration date"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 403 {object} problem.Details "Source BankAccount is not owned, or destination is not a known payee"
+// @Failure 404 {object} problem.Details "BankAccount not found"
+// @Failure 409 {object} problem.Details "The unknown payee must be confirmed"
+// @Failure 422 {object} problem.Details "Insufficient funds"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /transactions/pending [post]
+func PendingTransactionInsertHandler(
+	s services.TransactionService,
+	as services.AccountService,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dto.PendingTransactionRequestDTO
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+
+		defer func(Body io.ReadCloser) {
+			err := Body.Close()
+			if err != nil {
+				logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to close request body",
+					logging.Err(err))
+			}
+		}(r.Body)
+
+		if !authorizeBankAccount(w, r, as, req.FromBankAccountId) {
+			return
+		}
+
+		transactionInput, err := pendingTransactionDetailsToModel(&req)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, problem.InvalidPayload, "invalid amount given")
+			return
+		}
+
+		transactionId, err := s.AddPendingTransaction(transactionInput, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to add pending transaction")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(dto.PendingTransactionResponseDTO{Id: transactionId})
+		if err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode pending transaction response",
+				logging.Err(err))
+		}
+	}
+}
+
+// PendingTransactionApplyHandler creates a handler for applying a pending transaction.
+// @Summary Apply a pending transaction
+// @Description Releases the held amount and transfers it as a realized transaction. Only the holder of the bank
+// @Description account the amount is taken from may apply it.
+// @Tags transactions
+// @Produce json
+// @Param transactionId path string true "Pending transaction ID"
+// @Security BearerAuth
+// @Success 202 {string} string "Accepted"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 403 {object} problem.Details "Pending transaction is not taken from the authenticated account"
+// @Failure 404 {object} problem.Details "Pending transaction not found"
+// @Failure 409 {object} problem.Details "Pending transaction is no longer active"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /transactions/pending/{transactionId}/apply [post]
+func PendingTransactionApplyHandler(
+	s services.TransactionService,
+	as services.AccountService,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		transactionId := mux.Vars(r)["transactionId"]
+		if !authorizePendingTransaction(w, r, as, s, transactionId, pendingTransactionSender) {
+			return
+		}
+		err := s.ApplyPendingTransaction(transactionId, r.Context())
+		if err != nil {
+			problem.Error(w, r, err, "failed to apply pending transaction")
 			return
 		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
 
-		err = s.AddTransaction(transactionInput, ctx)
+// PendingTransactionRevokeHandler creates a handler for revoking a pending transaction.
+// @Summary Revoke a pending transaction
+// @Description Releases the held amount back to the pending balances of both accounts without transferring it.
+// @Description The holders of either bank account may revoke it.
+// @Tags transactions
+// @Produce json
+// @Param transactionId path string true "Pending transaction ID"
+// @Security BearerAuth
+// @Success 202 {string} string "Accepted"
+// @Failure 401 {object} problem.Details "Missing or invalid access token"
+// @Failure 403 {object} problem.Details "Pending transaction does not belong to the authenticated account"
+// @Failure 404 {object} problem.Details "Pending transaction not found"
+// @Failure 409 {object} problem.Details "Pending transaction is no longer active"
+// @Failure 500 {object} problem.Details "Internal server error"
+// @Failure 503 {object} problem.Details "Storage is unavailable"
+// @Router /transactions/pending/{transactionId}/revoke [post]
+func PendingTransactionRevokeHandler(
+	s services.TransactionService,
+	as services.AccountService,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		transactionId := mux.Vars(r)["transactionId"]
+		if !authorizePendingTransaction(w, r, as, s, transactionId, pendingTransactionParties) {
+			return
+		}
+		err := s.RevokePendingTransaction(transactionId, r.Context())
 		if err != nil {
-			utils.HttpError(w, "Failed to Add Transaction", http.StatusInternalServerError)
+			problem.Error(w, r, err, "failed to revoke pending transaction")
 			return
 		}
 		w.WriteHeader(http.StatusAccepted)
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webserver/internal/app/server/problem"
+	"webserver/internal/pkg/auth"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/domain/services"
+)
+
+// ownerAccountService answers ownership checks from a map of bank account IDs to the IDs of their account holders
+type ownerAccountService struct {
+	services.AccountService
+	owners map[string]string
+}
+
+func (s *ownerAccountService) IsBankAccountOwner(
+	accountId string,
+	bankAccountId string,
+	_ context.Context,
+) (bool, error) {
+	return s.owners[bankAccountId] == accountId, nil
+}
+
+// pendingTransactionService holds a single pending transaction and records which actions were carried out on it
+type pendingTransactionService struct {
+	services.TransactionService
+	transaction model.TransactionDetailsOutput
+	applied     bool
+	revoked     bool
+}
+
+func (s *pendingTransactionService) GetTransactionDetails(
+	transactionId string,
+	_ context.Context,
+) (*model.TransactionDetailsOutput, error) {
+	if transactionId != s.transaction.Id {
+		return nil, model.ErrNoMatchingTransaction
+	}
+	transaction := s.transaction
+	return &transaction, nil
+}
+
+func (s *pendingTransactionService) ApplyPendingTransaction(string, context.Context) error {
+	s.applied = true
+	return nil
+}
+
+func (s *pendingTransactionService) RevokePendingTransaction(string, context.Context) error {
+	s.revoked = true
+	return nil
+}
+
+func TestPendingTransactionApplyHandler(t *testing.T) {
+	t.Run("Applies the pending transaction for the sender", func(t *testing.T) {
+		as, ts := initializePendingTransactionServices()
+
+		w := servePendingTransaction(PendingTransactionApplyHandler(ts, as), "sender")
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.True(t, ts.applied)
+	})
+
+	t.Run("Refuses to apply the pending transaction for the recipient", func(t *testing.T) {
+		as, ts := initializePendingTransactionServices()
+
+		w := servePendingTransaction(PendingTransactionApplyHandler(ts, as), "recipient")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, model.ErrPendingTransactionNotOwned.Code, problemCode(t, w))
+		assert.False(t, ts.applied)
+	})
+
+	t.Run("Refuses to apply the pending transaction for other accounts", func(t *testing.T) {
+		as, ts := initializePendingTransactionServices()
+
+		w := servePendingTransaction(PendingTransactionApplyHandler(ts, as), "stranger")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.False(t, ts.applied)
+	})
+}
+
+func TestPendingTransactionRevokeHandler(t *testing.T) {
+	for _, accountId := range []string{"sender", "recipient"} {
+		t.Run("Revokes the pending transaction for the "+accountId, func(t *testing.T) {
+			as, ts := initializePendingTransactionServices()
+
+			w := servePendingTransaction(PendingTransactionRevokeHandler(ts, as), accountId)
+			assert.Equal(t, http.StatusAccepted, w.Code)
+			assert.True(t, ts.revoked)
+		})
+	}
+
+	t.Run("Refuses to revoke the pending transaction for other accounts", func(t *testing.T) {
+		as, ts := initializePendingTransactionServices()
+
+		w := servePendingTransaction(PendingTransactionRevokeHandler(ts, as), "stranger")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.False(t, ts.revoked)
+	})
+}
+
+func initializePendingTransactionServices() (*ownerAccountService, *pendingTransactionService) {
+	as := &ownerAccountService{owners: map[string]string{
+		"fromAccountID": "sender",
+		"toAccountID":   "recipient",
+	}}
+	ts := &pendingTransactionService{transaction: model.TransactionDetailsOutput{
+		Id:                "transactionId",
+		FromBankAccountId: "fromAccountID",
+		ToBankAccountId:   "toAccountID",
+		Type:              model.Pending,
+	}}
+	return as, ts
+}
+
+// servePendingTransaction serves a request of the account on the pending transaction with the handler
+func servePendingTransaction(handler http.HandlerFunc, accountId string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/transactions/pending/transactionId", nil)
+	r = r.WithContext(auth.ContextWithPrincipal(r.Context(), accountId))
+	r = mux.SetURLVars(r, map[string]string{"transactionId": "transactionId"})
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func problemCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var details problem.Details
+	if err := json.NewDecoder(w.Body).Decode(&details); err != nil {
+		t.Fatalf("Failed to decode problem details: %v", err)
+	}
+	return details.Code
+}
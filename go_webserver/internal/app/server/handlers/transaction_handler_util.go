@@ -1,23 +1,69 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"github.com/shopspring/decimal"
-	"log"
+	"net/http"
 	"webserver/internal/app/server/dto"
+	"webserver/internal/pkg/auth"
 	"webserver/internal/pkg/domain/model"
 )
 
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// idempotencyKeyFromRequest returns the idempotency key of the request scoped to the authenticated account, or nil
+// when the client did not send one
+func idempotencyKeyFromRequest(r *http.Request) (*model.IdempotencyKeyInput, error) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" {
+		return nil, nil
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, fmt.Errorf("%s header must be at most %d characters", idempotencyKeyHeader,
+			maxIdempotencyKeyLength)
+	}
+	accountId, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, errors.New("idempotency keys require an authenticated account")
+	}
+	return &model.IdempotencyKeyInput{Key: key, AccountId: accountId}, nil
+}
+
 func transactionDetailsToModel(tx *dto.TransactionRequestDTO) (model.TransactionDetailsInput, error) {
 	decimalAmount, err := decimal.NewFromString(tx.Amount)
 	if err != nil {
-		log.Printf("Failed to convert amount %s to decimal: %v", tx.Amount, err)
 		return model.TransactionDetailsInput{}, err
 	}
 
 	return model.TransactionDetailsInput{
-		FromBankAccountId: tx.FromBankAccountId,
-		ToBankAccountId:   tx.ToBankAccountId,
-		Amount:            decimalAmount,
-		Type:              model.Realized,
+		FromBankAccountId:   tx.FromBankAccountId,
+		ToBankAccountId:     tx.ToBankAccountId,
+		Amount:              decimalAmount,
+		Type:                model.Realized,
+		ConfirmUnknownPayee: tx.ConfirmUnknownPayee,
+		Memo:                tx.Memo,
+	}, nil
+}
+
+func pendingTransactionDetailsToModel(tx *dto.PendingTransactionRequestDTO) (model.TransactionDetailsInput, error) {
+	decimalAmount, err := decimal.NewFromString(tx.Amount)
+	if err != nil {
+		return model.TransactionDetailsInput{}, err
+	}
+
+	return model.TransactionDetailsInput{
+		FromBankAccountId:   tx.FromBankAccountId,
+		ToBankAccountId:     tx.ToBankAccountId,
+		Amount:              decimalAmount,
+		Type:                model.Pending,
+		ExpirationDate:      tx.ExpirationDate,
+		Status:              model.Active,
+		ConfirmUnknownPayee: tx.ConfirmUnknownPayee,
+		Memo:                tx.Memo,
 	}, nil
 }
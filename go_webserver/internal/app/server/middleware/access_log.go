@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"time"
+	"webserver/internal/pkg/logging"
+)
+
+type routeNameKey struct{}
+
+// AccessLog gives every request the logger in its context and logs it once it has been served, with the route it
+// matched, the status of the response and how long it took. Requests that fail on the server are logged as errors.
+func AccessLog(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			routeName := new(string)
+			ctx := context.WithValue(logging.ContextWithLogger(r.Context(), logger), routeNameKey{}, routeName)
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			level := slog.LevelInfo
+			if recorder.status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(ctx, level, "Served request",
+				slog.String(logging.MethodKey, r.Method),
+				slog.String(logging.PathKey, r.URL.Path),
+				slog.String(logging.RouteKey, *routeName),
+				slog.Int(logging.StatusKey, recorder.status),
+				slog.Duration(logging.LatencyKey, time.Since(start)),
+			)
+		})
+	}
+}
+
+// NameRoute records the name of the route the request matched for AccessLog, which runs before routing
+func NameRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if routeName, ok := r.Context().Value(routeNameKey{}).(*string); ok {
+			if route := mux.CurrentRoute(r); route != nil {
+				*routeName = route.GetName()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
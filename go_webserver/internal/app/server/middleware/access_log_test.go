@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webserver/internal/pkg/logging"
+)
+
+func TestAccessLog(t *testing.T) {
+	serve := func(t *testing.T, handler http.HandlerFunc) map[string]any {
+		var buf bytes.Buffer
+		logger, err := logging.New(&buf, logging.Options{Level: slog.LevelInfo, Format: logging.JSONFormat})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := mux.NewRouter()
+		r.Use(NameRoute)
+		r.HandleFunc("/accounts/{accountId}", handler).Name("accountDetails")
+
+		req := httptest.NewRequest(http.MethodGet, "/accounts/accountId1", nil)
+		req.Header.Set(RequestIdHeader, "requestId1")
+		RequestId(AccessLog(logger)(r)).ServeHTTP(httptest.NewRecorder(), req)
+
+		var record map[string]any
+		if err = json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		return record
+	}
+
+	t.Run("Logs the route, status and latency of every request", func(t *testing.T) {
+		record := serve(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+		assert.Equal(t, "INFO", record["level"])
+		assert.Equal(t, "requestId1", record[logging.RequestIdKey])
+		assert.Equal(t, "accountDetails", record[logging.RouteKey])
+		assert.Equal(t, "/accounts/accountId1", record[logging.PathKey])
+		assert.Equal(t, float64(http.StatusNotFound), record[logging.StatusKey])
+		assert.Contains(t, record, logging.LatencyKey)
+	})
+
+	t.Run("Logs server errors as errors", func(t *testing.T) {
+		record := serve(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		assert.Equal(t, "ERROR", record["level"])
+	})
+
+	t.Run("Gives handlers the logger through the request context", func(t *testing.T) {
+		record := serve(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.NotSame(t, slog.Default(), logging.FromContext(r.Context()))
+		})
+		assert.Equal(t, float64(http.StatusOK), record[logging.StatusKey])
+	})
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"webserver/internal/app/server/problem"
+	"webserver/internal/pkg/auth"
+	"webserver/internal/pkg/logging"
+)
+
+const bearerPrefix = "Bearer "
+
+// Authenticate rejects requests without a valid access token in the Authorization header and otherwise passes
+// them on with the ID of the authenticated account stored in the request context
+func Authenticate(tm *auth.TokenManager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if !strings.HasPrefix(header, bearerPrefix) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				problem.Write(w, r, http.StatusUnauthorized, problem.MissingToken, "missing access token")
+				return
+			}
+			accountId, err := tm.Verify(strings.TrimPrefix(header, bearerPrefix), auth.AccessToken)
+			if err != nil {
+				logging.FromContext(r.Context()).InfoContext(r.Context(), "Rejected access token",
+					slog.String(logging.PathKey, r.URL.Path), logging.Err(err))
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				problem.Write(w, r, http.StatusUnauthorized, problem.InvalidToken, "invalid or expired access token")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.ContextWithPrincipal(r.Context(), accountId)))
+		})
+	}
+}
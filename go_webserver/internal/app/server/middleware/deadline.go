@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RouteBudgets are the time limits for serving requests, keyed by the name of the route they match
+type RouteBudgets struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+func (b RouteBudgets) For(routeName string) time.Duration {
+	if budget, ok := b.Routes[routeName]; ok {
+		return budget
+	}
+	return b.Default
+}
+
+// ParseRouteBudgets parses budgets given as comma separated route=duration pairs, e.g. "login=5s,transactionInsert=2s".
+// Every route must be one of routeNames, so that a misspelt route is not silently given the default budget. Routes not
+// named in the spec are given the default budget.
+func ParseRouteBudgets(spec string, defaultBudget time.Duration, routeNames []string) (RouteBudgets, error) {
+	budgets := RouteBudgets{Default: defaultBudget, Routes: map[string]time.Duration{}}
+	if strings.TrimSpace(spec) == "" {
+		return budgets, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		routeName, rawBudget, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || routeName == "" {
+			return RouteBudgets{}, fmt.Errorf("invalid route budget %q, expected route=duration", pair)
+		}
+		if !slices.Contains(routeNames, routeName) {
+			return RouteBudgets{}, fmt.Errorf("unknown route %s", routeName)
+		}
+		budget, err := time.ParseDuration(rawBudget)
+		if err != nil {
+			return RouteBudgets{}, fmt.Errorf("invalid duration for route %s: %w", routeName, err)
+		}
+		if budget <= 0 {
+			return RouteBudgets{}, fmt.Errorf("budget for route %s must be positive", routeName)
+		}
+		budgets.Routes[routeName] = budget
+	}
+	return budgets, nil
+}
+
+// Deadline bounds the context of each request by the budget of the route it matched. The context is also cancelled
+// when the client disconnects, which abandons any database work done on its behalf.
+func Deadline(budgets RouteBudgets) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var routeName string
+			if route := mux.CurrentRoute(r); route != nil {
+				routeName = route.GetName()
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), budgets.For(routeName))
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRouteBudgets(t *testing.T) {
+	routeNames := []string{"login", "transactionInsert", "accountDetails"}
+
+	t.Run("Overrides the default budget for the named routes", func(t *testing.T) {
+		budgets, err := ParseRouteBudgets("login=5s, transactionInsert=250ms", 3*time.Second, routeNames)
+		assert.Nil(t, err)
+		assert.Equal(t, 5*time.Second, budgets.For("login"))
+		assert.Equal(t, 250*time.Millisecond, budgets.For("transactionInsert"))
+		assert.Equal(t, 3*time.Second, budgets.For("accountDetails"))
+	})
+
+	t.Run("Gives every route the default budget without a spec", func(t *testing.T) {
+		budgets, err := ParseRouteBudgets("", 3*time.Second, routeNames)
+		assert.Nil(t, err)
+		assert.Equal(t, 3*time.Second, budgets.For("login"))
+	})
+
+	t.Run("Rejects malformed budgets", func(t *testing.T) {
+		for _, spec := range []string{"login", "=5s", "login=soon", "login=-1s"} {
+			_, err := ParseRouteBudgets(spec, 3*time.Second, routeNames)
+			assert.Error(t, err, spec)
+		}
+	})
+
+	t.Run("Rejects routes that are not in the router", func(t *testing.T) {
+		_, err := ParseRouteBudgets("login=5s,logn=2s", 3*time.Second, routeNames)
+		assert.ErrorContains(t, err, "unknown route logn")
+	})
+}
+
+func TestDeadline(t *testing.T) {
+	t.Run("Bounds the request context by the budget of the matched route", func(t *testing.T) {
+		budgets := RouteBudgets{Default: time.Hour, Routes: map[string]time.Duration{"login": time.Minute}}
+		var remaining time.Duration
+		r := mux.NewRouter()
+		r.Use(Deadline(budgets))
+		r.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+			deadline, ok := r.Context().Deadline()
+			assert.True(t, ok)
+			remaining = time.Until(deadline)
+		}).Name("login")
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/login", nil))
+		assert.LessOrEqual(t, remaining, time.Minute)
+		assert.Greater(t, remaining, 50*time.Second)
+	})
+}
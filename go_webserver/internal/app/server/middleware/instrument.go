@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+	"webserver/internal/pkg/metrics"
+)
+
+// Instrument counts the requests served on each route and observes how long they took. It runs after routing, so
+// requests that match no route are left out rather than filling the route label with arbitrary paths.
+func Instrument(m *metrics.Metrics) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			var routeName string
+			if route := mux.CurrentRoute(r); route != nil {
+				routeName = route.GetName()
+			}
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+			m.ObserveRequest(routeName, r.Method, recorder.status, time.Since(start))
+		})
+	}
+}
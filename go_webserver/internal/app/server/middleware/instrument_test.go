@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webserver/internal/pkg/metrics"
+)
+
+func TestInstrument(t *testing.T) {
+	m := metrics.New()
+	r := mux.NewRouter()
+	r.Use(Instrument(m))
+	r.HandleFunc("/accounts/{accountId}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Name("accountDetails")
+	r.Handle("/metrics", m.Handler()).Name("metrics")
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/accounts/accountId1", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := recorder.Body.String()
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, body, `wallet_http_requests_total{method="GET",route="accountDetails",status="404"} 1`)
+	assert.Contains(t, body, `wallet_http_request_duration_seconds_count{method="GET",route="accountDetails"} 1`)
+	assert.NotContains(t, body, "/unknown")
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/requestcontext"
+)
+
+const RequestIdHeader = "X-Request-ID"
+
+var validRequestId = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestId stores the ID of the request in its context and echoes it in the response, reusing the ID sent by the
+// client when it is well-formed so that requests can be traced across services
+func RequestId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get(RequestIdHeader)
+		if !validRequestId.MatchString(requestId) {
+			requestId = newRequestId()
+		}
+		w.Header().Set(RequestIdHeader, requestId)
+		next.ServeHTTP(w, r.WithContext(requestcontext.WithRequestId(r.Context(), requestId)))
+	})
+}
+
+func newRequestId() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		slog.Error("Failed to generate request ID", logging.Err(err))
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
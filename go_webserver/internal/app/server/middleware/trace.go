@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"webserver/internal/pkg/requestcontext"
+)
+
+const requestIdAttribute = attribute.Key("http.request.id")
+
+// TraceRoute names the span of the request, started by otelhttp before routing, after the route it matched and tags
+// it with the path template of the route and the ID of the request
+func TraceRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+		if route := mux.CurrentRoute(r); route != nil {
+			if name := route.GetName(); name != "" {
+				span.SetName(name)
+			}
+			if template, err := route.GetPathTemplate(); err == nil {
+				span.SetAttributes(semconv.HTTPRoute(template))
+			}
+		}
+		if requestId := requestcontext.RequestIdFromContext(r.Context()); requestId != "" {
+			span.SetAttributes(requestIdAttribute.String(requestId))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
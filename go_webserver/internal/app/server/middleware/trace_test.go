@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTraceRoute(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	r := mux.NewRouter()
+	r.Use(TraceRoute)
+	r.HandleFunc("/accounts/{accountId}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Name("accountDetails")
+	handler := otelhttp.NewHandler(RequestId(r), "http.server", otelhttp.WithTracerProvider(tp))
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts/accountId1", nil)
+	req.Header.Set(RequestIdHeader, "requestId1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	spans := exporter.GetSpans()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+	assert.Equal(t, "accountDetails", spans[0].Name)
+	assert.Contains(t, spans[0].Attributes, semconv.HTTPRoute("/accounts/{accountId}"))
+	assert.Contains(t, spans[0].Attributes, requestIdAttribute.String("requestId1"))
+	assert.Equal(t, "http.server", spans[1].Name)
+}
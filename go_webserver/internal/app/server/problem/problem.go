@@ -0,0 +1,131 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"webserver/internal/app/server/validation"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/logging"
+)
+
+const (
+	ContentType = "application/problem+json"
+	typePrefix  = "urn:wallet:problem:"
+)
+
+// Codes of the problems found with a request before it reaches the domain, which has codes for its own errors
+const (
+	InvalidPayload = "invalid_payload"
+	InvalidFields  = "invalid_fields"
+	InvalidQuery   = "invalid_query"
+	InvalidHeader  = "invalid_header"
+	NotAcceptable  = "not_acceptable"
+	MissingToken   = "missing_token"
+	InvalidToken   = "invalid_token"
+	Timeout        = "timeout"
+	InternalError  = "internal_error"
+)
+
+// Details is the RFC 7807 body of error responses. Clients tell problems apart by their code, which never changes,
+// rather than by their detail, which is meant for humans
+type Details struct {
+	Type     string `json:"type" example:"urn:wallet:problem:insufficient_funds"`
+	Title    string `json:"title" example:"Unprocessable Entity"`
+	Status   int    `json:"status" example:"422"`
+	Detail   string `json:"detail,omitempty" example:"insufficient balance in BankAccount 65f1c0e4b4a1f0a9c8d7e6f5"`
+	Instance string `json:"instance,omitempty" example:"/transactions"`
+	Code     string `json:"code" example:"insufficient_funds"`
+	// The fields of the request that failed validation, only given with the invalid_fields code
+	Errors []validation.FieldError `json:"errors,omitempty"`
+}
+
+var statuses = []struct {
+	kind   error
+	status int
+}{
+	{model.ErrValidation, http.StatusBadRequest},
+	{model.ErrUnauthenticated, http.StatusUnauthorized},
+	{model.ErrForbidden, http.StatusForbidden},
+	{model.ErrNotFound, http.StatusNotFound},
+	{model.ErrConflict, http.StatusConflict},
+	{model.ErrInsufficientFunds, http.StatusUnprocessableEntity},
+	{model.ErrUnprocessable, http.StatusUnprocessableEntity},
+	{model.ErrUnavailable, http.StatusServiceUnavailable},
+}
+
+// Write writes a problem of the given status and code in response to the request
+func Write(w http.ResponseWriter, r *http.Request, status int, code string, detail string) {
+	write(w, r, newDetails(r, status, code, detail))
+}
+
+// Invalid writes a 400 listing the fields of the request that failed validation
+func Invalid(w http.ResponseWriter, r *http.Request, err *validation.Error) {
+	details := newDetails(r, http.StatusBadRequest, InvalidFields, "request has invalid fields")
+	details.Errors = err.Fields
+	write(w, r, details)
+}
+
+func newDetails(r *http.Request, status int, code string, detail string) Details {
+	return Details{
+		Type:     typePrefix + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+}
+
+func write(w http.ResponseWriter, r *http.Request, details Details) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(details.Status)
+	err := json.NewEncoder(w).Encode(details)
+	if err != nil {
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "Failed to encode problem", logging.Err(err))
+	}
+}
+
+// Error writes the problem matching the kind of the domain error in err. Errors outside the domain are logged and
+// answered with a 500 and the fallback detail, so that their messages never reach clients
+func Error(w http.ResponseWriter, r *http.Request, err error, fallbackDetail string) {
+	var domainErr *model.DomainError
+	switch {
+	case errors.As(err, &domainErr):
+		status := StatusOf(domainErr)
+		if status >= http.StatusInternalServerError {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), fallbackDetail, logging.Err(err))
+			Write(w, r, status, domainErr.Code, domainErr.Message)
+			return
+		}
+		Write(w, r, status, domainErr.Code, detailOf(err, domainErr))
+	case errors.Is(err, context.DeadlineExceeded):
+		logging.FromContext(r.Context()).WarnContext(r.Context(), fallbackDetail, logging.Err(err))
+		Write(w, r, http.StatusServiceUnavailable, Timeout, "request did not complete in time")
+	default:
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), fallbackDetail, logging.Err(err))
+		Write(w, r, http.StatusInternalServerError, InternalError, fallbackDetail)
+	}
+}
+
+// StatusOf returns the HTTP status of the kind of the domain error
+func StatusOf(err *model.DomainError) int {
+	for _, s := range statuses {
+		if errors.Is(err, s.kind) {
+			return s.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// detailOf drops what the layers above the domain error prefixed it with, keeping what it was wrapped with, like
+// the bank account lacking funds
+func detailOf(err error, domainErr *model.DomainError) string {
+	message := err.Error()
+	if i := strings.Index(message, domainErr.Message); i >= 0 {
+		return message[i:]
+	}
+	return domainErr.Message
+}
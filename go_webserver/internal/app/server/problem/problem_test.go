@@ -0,0 +1,111 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"webserver/internal/app/server/validation"
+	"webserver/internal/pkg/domain/model"
+)
+
+func respond(t *testing.T, err error) (*httptest.ResponseRecorder, Details) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	Error(w, httptest.NewRequest(http.MethodPost, "/transactions", nil), err, "failed to add transaction")
+	var details Details
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&details))
+	return w, details
+}
+
+func TestError(t *testing.T) {
+	t.Run("Maps the kind of domain errors to their status and keeps their code", func(t *testing.T) {
+		for _, tc := range []struct {
+			err    error
+			status int
+		}{
+			{model.ErrInvalidId, http.StatusBadRequest},
+			{model.ErrInvalidCredentials, http.StatusUnauthorized},
+			{model.ErrUnknownPayee, http.StatusForbidden},
+			{model.ErrNoMatchingBankAccount, http.StatusNotFound},
+			{model.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity},
+			{model.ErrInsufficientBalance, http.StatusUnprocessableEntity},
+			{model.ErrStorageUnavailable, http.StatusServiceUnavailable},
+		} {
+			w, details := respond(t, fmt.Errorf("error when adding transaction: %w", tc.err))
+			domainErr := tc.err.(*model.DomainError)
+			assert.Equal(t, tc.status, w.Code, domainErr.Code)
+			assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, Details{
+				Type:     "urn:wallet:problem:" + domainErr.Code,
+				Title:    http.StatusText(tc.status),
+				Status:   tc.status,
+				Detail:   domainErr.Message,
+				Instance: "/transactions",
+				Code:     domainErr.Code,
+			}, details)
+		}
+	})
+
+	t.Run("Details what the domain error was wrapped with, but not the layers above it", func(t *testing.T) {
+		err := fmt.Errorf("error when deducting balance: %w",
+			fmt.Errorf("%w in BankAccount %s", model.ErrInsufficientBalance, "bankAccountId1"))
+		_, details := respond(t, err)
+		assert.Equal(t, "insufficient balance in BankAccount bankAccountId1", details.Detail)
+	})
+
+	t.Run("Hides the cause of unavailable storage", func(t *testing.T) {
+		_, details := respond(t, fmt.Errorf("%w: %w", model.ErrStorageUnavailable, errors.New("dial tcp: refused")))
+		assert.Equal(t, "storage_unavailable", details.Code)
+		assert.Equal(t, "storage is unavailable", details.Detail)
+	})
+
+	t.Run("Answers requests that ran out of time with a 503", func(t *testing.T) {
+		w, details := respond(t, fmt.Errorf("error when getting account: %w", context.DeadlineExceeded))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, Timeout, details.Code)
+	})
+
+	t.Run("Answers errors outside the domain with a 500 and the fallback detail", func(t *testing.T) {
+		w, details := respond(t, errors.New("connection reset by peer"))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, InternalError, details.Code)
+		assert.Equal(t, "failed to add transaction", details.Detail)
+	})
+}
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	Write(w, httptest.NewRequest(http.MethodGet, "/accounts/payees", nil), http.StatusUnauthorized, MissingToken,
+		"missing access token")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{
+		"type": "urn:wallet:problem:missing_token",
+		"title": "Unauthorized",
+		"status": 401,
+		"detail": "missing access token",
+		"instance": "/accounts/payees",
+		"code": "missing_token"
+	}`, w.Body.String())
+}
+
+func TestInvalid(t *testing.T) {
+	w := httptest.NewRecorder()
+	Invalid(w, httptest.NewRequest(http.MethodPost, "/transactions", nil), &validation.Error{
+		Fields: []validation.FieldError{{Field: "amount", Message: "must be positive"}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{
+		"type": "urn:wallet:problem:invalid_fields",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "request has invalid fields",
+		"instance": "/transactions",
+		"code": "invalid_fields",
+		"errors": [{"field": "amount", "message": "must be positive"}]
+	}`, w.Body.String())
+}
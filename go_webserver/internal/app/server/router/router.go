@@ -1,23 +1,142 @@
 package router
 
 import (
-	"context"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
 	"net/http"
 	"webserver/internal/app/server/handlers"
+	"webserver/internal/app/server/middleware"
+	"webserver/internal/pkg/auth"
 	"webserver/internal/pkg/domain/services"
+	"webserver/internal/pkg/metrics"
+	"webserver/internal/pkg/statement"
 )
 
+// Route names, which are also the keys of their budgets in middleware.RouteBudgets
+const (
+	RegisterRoute                 = "register"
+	LoginRoute                    = "login"
+	TokenRefreshRoute             = "tokenRefresh"
+	AccountDetailsRoute           = "accountDetails"
+	AccountTransactionsRoute      = "accountTransactions"
+	AccountHistoryRoute           = "accountHistory"
+	StatementRoute                = "accountStatement"
+	MonthlyStatementRoute         = "accountMonthlyStatement"
+	TransactionInsertRoute        = "transactionInsert"
+	PendingTransactionInsertRoute = "pendingTransactionInsert"
+	PendingTransactionApplyRoute  = "pendingTransactionApply"
+	PendingTransactionRevokeRoute = "pendingTransactionRevoke"
+	BankAccountOpenRoute          = "bankAccountOpen"
+	PayeeListRoute                = "payeeList"
+	PayeeAddRoute                 = "payeeAdd"
+	PayeeRenameRoute              = "payeeRename"
+	PayeeRemoveRoute              = "payeeRemove"
+	CategoryRuleListRoute         = "categoryRuleList"
+	CategoryRuleAddRoute          = "categoryRuleAdd"
+	CategoryRuleRemoveRoute       = "categoryRuleRemove"
+	CategoryRulesApplyRoute       = "categoryRulesApply"
+	TransactionCategorizeRoute    = "transactionCategorize"
+)
+
+// RouteNames lists the names of every route of the router, which are the only routes a budget can be given to
+var RouteNames = []string{
+	RegisterRoute, LoginRoute, TokenRefreshRoute, AccountDetailsRoute, AccountTransactionsRoute, AccountHistoryRoute,
+	StatementRoute, MonthlyStatementRoute, TransactionInsertRoute, PendingTransactionInsertRoute,
+	PendingTransactionApplyRoute, PendingTransactionRevokeRoute, BankAccountOpenRoute, PayeeListRoute, PayeeAddRoute,
+	PayeeRenameRoute, PayeeRemoveRoute, CategoryRuleListRoute, CategoryRuleAddRoute, CategoryRuleRemoveRoute,
+	CategoryRulesApplyRoute, TransactionCategorizeRoute,
+}
+
 func CreateRouter(
 	accountService services.AccountService,
 	transactionService services.TransactionService,
-	ctx context.Context,
+	statementService services.StatementService,
+	categoryService services.CategoryService,
+	statementOptions statement.Options,
+	tokenManager *auth.TokenManager,
+	budgets middleware.RouteBudgets,
+	metrics *metrics.Metrics,
+	tracerProvider trace.TracerProvider,
+	logger *slog.Logger,
 ) http.Handler {
 	r := mux.NewRouter()
-	r.HandleFunc("/accounts/details/{accountId}", handlers.AccountDetailsHandler(accountService, ctx)).Methods("GET")
-	r.Handle("/transactions", handlers.TransactionInsertHandler(transactionService, ctx)).Methods("POST")
-	r.Handle("/accounts/transactions", handlers.AccountTransactionsHandler(accountService, ctx)).Methods("GET")
-	r.Handle("/accounts/login", handlers.AccountLoginHandler(accountService, ctx)).Methods("POST")
-	r.Handle("/accounts/history", handlers.AccountBalanceHistoryInMonthsHandler(accountService, ctx)).Methods("GET")
+	r.Use(middleware.NameRoute, middleware.TraceRoute, middleware.Instrument(metrics), middleware.Deadline(budgets))
+	r.Handle("/accounts", handlers.AccountRegisterHandler(accountService)).
+		Methods("POST").Name(RegisterRoute)
+	r.Handle("/accounts/login", handlers.AccountLoginHandler(accountService, tokenManager)).
+		Methods("POST").Name(LoginRoute)
+	r.Handle("/accounts/token/refresh", handlers.TokenRefreshHandler(tokenManager)).
+		Methods("POST").Name(TokenRefreshRoute)
+
+	protected := r.NewRoute().Subrouter()
+	protected.Use(middleware.Authenticate(tokenManager))
+	protected.Handle("/accounts/bank-accounts", handlers.BankAccountOpenHandler(accountService)).
+		Methods("POST").Name(BankAccountOpenRoute)
+	protected.Handle("/accounts/payees", handlers.PayeeListHandler(accountService)).
+		Methods("GET").Name(PayeeListRoute)
+	protected.Handle("/accounts/payees", handlers.PayeeAddHandler(accountService)).
+		Methods("POST").Name(PayeeAddRoute)
+	protected.Handle("/accounts/payees/{payeeId}", handlers.PayeeRenameHandler(accountService)).
+		Methods("PATCH").Name(PayeeRenameRoute)
+	protected.Handle("/accounts/payees/{payeeId}", handlers.PayeeRemoveHandler(accountService)).
+		Methods("DELETE").Name(PayeeRemoveRoute)
+	protected.Handle("/accounts/category-rules", handlers.CategoryRuleListHandler(categoryService)).
+		Methods("GET").Name(CategoryRuleListRoute)
+	protected.Handle("/accounts/category-rules", handlers.CategoryRuleAddHandler(categoryService)).
+		Methods("POST").Name(CategoryRuleAddRoute)
+	protected.Handle(
+		"/accounts/category-rules/apply",
+		handlers.CategoryRulesApplyHandler(categoryService, accountService),
+	).Methods("POST").Name(CategoryRulesApplyRoute)
+	protected.Handle("/accounts/category-rules/{ruleId}", handlers.CategoryRuleRemoveHandler(categoryService)).
+		Methods("DELETE").Name(CategoryRuleRemoveRoute)
+	protected.Handle("/accounts/details/{accountId}", handlers.AccountDetailsHandler(accountService)).
+		Methods("GET").Name(AccountDetailsRoute)
+	protected.Handle("/transactions", handlers.TransactionInsertHandler(transactionService, accountService)).
+		Methods("POST").Name(TransactionInsertRoute)
+	protected.Handle(
+		"/transactions/pending",
+		handlers.PendingTransactionInsertHandler(transactionService, accountService),
+	).Methods("POST").Name(PendingTransactionInsertRoute)
+	protected.Handle(
+		"/transactions/pending/{transactionId}/apply",
+		handlers.PendingTransactionApplyHandler(transactionService, accountService),
+	).Methods("POST").Name(PendingTransactionApplyRoute)
+	protected.Handle(
+		"/transactions/pending/{transactionId}/revoke",
+		handlers.PendingTransactionRevokeHandler(transactionService, accountService),
+	).Methods("POST").Name(PendingTransactionRevokeRoute)
+	protected.Handle(
+		"/transactions/{transactionId}/category",
+		handlers.TransactionCategorizeHandler(categoryService, accountService),
+	).Methods("PUT").Name(TransactionCategorizeRoute)
+	protected.Handle("/accounts/transactions", handlers.AccountTransactionsHandler(accountService)).
+		Methods("GET").Name(AccountTransactionsRoute)
+	protected.Handle("/accounts/history", handlers.AccountBalanceHistoryHandler(accountService)).
+		Methods("GET").Name(AccountHistoryRoute)
+	protected.Handle(
+		"/accounts/statement",
+		handlers.StatementHandler(statementService, accountService, statementOptions),
+	).Methods("GET").Name(StatementRoute)
+	protected.Handle(
+		"/accounts/statement/monthly",
+		handlers.MonthlyStatementHandler(statementService, accountService, statementOptions),
+	).Methods("GET").Name(MonthlyStatementRoute)
+	// The span of the request is started before the request ID and the access log, so that both can be correlated
+	// with the trace
+	return otelhttp.NewHandler(middleware.RequestId(middleware.AccessLog(logger)(r)), "http.server",
+		otelhttp.WithTracerProvider(tracerProvider),
+		otelhttp.WithPropagators(propagation.TraceContext{}),
+	)
+}
+
+// CreateMetricsRouter serves the metrics at /metrics. It is served on a listener of its own, apart from the API, as
+// the metrics are not authenticated and should only be reachable from inside the deployment.
+func CreateMetricsRouter(metrics *metrics.Metrics) http.Handler {
+	r := mux.NewRouter()
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
 	return r
 }
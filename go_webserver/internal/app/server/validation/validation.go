@@ -0,0 +1,135 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FieldError tells why the value of a field of a request was rejected
+type FieldError struct {
+	// The path of the field in the request body, e.g. person.firstName
+	Field string `json:"field" example:"amount"`
+	// Why the value of the field was rejected
+	Message string `json:"message" example:"must have at most 2 decimal places"`
+}
+
+// Error lists every field of a request that failed validation
+type Error struct {
+	Fields []FieldError
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+	return strings.Join(messages, ", ")
+}
+
+var validate = newValidate()
+
+func newValidate() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	must(v.RegisterValidation("objectid", func(fl validator.FieldLevel) bool {
+		return primitive.IsValidObjectID(fl.Field().String())
+	}))
+	must(v.RegisterValidation("decimal", func(fl validator.FieldLevel) bool {
+		_, err := decimal.NewFromString(fl.Field().String())
+		return err == nil
+	}))
+	must(v.RegisterValidation("positive", func(fl validator.FieldLevel) bool {
+		amount, err := decimal.NewFromString(fl.Field().String())
+		return err == nil && amount.IsPositive()
+	}))
+	must(v.RegisterValidation("scale", func(fl validator.FieldLevel) bool {
+		amount, err := decimal.NewFromString(fl.Field().String())
+		places, _ := strconv.Atoi(fl.Param())
+		return err == nil && amount.Equal(amount.Round(int32(places)))
+	}))
+	// maxmonths bounds the time range ending at the field to the given number of months after the FromTime field
+	must(v.RegisterValidation("maxmonths", func(fl validator.FieldLevel) bool {
+		toTime, ok := fl.Field().Interface().(time.Time)
+		fromField := fl.Parent().FieldByName("FromTime")
+		if !ok || !fromField.IsValid() {
+			return false
+		}
+		fromTime, ok := fromField.Interface().(time.Time)
+		months, _ := strconv.Atoi(fl.Param())
+		return ok && (fromTime.IsZero() || !toTime.After(fromTime.AddDate(0, months, 0)))
+	}))
+	return v
+}
+
+func must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Struct checks the request against the validate tags of its fields, failing with an *Error listing every field
+// that broke them
+func Struct(req any) error {
+	err := validate.Struct(req)
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+	fields := make([]FieldError, len(fieldErrs))
+	for i, fieldErr := range fieldErrs {
+		// The namespace starts with the name of the request type, which clients never see
+		_, path, _ := strings.Cut(fieldErr.Namespace(), ".")
+		fields[i] = FieldError{Field: path, Message: message(fieldErr)}
+	}
+	return &Error{Fields: fields}
+}
+
+func message(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "is required"
+	case "objectid":
+		return "must be a 24 character hexadecimal ID"
+	case "decimal":
+		return "must be a decimal number"
+	case "positive":
+		return "must be positive"
+	case "scale":
+		return fmt.Sprintf("must have at most %s decimal places", err.Param())
+	case "nefield":
+		return "must differ from " + jsonName(err.Param())
+	case "gtefield":
+		return "must not be before " + jsonName(err.Param())
+	case "maxmonths":
+		return fmt.Sprintf("must be at most %s months after fromTime", err.Param())
+	case "datetime":
+		return "must be formatted like " + err.Param()
+	case "timezone":
+		return "must be an IANA time zone such as Europe/Paris"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(err.Param(), " ", ", ")
+	default:
+		return "is invalid"
+	}
+}
+
+// jsonName returns the name of the field in the request body, which the fields of the DTOs follow
+func jsonName(fieldName string) string {
+	runes := []rune(fieldName)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,152 @@
+package validation
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"webserver/internal/app/server/dto"
+)
+
+const (
+	tomBankAccountId = "65f1c0e4b4a1f0a9c8d7e6f5"
+	samBankAccountId = "65f1c0e4b4a1f0a9c8d7e6f6"
+)
+
+func fieldErrors(t *testing.T, req any) []FieldError {
+	t.Helper()
+	err := Struct(req)
+	if err == nil {
+		return nil
+	}
+	validationErr, ok := err.(*Error)
+	if !assert.True(t, ok, err.Error()) {
+		return nil
+	}
+	return validationErr.Fields
+}
+
+func TestStruct(t *testing.T) {
+	t.Run("Accepts valid transfers", func(t *testing.T) {
+		for _, amount := range []string{"0.01", "100", "231.95", "1.500"} {
+			assert.Nil(t, fieldErrors(t, &dto.TransactionRequestDTO{
+				FromBankAccountId: tomBankAccountId,
+				ToBankAccountId:   samBankAccountId,
+				Amount:            amount,
+			}), amount)
+		}
+	})
+
+	t.Run("Rejects amounts that are not positive or have more than two decimal places", func(t *testing.T) {
+		for amount, message := range map[string]string{
+			"":        "is required",
+			"ten":     "must be a decimal number",
+			"0":       "must be positive",
+			"-5.00":   "must be positive",
+			"10.005":  "must have at most 2 decimal places",
+			"0.00001": "must have at most 2 decimal places",
+		} {
+			assert.Equal(t, []FieldError{{Field: "amount", Message: message}}, fieldErrors(t,
+				&dto.PendingTransactionRequestDTO{
+					FromBankAccountId: tomBankAccountId,
+					ToBankAccountId:   samBankAccountId,
+					Amount:            amount,
+					ExpirationDate:    time.Now().Add(time.Hour),
+				}), amount)
+		}
+	})
+
+	t.Run("Lists every invalid field by its name in the request body", func(t *testing.T) {
+		assert.Equal(t, []FieldError{
+			{Field: "toBankAccountId", Message: "must differ from fromBankAccountId"},
+			{Field: "amount", Message: "is required"},
+		}, fieldErrors(t, &dto.TransactionRequestDTO{
+			FromBankAccountId: tomBankAccountId,
+			ToBankAccountId:   tomBankAccountId,
+		}))
+		assert.Equal(t, []FieldError{
+			{Field: "toBankAccountId", Message: "must be a 24 character hexadecimal ID"},
+			{Field: "fromBankAccountId", Message: "must be a 24 character hexadecimal ID"},
+		}, fieldErrors(t, &dto.TransactionRequestDTO{
+			FromBankAccountId: "tom",
+			ToBankAccountId:   "sam",
+			Amount:            "10",
+		}))
+		assert.Equal(t, []FieldError{
+			{Field: "person.firstName", Message: "is required"},
+		}, fieldErrors(t, &dto.AccountRegisterRequestDTO{
+			Username: "tom",
+			Password: "password",
+			Person:   dto.PersonDTO{LastName: "Smith"},
+		}))
+	})
+
+	t.Run("Bounds balance history ranges", func(t *testing.T) {
+		fromTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		history := func(toTime time.Time) *dto.AccountBalanceHistoryRequestDTO {
+			return &dto.AccountBalanceHistoryRequestDTO{
+				BankAccountId: tomBankAccountId,
+				FromTime:      fromTime,
+				ToTime:        toTime,
+			}
+		}
+		assert.Nil(t, fieldErrors(t, history(fromTime)))
+		assert.Nil(t, fieldErrors(t, history(fromTime.AddDate(0, 60, 0))))
+		assert.Equal(t, []FieldError{{Field: "toTime", Message: "must not be before fromTime"}},
+			fieldErrors(t, history(fromTime.Add(-time.Second))))
+		assert.Equal(t, []FieldError{{Field: "toTime", Message: "must be at most 60 months after fromTime"}},
+			fieldErrors(t, history(fromTime.AddDate(0, 60, 1))))
+	})
+
+	t.Run("Requires known bucket sizes and time zones in balance histories", func(t *testing.T) {
+		history := func(bucketSize string, timeZone string) *dto.AccountBalanceHistoryRequestDTO {
+			return &dto.AccountBalanceHistoryRequestDTO{
+				BankAccountId: tomBankAccountId,
+				FromTime:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+				ToTime:        time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+				BucketSize:    bucketSize,
+				TimeZone:      timeZone,
+			}
+		}
+		assert.Nil(t, fieldErrors(t, history("", "")))
+		assert.Nil(t, fieldErrors(t, history("quarter", "America/New_York")))
+		assert.Equal(t, []FieldError{
+			{Field: "bucketSize", Message: "must be one of day, week, month, quarter"},
+			{Field: "timeZone", Message: "must be an IANA time zone such as Europe/Paris"},
+		}, fieldErrors(t, history("year", "Mars/Olympus_Mons")))
+	})
+
+	t.Run("Bounds statement periods and formats", func(t *testing.T) {
+		fromTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		statement := func(toTime time.Time, format string) *dto.StatementRequestDTO {
+			return &dto.StatementRequestDTO{
+				BankAccountId: tomBankAccountId,
+				FromTime:      fromTime,
+				ToTime:        toTime,
+				Format:        format,
+			}
+		}
+		assert.Nil(t, fieldErrors(t, statement(fromTime.AddDate(0, 12, 0), "")))
+		assert.Nil(t, fieldErrors(t, statement(fromTime, "camt053")))
+		assert.Equal(t, []FieldError{
+			{Field: "toTime", Message: "must be at most 12 months after fromTime"},
+			{Field: "format", Message: "must be one of csv, ofx, camt053"},
+		}, fieldErrors(t, statement(fromTime.AddDate(0, 12, 1), "pdf")))
+	})
+
+	t.Run("Requires monthly statements to name a month", func(t *testing.T) {
+		monthly := func(month string) *dto.MonthlyStatementRequestDTO {
+			return &dto.MonthlyStatementRequestDTO{BankAccountId: tomBankAccountId, Month: month}
+		}
+		assert.Nil(t, fieldErrors(t, monthly("2024-02")))
+		assert.Equal(t, []FieldError{{Field: "month", Message: "must be formatted like 2006-01"}},
+			fieldErrors(t, monthly("2024-2-01")))
+	})
+}
+
+func TestError(t *testing.T) {
+	err := &Error{Fields: []FieldError{
+		{Field: "amount", Message: "must be positive"},
+		{Field: "toBankAccountId", Message: "is required"},
+	}}
+	assert.EqualError(t, err, "amount must be positive, toBankAccountId is required")
+}
@@ -0,0 +1,16 @@
+package auth
+
+import "context"
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of the context carrying the ID of the authenticated account
+func ContextWithPrincipal(ctx context.Context, accountId string) context.Context {
+	return context.WithValue(ctx, principalKey{}, accountId)
+}
+
+// PrincipalFromContext returns the ID of the authenticated account, if any
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	accountId, ok := ctx.Value(principalKey{}).(string)
+	return accountId, ok && accountId != ""
+}
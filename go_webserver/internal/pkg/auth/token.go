@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+type TokenType string
+
+const (
+	AccessToken  TokenType = "access"
+	RefreshToken TokenType = "refresh"
+)
+
+const tokenIssuer = "wallet"
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+type TokenPair struct {
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+type sessionClaims struct {
+	Type TokenType `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// TokenManager issues and verifies HMAC-SHA256 signed session tokens whose subject is the ID of the account
+// that logged in. Access tokens authenticate requests, while the longer-lived refresh tokens can only be
+// exchanged for a new token pair.
+type TokenManager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+func NewTokenManager(secret []byte, accessTTL time.Duration, refreshTTL time.Duration) *TokenManager {
+	return &TokenManager{secret: secret, accessTTL: accessTTL, refreshTTL: refreshTTL, now: time.Now}
+}
+
+func (tm *TokenManager) IssueTokenPair(accountId string) (TokenPair, error) {
+	accessToken, accessExpiresAt, err := tm.issue(accountId, AccessToken, tm.accessTTL)
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("error when issuing access token for account %s: %w", accountId, err)
+	}
+	refreshToken, refreshExpiresAt, err := tm.issue(accountId, RefreshToken, tm.refreshTTL)
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("error when issuing refresh token for account %s: %w", accountId, err)
+	}
+	return TokenPair{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// Refresh exchanges a valid refresh token for a new token pair for the same account
+func (tm *TokenManager) Refresh(refreshToken string) (TokenPair, error) {
+	accountId, err := tm.Verify(refreshToken, RefreshToken)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return tm.IssueTokenPair(accountId)
+}
+
+// Verify checks the signature, expiry and type of the token and returns the account ID it was issued to
+func (tm *TokenManager) Verify(token string, tokenType TokenType) (string, error) {
+	claims := &sessionClaims{}
+	_, err := jwt.ParseWithClaims(
+		token,
+		claims,
+		func(*jwt.Token) (interface{}, error) { return tm.secret, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(tm.now),
+	)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Type != tokenType || claims.Subject == "" {
+		return "", ErrInvalidToken
+	}
+	return claims.Subject, nil
+}
+
+func (tm *TokenManager) issue(accountId string, tokenType TokenType, ttl time.Duration) (string, time.Time, error) {
+	issuedAt := tm.now()
+	expiresAt := issuedAt.Add(ttl)
+	claims := sessionClaims{
+		Type: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   accountId,
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(tm.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
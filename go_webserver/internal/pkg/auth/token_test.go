@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTokenManager(t *testing.T) {
+	t.Run("Verifies the tokens it issued for the account", func(t *testing.T) {
+		tm := NewTokenManager([]byte("secret"), time.Minute, time.Hour)
+		tokenPair, err := tm.IssueTokenPair("accountId")
+		assert.Nil(t, err)
+
+		accountId, err := tm.Verify(tokenPair.AccessToken, AccessToken)
+		assert.Nil(t, err)
+		assert.Equal(t, "accountId", accountId)
+		accountId, err = tm.Verify(tokenPair.RefreshToken, RefreshToken)
+		assert.Nil(t, err)
+		assert.Equal(t, "accountId", accountId)
+		assert.True(t, tokenPair.RefreshTokenExpiresAt.After(tokenPair.AccessTokenExpiresAt))
+	})
+
+	t.Run("Rejects a token used as the wrong type", func(t *testing.T) {
+		tm := NewTokenManager([]byte("secret"), time.Minute, time.Hour)
+		tokenPair, _ := tm.IssueTokenPair("accountId")
+
+		_, err := tm.Verify(tokenPair.RefreshToken, AccessToken)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		_, err = tm.Refresh(tokenPair.AccessToken)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Rejects an expired token", func(t *testing.T) {
+		tm := NewTokenManager([]byte("secret"), time.Minute, time.Hour)
+		tokenPair, _ := tm.IssueTokenPair("accountId")
+		tm.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+		_, err := tm.Verify(tokenPair.AccessToken, AccessToken)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		_, err = tm.Verify(tokenPair.RefreshToken, RefreshToken)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Rejects a token signed with a different secret", func(t *testing.T) {
+		tokenPair, _ := NewTokenManager([]byte("other"), time.Minute, time.Hour).IssueTokenPair("accountId")
+		tm := NewTokenManager([]byte("secret"), time.Minute, time.Hour)
+
+		_, err := tm.Verify(tokenPair.AccessToken, AccessToken)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		_, err = tm.Verify("not a token", AccessToken)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Refresh issues a new pair of tokens for the same account", func(t *testing.T) {
+		tm := NewTokenManager([]byte("secret"), time.Minute, time.Hour)
+		tokenPair, _ := tm.IssueTokenPair("accountId")
+
+		refreshed, err := tm.Refresh(tokenPair.RefreshToken)
+		assert.Nil(t, err)
+		accountId, err := tm.Verify(refreshed.AccessToken, AccessToken)
+		assert.Nil(t, err)
+		assert.Equal(t, "accountId", accountId)
+	})
+}
@@ -1,7 +1,6 @@
 package model
 
 import (
-	"errors"
 	"github.com/shopspring/decimal"
 	"time"
 )
@@ -9,13 +8,35 @@ import (
 type AccountDetailsOutput struct {
 	Id                string
 	Username          string
-	Password          string
 	Person            Person
 	BankAccounts      []BankAccount
 	KnownBankAccounts []KnownBankAccount
 	CreatedAt         time.Time
 }
 
+type AccountCredentialsOutput struct {
+	Id           string
+	Username     string
+	PasswordHash string
+}
+
+type AccountInput struct {
+	Username     string
+	PasswordHash string
+	Person       Person
+}
+
+type RegisterAccountInput struct {
+	Username string
+	Password string
+	Person   Person
+}
+
+type BankAccountInput struct {
+	AccountNumber string
+	AccountType   BankAccountType
+}
+
 type BankAccountType string
 
 const (
@@ -42,6 +63,7 @@ type KnownBankAccount struct {
 	AccountNumber string
 	AccountHolder string
 	AccountType   BankAccountType
+	Nickname      string
 }
 
 type BankAccountTransactionOutput struct {
@@ -54,16 +76,69 @@ type BankAccountTransactionOutput struct {
 	Status             PendingTransactionStatus
 	Amount             decimal.Decimal
 	CreatedAt          time.Time
+	Memo               string
+	// Category is the category the bank account gave the transaction
+	Category TransactionCategory
 }
 
 var (
-	ErrNoMatchingUsername = errors.New("no matching username found for account")
-	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrNoMatchingUsername = NewDomainError(ErrNotFound, "username_not_found",
+		"no matching username found for account")
+	ErrInvalidCredentials = NewDomainError(ErrUnauthenticated, "invalid_credentials",
+		"invalid username or password")
+	ErrNoMatchingBankAccount  = NewDomainError(ErrNotFound, "bank_account_not_found", "no matching bank account found")
+	ErrNoMatchingAccount      = NewDomainError(ErrNotFound, "account_not_found", "no matching account found")
+	ErrUsernameTaken          = NewDomainError(ErrConflict, "username_taken", "username is already taken")
+	ErrAccountNumberTaken     = NewDomainError(ErrConflict, "account_number_taken", "account number is already taken")
+	ErrInvalidAccountDetails  = NewDomainError(ErrValidation, "invalid_account_details", "invalid account details")
+	ErrInvalidBankAccountType = NewDomainError(ErrValidation, "invalid_bank_account_type", "invalid bank account type")
+	ErrBankAccountNotOwned    = NewDomainError(ErrForbidden, "bank_account_not_owned",
+		"bank account does not belong to the authenticated account")
+	ErrInvalidBalanceHistoryQuery = NewDomainError(ErrValidation, "invalid_balance_history_query",
+		"invalid balance history query")
 )
 
-type AccountBalanceMonthsOutput struct {
+// BalanceBucketSize is the length of the periods the balance history of a bank account is split into
+type BalanceBucketSize string
+
+const (
+	DailyBuckets     BalanceBucketSize = "day"
+	WeeklyBuckets    BalanceBucketSize = "week"
+	MonthlyBuckets   BalanceBucketSize = "month"
+	QuarterlyBuckets BalanceBucketSize = "quarter"
+)
+
+// AccountBalanceHistoryInput asks for the balance history of a bank account over the buckets that cover its time
+// range. Buckets start at midnight in the location, weeks on Mondays and quarters in January, April, July and October.
+type AccountBalanceHistoryInput struct {
 	BankAccountId string
-	Months        []AccountBalanceMonth
+	FromTime      time.Time
+	ToTime        time.Time
+	BucketSize    BalanceBucketSize
+	Location      *time.Location
+}
+
+// AccountBalanceHistoryOutput holds the buckets of the balance history of a bank account, newest first
+type AccountBalanceHistoryOutput struct {
+	BankAccountId string
+	BucketSize    BalanceBucketSize
+	Location      *time.Location
+	Buckets       []AccountBalanceBucket
+}
+
+// AccountBalanceBucket holds the available balances of a bank account from StartTime up to EndTime, which is the
+// StartTime of the next bucket, along with the totals of the transactions realized in between. Debits added to the
+// balance and credits took from it, like the natures of the transactions themselves.
+type AccountBalanceBucket struct {
+	StartTime               time.Time
+	EndTime                 time.Time
+	OpeningAvailableBalance decimal.Decimal
+	ClosingAvailableBalance decimal.Decimal
+	MinAvailableBalance     decimal.Decimal
+	MaxAvailableBalance     decimal.Decimal
+	ClosingPendingBalance   decimal.Decimal
+	TotalDebits             decimal.Decimal
+	TotalCredits            decimal.Decimal
 }
 
 type AccountBalanceMonth struct {
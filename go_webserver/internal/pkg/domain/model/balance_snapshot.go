@@ -0,0 +1,31 @@
+package model
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// BalanceSnapshot holds the balances of a bank account at the end of a day in UTC, which is the midnight that starts
+// the next day, along with what happened during the day, so that balance histories need not replay its transactions.
+// Snapshots are taken once their day is over and never changed afterwards.
+type BalanceSnapshot struct {
+	BankAccountId string
+	// Day is the midnight UTC that starts the day
+	Day              time.Time
+	AvailableBalance decimal.Decimal
+	PendingBalance   decimal.Decimal
+	// OpeningAvailableBalance and OpeningPendingBalance are the balances at the midnight that starts the day
+	OpeningAvailableBalance decimal.Decimal
+	OpeningPendingBalance   decimal.Decimal
+	MinAvailableBalance     decimal.Decimal
+	MaxAvailableBalance     decimal.Decimal
+	// TotalDebits and TotalCredits add up the realized transactions of the day
+	TotalDebits  decimal.Decimal
+	TotalCredits decimal.Decimal
+	CreatedAt    time.Time
+}
+
+var (
+	ErrNoMatchingBalanceSnapshot = NewDomainError(ErrNotFound, "balance_snapshot_not_found",
+		"no matching balance snapshot found")
+)
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// CategorySource tells whether the category of a transaction was assigned by a rule or by the account holder
+type CategorySource string
+
+const (
+	RuleCategory   CategorySource = "rule"
+	ManualCategory CategorySource = "manual"
+)
+
+// TransactionCategory is the category one side of a transfer gives it. The zero value is uncategorized.
+type TransactionCategory struct {
+	Name   string
+	Source CategorySource
+}
+
+// CategoryRuleInput describes the transactions a rule categorizes. Conditions left at their zero value match every
+// transaction.
+type CategoryRuleInput struct {
+	Category                  string
+	CounterpartyBankAccountId string
+	TransactionNature         TransactionNature
+	MinAmount                 decimal.NullDecimal
+	MaxAmount                 decimal.NullDecimal
+	// MemoPattern is a regular expression matched against the memo of the transaction
+	MemoPattern string
+}
+
+// CategoryRule categorizes the transactions of the bank accounts of an account. The rules of an account are tried in
+// the order they were created, and the first one matching a transaction gives it its category.
+type CategoryRule struct {
+	Id                        string
+	AccountId                 string
+	Category                  string
+	CounterpartyBankAccountId string
+	TransactionNature         TransactionNature
+	MinAmount                 decimal.NullDecimal
+	MaxAmount                 decimal.NullDecimal
+	MemoPattern               string
+	CreatedAt                 time.Time
+}
+
+// ApplyCategoryRulesOutput holds how many transactions a run of the category rules categorized differently, along with
+// the cursor of the transactions it stopped before, which is nil once every transaction was categorized
+type ApplyCategoryRulesOutput struct {
+	Changed int
+	Next    *TransactionCursor
+}
+
+var (
+	ErrNoMatchingCategoryRule = NewDomainError(ErrNotFound, "category_rule_not_found",
+		"no matching category rule found")
+	ErrInvalidCategoryRule  = NewDomainError(ErrValidation, "invalid_category_rule", "invalid category rule")
+	ErrTooManyCategoryRules = NewDomainError(ErrConflict, "too_many_category_rules",
+		"account has too many category rules")
+	ErrInvalidCategory = NewDomainError(ErrValidation, "invalid_category", "invalid category")
+)
@@ -0,0 +1,43 @@
+package model
+
+import "errors"
+
+// Kinds of domain errors. Every error of the domain is of one kind, which errors.Is matches, so that callers can tell
+// how to react to an error without knowing every specific one.
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrValidation        = errors.New("validation failed")
+	ErrConflict          = errors.New("conflict")
+	ErrForbidden         = errors.New("forbidden")
+	ErrUnauthenticated   = errors.New("unauthenticated")
+	ErrUnavailable       = errors.New("unavailable")
+	// ErrUnprocessable is the kind of valid requests that cannot be carried out for reasons other than funds
+	ErrUnprocessable = errors.New("unprocessable")
+)
+
+// DomainError is a specific error of the domain. Its code identifies it in API responses and must not change once
+// clients may rely on it.
+type DomainError struct {
+	Kind    error
+	Code    string
+	Message string
+}
+
+func NewDomainError(kind error, code string, message string) *DomainError {
+	return &DomainError{Kind: kind, Code: code, Message: message}
+}
+
+func (e *DomainError) Error() string {
+	return e.Message
+}
+
+func (e *DomainError) Is(target error) bool {
+	return target == e.Kind
+}
+
+var (
+	ErrInvalidId = NewDomainError(ErrValidation, "invalid_id", "invalid ID")
+	// ErrStorageUnavailable is matched by the errors of storage that cannot be reached or did not answer in time
+	ErrStorageUnavailable = NewDomainError(ErrUnavailable, "storage_unavailable", "storage is unavailable")
+)
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+)
+
+// IdempotencyKeyInput identifies a client request that must take effect at most once. Keys are scoped to the
+// account that sent the request, so different accounts may use the same key.
+type IdempotencyKeyInput struct {
+	Key       string
+	AccountId string
+}
+
+// IdempotencyKeyTTL is how long a client can safely retry a request with the same idempotency key. Older keys are
+// treated as never used and deleted, by the TTL index of the v4 schema migration on MongoDB.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+type IdempotencyRecord struct {
+	Key           string
+	AccountId     string
+	RequestHash   string
+	TransactionId string
+	CreatedAt     time.Time
+}
+
+type TransactionCreatedOutput struct {
+	Id string
+	// Replayed is true when the transaction was created by an earlier request with the same idempotency key
+	Replayed bool
+}
+
+var (
+	ErrNoMatchingIdempotencyKey = NewDomainError(ErrNotFound, "idempotency_key_not_found",
+		"no matching idempotency key found")
+	ErrIdempotencyKeyReused = NewDomainError(ErrUnprocessable, "idempotency_key_reused",
+		"idempotency key was already used for a different request")
+	ErrIdempotencyKeyInUse = NewDomainError(ErrConflict, "idempotency_key_in_use",
+		"idempotency key is in use by a concurrent request")
+)
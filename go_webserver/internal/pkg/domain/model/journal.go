@@ -0,0 +1,68 @@
+package model
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+type BalanceKind string
+
+const (
+	AvailableBalance BalanceKind = "available"
+	PendingBalance   BalanceKind = "pending"
+)
+
+type JournalEntryKind string
+
+const (
+	TransferEntry       JournalEntryKind = "transfer"
+	PendingHoldEntry    JournalEntryKind = "pendingHold"
+	PendingReleaseEntry JournalEntryKind = "pendingRelease"
+	OpeningBalanceEntry JournalEntryKind = "openingBalance"
+)
+
+// OpeningBalanceEquityAccountId is the ledger account that balances existing before the journal are posted against
+const OpeningBalanceEquityAccountId = "000000000000000000000000"
+
+// Posting is a signed change to one balance of a bank account
+type Posting struct {
+	BankAccountId string
+	Balance       BalanceKind
+	Amount        decimal.Decimal
+}
+
+// JournalEntryInput is an immutable record of the postings of a single balance movement. The postings to each kind
+// of balance sum to zero.
+type JournalEntryInput struct {
+	TransactionId string
+	Kind          JournalEntryKind
+	Postings      []Posting
+}
+
+// PostingTotal is the sum of all postings to one balance of a bank account
+type PostingTotal struct {
+	BankAccountId string
+	Balance       BalanceKind
+	Amount        decimal.Decimal
+}
+
+type BankAccountBalance struct {
+	BankAccountId    string
+	AccountNumber    string
+	AvailableBalance decimal.Decimal
+	PendingBalance   decimal.Decimal
+}
+
+// BalanceDiscrepancy is a bank account whose stored balances differ from the totals of its postings
+type BalanceDiscrepancy struct {
+	BankAccountId   string
+	AccountNumber   string
+	StoredAvailable decimal.Decimal
+	PostedAvailable decimal.Decimal
+	StoredPending   decimal.Decimal
+	PostedPending   decimal.Decimal
+}
+
+var (
+	ErrUnbalancedJournalEntry = NewDomainError(ErrValidation, "unbalanced_journal_entry",
+		"journal entry postings do not sum to zero")
+)
@@ -0,0 +1,29 @@
+package model
+
+type PayeeInput struct {
+	AccountNumber string
+	Nickname      string
+}
+
+// UnknownPayeePolicy decides what happens to transfers whose destination is neither a bank account of the sender
+// nor one of their known payees
+type UnknownPayeePolicy string
+
+const (
+	AllowUnknownPayees   UnknownPayeePolicy = "allow"
+	RejectUnknownPayees  UnknownPayeePolicy = "reject"
+	ConfirmUnknownPayees UnknownPayeePolicy = "confirm"
+)
+
+var (
+	ErrNoMatchingPayee       = NewDomainError(ErrNotFound, "payee_not_found", "no matching payee found")
+	ErrPayeeAlreadyKnown     = NewDomainError(ErrConflict, "payee_already_known", "payee is already known")
+	ErrPayeeIsOwnBankAccount = NewDomainError(ErrValidation, "payee_is_own_bank_account",
+		"payee is a bank account of the same account")
+	ErrInvalidPayeeNickname      = NewDomainError(ErrValidation, "invalid_payee_nickname", "invalid payee nickname")
+	ErrInvalidPayeeAccountNumber = NewDomainError(ErrValidation, "invalid_payee_account_number",
+		"invalid payee account number")
+	ErrUnknownPayee             = NewDomainError(ErrForbidden, "unknown_payee", "destination is not a known payee")
+	ErrUnknownPayeeNotConfirmed = NewDomainError(ErrConflict, "unknown_payee_not_confirmed",
+		"transfer to an unknown payee was not confirmed")
+)
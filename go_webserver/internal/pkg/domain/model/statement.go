@@ -0,0 +1,51 @@
+package model
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type StatementInput struct {
+	BankAccountId string
+	FromTime      time.Time
+	ToTime        time.Time
+}
+
+// StatementEntry is a realized transaction of the bank account of a statement along with the bank account on the
+// other side of it. The account number and holder of the counterparty are empty when it no longer exists.
+type StatementEntry struct {
+	BankAccountTransactionOutput
+	CounterpartyAccountNumber string
+	CounterpartyHolder        string
+}
+
+// StatementOutput holds the realized transactions of a bank account over a period, oldest first, along with its
+// available balance at the start and at the end of the period
+type StatementOutput struct {
+	BankAccountId           string
+	AccountNumber           string
+	AccountType             BankAccountType
+	AccountHolder           string
+	FromTime                time.Time
+	ToTime                  time.Time
+	OpeningAvailableBalance decimal.Decimal
+	ClosingAvailableBalance decimal.Decimal
+	Entries                 []StatementEntry
+	CreatedAt               time.Time
+}
+
+type MonthlyStatementInput struct {
+	BankAccountId string
+	Year          int
+	Month         time.Month
+}
+
+// MonthlyStatementOutput holds what the printable statement of a bank account over a calendar month shows: the
+// account holding it, its balances at the end of the month and the realized transactions of the month, oldest first
+type MonthlyStatementOutput struct {
+	Account      AccountDetailsOutput
+	BankAccount  BankAccount
+	Month        AccountBalanceMonth
+	Transactions []BankAccountTransactionOutput
+	CreatedAt    time.Time
+}
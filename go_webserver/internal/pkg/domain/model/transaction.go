@@ -12,6 +12,14 @@ type TransactionDetailsInput struct {
 	Type              TransactionType
 	ExpirationDate    time.Time
 	Status            PendingTransactionStatus
+	// Memo is the reference given by the sender, seen by both sides of the transfer
+	Memo string
+	// FromCategory and ToCategory are the categories each side gives the transfer. Sides left uncategorized are
+	// categorized by the rules of their account holder.
+	FromCategory TransactionCategory
+	ToCategory   TransactionCategory
+	// ConfirmUnknownPayee acknowledges a transfer to a destination that is not a known payee
+	ConfirmUnknownPayee bool
 }
 
 type TransactionDetailsOutput struct {
@@ -22,6 +30,9 @@ type TransactionDetailsOutput struct {
 	Type              TransactionType
 	ExpirationDate    time.Time
 	Status            PendingTransactionStatus
+	Memo              string
+	FromCategory      TransactionCategory
+	ToCategory        TransactionCategory
 }
 
 type TransactionType string
@@ -45,10 +56,47 @@ type TransactionsForBankAccountInput struct {
 	ToTime        time.Time
 }
 
-type AccountHistoryInMonthsInput struct {
+type SortOrder string
+
+const (
+	Ascending  SortOrder = "asc"
+	Descending SortOrder = "desc"
+)
+
+// TransactionFilter narrows down the transactions of a bank account. Zero values do not filter.
+type TransactionFilter struct {
+	TransactionNature         TransactionNature
+	TransactionType           TransactionType
+	Status                    PendingTransactionStatus
+	MinAmount                 decimal.NullDecimal
+	MaxAmount                 decimal.NullDecimal
+	CounterpartyBankAccountId string
+}
+
+// TransactionCursor is the position of a transaction in the listing of a bank account's transactions, which are
+// ordered by creation time and then by ID
+type TransactionCursor struct {
+	CreatedAt time.Time
+	Id        string
+}
+
+// TransactionPageInput requests the transactions of a bank account that follow the After cursor. Zero times leave
+// the time window unbounded on that side.
+type TransactionPageInput struct {
 	BankAccountId string
 	FromTime      time.Time
 	ToTime        time.Time
+	Filter        TransactionFilter
+	Order         SortOrder
+	PageSize      int
+	After         *TransactionCursor
+}
+
+// TransactionPageOutput holds a page of transactions along with the cursor of the next page, which is nil on the
+// last page
+type TransactionPageOutput struct {
+	Transactions []BankAccountTransactionOutput
+	Next         *TransactionCursor
 }
 
 type TransactionNature string
@@ -57,3 +105,20 @@ const (
 	Debit  TransactionNature = "debit"
 	Credit TransactionNature = "credit"
 )
+
+var (
+	ErrNoMatchingTransaction = NewDomainError(ErrNotFound, "transaction_not_found",
+		"no matching transaction found")
+	ErrPendingTransactionNotActive = NewDomainError(ErrConflict, "pending_transaction_not_active",
+		"pending transaction is no longer active")
+	ErrInvalidExpirationDate = NewDomainError(ErrValidation, "invalid_expiration_date",
+		"expiration date must be in the future")
+	ErrInvalidMemo             = NewDomainError(ErrValidation, "invalid_memo", "invalid memo")
+	ErrInvalidTransactionQuery = NewDomainError(ErrValidation, "invalid_transaction_query",
+		"invalid transaction query")
+	ErrInvalidTransactionCursor = NewDomainError(ErrValidation, "invalid_transaction_cursor",
+		"invalid transaction cursor")
+	ErrInsufficientBalance        = NewDomainError(ErrInsufficientFunds, "insufficient_funds", "insufficient balance")
+	ErrPendingTransactionNotOwned = NewDomainError(ErrForbidden, "pending_transaction_not_owned",
+		"pending transaction does not belong to the authenticated account")
+)
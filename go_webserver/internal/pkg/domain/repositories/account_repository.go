@@ -8,6 +8,7 @@ import (
 
 type AccountRepository interface {
 	GetAccountDetailsFromBankAccountId(bankAccountId string, ctx context.Context) (*model.AccountDetailsOutput, error)
+	GetAccountIdFromBankAccountId(bankAccountId string, ctx context.Context) (string, error)
 	AddBalance(bankAccountId string, amount decimal.Decimal, toPending bool, ctx context.Context) error
 	DeductBalance(bankAccountId string, amount decimal.Decimal, toPending bool, ctx context.Context) (
 		decimal.Decimal,
@@ -15,5 +16,20 @@ type AccountRepository interface {
 		error,
 	)
 	GetAccountDetailsFromUsername(username string, ctx context.Context) (*model.AccountDetailsOutput, error)
+	GetAccountCredentialsFromUsername(username string, ctx context.Context) (*model.AccountCredentialsOutput, error)
+	UpdatePasswordHash(accountId string, passwordHash string, ctx context.Context) error
 	GetAccountBalance(bankAccountId string, ctx context.Context) (decimal.Decimal, decimal.Decimal, error)
+	AddAccount(input *model.AccountInput, ctx context.Context) (string, error)
+	AddBankAccount(accountId string, input *model.BankAccountInput, ctx context.Context) (string, error)
+	GetAccountDetailsFromAccountNumber(accountNumber string, ctx context.Context) (*model.AccountDetailsOutput, error)
+	GetKnownBankAccounts(accountId string, ctx context.Context) ([]model.KnownBankAccount, error)
+	AddKnownBankAccount(accountId string, knownBankAccount *model.KnownBankAccount, ctx context.Context) error
+	UpdateKnownBankAccountNickname(
+		accountId string,
+		knownBankAccountId string,
+		nickname string,
+		ctx context.Context,
+	) error
+	RemoveKnownBankAccount(accountId string, knownBankAccountId string, ctx context.Context) error
+	GetAllBankAccountBalances(ctx context.Context) ([]model.BankAccountBalance, error)
 }
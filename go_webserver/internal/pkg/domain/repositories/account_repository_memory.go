@@ -0,0 +1,502 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/memory"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
+)
+
+type AccountRepositoryMemory struct {
+	store  *memory.Store
+	logger *slog.Logger
+}
+
+func CreateNewAccountRepositoryMemory(store *memory.Store, logger *slog.Logger) *AccountRepositoryMemory {
+	return &AccountRepositoryMemory{store: store, logger: logger}
+}
+
+func (ar *AccountRepositoryMemory) GetAccountDetailsFromBankAccountId(
+	bankAccountId string,
+	ctx context.Context,
+) (*model.AccountDetailsOutput, error) {
+	if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+		return nil, fmt.Errorf("error when converting account ID to object ID for "+
+			"bankAccountId %s: %w", bankAccountId, err)
+	}
+	var res *model.AccountDetailsOutput
+	err := ar.store.Run(ctx, func(tx *memory.Tx) error {
+		account, _, err := findAccountByBankAccountId(tx, bankAccountId)
+		if err != nil {
+			return err
+		}
+		if account == nil {
+			return model.ErrNoMatchingBankAccount
+		}
+		res = fromMemoryAccountDetails(account)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ar.logger.DebugContext(ctx, "Retrieved account details", logging.BankAccountId(bankAccountId))
+	return res, nil
+}
+
+func (ar *AccountRepositoryMemory) GetAccountIdFromBankAccountId(
+	bankAccountId string,
+	ctx context.Context,
+) (string, error) {
+	if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+		return "", fmt.Errorf("error when converting account ID to object ID for "+
+			"bankAccountId %s: %w", bankAccountId, err)
+	}
+	var accountId string
+	err := ar.store.Run(ctx, func(tx *memory.Tx) error {
+		account, _, err := findAccountByBankAccountId(tx, bankAccountId)
+		if err != nil {
+			return err
+		}
+		if account == nil {
+			return model.ErrNoMatchingBankAccount
+		}
+		accountId = account.Id
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return accountId, nil
+}
+
+func (ar *AccountRepositoryMemory) AddBalance(
+	bankAccountId string,
+	amount decimal.Decimal,
+	toPending bool,
+	ctx context.Context,
+) error {
+	_, _, err := ar.updateBalance(bankAccountId, amount, toPending, ctx)
+	return err
+}
+
+func (ar *AccountRepositoryMemory) DeductBalance(
+	bankAccountId string,
+	amount decimal.Decimal,
+	toPending bool,
+	ctx context.Context,
+) (decimal.Decimal, decimal.Decimal, error) {
+	return ar.updateBalance(bankAccountId, amount.Neg(), toPending, ctx)
+}
+
+// updateBalance adds the amount to the pending balance of the bank account, and to its available balance unless
+// only the pending balance is to change. It returns the updated available and pending balances.
+func (ar *AccountRepositoryMemory) updateBalance(
+	bankAccountId string,
+	amount decimal.Decimal,
+	toPending bool,
+	ctx context.Context,
+) (decimal.Decimal, decimal.Decimal, error) {
+	defaultDecimal := decimal.NewFromInt(0)
+	if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+		return defaultDecimal, defaultDecimal,
+			fmt.Errorf("error when converting account ID to object ID for bankAccountId "+
+				"%s: %w", bankAccountId, err)
+	}
+	var available, pending decimal.Decimal
+	err := ar.store.Run(ctx, func(tx *memory.Tx) error {
+		account, i, err := findAccountByBankAccountId(tx, bankAccountId)
+		if err != nil {
+			return fmt.Errorf("error when updating account balance for bankAccountId %s: %w", bankAccountId, err)
+		}
+		if account == nil {
+			return fmt.Errorf("%w for bankAccountId %s", model.ErrNoMatchingBankAccount, bankAccountId)
+		}
+		bankAccount := &account.BankAccounts[i]
+		bankAccount.PendingBalance = bankAccount.PendingBalance.Add(amount)
+		if !toPending {
+			bankAccount.AvailableBalance = bankAccount.AvailableBalance.Add(amount)
+		}
+		available, pending = bankAccount.AvailableBalance, bankAccount.PendingBalance
+		return tx.Put(memory.AccountCollectionName, account.Id, account)
+	})
+	if err != nil {
+		return defaultDecimal, defaultDecimal, err
+	}
+	return available, pending, nil
+}
+
+func (ar *AccountRepositoryMemory) GetAccountBalance(
+	bankAccountId string,
+	ctx context.Context,
+) (decimal.Decimal, decimal.Decimal, error) {
+	defaultDecimal := decimal.NewFromInt(0)
+	if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+		return defaultDecimal, defaultDecimal,
+			fmt.Errorf("error when converting account ID to object ID for bankAccountId "+
+				"%s: %w", bankAccountId, err)
+	}
+	var available, pending decimal.Decimal
+	err := ar.store.Run(ctx, func(tx *memory.Tx) error {
+		account, i, err := findAccountByBankAccountId(tx, bankAccountId)
+		if err != nil {
+			return err
+		}
+		if account == nil {
+			return model.ErrNoMatchingBankAccount
+		}
+		available, pending = account.BankAccounts[i].AvailableBalance, account.BankAccounts[i].PendingBalance
+		return nil
+	})
+	if err != nil {
+		return defaultDecimal, defaultDecimal,
+			fmt.Errorf("error when finding account by ID %s: %w", bankAccountId, err)
+	}
+	return available, pending, nil
+}
+
+func (ar *AccountRepositoryMemory) GetAllBankAccountBalances(ctx context.Context) ([]model.BankAccountBalance, error) {
+	res := make([]model.BankAccountBalance, 0)
+	err := ar.store.Run(ctx, func(tx *memory.Tx) error {
+		accounts, err := tx.Find(memory.AccountCollectionName, nil)
+		if err != nil {
+			return err
+		}
+		for _, doc := range accounts {
+			for _, bankAccount := range doc.(*memory.AccountRecord).BankAccounts {
+				res = append(res, model.BankAccountBalance{
+					BankAccountId:    bankAccount.Id,
+					AccountNumber:    bankAccount.AccountNumber,
+					AvailableBalance: bankAccount.AvailableBalance,
+					PendingBalance:   bankAccount.PendingBalance,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when getting bank account balances: %w", err)
+	}
+	return res, nil
+}
+
+func (ar *AccountRepositoryMemory) GetAccountDetailsFromUsername(
+	username string,
+	ctx context.Context,
+) (*model.AccountDetailsOutput, error) {
+	account, err := ar.findAccountByUsername(username, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fromMemoryAccountDetails(account), nil
+}
+
+func (ar *AccountRepositoryMemory) GetAccountCredentialsFromUsername(
+	username string,
+	ctx context.Context,
+) (*model.AccountCredentialsOutput, error) {
+	account, err := ar.findAccountByUsername(username, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &model.AccountCredentialsOutput{
+		Id:           account.Id,
+		Username:     account.Username,
+		PasswordHash: account.Password,
+	}, nil
+}
+
+func (ar *AccountRepositoryMemory) UpdatePasswordHash(
+	accountId string,
+	passwordHash string,
+	ctx context.Context,
+) error {
+	err := ar.updateAccount(accountId, ctx, func(account *memory.AccountRecord) error {
+		account.Password = passwordHash
+		return nil
+	})
+	if errors.Is(err, model.ErrNoMatchingAccount) {
+		return fmt.Errorf("%w for accountId %s", model.ErrNoMatchingAccount, accountId)
+	}
+	if err != nil {
+		return err
+	}
+	ar.logger.DebugContext(ctx, "Updated password hash", logging.AccountId(accountId))
+	return nil
+}
+
+func (ar *AccountRepositoryMemory) AddAccount(input *model.AccountInput, ctx context.Context) (string, error) {
+	account := &memory.AccountRecord{
+		Id:       primitive.NewObjectID().Hex(),
+		Username: input.Username,
+		Password: input.PasswordHash,
+		Person: memory.Person{
+			FirstName: input.Person.FirstName,
+			LastName:  input.Person.LastName,
+		},
+		BankAccounts:      []memory.BankAccount{},
+		KnownBankAccounts: []memory.KnownBankAccount{},
+		CreatedAt:         currentTime(),
+	}
+	err := ar.store.Run(ctx, func(tx *memory.Tx) error {
+		return tx.Put(memory.AccountCollectionName, account.Id, account)
+	})
+	if err != nil {
+		if errors.Is(err, memory.ErrDuplicateKey) {
+			return "", model.ErrUsernameTaken
+		}
+		return "", fmt.Errorf("error when inserting account for username %s: %w", input.Username, err)
+	}
+	ar.logger.DebugContext(ctx, "Inserted account", logging.AccountId(account.Id),
+		slog.String(logging.UsernameKey, input.Username))
+	return account.Id, nil
+}
+
+func (ar *AccountRepositoryMemory) AddBankAccount(
+	accountId string,
+	input *model.BankAccountInput,
+	ctx context.Context,
+) (string, error) {
+	bankAccount := memory.BankAccount{
+		Id:               primitive.NewObjectID().Hex(),
+		AccountNumber:    input.AccountNumber,
+		AccountType:      string(input.AccountType),
+		PendingBalance:   decimal.Zero,
+		AvailableBalance: decimal.Zero,
+	}
+	err := ar.updateAccount(accountId, ctx, func(account *memory.AccountRecord) error {
+		account.BankAccounts = append(account.BankAccounts, bankAccount)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, memory.ErrDuplicateKey) {
+			return "", model.ErrAccountNumberTaken
+		}
+		return "", err
+	}
+	ar.logger.DebugContext(ctx, "Added bank account", logging.BankAccountId(bankAccount.Id),
+		logging.AccountId(accountId))
+	return bankAccount.Id, nil
+}
+
+func (ar *AccountRepositoryMemory) GetAccountDetailsFromAccountNumber(
+	accountNumber string,
+	ctx context.Context,
+) (*model.AccountDetailsOutput, error) {
+	var res *model.AccountDetailsOutput
+	err := ar.store.Run(ctx, func(tx *memory.Tx) error {
+		accounts, err := tx.Find(memory.AccountCollectionName, func(doc memory.Document) bool {
+			for _, bankAccount := range doc.(*memory.AccountRecord).BankAccounts {
+				if bankAccount.AccountNumber == accountNumber {
+					return true
+				}
+			}
+			return false
+		})
+		if err != nil {
+			return fmt.Errorf("error when finding account by account number %s: %w", accountNumber, err)
+		}
+		if len(accounts) == 0 {
+			return model.ErrNoMatchingBankAccount
+		}
+		res = fromMemoryAccountDetails(accounts[0].(*memory.AccountRecord))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ar.logger.DebugContext(ctx, "Retrieved account details by account number", logging.AccountNumber(accountNumber))
+	return res, nil
+}
+
+func (ar *AccountRepositoryMemory) GetKnownBankAccounts(
+	accountId string,
+	ctx context.Context,
+) ([]model.KnownBankAccount, error) {
+	if _, err := utils.StringToObjectId(accountId); err != nil {
+		return nil, fmt.Errorf("error when converting account ID to object ID for accountId %s: %w", accountId, err)
+	}
+	var res []model.KnownBankAccount
+	err := ar.store.Run(ctx, func(tx *memory.Tx) error {
+		doc, found, err := tx.Get(memory.AccountCollectionName, accountId)
+		if err != nil {
+			return fmt.Errorf("error when finding known bank accounts for accountId %s: %w", accountId, err)
+		}
+		if !found {
+			return model.ErrNoMatchingAccount
+		}
+		res = fromMemoryKnownAccounts(doc.(*memory.AccountRecord).KnownBankAccounts)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// AddKnownBankAccount stores the bank account as a payee of the account. The payee is identified by the ID of its
+// bank account, so a bank account can only be known once.
+func (ar *AccountRepositoryMemory) AddKnownBankAccount(
+	accountId string,
+	knownBankAccount *model.KnownBankAccount,
+	ctx context.Context,
+) error {
+	if _, err := utils.StringToObjectId(knownBankAccount.Id); err != nil {
+		return fmt.Errorf("error when converting known bank account ID to object ID for accountId %s: %w",
+			accountId, err)
+	}
+	err := ar.updateAccount(accountId, ctx, func(account *memory.AccountRecord) error {
+		for _, known := range account.KnownBankAccounts {
+			if known.Id == knownBankAccount.Id {
+				return model.ErrPayeeAlreadyKnown
+			}
+		}
+		account.KnownBankAccounts = append(account.KnownBankAccounts, memory.KnownBankAccount{
+			Id:            knownBankAccount.Id,
+			AccountNumber: knownBankAccount.AccountNumber,
+			AccountHolder: knownBankAccount.AccountHolder,
+			AccountType:   string(knownBankAccount.AccountType),
+			Nickname:      knownBankAccount.Nickname,
+		})
+		return nil
+	})
+	// Like the MongoDB filter on the account and payee, a missing account is reported as an already known payee
+	if errors.Is(err, model.ErrNoMatchingAccount) {
+		return model.ErrPayeeAlreadyKnown
+	}
+	if err != nil {
+		return err
+	}
+	ar.logger.DebugContext(ctx, "Added payee", slog.String(logging.PayeeIdKey, knownBankAccount.Id),
+		logging.AccountId(accountId))
+	return nil
+}
+
+func (ar *AccountRepositoryMemory) UpdateKnownBankAccountNickname(
+	accountId string,
+	knownBankAccountId string,
+	nickname string,
+	ctx context.Context,
+) error {
+	return ar.updateKnownBankAccount(accountId, knownBankAccountId, ctx,
+		func(account *memory.AccountRecord, i int) {
+			account.KnownBankAccounts[i].Nickname = nickname
+		})
+}
+
+func (ar *AccountRepositoryMemory) RemoveKnownBankAccount(
+	accountId string,
+	knownBankAccountId string,
+	ctx context.Context,
+) error {
+	err := ar.updateKnownBankAccount(accountId, knownBankAccountId, ctx,
+		func(account *memory.AccountRecord, i int) {
+			account.KnownBankAccounts = append(account.KnownBankAccounts[:i], account.KnownBankAccounts[i+1:]...)
+		})
+	if err != nil {
+		return err
+	}
+	ar.logger.DebugContext(ctx, "Removed payee", slog.String(logging.PayeeIdKey, knownBankAccountId),
+		logging.AccountId(accountId))
+	return nil
+}
+
+func (ar *AccountRepositoryMemory) updateKnownBankAccount(
+	accountId string,
+	knownBankAccountId string,
+	ctx context.Context,
+	update func(account *memory.AccountRecord, i int),
+) error {
+	if _, err := utils.StringToObjectId(knownBankAccountId); err != nil {
+		if _, err = utils.StringToObjectId(accountId); err != nil {
+			return fmt.Errorf("error when converting account ID to object ID for accountId %s: %w", accountId, err)
+		}
+		return model.ErrNoMatchingPayee
+	}
+	err := ar.updateAccount(accountId, ctx, func(account *memory.AccountRecord) error {
+		for i, known := range account.KnownBankAccounts {
+			if known.Id == knownBankAccountId {
+				update(account, i)
+				return nil
+			}
+		}
+		return model.ErrNoMatchingPayee
+	})
+	if errors.Is(err, model.ErrNoMatchingAccount) {
+		return model.ErrNoMatchingPayee
+	}
+	return err
+}
+
+// updateAccount applies the update to the account with the given ID and writes it back, unless the update fails
+func (ar *AccountRepositoryMemory) updateAccount(
+	accountId string,
+	ctx context.Context,
+	update func(account *memory.AccountRecord) error,
+) error {
+	if _, err := utils.StringToObjectId(accountId); err != nil {
+		return fmt.Errorf("error when converting account ID to object ID for accountId %s: %w", accountId, err)
+	}
+	return ar.store.Run(ctx, func(tx *memory.Tx) error {
+		doc, found, err := tx.Get(memory.AccountCollectionName, accountId)
+		if err != nil {
+			return fmt.Errorf("error when finding account %s: %w", accountId, err)
+		}
+		if !found {
+			return model.ErrNoMatchingAccount
+		}
+		account := doc.(*memory.AccountRecord)
+		if err = update(account); err != nil {
+			return err
+		}
+		return tx.Put(memory.AccountCollectionName, accountId, account)
+	})
+}
+
+func (ar *AccountRepositoryMemory) findAccountByUsername(
+	username string,
+	ctx context.Context,
+) (*memory.AccountRecord, error) {
+	var account *memory.AccountRecord
+	err := ar.store.Run(ctx, func(tx *memory.Tx) error {
+		accounts, err := tx.Find(memory.AccountCollectionName, func(doc memory.Document) bool {
+			return doc.(*memory.AccountRecord).Username == username
+		})
+		if err != nil {
+			return fmt.Errorf("error when finding account by username: %w", err)
+		}
+		if len(accounts) == 0 {
+			return model.ErrNoMatchingUsername
+		}
+		account = accounts[0].(*memory.AccountRecord)
+		return nil
+	})
+	return account, err
+}
+
+// findAccountByBankAccountId returns the account holding the bank account along with the index of the bank account,
+// or a nil account when there is none
+func findAccountByBankAccountId(tx *memory.Tx, bankAccountId string) (*memory.AccountRecord, int, error) {
+	accounts, err := tx.Find(memory.AccountCollectionName, func(doc memory.Document) bool {
+		return bankAccountIndex(doc.(*memory.AccountRecord), bankAccountId) >= 0
+	})
+	if err != nil || len(accounts) == 0 {
+		return nil, -1, err
+	}
+	account := accounts[0].(*memory.AccountRecord)
+	return account, bankAccountIndex(account, bankAccountId), nil
+}
+
+func bankAccountIndex(account *memory.AccountRecord, bankAccountId string) int {
+	for i, bankAccount := range account.BankAccounts {
+		if bankAccount.Id == bankAccountId {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/memory"
+)
+
+// currentTime is the time documents are created at. It is truncated to seconds, which is the precision MongoDB
+// timestamps are stored at, so that both backends list and filter documents by the same times.
+func currentTime() time.Time {
+	return time.Unix(time.Now().Unix(), 0)
+}
+
+func fromMemoryAccountDetails(account *memory.AccountRecord) *model.AccountDetailsOutput {
+	bankAccounts := make([]model.BankAccount, len(account.BankAccounts))
+	for i, bankAccount := range account.BankAccounts {
+		bankAccounts[i] = model.BankAccount{
+			Id:               bankAccount.Id,
+			AccountNumber:    bankAccount.AccountNumber,
+			AccountType:      model.BankAccountType(bankAccount.AccountType),
+			PendingBalance:   bankAccount.PendingBalance,
+			AvailableBalance: bankAccount.AvailableBalance,
+		}
+	}
+	return &model.AccountDetailsOutput{
+		Id:       account.Id,
+		Username: account.Username,
+		Person: model.Person{
+			FirstName: account.Person.FirstName,
+			LastName:  account.Person.LastName,
+		},
+		BankAccounts:      bankAccounts,
+		KnownBankAccounts: fromMemoryKnownAccounts(account.KnownBankAccounts),
+		CreatedAt:         account.CreatedAt,
+	}
+}
+
+func fromMemoryKnownAccounts(knownAccounts []memory.KnownBankAccount) []model.KnownBankAccount {
+	res := make([]model.KnownBankAccount, len(knownAccounts))
+	for i, known := range knownAccounts {
+		res[i] = model.KnownBankAccount{
+			Id:            known.Id,
+			AccountNumber: known.AccountNumber,
+			AccountHolder: known.AccountHolder,
+			AccountType:   model.BankAccountType(known.AccountType),
+			Nickname:      known.Nickname,
+		}
+	}
+	return res
+}
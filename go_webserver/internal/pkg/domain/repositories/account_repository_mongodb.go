@@ -6,20 +6,33 @@ import (
 	"fmt"
 	"github.com/shopspring/decimal"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"log"
+	"log/slog"
+	"time"
 	"webserver/internal/pkg/domain/model"
 	"webserver/internal/pkg/infrastructure/mongodb"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
 	"webserver/internal/pkg/utils"
 )
 
+// accountDetailsProjection keeps the password hash from ever leaving the repository with the account details
+var accountDetailsProjection = bson.M{"password": 0}
+
 type AccountRepositoryMongodb struct {
-	col *mongo.Collection
+	col     *mongo.Collection
+	metrics *metrics.Metrics
+	logger  *slog.Logger
 }
 
-func CreateNewAccountRepositoryMongodb(col *mongo.Collection) *AccountRepositoryMongodb {
-	ar := AccountRepositoryMongodb{col: col}
+func CreateNewAccountRepositoryMongodb(
+	col *mongo.Collection,
+	metrics *metrics.Metrics,
+	logger *slog.Logger,
+) *AccountRepositoryMongodb {
+	ar := AccountRepositoryMongodb{col: col, metrics: metrics, logger: logger}
 	return &ar
 }
 
@@ -27,6 +40,7 @@ func (ar *AccountRepositoryMongodb) GetAccountDetailsFromBankAccountId(
 	bankAccountId string,
 	ctx context.Context,
 ) (*model.AccountDetailsOutput, error) {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "GetAccountDetailsFromBankAccountId", time.Now())
 	var accountDetails mongodb.MongoAccountOutput
 	var res *model.AccountDetailsOutput
 	objectId, err := utils.StringToObjectId(bankAccountId)
@@ -35,24 +49,54 @@ func (ar *AccountRepositoryMongodb) GetAccountDetailsFromBankAccountId(
 			"bankAccountId %s: %w", bankAccountId, err)
 	}
 	filter := bson.M{"bankAccounts._id": objectId}
-	err = ar.col.FindOne(ctx, filter).Decode(&accountDetails)
+	opts := options.FindOne().SetProjection(accountDetailsProjection)
+	err = ar.col.FindOne(ctx, filter, opts).Decode(&accountDetails)
 	if err != nil {
-		return nil, fmt.Errorf("error when finding account by ID %s: %w", bankAccountId, err)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, model.ErrNoMatchingBankAccount
+		}
+		return nil, fmt.Errorf("error when finding account by ID %s: %w", bankAccountId, utils.ClassifyMongoError(err))
 	}
 	res, err = fromMongoAccountDetails(&accountDetails)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("Successfully retrieved account details for account %s\n", bankAccountId)
+	ar.logger.DebugContext(ctx, "Retrieved account details", logging.BankAccountId(bankAccountId))
 	return res, nil
 }
 
+func (ar *AccountRepositoryMongodb) GetAccountIdFromBankAccountId(
+	bankAccountId string,
+	ctx context.Context,
+) (string, error) {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "GetAccountIdFromBankAccountId", time.Now())
+	objectId, err := utils.StringToObjectId(bankAccountId)
+	if err != nil {
+		return "", fmt.Errorf("error when converting account ID to object ID for "+
+			"bankAccountId %s: %w", bankAccountId, err)
+	}
+	var account struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1})
+	err = ar.col.FindOne(ctx, bson.M{"bankAccounts._id": objectId}, opts).Decode(&account)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", model.ErrNoMatchingBankAccount
+		}
+		return "", fmt.Errorf("error when finding account of BankAccount %s: %w", bankAccountId,
+			utils.ClassifyMongoError(err))
+	}
+	return account.Id.Hex(), nil
+}
+
 func (ar *AccountRepositoryMongodb) AddBalance(
 	bankAccountId string,
 	amount decimal.Decimal,
 	toPending bool,
 	ctx context.Context,
 ) error {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "AddBalance", time.Now())
 	objectId, err := utils.StringToObjectId(bankAccountId)
 	if err != nil {
 		return fmt.Errorf("error when converting account ID to object ID for "+
@@ -72,12 +116,13 @@ func (ar *AccountRepositoryMongodb) AddBalance(
 	result, err := ar.col.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return fmt.Errorf(
-			"error when updating account balance for bankAccountId %s: %w", bankAccountId, err,
+			"error when updating account balance for bankAccountId %s: %w", bankAccountId,
+			utils.ClassifyMongoError(err),
 		)
 	}
 
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("no matching account found for bankAccountId %s", bankAccountId)
+		return fmt.Errorf("%w for bankAccountId %s", model.ErrNoMatchingBankAccount, bankAccountId)
 	} else if result.ModifiedCount == 0 {
 		return fmt.Errorf("update failed to the account balance for bankAccountId %s", bankAccountId)
 	} else {
@@ -91,6 +136,7 @@ func (ar *AccountRepositoryMongodb) DeductBalance(
 	toPending bool,
 	ctx context.Context,
 ) (decimal.Decimal, decimal.Decimal, error) {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "DeductBalance", time.Now())
 	objectId, err := utils.StringToObjectId(bankAccountId)
 	defaultDecimal := decimal.NewFromInt(0)
 	if err != nil {
@@ -113,11 +159,12 @@ func (ar *AccountRepositoryMongodb) DeductBalance(
 	result, err := ar.col.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return defaultDecimal, defaultDecimal,
-			fmt.Errorf("error when updating account balance for bankAccountId %s: %w", bankAccountId, err)
+			fmt.Errorf("error when updating account balance for bankAccountId %s: %w", bankAccountId,
+				utils.ClassifyMongoError(err))
 	}
 	if result.MatchedCount == 0 {
 		return defaultDecimal, defaultDecimal,
-			fmt.Errorf("no matching account found for bankAccountId %s", bankAccountId)
+			fmt.Errorf("%w for bankAccountId %s", model.ErrNoMatchingBankAccount, bankAccountId)
 	} else if result.ModifiedCount == 0 {
 		return defaultDecimal, defaultDecimal,
 			fmt.Errorf("update failed to the account balance for bankAccountId %s", bankAccountId)
@@ -135,6 +182,7 @@ func (ar *AccountRepositoryMongodb) GetAccountBalance(
 	bankAccountId string,
 	ctx context.Context,
 ) (decimal.Decimal, decimal.Decimal, error) {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "GetAccountBalance", time.Now())
 	objectId, err := utils.StringToObjectId(bankAccountId)
 	defaultDecimal := decimal.NewFromInt(0)
 	if err != nil {
@@ -148,7 +196,7 @@ func (ar *AccountRepositoryMongodb) GetAccountBalance(
 	err = ar.col.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&account)
 	if err != nil {
 		return defaultDecimal, defaultDecimal,
-			fmt.Errorf("error when finding account by ID %s: %w", bankAccountId, err)
+			fmt.Errorf("error when finding account by ID %s: %w", bankAccountId, utils.ClassifyMongoError(err))
 	}
 	availableBalanceDecimal, err := utils.FromPrimitiveDecimal128ToDecimal(account.BankAccounts[0].AvailableBalance)
 	if err != nil {
@@ -165,18 +213,335 @@ func (ar *AccountRepositoryMongodb) GetAccountBalance(
 	return availableBalanceDecimal, pendingBalanceDecimal, nil
 }
 
+func (ar *AccountRepositoryMongodb) GetAllBankAccountBalances(ctx context.Context) ([]model.BankAccountBalance, error) {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "GetAllBankAccountBalances", time.Now())
+	pipeline := mongo.Pipeline{
+		{{"$unwind", "$bankAccounts"}},
+		{{"$replaceRoot", bson.D{{"newRoot", "$bankAccounts"}}}},
+		{{"$project", bson.D{
+			{"_id", 1},
+			{"accountNumber", 1},
+			{"availableBalance", 1},
+			{"pendingBalance", 1},
+		}}},
+	}
+	cursor, err := ar.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error when aggregating bank account balances: %w", utils.ClassifyMongoError(err))
+	}
+
+	defer func() {
+		err := cursor.Close(ctx)
+		if err != nil {
+			ar.logger.ErrorContext(ctx, "Unable to close cursor when getting bank account balances", logging.Err(err))
+		}
+	}()
+
+	var mongoResults []mongodb.MongoBankAccountBalance
+	if err = cursor.All(ctx, &mongoResults); err != nil {
+		return nil, fmt.Errorf("error when iterating over mongo Cursor when getting bank account balances: %w",
+			utils.ClassifyMongoError(err))
+	}
+	res := make([]model.BankAccountBalance, len(mongoResults))
+	for i, elem := range mongoResults {
+		availableBalance, err := utils.FromPrimitiveDecimal128ToDecimal(elem.AvailableBalance)
+		if err != nil {
+			return nil, fmt.Errorf("error when converting available balance to decimal for bankAccountId "+
+				"%s: %w", elem.Id.Hex(), err)
+		}
+		pendingBalance, err := utils.FromPrimitiveDecimal128ToDecimal(elem.PendingBalance)
+		if err != nil {
+			return nil, fmt.Errorf("error when converting pending balance to decimal for bankAccountId "+
+				"%s: %w", elem.Id.Hex(), err)
+		}
+		res[i] = model.BankAccountBalance{
+			BankAccountId:    elem.Id.Hex(),
+			AccountNumber:    elem.AccountNumber,
+			AvailableBalance: availableBalance,
+			PendingBalance:   pendingBalance,
+		}
+	}
+	return res, nil
+}
+
 func (ar *AccountRepositoryMongodb) GetAccountDetailsFromUsername(
 	username string,
 	ctx context.Context,
 ) (*model.AccountDetailsOutput, error) {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "GetAccountDetailsFromUsername", time.Now())
 	var accountDetails mongodb.MongoAccountOutput
 	filter := bson.M{"username": username}
-	err := ar.col.FindOne(ctx, filter).Decode(&accountDetails)
+	opts := options.FindOne().SetProjection(accountDetailsProjection)
+	err := ar.col.FindOne(ctx, filter, opts).Decode(&accountDetails)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, model.ErrNoMatchingUsername
 		}
-		return nil, fmt.Errorf("error when finding account by username: %s", err.Error())
+		return nil, fmt.Errorf("error when finding account by username: %w", utils.ClassifyMongoError(err))
 	}
 	return fromMongoAccountDetails(&accountDetails)
 }
+
+func (ar *AccountRepositoryMongodb) GetAccountCredentialsFromUsername(
+	username string,
+	ctx context.Context,
+) (*model.AccountCredentialsOutput, error) {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "GetAccountCredentialsFromUsername", time.Now())
+	var credentials mongodb.MongoAccountCredentialsOutput
+	filter := bson.M{"username": username}
+	projection := bson.M{"_id": 1, "username": 1, "password": 1}
+	err := ar.col.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&credentials)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, model.ErrNoMatchingUsername
+		}
+		return nil, fmt.Errorf("error when finding account credentials by username: %w", utils.ClassifyMongoError(err))
+	}
+	accountId, err := utils.ObjectIdToString(credentials.Id)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting object ID to string for username %s: %w", username, err)
+	}
+	return &model.AccountCredentialsOutput{
+		Id:           accountId,
+		Username:     credentials.Username,
+		PasswordHash: credentials.Password,
+	}, nil
+}
+
+func (ar *AccountRepositoryMongodb) UpdatePasswordHash(
+	accountId string,
+	passwordHash string,
+	ctx context.Context,
+) error {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "UpdatePasswordHash", time.Now())
+	objectId, err := utils.StringToObjectId(accountId)
+	if err != nil {
+		return fmt.Errorf("error when converting account ID to object ID for accountId %s: %w", accountId, err)
+	}
+	result, err := ar.col.UpdateOne(ctx, bson.M{"_id": objectId}, bson.M{"$set": bson.M{"password": passwordHash}})
+	if err != nil {
+		return fmt.Errorf("error when updating password hash for accountId %s: %w", accountId,
+			utils.ClassifyMongoError(err))
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w for accountId %s", model.ErrNoMatchingAccount, accountId)
+	}
+	ar.logger.DebugContext(ctx, "Updated password hash", logging.AccountId(accountId))
+	return nil
+}
+
+func (ar *AccountRepositoryMongodb) AddAccount(input *model.AccountInput, ctx context.Context) (string, error) {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "AddAccount", time.Now())
+	mongoInput := mongodb.MongoAccountInput{
+		Username: input.Username,
+		Password: input.PasswordHash,
+		Person: mongodb.Person{
+			FirstName: input.Person.FirstName,
+			LastName:  input.Person.LastName,
+		},
+		BankAccounts:      []mongodb.BankAccount{},
+		KnownBankAccounts: []mongodb.KnownBankAccount{},
+		CreatedAt:         utils.GetCurrentTimestamp(),
+	}
+	result, err := ar.col.InsertOne(ctx, mongoInput)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", model.ErrUsernameTaken
+		}
+		return "", fmt.Errorf("error when inserting account for username %s: %w", input.Username,
+			utils.ClassifyMongoError(err))
+	}
+	accountId, err := utils.ObjectIdToString(result.InsertedID)
+	if err != nil {
+		return "", fmt.Errorf("error when converting inserted account ID to string for username %s: %w",
+			input.Username, err)
+	}
+	ar.logger.DebugContext(ctx, "Inserted account", logging.AccountId(accountId),
+		slog.String(logging.UsernameKey, input.Username))
+	return accountId, nil
+}
+
+func (ar *AccountRepositoryMongodb) AddBankAccount(
+	accountId string,
+	input *model.BankAccountInput,
+	ctx context.Context,
+) (string, error) {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "AddBankAccount", time.Now())
+	objectId, err := utils.StringToObjectId(accountId)
+	if err != nil {
+		return "", fmt.Errorf("error when converting account ID to object ID for accountId %s: %w", accountId, err)
+	}
+	zeroBalance, err := utils.FromDecimalToPrimitiveDecimal128(decimal.Zero)
+	if err != nil {
+		return "", fmt.Errorf("error when converting zero balance to Decimal128: %w", err)
+	}
+	bankAccount := mongodb.BankAccount{
+		Id:               primitive.NewObjectID(),
+		AccountNumber:    input.AccountNumber,
+		AccountType:      string(input.AccountType),
+		PendingBalance:   zeroBalance,
+		AvailableBalance: zeroBalance,
+	}
+	update := bson.M{"$push": bson.M{"bankAccounts": bankAccount}}
+	result, err := ar.col.UpdateOne(ctx, bson.M{"_id": objectId}, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", model.ErrAccountNumberTaken
+		}
+		return "", fmt.Errorf("error when adding bank account to accountId %s: %w", accountId,
+			utils.ClassifyMongoError(err))
+	}
+	if result.MatchedCount == 0 {
+		return "", model.ErrNoMatchingAccount
+	}
+	bankAccountId, err := utils.ObjectIdToString(bankAccount.Id)
+	if err != nil {
+		return "", fmt.Errorf("error when converting bank account ID to string for accountId %s: %w", accountId, err)
+	}
+	ar.logger.DebugContext(ctx, "Added bank account", logging.BankAccountId(bankAccountId),
+		logging.AccountId(accountId))
+	return bankAccountId, nil
+}
+
+func (ar *AccountRepositoryMongodb) GetAccountDetailsFromAccountNumber(
+	accountNumber string,
+	ctx context.Context,
+) (*model.AccountDetailsOutput, error) {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "GetAccountDetailsFromAccountNumber", time.Now())
+	var accountDetails mongodb.MongoAccountOutput
+	filter := bson.M{"bankAccounts.accountNumber": accountNumber}
+	opts := options.FindOne().SetProjection(accountDetailsProjection)
+	err := ar.col.FindOne(ctx, filter, opts).Decode(&accountDetails)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, model.ErrNoMatchingBankAccount
+		}
+		return nil, fmt.Errorf("error when finding account by account number %s: %w", accountNumber,
+			utils.ClassifyMongoError(err))
+	}
+	res, err := fromMongoAccountDetails(&accountDetails)
+	if err != nil {
+		return nil, err
+	}
+	ar.logger.DebugContext(ctx, "Retrieved account details by account number", logging.AccountNumber(accountNumber))
+	return res, nil
+}
+
+func (ar *AccountRepositoryMongodb) GetKnownBankAccounts(
+	accountId string,
+	ctx context.Context,
+) ([]model.KnownBankAccount, error) {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "GetKnownBankAccounts", time.Now())
+	objectId, err := utils.StringToObjectId(accountId)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting account ID to object ID for accountId %s: %w", accountId, err)
+	}
+	var accountDetails mongodb.MongoAccountOutput
+	opts := options.FindOne().SetProjection(bson.M{"knownBankAccounts": 1})
+	err = ar.col.FindOne(ctx, bson.M{"_id": objectId}, opts).Decode(&accountDetails)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, model.ErrNoMatchingAccount
+		}
+		return nil, fmt.Errorf("error when finding known bank accounts for accountId %s: %w", accountId,
+			utils.ClassifyMongoError(err))
+	}
+	return fromMongoKnownAccount(accountDetails.KnownBankAccounts)
+}
+
+// AddKnownBankAccount stores the bank account as a payee of the account. The payee is identified by the ID of its
+// bank account, so a bank account can only be known once.
+func (ar *AccountRepositoryMongodb) AddKnownBankAccount(
+	accountId string,
+	knownBankAccount *model.KnownBankAccount,
+	ctx context.Context,
+) error {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "AddKnownBankAccount", time.Now())
+	objectId, err := utils.StringToObjectId(accountId)
+	if err != nil {
+		return fmt.Errorf("error when converting account ID to object ID for accountId %s: %w", accountId, err)
+	}
+	knownObjectId, err := utils.StringToObjectId(knownBankAccount.Id)
+	if err != nil {
+		return fmt.Errorf("error when converting known bank account ID to object ID for accountId %s: %w",
+			accountId, err)
+	}
+	filter := bson.M{"_id": objectId, "knownBankAccounts._id": bson.M{"$ne": knownObjectId}}
+	update := bson.M{"$push": bson.M{"knownBankAccounts": mongodb.KnownBankAccount{
+		Id:            knownObjectId,
+		AccountNumber: knownBankAccount.AccountNumber,
+		AccountHolder: knownBankAccount.AccountHolder,
+		AccountType:   string(knownBankAccount.AccountType),
+		Nickname:      knownBankAccount.Nickname,
+	}}}
+	result, err := ar.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("error when adding known bank account to accountId %s: %w", accountId,
+			utils.ClassifyMongoError(err))
+	}
+	if result.MatchedCount == 0 {
+		return model.ErrPayeeAlreadyKnown
+	}
+	ar.logger.DebugContext(ctx, "Added payee", slog.String(logging.PayeeIdKey, knownBankAccount.Id),
+		logging.AccountId(accountId))
+	return nil
+}
+
+func (ar *AccountRepositoryMongodb) UpdateKnownBankAccountNickname(
+	accountId string,
+	knownBankAccountId string,
+	nickname string,
+	ctx context.Context,
+) error {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "UpdateKnownBankAccountNickname", time.Now())
+	filter, err := knownBankAccountFilter(accountId, knownBankAccountId)
+	if err != nil {
+		return err
+	}
+	update := bson.M{"$set": bson.M{"knownBankAccounts.$.nickname": nickname}}
+	result, err := ar.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("error when updating nickname of known BankAccount %s for accountId %s: %w",
+			knownBankAccountId, accountId, utils.ClassifyMongoError(err))
+	}
+	if result.MatchedCount == 0 {
+		return model.ErrNoMatchingPayee
+	}
+	return nil
+}
+
+func (ar *AccountRepositoryMongodb) RemoveKnownBankAccount(
+	accountId string,
+	knownBankAccountId string,
+	ctx context.Context,
+) error {
+	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "RemoveKnownBankAccount", time.Now())
+	filter, err := knownBankAccountFilter(accountId, knownBankAccountId)
+	if err != nil {
+		return err
+	}
+	update := bson.M{"$pull": bson.M{"knownBankAccounts": bson.M{"_id": filter["knownBankAccounts._id"]}}}
+	result, err := ar.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("error when removing known BankAccount %s from accountId %s: %w",
+			knownBankAccountId, accountId, utils.ClassifyMongoError(err))
+	}
+	if result.MatchedCount == 0 {
+		return model.ErrNoMatchingPayee
+	}
+	ar.logger.DebugContext(ctx, "Removed payee", slog.String(logging.PayeeIdKey, knownBankAccountId),
+		logging.AccountId(accountId))
+	return nil
+}
+
+func knownBankAccountFilter(accountId string, knownBankAccountId string) (bson.M, error) {
+	objectId, err := utils.StringToObjectId(accountId)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting account ID to object ID for accountId %s: %w", accountId, err)
+	}
+	knownObjectId, err := utils.StringToObjectId(knownBankAccountId)
+	if err != nil {
+		return nil, model.ErrNoMatchingPayee
+	}
+	return bson.M{"_id": objectId, "knownBankAccounts._id": knownObjectId}, nil
+}
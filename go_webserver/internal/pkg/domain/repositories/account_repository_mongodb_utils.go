@@ -40,6 +40,7 @@ func fromMongoKnownAccount(knownAccount []mongodb.KnownBankAccount) ([]model.Kno
 			AccountNumber: ka.AccountNumber,
 			AccountHolder: ka.AccountHolder,
 			AccountType:   accountType,
+			Nickname:      ka.Nickname,
 		}
 	}
 	return res, nil
@@ -102,7 +103,6 @@ func fromMongoAccountDetails(details *mongodb.MongoAccountOutput) (*model.Accoun
 	return &model.AccountDetailsOutput{
 		Id:       accountId,
 		Username: details.Username,
-		Password: details.Password,
 		Person: model.Person{
 			FirstName: details.Person.FirstName,
 			LastName:  details.Person.LastName,
@@ -0,0 +1,414 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
+)
+
+type AccountRepositorySQLite struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func CreateNewAccountRepositorySQLite(db *sql.DB, logger *slog.Logger) *AccountRepositorySQLite {
+	return &AccountRepositorySQLite{db: db, logger: logger}
+}
+
+func (ar *AccountRepositorySQLite) GetAccountDetailsFromBankAccountId(
+	bankAccountId string,
+	ctx context.Context,
+) (*model.AccountDetailsOutput, error) {
+	if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+		return nil, fmt.Errorf("error when converting account ID to object ID for "+
+			"bankAccountId %s: %w", bankAccountId, err)
+	}
+	res, err := ar.findAccountDetails(ctx, model.ErrNoMatchingBankAccount,
+		"id = (SELECT account_id FROM bank_account WHERE id = ?)", bankAccountId)
+	if err != nil {
+		return nil, err
+	}
+	ar.logger.DebugContext(ctx, "Retrieved account details", logging.BankAccountId(bankAccountId))
+	return res, nil
+}
+
+func (ar *AccountRepositorySQLite) GetAccountIdFromBankAccountId(
+	bankAccountId string,
+	ctx context.Context,
+) (string, error) {
+	if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+		return "", fmt.Errorf("error when converting account ID to object ID for "+
+			"bankAccountId %s: %w", bankAccountId, err)
+	}
+	var accountId string
+	err := sqlite.Executor(ctx, ar.db).QueryRowContext(ctx, `SELECT account_id FROM bank_account WHERE id = ?`,
+		bankAccountId).Scan(&accountId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", model.ErrNoMatchingBankAccount
+	}
+	if err != nil {
+		return "", fmt.Errorf("error when finding account of BankAccount %s: %w", bankAccountId, err)
+	}
+	return accountId, nil
+}
+
+func (ar *AccountRepositorySQLite) AddBalance(
+	bankAccountId string,
+	amount decimal.Decimal,
+	toPending bool,
+	ctx context.Context,
+) error {
+	_, _, err := ar.updateBalance(bankAccountId, amount, toPending, ctx)
+	return err
+}
+
+func (ar *AccountRepositorySQLite) DeductBalance(
+	bankAccountId string,
+	amount decimal.Decimal,
+	toPending bool,
+	ctx context.Context,
+) (decimal.Decimal, decimal.Decimal, error) {
+	return ar.updateBalance(bankAccountId, amount.Neg(), toPending, ctx)
+}
+
+// updateBalance adds the amount to the pending balance of the bank account, and to its available balance unless
+// only the pending balance is to change. The balances are updated by a single statement, so that concurrent updates
+// are never lost. It returns the updated available and pending balances.
+func (ar *AccountRepositorySQLite) updateBalance(
+	bankAccountId string,
+	amount decimal.Decimal,
+	toPending bool,
+	ctx context.Context,
+) (decimal.Decimal, decimal.Decimal, error) {
+	defaultDecimal := decimal.NewFromInt(0)
+	if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+		return defaultDecimal, defaultDecimal,
+			fmt.Errorf("error when converting account ID to object ID for bankAccountId "+
+				"%s: %w", bankAccountId, err)
+	}
+	var available, pending decimal.Decimal
+	err := sqlite.Executor(ctx, ar.db).QueryRowContext(ctx, `UPDATE bank_account
+		SET pending_balance = decimal_add(pending_balance, ?),
+			available_balance = CASE WHEN ? THEN available_balance ELSE decimal_add(available_balance, ?) END
+		WHERE id = ?
+		RETURNING available_balance, pending_balance`,
+		amount, toPending, amount, bankAccountId).Scan(&available, &pending)
+	if errors.Is(err, sql.ErrNoRows) {
+		return defaultDecimal, defaultDecimal, fmt.Errorf("%w for bankAccountId %s", model.ErrNoMatchingBankAccount,
+			bankAccountId)
+	}
+	if err != nil {
+		return defaultDecimal, defaultDecimal,
+			fmt.Errorf("error when updating account balance for bankAccountId %s: %w", bankAccountId, err)
+	}
+	return available, pending, nil
+}
+
+func (ar *AccountRepositorySQLite) GetAccountBalance(
+	bankAccountId string,
+	ctx context.Context,
+) (decimal.Decimal, decimal.Decimal, error) {
+	defaultDecimal := decimal.NewFromInt(0)
+	if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+		return defaultDecimal, defaultDecimal,
+			fmt.Errorf("error when converting account ID to object ID for bankAccountId "+
+				"%s: %w", bankAccountId, err)
+	}
+	var available, pending decimal.Decimal
+	err := sqlite.Executor(ctx, ar.db).QueryRowContext(ctx,
+		`SELECT available_balance, pending_balance FROM bank_account WHERE id = ?`,
+		bankAccountId).Scan(&available, &pending)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = model.ErrNoMatchingBankAccount
+	}
+	if err != nil {
+		return defaultDecimal, defaultDecimal,
+			fmt.Errorf("error when finding account by ID %s: %w", bankAccountId, err)
+	}
+	return available, pending, nil
+}
+
+func (ar *AccountRepositorySQLite) GetAllBankAccountBalances(ctx context.Context) ([]model.BankAccountBalance, error) {
+	rows, err := sqlite.Executor(ctx, ar.db).QueryContext(ctx,
+		`SELECT id, account_number, available_balance, pending_balance FROM bank_account ORDER BY rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("error when getting bank account balances: %w", err)
+	}
+	defer rows.Close()
+	res := make([]model.BankAccountBalance, 0)
+	for rows.Next() {
+		var balance model.BankAccountBalance
+		err = rows.Scan(&balance.BankAccountId, &balance.AccountNumber, &balance.AvailableBalance,
+			&balance.PendingBalance)
+		if err != nil {
+			return nil, fmt.Errorf("error when getting bank account balances: %w", err)
+		}
+		res = append(res, balance)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error when getting bank account balances: %w", err)
+	}
+	return res, nil
+}
+
+func (ar *AccountRepositorySQLite) GetAccountDetailsFromUsername(
+	username string,
+	ctx context.Context,
+) (*model.AccountDetailsOutput, error) {
+	return ar.findAccountDetails(ctx, model.ErrNoMatchingUsername, "username = ?", username)
+}
+
+func (ar *AccountRepositorySQLite) GetAccountCredentialsFromUsername(
+	username string,
+	ctx context.Context,
+) (*model.AccountCredentialsOutput, error) {
+	var res model.AccountCredentialsOutput
+	err := sqlite.Executor(ctx, ar.db).QueryRowContext(ctx,
+		`SELECT id, username, password FROM account WHERE username = ?`,
+		username).Scan(&res.Id, &res.Username, &res.PasswordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNoMatchingUsername
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error when finding account by username: %w", err)
+	}
+	return &res, nil
+}
+
+func (ar *AccountRepositorySQLite) UpdatePasswordHash(
+	accountId string,
+	passwordHash string,
+	ctx context.Context,
+) error {
+	if _, err := utils.StringToObjectId(accountId); err != nil {
+		return fmt.Errorf("error when converting account ID to object ID for accountId %s: %w", accountId, err)
+	}
+	res, err := sqlite.Executor(ctx, ar.db).ExecContext(ctx, `UPDATE account SET password = ? WHERE id = ?`,
+		passwordHash, accountId)
+	if err != nil {
+		return fmt.Errorf("error when updating password hash for accountId %s: %w", accountId, err)
+	}
+	if updated, err := res.RowsAffected(); err != nil || updated == 0 {
+		return fmt.Errorf("%w for accountId %s", model.ErrNoMatchingAccount, accountId)
+	}
+	ar.logger.DebugContext(ctx, "Updated password hash", logging.AccountId(accountId))
+	return nil
+}
+
+func (ar *AccountRepositorySQLite) AddAccount(input *model.AccountInput, ctx context.Context) (string, error) {
+	accountId := primitive.NewObjectID().Hex()
+	_, err := sqlite.Executor(ctx, ar.db).ExecContext(ctx, `INSERT INTO account
+		(id, username, password, first_name, last_name, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		accountId, input.Username, input.PasswordHash, input.Person.FirstName, input.Person.LastName,
+		toSQLiteTime(currentTime()))
+	if err != nil {
+		if sqlite.IsUniqueViolation(err) {
+			return "", model.ErrUsernameTaken
+		}
+		return "", fmt.Errorf("error when inserting account for username %s: %w", input.Username, err)
+	}
+	ar.logger.DebugContext(ctx, "Inserted account", logging.AccountId(accountId),
+		slog.String(logging.UsernameKey, input.Username))
+	return accountId, nil
+}
+
+func (ar *AccountRepositorySQLite) AddBankAccount(
+	accountId string,
+	input *model.BankAccountInput,
+	ctx context.Context,
+) (string, error) {
+	if _, err := utils.StringToObjectId(accountId); err != nil {
+		return "", fmt.Errorf("error when converting account ID to object ID for accountId %s: %w", accountId, err)
+	}
+	bankAccountId := primitive.NewObjectID().Hex()
+	res, err := sqlite.Executor(ctx, ar.db).ExecContext(ctx, `INSERT INTO bank_account
+		(id, account_id, account_number, account_type, available_balance, pending_balance)
+		SELECT ?, id, ?, ?, ?, ? FROM account WHERE id = ?`,
+		bankAccountId, input.AccountNumber, string(input.AccountType), decimal.Zero, decimal.Zero, accountId)
+	if err != nil {
+		if sqlite.IsUniqueViolation(err) {
+			return "", model.ErrAccountNumberTaken
+		}
+		return "", fmt.Errorf("error when adding BankAccount to account %s: %w", accountId, err)
+	}
+	if inserted, err := res.RowsAffected(); err != nil || inserted == 0 {
+		return "", model.ErrNoMatchingAccount
+	}
+	ar.logger.DebugContext(ctx, "Added bank account", logging.BankAccountId(bankAccountId),
+		logging.AccountId(accountId))
+	return bankAccountId, nil
+}
+
+func (ar *AccountRepositorySQLite) GetAccountDetailsFromAccountNumber(
+	accountNumber string,
+	ctx context.Context,
+) (*model.AccountDetailsOutput, error) {
+	res, err := ar.findAccountDetails(ctx, model.ErrNoMatchingBankAccount,
+		"id = (SELECT account_id FROM bank_account WHERE account_number = ?)", accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	ar.logger.DebugContext(ctx, "Retrieved account details by account number", logging.AccountNumber(accountNumber))
+	return res, nil
+}
+
+func (ar *AccountRepositorySQLite) GetKnownBankAccounts(
+	accountId string,
+	ctx context.Context,
+) ([]model.KnownBankAccount, error) {
+	if _, err := utils.StringToObjectId(accountId); err != nil {
+		return nil, fmt.Errorf("error when converting account ID to object ID for accountId %s: %w", accountId, err)
+	}
+	var res []model.KnownBankAccount
+	err := sqlite.Read(ctx, ar.db, func(q sqlite.Querier) error {
+		var found int
+		err := q.QueryRowContext(ctx, `SELECT count(*) FROM account WHERE id = ?`, accountId).Scan(&found)
+		if err != nil {
+			return fmt.Errorf("error when finding known bank accounts for accountId %s: %w", accountId, err)
+		}
+		if found == 0 {
+			return model.ErrNoMatchingAccount
+		}
+		res, err = findKnownBankAccounts(q, accountId, ctx)
+		if err != nil {
+			return fmt.Errorf("error when finding known bank accounts for accountId %s: %w", accountId, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// AddKnownBankAccount stores the bank account as a payee of the account. The payee is identified by the ID of its
+// bank account, so a bank account can only be known once.
+func (ar *AccountRepositorySQLite) AddKnownBankAccount(
+	accountId string,
+	knownBankAccount *model.KnownBankAccount,
+	ctx context.Context,
+) error {
+	if _, err := utils.StringToObjectId(knownBankAccount.Id); err != nil {
+		return fmt.Errorf("error when converting known bank account ID to object ID for accountId %s: %w",
+			accountId, err)
+	}
+	if _, err := utils.StringToObjectId(accountId); err != nil {
+		return fmt.Errorf("error when converting account ID to object ID for accountId %s: %w", accountId, err)
+	}
+	res, err := sqlite.Executor(ctx, ar.db).ExecContext(ctx, `INSERT INTO known_bank_account
+		(account_id, id, account_number, account_holder, account_type, nickname)
+		SELECT id, ?, ?, ?, ?, ? FROM account WHERE id = ?`,
+		knownBankAccount.Id, knownBankAccount.AccountNumber, knownBankAccount.AccountHolder,
+		string(knownBankAccount.AccountType), knownBankAccount.Nickname, accountId)
+	if err != nil {
+		if sqlite.IsUniqueViolation(err) {
+			return model.ErrPayeeAlreadyKnown
+		}
+		return fmt.Errorf("error when adding known BankAccount %s to account %s: %w", knownBankAccount.Id,
+			accountId, err)
+	}
+	// Like the MongoDB filter on the account and payee, a missing account is reported as an already known payee
+	if inserted, err := res.RowsAffected(); err != nil || inserted == 0 {
+		return model.ErrPayeeAlreadyKnown
+	}
+	ar.logger.DebugContext(ctx, "Added payee", slog.String(logging.PayeeIdKey, knownBankAccount.Id),
+		logging.AccountId(accountId))
+	return nil
+}
+
+func (ar *AccountRepositorySQLite) UpdateKnownBankAccountNickname(
+	accountId string,
+	knownBankAccountId string,
+	nickname string,
+	ctx context.Context,
+) error {
+	return ar.updateKnownBankAccount(accountId, knownBankAccountId, ctx,
+		`UPDATE known_bank_account SET nickname = ? WHERE account_id = ? AND id = ?`,
+		nickname, accountId, knownBankAccountId)
+}
+
+func (ar *AccountRepositorySQLite) RemoveKnownBankAccount(
+	accountId string,
+	knownBankAccountId string,
+	ctx context.Context,
+) error {
+	err := ar.updateKnownBankAccount(accountId, knownBankAccountId, ctx,
+		`DELETE FROM known_bank_account WHERE account_id = ? AND id = ?`, accountId, knownBankAccountId)
+	if err != nil {
+		return err
+	}
+	ar.logger.DebugContext(ctx, "Removed payee", slog.String(logging.PayeeIdKey, knownBankAccountId),
+		logging.AccountId(accountId))
+	return nil
+}
+
+// updateKnownBankAccount runs the statement changing a known bank account of the account, reporting a statement that
+// changed no row as a missing payee
+func (ar *AccountRepositorySQLite) updateKnownBankAccount(
+	accountId string,
+	knownBankAccountId string,
+	ctx context.Context,
+	statement string,
+	args ...any,
+) error {
+	if _, err := utils.StringToObjectId(accountId); err != nil {
+		return fmt.Errorf("error when converting account ID to object ID for accountId %s: %w", accountId, err)
+	}
+	if _, err := utils.StringToObjectId(knownBankAccountId); err != nil {
+		return model.ErrNoMatchingPayee
+	}
+	res, err := sqlite.Executor(ctx, ar.db).ExecContext(ctx, statement, args...)
+	if err != nil {
+		return fmt.Errorf("error when updating known BankAccount %s of account %s: %w", knownBankAccountId,
+			accountId, err)
+	}
+	if updated, err := res.RowsAffected(); err != nil || updated == 0 {
+		return model.ErrNoMatchingPayee
+	}
+	return nil
+}
+
+// findAccountDetails reads the account matching the condition on the account table along with its bank accounts and
+// known bank accounts, or returns errNotFound when there is none
+func (ar *AccountRepositorySQLite) findAccountDetails(
+	ctx context.Context,
+	errNotFound error,
+	condition string,
+	args ...any,
+) (*model.AccountDetailsOutput, error) {
+	var res *model.AccountDetailsOutput
+	err := sqlite.Read(ctx, ar.db, func(q sqlite.Querier) error {
+		var account model.AccountDetailsOutput
+		var createdAt int64
+		err := q.QueryRowContext(ctx, `SELECT id, username, first_name, last_name, created_at FROM account WHERE `+
+			condition, args...).Scan(&account.Id, &account.Username, &account.Person.FirstName,
+			&account.Person.LastName, &createdAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("error when finding account: %w", err)
+		}
+		account.CreatedAt = fromSQLiteTime(createdAt)
+		if account.BankAccounts, err = findBankAccounts(q, account.Id, ctx); err != nil {
+			return fmt.Errorf("error when finding bank accounts of account %s: %w", account.Id, err)
+		}
+		if account.KnownBankAccounts, err = findKnownBankAccounts(q, account.Id, ctx); err != nil {
+			return fmt.Errorf("error when finding known bank accounts of account %s: %w", account.Id, err)
+		}
+		res = &account
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
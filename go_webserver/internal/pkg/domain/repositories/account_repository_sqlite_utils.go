@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/utils"
+)
+
+// toSQLiteTime stores times as Unix seconds, which is the precision MongoDB timestamps are stored at, so that both
+// backends list and filter rows by the same times
+func toSQLiteTime(tm time.Time) int64 {
+	return int64(utils.TimeToTimestamp(tm).T)
+}
+
+func fromSQLiteTime(seconds int64) time.Time {
+	return utils.TimestampToTime(primitive.Timestamp{T: uint32(seconds)})
+}
+
+func findBankAccounts(q sqlite.Querier, accountId string, ctx context.Context) ([]model.BankAccount, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id, account_number, account_type, pending_balance, available_balance
+		FROM bank_account WHERE account_id = ? ORDER BY rowid`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := make([]model.BankAccount, 0)
+	for rows.Next() {
+		var bankAccount model.BankAccount
+		err = rows.Scan(&bankAccount.Id, &bankAccount.AccountNumber, &bankAccount.AccountType,
+			&bankAccount.PendingBalance, &bankAccount.AvailableBalance)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, bankAccount)
+	}
+	return res, rows.Err()
+}
+
+func findKnownBankAccounts(q sqlite.Querier, accountId string, ctx context.Context) ([]model.KnownBankAccount, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id, account_number, account_holder, account_type, nickname
+		FROM known_bank_account WHERE account_id = ? ORDER BY rowid`, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := make([]model.KnownBankAccount, 0)
+	for rows.Next() {
+		var known model.KnownBankAccount
+		err = rows.Scan(&known.Id, &known.AccountNumber, &known.AccountHolder, &known.AccountType, &known.Nickname)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, known)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/tracing"
+)
+
+// AccountRepositoryTraced records a span for every call to the account repository it wraps
+type AccountRepositoryTraced struct {
+	next   AccountRepository
+	tracer trace.Tracer
+}
+
+func CreateNewAccountRepositoryTraced(next AccountRepository, tracer trace.Tracer) *AccountRepositoryTraced {
+	return &AccountRepositoryTraced{next: next, tracer: tracer}
+}
+
+func (ar *AccountRepositoryTraced) GetAccountDetailsFromBankAccountId(
+	bankAccountId string,
+	ctx context.Context,
+) (_ *model.AccountDetailsOutput, err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.GetAccountDetailsFromBankAccountId")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.GetAccountDetailsFromBankAccountId(bankAccountId, ctx)
+}
+
+func (ar *AccountRepositoryTraced) GetAccountIdFromBankAccountId(
+	bankAccountId string,
+	ctx context.Context,
+) (_ string, err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.GetAccountIdFromBankAccountId")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.GetAccountIdFromBankAccountId(bankAccountId, ctx)
+}
+
+func (ar *AccountRepositoryTraced) AddBalance(
+	bankAccountId string,
+	amount decimal.Decimal,
+	toPending bool,
+	ctx context.Context,
+) (err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.AddBalance")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.AddBalance(bankAccountId, amount, toPending, ctx)
+}
+
+func (ar *AccountRepositoryTraced) DeductBalance(
+	bankAccountId string,
+	amount decimal.Decimal,
+	toPending bool,
+	ctx context.Context,
+) (_ decimal.Decimal, _ decimal.Decimal, err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.DeductBalance")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.DeductBalance(bankAccountId, amount, toPending, ctx)
+}
+
+func (ar *AccountRepositoryTraced) GetAccountDetailsFromUsername(
+	username string,
+	ctx context.Context,
+) (_ *model.AccountDetailsOutput, err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.GetAccountDetailsFromUsername")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.GetAccountDetailsFromUsername(username, ctx)
+}
+
+func (ar *AccountRepositoryTraced) GetAccountCredentialsFromUsername(
+	username string,
+	ctx context.Context,
+) (_ *model.AccountCredentialsOutput, err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.GetAccountCredentialsFromUsername")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.GetAccountCredentialsFromUsername(username, ctx)
+}
+
+func (ar *AccountRepositoryTraced) UpdatePasswordHash(
+	accountId string,
+	passwordHash string,
+	ctx context.Context,
+) (err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.UpdatePasswordHash")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.UpdatePasswordHash(accountId, passwordHash, ctx)
+}
+
+func (ar *AccountRepositoryTraced) GetAccountBalance(
+	bankAccountId string,
+	ctx context.Context,
+) (_ decimal.Decimal, _ decimal.Decimal, err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.GetAccountBalance")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.GetAccountBalance(bankAccountId, ctx)
+}
+
+func (ar *AccountRepositoryTraced) AddAccount(input *model.AccountInput, ctx context.Context) (_ string, err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.AddAccount")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.AddAccount(input, ctx)
+}
+
+func (ar *AccountRepositoryTraced) AddBankAccount(
+	accountId string,
+	input *model.BankAccountInput,
+	ctx context.Context,
+) (_ string, err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.AddBankAccount")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.AddBankAccount(accountId, input, ctx)
+}
+
+func (ar *AccountRepositoryTraced) GetAccountDetailsFromAccountNumber(
+	accountNumber string,
+	ctx context.Context,
+) (_ *model.AccountDetailsOutput, err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.GetAccountDetailsFromAccountNumber")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.GetAccountDetailsFromAccountNumber(accountNumber, ctx)
+}
+
+func (ar *AccountRepositoryTraced) GetKnownBankAccounts(
+	accountId string,
+	ctx context.Context,
+) (_ []model.KnownBankAccount, err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.GetKnownBankAccounts")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.GetKnownBankAccounts(accountId, ctx)
+}
+
+func (ar *AccountRepositoryTraced) AddKnownBankAccount(
+	accountId string,
+	knownBankAccount *model.KnownBankAccount,
+	ctx context.Context,
+) (err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.AddKnownBankAccount")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.AddKnownBankAccount(accountId, knownBankAccount, ctx)
+}
+
+func (ar *AccountRepositoryTraced) UpdateKnownBankAccountNickname(
+	accountId string,
+	knownBankAccountId string,
+	nickname string,
+	ctx context.Context,
+) (err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.UpdateKnownBankAccountNickname")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.UpdateKnownBankAccountNickname(accountId, knownBankAccountId, nickname, ctx)
+}
+
+func (ar *AccountRepositoryTraced) RemoveKnownBankAccount(
+	accountId string,
+	knownBankAccountId string,
+	ctx context.Context,
+) (err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.RemoveKnownBankAccount")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.RemoveKnownBankAccount(accountId, knownBankAccountId, ctx)
+}
+
+func (ar *AccountRepositoryTraced) GetAllBankAccountBalances(
+	ctx context.Context,
+) (_ []model.BankAccountBalance, err error) {
+	ctx, span := ar.tracer.Start(ctx, "AccountRepository.GetAllBankAccountBalances")
+	defer func() { tracing.End(span, err) }()
+	return ar.next.GetAllBankAccountBalances(ctx)
+}
@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"context"
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+// BalanceSnapshotRepository only ever adds balance snapshots, so that the balances of a day never change once written
+type BalanceSnapshotRepository interface {
+	// AddBalanceSnapshots stores the snapshots of days that have none yet for their bank account, leaving the others
+	// untouched, and returns how many it stored
+	AddBalanceSnapshots(snapshots []model.BalanceSnapshot, ctx context.Context) (int, error)
+	// GetEarliestBalanceSnapshot returns the snapshot of the bank account with the earliest day starting at or after
+	// fromDay
+	GetEarliestBalanceSnapshot(bankAccountId string, fromDay time.Time, ctx context.Context) (
+		*model.BalanceSnapshot,
+		error,
+	)
+	// GetBalanceSnapshots returns the snapshots of the bank account for the days from fromDay to toDay, oldest first
+	GetBalanceSnapshots(bankAccountId string, fromDay time.Time, toDay time.Time, ctx context.Context) (
+		[]model.BalanceSnapshot,
+		error,
+	)
+	GetLatestBalanceSnapshot(bankAccountId string, ctx context.Context) (*model.BalanceSnapshot, error)
+}
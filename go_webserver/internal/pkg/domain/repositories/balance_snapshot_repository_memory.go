@@ -0,0 +1,181 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/memory"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
+)
+
+type BalanceSnapshotRepositoryMemory struct {
+	store  *memory.Store
+	logger *slog.Logger
+}
+
+func CreateNewBalanceSnapshotRepositoryMemory(
+	store *memory.Store,
+	logger *slog.Logger,
+) *BalanceSnapshotRepositoryMemory {
+	return &BalanceSnapshotRepositoryMemory{store: store, logger: logger}
+}
+
+func (sr *BalanceSnapshotRepositoryMemory) AddBalanceSnapshots(
+	snapshots []model.BalanceSnapshot,
+	ctx context.Context,
+) (int, error) {
+	for _, snapshot := range snapshots {
+		if _, err := utils.StringToObjectId(snapshot.BankAccountId); err != nil {
+			return 0, fmt.Errorf("error when converting BankAccount ID %s to ObjectID: %w", snapshot.BankAccountId,
+				err)
+		}
+	}
+	createdAt := currentTime()
+	var added int
+	err := sr.store.Run(ctx, func(tx *memory.Tx) error {
+		added = 0
+		for _, snapshot := range snapshots {
+			id := memory.BalanceSnapshotId(snapshot.BankAccountId, snapshot.Day)
+			_, found, err := tx.Get(memory.BalanceSnapshotCollectionName, id)
+			if err != nil {
+				return err
+			}
+			if found {
+				continue
+			}
+			err = tx.Put(memory.BalanceSnapshotCollectionName, id, &memory.BalanceSnapshotRecord{
+				Id:                      id,
+				BankAccountId:           snapshot.BankAccountId,
+				Day:                     snapshot.Day.UTC(),
+				AvailableBalance:        snapshot.AvailableBalance,
+				PendingBalance:          snapshot.PendingBalance,
+				OpeningAvailableBalance: snapshot.OpeningAvailableBalance,
+				OpeningPendingBalance:   snapshot.OpeningPendingBalance,
+				MinAvailableBalance:     snapshot.MinAvailableBalance,
+				MaxAvailableBalance:     snapshot.MaxAvailableBalance,
+				TotalDebits:             snapshot.TotalDebits,
+				TotalCredits:            snapshot.TotalCredits,
+				CreatedAt:               createdAt,
+			})
+			if err != nil {
+				return err
+			}
+			added++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error when inserting balance snapshots: %w", err)
+	}
+	sr.logger.DebugContext(ctx, "Inserted balance snapshots", slog.Int("count", added))
+	return added, nil
+}
+
+func (sr *BalanceSnapshotRepositoryMemory) GetEarliestBalanceSnapshot(
+	bankAccountId string,
+	fromDay time.Time,
+	ctx context.Context,
+) (*model.BalanceSnapshot, error) {
+	return sr.findBalanceSnapshot(bankAccountId, func(record, found *memory.BalanceSnapshotRecord) bool {
+		return !record.Day.Before(fromDay) && (found == nil || record.Day.Before(found.Day))
+	}, ctx)
+}
+
+func (sr *BalanceSnapshotRepositoryMemory) GetLatestBalanceSnapshot(
+	bankAccountId string,
+	ctx context.Context,
+) (*model.BalanceSnapshot, error) {
+	return sr.findBalanceSnapshot(bankAccountId, func(record, found *memory.BalanceSnapshotRecord) bool {
+		return found == nil || record.Day.After(found.Day)
+	}, ctx)
+}
+
+// findBalanceSnapshot returns the snapshot of the bank account that better prefers over every other one
+func (sr *BalanceSnapshotRepositoryMemory) findBalanceSnapshot(
+	bankAccountId string,
+	better func(record *memory.BalanceSnapshotRecord, found *memory.BalanceSnapshotRecord) bool,
+	ctx context.Context,
+) (*model.BalanceSnapshot, error) {
+	if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+		return nil, fmt.Errorf("error when converting BankAccount ID %s to ObjectID: %w", bankAccountId, err)
+	}
+	var found *memory.BalanceSnapshotRecord
+	err := sr.store.Run(ctx, func(tx *memory.Tx) error {
+		records, err := tx.Find(memory.BalanceSnapshotCollectionName, func(doc memory.Document) bool {
+			return doc.(*memory.BalanceSnapshotRecord).BankAccountId == bankAccountId
+		})
+		if err != nil {
+			return err
+		}
+		for _, doc := range records {
+			if record := doc.(*memory.BalanceSnapshotRecord); better(record, found) {
+				found = record
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when finding balance snapshot of BankAccount %s: %w", bankAccountId, err)
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w for bankAccountId %s", model.ErrNoMatchingBalanceSnapshot, bankAccountId)
+	}
+	sr.logger.DebugContext(ctx, "Retrieved balance snapshot", logging.BankAccountId(bankAccountId))
+	snapshot := balanceSnapshotFromRecord(found)
+	return &snapshot, nil
+}
+
+func (sr *BalanceSnapshotRepositoryMemory) GetBalanceSnapshots(
+	bankAccountId string,
+	fromDay time.Time,
+	toDay time.Time,
+	ctx context.Context,
+) ([]model.BalanceSnapshot, error) {
+	if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+		return nil, fmt.Errorf("error when converting BankAccount ID %s to ObjectID: %w", bankAccountId, err)
+	}
+	var snapshots []model.BalanceSnapshot
+	err := sr.store.Run(ctx, func(tx *memory.Tx) error {
+		records, err := tx.Find(memory.BalanceSnapshotCollectionName, func(doc memory.Document) bool {
+			record := doc.(*memory.BalanceSnapshotRecord)
+			return record.BankAccountId == bankAccountId && !record.Day.Before(fromDay) && !record.Day.After(toDay)
+		})
+		if err != nil {
+			return err
+		}
+		snapshots = make([]model.BalanceSnapshot, 0, len(records))
+		for _, doc := range records {
+			snapshots = append(snapshots, balanceSnapshotFromRecord(doc.(*memory.BalanceSnapshotRecord)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when finding balance snapshots of BankAccount %s: %w", bankAccountId, err)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Day.Before(snapshots[j].Day)
+	})
+	sr.logger.DebugContext(ctx, "Retrieved balance snapshots", logging.BankAccountId(bankAccountId),
+		slog.Int("count", len(snapshots)))
+	return snapshots, nil
+}
+
+func balanceSnapshotFromRecord(record *memory.BalanceSnapshotRecord) model.BalanceSnapshot {
+	return model.BalanceSnapshot{
+		BankAccountId:           record.BankAccountId,
+		Day:                     record.Day,
+		AvailableBalance:        record.AvailableBalance,
+		PendingBalance:          record.PendingBalance,
+		OpeningAvailableBalance: record.OpeningAvailableBalance,
+		OpeningPendingBalance:   record.OpeningPendingBalance,
+		MinAvailableBalance:     record.MinAvailableBalance,
+		MaxAvailableBalance:     record.MaxAvailableBalance,
+		TotalDebits:             record.TotalDebits,
+		TotalCredits:            record.TotalCredits,
+		CreatedAt:               record.CreatedAt,
+	}
+}
@@ -0,0 +1,230 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/mongodb"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+	"webserver/internal/pkg/utils"
+)
+
+type BalanceSnapshotRepositoryMongodb struct {
+	col     *mongo.Collection
+	metrics *metrics.Metrics
+	logger  *slog.Logger
+}
+
+func CreateNewBalanceSnapshotRepositoryMongodb(
+	col *mongo.Collection,
+	metrics *metrics.Metrics,
+	logger *slog.Logger,
+) *BalanceSnapshotRepositoryMongodb {
+	return &BalanceSnapshotRepositoryMongodb{col: col, metrics: metrics, logger: logger}
+}
+
+// AddBalanceSnapshots upserts the snapshots without ever updating them, so that the snapshots of days that were
+// already taken stay as they are
+func (sr *BalanceSnapshotRepositoryMongodb) AddBalanceSnapshots(
+	snapshots []model.BalanceSnapshot,
+	ctx context.Context,
+) (int, error) {
+	if len(snapshots) == 0 {
+		return 0, nil
+	}
+	defer sr.metrics.ObserveMongoOperation(sr.col.Name(), "AddBalanceSnapshots", time.Now())
+	createdAt := utils.GetCurrentTimestamp()
+	writes := make([]mongo.WriteModel, len(snapshots))
+	for i, snapshot := range snapshots {
+		mongoSnapshot, err := fromDomainBalanceSnapshot(&snapshot)
+		if err != nil {
+			return 0, fmt.Errorf("error when converting balance snapshot of BankAccount %s: %w",
+				snapshot.BankAccountId, err)
+		}
+		mongoSnapshot.CreatedAt = createdAt
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"bankAccountId": mongoSnapshot.BankAccountId, "day": mongoSnapshot.Day}).
+			SetUpdate(bson.M{"$setOnInsert": mongoSnapshot}).
+			SetUpsert(true)
+	}
+	result, err := sr.col.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	// Replicas taking the snapshots of the same day at the same time race to insert them, and the unique index lets
+	// only one of them win
+	if err != nil && !onlyDuplicateKeyErrors(err) {
+		return 0, fmt.Errorf("error when inserting balance snapshots: %w", utils.ClassifyMongoError(err))
+	}
+	added := int(result.UpsertedCount)
+	sr.logger.DebugContext(ctx, "Inserted balance snapshots", slog.Int("count", added))
+	return added, nil
+}
+
+func (sr *BalanceSnapshotRepositoryMongodb) GetEarliestBalanceSnapshot(
+	bankAccountId string,
+	fromDay time.Time,
+	ctx context.Context,
+) (*model.BalanceSnapshot, error) {
+	defer sr.metrics.ObserveMongoOperation(sr.col.Name(), "GetEarliestBalanceSnapshot", time.Now())
+	return sr.findBalanceSnapshot(bankAccountId, bson.M{"$gte": fromDay}, 1, ctx)
+}
+
+func (sr *BalanceSnapshotRepositoryMongodb) GetLatestBalanceSnapshot(
+	bankAccountId string,
+	ctx context.Context,
+) (*model.BalanceSnapshot, error) {
+	defer sr.metrics.ObserveMongoOperation(sr.col.Name(), "GetLatestBalanceSnapshot", time.Now())
+	return sr.findBalanceSnapshot(bankAccountId, nil, -1, ctx)
+}
+
+func (sr *BalanceSnapshotRepositoryMongodb) GetBalanceSnapshots(
+	bankAccountId string,
+	fromDay time.Time,
+	toDay time.Time,
+	ctx context.Context,
+) ([]model.BalanceSnapshot, error) {
+	defer sr.metrics.ObserveMongoOperation(sr.col.Name(), "GetBalanceSnapshots", time.Now())
+	objectId, err := utils.StringToObjectId(bankAccountId)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting BankAccount ID %s to ObjectID: %w", bankAccountId, err)
+	}
+	filter := bson.M{"bankAccountId": objectId, "day": bson.M{"$gte": fromDay, "$lte": toDay}}
+	cursor, err := sr.col.Find(ctx, filter, options.Find().SetSort(bson.D{{"day", 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("error when finding balance snapshots of BankAccount %s: %w", bankAccountId,
+			utils.ClassifyMongoError(err))
+	}
+
+	defer func() {
+		err := cursor.Close(ctx)
+		if err != nil {
+			sr.logger.ErrorContext(ctx, "Unable to close cursor when getting balance snapshots",
+				logging.BankAccountId(bankAccountId), logging.Err(err))
+		}
+	}()
+
+	var mongoSnapshots []mongodb.MongoBalanceSnapshot
+	if err = cursor.All(ctx, &mongoSnapshots); err != nil {
+		return nil, fmt.Errorf("error when iterating over mongo Cursor when getting balance snapshots of "+
+			"BankAccount %s: %w", bankAccountId, utils.ClassifyMongoError(err))
+	}
+	res := make([]model.BalanceSnapshot, len(mongoSnapshots))
+	for i := range mongoSnapshots {
+		snapshot, err := toDomainBalanceSnapshot(&mongoSnapshots[i])
+		if err != nil {
+			return nil, fmt.Errorf("error when converting balance snapshot of BankAccount %s: %w", bankAccountId,
+				err)
+		}
+		res[i] = *snapshot
+	}
+	sr.logger.DebugContext(ctx, "Retrieved balance snapshots", logging.BankAccountId(bankAccountId),
+		slog.Int("count", len(res)))
+	return res, nil
+}
+
+// findBalanceSnapshot returns the first snapshot of the bank account whose day matches dayFilter, in the order of
+// daySort
+func (sr *BalanceSnapshotRepositoryMongodb) findBalanceSnapshot(
+	bankAccountId string,
+	dayFilter bson.M,
+	daySort int,
+	ctx context.Context,
+) (*model.BalanceSnapshot, error) {
+	objectId, err := utils.StringToObjectId(bankAccountId)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting BankAccount ID %s to ObjectID: %w", bankAccountId, err)
+	}
+	filter := bson.M{"bankAccountId": objectId}
+	if dayFilter != nil {
+		filter["day"] = dayFilter
+	}
+	var mongoSnapshot mongodb.MongoBalanceSnapshot
+	err = sr.col.FindOne(ctx, filter, options.FindOne().SetSort(bson.D{{"day", daySort}})).Decode(&mongoSnapshot)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w for bankAccountId %s", model.ErrNoMatchingBalanceSnapshot, bankAccountId)
+		}
+		return nil, fmt.Errorf("error when finding balance snapshot of BankAccount %s: %w", bankAccountId,
+			utils.ClassifyMongoError(err))
+	}
+	snapshot, err := toDomainBalanceSnapshot(&mongoSnapshot)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting balance snapshot of BankAccount %s: %w", bankAccountId, err)
+	}
+	sr.logger.DebugContext(ctx, "Retrieved balance snapshot", logging.BankAccountId(bankAccountId))
+	return snapshot, nil
+}
+
+// onlyDuplicateKeyErrors reports whether every write of a bulk write that failed was rejected by a unique index
+func onlyDuplicateKeyErrors(err error) bool {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return false
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if !mongo.IsDuplicateKeyError(writeErr) {
+			return false
+		}
+	}
+	return true
+}
+
+func fromDomainBalanceSnapshot(snapshot *model.BalanceSnapshot) (*mongodb.MongoBalanceSnapshot, error) {
+	bankAccountId, err := utils.StringToObjectId(snapshot.BankAccountId)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting BankAccount ID %s to ObjectID: %w", snapshot.BankAccountId,
+			err)
+	}
+	mongoSnapshot := &mongodb.MongoBalanceSnapshot{BankAccountId: bankAccountId, Day: snapshot.Day.UTC()}
+	for _, amount := range balanceSnapshotAmounts(snapshot, mongoSnapshot) {
+		if *amount.mongo, err = utils.FromDecimalToPrimitiveDecimal128(*amount.domain); err != nil {
+			return nil, fmt.Errorf("error when converting %s %s to Decimal128: %w", amount.name, amount.domain, err)
+		}
+	}
+	return mongoSnapshot, nil
+}
+
+func toDomainBalanceSnapshot(mongoSnapshot *mongodb.MongoBalanceSnapshot) (*model.BalanceSnapshot, error) {
+	snapshot := &model.BalanceSnapshot{
+		BankAccountId: mongoSnapshot.BankAccountId.Hex(),
+		Day:           mongoSnapshot.Day.UTC(),
+		CreatedAt:     utils.TimestampToTime(mongoSnapshot.CreatedAt),
+	}
+	var err error
+	for _, amount := range balanceSnapshotAmounts(snapshot, mongoSnapshot) {
+		if *amount.domain, err = utils.FromPrimitiveDecimal128ToDecimal(*amount.mongo); err != nil {
+			return nil, fmt.Errorf("error when converting %s to decimal: %w", amount.name, err)
+		}
+	}
+	return snapshot, nil
+}
+
+// balanceSnapshotAmount pairs an amount of a snapshot with the field storing it in MongoDB
+type balanceSnapshotAmount struct {
+	name   string
+	domain *decimal.Decimal
+	mongo  *primitive.Decimal128
+}
+
+func balanceSnapshotAmounts(
+	snapshot *model.BalanceSnapshot,
+	mongoSnapshot *mongodb.MongoBalanceSnapshot,
+) []balanceSnapshotAmount {
+	return []balanceSnapshotAmount{
+		{"available balance", &snapshot.AvailableBalance, &mongoSnapshot.AvailableBalance},
+		{"pending balance", &snapshot.PendingBalance, &mongoSnapshot.PendingBalance},
+		{"opening available balance", &snapshot.OpeningAvailableBalance, &mongoSnapshot.OpeningAvailableBalance},
+		{"opening pending balance", &snapshot.OpeningPendingBalance, &mongoSnapshot.OpeningPendingBalance},
+		{"minimum available balance", &snapshot.MinAvailableBalance, &mongoSnapshot.MinAvailableBalance},
+		{"maximum available balance", &snapshot.MaxAvailableBalance, &mongoSnapshot.MaxAvailableBalance},
+		{"total debits", &snapshot.TotalDebits, &mongoSnapshot.TotalDebits},
+		{"total credits", &snapshot.TotalCredits, &mongoSnapshot.TotalCredits},
+	}
+}
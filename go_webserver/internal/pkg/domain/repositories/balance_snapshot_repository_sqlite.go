@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
+)
+
+// balanceSnapshotColumns are the columns scanBalanceSnapshot reads, in its order
+const balanceSnapshotColumns = `bank_account_id, day, available_balance, pending_balance, opening_available_balance,
+	opening_pending_balance, min_available_balance, max_available_balance, total_debits, total_credits, created_at`
+
+type BalanceSnapshotRepositorySQLite struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func CreateNewBalanceSnapshotRepositorySQLite(db *sql.DB, logger *slog.Logger) *BalanceSnapshotRepositorySQLite {
+	return &BalanceSnapshotRepositorySQLite{db: db, logger: logger}
+}
+
+func (sr *BalanceSnapshotRepositorySQLite) AddBalanceSnapshots(
+	snapshots []model.BalanceSnapshot,
+	ctx context.Context,
+) (int, error) {
+	for _, snapshot := range snapshots {
+		if _, err := utils.StringToObjectId(snapshot.BankAccountId); err != nil {
+			return 0, fmt.Errorf("error when converting BankAccount ID %s to ObjectID: %w", snapshot.BankAccountId,
+				err)
+		}
+	}
+	createdAt := toSQLiteTime(currentTime())
+	var added int
+	err := sqlite.Run(ctx, sr.db, func(q sqlite.Querier) error {
+		added = 0
+		for _, snapshot := range snapshots {
+			res, err := q.ExecContext(ctx, `INSERT INTO balance_snapshot (`+balanceSnapshotColumns+`)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`, snapshot.BankAccountId,
+				toSQLiteTime(snapshot.Day), snapshot.AvailableBalance, snapshot.PendingBalance,
+				snapshot.OpeningAvailableBalance, snapshot.OpeningPendingBalance, snapshot.MinAvailableBalance,
+				snapshot.MaxAvailableBalance, snapshot.TotalDebits, snapshot.TotalCredits, createdAt)
+			if err != nil {
+				return err
+			}
+			inserted, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			added += int(inserted)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error when inserting balance snapshots: %w", err)
+	}
+	sr.logger.DebugContext(ctx, "Inserted balance snapshots", slog.Int("count", added))
+	return added, nil
+}
+
+func (sr *BalanceSnapshotRepositorySQLite) GetEarliestBalanceSnapshot(
+	bankAccountId string,
+	fromDay time.Time,
+	ctx context.Context,
+) (*model.BalanceSnapshot, error) {
+	return sr.findBalanceSnapshot(bankAccountId, `SELECT `+balanceSnapshotColumns+` FROM balance_snapshot
+		WHERE bank_account_id = ? AND day >= ? ORDER BY day LIMIT 1`, ctx, bankAccountId, toSQLiteTime(fromDay))
+}
+
+func (sr *BalanceSnapshotRepositorySQLite) GetLatestBalanceSnapshot(
+	bankAccountId string,
+	ctx context.Context,
+) (*model.BalanceSnapshot, error) {
+	return sr.findBalanceSnapshot(bankAccountId, `SELECT `+balanceSnapshotColumns+` FROM balance_snapshot
+		WHERE bank_account_id = ? ORDER BY day DESC LIMIT 1`, ctx, bankAccountId)
+}
+
+func (sr *BalanceSnapshotRepositorySQLite) GetBalanceSnapshots(
+	bankAccountId string,
+	fromDay time.Time,
+	toDay time.Time,
+	ctx context.Context,
+) ([]model.BalanceSnapshot, error) {
+	if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+		return nil, fmt.Errorf("error when converting BankAccount ID %s to ObjectID: %w", bankAccountId, err)
+	}
+	rows, err := sqlite.Executor(ctx, sr.db).QueryContext(ctx, `SELECT `+balanceSnapshotColumns+`
+		FROM balance_snapshot WHERE bank_account_id = ? AND day BETWEEN ? AND ? ORDER BY day`, bankAccountId,
+		toSQLiteTime(fromDay), toSQLiteTime(toDay))
+	if err != nil {
+		return nil, fmt.Errorf("error when finding balance snapshots of BankAccount %s: %w", bankAccountId, err)
+	}
+	defer rows.Close()
+	res := make([]model.BalanceSnapshot, 0)
+	for rows.Next() {
+		snapshot, err := scanBalanceSnapshot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error when reading balance snapshot of BankAccount %s: %w", bankAccountId, err)
+		}
+		res = append(res, snapshot)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error when reading balance snapshots of BankAccount %s: %w", bankAccountId, err)
+	}
+	sr.logger.DebugContext(ctx, "Retrieved balance snapshots", logging.BankAccountId(bankAccountId),
+		slog.Int("count", len(res)))
+	return res, nil
+}
+
+func (sr *BalanceSnapshotRepositorySQLite) findBalanceSnapshot(
+	bankAccountId string,
+	query string,
+	ctx context.Context,
+	args ...any,
+) (*model.BalanceSnapshot, error) {
+	if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+		return nil, fmt.Errorf("error when converting BankAccount ID %s to ObjectID: %w", bankAccountId, err)
+	}
+	res, err := scanBalanceSnapshot(sqlite.Executor(ctx, sr.db).QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w for bankAccountId %s", model.ErrNoMatchingBalanceSnapshot, bankAccountId)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error when finding balance snapshot of BankAccount %s: %w", bankAccountId, err)
+	}
+	sr.logger.DebugContext(ctx, "Retrieved balance snapshot", logging.BankAccountId(bankAccountId))
+	return &res, nil
+}
+
+// scanBalanceSnapshot reads a snapshot selected with balanceSnapshotColumns from a row or from the current row of rows
+func scanBalanceSnapshot(row interface{ Scan(dest ...any) error }) (model.BalanceSnapshot, error) {
+	var res model.BalanceSnapshot
+	var day, createdAt int64
+	err := row.Scan(&res.BankAccountId, &day, &res.AvailableBalance, &res.PendingBalance,
+		&res.OpeningAvailableBalance, &res.OpeningPendingBalance, &res.MinAvailableBalance, &res.MaxAvailableBalance,
+		&res.TotalDebits, &res.TotalCredits, &createdAt)
+	if err != nil {
+		return model.BalanceSnapshot{}, err
+	}
+	res.Day = fromSQLiteTime(day).UTC()
+	res.CreatedAt = fromSQLiteTime(createdAt)
+	return res, nil
+}
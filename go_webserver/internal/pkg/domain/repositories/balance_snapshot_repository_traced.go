@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/tracing"
+)
+
+// BalanceSnapshotRepositoryTraced records a span for every call to the balance snapshot repository it wraps
+type BalanceSnapshotRepositoryTraced struct {
+	next   BalanceSnapshotRepository
+	tracer trace.Tracer
+}
+
+func CreateNewBalanceSnapshotRepositoryTraced(
+	next BalanceSnapshotRepository,
+	tracer trace.Tracer,
+) *BalanceSnapshotRepositoryTraced {
+	return &BalanceSnapshotRepositoryTraced{next: next, tracer: tracer}
+}
+
+func (sr *BalanceSnapshotRepositoryTraced) AddBalanceSnapshots(
+	snapshots []model.BalanceSnapshot,
+	ctx context.Context,
+) (_ int, err error) {
+	ctx, span := sr.tracer.Start(ctx, "BalanceSnapshotRepository.AddBalanceSnapshots")
+	defer func() { tracing.End(span, err) }()
+	return sr.next.AddBalanceSnapshots(snapshots, ctx)
+}
+
+func (sr *BalanceSnapshotRepositoryTraced) GetEarliestBalanceSnapshot(
+	bankAccountId string,
+	fromDay time.Time,
+	ctx context.Context,
+) (_ *model.BalanceSnapshot, err error) {
+	ctx, span := sr.tracer.Start(ctx, "BalanceSnapshotRepository.GetEarliestBalanceSnapshot")
+	defer func() { tracing.End(span, err) }()
+	return sr.next.GetEarliestBalanceSnapshot(bankAccountId, fromDay, ctx)
+}
+
+func (sr *BalanceSnapshotRepositoryTraced) GetBalanceSnapshots(
+	bankAccountId string,
+	fromDay time.Time,
+	toDay time.Time,
+	ctx context.Context,
+) (_ []model.BalanceSnapshot, err error) {
+	ctx, span := sr.tracer.Start(ctx, "BalanceSnapshotRepository.GetBalanceSnapshots")
+	defer func() { tracing.End(span, err) }()
+	return sr.next.GetBalanceSnapshots(bankAccountId, fromDay, toDay, ctx)
+}
+
+func (sr *BalanceSnapshotRepositoryTraced) GetLatestBalanceSnapshot(
+	bankAccountId string,
+	ctx context.Context,
+) (_ *model.BalanceSnapshot, err error) {
+	ctx, span := sr.tracer.Start(ctx, "BalanceSnapshotRepository.GetLatestBalanceSnapshot")
+	defer func() { tracing.End(span, err) }()
+	return sr.next.GetLatestBalanceSnapshot(bankAccountId, ctx)
+}
@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"context"
+	"webserver/internal/pkg/domain/model"
+)
+
+type CategoryRuleRepository interface {
+	AddCategoryRule(accountId string, input *model.CategoryRuleInput, ctx context.Context) (*model.CategoryRule, error)
+	// GetCategoryRules lists the rules of the account in the order they were created
+	GetCategoryRules(accountId string, ctx context.Context) ([]model.CategoryRule, error)
+	RemoveCategoryRule(accountId string, ruleId string, ctx context.Context) error
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"sort"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/memory"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
+)
+
+type CategoryRuleRepositoryMemory struct {
+	store  *memory.Store
+	logger *slog.Logger
+}
+
+func CreateNewCategoryRuleRepositoryMemory(store *memory.Store, logger *slog.Logger) *CategoryRuleRepositoryMemory {
+	return &CategoryRuleRepositoryMemory{store: store, logger: logger}
+}
+
+func (cr *CategoryRuleRepositoryMemory) AddCategoryRule(
+	accountId string,
+	input *model.CategoryRuleInput,
+	ctx context.Context,
+) (*model.CategoryRule, error) {
+	if _, err := utils.StringToObjectId(accountId); err != nil {
+		return nil, fmt.Errorf("error when converting account ID %s to ObjectID: %w", accountId, err)
+	}
+	if input.CounterpartyBankAccountId != "" {
+		if _, err := utils.StringToObjectId(input.CounterpartyBankAccountId); err != nil {
+			return nil, fmt.Errorf("error when converting counterparty BankAccount ID %s to ObjectID: %w",
+				input.CounterpartyBankAccountId, err)
+		}
+	}
+	record := &memory.CategoryRuleRecord{
+		Id:                        primitive.NewObjectID().Hex(),
+		AccountId:                 accountId,
+		Category:                  input.Category,
+		CounterpartyBankAccountId: input.CounterpartyBankAccountId,
+		TransactionNature:         string(input.TransactionNature),
+		MinAmount:                 input.MinAmount,
+		MaxAmount:                 input.MaxAmount,
+		MemoPattern:               input.MemoPattern,
+		CreatedAt:                 currentTime(),
+	}
+	err := cr.store.Run(ctx, func(tx *memory.Tx) error {
+		return tx.Put(memory.CategoryRuleCollectionName, record.Id, record)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when inserting category rule of account %s: %w", accountId, err)
+	}
+	cr.logger.DebugContext(ctx, "Inserted category rule", logging.AccountId(accountId),
+		slog.String(logging.CategoryRuleIdKey, record.Id))
+	return fromMemoryCategoryRule(record), nil
+}
+
+func (cr *CategoryRuleRepositoryMemory) GetCategoryRules(
+	accountId string,
+	ctx context.Context,
+) ([]model.CategoryRule, error) {
+	if _, err := utils.StringToObjectId(accountId); err != nil {
+		return nil, fmt.Errorf("error when converting account ID %s to ObjectID: %w", accountId, err)
+	}
+	var records []*memory.CategoryRuleRecord
+	err := cr.store.Run(ctx, func(tx *memory.Tx) error {
+		docs, err := tx.Find(memory.CategoryRuleCollectionName, func(doc memory.Document) bool {
+			return doc.(*memory.CategoryRuleRecord).AccountId == accountId
+		})
+		if err != nil {
+			return err
+		}
+		records = make([]*memory.CategoryRuleRecord, len(docs))
+		for i, doc := range docs {
+			records[i] = doc.(*memory.CategoryRuleRecord)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when finding category rules of account %s: %w", accountId, err)
+	}
+	// Hex ObjectIDs sort like the ObjectIDs themselves, which start with their creation time
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Id < records[j].Id
+	})
+	res := make([]model.CategoryRule, len(records))
+	for i, record := range records {
+		res[i] = *fromMemoryCategoryRule(record)
+	}
+	cr.logger.DebugContext(ctx, "Retrieved category rules", logging.AccountId(accountId))
+	return res, nil
+}
+
+func (cr *CategoryRuleRepositoryMemory) RemoveCategoryRule(
+	accountId string,
+	ruleId string,
+	ctx context.Context,
+) error {
+	if _, err := utils.StringToObjectId(ruleId); err != nil {
+		return fmt.Errorf("error when converting category rule ID %s to ObjectID: %w", ruleId, err)
+	}
+	err := cr.store.Run(ctx, func(tx *memory.Tx) error {
+		doc, found, err := tx.Get(memory.CategoryRuleCollectionName, ruleId)
+		if err != nil {
+			return fmt.Errorf("error when finding category rule %s: %w", ruleId, err)
+		}
+		if !found || doc.(*memory.CategoryRuleRecord).AccountId != accountId {
+			return model.ErrNoMatchingCategoryRule
+		}
+		return tx.Delete(memory.CategoryRuleCollectionName, ruleId)
+	})
+	if err != nil {
+		return err
+	}
+	cr.logger.DebugContext(ctx, "Removed category rule", logging.AccountId(accountId),
+		slog.String(logging.CategoryRuleIdKey, ruleId))
+	return nil
+}
+
+func fromMemoryCategoryRule(record *memory.CategoryRuleRecord) *model.CategoryRule {
+	return &model.CategoryRule{
+		Id:                        record.Id,
+		AccountId:                 record.AccountId,
+		Category:                  record.Category,
+		CounterpartyBankAccountId: record.CounterpartyBankAccountId,
+		TransactionNature:         model.TransactionNature(record.TransactionNature),
+		MinAmount:                 record.MinAmount,
+		MaxAmount:                 record.MaxAmount,
+		MemoPattern:               record.MemoPattern,
+		CreatedAt:                 record.CreatedAt,
+	}
+}
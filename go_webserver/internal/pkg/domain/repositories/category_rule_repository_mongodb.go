@@ -0,0 +1,196 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/mongodb"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+	"webserver/internal/pkg/utils"
+)
+
+type CategoryRuleRepositoryMongodb struct {
+	col     *mongo.Collection
+	metrics *metrics.Metrics
+	logger  *slog.Logger
+}
+
+func CreateNewCategoryRuleRepositoryMongodb(
+	col *mongo.Collection,
+	metrics *metrics.Metrics,
+	logger *slog.Logger,
+) *CategoryRuleRepositoryMongodb {
+	return &CategoryRuleRepositoryMongodb{col: col, metrics: metrics, logger: logger}
+}
+
+func (cr *CategoryRuleRepositoryMongodb) AddCategoryRule(
+	accountId string,
+	input *model.CategoryRuleInput,
+	ctx context.Context,
+) (*model.CategoryRule, error) {
+	defer cr.metrics.ObserveMongoOperation(cr.col.Name(), "AddCategoryRule", time.Now())
+	mongoRule, err := fromDomainCategoryRule(accountId, input)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = cr.col.InsertOne(ctx, mongoRule); err != nil {
+		return nil, fmt.Errorf("error when inserting category rule of account %s: %w", accountId,
+			utils.ClassifyMongoError(err))
+	}
+	cr.logger.DebugContext(ctx, "Inserted category rule", logging.AccountId(accountId),
+		slog.String(logging.CategoryRuleIdKey, mongoRule.Id.Hex()))
+	return toDomainCategoryRule(mongoRule)
+}
+
+func (cr *CategoryRuleRepositoryMongodb) GetCategoryRules(
+	accountId string,
+	ctx context.Context,
+) ([]model.CategoryRule, error) {
+	defer cr.metrics.ObserveMongoOperation(cr.col.Name(), "GetCategoryRules", time.Now())
+	objectId, err := utils.StringToObjectId(accountId)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting account ID %s to ObjectID: %w", accountId, err)
+	}
+	// ObjectIDs start with their creation time
+	opts := options.Find().SetSort(bson.D{{"_id", 1}})
+	cursor, err := cr.col.Find(ctx, bson.M{"accountId": objectId}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error when finding category rules of account %s: %w", accountId,
+			utils.ClassifyMongoError(err))
+	}
+
+	defer func() {
+		err := cursor.Close(ctx)
+		if err != nil {
+			cr.logger.ErrorContext(ctx, "Unable to close cursor when getting category rules",
+				logging.AccountId(accountId), logging.Err(err))
+		}
+	}()
+
+	var mongoRules []mongodb.MongoCategoryRule
+	if err = cursor.All(ctx, &mongoRules); err != nil {
+		return nil, fmt.Errorf("error when iterating over mongo Cursor when getting category rules of "+
+			"account %s: %w", accountId, utils.ClassifyMongoError(err))
+	}
+	res := make([]model.CategoryRule, len(mongoRules))
+	for i := range mongoRules {
+		rule, err := toDomainCategoryRule(&mongoRules[i])
+		if err != nil {
+			return nil, fmt.Errorf("error when converting mongo category rule of account %s: %w", accountId, err)
+		}
+		res[i] = *rule
+	}
+	cr.logger.DebugContext(ctx, "Retrieved category rules", logging.AccountId(accountId))
+	return res, nil
+}
+
+func (cr *CategoryRuleRepositoryMongodb) RemoveCategoryRule(
+	accountId string,
+	ruleId string,
+	ctx context.Context,
+) error {
+	defer cr.metrics.ObserveMongoOperation(cr.col.Name(), "RemoveCategoryRule", time.Now())
+	objectId, err := utils.StringToObjectId(accountId)
+	if err != nil {
+		return fmt.Errorf("error when converting account ID %s to ObjectID: %w", accountId, err)
+	}
+	ruleObjectId, err := utils.StringToObjectId(ruleId)
+	if err != nil {
+		return fmt.Errorf("error when converting category rule ID %s to ObjectID: %w", ruleId, err)
+	}
+	result, err := cr.col.DeleteOne(ctx, bson.M{"_id": ruleObjectId, "accountId": objectId})
+	if err != nil {
+		return fmt.Errorf("error when removing category rule %s: %w", ruleId, utils.ClassifyMongoError(err))
+	}
+	if result.DeletedCount == 0 {
+		return model.ErrNoMatchingCategoryRule
+	}
+	cr.logger.DebugContext(ctx, "Removed category rule", logging.AccountId(accountId),
+		slog.String(logging.CategoryRuleIdKey, ruleId))
+	return nil
+}
+
+func fromDomainCategoryRule(accountId string, input *model.CategoryRuleInput) (*mongodb.MongoCategoryRule, error) {
+	objectId, err := utils.StringToObjectId(accountId)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting account ID %s to ObjectID: %w", accountId, err)
+	}
+	rule := &mongodb.MongoCategoryRule{
+		Id:                primitive.NewObjectID(),
+		AccountId:         objectId,
+		Category:          input.Category,
+		TransactionNature: string(input.TransactionNature),
+		MemoPattern:       input.MemoPattern,
+		CreatedAt:         utils.GetCurrentTimestamp(),
+	}
+	if input.CounterpartyBankAccountId != "" {
+		counterpartyId, err := utils.StringToObjectId(input.CounterpartyBankAccountId)
+		if err != nil {
+			return nil, fmt.Errorf("error when converting counterparty BankAccount ID %s to ObjectID: %w",
+				input.CounterpartyBankAccountId, err)
+		}
+		rule.CounterpartyBankAccountId = &counterpartyId
+	}
+	if rule.MinAmount, err = fromDomainNullDecimal(input.MinAmount); err != nil {
+		return nil, fmt.Errorf("error when converting minimum amount %s to Decimal128: %w", input.MinAmount.Decimal,
+			err)
+	}
+	if rule.MaxAmount, err = fromDomainNullDecimal(input.MaxAmount); err != nil {
+		return nil, fmt.Errorf("error when converting maximum amount %s to Decimal128: %w", input.MaxAmount.Decimal,
+			err)
+	}
+	return rule, nil
+}
+
+func toDomainCategoryRule(rule *mongodb.MongoCategoryRule) (*model.CategoryRule, error) {
+	res := &model.CategoryRule{
+		Id:                rule.Id.Hex(),
+		AccountId:         rule.AccountId.Hex(),
+		Category:          rule.Category,
+		TransactionNature: model.TransactionNature(rule.TransactionNature),
+		MemoPattern:       rule.MemoPattern,
+		CreatedAt:         utils.TimestampToTime(rule.CreatedAt),
+	}
+	if rule.CounterpartyBankAccountId != nil {
+		res.CounterpartyBankAccountId = rule.CounterpartyBankAccountId.Hex()
+	}
+	var err error
+	if res.MinAmount, err = toDomainNullDecimal(rule.MinAmount); err != nil {
+		return nil, fmt.Errorf("error when converting minimum amount of category rule %s: %w", res.Id, err)
+	}
+	if res.MaxAmount, err = toDomainNullDecimal(rule.MaxAmount); err != nil {
+		return nil, fmt.Errorf("error when converting maximum amount of category rule %s: %w", res.Id, err)
+	}
+	return res, nil
+}
+
+// fromDomainNullDecimal leaves the amount out of the document when it is not set
+func fromDomainNullDecimal(amount decimal.NullDecimal) (*primitive.Decimal128, error) {
+	if !amount.Valid {
+		return nil, nil
+	}
+	decimal128Amount, err := utils.FromDecimalToPrimitiveDecimal128(amount.Decimal)
+	if err != nil {
+		return nil, err
+	}
+	return &decimal128Amount, nil
+}
+
+func toDomainNullDecimal(amount *primitive.Decimal128) (decimal.NullDecimal, error) {
+	if amount == nil {
+		return decimal.NullDecimal{}, nil
+	}
+	decimalAmount, err := utils.FromPrimitiveDecimal128ToDecimal(*amount)
+	if err != nil {
+		return decimal.NullDecimal{}, err
+	}
+	return decimal.NewNullDecimal(decimalAmount), nil
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
+)
+
+type CategoryRuleRepositorySQLite struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func CreateNewCategoryRuleRepositorySQLite(db *sql.DB, logger *slog.Logger) *CategoryRuleRepositorySQLite {
+	return &CategoryRuleRepositorySQLite{db: db, logger: logger}
+}
+
+func (cr *CategoryRuleRepositorySQLite) AddCategoryRule(
+	accountId string,
+	input *model.CategoryRuleInput,
+	ctx context.Context,
+) (*model.CategoryRule, error) {
+	if _, err := utils.StringToObjectId(accountId); err != nil {
+		return nil, fmt.Errorf("error when converting account ID %s to ObjectID: %w", accountId, err)
+	}
+	if input.CounterpartyBankAccountId != "" {
+		if _, err := utils.StringToObjectId(input.CounterpartyBankAccountId); err != nil {
+			return nil, fmt.Errorf("error when converting counterparty BankAccount ID %s to ObjectID: %w",
+				input.CounterpartyBankAccountId, err)
+		}
+	}
+	rule := &model.CategoryRule{
+		Id:                        primitive.NewObjectID().Hex(),
+		AccountId:                 accountId,
+		Category:                  input.Category,
+		CounterpartyBankAccountId: input.CounterpartyBankAccountId,
+		TransactionNature:         input.TransactionNature,
+		MinAmount:                 input.MinAmount,
+		MaxAmount:                 input.MaxAmount,
+		MemoPattern:               input.MemoPattern,
+		CreatedAt:                 currentTime(),
+	}
+	_, err := sqlite.Executor(ctx, cr.db).ExecContext(ctx, `INSERT INTO category_rule
+		(id, account_id, category, counterparty_bank_account_id, transaction_nature, min_amount, max_amount,
+		memo_pattern, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.Id, accountId, rule.Category, rule.CounterpartyBankAccountId, string(rule.TransactionNature),
+		rule.MinAmount, rule.MaxAmount, rule.MemoPattern, toSQLiteTime(rule.CreatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("error when inserting category rule of account %s: %w", accountId, err)
+	}
+	cr.logger.DebugContext(ctx, "Inserted category rule", logging.AccountId(accountId),
+		slog.String(logging.CategoryRuleIdKey, rule.Id))
+	return rule, nil
+}
+
+func (cr *CategoryRuleRepositorySQLite) GetCategoryRules(
+	accountId string,
+	ctx context.Context,
+) ([]model.CategoryRule, error) {
+	if _, err := utils.StringToObjectId(accountId); err != nil {
+		return nil, fmt.Errorf("error when converting account ID %s to ObjectID: %w", accountId, err)
+	}
+	// Hex ObjectIDs sort like the ObjectIDs themselves, which start with their creation time
+	rows, err := sqlite.Executor(ctx, cr.db).QueryContext(ctx, `SELECT id, account_id, category,
+		counterparty_bank_account_id, transaction_nature, min_amount, max_amount, memo_pattern, created_at
+		FROM category_rule WHERE account_id = ? ORDER BY id`, accountId)
+	if err != nil {
+		return nil, fmt.Errorf("error when finding category rules of account %s: %w", accountId, err)
+	}
+	defer rows.Close()
+	res := make([]model.CategoryRule, 0)
+	for rows.Next() {
+		var rule model.CategoryRule
+		var createdAt int64
+		err = rows.Scan(&rule.Id, &rule.AccountId, &rule.Category, &rule.CounterpartyBankAccountId,
+			&rule.TransactionNature, &rule.MinAmount, &rule.MaxAmount, &rule.MemoPattern, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("error when reading category rule of account %s: %w", accountId, err)
+		}
+		rule.CreatedAt = fromSQLiteTime(createdAt)
+		res = append(res, rule)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error when reading category rules of account %s: %w", accountId, err)
+	}
+	cr.logger.DebugContext(ctx, "Retrieved category rules", logging.AccountId(accountId))
+	return res, nil
+}
+
+func (cr *CategoryRuleRepositorySQLite) RemoveCategoryRule(
+	accountId string,
+	ruleId string,
+	ctx context.Context,
+) error {
+	if _, err := utils.StringToObjectId(ruleId); err != nil {
+		return fmt.Errorf("error when converting category rule ID %s to ObjectID: %w", ruleId, err)
+	}
+	res, err := sqlite.Executor(ctx, cr.db).ExecContext(ctx,
+		`DELETE FROM category_rule WHERE id = ? AND account_id = ?`, ruleId, accountId)
+	if err != nil {
+		return fmt.Errorf("error when removing category rule %s: %w", ruleId, err)
+	}
+	if removed, err := res.RowsAffected(); err != nil || removed == 0 {
+		return model.ErrNoMatchingCategoryRule
+	}
+	cr.logger.DebugContext(ctx, "Removed category rule", logging.AccountId(accountId),
+		slog.String(logging.CategoryRuleIdKey, ruleId))
+	return nil
+}
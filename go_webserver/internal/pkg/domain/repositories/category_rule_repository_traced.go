@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/tracing"
+)
+
+// CategoryRuleRepositoryTraced records a span for every call to the category rule repository it wraps
+type CategoryRuleRepositoryTraced struct {
+	next   CategoryRuleRepository
+	tracer trace.Tracer
+}
+
+func CreateNewCategoryRuleRepositoryTraced(
+	next CategoryRuleRepository,
+	tracer trace.Tracer,
+) *CategoryRuleRepositoryTraced {
+	return &CategoryRuleRepositoryTraced{next: next, tracer: tracer}
+}
+
+func (cr *CategoryRuleRepositoryTraced) AddCategoryRule(
+	accountId string,
+	input *model.CategoryRuleInput,
+	ctx context.Context,
+) (_ *model.CategoryRule, err error) {
+	ctx, span := cr.tracer.Start(ctx, "CategoryRuleRepository.AddCategoryRule")
+	defer func() { tracing.End(span, err) }()
+	return cr.next.AddCategoryRule(accountId, input, ctx)
+}
+
+func (cr *CategoryRuleRepositoryTraced) GetCategoryRules(
+	accountId string,
+	ctx context.Context,
+) (_ []model.CategoryRule, err error) {
+	ctx, span := cr.tracer.Start(ctx, "CategoryRuleRepository.GetCategoryRules")
+	defer func() { tracing.End(span, err) }()
+	return cr.next.GetCategoryRules(accountId, ctx)
+}
+
+func (cr *CategoryRuleRepositoryTraced) RemoveCategoryRule(
+	accountId string,
+	ruleId string,
+	ctx context.Context,
+) (err error) {
+	ctx, span := cr.tracer.Start(ctx, "CategoryRuleRepository.RemoveCategoryRule")
+	defer func() { tracing.End(span, err) }()
+	return cr.next.RemoveCategoryRule(accountId, ruleId, ctx)
+}
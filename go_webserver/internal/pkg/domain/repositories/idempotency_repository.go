@@ -0,0 +1,15 @@
+package repositories
+
+import (
+	"context"
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+type IdempotencyRepository interface {
+	GetIdempotencyRecord(key *model.IdempotencyKeyInput, ctx context.Context) (*model.IdempotencyRecord, error)
+	AddIdempotencyRecord(record *model.IdempotencyRecord, ctx context.Context) error
+	// DeleteExpiredIdempotencyRecords deletes the records that are older than IdempotencyKeyTTL at the given time and
+	// returns how many it deleted
+	DeleteExpiredIdempotencyRecords(now time.Time, ctx context.Context) (int, error)
+}
@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/memory"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
+)
+
+type IdempotencyRepositoryMemory struct {
+	store  *memory.Store
+	logger *slog.Logger
+}
+
+func CreateNewIdempotencyRepositoryMemory(store *memory.Store, logger *slog.Logger) *IdempotencyRepositoryMemory {
+	return &IdempotencyRepositoryMemory{store: store, logger: logger}
+}
+
+func (ir *IdempotencyRepositoryMemory) GetIdempotencyRecord(
+	key *model.IdempotencyKeyInput,
+	ctx context.Context,
+) (*model.IdempotencyRecord, error) {
+	if _, err := utils.StringToObjectId(key.AccountId); err != nil {
+		return nil, fmt.Errorf("error when converting account ID to object ID for "+
+			"accountId %s: %w", key.AccountId, err)
+	}
+	var res *model.IdempotencyRecord
+	expiredBy := currentTime().Add(-model.IdempotencyKeyTTL)
+	err := ir.store.Run(ctx, func(tx *memory.Tx) error {
+		records, err := tx.Find(memory.IdempotencyKeyCollectionName, func(doc memory.Document) bool {
+			record := doc.(*memory.IdempotencyRecord)
+			return record.AccountId == key.AccountId && record.Key == key.Key && record.CreatedAt.After(expiredBy)
+		})
+		if err != nil {
+			return fmt.Errorf("error when finding idempotency key %s for account %s: %w",
+				key.Key, key.AccountId, err)
+		}
+		if len(records) == 0 {
+			return model.ErrNoMatchingIdempotencyKey
+		}
+		record := records[0].(*memory.IdempotencyRecord)
+		res = &model.IdempotencyRecord{
+			Key:           record.Key,
+			AccountId:     record.AccountId,
+			RequestHash:   record.RequestHash,
+			TransactionId: record.TransactionId,
+			CreatedAt:     record.CreatedAt,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (ir *IdempotencyRepositoryMemory) AddIdempotencyRecord(
+	record *model.IdempotencyRecord,
+	ctx context.Context,
+) error {
+	for _, id := range []string{record.AccountId, record.TransactionId} {
+		if _, err := utils.StringToObjectId(id); err != nil {
+			return fmt.Errorf("error when converting ID %s of idempotency key %s to object ID: %w", id,
+				record.Key, err)
+		}
+	}
+	memoryRecord := &memory.IdempotencyRecord{
+		Id:            primitive.NewObjectID().Hex(),
+		AccountId:     record.AccountId,
+		Key:           record.Key,
+		RequestHash:   record.RequestHash,
+		TransactionId: record.TransactionId,
+		CreatedAt:     record.CreatedAt,
+	}
+	expiredBy := currentTime().Add(-model.IdempotencyKeyTTL)
+	err := ir.store.Run(ctx, func(tx *memory.Tx) error {
+		// Expired keys of the account are evicted first, so that an expired key can be used again
+		expired, err := tx.Find(memory.IdempotencyKeyCollectionName, func(doc memory.Document) bool {
+			other := doc.(*memory.IdempotencyRecord)
+			return other.AccountId == record.AccountId && !other.CreatedAt.After(expiredBy)
+		})
+		if err != nil {
+			return fmt.Errorf("error when finding expired idempotency keys: %w", err)
+		}
+		for _, doc := range expired {
+			if err = tx.Delete(memory.IdempotencyKeyCollectionName, doc.(*memory.IdempotencyRecord).Id); err != nil {
+				return fmt.Errorf("error when evicting expired idempotency key: %w", err)
+			}
+		}
+		return tx.Put(memory.IdempotencyKeyCollectionName, memoryRecord.Id, memoryRecord)
+	})
+	if err != nil {
+		if errors.Is(err, memory.ErrDuplicateKey) {
+			return model.ErrIdempotencyKeyInUse
+		}
+		return fmt.Errorf("error when inserting idempotency key %s for account %s: %w",
+			record.Key, record.AccountId, err)
+	}
+	ir.logger.DebugContext(ctx, "Stored idempotency key", slog.String(logging.IdempotencyKeyKey, record.Key),
+		logging.AccountId(record.AccountId))
+	return nil
+}
+
+func (ir *IdempotencyRepositoryMemory) DeleteExpiredIdempotencyRecords(
+	now time.Time,
+	ctx context.Context,
+) (int, error) {
+	expiredBy := now.Add(-model.IdempotencyKeyTTL)
+	deleted := 0
+	err := ir.store.Run(ctx, func(tx *memory.Tx) error {
+		deleted = 0
+		expired, err := tx.Find(memory.IdempotencyKeyCollectionName, func(doc memory.Document) bool {
+			return !doc.(*memory.IdempotencyRecord).CreatedAt.After(expiredBy)
+		})
+		if err != nil {
+			return fmt.Errorf("error when finding expired idempotency keys: %w", err)
+		}
+		for _, doc := range expired {
+			if err = tx.Delete(memory.IdempotencyKeyCollectionName, doc.(*memory.IdempotencyRecord).Id); err != nil {
+				return fmt.Errorf("error when deleting expired idempotency key: %w", err)
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log/slog"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/mongodb"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+	"webserver/internal/pkg/utils"
+)
+
+type IdempotencyRepositoryMongodb struct {
+	col     *mongo.Collection
+	metrics *metrics.Metrics
+	logger  *slog.Logger
+}
+
+func CreateNewIdempotencyRepositoryMongodb(
+	col *mongo.Collection,
+	metrics *metrics.Metrics,
+	logger *slog.Logger,
+) *IdempotencyRepositoryMongodb {
+	ir := IdempotencyRepositoryMongodb{col: col, metrics: metrics, logger: logger}
+	return &ir
+}
+
+func (ir *IdempotencyRepositoryMongodb) GetIdempotencyRecord(
+	key *model.IdempotencyKeyInput,
+	ctx context.Context,
+) (*model.IdempotencyRecord, error) {
+	defer ir.metrics.ObserveMongoOperation(ir.col.Name(), "GetIdempotencyRecord", time.Now())
+	accountId, err := utils.StringToObjectId(key.AccountId)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting account ID to object ID for "+
+			"accountId %s: %w", key.AccountId, err)
+	}
+	var mongoRecord mongodb.MongoIdempotencyRecord
+	// Expired keys are only deleted by the TTL index about once a minute, so they are filtered out here
+	filter := bson.M{
+		"accountId": accountId,
+		"key":       key.Key,
+		"createdAt": bson.M{"$gt": currentTime().Add(-model.IdempotencyKeyTTL)},
+	}
+	err = ir.col.FindOne(ctx, filter).Decode(&mongoRecord)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, model.ErrNoMatchingIdempotencyKey
+		}
+		return nil, fmt.Errorf("error when finding idempotency key %s for account %s: %w",
+			key.Key, key.AccountId, utils.ClassifyMongoError(err))
+	}
+	transactionId, err := utils.ObjectIdToString(mongoRecord.TransactionId)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting transaction ID to string for idempotency key %s: %w",
+			key.Key, err)
+	}
+	return &model.IdempotencyRecord{
+		Key:           mongoRecord.Key,
+		AccountId:     key.AccountId,
+		RequestHash:   mongoRecord.RequestHash,
+		TransactionId: transactionId,
+		CreatedAt:     mongoRecord.CreatedAt,
+	}, nil
+}
+
+func (ir *IdempotencyRepositoryMongodb) AddIdempotencyRecord(
+	record *model.IdempotencyRecord,
+	ctx context.Context,
+) error {
+	defer ir.metrics.ObserveMongoOperation(ir.col.Name(), "AddIdempotencyRecord", time.Now())
+	accountId, err := utils.StringToObjectId(record.AccountId)
+	if err != nil {
+		return fmt.Errorf("error when converting account ID to object ID for "+
+			"accountId %s: %w", record.AccountId, err)
+	}
+	transactionId, err := utils.StringToObjectId(record.TransactionId)
+	if err != nil {
+		return fmt.Errorf("error when converting transaction ID to object ID for "+
+			"transactionId %s: %w", record.TransactionId, err)
+	}
+	mongoRecord := mongodb.MongoIdempotencyRecord{
+		AccountId:     accountId,
+		Key:           record.Key,
+		RequestHash:   record.RequestHash,
+		TransactionId: transactionId,
+		CreatedAt:     record.CreatedAt,
+	}
+	// An expired key that the TTL index did not delete yet is replaced, so that it can be used again
+	_, err = ir.col.DeleteOne(ctx, bson.M{
+		"accountId": accountId,
+		"key":       record.Key,
+		"createdAt": bson.M{"$lte": currentTime().Add(-model.IdempotencyKeyTTL)},
+	})
+	if err != nil {
+		return fmt.Errorf("error when deleting expired idempotency key %s for account %s: %w",
+			record.Key, record.AccountId, utils.ClassifyMongoError(err))
+	}
+	_, err = ir.col.InsertOne(ctx, mongoRecord)
+	if err != nil {
+		// A write conflict with a concurrent request that is still storing the same key is left to be retried by
+		// the database transaction, after which the key is found and the request replayed
+		if mongo.IsDuplicateKeyError(err) {
+			return model.ErrIdempotencyKeyInUse
+		}
+		return fmt.Errorf("error when inserting idempotency key %s for account %s: %w",
+			record.Key, record.AccountId, utils.ClassifyMongoError(err))
+	}
+	ir.logger.DebugContext(ctx, "Stored idempotency key", slog.String(logging.IdempotencyKeyKey, record.Key),
+		logging.AccountId(record.AccountId))
+	return nil
+}
+
+// DeleteExpiredIdempotencyRecords deletes the expired records the TTL index has not deleted yet, which it does about
+// once a minute
+func (ir *IdempotencyRepositoryMongodb) DeleteExpiredIdempotencyRecords(
+	now time.Time,
+	ctx context.Context,
+) (int, error) {
+	defer ir.metrics.ObserveMongoOperation(ir.col.Name(), "DeleteExpiredIdempotencyRecords", time.Now())
+	res, err := ir.col.DeleteMany(ctx, bson.M{"createdAt": bson.M{"$lte": now.Add(-model.IdempotencyKeyTTL)}})
+	if err != nil {
+		return 0, fmt.Errorf("error when deleting expired idempotency keys: %w", utils.ClassifyMongoError(err))
+	}
+	return int(res.DeletedCount), nil
+}
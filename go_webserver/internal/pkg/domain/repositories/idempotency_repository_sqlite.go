@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
+)
+
+type IdempotencyRepositorySQLite struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func CreateNewIdempotencyRepositorySQLite(db *sql.DB, logger *slog.Logger) *IdempotencyRepositorySQLite {
+	return &IdempotencyRepositorySQLite{db: db, logger: logger}
+}
+
+func (ir *IdempotencyRepositorySQLite) GetIdempotencyRecord(
+	key *model.IdempotencyKeyInput,
+	ctx context.Context,
+) (*model.IdempotencyRecord, error) {
+	if _, err := utils.StringToObjectId(key.AccountId); err != nil {
+		return nil, fmt.Errorf("error when converting account ID to object ID for "+
+			"accountId %s: %w", key.AccountId, err)
+	}
+	var res model.IdempotencyRecord
+	var createdAt int64
+	expiredBy := currentTime().Add(-model.IdempotencyKeyTTL)
+	err := sqlite.Executor(ctx, ir.db).QueryRowContext(ctx, `SELECT key, account_id, request_hash, transaction_id,
+		created_at FROM idempotency_key WHERE account_id = ? AND key = ? AND created_at > ?`, key.AccountId, key.Key,
+		toSQLiteTime(expiredBy)).Scan(&res.Key, &res.AccountId, &res.RequestHash, &res.TransactionId, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNoMatchingIdempotencyKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error when finding idempotency key %s for account %s: %w", key.Key,
+			key.AccountId, err)
+	}
+	res.CreatedAt = fromSQLiteTime(createdAt)
+	return &res, nil
+}
+
+func (ir *IdempotencyRepositorySQLite) AddIdempotencyRecord(
+	record *model.IdempotencyRecord,
+	ctx context.Context,
+) error {
+	for _, id := range []string{record.AccountId, record.TransactionId} {
+		if _, err := utils.StringToObjectId(id); err != nil {
+			return fmt.Errorf("error when converting ID %s of idempotency key %s to object ID: %w", id,
+				record.Key, err)
+		}
+	}
+	executor := sqlite.Executor(ctx, ir.db)
+	// An expired key that was not deleted yet is replaced, so that it can be used again
+	_, err := executor.ExecContext(ctx, `DELETE FROM idempotency_key WHERE account_id = ? AND key = ?
+		AND created_at <= ?`, record.AccountId, record.Key, toSQLiteTime(currentTime().Add(-model.IdempotencyKeyTTL)))
+	if err != nil {
+		return fmt.Errorf("error when deleting expired idempotency key %s for account %s: %w",
+			record.Key, record.AccountId, err)
+	}
+	_, err = executor.ExecContext(ctx, `INSERT INTO idempotency_key
+		(id, account_id, key, request_hash, transaction_id, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		primitive.NewObjectID().Hex(), record.AccountId, record.Key, record.RequestHash, record.TransactionId,
+		toSQLiteTime(record.CreatedAt))
+	if err != nil {
+		if sqlite.IsUniqueViolation(err) {
+			return model.ErrIdempotencyKeyInUse
+		}
+		return fmt.Errorf("error when inserting idempotency key %s for account %s: %w",
+			record.Key, record.AccountId, err)
+	}
+	ir.logger.DebugContext(ctx, "Stored idempotency key", slog.String(logging.IdempotencyKeyKey, record.Key),
+		logging.AccountId(record.AccountId))
+	return nil
+}
+
+func (ir *IdempotencyRepositorySQLite) DeleteExpiredIdempotencyRecords(
+	now time.Time,
+	ctx context.Context,
+) (int, error) {
+	res, err := sqlite.Executor(ctx, ir.db).ExecContext(ctx, `DELETE FROM idempotency_key WHERE created_at <= ?`,
+		toSQLiteTime(now.Add(-model.IdempotencyKeyTTL)))
+	if err != nil {
+		return 0, fmt.Errorf("error when deleting expired idempotency keys: %w", err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error when counting deleted idempotency keys: %w", err)
+	}
+	return int(deleted), nil
+}
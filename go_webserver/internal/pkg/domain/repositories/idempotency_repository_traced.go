@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/tracing"
+)
+
+// IdempotencyRepositoryTraced records a span for every call to the idempotency repository it wraps
+type IdempotencyRepositoryTraced struct {
+	next   IdempotencyRepository
+	tracer trace.Tracer
+}
+
+func CreateNewIdempotencyRepositoryTraced(
+	next IdempotencyRepository,
+	tracer trace.Tracer,
+) *IdempotencyRepositoryTraced {
+	return &IdempotencyRepositoryTraced{next: next, tracer: tracer}
+}
+
+func (ir *IdempotencyRepositoryTraced) GetIdempotencyRecord(
+	key *model.IdempotencyKeyInput,
+	ctx context.Context,
+) (_ *model.IdempotencyRecord, err error) {
+	ctx, span := ir.tracer.Start(ctx, "IdempotencyRepository.GetIdempotencyRecord")
+	defer func() { tracing.End(span, err) }()
+	return ir.next.GetIdempotencyRecord(key, ctx)
+}
+
+func (ir *IdempotencyRepositoryTraced) AddIdempotencyRecord(
+	record *model.IdempotencyRecord,
+	ctx context.Context,
+) (err error) {
+	ctx, span := ir.tracer.Start(ctx, "IdempotencyRepository.AddIdempotencyRecord")
+	defer func() { tracing.End(span, err) }()
+	return ir.next.AddIdempotencyRecord(record, ctx)
+}
+
+func (ir *IdempotencyRepositoryTraced) DeleteExpiredIdempotencyRecords(
+	now time.Time,
+	ctx context.Context,
+) (_ int, err error) {
+	ctx, span := ir.tracer.Start(ctx, "IdempotencyRepository.DeleteExpiredIdempotencyRecords")
+	defer func() { tracing.End(span, err) }()
+	return ir.next.DeleteExpiredIdempotencyRecords(now, ctx)
+}
@@ -0,0 +1,12 @@
+package repositories
+
+import (
+	"context"
+	"webserver/internal/pkg/domain/model"
+)
+
+// JournalRepository only ever appends journal entries, so that postings are never changed once written
+type JournalRepository interface {
+	AddJournalEntry(entry *model.JournalEntryInput, ctx context.Context) (string, error)
+	GetPostingTotals(ctx context.Context) ([]model.PostingTotal, error)
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/memory"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
+)
+
+type JournalRepositoryMemory struct {
+	store  *memory.Store
+	logger *slog.Logger
+}
+
+func CreateNewJournalRepositoryMemory(store *memory.Store, logger *slog.Logger) *JournalRepositoryMemory {
+	return &JournalRepositoryMemory{store: store, logger: logger}
+}
+
+func (jr *JournalRepositoryMemory) AddJournalEntry(
+	entry *model.JournalEntryInput,
+	ctx context.Context,
+) (string, error) {
+	if entry.TransactionId != "" {
+		if _, err := utils.StringToObjectId(entry.TransactionId); err != nil {
+			return "", fmt.Errorf("error when converting transaction ID to ObjectID: %w", err)
+		}
+	}
+	record := &memory.JournalEntryRecord{
+		Id:            primitive.NewObjectID().Hex(),
+		TransactionId: entry.TransactionId,
+		Kind:          string(entry.Kind),
+		Postings:      make([]memory.Posting, len(entry.Postings)),
+		CreatedAt:     currentTime(),
+	}
+	for i, posting := range entry.Postings {
+		if _, err := utils.StringToObjectId(posting.BankAccountId); err != nil {
+			return "", fmt.Errorf("error when converting BankAccount ID %s to ObjectID: %w",
+				posting.BankAccountId, err)
+		}
+		record.Postings[i] = memory.Posting{
+			BankAccountId: posting.BankAccountId,
+			Balance:       string(posting.Balance),
+			Amount:        posting.Amount,
+		}
+	}
+	err := jr.store.Run(ctx, func(tx *memory.Tx) error {
+		return tx.Put(memory.JournalEntryCollectionName, record.Id, record)
+	})
+	if err != nil {
+		return "", fmt.Errorf("error when inserting %s journal entry for transaction %s: %w", entry.Kind,
+			entry.TransactionId, err)
+	}
+	jr.logger.DebugContext(ctx, "Inserted journal entry", slog.String("journal_entry_id", record.Id),
+		logging.TransactionId(entry.TransactionId), slog.String("kind", string(entry.Kind)))
+	return record.Id, nil
+}
+
+func (jr *JournalRepositoryMemory) GetPostingTotals(ctx context.Context) ([]model.PostingTotal, error) {
+	type postingKey struct {
+		bankAccountId string
+		balance       string
+	}
+	var keys []postingKey
+	totals := make(map[postingKey]decimal.Decimal)
+	err := jr.store.Run(ctx, func(tx *memory.Tx) error {
+		entries, err := tx.Find(memory.JournalEntryCollectionName, nil)
+		if err != nil {
+			return err
+		}
+		for _, doc := range entries {
+			for _, posting := range doc.(*memory.JournalEntryRecord).Postings {
+				key := postingKey{posting.BankAccountId, posting.Balance}
+				total, ok := totals[key]
+				if !ok {
+					keys = append(keys, key)
+				}
+				totals[key] = total.Add(posting.Amount)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when getting posting totals: %w", err)
+	}
+	res := make([]model.PostingTotal, len(keys))
+	for i, key := range keys {
+		res[i] = model.PostingTotal{
+			BankAccountId: key.bankAccountId,
+			Balance:       model.BalanceKind(key.balance),
+			Amount:        totals[key],
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log/slog"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/mongodb"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+	"webserver/internal/pkg/utils"
+)
+
+type JournalRepositoryMongodb struct {
+	col     *mongo.Collection
+	metrics *metrics.Metrics
+	logger  *slog.Logger
+}
+
+func CreateNewJournalRepositoryMongodb(
+	col *mongo.Collection,
+	metrics *metrics.Metrics,
+	logger *slog.Logger,
+) *JournalRepositoryMongodb {
+	return &JournalRepositoryMongodb{col: col, metrics: metrics, logger: logger}
+}
+
+func (jr *JournalRepositoryMongodb) AddJournalEntry(
+	entry *model.JournalEntryInput,
+	ctx context.Context,
+) (string, error) {
+	defer jr.metrics.ObserveMongoOperation(jr.col.Name(), "AddJournalEntry", time.Now())
+	mongoEntry, err := fromDomainJournalEntry(entry)
+	if err != nil {
+		return "", fmt.Errorf("error when converting journal entry for transaction %s: %w", entry.TransactionId, err)
+	}
+	result, err := jr.col.InsertOne(ctx, mongoEntry)
+	if err != nil {
+		return "", fmt.Errorf("error when inserting %s journal entry for transaction %s: %w", entry.Kind,
+			entry.TransactionId, utils.ClassifyMongoError(err))
+	}
+	entryId, err := utils.ObjectIdToString(result.InsertedID)
+	if err != nil {
+		return "", fmt.Errorf("error when converting journal entry ID to string: %w", err)
+	}
+	jr.logger.DebugContext(ctx, "Inserted journal entry", slog.String("journal_entry_id", entryId),
+		logging.TransactionId(entry.TransactionId), slog.String("kind", string(entry.Kind)))
+	return entryId, nil
+}
+
+func (jr *JournalRepositoryMongodb) GetPostingTotals(ctx context.Context) ([]model.PostingTotal, error) {
+	defer jr.metrics.ObserveMongoOperation(jr.col.Name(), "GetPostingTotals", time.Now())
+	pipeline := mongo.Pipeline{
+		{{"$unwind", "$postings"}},
+		{{"$group", bson.D{
+			{"_id", bson.D{
+				{"bankAccountId", "$postings.bankAccountId"},
+				{"balance", "$postings.balance"},
+			}},
+			{"amount", bson.D{{"$sum", "$postings.amount"}}},
+		}}},
+	}
+	cursor, err := jr.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error when aggregating posting totals: %w", utils.ClassifyMongoError(err))
+	}
+
+	defer func() {
+		err := cursor.Close(ctx)
+		if err != nil {
+			jr.logger.ErrorContext(ctx, "Unable to close cursor when getting posting totals", logging.Err(err))
+		}
+	}()
+
+	var mongoResults []mongodb.MongoPostingTotal
+	if err = cursor.All(ctx, &mongoResults); err != nil {
+		return nil, fmt.Errorf("error when iterating over mongo Cursor when getting posting totals: %w",
+			utils.ClassifyMongoError(err))
+	}
+	res := make([]model.PostingTotal, len(mongoResults))
+	for i, elem := range mongoResults {
+		amount, err := utils.FromPrimitiveDecimal128ToDecimal(elem.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("error when converting posting total of BankAccount %s to decimal: %w",
+				elem.Id.BankAccountId.Hex(), err)
+		}
+		res[i] = model.PostingTotal{
+			BankAccountId: elem.Id.BankAccountId.Hex(),
+			Balance:       model.BalanceKind(elem.Id.Balance),
+			Amount:        amount,
+		}
+	}
+	return res, nil
+}
+
+func fromDomainJournalEntry(entry *model.JournalEntryInput) (*mongodb.MongoJournalEntryInput, error) {
+	mongoEntry := mongodb.MongoJournalEntryInput{
+		Kind:      string(entry.Kind),
+		Postings:  make([]mongodb.MongoPosting, len(entry.Postings)),
+		CreatedAt: utils.GetCurrentTimestamp(),
+	}
+	if entry.TransactionId != "" {
+		transactionId, err := utils.StringToObjectId(entry.TransactionId)
+		if err != nil {
+			return nil, fmt.Errorf("error when converting transaction ID to ObjectID: %w", err)
+		}
+		mongoEntry.TransactionId = transactionId
+	}
+	for i, posting := range entry.Postings {
+		bankAccountId, err := utils.StringToObjectId(posting.BankAccountId)
+		if err != nil {
+			return nil, fmt.Errorf("error when converting BankAccount ID %s to ObjectID: %w",
+				posting.BankAccountId, err)
+		}
+		amount, err := utils.FromDecimalToPrimitiveDecimal128(posting.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("error when converting posting amount %s to Decimal128: %w", posting.Amount, err)
+		}
+		mongoEntry.Postings[i] = mongodb.MongoPosting{
+			BankAccountId: bankAccountId,
+			Balance:       string(posting.Balance),
+			Amount:        amount,
+		}
+	}
+	return &mongoEntry, nil
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
+)
+
+type JournalRepositorySQLite struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func CreateNewJournalRepositorySQLite(db *sql.DB, logger *slog.Logger) *JournalRepositorySQLite {
+	return &JournalRepositorySQLite{db: db, logger: logger}
+}
+
+// AddJournalEntry inserts the entry and its postings together, in a transaction of their own when the context
+// carries none
+func (jr *JournalRepositorySQLite) AddJournalEntry(
+	entry *model.JournalEntryInput,
+	ctx context.Context,
+) (string, error) {
+	if entry.TransactionId != "" {
+		if _, err := utils.StringToObjectId(entry.TransactionId); err != nil {
+			return "", fmt.Errorf("error when converting transaction ID to ObjectID: %w", err)
+		}
+	}
+	for _, posting := range entry.Postings {
+		if _, err := utils.StringToObjectId(posting.BankAccountId); err != nil {
+			return "", fmt.Errorf("error when converting BankAccount ID %s to ObjectID: %w",
+				posting.BankAccountId, err)
+		}
+	}
+	entryId := primitive.NewObjectID().Hex()
+	err := sqlite.Run(ctx, jr.db, func(q sqlite.Querier) error {
+		_, err := q.ExecContext(ctx, `INSERT INTO journal_entry (id, transaction_id, kind, created_at)
+			VALUES (?, ?, ?, ?)`, entryId, entry.TransactionId, string(entry.Kind), toSQLiteTime(currentTime()))
+		if err != nil {
+			return err
+		}
+		for i, posting := range entry.Postings {
+			_, err = q.ExecContext(ctx, `INSERT INTO posting
+				(journal_entry_id, position, bank_account_id, balance, amount) VALUES (?, ?, ?, ?, ?)`,
+				entryId, i, posting.BankAccountId, string(posting.Balance), posting.Amount)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error when inserting %s journal entry for transaction %s: %w", entry.Kind,
+			entry.TransactionId, err)
+	}
+	jr.logger.DebugContext(ctx, "Inserted journal entry", slog.String("journal_entry_id", entryId),
+		logging.TransactionId(entry.TransactionId), slog.String("kind", string(entry.Kind)))
+	return entryId, nil
+}
+
+// GetPostingTotals sums the postings in Go rather than in SQL, which would add the amounts up as floating point
+// numbers
+func (jr *JournalRepositorySQLite) GetPostingTotals(ctx context.Context) ([]model.PostingTotal, error) {
+	type postingKey struct {
+		bankAccountId string
+		balance       string
+	}
+	var keys []postingKey
+	totals := make(map[postingKey]decimal.Decimal)
+	rows, err := sqlite.Executor(ctx, jr.db).QueryContext(ctx,
+		`SELECT bank_account_id, balance, amount FROM posting ORDER BY rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("error when getting posting totals: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key postingKey
+		var amount decimal.Decimal
+		if err = rows.Scan(&key.bankAccountId, &key.balance, &amount); err != nil {
+			return nil, fmt.Errorf("error when getting posting totals: %w", err)
+		}
+		total, ok := totals[key]
+		if !ok {
+			keys = append(keys, key)
+		}
+		totals[key] = total.Add(amount)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error when getting posting totals: %w", err)
+	}
+	res := make([]model.PostingTotal, len(keys))
+	for i, key := range keys {
+		res[i] = model.PostingTotal{
+			BankAccountId: key.bankAccountId,
+			Balance:       model.BalanceKind(key.balance),
+			Amount:        totals[key],
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/tracing"
+)
+
+// JournalRepositoryTraced records a span for every call to the journal repository it wraps
+type JournalRepositoryTraced struct {
+	next   JournalRepository
+	tracer trace.Tracer
+}
+
+func CreateNewJournalRepositoryTraced(next JournalRepository, tracer trace.Tracer) *JournalRepositoryTraced {
+	return &JournalRepositoryTraced{next: next, tracer: tracer}
+}
+
+func (jr *JournalRepositoryTraced) AddJournalEntry(
+	entry *model.JournalEntryInput,
+	ctx context.Context,
+) (_ string, err error) {
+	ctx, span := jr.tracer.Start(ctx, "JournalRepository.AddJournalEntry")
+	defer func() { tracing.End(span, err) }()
+	return jr.next.AddJournalEntry(entry, ctx)
+}
+
+func (jr *JournalRepositoryTraced) GetPostingTotals(ctx context.Context) (_ []model.PostingTotal, err error) {
+	ctx, span := jr.tracer.Start(ctx, "JournalRepository.GetPostingTotals")
+	defer func() { tracing.End(span, err) }()
+	return jr.next.GetPostingTotals(ctx)
+}
@@ -2,13 +2,32 @@ package repositories
 
 import (
 	"context"
+	"time"
 	"webserver/internal/pkg/domain/model"
 )
 
 type TransactionRepository interface {
-	AddTransaction(details *model.TransactionDetailsInput, ctx context.Context) error
+	AddTransaction(details *model.TransactionDetailsInput, ctx context.Context) (string, error)
 	GetTransactionsFromBankAccountId(input *model.TransactionsForBankAccountInput, ctx context.Context) (
 		[]model.BankAccountTransactionOutput,
 		error,
 	)
+	GetTransactionsPageFromBankAccountId(input *model.TransactionPageInput, ctx context.Context) (
+		*model.TransactionPageOutput,
+		error,
+	)
+	GetTransactionFromId(transactionId string, ctx context.Context) (*model.TransactionDetailsOutput, error)
+	UpdatePendingTransactionStatus(
+		transactionId string,
+		status model.PendingTransactionStatus,
+		ctx context.Context,
+	) error
+	GetExpiredPendingTransactions(expiredBy time.Time, ctx context.Context) ([]model.TransactionDetailsOutput, error)
+	// UpdateTransactionCategory sets the category of the side of the transaction the bank account is on
+	UpdateTransactionCategory(
+		transactionId string,
+		bankAccountId string,
+		category model.TransactionCategory,
+		ctx context.Context,
+	) error
 }
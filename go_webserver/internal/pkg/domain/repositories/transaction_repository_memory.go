@@ -0,0 +1,254 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"sort"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/memory"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
+)
+
+type TransactionRepositoryMemory struct {
+	store  *memory.Store
+	logger *slog.Logger
+}
+
+func CreateNewTransactionRepositoryMemory(store *memory.Store, logger *slog.Logger) *TransactionRepositoryMemory {
+	return &TransactionRepositoryMemory{store: store, logger: logger}
+}
+
+func (tr *TransactionRepositoryMemory) AddTransaction(
+	details *model.TransactionDetailsInput,
+	ctx context.Context,
+) (string, error) {
+	for _, bankAccountId := range []string{details.FromBankAccountId, details.ToBankAccountId} {
+		if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+			return "", fmt.Errorf("error when converting BankAccount %s to ObjectID: %w", bankAccountId, err)
+		}
+	}
+	transaction := &memory.TransactionRecord{
+		Id:                 primitive.NewObjectID().Hex(),
+		FromBankAccountId:  details.FromBankAccountId,
+		ToBankAccountId:    details.ToBankAccountId,
+		Amount:             details.Amount,
+		Type:               string(details.Type),
+		ExpirationDate:     truncateToTimestamp(details.ExpirationDate),
+		Status:             string(details.Status),
+		Memo:               details.Memo,
+		FromCategory:       details.FromCategory.Name,
+		FromCategorySource: string(details.FromCategory.Source),
+		ToCategory:         details.ToCategory.Name,
+		ToCategorySource:   string(details.ToCategory.Source),
+		CreatedAt:          currentTime(),
+	}
+	err := tr.store.Run(ctx, func(tx *memory.Tx) error {
+		return tx.Put(memory.TransactionCollectionName, transaction.Id, transaction)
+	})
+	if err != nil {
+		return "", fmt.Errorf("error when inserting transaction from BankAccount %s to BankAccount %s: %w",
+			details.FromBankAccountId, details.ToBankAccountId, err)
+	}
+	tr.logger.DebugContext(ctx, "Inserted transaction", logging.TransactionId(transaction.Id),
+		logging.FromBankAccountId(details.FromBankAccountId), logging.ToBankAccountId(details.ToBankAccountId))
+	return transaction.Id, nil
+}
+
+func (tr *TransactionRepositoryMemory) GetTransactionFromId(
+	transactionId string,
+	ctx context.Context,
+) (*model.TransactionDetailsOutput, error) {
+	if _, err := utils.StringToObjectId(transactionId); err != nil {
+		return nil, fmt.Errorf("error when converting transaction ID to object ID for "+
+			"transactionId %s: %w", transactionId, err)
+	}
+	var res *model.TransactionDetailsOutput
+	err := tr.store.Run(ctx, func(tx *memory.Tx) error {
+		doc, found, err := tx.Get(memory.TransactionCollectionName, transactionId)
+		if err != nil {
+			return fmt.Errorf("error when finding transaction by ID %s: %w", transactionId, err)
+		}
+		if !found {
+			return model.ErrNoMatchingTransaction
+		}
+		res = fromMemoryTransactionDetails(doc.(*memory.TransactionRecord))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (tr *TransactionRepositoryMemory) UpdatePendingTransactionStatus(
+	transactionId string,
+	status model.PendingTransactionStatus,
+	ctx context.Context,
+) error {
+	if _, err := utils.StringToObjectId(transactionId); err != nil {
+		return fmt.Errorf("error when converting transaction ID to object ID for "+
+			"transactionId %s: %w", transactionId, err)
+	}
+	err := tr.store.Run(ctx, func(tx *memory.Tx) error {
+		doc, found, err := tx.Get(memory.TransactionCollectionName, transactionId)
+		if err != nil {
+			return fmt.Errorf("error when updating status of pending transaction %s: %w", transactionId, err)
+		}
+		// Only active pending transactions may move to another status, which also guards against
+		// a transaction being applied and revoked concurrently
+		if !found {
+			return model.ErrPendingTransactionNotActive
+		}
+		transaction := doc.(*memory.TransactionRecord)
+		if transaction.Type != string(model.Pending) || transaction.Status != string(model.Active) {
+			return model.ErrPendingTransactionNotActive
+		}
+		transaction.Status = string(status)
+		return tx.Put(memory.TransactionCollectionName, transactionId, transaction)
+	})
+	if err != nil {
+		return err
+	}
+	tr.logger.DebugContext(ctx, "Set status of pending transaction", logging.TransactionId(transactionId),
+		slog.String("status", string(status)))
+	return nil
+}
+
+func (tr *TransactionRepositoryMemory) UpdateTransactionCategory(
+	transactionId string,
+	bankAccountId string,
+	category model.TransactionCategory,
+	ctx context.Context,
+) error {
+	if _, err := utils.StringToObjectId(transactionId); err != nil {
+		return fmt.Errorf("error when converting transaction ID to object ID for "+
+			"transactionId %s: %w", transactionId, err)
+	}
+	err := tr.store.Run(ctx, func(tx *memory.Tx) error {
+		doc, found, err := tx.Get(memory.TransactionCollectionName, transactionId)
+		if err != nil {
+			return fmt.Errorf("error when updating category of transaction %s: %w", transactionId, err)
+		}
+		if !found {
+			return model.ErrNoMatchingTransaction
+		}
+		transaction := doc.(*memory.TransactionRecord)
+		switch bankAccountId {
+		case transaction.FromBankAccountId:
+			transaction.FromCategory, transaction.FromCategorySource = category.Name, string(category.Source)
+		case transaction.ToBankAccountId:
+			transaction.ToCategory, transaction.ToCategorySource = category.Name, string(category.Source)
+		default:
+			return model.ErrNoMatchingTransaction
+		}
+		return tx.Put(memory.TransactionCollectionName, transactionId, transaction)
+	})
+	if err != nil {
+		return err
+	}
+	tr.logger.DebugContext(ctx, "Set category of transaction", logging.TransactionId(transactionId),
+		logging.BankAccountId(bankAccountId))
+	return nil
+}
+
+func (tr *TransactionRepositoryMemory) GetExpiredPendingTransactions(
+	expiredBy time.Time,
+	ctx context.Context,
+) ([]model.TransactionDetailsOutput, error) {
+	expiredBySeconds := utils.TimeToTimestamp(expiredBy).T
+	transactions, err := tr.findTransactions(ctx, func(transaction *memory.TransactionRecord) bool {
+		return transaction.Type == string(model.Pending) &&
+			transaction.Status == string(model.Active) &&
+			utils.TimeToTimestamp(transaction.ExpirationDate).T <= expiredBySeconds
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when finding pending transactions expired by %s: %w", expiredBy, err)
+	}
+	res := make([]model.TransactionDetailsOutput, len(transactions))
+	for i, transaction := range transactions {
+		res[i] = *fromMemoryTransactionDetails(transaction)
+	}
+	return res, nil
+}
+
+func (tr *TransactionRepositoryMemory) GetTransactionsFromBankAccountId(
+	input *model.TransactionsForBankAccountInput,
+	ctx context.Context,
+) ([]model.BankAccountTransactionOutput, error) {
+	if _, err := utils.StringToObjectId(input.BankAccountId); err != nil {
+		return nil, fmt.Errorf("error when converting bank account ID to ObjectID: %w", err)
+	}
+	fromSeconds, toSeconds := utils.TimeToTimestamp(input.FromTime).T, utils.TimeToTimestamp(input.ToTime).T
+	transactions, err := tr.findTransactions(ctx, func(transaction *memory.TransactionRecord) bool {
+		createdAt := utils.TimeToTimestamp(transaction.CreatedAt).T
+		return (transaction.FromBankAccountId == input.BankAccountId ||
+			transaction.ToBankAccountId == input.BankAccountId) &&
+			createdAt >= fromSeconds && createdAt <= toSeconds
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when finding transactions for BankAccount %s: %w", input.BankAccountId, err)
+	}
+	tr.logger.DebugContext(ctx, "Retrieved bank account transactions", logging.BankAccountId(input.BankAccountId))
+	return fromMemoryAccountTransactions(input.BankAccountId, transactions), nil
+}
+
+// GetTransactionsPageFromBankAccountId lists the transactions of the bank account matching the filter, ordered by
+// creation time and then by ID so that the cursor of the last transaction marks a stable position in the listing
+func (tr *TransactionRepositoryMemory) GetTransactionsPageFromBankAccountId(
+	input *model.TransactionPageInput,
+	ctx context.Context,
+) (*model.TransactionPageOutput, error) {
+	if _, err := utils.StringToObjectId(input.BankAccountId); err != nil {
+		return nil, fmt.Errorf("error when converting bank account ID to ObjectID: %w", err)
+	}
+	match, err := transactionPageMatchMemory(input)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := tr.findTransactions(ctx, match)
+	if err != nil {
+		return nil, fmt.Errorf("error when finding transactions page for BankAccount %s: %w",
+			input.BankAccountId, err)
+	}
+	sort.Slice(transactions, func(i, j int) bool {
+		if input.Order == model.Descending {
+			return transactionBefore(transactions[j], transactions[i])
+		}
+		return transactionBefore(transactions[i], transactions[j])
+	})
+	var next *model.TransactionCursor
+	if len(transactions) > input.PageSize {
+		transactions = transactions[:input.PageSize]
+		last := transactions[input.PageSize-1]
+		next = &model.TransactionCursor{CreatedAt: last.CreatedAt, Id: last.Id}
+	}
+	return &model.TransactionPageOutput{
+		Transactions: fromMemoryAccountTransactions(input.BankAccountId, transactions),
+		Next:         next,
+	}, nil
+}
+
+func (tr *TransactionRepositoryMemory) findTransactions(
+	ctx context.Context,
+	match func(transaction *memory.TransactionRecord) bool,
+) ([]*memory.TransactionRecord, error) {
+	var res []*memory.TransactionRecord
+	err := tr.store.Run(ctx, func(tx *memory.Tx) error {
+		docs, err := tx.Find(memory.TransactionCollectionName, func(doc memory.Document) bool {
+			return match(doc.(*memory.TransactionRecord))
+		})
+		if err != nil {
+			return err
+		}
+		res = make([]*memory.TransactionRecord, len(docs))
+		for i, doc := range docs {
+			res[i] = doc.(*memory.TransactionRecord)
+		}
+		return nil
+	})
+	return res, err
+}
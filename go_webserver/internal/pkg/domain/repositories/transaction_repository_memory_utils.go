@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/memory"
+	"webserver/internal/pkg/utils"
+)
+
+// truncateToTimestamp drops what a MongoDB timestamp cannot hold from the time, so that times read back from either
+// backend are equal
+func truncateToTimestamp(tm time.Time) time.Time {
+	return utils.TimestampToTime(utils.TimeToTimestamp(tm))
+}
+
+func fromMemoryTransactionDetails(transaction *memory.TransactionRecord) *model.TransactionDetailsOutput {
+	res := &model.TransactionDetailsOutput{
+		Id:                transaction.Id,
+		FromBankAccountId: transaction.FromBankAccountId,
+		ToBankAccountId:   transaction.ToBankAccountId,
+		Amount:            transaction.Amount,
+		Type:              model.TransactionType(transaction.Type),
+		Memo:              transaction.Memo,
+		FromCategory: model.TransactionCategory{
+			Name:   transaction.FromCategory,
+			Source: model.CategorySource(transaction.FromCategorySource),
+		},
+		ToCategory: model.TransactionCategory{
+			Name:   transaction.ToCategory,
+			Source: model.CategorySource(transaction.ToCategorySource),
+		},
+	}
+	if res.Type == model.Pending {
+		res.Status = model.PendingTransactionStatus(transaction.Status)
+		res.ExpirationDate = transaction.ExpirationDate
+	}
+	return res
+}
+
+// fromMemoryAccountTransactions shapes the transactions into transactions seen from the given bank account
+func fromMemoryAccountTransactions(
+	bankAccountId string,
+	transactions []*memory.TransactionRecord,
+) []model.BankAccountTransactionOutput {
+	res := make([]model.BankAccountTransactionOutput, len(transactions))
+	for i, transaction := range transactions {
+		nature, otherBankAccountId := model.Debit, transaction.FromBankAccountId
+		category, categorySource := transaction.ToCategory, transaction.ToCategorySource
+		if transaction.FromBankAccountId == bankAccountId {
+			nature, otherBankAccountId = model.Credit, transaction.ToBankAccountId
+			category, categorySource = transaction.FromCategory, transaction.FromCategorySource
+		}
+		res[i] = model.BankAccountTransactionOutput{
+			Id:                 transaction.Id,
+			BankAccountId:      bankAccountId,
+			OtherBankAccountId: otherBankAccountId,
+			TransactionNature:  nature,
+			TransactionType:    model.TransactionType(transaction.Type),
+			Amount:             transaction.Amount,
+			CreatedAt:          transaction.CreatedAt,
+			Memo:               transaction.Memo,
+			Category:           model.TransactionCategory{Name: category, Source: model.CategorySource(categorySource)},
+		}
+		if res[i].TransactionType == model.Pending {
+			res[i].Status = model.PendingTransactionStatus(transaction.Status)
+			res[i].ExpirationDate = transaction.ExpirationDate
+		}
+	}
+	return res
+}
+
+// transactionBefore orders transactions by creation time and then by ID. Hex ObjectIDs sort like the ObjectIDs
+// themselves.
+func transactionBefore(a *memory.TransactionRecord, b *memory.TransactionRecord) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.Id < b.Id
+}
+
+// transactionPageMatchMemory builds the predicate selecting the transactions of a page, matching the same
+// transactions as transactionPageMatch
+func transactionPageMatchMemory(
+	input *model.TransactionPageInput,
+) (func(transaction *memory.TransactionRecord) bool, error) {
+	filter := input.Filter
+	if filter.CounterpartyBankAccountId != "" {
+		if _, err := utils.StringToObjectId(filter.CounterpartyBankAccountId); err != nil {
+			return nil, fmt.Errorf("%w: counterparty must be a BankAccount ID", model.ErrInvalidTransactionQuery)
+		}
+	}
+	if input.After != nil {
+		if _, err := utils.StringToObjectId(input.After.Id); err != nil {
+			return nil, model.ErrInvalidTransactionCursor
+		}
+	}
+	fromSeconds, toSeconds := utils.TimeToTimestamp(input.FromTime).T, utils.TimeToTimestamp(input.ToTime).T
+	return func(transaction *memory.TransactionRecord) bool {
+		credit := filter.TransactionNature != model.Debit &&
+			transaction.FromBankAccountId == input.BankAccountId &&
+			(filter.CounterpartyBankAccountId == "" || transaction.ToBankAccountId == filter.CounterpartyBankAccountId)
+		debit := filter.TransactionNature != model.Credit &&
+			transaction.ToBankAccountId == input.BankAccountId &&
+			(filter.CounterpartyBankAccountId == "" ||
+				transaction.FromBankAccountId == filter.CounterpartyBankAccountId)
+		if !credit && !debit {
+			return false
+		}
+		createdAt := utils.TimeToTimestamp(transaction.CreatedAt).T
+		if !input.FromTime.IsZero() && createdAt < fromSeconds {
+			return false
+		}
+		if !input.ToTime.IsZero() && createdAt > toSeconds {
+			return false
+		}
+		if filter.TransactionType != "" && transaction.Type != string(filter.TransactionType) {
+			return false
+		}
+		if filter.Status != "" && transaction.Status != string(filter.Status) {
+			return false
+		}
+		if filter.MinAmount.Valid && transaction.Amount.LessThan(filter.MinAmount.Decimal) {
+			return false
+		}
+		if filter.MaxAmount.Valid && transaction.Amount.GreaterThan(filter.MaxAmount.Decimal) {
+			return false
+		}
+		if input.After != nil {
+			after := &memory.TransactionRecord{
+				CreatedAt: truncateToTimestamp(input.After.CreatedAt),
+				Id:        input.After.Id,
+			}
+			if input.Order == model.Descending {
+				return transactionBefore(transaction, after)
+			}
+			return transactionBefore(after, transaction)
+		}
+		return true
+	}, nil
+}
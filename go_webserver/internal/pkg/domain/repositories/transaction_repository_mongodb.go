@@ -2,46 +2,203 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"log"
+	"log/slog"
+	"time"
 	"webserver/internal/pkg/domain/model"
 	"webserver/internal/pkg/infrastructure/mongodb"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+	"webserver/internal/pkg/utils"
 )
 
 type TransactionRepositoryMongodb struct {
-	col *mongo.Collection
+	col     *mongo.Collection
+	metrics *metrics.Metrics
+	logger  *slog.Logger
 }
 
-func CreateNewTransactionRepositoryMongodb(col *mongo.Collection) *TransactionRepositoryMongodb {
-	ar := TransactionRepositoryMongodb{col: col}
+func CreateNewTransactionRepositoryMongodb(
+	col *mongo.Collection,
+	metrics *metrics.Metrics,
+	logger *slog.Logger,
+) *TransactionRepositoryMongodb {
+	ar := TransactionRepositoryMongodb{col: col, metrics: metrics, logger: logger}
 	return &ar
 }
 
 func (tr *TransactionRepositoryMongodb) AddTransaction(
 	details *model.TransactionDetailsInput,
 	ctx context.Context,
-) error {
+) (string, error) {
+	defer tr.metrics.ObserveMongoOperation(tr.col.Name(), "AddTransaction", time.Now())
 	mongoDetails, err := fromDomainTransactionDetails(details)
 	if err != nil {
-		return fmt.Errorf("error when converting domain TransactionDetailsInput to mongo TransactionDetailsInput "+
-			"from BankAccount %s to BankAccount %s: %w", details.FromBankAccountId, details.ToBankAccountId, err)
+		return "", fmt.Errorf("error when converting domain TransactionDetailsInput to mongo "+
+			"TransactionDetailsInput from BankAccount %s to BankAccount %s: %w",
+			details.FromBankAccountId, details.ToBankAccountId, err)
 	}
-	_, err = tr.col.InsertOne(ctx, mongoDetails)
+	result, err := tr.col.InsertOne(ctx, mongoDetails)
 	if err != nil {
-		return fmt.Errorf("error when inserting transaction from BankAccount %s to BankAccount %s: %w",
-			details.FromBankAccountId, details.ToBankAccountId, err)
+		return "", fmt.Errorf("error when inserting transaction from BankAccount %s to BankAccount %s: %w",
+			details.FromBankAccountId, details.ToBankAccountId, utils.ClassifyMongoError(err))
+	}
+	transactionId, err := utils.ObjectIdToString(result.InsertedID)
+	if err != nil {
+		return "", fmt.Errorf("error when converting inserted transaction ID to string for transaction from "+
+			"BankAccount %s to BankAccount %s: %w", details.FromBankAccountId, details.ToBankAccountId, err)
+	}
+	tr.logger.DebugContext(ctx, "Inserted transaction", logging.TransactionId(transactionId),
+		logging.FromBankAccountId(details.FromBankAccountId), logging.ToBankAccountId(details.ToBankAccountId))
+	return transactionId, nil
+}
+
+func (tr *TransactionRepositoryMongodb) GetTransactionFromId(
+	transactionId string,
+	ctx context.Context,
+) (*model.TransactionDetailsOutput, error) {
+	defer tr.metrics.ObserveMongoOperation(tr.col.Name(), "GetTransactionFromId", time.Now())
+	objectId, err := utils.StringToObjectId(transactionId)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting transaction ID to object ID for "+
+			"transactionId %s: %w", transactionId, err)
+	}
+	var mongoTransaction mongodb.MongoTransactionOutput
+	err = tr.col.FindOne(ctx, bson.M{"_id": objectId}).Decode(&mongoTransaction)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, model.ErrNoMatchingTransaction
+		}
+		return nil, fmt.Errorf("error when finding transaction by ID %s: %w", transactionId,
+			utils.ClassifyMongoError(err))
+	}
+	res, err := fromMongoTransactionDetails(&mongoTransaction)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting mongo transaction to domain transaction for "+
+			"transactionId %s: %w", transactionId, err)
+	}
+	return res, nil
+}
+
+func (tr *TransactionRepositoryMongodb) UpdatePendingTransactionStatus(
+	transactionId string,
+	status model.PendingTransactionStatus,
+	ctx context.Context,
+) error {
+	defer tr.metrics.ObserveMongoOperation(tr.col.Name(), "UpdatePendingTransactionStatus", time.Now())
+	objectId, err := utils.StringToObjectId(transactionId)
+	if err != nil {
+		return fmt.Errorf("error when converting transaction ID to object ID for "+
+			"transactionId %s: %w", transactionId, err)
 	}
-	log.Printf("Successfully inserted transaction from "+
-		"BankAccount %s to BankAccount %s\n", details.FromBankAccountId, details.ToBankAccountId)
+	// Only active pending transactions may move to another status, which also guards against
+	// a transaction being applied and revoked concurrently
+	filter := bson.M{"_id": objectId, "type": string(model.Pending), "status": string(model.Active)}
+	update := bson.M{"$set": bson.M{"status": string(status)}}
+	result, err := tr.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("error when updating status of pending transaction %s: %w", transactionId,
+			utils.ClassifyMongoError(err))
+	}
+	if result.MatchedCount == 0 {
+		return model.ErrPendingTransactionNotActive
+	}
+	tr.logger.DebugContext(ctx, "Set status of pending transaction", logging.TransactionId(transactionId),
+		slog.String("status", string(status)))
+	return nil
+}
+
+func (tr *TransactionRepositoryMongodb) UpdateTransactionCategory(
+	transactionId string,
+	bankAccountId string,
+	category model.TransactionCategory,
+	ctx context.Context,
+) error {
+	defer tr.metrics.ObserveMongoOperation(tr.col.Name(), "UpdateTransactionCategory", time.Now())
+	objectId, err := utils.StringToObjectId(transactionId)
+	if err != nil {
+		return fmt.Errorf("error when converting transaction ID to object ID for "+
+			"transactionId %s: %w", transactionId, err)
+	}
+	bankAccountObjectId, err := utils.StringToObjectId(bankAccountId)
+	if err != nil {
+		return fmt.Errorf("error when converting BankAccount ID %s to ObjectID: %w", bankAccountId, err)
+	}
+	// The side of the transfer is picked within the update, so that the transaction is read and written at once
+	update := mongo.Pipeline{{{"$set", bson.D{
+		{"fromCategory", categorySideValue("$fromBankAccountId", bankAccountObjectId, category.Name,
+			"$fromCategory")},
+		{"fromCategorySource", categorySideValue("$fromBankAccountId", bankAccountObjectId, string(category.Source),
+			"$fromCategorySource")},
+		{"toCategory", categorySideValue("$toBankAccountId", bankAccountObjectId, category.Name, "$toCategory")},
+		{"toCategorySource", categorySideValue("$toBankAccountId", bankAccountObjectId, string(category.Source),
+			"$toCategorySource")},
+	}}}}
+	filter := bson.M{"_id": objectId, "$or": bson.A{
+		bson.M{"fromBankAccountId": bankAccountObjectId},
+		bson.M{"toBankAccountId": bankAccountObjectId},
+	}}
+	result, err := tr.col.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("error when updating category of transaction %s: %w", transactionId,
+			utils.ClassifyMongoError(err))
+	}
+	if result.MatchedCount == 0 {
+		return model.ErrNoMatchingTransaction
+	}
+	tr.logger.DebugContext(ctx, "Set category of transaction", logging.TransactionId(transactionId),
+		logging.BankAccountId(bankAccountId))
 	return nil
 }
 
+func (tr *TransactionRepositoryMongodb) GetExpiredPendingTransactions(
+	expiredBy time.Time,
+	ctx context.Context,
+) ([]model.TransactionDetailsOutput, error) {
+	defer tr.metrics.ObserveMongoOperation(tr.col.Name(), "GetExpiredPendingTransactions", time.Now())
+	filter := bson.M{
+		"type":           string(model.Pending),
+		"status":         string(model.Active),
+		"expirationDate": bson.M{"$lte": utils.TimeToTimestamp(expiredBy)},
+	}
+	cursor, err := tr.col.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error when finding pending transactions expired by %s: %w", expiredBy,
+			utils.ClassifyMongoError(err))
+	}
+
+	defer func() {
+		err := cursor.Close(ctx)
+		if err != nil {
+			tr.logger.ErrorContext(ctx, "Unable to close cursor when getting expired pending transactions",
+				logging.Err(err))
+		}
+	}()
+
+	var mongoResults []mongodb.MongoTransactionOutput
+	if err = cursor.All(ctx, &mongoResults); err != nil {
+		return nil, fmt.Errorf("error when iterating over mongo Cursor when getting expired pending "+
+			"transactions: %w", utils.ClassifyMongoError(err))
+	}
+	res := make([]model.TransactionDetailsOutput, len(mongoResults))
+	for i, elem := range mongoResults {
+		details, err := fromMongoTransactionDetails(&elem)
+		if err != nil {
+			return nil, fmt.Errorf("error when converting mongo transaction to domain transaction: %w", err)
+		}
+		res[i] = *details
+	}
+	return res, nil
+}
+
 func (tr *TransactionRepositoryMongodb) GetTransactionsFromBankAccountId(
 	input *model.TransactionsForBankAccountInput,
 	ctx context.Context,
 ) ([]model.BankAccountTransactionOutput, error) {
+	defer tr.metrics.ObserveMongoOperation(tr.col.Name(), "GetTransactionsFromBankAccountId", time.Now())
 	var res []model.BankAccountTransactionOutput
 	mongoInput, err := fromDomainTransactionForBankAccountInput(input)
 	if err != nil {
@@ -60,33 +217,13 @@ func (tr *TransactionRepositoryMongodb) GetTransactionsFromBankAccountId(
 				{"$lte", mongoInput.ToTime},
 			}},
 		}}},
-		// Add a new field 'transactionType' to indicate debit or credit transaction
-		{{"$addFields", bson.D{
-			{"transactionNature", bson.D{{"$cond", bson.A{
-				bson.D{{"$eq", bson.A{"$fromBankAccountId", mongoInput.BankAccountId}}},
-				"credit",
-				"debit",
-			}}}}},
-		}},
-		{{"$project", bson.D{
-			{"_id", 1},
-			{"_createdAt", 1},
-			{"amount", 1},
-			{"transactionNature", 1},
-			{"type", 1},
-			{"status", 1},
-			{"expirationDate", 1},
-			{"bankAccountId", mongoInput.BankAccountId},
-			{"otherBankAccountId", bson.D{{"$cond", bson.A{
-				bson.D{{"$eq", bson.A{"$fromBankAccountId", mongoInput.BankAccountId}}},
-				"$toBankAccountId",
-				"$fromBankAccountId",
-			}}}},
-		}}}}
+	}
+	pipeline = append(pipeline, bankAccountTransactionStages(mongoInput.BankAccountId)...)
 
 	cursor, err := tr.col.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, fmt.Errorf("error when aggregating transactions for BankAccount %s: %w", input.BankAccountId, err)
+		return nil, fmt.Errorf("error when aggregating transactions for BankAccount %s: %w", input.BankAccountId,
+			utils.ClassifyMongoError(err))
 	}
 
 	var mongoResults []mongodb.MongoAccountTransactionOutput
@@ -94,19 +231,79 @@ func (tr *TransactionRepositoryMongodb) GetTransactionsFromBankAccountId(
 	defer func() {
 		err := cursor.Close(ctx)
 		if err != nil {
-			log.Printf("Error when closing mongo Cursor when getting BankAccount Transactions "+
-				"for BankAccount %s", input.BankAccountId)
+			tr.logger.ErrorContext(ctx, "Unable to close cursor when getting bank account transactions",
+				logging.BankAccountId(input.BankAccountId), logging.Err(err))
 		}
 	}()
 
 	if err = cursor.All(ctx, &mongoResults); err != nil {
 		return nil, fmt.Errorf("error when iterating over mongo Cursor when getting BankAccount Transactions "+
-			"for BankAccount %s: %w", input.BankAccountId, err)
+			"for BankAccount %s: %w", input.BankAccountId, utils.ClassifyMongoError(err))
 	}
 	if res, err = fromMongoAccountTransaction(mongoResults); err != nil {
 		return nil, fmt.Errorf("error when converting mongo BankAccount Transactions to domain BankAccount "+
 			"Transactions for BankAccount %s: %w", input.BankAccountId, err)
 	}
-	log.Printf("Successfully retrieved BankAccount Transactions for BankAccount %s\n", input.BankAccountId)
+	tr.logger.DebugContext(ctx, "Retrieved bank account transactions", logging.BankAccountId(input.BankAccountId))
 	return res, nil
 }
+
+// GetTransactionsPageFromBankAccountId lists the transactions of the bank account matching the filter, ordered by
+// creation time and then by ID so that the cursor of the last transaction marks a stable position in the listing
+func (tr *TransactionRepositoryMongodb) GetTransactionsPageFromBankAccountId(
+	input *model.TransactionPageInput,
+	ctx context.Context,
+) (*model.TransactionPageOutput, error) {
+	defer tr.metrics.ObserveMongoOperation(tr.col.Name(), "GetTransactionsPageFromBankAccountId", time.Now())
+	bankAccountId, err := utils.StringToObjectId(input.BankAccountId)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting bank account ID to ObjectID: %w", err)
+	}
+	match, err := transactionPageMatch(bankAccountId, input)
+	if err != nil {
+		return nil, err
+	}
+	direction := 1
+	if input.Order == model.Descending {
+		direction = -1
+	}
+	pipeline := mongo.Pipeline{
+		{{"$match", match}},
+		{{"$sort", bson.D{{"_createdAt", direction}, {"_id", direction}}}},
+		// Fetch one transaction past the page to find out whether there is a next page
+		{{"$limit", input.PageSize + 1}},
+	}
+	pipeline = append(pipeline, bankAccountTransactionStages(bankAccountId)...)
+
+	cursor, err := tr.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("error when aggregating transactions page for BankAccount %s: %w",
+			input.BankAccountId, utils.ClassifyMongoError(err))
+	}
+
+	defer func() {
+		err := cursor.Close(ctx)
+		if err != nil {
+			tr.logger.ErrorContext(ctx, "Unable to close cursor when getting bank account transactions page",
+				logging.BankAccountId(input.BankAccountId), logging.Err(err))
+		}
+	}()
+
+	var mongoResults []mongodb.MongoAccountTransactionOutput
+	if err = cursor.All(ctx, &mongoResults); err != nil {
+		return nil, fmt.Errorf("error when iterating over mongo Cursor when getting BankAccount Transactions "+
+			"page for BankAccount %s: %w", input.BankAccountId, utils.ClassifyMongoError(err))
+	}
+	var next *model.TransactionCursor
+	if len(mongoResults) > input.PageSize {
+		mongoResults = mongoResults[:input.PageSize]
+		last := mongoResults[input.PageSize-1]
+		next = &model.TransactionCursor{CreatedAt: utils.TimestampToTime(last.CreatedAt), Id: last.Id.Hex()}
+	}
+	transactions, err := fromMongoAccountTransaction(mongoResults)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting mongo BankAccount Transactions to domain BankAccount "+
+			"Transactions for BankAccount %s: %w", input.BankAccountId, err)
+	}
+	return &model.TransactionPageOutput{Transactions: transactions, Next: next}, nil
+}
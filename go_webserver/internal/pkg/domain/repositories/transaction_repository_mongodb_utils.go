@@ -2,7 +2,10 @@ package repositories
 
 import (
 	"fmt"
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"time"
 	"webserver/internal/pkg/domain/model"
 	"webserver/internal/pkg/infrastructure/mongodb"
@@ -30,13 +33,71 @@ func fromDomainTransactionDetails(details *model.TransactionDetailsInput) (*mong
 	status := string(details.Status)
 
 	return &mongodb.MongoTransactionInput{
-		FromBankAccountId: fromAccount,
-		ToBankAccountId:   toAccount,
-		Amount:            decimal128Amount,
-		CreatedAt:         utils.GetCurrentTimestamp(),
+		FromBankAccountId:  fromAccount,
+		ToBankAccountId:    toAccount,
+		Amount:             decimal128Amount,
+		CreatedAt:          utils.GetCurrentTimestamp(),
+		Type:               transactionType,
+		ExpirationDate:     expirationDate,
+		Status:             status,
+		Memo:               details.Memo,
+		FromCategory:       details.FromCategory.Name,
+		FromCategorySource: string(details.FromCategory.Source),
+		ToCategory:         details.ToCategory.Name,
+		ToCategorySource:   string(details.ToCategory.Source),
+	}, nil
+}
+
+func fromMongoTransactionDetails(details *mongodb.MongoTransactionOutput) (*model.TransactionDetailsOutput, error) {
+	transactionId, err := utils.ObjectIdToString(details.Id)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting transaction ID to string: %w", err)
+	}
+	fromAccountId, err := utils.ObjectIdToString(details.FromBankAccountId)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting fromAccount to string for transaction %s: %w",
+			transactionId, err)
+	}
+	toAccountId, err := utils.ObjectIdToString(details.ToBankAccountId)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting toAccount to string for transaction %s: %w",
+			transactionId, err)
+	}
+	decimalAmount, err := utils.FromPrimitiveDecimal128ToDecimal(details.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("error when converting amount to decimal for transaction %s: %w", transactionId, err)
+	}
+	transactionType, err := toTransactionType(details.Type)
+	if err != nil {
+		return nil, fmt.Errorf("error when parsing transactionType for transaction %s: %w", transactionId, err)
+	}
+	var status model.PendingTransactionStatus
+	var expirationDate time.Time
+	if transactionType == model.Pending {
+		status, err = toPendingTransactionStatus(details.Status)
+		if err != nil {
+			return nil, fmt.Errorf("error when parsing pendingTransactionStatus for transaction %s: %w",
+				transactionId, err)
+		}
+		expirationDate = utils.TimestampToTime(details.ExpirationDate)
+	}
+	return &model.TransactionDetailsOutput{
+		Id:                transactionId,
+		FromBankAccountId: fromAccountId,
+		ToBankAccountId:   toAccountId,
+		Amount:            decimalAmount,
 		Type:              transactionType,
 		ExpirationDate:    expirationDate,
 		Status:            status,
+		Memo:              details.Memo,
+		FromCategory: model.TransactionCategory{
+			Name:   details.FromCategory,
+			Source: model.CategorySource(details.FromCategorySource),
+		},
+		ToCategory: model.TransactionCategory{
+			Name:   details.ToCategory,
+			Source: model.CategorySource(details.ToCategorySource),
+		},
 	}, nil
 }
 
@@ -102,6 +163,11 @@ func fromMongoAccountTransaction(
 			Status:             pendingTransactionStatus,
 			Amount:             decimalAmount,
 			CreatedAt:          utils.TimestampToTime(elem.CreatedAt),
+			Memo:               elem.Memo,
+			Category: model.TransactionCategory{
+				Name:   elem.Category,
+				Source: model.CategorySource(elem.CategorySource),
+			},
 		}
 	}
 	return res, nil
@@ -155,3 +221,135 @@ func toTransactionNature(nature string) (model.TransactionNature, error) {
 		return "", fmt.Errorf("invalid transaction nature: %s", nature)
 	}
 }
+
+// bankAccountTransactionStages shape matched transactions into transactions seen from the given bank account
+func bankAccountTransactionStages(bankAccountId primitive.ObjectID) mongo.Pipeline {
+	return mongo.Pipeline{
+		// Add a new field 'transactionType' to indicate debit or credit transaction
+		{{"$addFields", bson.D{
+			{"transactionNature", bson.D{{"$cond", bson.A{
+				bson.D{{"$eq", bson.A{"$fromBankAccountId", bankAccountId}}},
+				"credit",
+				"debit",
+			}}}}},
+		}},
+		{{"$project", bson.D{
+			{"_id", 1},
+			{"_createdAt", 1},
+			{"amount", 1},
+			{"transactionNature", 1},
+			{"type", 1},
+			{"status", 1},
+			{"expirationDate", 1},
+			{"memo", 1},
+			{"bankAccountId", bankAccountId},
+			{"otherBankAccountId", bson.D{{"$cond", bson.A{
+				bson.D{{"$eq", bson.A{"$fromBankAccountId", bankAccountId}}},
+				"$toBankAccountId",
+				"$fromBankAccountId",
+			}}}},
+			// Each side of a transfer categorizes it on its own
+			{"category", bson.D{{"$cond", bson.A{
+				bson.D{{"$eq", bson.A{"$fromBankAccountId", bankAccountId}}},
+				"$fromCategory",
+				"$toCategory",
+			}}}},
+			{"categorySource", bson.D{{"$cond", bson.A{
+				bson.D{{"$eq", bson.A{"$fromBankAccountId", bankAccountId}}},
+				"$fromCategorySource",
+				"$toCategorySource",
+			}}}},
+		}}},
+	}
+}
+
+// transactionPageMatch builds the $match stage selecting the transactions of a page. Nature and counterparty filters
+// are folded into the branches on fromBankAccountId and toBankAccountId so that both can be served by an index.
+func transactionPageMatch(bankAccountId primitive.ObjectID, input *model.TransactionPageInput) (bson.D, error) {
+	filter := input.Filter
+	var counterpartyId primitive.ObjectID
+	if filter.CounterpartyBankAccountId != "" {
+		var err error
+		counterpartyId, err = utils.StringToObjectId(filter.CounterpartyBankAccountId)
+		if err != nil {
+			return nil, fmt.Errorf("%w: counterparty must be a BankAccount ID", model.ErrInvalidTransactionQuery)
+		}
+	}
+	branches := bson.A{}
+	if filter.TransactionNature != model.Debit {
+		branch := bson.D{{"fromBankAccountId", bankAccountId}}
+		if filter.CounterpartyBankAccountId != "" {
+			branch = append(branch, bson.E{Key: "toBankAccountId", Value: counterpartyId})
+		}
+		branches = append(branches, branch)
+	}
+	if filter.TransactionNature != model.Credit {
+		branch := bson.D{{"toBankAccountId", bankAccountId}}
+		if filter.CounterpartyBankAccountId != "" {
+			branch = append(branch, bson.E{Key: "fromBankAccountId", Value: counterpartyId})
+		}
+		branches = append(branches, branch)
+	}
+	conditions := bson.A{bson.D{{"$or", branches}}}
+
+	createdAt := bson.D{}
+	if !input.FromTime.IsZero() {
+		createdAt = append(createdAt, bson.E{Key: "$gte", Value: utils.TimeToTimestamp(input.FromTime)})
+	}
+	if !input.ToTime.IsZero() {
+		createdAt = append(createdAt, bson.E{Key: "$lte", Value: utils.TimeToTimestamp(input.ToTime)})
+	}
+	if len(createdAt) > 0 {
+		conditions = append(conditions, bson.D{{"_createdAt", createdAt}})
+	}
+	if filter.TransactionType != "" {
+		conditions = append(conditions, bson.D{{"type", string(filter.TransactionType)}})
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, bson.D{{"status", string(filter.Status)}})
+	}
+	amount := bson.D{}
+	for _, bound := range []struct {
+		operator string
+		value    decimal.NullDecimal
+	}{{"$gte", filter.MinAmount}, {"$lte", filter.MaxAmount}} {
+		if !bound.value.Valid {
+			continue
+		}
+		decimal128Amount, err := utils.FromDecimalToPrimitiveDecimal128(bound.value.Decimal)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid amount %s", model.ErrInvalidTransactionQuery, bound.value.Decimal)
+		}
+		amount = append(amount, bson.E{Key: bound.operator, Value: decimal128Amount})
+	}
+	if len(amount) > 0 {
+		conditions = append(conditions, bson.D{{"amount", amount}})
+	}
+
+	if input.After != nil {
+		afterId, err := utils.StringToObjectId(input.After.Id)
+		if err != nil {
+			return nil, model.ErrInvalidTransactionCursor
+		}
+		operator := "$gt"
+		if input.Order == model.Descending {
+			operator = "$lt"
+		}
+		afterCreatedAt := utils.TimeToTimestamp(input.After.CreatedAt)
+		conditions = append(conditions, bson.D{{"$or", bson.A{
+			bson.D{{"_createdAt", bson.D{{operator, afterCreatedAt}}}},
+			bson.D{{"_createdAt", afterCreatedAt}, {"_id", bson.D{{operator, afterId}}}},
+		}}})
+	}
+	return bson.D{{"$and", conditions}}, nil
+}
+
+// categorySideValue is the value of a category field of a side of a transfer in an update pipeline, which only
+// changes when the bank account is on that side
+func categorySideValue(side string, bankAccountId primitive.ObjectID, value string, current string) bson.D {
+	return bson.D{{"$cond", bson.A{
+		bson.D{{"$eq", bson.A{side, bankAccountId}}},
+		bson.D{{"$literal", value}},
+		current,
+	}}}
+}
@@ -0,0 +1,208 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
+)
+
+// insertionOrder lists rows in the order they were inserted, like the other backends do when no order is given
+const insertionOrder = "rowid"
+
+type TransactionRepositorySQLite struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func CreateNewTransactionRepositorySQLite(db *sql.DB, logger *slog.Logger) *TransactionRepositorySQLite {
+	return &TransactionRepositorySQLite{db: db, logger: logger}
+}
+
+func (tr *TransactionRepositorySQLite) AddTransaction(
+	details *model.TransactionDetailsInput,
+	ctx context.Context,
+) (string, error) {
+	for _, bankAccountId := range []string{details.FromBankAccountId, details.ToBankAccountId} {
+		if _, err := utils.StringToObjectId(bankAccountId); err != nil {
+			return "", fmt.Errorf("error when converting BankAccount %s to ObjectID: %w", bankAccountId, err)
+		}
+	}
+	transactionId := primitive.NewObjectID().Hex()
+	_, err := sqlite.Executor(ctx, tr.db).ExecContext(ctx, `INSERT INTO bank_transaction
+		(id, from_bank_account_id, to_bank_account_id, amount, type, status, expiration_date, memo, from_category,
+		from_category_source, to_category, to_category_source, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		transactionId, details.FromBankAccountId, details.ToBankAccountId, details.Amount, string(details.Type),
+		string(details.Status), toSQLiteTime(details.ExpirationDate), details.Memo, details.FromCategory.Name,
+		string(details.FromCategory.Source), details.ToCategory.Name, string(details.ToCategory.Source),
+		toSQLiteTime(currentTime()))
+	if err != nil {
+		return "", fmt.Errorf("error when inserting transaction from BankAccount %s to BankAccount %s: %w",
+			details.FromBankAccountId, details.ToBankAccountId, err)
+	}
+	tr.logger.DebugContext(ctx, "Inserted transaction", logging.TransactionId(transactionId),
+		logging.FromBankAccountId(details.FromBankAccountId), logging.ToBankAccountId(details.ToBankAccountId))
+	return transactionId, nil
+}
+
+func (tr *TransactionRepositorySQLite) GetTransactionFromId(
+	transactionId string,
+	ctx context.Context,
+) (*model.TransactionDetailsOutput, error) {
+	if _, err := utils.StringToObjectId(transactionId); err != nil {
+		return nil, fmt.Errorf("error when converting transaction ID to object ID for "+
+			"transactionId %s: %w", transactionId, err)
+	}
+	transactions, err := tr.findTransactions(ctx, "id = ?", insertionOrder, transactionId)
+	if err != nil {
+		return nil, fmt.Errorf("error when finding transaction by ID %s: %w", transactionId, err)
+	}
+	if len(transactions) == 0 {
+		return nil, model.ErrNoMatchingTransaction
+	}
+	return &transactions[0].details, nil
+}
+
+func (tr *TransactionRepositorySQLite) UpdatePendingTransactionStatus(
+	transactionId string,
+	status model.PendingTransactionStatus,
+	ctx context.Context,
+) error {
+	if _, err := utils.StringToObjectId(transactionId); err != nil {
+		return fmt.Errorf("error when converting transaction ID to object ID for "+
+			"transactionId %s: %w", transactionId, err)
+	}
+	// Only active pending transactions may move to another status, which also guards against
+	// a transaction being applied and revoked concurrently
+	res, err := sqlite.Executor(ctx, tr.db).ExecContext(ctx, `UPDATE bank_transaction SET status = ?
+		WHERE id = ? AND type = ? AND status = ?`,
+		string(status), transactionId, string(model.Pending), string(model.Active))
+	if err != nil {
+		return fmt.Errorf("error when updating status of pending transaction %s: %w", transactionId, err)
+	}
+	if updated, err := res.RowsAffected(); err != nil || updated == 0 {
+		return model.ErrPendingTransactionNotActive
+	}
+	tr.logger.DebugContext(ctx, "Set status of pending transaction", logging.TransactionId(transactionId),
+		slog.String("status", string(status)))
+	return nil
+}
+
+func (tr *TransactionRepositorySQLite) UpdateTransactionCategory(
+	transactionId string,
+	bankAccountId string,
+	category model.TransactionCategory,
+	ctx context.Context,
+) error {
+	if _, err := utils.StringToObjectId(transactionId); err != nil {
+		return fmt.Errorf("error when converting transaction ID to object ID for "+
+			"transactionId %s: %w", transactionId, err)
+	}
+	res, err := sqlite.Executor(ctx, tr.db).ExecContext(ctx, `UPDATE bank_transaction SET
+		from_category = CASE WHEN from_bank_account_id = ?1 THEN ?2 ELSE from_category END,
+		from_category_source = CASE WHEN from_bank_account_id = ?1 THEN ?3 ELSE from_category_source END,
+		to_category = CASE WHEN to_bank_account_id = ?1 THEN ?2 ELSE to_category END,
+		to_category_source = CASE WHEN to_bank_account_id = ?1 THEN ?3 ELSE to_category_source END
+		WHERE id = ?4 AND ?1 IN (from_bank_account_id, to_bank_account_id)`,
+		bankAccountId, category.Name, string(category.Source), transactionId)
+	if err != nil {
+		return fmt.Errorf("error when updating category of transaction %s: %w", transactionId, err)
+	}
+	if updated, err := res.RowsAffected(); err != nil || updated == 0 {
+		return model.ErrNoMatchingTransaction
+	}
+	tr.logger.DebugContext(ctx, "Set category of transaction", logging.TransactionId(transactionId),
+		logging.BankAccountId(bankAccountId))
+	return nil
+}
+
+func (tr *TransactionRepositorySQLite) GetExpiredPendingTransactions(
+	expiredBy time.Time,
+	ctx context.Context,
+) ([]model.TransactionDetailsOutput, error) {
+	transactions, err := tr.findTransactions(ctx, "type = ? AND status = ? AND expiration_date <= ?",
+		insertionOrder, string(model.Pending), string(model.Active), toSQLiteTime(expiredBy))
+	if err != nil {
+		return nil, fmt.Errorf("error when finding pending transactions expired by %s: %w", expiredBy, err)
+	}
+	res := make([]model.TransactionDetailsOutput, len(transactions))
+	for i, transaction := range transactions {
+		res[i] = transaction.details
+	}
+	return res, nil
+}
+
+func (tr *TransactionRepositorySQLite) GetTransactionsFromBankAccountId(
+	input *model.TransactionsForBankAccountInput,
+	ctx context.Context,
+) ([]model.BankAccountTransactionOutput, error) {
+	if _, err := utils.StringToObjectId(input.BankAccountId); err != nil {
+		return nil, fmt.Errorf("error when converting bank account ID to ObjectID: %w", err)
+	}
+	transactions, err := tr.findTransactions(ctx,
+		"(from_bank_account_id = ? OR to_bank_account_id = ?) AND created_at BETWEEN ? AND ?", insertionOrder,
+		input.BankAccountId, input.BankAccountId, toSQLiteTime(input.FromTime), toSQLiteTime(input.ToTime))
+	if err != nil {
+		return nil, fmt.Errorf("error when finding transactions for BankAccount %s: %w", input.BankAccountId, err)
+	}
+	tr.logger.DebugContext(ctx, "Retrieved bank account transactions", logging.BankAccountId(input.BankAccountId))
+	return fromSQLiteAccountTransactions(input.BankAccountId, transactions), nil
+}
+
+// GetTransactionsPageFromBankAccountId lists the transactions of the bank account matching the filter, ordered by
+// creation time and then by ID so that the cursor of the last transaction marks a stable position in the listing
+func (tr *TransactionRepositorySQLite) GetTransactionsPageFromBankAccountId(
+	input *model.TransactionPageInput,
+	ctx context.Context,
+) (*model.TransactionPageOutput, error) {
+	if _, err := utils.StringToObjectId(input.BankAccountId); err != nil {
+		return nil, fmt.Errorf("error when converting bank account ID to ObjectID: %w", err)
+	}
+	condition, args, err := transactionPageMatchSQLite(input)
+	if err != nil {
+		return nil, err
+	}
+	order := "ASC"
+	if input.Order == model.Descending {
+		order = "DESC"
+	}
+	// One transaction more than the page holds tells whether there is a next page
+	transactions, err := tr.findTransactions(ctx, condition,
+		fmt.Sprintf("created_at %s, id %s LIMIT %d", order, order, input.PageSize+1), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error when finding transactions page for BankAccount %s: %w",
+			input.BankAccountId, err)
+	}
+	var next *model.TransactionCursor
+	if len(transactions) > input.PageSize {
+		transactions = transactions[:input.PageSize]
+		last := transactions[input.PageSize-1]
+		next = &model.TransactionCursor{CreatedAt: last.createdAt, Id: last.details.Id}
+	}
+	return &model.TransactionPageOutput{
+		Transactions: fromSQLiteAccountTransactions(input.BankAccountId, transactions),
+		Next:         next,
+	}, nil
+}
+
+// findTransactions returns the transactions matching the condition, listed in the given order and limit
+func (tr *TransactionRepositorySQLite) findTransactions(
+	ctx context.Context,
+	condition string,
+	orderBy string,
+	args ...any,
+) ([]transactionRow, error) {
+	query := "SELECT " + transactionColumns + " FROM bank_transaction WHERE " + condition + " ORDER BY " + orderBy
+	rows, err := sqlite.Executor(ctx, tr.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanTransactions(rows)
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/utils"
+)
+
+// transactionColumns are the columns of a transaction read by scanTransactions, in order
+const transactionColumns = "id, from_bank_account_id, to_bank_account_id, amount, type, status, expiration_date, " +
+	"memo, from_category, from_category_source, to_category, to_category_source, created_at"
+
+type transactionRow struct {
+	details   model.TransactionDetailsOutput
+	createdAt time.Time
+}
+
+func scanTransactions(rows *sql.Rows) ([]transactionRow, error) {
+	defer rows.Close()
+	res := make([]transactionRow, 0)
+	for rows.Next() {
+		var row transactionRow
+		var expirationDate, createdAt int64
+		err := rows.Scan(&row.details.Id, &row.details.FromBankAccountId, &row.details.ToBankAccountId,
+			&row.details.Amount, &row.details.Type, &row.details.Status, &expirationDate, &row.details.Memo,
+			&row.details.FromCategory.Name, &row.details.FromCategory.Source, &row.details.ToCategory.Name,
+			&row.details.ToCategory.Source, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		if row.details.Type == model.Pending {
+			row.details.ExpirationDate = fromSQLiteTime(expirationDate)
+		} else {
+			row.details.Status = ""
+		}
+		row.createdAt = fromSQLiteTime(createdAt)
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
+// fromSQLiteAccountTransactions shapes the transactions into transactions seen from the given bank account
+func fromSQLiteAccountTransactions(
+	bankAccountId string,
+	transactions []transactionRow,
+) []model.BankAccountTransactionOutput {
+	res := make([]model.BankAccountTransactionOutput, len(transactions))
+	for i, row := range transactions {
+		transaction := row.details
+		nature, otherBankAccountId, category := model.Debit, transaction.FromBankAccountId, transaction.ToCategory
+		if transaction.FromBankAccountId == bankAccountId {
+			nature, otherBankAccountId, category = model.Credit, transaction.ToBankAccountId, transaction.FromCategory
+		}
+		res[i] = model.BankAccountTransactionOutput{
+			Id:                 transaction.Id,
+			BankAccountId:      bankAccountId,
+			OtherBankAccountId: otherBankAccountId,
+			TransactionNature:  nature,
+			TransactionType:    transaction.Type,
+			Amount:             transaction.Amount,
+			CreatedAt:          row.createdAt,
+			Status:             transaction.Status,
+			ExpirationDate:     transaction.ExpirationDate,
+			Memo:               transaction.Memo,
+			Category:           category,
+		}
+	}
+	return res
+}
+
+// transactionPageMatchSQLite builds the condition and its arguments selecting the transactions of a page, matching the
+// same transactions as transactionPageMatch
+func transactionPageMatchSQLite(input *model.TransactionPageInput) (string, []any, error) {
+	filter := input.Filter
+	var conditions []string
+	var args []any
+	sides := make([]string, 0, 2)
+	if filter.TransactionNature != model.Debit {
+		sides = append(sides, "from_bank_account_id = ? AND (? = '' OR to_bank_account_id = ?)")
+		args = append(args, input.BankAccountId, filter.CounterpartyBankAccountId, filter.CounterpartyBankAccountId)
+	}
+	if filter.TransactionNature != model.Credit {
+		sides = append(sides, "to_bank_account_id = ? AND (? = '' OR from_bank_account_id = ?)")
+		args = append(args, input.BankAccountId, filter.CounterpartyBankAccountId, filter.CounterpartyBankAccountId)
+	}
+	conditions = append(conditions, "(("+strings.Join(sides, ") OR (")+"))")
+	if filter.CounterpartyBankAccountId != "" {
+		if _, err := utils.StringToObjectId(filter.CounterpartyBankAccountId); err != nil {
+			return "", nil, fmt.Errorf("%w: counterparty must be a BankAccount ID", model.ErrInvalidTransactionQuery)
+		}
+	}
+	if !input.FromTime.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, toSQLiteTime(input.FromTime))
+	}
+	if !input.ToTime.IsZero() {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, toSQLiteTime(input.ToTime))
+	}
+	if filter.TransactionType != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, string(filter.TransactionType))
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, string(filter.Status))
+	}
+	if filter.MinAmount.Valid {
+		conditions = append(conditions, "decimal_cmp(amount, ?) >= 0")
+		args = append(args, filter.MinAmount.Decimal)
+	}
+	if filter.MaxAmount.Valid {
+		conditions = append(conditions, "decimal_cmp(amount, ?) <= 0")
+		args = append(args, filter.MaxAmount.Decimal)
+	}
+	if input.After != nil {
+		if _, err := utils.StringToObjectId(input.After.Id); err != nil {
+			return "", nil, model.ErrInvalidTransactionCursor
+		}
+		// Hex ObjectIDs sort like the ObjectIDs themselves
+		if input.Order == model.Descending {
+			conditions = append(conditions, "(created_at, id) < (?, ?)")
+		} else {
+			conditions = append(conditions, "(created_at, id) > (?, ?)")
+		}
+		args = append(args, toSQLiteTime(input.After.CreatedAt), input.After.Id)
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/tracing"
+)
+
+// TransactionRepositoryTraced records a span for every call to the transaction repository it wraps
+type TransactionRepositoryTraced struct {
+	next   TransactionRepository
+	tracer trace.Tracer
+}
+
+func CreateNewTransactionRepositoryTraced(
+	next TransactionRepository,
+	tracer trace.Tracer,
+) *TransactionRepositoryTraced {
+	return &TransactionRepositoryTraced{next: next, tracer: tracer}
+}
+
+func (tr *TransactionRepositoryTraced) AddTransaction(
+	details *model.TransactionDetailsInput,
+	ctx context.Context,
+) (_ string, err error) {
+	ctx, span := tr.tracer.Start(ctx, "TransactionRepository.AddTransaction")
+	defer func() { tracing.End(span, err) }()
+	return tr.next.AddTransaction(details, ctx)
+}
+
+func (tr *TransactionRepositoryTraced) GetTransactionsFromBankAccountId(
+	input *model.TransactionsForBankAccountInput,
+	ctx context.Context,
+) (_ []model.BankAccountTransactionOutput, err error) {
+	ctx, span := tr.tracer.Start(ctx, "TransactionRepository.GetTransactionsFromBankAccountId")
+	defer func() { tracing.End(span, err) }()
+	return tr.next.GetTransactionsFromBankAccountId(input, ctx)
+}
+
+func (tr *TransactionRepositoryTraced) GetTransactionsPageFromBankAccountId(
+	input *model.TransactionPageInput,
+	ctx context.Context,
+) (_ *model.TransactionPageOutput, err error) {
+	ctx, span := tr.tracer.Start(ctx, "TransactionRepository.GetTransactionsPageFromBankAccountId")
+	defer func() { tracing.End(span, err) }()
+	return tr.next.GetTransactionsPageFromBankAccountId(input, ctx)
+}
+
+func (tr *TransactionRepositoryTraced) GetTransactionFromId(
+	transactionId string,
+	ctx context.Context,
+) (_ *model.TransactionDetailsOutput, err error) {
+	ctx, span := tr.tracer.Start(ctx, "TransactionRepository.GetTransactionFromId")
+	defer func() { tracing.End(span, err) }()
+	return tr.next.GetTransactionFromId(transactionId, ctx)
+}
+
+func (tr *TransactionRepositoryTraced) UpdatePendingTransactionStatus(
+	transactionId string,
+	status model.PendingTransactionStatus,
+	ctx context.Context,
+) (err error) {
+	ctx, span := tr.tracer.Start(ctx, "TransactionRepository.UpdatePendingTransactionStatus")
+	defer func() { tracing.End(span, err) }()
+	return tr.next.UpdatePendingTransactionStatus(transactionId, status, ctx)
+}
+
+func (tr *TransactionRepositoryTraced) GetExpiredPendingTransactions(
+	expiredBy time.Time,
+	ctx context.Context,
+) (_ []model.TransactionDetailsOutput, err error) {
+	ctx, span := tr.tracer.Start(ctx, "TransactionRepository.GetExpiredPendingTransactions")
+	defer func() { tracing.End(span, err) }()
+	return tr.next.GetExpiredPendingTransactions(expiredBy, ctx)
+}
+
+func (tr *TransactionRepositoryTraced) UpdateTransactionCategory(
+	transactionId string,
+	bankAccountId string,
+	category model.TransactionCategory,
+	ctx context.Context,
+) (err error) {
+	ctx, span := tr.tracer.Start(ctx, "TransactionRepository.UpdateTransactionCategory")
+	defer func() { tracing.End(span, err) }()
+	return tr.next.UpdateTransactionCategory(transactionId, bankAccountId, category, ctx)
+}
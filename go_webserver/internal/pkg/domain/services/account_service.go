@@ -12,9 +12,24 @@ type AccountService interface {
 		ctx context.Context,
 	) (
 		[]model.BankAccountTransactionOutput, error)
+	GetBankAccountTransactionsPage(
+		input *model.TransactionPageInput,
+		ctx context.Context,
+	) (*model.TransactionPageOutput, error)
 	Login(username string, password string, ctx context.Context) (*model.AccountDetailsOutput, error)
-	GetAccountBalanceHistoryInMonths(
-		input *model.AccountHistoryInMonthsInput,
+	GetAccountBalanceHistory(
+		input *model.AccountBalanceHistoryInput,
+		ctx context.Context,
+	) (*model.AccountBalanceHistoryOutput, error)
+	IsBankAccountOwner(accountId string, bankAccountId string, ctx context.Context) (bool, error)
+	Register(input *model.RegisterAccountInput, ctx context.Context) (*model.AccountDetailsOutput, error)
+	OpenBankAccount(
+		accountId string,
+		accountType model.BankAccountType,
 		ctx context.Context,
-	) (model.AccountBalanceMonthsOutput, error)
+	) (*model.BankAccount, error)
+	GetPayees(accountId string, ctx context.Context) ([]model.KnownBankAccount, error)
+	AddPayee(accountId string, input *model.PayeeInput, ctx context.Context) (*model.KnownBankAccount, error)
+	RenamePayee(accountId string, payeeId string, nickname string, ctx context.Context) error
+	RemovePayee(accountId string, payeeId string, ctx context.Context) error
 }
@@ -5,26 +5,35 @@ import (
 	"errors"
 	"fmt"
 	"github.com/shopspring/decimal"
-	"log"
+	"log/slog"
 	"regexp"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 	"webserver/internal/pkg/domain/model"
 	"webserver/internal/pkg/domain/repositories"
 	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/utils"
 )
 
 type AccountServiceImpl struct {
-	ar   repositories.AccountRepository
-	tr   repositories.TransactionRepository
-	tran transactional.Transactional
+	ar     repositories.AccountRepository
+	tr     repositories.TransactionRepository
+	sr     repositories.BalanceSnapshotRepository
+	tran   transactional.Transactional
+	logger *slog.Logger
 }
 
 func CreateNewAccountServiceImpl(
 	ar repositories.AccountRepository,
 	tr repositories.TransactionRepository,
+	sr repositories.BalanceSnapshotRepository,
 	tran transactional.Transactional,
+	logger *slog.Logger,
 ) *AccountServiceImpl {
-	return &AccountServiceImpl{ar: ar, tr: tr, tran: tran}
+	return &AccountServiceImpl{ar: ar, tr: tr, sr: sr, tran: tran, logger: logger}
 }
 
 func validateAccountDetails(accountDetails *model.AccountDetailsOutput) error {
@@ -35,16 +44,12 @@ func validateAccountDetails(accountDetails *model.AccountDetailsOutput) error {
 	return nil
 }
 
+var accountNumberRegex = regexp.MustCompile(`^\d{3}-\d{5}-\d{1}$`)
+
 func validateAccountNumbers(accounts []model.BankAccount) error {
-	const pattern = `^\d{3}-\d{5}-\d{1}$`
-	var accountNumberRegex, err = regexp.Compile(pattern)
-	if err != nil {
-		log.Printf("Unable to compile regex pattern for account number validation with error: %v", err)
-	} else {
-		for _, account := range accounts {
-			if !accountNumberRegex.MatchString(account.AccountNumber) {
-				return errors.New("account number does not match expected pattern of XXX-XXXXX-X")
-			}
+	for _, account := range accounts {
+		if !accountNumberRegex.MatchString(account.AccountNumber) {
+			return errors.New("account number does not match expected pattern of XXX-XXXXX-X")
 		}
 	}
 	return nil
@@ -54,187 +59,743 @@ func (a *AccountServiceImpl) GetAccountDetailsFromBankAccountId(
 	bankAccountId string,
 	ctx context.Context,
 ) (*model.AccountDetailsOutput, error) {
-	getCtx, cancel := context.WithTimeout(ctx, addTimeout)
+	getCtx, cancel := withOperationDeadline(ctx)
 	defer cancel()
 	accountDetails, err := a.ar.GetAccountDetailsFromBankAccountId(bankAccountId, getCtx)
 	if err != nil {
-		log.Printf("Unable to get account details for BankAccount %s with error: %v", bankAccountId, err)
+		a.logger.ErrorContext(ctx, "Unable to get account details", logging.BankAccountId(bankAccountId),
+			logging.Err(err))
 		return nil, fmt.Errorf("unable to get account details with error: %w", err)
 	}
 	err = validateAccountDetails(accountDetails)
 	if err != nil {
-		log.Printf("Unable to successfully validate account details for BankAccount %s with error: "+
-			"%v", bankAccountId, err)
+		a.logger.ErrorContext(ctx, "Unable to validate account details", logging.BankAccountId(bankAccountId),
+			logging.Err(err))
 		return nil, fmt.Errorf("unable to validate account details with error: %w", err)
 	}
 	return accountDetails, nil
 }
 
+// IsBankAccountOwner reports whether the bank account belongs to the account. Unknown bank accounts are reported
+// as not owned so that callers cannot tell them apart from bank accounts belonging to someone else.
+func (a *AccountServiceImpl) IsBankAccountOwner(
+	accountId string,
+	bankAccountId string,
+	ctx context.Context,
+) (bool, error) {
+	getCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+	accountDetails, err := a.ar.GetAccountDetailsFromBankAccountId(bankAccountId, getCtx)
+	if err != nil {
+		if errors.Is(err, model.ErrNoMatchingBankAccount) {
+			return false, nil
+		}
+		a.logger.ErrorContext(ctx, "Unable to get owner of bank account", logging.BankAccountId(bankAccountId),
+			logging.Err(err))
+		return false, fmt.Errorf("unable to get owner of bank account with error: %w", err)
+	}
+	return accountDetails.Id == accountId, nil
+}
+
 func (a *AccountServiceImpl) GetBankAccountTransactions(
 	input *model.TransactionsForBankAccountInput,
 	ctx context.Context,
 ) ([]model.BankAccountTransactionOutput, error) {
-	getCtx, cancel := context.WithTimeout(ctx, addTimeout)
+	getCtx, cancel := withOperationDeadline(ctx)
 	defer cancel()
 
 	txnCtx, err := a.tran.BeginTransaction(getCtx, transactional.IsolationHigh, transactional.DurabilityHigh)
 	if err != nil {
-		log.Printf("Error encountered when starting Get BankAccount Transactions database transaction for "+
-			"BankAccount %s: %v", input.BankAccountId, err)
+		a.logger.ErrorContext(ctx, "Unable to begin transaction for bank account transactions",
+			logging.BankAccountId(input.BankAccountId), logging.Err(err))
 		return nil, fmt.Errorf("unable to begin transaction with error: %w", err)
 	}
 
 	defer func() {
 		if rollErr := a.tran.Rollback(txnCtx); rollErr != nil {
-			log.Printf("Error rolling back transaction: %v", rollErr)
+			a.logger.ErrorContext(ctx, "Unable to roll back transaction", logging.Err(rollErr))
 		}
 	}()
 
-	accountTransactions, err := a.tr.GetTransactionsFromBankAccountId(input, getCtx)
+	accountTransactions, err := a.tr.GetTransactionsFromBankAccountId(input, txnCtx)
 	if err != nil {
-		log.Printf(
-			"Unable to get transaction details for BankAccount %s with error: %v",
-			input.BankAccountId,
-			err,
-		)
+		a.logger.ErrorContext(ctx, "Unable to get bank account transactions",
+			logging.BankAccountId(input.BankAccountId), logging.Err(err))
 		return nil, fmt.Errorf("unable to get transaction details with error: %w", err)
 	}
 	return accountTransactions, nil
 }
 
+func validateTransactionPageInput(input *model.TransactionPageInput) error {
+	if input.PageSize < 0 || input.PageSize > maxTransactionPageSize {
+		return fmt.Errorf("%w: page size must be between 1 and %d", model.ErrInvalidTransactionQuery,
+			maxTransactionPageSize)
+	}
+	if input.Order != model.Ascending && input.Order != model.Descending {
+		return fmt.Errorf("%w: order must be asc or desc", model.ErrInvalidTransactionQuery)
+	}
+	if !input.FromTime.IsZero() && !input.ToTime.IsZero() && input.FromTime.After(input.ToTime) {
+		return fmt.Errorf("%w: fromTime must not be after toTime", model.ErrInvalidTransactionQuery)
+	}
+	filter := input.Filter
+	if filter.MinAmount.Valid && filter.MaxAmount.Valid &&
+		filter.MinAmount.Decimal.GreaterThan(filter.MaxAmount.Decimal) {
+		return fmt.Errorf("%w: minAmount must not exceed maxAmount", model.ErrInvalidTransactionQuery)
+	}
+	switch filter.TransactionNature {
+	case "", model.Credit, model.Debit:
+	default:
+		return fmt.Errorf("%w: nature must be credit or debit", model.ErrInvalidTransactionQuery)
+	}
+	switch filter.TransactionType {
+	case "", model.Realized, model.Pending:
+	default:
+		return fmt.Errorf("%w: type must be realized or pending", model.ErrInvalidTransactionQuery)
+	}
+	switch filter.Status {
+	case "", model.Active, model.Applied, model.Revoked:
+	default:
+		return fmt.Errorf("%w: status must be active, applied or revoked", model.ErrInvalidTransactionQuery)
+	}
+	return nil
+}
+
+// GetBankAccountTransactionsPage lists the transactions of the bank account one page at a time. The order defaults
+// to newest first and the page size to defaultTransactionPageSize.
+func (a *AccountServiceImpl) GetBankAccountTransactionsPage(
+	input *model.TransactionPageInput,
+	ctx context.Context,
+) (*model.TransactionPageOutput, error) {
+	pageInput := *input
+	if pageInput.Order == "" {
+		pageInput.Order = model.Descending
+	}
+	if err := validateTransactionPageInput(&pageInput); err != nil {
+		return nil, err
+	}
+	if pageInput.PageSize == 0 {
+		pageInput.PageSize = defaultTransactionPageSize
+	}
+	getCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	page, err := a.tr.GetTransactionsPageFromBankAccountId(&pageInput, getCtx)
+	if err != nil {
+		a.logger.WarnContext(ctx, "Unable to get transactions page", logging.BankAccountId(input.BankAccountId),
+			logging.Err(err))
+		if errors.Is(err, model.ErrInvalidTransactionQuery) || errors.Is(err, model.ErrInvalidTransactionCursor) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to get transactions page with error: %w", err)
+	}
+	return page, nil
+}
+
 func (a *AccountServiceImpl) Login(
 	username string,
 	password string,
 	ctx context.Context,
 ) (*model.AccountDetailsOutput, error) {
-	getCtx, cancel := context.WithTimeout(ctx, addTimeout)
+	getCtx, cancel := withOperationDeadline(ctx)
 	defer cancel()
 
 	txnCtx, err := a.tran.BeginTransaction(getCtx, transactional.IsolationLow, transactional.DurabilityLow)
 	if err != nil {
-		log.Printf("Error encountered when starting Login database transaction for "+
-			"Username %s: ", username)
+		a.logger.ErrorContext(ctx, "Unable to begin login transaction", slog.String(logging.UsernameKey, username),
+			logging.Err(err))
 		return nil, fmt.Errorf("unable to begin transaction with error: %w", err)
 	}
 
+	committed := false
 	defer func() {
+		if committed {
+			return
+		}
 		if rollErr := a.tran.Rollback(txnCtx); rollErr != nil {
-			log.Printf("Error rolling back transaction: %v", rollErr)
+			a.logger.ErrorContext(ctx, "Unable to roll back transaction", logging.Err(rollErr))
 		}
 	}()
 
-	accountDetails, err := a.ar.GetAccountDetailsFromUsername(username, getCtx)
+	credentials, err := a.ar.GetAccountCredentialsFromUsername(username, txnCtx)
 	if err != nil {
-		log.Printf("Unable to login with error: %v", err)
+		a.logger.WarnContext(ctx, "Unable to get login credentials", slog.String(logging.UsernameKey, username),
+			logging.Err(err))
 		if errors.Is(err, model.ErrNoMatchingUsername) {
+			// Spend as long as a real verification would so that response times do not reveal valid usernames
+			_, _, _ = utils.VerifyPassword(dummyPasswordHash, password)
 			return nil, model.ErrInvalidCredentials
 		}
 		return nil, fmt.Errorf("unable to login with error: %w", err)
 	}
-	exists := accountDetails.Password == password
-	if !exists {
-		log.Printf("Login failed for Username %s", username)
+	matches, needsRehash, err := utils.VerifyPassword(credentials.PasswordHash, password)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "Unable to verify password", slog.String(logging.UsernameKey, username),
+			logging.Err(err))
+		return nil, model.ErrInvalidCredentials
+	}
+	if !matches {
+		a.logger.InfoContext(ctx, "Login failed", slog.String(logging.UsernameKey, username))
 		return nil, model.ErrInvalidCredentials
 	}
+
+	accountDetails, err := a.ar.GetAccountDetailsFromUsername(username, txnCtx)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "Unable to get account details after login",
+			slog.String(logging.UsernameKey, username), logging.Err(err))
+		return nil, fmt.Errorf("unable to login with error: %w", err)
+	}
+
+	// The rehash is written in the transaction that read the credentials it verified, after the last read so that
+	// a failed write cannot abort the reads, and the transaction is only committed when there is a rehash to store
+	if needsRehash && a.rehashPassword(credentials.Id, password, txnCtx) {
+		committed = true
+		if err = a.tran.Commit(txnCtx); err != nil {
+			a.logger.ErrorContext(ctx, "Unable to commit rehashed password", logging.AccountId(credentials.Id),
+				logging.Err(err))
+		}
+	}
 	return accountDetails, nil
 }
 
-func (a *AccountServiceImpl) GetAccountBalanceHistoryInMonths(
-	input *model.AccountHistoryInMonthsInput,
+func validateRegisterAccountInput(input *model.RegisterAccountInput) error {
+	if !usernameRegex.MatchString(input.Username) {
+		return fmt.Errorf("%w: username must be 3 to 32 letters, digits, '.', '_' or '-'",
+			model.ErrInvalidAccountDetails)
+	}
+	if len(input.Password) < minPasswordLength {
+		return fmt.Errorf("%w: password must be at least %d characters long", model.ErrInvalidAccountDetails,
+			minPasswordLength)
+	}
+	if strings.TrimSpace(input.Person.FirstName) == "" || strings.TrimSpace(input.Person.LastName) == "" {
+		return fmt.Errorf("%w: first and last name are required", model.ErrInvalidAccountDetails)
+	}
+	return nil
+}
+
+func (a *AccountServiceImpl) Register(
+	input *model.RegisterAccountInput,
 	ctx context.Context,
-) (model.AccountBalanceMonthsOutput, error) {
-	bankAccountTransactionsInput := model.TransactionsForBankAccountInput{
-		BankAccountId: input.BankAccountId,
-		FromTime:      input.FromTime,
-		ToTime:        input.ToTime,
+) (*model.AccountDetailsOutput, error) {
+	if err := validateRegisterAccountInput(input); err != nil {
+		return nil, err
 	}
-	transactions, err := a.GetBankAccountTransactions(&bankAccountTransactionsInput, ctx)
-	defaultOutput := model.AccountBalanceMonthsOutput{}
+	addCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	passwordHash, err := utils.HashPassword(input.Password)
 	if err != nil {
-		log.Printf("Unable to get Account History for BankAccount %s with error: %v", input.BankAccountId, err)
-		return defaultOutput, fmt.Errorf("unable to get account history with error: %w", err)
+		a.logger.ErrorContext(ctx, "Unable to hash password", slog.String(logging.UsernameKey, input.Username),
+			logging.Err(err))
+		return nil, fmt.Errorf("unable to hash password with error: %w", err)
+	}
+	accountInput := model.AccountInput{
+		Username:     input.Username,
+		PasswordHash: passwordHash,
+		Person: model.Person{
+			FirstName: strings.TrimSpace(input.Person.FirstName),
+			LastName:  strings.TrimSpace(input.Person.LastName),
+		},
+	}
+	if _, err = a.ar.AddAccount(&accountInput, addCtx); err != nil {
+		a.logger.WarnContext(ctx, "Unable to register account", slog.String(logging.UsernameKey, input.Username),
+			logging.Err(err))
+		if errors.Is(err, model.ErrUsernameTaken) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to register account with error: %w", err)
 	}
-	availableBalance, pendingBalance, err := a.ar.GetAccountBalance(input.BankAccountId, ctx)
+	accountDetails, err := a.ar.GetAccountDetailsFromUsername(input.Username, addCtx)
 	if err != nil {
-		log.Printf("Unable to get Account Balance for BankAccount %s with error: %v", input.BankAccountId, err)
-		return defaultOutput, fmt.Errorf("unable to get account balance with error: %w", err)
-	}
-	months := getAccountBalanceMonths(
-		input.BankAccountId,
-		transactions,
-		availableBalance,
-		input.FromTime,
-		input.ToTime,
-		pendingBalance,
-	)
-	return months, nil
+		a.logger.ErrorContext(ctx, "Unable to get account details after registering",
+			slog.String(logging.UsernameKey, input.Username), logging.Err(err))
+		return nil, fmt.Errorf("unable to get registered account with error: %w", err)
+	}
+	return accountDetails, nil
 }
 
-func getAccountBalanceMonths(
-	bankAccountId string,
-	transactions []model.BankAccountTransactionOutput,
-	availableBalance decimal.Decimal,
-	fromTime time.Time,
-	toTime time.Time,
-	pendingBalance decimal.Decimal,
-) model.AccountBalanceMonthsOutput {
-	numMonths := (toTime.Year()-fromTime.Year())*12 + int(toTime.Month()) - int(fromTime.Month()) + 1
-	months := make([]model.AccountBalanceMonth, numMonths)
-	latestMonth := model.AccountBalanceMonth{
-		Month:            toTime.Month(),
-		Year:             toTime.Year(),
-		AvailableBalance: availableBalance,
-		PendingBalance:   pendingBalance,
-	}
-	months[0] = latestMonth
-	transactionsMonthMap := createTransactionsMonthMap(transactions)
-	for i := 1; i < numMonths; i++ {
-		previousMonthBalance := months[i-1]
-		currentYear := previousMonthBalance.Year
-		if previousMonthBalance.Month == time.January {
-			currentYear--
+// OpenBankAccount opens an empty bank account of the given type for the account. A freshly generated account number
+// can collide with an existing one, in which case a new number is drawn up to maxAccountNumberAttempts times.
+func (a *AccountServiceImpl) OpenBankAccount(
+	accountId string,
+	accountType model.BankAccountType,
+	ctx context.Context,
+) (*model.BankAccount, error) {
+	switch accountType {
+	case model.Savings, model.Checking, model.Investment:
+	default:
+		return nil, model.ErrInvalidBankAccountType
+	}
+	addCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	for attempt := 1; attempt <= maxAccountNumberAttempts; attempt++ {
+		accountNumber, err := utils.GenerateAccountNumber()
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate account number with error: %w", err)
+		}
+		input := model.BankAccountInput{AccountNumber: accountNumber, AccountType: accountType}
+		bankAccountId, err := a.ar.AddBankAccount(accountId, &input, addCtx)
+		if errors.Is(err, model.ErrAccountNumberTaken) {
+			a.logger.InfoContext(ctx, "Account number is already taken", logging.AccountNumber(accountNumber),
+				slog.Int("attempt", attempt), slog.Int("max_attempts", maxAccountNumberAttempts))
+			continue
+		}
+		if err != nil {
+			a.logger.ErrorContext(ctx, "Unable to open bank account", logging.AccountId(accountId),
+				slog.String("account_type", string(accountType)), logging.Err(err))
+			if errors.Is(err, model.ErrNoMatchingAccount) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("unable to open bank account with error: %w", err)
+		}
+		return &model.BankAccount{
+			Id:               bankAccountId,
+			AccountNumber:    accountNumber,
+			AccountType:      accountType,
+			PendingBalance:   decimal.Zero,
+			AvailableBalance: decimal.Zero,
+		}, nil
+	}
+	return nil, fmt.Errorf("unable to open bank account with error: %w", model.ErrAccountNumberTaken)
+}
+
+func validatePayeeNickname(nickname string) (string, error) {
+	nickname = strings.TrimSpace(nickname)
+	if len(nickname) > maxPayeeNicknameLength {
+		return "", fmt.Errorf("%w: nickname must be at most %d characters long", model.ErrInvalidPayeeNickname,
+			maxPayeeNicknameLength)
+	}
+	return nickname, nil
+}
+
+// validatePayeeAccountNumber rejects mistyped account numbers before they are looked up
+func validatePayeeAccountNumber(accountNumber string) error {
+	if !accountNumberRegex.MatchString(accountNumber) || !utils.HasValidCheckDigit(accountNumber) {
+		return fmt.Errorf("%w: account number must match the pattern XXX-XXXXX-X and end with its check digit",
+			model.ErrInvalidPayeeAccountNumber)
+	}
+	return nil
+}
+
+func (a *AccountServiceImpl) GetPayees(accountId string, ctx context.Context) ([]model.KnownBankAccount, error) {
+	getCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+	payees, err := a.ar.GetKnownBankAccounts(accountId, getCtx)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "Unable to get payees", logging.AccountId(accountId), logging.Err(err))
+		return nil, fmt.Errorf("unable to get payees with error: %w", err)
+	}
+	return payees, nil
+}
+
+// AddPayee resolves the account number against the bank accounts of all accounts and stores the matching bank
+// account as a payee of the account. Bank accounts of the account itself cannot be added as payees.
+func (a *AccountServiceImpl) AddPayee(
+	accountId string,
+	input *model.PayeeInput,
+	ctx context.Context,
+) (*model.KnownBankAccount, error) {
+	nickname, err := validatePayeeNickname(input.Nickname)
+	if err != nil {
+		return nil, err
+	}
+	if err = validatePayeeAccountNumber(input.AccountNumber); err != nil {
+		return nil, err
+	}
+	addCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	payeeDetails, err := a.ar.GetAccountDetailsFromAccountNumber(input.AccountNumber, addCtx)
+	if err != nil {
+		a.logger.WarnContext(ctx, "Unable to resolve payee account number", logging.AccountId(accountId),
+			logging.AccountNumber(input.AccountNumber), logging.Err(err))
+		if errors.Is(err, model.ErrNoMatchingBankAccount) {
+			return nil, err
 		}
-		currentMonth := time.December
-		if previousMonthBalance.Month != time.January {
-			currentMonth = previousMonthBalance.Month - 1
+		return nil, fmt.Errorf("unable to resolve account number with error: %w", err)
+	}
+	if payeeDetails.Id == accountId {
+		return nil, model.ErrPayeeIsOwnBankAccount
+	}
+	var payee *model.KnownBankAccount
+	for _, bankAccount := range payeeDetails.BankAccounts {
+		if bankAccount.AccountNumber == input.AccountNumber {
+			payee = &model.KnownBankAccount{
+				Id:            bankAccount.Id,
+				AccountNumber: bankAccount.AccountNumber,
+				AccountHolder: payeeDetails.Person.FirstName + " " + payeeDetails.Person.LastName,
+				AccountType:   bankAccount.AccountType,
+				Nickname:      nickname,
+			}
+			break
+		}
+	}
+	if payee == nil {
+		return nil, model.ErrNoMatchingBankAccount
+	}
+
+	if err = a.ar.AddKnownBankAccount(accountId, payee, addCtx); err != nil {
+		a.logger.WarnContext(ctx, "Unable to add payee", logging.AccountId(accountId),
+			slog.String(logging.PayeeIdKey, payee.Id), logging.Err(err))
+		if errors.Is(err, model.ErrPayeeAlreadyKnown) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to add payee with error: %w", err)
+	}
+	return payee, nil
+}
+
+func (a *AccountServiceImpl) RenamePayee(accountId string, payeeId string, nickname string, ctx context.Context) error {
+	nickname, err := validatePayeeNickname(nickname)
+	if err != nil {
+		return err
+	}
+	updateCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+	if err = a.ar.UpdateKnownBankAccountNickname(accountId, payeeId, nickname, updateCtx); err != nil {
+		a.logger.WarnContext(ctx, "Unable to rename payee", logging.AccountId(accountId),
+			slog.String(logging.PayeeIdKey, payeeId), logging.Err(err))
+		if errors.Is(err, model.ErrNoMatchingPayee) {
+			return err
+		}
+		return fmt.Errorf("unable to rename payee with error: %w", err)
+	}
+	return nil
+}
+
+func (a *AccountServiceImpl) RemovePayee(accountId string, payeeId string, ctx context.Context) error {
+	removeCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+	if err := a.ar.RemoveKnownBankAccount(accountId, payeeId, removeCtx); err != nil {
+		a.logger.WarnContext(ctx, "Unable to remove payee", logging.AccountId(accountId),
+			slog.String(logging.PayeeIdKey, payeeId), logging.Err(err))
+		if errors.Is(err, model.ErrNoMatchingPayee) {
+			return err
+		}
+		return fmt.Errorf("unable to remove payee with error: %w", err)
+	}
+	return nil
+}
+
+// rehashPassword upgrades a password hash created with outdated parameters. Failures are only logged since the
+// user has already been authenticated and the old hash remains valid. It reports whether the new hash was stored.
+func (a *AccountServiceImpl) rehashPassword(accountId string, password string, ctx context.Context) bool {
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "Unable to rehash password", logging.AccountId(accountId), logging.Err(err))
+		return false
+	}
+	if err = a.ar.UpdatePasswordHash(accountId, passwordHash, ctx); err != nil {
+		a.logger.ErrorContext(ctx, "Unable to store rehashed password", logging.AccountId(accountId),
+			logging.Err(err))
+		return false
+	}
+	return true
+}
+
+// GetAccountBalanceHistory splits the history of the bank account into buckets covering the time range of the
+// input. Days in UTC a bucket covers whole take their balances straight from their end-of-day snapshot when they have
+// one. The balances of the rest of the bucket are found by undoing the transactions made since, starting from the
+// snapshot of the day after or from the balances historyStartingBalances returns. Snapshots, balances and
+// transactions are all read from the snapshot of a single database transaction.
+func (a *AccountServiceImpl) GetAccountBalanceHistory(
+	input *model.AccountBalanceHistoryInput,
+	ctx context.Context,
+) (*model.AccountBalanceHistoryOutput, error) {
+	location := input.Location
+	if location == nil {
+		location = time.UTC
+	}
+	buckets, err := balanceBuckets(input.FromTime, input.ToTime, input.BucketSize, location)
+	if err != nil {
+		return nil, err
+	}
+	getCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	txnCtx, err := a.tran.BeginTransaction(getCtx, transactional.IsolationHigh, transactional.DurabilityHigh)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "Unable to begin transaction for account history",
+			logging.BankAccountId(input.BankAccountId), logging.Err(err))
+		return nil, fmt.Errorf("unable to begin transaction with error: %w", err)
+	}
+	defer func() {
+		if rollErr := a.tran.Rollback(txnCtx); rollErr != nil {
+			a.logger.ErrorContext(ctx, "Unable to roll back transaction", logging.Err(rollErr))
+		}
+	}()
+
+	snapshots, err := a.sr.GetBalanceSnapshots(input.BankAccountId, utcDay(buckets[0].StartTime),
+		utcDay(buckets[len(buckets)-1].EndTime.Add(-time.Nanosecond)), txnCtx)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "Unable to get balance snapshots", logging.BankAccountId(input.BankAccountId),
+			logging.Err(err))
+		return nil, fmt.Errorf("unable to get balance snapshots with error: %w", err)
+	}
+	parts := historyParts(buckets, snapshots)
+	if err = a.replayHistoryParts(input.BankAccountId, parts, txnCtx); err != nil {
+		return nil, err
+	}
+	mergeHistoryParts(buckets, parts)
+	slices.Reverse(buckets)
+	return &model.AccountBalanceHistoryOutput{
+		BankAccountId: input.BankAccountId,
+		BucketSize:    input.BucketSize,
+		Location:      location,
+		Buckets:       buckets,
+	}, nil
+}
+
+// historyPart is a part of a bucket of a balance history, either a whole day in UTC that was snapshotted or the
+// time between such days
+type historyPart struct {
+	model.AccountBalanceBucket
+	// bucket is the index of the bucket the part belongs to
+	bucket                int
+	openingPendingBalance decimal.Decimal
+	snapshotted           bool
+}
+
+// historyParts splits the buckets into parts, oldest first, filling the parts of snapshotted days from their
+// snapshot
+func historyParts(buckets []model.AccountBalanceBucket, snapshots []model.BalanceSnapshot) []historyPart {
+	snapshotsByDay := make(map[int64]*model.BalanceSnapshot, len(snapshots))
+	for i := range snapshots {
+		snapshotsByDay[snapshots[i].Day.Unix()] = &snapshots[i]
+	}
+	var parts []historyPart
+	for i, bucket := range buckets {
+		for start := bucket.StartTime; start.Before(bucket.EndTime); {
+			end := utcDay(start).AddDate(0, 0, 1)
+			if end.After(bucket.EndTime) {
+				end = bucket.EndTime
+			}
+			snapshot, found := snapshotsByDay[start.Unix()]
+			if found && end.Sub(start) == 24*time.Hour {
+				parts = append(parts, historyPart{
+					AccountBalanceBucket: model.AccountBalanceBucket{
+						StartTime:               start,
+						EndTime:                 end,
+						OpeningAvailableBalance: snapshot.OpeningAvailableBalance,
+						ClosingAvailableBalance: snapshot.AvailableBalance,
+						MinAvailableBalance:     snapshot.MinAvailableBalance,
+						MaxAvailableBalance:     snapshot.MaxAvailableBalance,
+						ClosingPendingBalance:   snapshot.PendingBalance,
+						TotalDebits:             snapshot.TotalDebits,
+						TotalCredits:            snapshot.TotalCredits,
+					},
+					bucket:                i,
+					openingPendingBalance: snapshot.OpeningPendingBalance,
+					snapshotted:           true,
+				})
+			} else if last := len(parts) - 1; last >= 0 && parts[last].bucket == i && !parts[last].snapshotted {
+				parts[last].EndTime = end
+			} else {
+				parts = append(parts, historyPart{
+					AccountBalanceBucket: model.AccountBalanceBucket{StartTime: start, EndTime: end},
+					bucket:               i,
+				})
+			}
+			start = end
+		}
+	}
+	return parts
+}
+
+// replayHistoryParts fills the parts that were not snapshotted by undoing transactions, walking back from the newest
+// part. The transactions of every run of such parts are read at once and undone from the opening balances of the
+// snapshotted part after the run, or from the balances historyStartingBalances returns for the newest run.
+func (a *AccountServiceImpl) replayHistoryParts(bankAccountId string, parts []historyPart, ctx context.Context) error {
+	for end := len(parts); end > 0; {
+		if parts[end-1].snapshotted {
+			end--
+			continue
 		}
-		currentAccountBalanceMonth := model.AccountBalanceMonth{
-			Month:            currentMonth,
-			Year:             currentYear,
-			AvailableBalance: previousMonthBalance.AvailableBalance,
-			PendingBalance:   previousMonthBalance.PendingBalance,
+		start := end - 1
+		for start > 0 && !parts[start-1].snapshotted {
+			start--
 		}
-		key := int(currentMonth) + currentYear*12
-		for _, transaction := range transactionsMonthMap[key] {
-			currentAccountBalanceMonth.AvailableBalance, currentAccountBalanceMonth.PendingBalance = undoTransaction(
-				transaction,
-				currentAccountBalanceMonth.AvailableBalance,
-				currentAccountBalanceMonth.PendingBalance,
-			)
+
+		var balancesTime time.Time
+		var availableBalance, pendingBalance decimal.Decimal
+		if end == len(parts) {
+			var err error
+			balancesTime, availableBalance, pendingBalance, err = a.historyStartingBalances(bankAccountId,
+				parts[end-1].EndTime, ctx)
+			if err != nil {
+				return err
+			}
+		} else {
+			balancesTime = parts[end].StartTime.Add(-time.Second)
+			availableBalance, pendingBalance = parts[end].OpeningAvailableBalance, parts[end].openingPendingBalance
+		}
+		transactions, err := a.tr.GetTransactionsFromBankAccountId(&model.TransactionsForBankAccountInput{
+			BankAccountId: bankAccountId,
+			FromTime:      parts[start].StartTime,
+			ToTime:        balancesTime,
+		}, ctx)
+		if err != nil {
+			a.logger.ErrorContext(ctx, "Unable to get account history", logging.BankAccountId(bankAccountId),
+				logging.Err(err))
+			return fmt.Errorf("unable to get account history with error: %w", err)
+		}
+		sortNewestFirst(transactions)
+
+		run := make([]model.AccountBalanceBucket, end-start)
+		for i := range run {
+			run[i] = parts[start+i].AccountBalanceBucket
+		}
+		_, openingPendingBalance := fillBalanceBuckets(run, transactions, availableBalance, pendingBalance)
+		for i := range run {
+			// Every part opens with the pending balance the part before it closes with
+			if i > 0 {
+				openingPendingBalance = run[i-1].ClosingPendingBalance
+			}
+			parts[start+i].AccountBalanceBucket = run[i]
+			parts[start+i].openingPendingBalance = openingPendingBalance
+		}
+		end = start
+	}
+	return nil
+}
+
+// mergeHistoryParts fills every bucket from its parts, which are ordered like the buckets
+func mergeHistoryParts(buckets []model.AccountBalanceBucket, parts []historyPart) {
+	for i, part := range parts {
+		bucket := &buckets[part.bucket]
+		if i == 0 || parts[i-1].bucket != part.bucket {
+			bucket.OpeningAvailableBalance = part.OpeningAvailableBalance
+			bucket.MinAvailableBalance, bucket.MaxAvailableBalance = part.MinAvailableBalance, part.MaxAvailableBalance
+			bucket.TotalDebits, bucket.TotalCredits = decimal.Zero, decimal.Zero
 		}
-		months[i] = currentAccountBalanceMonth
+		bucket.ClosingAvailableBalance, bucket.ClosingPendingBalance = part.ClosingAvailableBalance,
+			part.ClosingPendingBalance
+		bucket.MinAvailableBalance = decimal.Min(bucket.MinAvailableBalance, part.MinAvailableBalance)
+		bucket.MaxAvailableBalance = decimal.Max(bucket.MaxAvailableBalance, part.MaxAvailableBalance)
+		bucket.TotalDebits = bucket.TotalDebits.Add(part.TotalDebits)
+		bucket.TotalCredits = bucket.TotalCredits.Add(part.TotalCredits)
 	}
-	return model.AccountBalanceMonthsOutput{
-		BankAccountId: bankAccountId,
-		Months:        months,
+}
+
+// historyStartingBalances returns the balances a balance history is computed backwards from, along with the last
+// second whose transactions they include. Those are the balances of the earliest snapshot of a day ending at or after
+// endTime, so that only the transactions made between endTime and the end of that day are undone on top of the
+// history itself, or the current balances when no such day was snapshotted yet.
+func (a *AccountServiceImpl) historyStartingBalances(
+	bankAccountId string,
+	endTime time.Time,
+	ctx context.Context,
+) (time.Time, decimal.Decimal, decimal.Decimal, error) {
+	snapshot, err := a.sr.GetEarliestBalanceSnapshot(bankAccountId, endTime.Add(-24*time.Hour), ctx)
+	if err == nil {
+		return snapshot.Day.AddDate(0, 0, 1).Add(-time.Second), snapshot.AvailableBalance, snapshot.PendingBalance,
+			nil
+	}
+	if !errors.Is(err, model.ErrNoMatchingBalanceSnapshot) {
+		a.logger.ErrorContext(ctx, "Unable to get balance snapshot", logging.BankAccountId(bankAccountId),
+			logging.Err(err))
+		return time.Time{}, decimal.Zero, decimal.Zero, fmt.Errorf("unable to get balance snapshot with error: %w",
+			err)
+	}
+	now := time.Now()
+	availableBalance, pendingBalance, err := a.ar.GetAccountBalance(bankAccountId, ctx)
+	if err != nil {
+		a.logger.ErrorContext(ctx, "Unable to get account balance", logging.BankAccountId(bankAccountId),
+			logging.Err(err))
+		return time.Time{}, decimal.Zero, decimal.Zero, fmt.Errorf("unable to get account balance with error: %w",
+			err)
+	}
+	return now, availableBalance, pendingBalance, nil
+}
+
+// balanceBuckets lists the buckets from the one holding fromTime to the one holding toTime, oldest first. Bucket
+// boundaries are computed from calendar dates, so that days stay aligned to midnight across daylight saving changes.
+func balanceBuckets(
+	fromTime time.Time,
+	toTime time.Time,
+	size model.BalanceBucketSize,
+	location *time.Location,
+) ([]model.AccountBalanceBucket, error) {
+	if fromTime.After(toTime) {
+		return nil, fmt.Errorf("%w: fromTime must not be after toTime", model.ErrInvalidBalanceHistoryQuery)
 	}
+	from := fromTime.In(location)
+	year, month, day := from.Date()
+	var months, days int
+	switch size {
+	case model.DailyBuckets:
+		days = 1
+	case model.WeeklyBuckets:
+		// Weeks start on Mondays, like ISO 8601 weeks
+		day -= (int(from.Weekday()) + 6) % 7
+		days = 7
+	case model.MonthlyBuckets:
+		day, months = 1, 1
+	case model.QuarterlyBuckets:
+		day, month, months = 1, month-(month-1)%3, 3
+	default:
+		return nil, fmt.Errorf("%w: bucket size must be day, week, month or quarter",
+			model.ErrInvalidBalanceHistoryQuery)
+	}
+	var buckets []model.AccountBalanceBucket
+	start := time.Date(year, month, day, 0, 0, 0, 0, location)
+	for !start.After(toTime) {
+		if len(buckets) == maxBalanceHistoryBuckets {
+			return nil, fmt.Errorf("%w: time range must span at most %d buckets", model.ErrInvalidBalanceHistoryQuery,
+				maxBalanceHistoryBuckets)
+		}
+		year, month, day = start.Date()
+		end := time.Date(year, month+time.Month(months), day+days, 0, 0, 0, 0, location)
+		buckets = append(buckets, model.AccountBalanceBucket{StartTime: start, EndTime: end})
+		start = end
+	}
+	return buckets, nil
 }
 
-func createTransactionsMonthMap(
+// fillBalanceBuckets walks the buckets back from the given balances, undoing the transactions, newest first, made
+// after each bucket and then those made during it, and returns the balances at the start of the first bucket. Only
+// realized transactions move the available balance, so only they count towards the totals and the minimum and maximum
+// balances.
+func fillBalanceBuckets(
+	buckets []model.AccountBalanceBucket,
 	transactions []model.BankAccountTransactionOutput,
-) map[int][]model.BankAccountTransactionOutput {
-	transactionsMonthMap := make(map[int][]model.BankAccountTransactionOutput)
-	for _, transaction := range transactions {
-		month := transaction.CreatedAt.Month()
-		year := transaction.CreatedAt.Year()
-		key := int(month) + year*12
-		_, ok := transactionsMonthMap[key]
-		if !ok {
-			transactionsMonthMap[key] = make([]model.BankAccountTransactionOutput, 0)
+	availableBalance decimal.Decimal,
+	pendingBalance decimal.Decimal,
+) (decimal.Decimal, decimal.Decimal) {
+	next := 0
+	for i := len(buckets) - 1; i >= 0; i-- {
+		bucket := &buckets[i]
+		for ; next < len(transactions) && !transactions[next].CreatedAt.Before(bucket.EndTime); next++ {
+			availableBalance, pendingBalance = undoTransaction(transactions[next], availableBalance, pendingBalance)
 		}
-		transactionsMonthMap[key] = append(transactionsMonthMap[key], transaction)
+		bucket.ClosingAvailableBalance, bucket.ClosingPendingBalance = availableBalance, pendingBalance
+		bucket.MinAvailableBalance, bucket.MaxAvailableBalance = availableBalance, availableBalance
+		bucket.TotalDebits, bucket.TotalCredits = decimal.Zero, decimal.Zero
+		for ; next < len(transactions) && !transactions[next].CreatedAt.Before(bucket.StartTime); next++ {
+			transaction := transactions[next]
+			if transaction.TransactionType == model.Realized && transaction.TransactionNature == model.Debit {
+				bucket.TotalDebits = bucket.TotalDebits.Add(transaction.Amount)
+			} else if transaction.TransactionType == model.Realized {
+				bucket.TotalCredits = bucket.TotalCredits.Add(transaction.Amount)
+			}
+			availableBalance, pendingBalance = undoTransaction(transaction, availableBalance, pendingBalance)
+			bucket.MinAvailableBalance = decimal.Min(bucket.MinAvailableBalance, availableBalance)
+			bucket.MaxAvailableBalance = decimal.Max(bucket.MaxAvailableBalance, availableBalance)
+		}
+		bucket.OpeningAvailableBalance = availableBalance
 	}
-	return transactionsMonthMap
+	return availableBalance, pendingBalance
+}
+
+// sortNewestFirst orders transactions the way they are undone from the current balances. Transactions are stored to
+// the second, so those of the same second are ordered by ID like listings of transactions are.
+func sortNewestFirst(transactions []model.BankAccountTransactionOutput) {
+	sort.Slice(transactions, func(i, j int) bool {
+		if !transactions[i].CreatedAt.Equal(transactions[j].CreatedAt) {
+			return transactions[i].CreatedAt.After(transactions[j].CreatedAt)
+		}
+		return transactions[i].Id > transactions[j].Id
+	})
 }
 
 func undoTransaction(
@@ -6,9 +6,13 @@ import (
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
 	"testing"
 	"time"
 	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/logging"
+	pkgutils "webserver/internal/pkg/utils"
 	"webserver/test/mocks"
 	"webserver/test/utils"
 )
@@ -44,15 +48,55 @@ func TestGetAccountDetails(t *testing.T) {
 	})
 }
 
+func TestIsBankAccountOwner(t *testing.T) {
+	t.Run("Reports whether the bank account belongs to the account", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		stubDetails := utils.TomAccountDetailsModel
+		stubDetails.Id = "tomId"
+		mockAccRepo.On("GetAccountDetailsFromBankAccountId", "bankAccountId", mock.Anything).Return(&stubDetails, nil)
+
+		owned, err := service.IsBankAccountOwner("tomId", "bankAccountId", ctx)
+		assert.Nil(t, err)
+		assert.True(t, owned)
+		owned, err = service.IsBankAccountOwner("samId", "bankAccountId", ctx)
+		assert.Nil(t, err)
+		assert.False(t, owned)
+	})
+
+	t.Run("Reports an unknown bank account as not owned", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		mockAccRepo.On("GetAccountDetailsFromBankAccountId", mock.Anything, mock.Anything).
+			Return(nil, model.ErrNoMatchingBankAccount)
+
+		owned, err := service.IsBankAccountOwner("tomId", "bankAccountId", ctx)
+		assert.Nil(t, err)
+		assert.False(t, owned)
+	})
+
+	t.Run("Returns error if GetAccountDetailsFromBankAccountId is unsuccessful", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		mockAccRepo.On("GetAccountDetailsFromBankAccountId", mock.Anything, mock.Anything).
+			Return(nil, errors.New("cannot GetAccountDetailsFromBankAccountId"))
+
+		owned, err := service.IsBankAccountOwner("tomId", "bankAccountId", ctx)
+		assert.False(t, owned)
+		assert.Error(t, err)
+	})
+}
+
 func TestGetAccountTransaction(t *testing.T) {
 	t.Run("Returns correct output assuming happy path", func(t *testing.T) {
 		mockTranRepo, _, mockTran, service, ctx, cancel := initializeAccountMocks()
 		defer cancel()
 		stubTransactions := []model.BankAccountTransactionOutput{
-			{Id: "transactionId", BankAccountId: "accountId", Amount: decimal.NewFromFloat(123.13), CreatedAt: time.Now()},
+			{Id: "transactionId", BankAccountId: "accountId", Amount: decimal.NewFromFloat(123.13),
+				CreatedAt: time.Now()},
 		}
 		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
-		mockTranRepo.On("GetBankAccountTransactions", mock.Anything, mock.Anything).
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.Anything, mock.Anything).
 			Return(stubTransactions, nil)
 		mockTran.On("Rollback", mock.Anything).Return(nil)
 
@@ -70,7 +114,7 @@ func TestGetAccountTransaction(t *testing.T) {
 		_, err := service.GetBankAccountTransactions(&input, ctx)
 		assert.ErrorContains(t, err, "can't start transaction")
 		mockTran.AssertNumberOfCalls(t, "Rollback", 0)
-		mockTranRepo.AssertNumberOfCalls(t, "GetBankAccountTransactions", 0)
+		mockTranRepo.AssertNumberOfCalls(t, "GetTransactionsFromBankAccountId", 0)
 	})
 
 	t.Run("Returns error if GetBankAccountTransactions isn't successful", func(t *testing.T) {
@@ -78,12 +122,12 @@ func TestGetAccountTransaction(t *testing.T) {
 		defer cancel()
 		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
 		mockTran.On("Rollback", mock.Anything).Return(nil)
-		mockTranRepo.On("GetBankAccountTransactions", mock.Anything, mock.Anything).
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.Anything, mock.Anything).
 			Return(nil, errors.New("can't GetBankAccountTransactions"))
 
 		_, err := service.GetBankAccountTransactions(&input, ctx)
 		assert.ErrorContains(t, err, "can't GetBankAccountTransactions")
-		mockTranRepo.AssertNumberOfCalls(t, "GetBankAccountTransactions", 1)
+		mockTranRepo.AssertNumberOfCalls(t, "GetTransactionsFromBankAccountId", 1)
 	})
 
 	t.Run("Rollback even if returning an error", func(t *testing.T) {
@@ -91,7 +135,7 @@ func TestGetAccountTransaction(t *testing.T) {
 		defer cancel()
 		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
 		mockTran.On("Rollback", mock.Anything).Return(nil)
-		mockTranRepo.On("GetBankAccountTransactions", mock.Anything, mock.Anything).
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.Anything, mock.Anything).
 			Return(nil, errors.New("can't GetBankAccountTransactions"))
 
 		service.GetBankAccountTransactions(&input, ctx)
@@ -99,6 +143,549 @@ func TestGetAccountTransaction(t *testing.T) {
 	})
 }
 
+func TestGetBankAccountTransactionsPage(t *testing.T) {
+	t.Run("Lists newest first with the default page size", func(t *testing.T) {
+		mockTranRepo, _, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		stubPage := &model.TransactionPageOutput{Transactions: []model.BankAccountTransactionOutput{{Id: "id"}}}
+		isDefaultPage := func(input *model.TransactionPageInput) bool {
+			return input.Order == model.Descending && input.PageSize == defaultTransactionPageSize
+		}
+		mockTranRepo.On("GetTransactionsPageFromBankAccountId", mock.MatchedBy(isDefaultPage), mock.Anything).
+			Return(stubPage, nil)
+
+		res, err := service.GetBankAccountTransactionsPage(&model.TransactionPageInput{BankAccountId: "accountId"}, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, stubPage, res)
+	})
+
+	t.Run("Rejects invalid queries without listing transactions", func(t *testing.T) {
+		invalidInputs := map[string]model.TransactionPageInput{
+			"page size":   {PageSize: maxTransactionPageSize + 1},
+			"order":       {Order: "sideways"},
+			"time window": {FromTime: input.ToTime, ToTime: input.FromTime},
+			"nature":      {Filter: model.TransactionFilter{TransactionNature: "refund"}},
+			"status":      {Filter: model.TransactionFilter{Status: "cancelled"}},
+			"amount range": {Filter: model.TransactionFilter{
+				MinAmount: decimal.NewNullDecimal(decimal.NewFromInt(10)),
+				MaxAmount: decimal.NewNullDecimal(decimal.NewFromInt(5)),
+			}},
+		}
+		for name, invalidInput := range invalidInputs {
+			t.Run(name, func(t *testing.T) {
+				mockTranRepo, _, _, service, ctx, cancel := initializeAccountMocks()
+				defer cancel()
+
+				res, err := service.GetBankAccountTransactionsPage(&invalidInput, ctx)
+				assert.Nil(t, res)
+				assert.ErrorIs(t, err, model.ErrInvalidTransactionQuery)
+				mockTranRepo.AssertNotCalled(t, "GetTransactionsPageFromBankAccountId", mock.Anything, mock.Anything)
+			})
+		}
+	})
+}
+
+func TestGetAccountBalanceHistory(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatalf("Failed to load time zone: %v", err)
+	}
+	historyInput := model.AccountBalanceHistoryInput{
+		BankAccountId: "accountId",
+		FromTime:      time.Date(2024, time.March, 30, 12, 0, 0, 0, paris),
+		ToTime:        time.Date(2024, time.March, 31, 12, 0, 0, 0, paris),
+		BucketSize:    model.DailyBuckets,
+		Location:      paris,
+	}
+
+	transaction := func(
+		id string,
+		nature model.TransactionNature,
+		transactionType model.TransactionType,
+		amount string,
+		createdAt time.Time,
+	) model.BankAccountTransactionOutput {
+		return model.BankAccountTransactionOutput{Id: id, BankAccountId: "accountId", TransactionNature: nature,
+			TransactionType: transactionType, Status: model.Active, Amount: decimal.RequireFromString(amount),
+			CreatedAt: createdAt}
+	}
+	balances := func(bucket model.AccountBalanceBucket) []string {
+		return []string{bucket.OpeningAvailableBalance.String(), bucket.ClosingAvailableBalance.String(),
+			bucket.MinAvailableBalance.String(), bucket.MaxAvailableBalance.String(),
+			bucket.ClosingPendingBalance.String(), bucket.TotalDebits.String(), bucket.TotalCredits.String()}
+	}
+
+	t.Run("Splits the history into days of the time zone of the input", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockSnapshotRepo, mockTran, service, ctx, cancel := initializeSnapshotAccountMocks()
+		defer cancel()
+		transactions := []model.BankAccountTransactionOutput{
+			transaction("sent", model.Credit, model.Realized, "30", time.Date(2024, 3, 30, 10, 0, 0, 0, time.UTC)),
+			// Made on the 30th in UTC but on the 31st in Paris
+			transaction("received", model.Debit, model.Realized, "50", time.Date(2024, 3, 30, 23, 30, 0, 0, time.UTC)),
+			transaction("pending", model.Credit, model.Pending, "5", time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)),
+			transaction("later", model.Debit, model.Realized, "20", time.Date(2024, 4, 2, 8, 0, 0, 0, time.UTC)),
+		}
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockSnapshotRepo.On("GetBalanceSnapshots", "accountId", mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, nil)
+		mockSnapshotRepo.On("GetEarliestBalanceSnapshot", "accountId", mock.Anything, mock.Anything).
+			Return(nil, model.ErrNoMatchingBalanceSnapshot)
+		mockAccRepo.On("GetAccountBalance", "accountId", mock.Anything).
+			Return(decimal.RequireFromString("100"), decimal.RequireFromString("95"), nil)
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.MatchedBy(
+			func(input *model.TransactionsForBankAccountInput) bool {
+				return input.FromTime.Equal(time.Date(2024, time.March, 29, 23, 0, 0, 0, time.UTC))
+			}), mock.Anything).Return(transactions, nil)
+
+		res, err := service.GetAccountBalanceHistory(&historyInput, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, model.DailyBuckets, res.BucketSize)
+		assert.Len(t, res.Buckets, 2)
+		// The 31st is 23 hours long in Paris, which moves to summer time that day
+		assert.Equal(t, time.Date(2024, time.March, 31, 0, 0, 0, 0, paris), res.Buckets[0].StartTime)
+		assert.Equal(t, 23*time.Hour, res.Buckets[0].EndTime.Sub(res.Buckets[0].StartTime))
+		assert.Equal(t, []string{"30", "80", "30", "80", "75", "50", "0"}, balances(res.Buckets[0]))
+		assert.Equal(t, time.Date(2024, time.March, 30, 0, 0, 0, 0, paris), res.Buckets[1].StartTime)
+		assert.Equal(t, []string{"60", "30", "30", "60", "30", "0", "30"}, balances(res.Buckets[1]))
+		mockTran.AssertNumberOfCalls(t, "Rollback", 1)
+	})
+
+	t.Run("Starts from the earliest snapshot of a day ending after the history", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockSnapshotRepo, mockTran, service, ctx, cancel := initializeSnapshotAccountMocks()
+		defer cancel()
+		transactions := []model.BankAccountTransactionOutput{
+			transaction("sent", model.Credit, model.Realized, "30", time.Date(2024, 3, 30, 10, 0, 0, 0, time.UTC)),
+			transaction("received", model.Debit, model.Realized, "50", time.Date(2024, 3, 30, 23, 30, 0, 0, time.UTC)),
+			transaction("pending", model.Credit, model.Pending, "5", time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)),
+		}
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		// No day of Paris is a whole day in UTC, so the snapshot only gives the balances to start from
+		mockSnapshotRepo.On("GetBalanceSnapshots", "accountId", mock.Anything, mock.Anything, mock.Anything).
+			Return([]model.BalanceSnapshot{{
+				BankAccountId:    "accountId",
+				Day:              time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC),
+				AvailableBalance: decimal.RequireFromString("80"),
+				PendingBalance:   decimal.RequireFromString("75"),
+			}}, nil)
+		// The last day of the history ends at 22:00 UTC on the 31st, which the snapshot of the 31st in UTC follows
+		mockSnapshotRepo.On("GetEarliestBalanceSnapshot", "accountId", mock.MatchedBy(func(fromDay time.Time) bool {
+			return fromDay.Equal(time.Date(2024, time.March, 30, 22, 0, 0, 0, time.UTC))
+		}), mock.Anything).
+			Return(&model.BalanceSnapshot{
+				BankAccountId:    "accountId",
+				Day:              time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC),
+				AvailableBalance: decimal.RequireFromString("80"),
+				PendingBalance:   decimal.RequireFromString("75"),
+			}, nil)
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.MatchedBy(
+			func(input *model.TransactionsForBankAccountInput) bool {
+				return input.ToTime.Equal(time.Date(2024, time.March, 31, 23, 59, 59, 0, time.UTC))
+			}), mock.Anything).Return(transactions, nil)
+
+		res, err := service.GetAccountBalanceHistory(&historyInput, ctx)
+		assert.Nil(t, err)
+		assert.Len(t, res.Buckets, 2)
+		assert.Equal(t, []string{"30", "80", "30", "80", "75", "50", "0"}, balances(res.Buckets[0]))
+		assert.Equal(t, []string{"60", "30", "30", "60", "30", "0", "30"}, balances(res.Buckets[1]))
+		mockAccRepo.AssertNotCalled(t, "GetAccountBalance", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Reads snapshotted days from their snapshot and only replays the other days", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockSnapshotRepo, mockTran, service, ctx, cancel := initializeSnapshotAccountMocks()
+		defer cancel()
+		utcInput := model.AccountBalanceHistoryInput{
+			BankAccountId: "accountId",
+			FromTime:      time.Date(2024, time.March, 29, 12, 0, 0, 0, time.UTC),
+			ToTime:        time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC),
+			BucketSize:    model.DailyBuckets,
+		}
+		transactions := []model.BankAccountTransactionOutput{
+			transaction("sent", model.Credit, model.Realized, "30", time.Date(2024, 3, 30, 10, 0, 0, 0, time.UTC)),
+			transaction("pending", model.Credit, model.Pending, "5", time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC)),
+		}
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		// The 30th was never snapshotted
+		mockSnapshotRepo.On("GetBalanceSnapshots", "accountId", time.Date(2024, time.March, 29, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC), mock.Anything).
+			Return([]model.BalanceSnapshot{
+				{
+					BankAccountId:           "accountId",
+					Day:                     time.Date(2024, time.March, 29, 0, 0, 0, 0, time.UTC),
+					AvailableBalance:        decimal.RequireFromString("60"),
+					PendingBalance:          decimal.RequireFromString("60"),
+					OpeningAvailableBalance: decimal.RequireFromString("10"),
+					OpeningPendingBalance:   decimal.RequireFromString("10"),
+					MinAvailableBalance:     decimal.RequireFromString("10"),
+					MaxAvailableBalance:     decimal.RequireFromString("60"),
+					TotalDebits:             decimal.RequireFromString("50"),
+					TotalCredits:            decimal.Zero,
+				},
+				{
+					BankAccountId:           "accountId",
+					Day:                     time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC),
+					AvailableBalance:        decimal.RequireFromString("80"),
+					PendingBalance:          decimal.RequireFromString("75"),
+					OpeningAvailableBalance: decimal.RequireFromString("30"),
+					OpeningPendingBalance:   decimal.RequireFromString("25"),
+					MinAvailableBalance:     decimal.RequireFromString("30"),
+					MaxAvailableBalance:     decimal.RequireFromString("80"),
+					TotalDebits:             decimal.RequireFromString("50"),
+					TotalCredits:            decimal.Zero,
+				},
+			}, nil)
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.MatchedBy(
+			func(input *model.TransactionsForBankAccountInput) bool {
+				return input.FromTime.Equal(time.Date(2024, time.March, 30, 0, 0, 0, 0, time.UTC)) &&
+					input.ToTime.Equal(time.Date(2024, time.March, 30, 23, 59, 59, 0, time.UTC))
+			}), mock.Anything).Return(transactions, nil)
+
+		res, err := service.GetAccountBalanceHistory(&utcInput, ctx)
+		assert.Nil(t, err)
+		assert.Len(t, res.Buckets, 3)
+		assert.Equal(t, []string{"30", "80", "30", "80", "75", "50", "0"}, balances(res.Buckets[0]))
+		assert.Equal(t, []string{"60", "30", "30", "60", "25", "0", "30"}, balances(res.Buckets[1]))
+		assert.Equal(t, []string{"10", "60", "10", "60", "60", "50", "0"}, balances(res.Buckets[2]))
+		mockTranRepo.AssertNumberOfCalls(t, "GetTransactionsFromBankAccountId", 1)
+		mockSnapshotRepo.AssertNotCalled(t, "GetEarliestBalanceSnapshot", mock.Anything, mock.Anything,
+			mock.Anything)
+		mockAccRepo.AssertNotCalled(t, "GetAccountBalance", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects unknown bucket sizes without reading the history", func(t *testing.T) {
+		mockTranRepo, _, mockTran, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		invalidInput := historyInput
+		invalidInput.BucketSize = "year"
+
+		res, err := service.GetAccountBalanceHistory(&invalidInput, ctx)
+		assert.Nil(t, res)
+		assert.ErrorIs(t, err, model.ErrInvalidBalanceHistoryQuery)
+		mockTran.AssertNotCalled(t, "BeginTransaction", mock.Anything, mock.Anything, mock.Anything)
+		mockTranRepo.AssertNotCalled(t, "GetTransactionsFromBankAccountId", mock.Anything, mock.Anything)
+	})
+}
+
+func TestBalanceBuckets(t *testing.T) {
+	starts := func(buckets []model.AccountBalanceBucket) []string {
+		res := make([]string, len(buckets))
+		for i, bucket := range buckets {
+			res[i] = bucket.StartTime.Format(time.DateOnly)
+		}
+		return res
+	}
+
+	t.Run("Starts weeks on Mondays", func(t *testing.T) {
+		buckets, err := balanceBuckets(time.Date(2024, time.January, 3, 9, 0, 0, 0, time.UTC),
+			time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC), model.WeeklyBuckets, time.UTC)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"2024-01-01", "2024-01-08", "2024-01-15"}, starts(buckets))
+		assert.Equal(t, time.Date(2024, time.January, 22, 0, 0, 0, 0, time.UTC), buckets[2].EndTime)
+	})
+
+	t.Run("Starts quarters in January, April, July and October", func(t *testing.T) {
+		buckets, err := balanceBuckets(time.Date(2024, time.May, 20, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.November, 2, 0, 0, 0, 0, time.UTC), model.QuarterlyBuckets, time.UTC)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"2024-04-01", "2024-07-01", "2024-10-01"}, starts(buckets))
+	})
+
+	t.Run("Rejects time ranges that are reversed or span too many buckets", func(t *testing.T) {
+		fromTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		_, err := balanceBuckets(fromTime, fromTime.Add(-time.Second), model.MonthlyBuckets, time.UTC)
+		assert.ErrorIs(t, err, model.ErrInvalidBalanceHistoryQuery)
+		_, err = balanceBuckets(fromTime, fromTime.AddDate(0, 0, maxBalanceHistoryBuckets), model.DailyBuckets,
+			time.UTC)
+		assert.ErrorIs(t, err, model.ErrInvalidBalanceHistoryQuery)
+	})
+}
+
+func TestLogin(t *testing.T) {
+	passwordHash, _ := pkgutils.HashPassword("password")
+	credentials := &model.AccountCredentialsOutput{Id: "accountId", Username: "Tom", PasswordHash: passwordHash}
+
+	t.Run("Returns the account details if the password matches", func(t *testing.T) {
+		_, mockAccRepo, mockTran, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		stubDetails := &utils.TomAccountDetailsModel
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountCredentialsFromUsername", "Tom", mock.Anything).Return(credentials, nil)
+		mockAccRepo.On("GetAccountDetailsFromUsername", "Tom", mock.Anything).Return(stubDetails, nil)
+
+		res, err := service.Login("Tom", "password", ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, stubDetails, res)
+		mockAccRepo.AssertNumberOfCalls(t, "UpdatePasswordHash", 0)
+	})
+
+	t.Run("Returns invalid credentials if the password does not match", func(t *testing.T) {
+		_, mockAccRepo, mockTran, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountCredentialsFromUsername", "Tom", mock.Anything).Return(credentials, nil)
+
+		_, err := service.Login("Tom", "wrongpassword", ctx)
+		assert.ErrorIs(t, err, model.ErrInvalidCredentials)
+		mockAccRepo.AssertNumberOfCalls(t, "GetAccountDetailsFromUsername", 0)
+	})
+
+	t.Run("Returns invalid credentials if the username does not exist", func(t *testing.T) {
+		_, mockAccRepo, mockTran, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountCredentialsFromUsername", "Nobody", mock.Anything).
+			Return(nil, model.ErrNoMatchingUsername)
+
+		_, err := service.Login("Nobody", "password", ctx)
+		assert.ErrorIs(t, err, model.ErrInvalidCredentials)
+	})
+
+	t.Run("Rehashes the password if it was hashed with an outdated algorithm", func(t *testing.T) {
+		_, mockAccRepo, mockTran, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+		outdatedCredentials := &model.AccountCredentialsOutput{
+			Id: "accountId", Username: "Tom", PasswordHash: string(bcryptHash),
+		}
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Commit", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountCredentialsFromUsername", "Tom", mock.Anything).Return(outdatedCredentials, nil)
+		mockAccRepo.On("UpdatePasswordHash", "accountId", mock.MatchedBy(func(hash string) bool {
+			return strings.HasPrefix(hash, "$argon2id$")
+		}), mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountDetailsFromUsername", "Tom", mock.Anything).
+			Return(&utils.TomAccountDetailsModel, nil)
+
+		_, err := service.Login("Tom", "password", ctx)
+		assert.Nil(t, err)
+		mockAccRepo.AssertNumberOfCalls(t, "UpdatePasswordHash", 1)
+		mockTran.AssertNumberOfCalls(t, "Commit", 1)
+		mockTran.AssertNumberOfCalls(t, "Rollback", 0)
+	})
+
+	t.Run("Logs in and rolls back if the rehashed password cannot be stored", func(t *testing.T) {
+		_, mockAccRepo, mockTran, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+		outdatedCredentials := &model.AccountCredentialsOutput{
+			Id: "accountId", Username: "Tom", PasswordHash: string(bcryptHash),
+		}
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountCredentialsFromUsername", "Tom", mock.Anything).Return(outdatedCredentials, nil)
+		mockAccRepo.On("UpdatePasswordHash", "accountId", mock.Anything, mock.Anything).
+			Return(errors.New("write failed"))
+		mockAccRepo.On("GetAccountDetailsFromUsername", "Tom", mock.Anything).
+			Return(&utils.TomAccountDetailsModel, nil)
+
+		res, err := service.Login("Tom", "password", ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, &utils.TomAccountDetailsModel, res)
+		mockTran.AssertNumberOfCalls(t, "Commit", 0)
+		mockTran.AssertNumberOfCalls(t, "Rollback", 1)
+	})
+}
+
+func TestRegister(t *testing.T) {
+	registerInput := model.RegisterAccountInput{
+		Username: "Tom",
+		Password: "password",
+		Person:   model.Person{FirstName: " Tom ", LastName: "Smith"},
+	}
+
+	t.Run("Stores a hashed password and returns the new account", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		stubDetails := &utils.TomAccountDetailsModel
+		mockAccRepo.On("AddAccount", mock.MatchedBy(func(input *model.AccountInput) bool {
+			matches, _, err := pkgutils.VerifyPassword(input.PasswordHash, registerInput.Password)
+			return err == nil && matches && input.Username == "Tom" && input.Person.FirstName == "Tom"
+		}), mock.Anything).Return("tomId", nil)
+		mockAccRepo.On("GetAccountDetailsFromUsername", "Tom", mock.Anything).Return(stubDetails, nil)
+
+		res, err := service.Register(&registerInput, ctx)
+		assert.Nil(t, err)
+		assert.EqualExportedValues(t, stubDetails, res)
+		mockAccRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects invalid account details without storing them", func(t *testing.T) {
+		invalidInputs := map[string]model.RegisterAccountInput{
+			"short username":   {Username: "To", Password: "password", Person: registerInput.Person},
+			"invalid username": {Username: "Tom Smith", Password: "password", Person: registerInput.Person},
+			"short password":   {Username: "Tom", Password: "pass", Person: registerInput.Person},
+			"missing name":     {Username: "Tom", Password: "password", Person: model.Person{FirstName: "Tom"}},
+		}
+		for name, invalidInput := range invalidInputs {
+			t.Run(name, func(t *testing.T) {
+				_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+				defer cancel()
+
+				res, err := service.Register(&invalidInput, ctx)
+				assert.Nil(t, res)
+				assert.ErrorIs(t, err, model.ErrInvalidAccountDetails)
+				mockAccRepo.AssertNotCalled(t, "AddAccount", mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("Returns ErrUsernameTaken if the username is already registered", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		mockAccRepo.On("AddAccount", mock.Anything, mock.Anything).Return("", model.ErrUsernameTaken)
+
+		res, err := service.Register(&registerInput, ctx)
+		assert.Nil(t, res)
+		assert.ErrorIs(t, err, model.ErrUsernameTaken)
+	})
+}
+
+func TestOpenBankAccount(t *testing.T) {
+	t.Run("Opens an empty bank account with a valid account number", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		mockAccRepo.On("AddBankAccount", "tomId", mock.Anything, mock.Anything).Return("bankAccountId", nil)
+
+		res, err := service.OpenBankAccount("tomId", model.Savings, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "bankAccountId", res.Id)
+		assert.Equal(t, model.Savings, res.AccountType)
+		assert.True(t, pkgutils.HasValidCheckDigit(res.AccountNumber))
+		assert.True(t, res.AvailableBalance.IsZero())
+		assert.True(t, res.PendingBalance.IsZero())
+	})
+
+	t.Run("Draws a new account number if the first one is taken", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		mockAccRepo.On("AddBankAccount", "tomId", mock.Anything, mock.Anything).
+			Return("", model.ErrAccountNumberTaken).Once()
+		mockAccRepo.On("AddBankAccount", "tomId", mock.Anything, mock.Anything).Return("bankAccountId", nil).Once()
+
+		res, err := service.OpenBankAccount("tomId", model.Checking, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "bankAccountId", res.Id)
+		mockAccRepo.AssertNumberOfCalls(t, "AddBankAccount", 2)
+	})
+
+	t.Run("Gives up after too many taken account numbers", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		mockAccRepo.On("AddBankAccount", "tomId", mock.Anything, mock.Anything).Return("", model.ErrAccountNumberTaken)
+
+		res, err := service.OpenBankAccount("tomId", model.Checking, ctx)
+		assert.Nil(t, res)
+		assert.ErrorIs(t, err, model.ErrAccountNumberTaken)
+		mockAccRepo.AssertNumberOfCalls(t, "AddBankAccount", maxAccountNumberAttempts)
+	})
+
+	t.Run("Rejects unknown bank account types", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+
+		res, err := service.OpenBankAccount("tomId", "brokerage", ctx)
+		assert.Nil(t, res)
+		assert.ErrorIs(t, err, model.ErrInvalidBankAccountType)
+		mockAccRepo.AssertNotCalled(t, "AddBankAccount", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAddPayee(t *testing.T) {
+	samDetails := &model.AccountDetailsOutput{
+		Id:     "samId",
+		Person: model.Person{FirstName: "Sam", LastName: "Jones"},
+		BankAccounts: []model.BankAccount{
+			{Id: "samSavingsId", AccountNumber: "111-11111-8", AccountType: model.Savings},
+			{Id: "samCheckingId", AccountNumber: "987-65432-4", AccountType: model.Checking},
+		},
+	}
+
+	t.Run("Stores the bank account matching the account number as a payee", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		expected := model.KnownBankAccount{
+			Id:            "samCheckingId",
+			AccountNumber: "987-65432-4",
+			AccountHolder: "Sam Jones",
+			AccountType:   model.Checking,
+			Nickname:      "Sam",
+		}
+		mockAccRepo.On("GetAccountDetailsFromAccountNumber", "987-65432-4", mock.Anything).Return(samDetails, nil)
+		mockAccRepo.On("AddKnownBankAccount", "tomId", &expected, mock.Anything).Return(nil)
+
+		res, err := service.AddPayee("tomId", &model.PayeeInput{AccountNumber: "987-65432-4", Nickname: " Sam "}, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, &expected, res)
+		mockAccRepo.AssertExpectations(t)
+	})
+
+	t.Run("Does not add a bank account of the same account", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		mockAccRepo.On("GetAccountDetailsFromAccountNumber", "987-65432-4", mock.Anything).Return(samDetails, nil)
+
+		_, err := service.AddPayee("samId", &model.PayeeInput{AccountNumber: "987-65432-4"}, ctx)
+		assert.ErrorIs(t, err, model.ErrPayeeIsOwnBankAccount)
+		mockAccRepo.AssertNotCalled(t, "AddKnownBankAccount", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Returns ErrNoMatchingBankAccount for an unknown account number", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		mockAccRepo.On("GetAccountDetailsFromAccountNumber", mock.Anything, mock.Anything).
+			Return(nil, model.ErrNoMatchingBankAccount)
+
+		_, err := service.AddPayee("tomId", &model.PayeeInput{AccountNumber: "000-00000-0"}, ctx)
+		assert.ErrorIs(t, err, model.ErrNoMatchingBankAccount)
+	})
+
+	t.Run("Rejects nicknames that are too long", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+
+		input := &model.PayeeInput{AccountNumber: "987-65432-4", Nickname: strings.Repeat("a", 65)}
+		_, err := service.AddPayee("tomId", input, ctx)
+		assert.ErrorIs(t, err, model.ErrInvalidPayeeNickname)
+		mockAccRepo.AssertNotCalled(t, "GetAccountDetailsFromAccountNumber", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects account numbers with a wrong check digit or format", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+
+		for _, accountNumber := range []string{"987-65432-1", "98765432-4", "987-6543a-4"} {
+			_, err := service.AddPayee("tomId", &model.PayeeInput{AccountNumber: accountNumber}, ctx)
+			assert.ErrorIs(t, err, model.ErrInvalidPayeeAccountNumber, accountNumber)
+		}
+		mockAccRepo.AssertNotCalled(t, "GetAccountDetailsFromAccountNumber", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRenameAndRemovePayee(t *testing.T) {
+	t.Run("Renames a known payee", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		mockAccRepo.On("UpdateKnownBankAccountNickname", "tomId", "payeeId", "Sam", mock.Anything).Return(nil)
+
+		err := service.RenamePayee("tomId", "payeeId", "Sam", ctx)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Returns ErrNoMatchingPayee when removing an unknown payee", func(t *testing.T) {
+		_, mockAccRepo, _, service, ctx, cancel := initializeAccountMocks()
+		defer cancel()
+		mockAccRepo.On("RemoveKnownBankAccount", "tomId", "payeeId", mock.Anything).Return(model.ErrNoMatchingPayee)
+
+		err := service.RemovePayee("tomId", "payeeId", ctx)
+		assert.ErrorIs(t, err, model.ErrNoMatchingPayee)
+	})
+}
+
 func initializeAccountMocks() (
 	*mocks.MockTransactionRepository,
 	*mocks.MockAccountRepository,
@@ -106,12 +693,26 @@ func initializeAccountMocks() (
 	*AccountServiceImpl,
 	context.Context,
 	context.CancelFunc,
+) {
+	mockTranRepo, mockAccRepo, _, mockTran, service, addCtx, cancel := initializeSnapshotAccountMocks()
+	return mockTranRepo, mockAccRepo, mockTran, service, addCtx, cancel
+}
+
+func initializeSnapshotAccountMocks() (
+	*mocks.MockTransactionRepository,
+	*mocks.MockAccountRepository,
+	*mocks.MockBalanceSnapshotRepository,
+	*mocks.MockTransactional,
+	*AccountServiceImpl,
+	context.Context,
+	context.CancelFunc,
 ) {
 	mockTranRepo := new(mocks.MockTransactionRepository)
 	mockAccRepo := &mocks.MockAccountRepository{}
+	mockSnapshotRepo := &mocks.MockBalanceSnapshotRepository{}
 	mockTran := &mocks.MockTransactional{}
 
-	service := CreateNewAccountServiceImpl(mockAccRepo, mockTranRepo, mockTran)
+	service := CreateNewAccountServiceImpl(mockAccRepo, mockTranRepo, mockSnapshotRepo, mockTran, logging.Discard())
 	addCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	return mockTranRepo, mockAccRepo, mockTran, service, addCtx, cancel
+	return mockTranRepo, mockAccRepo, mockSnapshotRepo, mockTran, service, addCtx, cancel
 }
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/tracing"
+)
+
+// AccountServiceTraced records a span for every call to the account service it wraps
+type AccountServiceTraced struct {
+	next   AccountService
+	tracer trace.Tracer
+}
+
+func CreateNewAccountServiceTraced(next AccountService, tracer trace.Tracer) *AccountServiceTraced {
+	return &AccountServiceTraced{next: next, tracer: tracer}
+}
+
+func (a *AccountServiceTraced) GetAccountDetailsFromBankAccountId(
+	bankAccountId string,
+	ctx context.Context,
+) (_ *model.AccountDetailsOutput, err error) {
+	ctx, span := a.tracer.Start(ctx, "AccountService.GetAccountDetailsFromBankAccountId")
+	defer func() { tracing.End(span, err) }()
+	return a.next.GetAccountDetailsFromBankAccountId(bankAccountId, ctx)
+}
+
+func (a *AccountServiceTraced) GetBankAccountTransactions(
+	input *model.TransactionsForBankAccountInput,
+	ctx context.Context,
+) (_ []model.BankAccountTransactionOutput, err error) {
+	ctx, span := a.tracer.Start(ctx, "AccountService.GetBankAccountTransactions")
+	defer func() { tracing.End(span, err) }()
+	return a.next.GetBankAccountTransactions(input, ctx)
+}
+
+func (a *AccountServiceTraced) GetBankAccountTransactionsPage(
+	input *model.TransactionPageInput,
+	ctx context.Context,
+) (_ *model.TransactionPageOutput, err error) {
+	ctx, span := a.tracer.Start(ctx, "AccountService.GetBankAccountTransactionsPage")
+	defer func() { tracing.End(span, err) }()
+	return a.next.GetBankAccountTransactionsPage(input, ctx)
+}
+
+func (a *AccountServiceTraced) Login(
+	username string,
+	password string,
+	ctx context.Context,
+) (_ *model.AccountDetailsOutput, err error) {
+	ctx, span := a.tracer.Start(ctx, "AccountService.Login")
+	defer func() { tracing.End(span, err) }()
+	return a.next.Login(username, password, ctx)
+}
+
+func (a *AccountServiceTraced) GetAccountBalanceHistory(
+	input *model.AccountBalanceHistoryInput,
+	ctx context.Context,
+) (_ *model.AccountBalanceHistoryOutput, err error) {
+	ctx, span := a.tracer.Start(ctx, "AccountService.GetAccountBalanceHistory")
+	defer func() { tracing.End(span, err) }()
+	return a.next.GetAccountBalanceHistory(input, ctx)
+}
+
+func (a *AccountServiceTraced) IsBankAccountOwner(
+	accountId string,
+	bankAccountId string,
+	ctx context.Context,
+) (_ bool, err error) {
+	ctx, span := a.tracer.Start(ctx, "AccountService.IsBankAccountOwner")
+	defer func() { tracing.End(span, err) }()
+	return a.next.IsBankAccountOwner(accountId, bankAccountId, ctx)
+}
+
+func (a *AccountServiceTraced) Register(
+	input *model.RegisterAccountInput,
+	ctx context.Context,
+) (_ *model.AccountDetailsOutput, err error) {
+	ctx, span := a.tracer.Start(ctx, "AccountService.Register")
+	defer func() { tracing.End(span, err) }()
+	return a.next.Register(input, ctx)
+}
+
+func (a *AccountServiceTraced) OpenBankAccount(
+	accountId string,
+	accountType model.BankAccountType,
+	ctx context.Context,
+) (_ *model.BankAccount, err error) {
+	ctx, span := a.tracer.Start(ctx, "AccountService.OpenBankAccount")
+	defer func() { tracing.End(span, err) }()
+	return a.next.OpenBankAccount(accountId, accountType, ctx)
+}
+
+func (a *AccountServiceTraced) GetPayees(
+	accountId string,
+	ctx context.Context,
+) (_ []model.KnownBankAccount, err error) {
+	ctx, span := a.tracer.Start(ctx, "AccountService.GetPayees")
+	defer func() { tracing.End(span, err) }()
+	return a.next.GetPayees(accountId, ctx)
+}
+
+func (a *AccountServiceTraced) AddPayee(
+	accountId string,
+	input *model.PayeeInput,
+	ctx context.Context,
+) (_ *model.KnownBankAccount, err error) {
+	ctx, span := a.tracer.Start(ctx, "AccountService.AddPayee")
+	defer func() { tracing.End(span, err) }()
+	return a.next.AddPayee(accountId, input, ctx)
+}
+
+func (a *AccountServiceTraced) RenamePayee(
+	accountId string,
+	payeeId string,
+	nickname string,
+	ctx context.Context,
+) (err error) {
+	ctx, span := a.tracer.Start(ctx, "AccountService.RenamePayee")
+	defer func() { tracing.End(span, err) }()
+	return a.next.RenamePayee(accountId, payeeId, nickname, ctx)
+}
+
+func (a *AccountServiceTraced) RemovePayee(accountId string, payeeId string, ctx context.Context) (err error) {
+	ctx, span := a.tracer.Start(ctx, "AccountService.RemovePayee")
+	defer func() { tracing.End(span, err) }()
+	return a.next.RemovePayee(accountId, payeeId, ctx)
+}
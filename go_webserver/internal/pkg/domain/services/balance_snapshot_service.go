@@ -0,0 +1,11 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+type BalanceSnapshotService interface {
+	TakeBalanceSnapshots(now time.Time, ctx context.Context) (int, error)
+	BackfillBalanceSnapshots(fromTime time.Time, now time.Time, ctx context.Context) (int, error)
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+)
+
+type BalanceSnapshotServiceImpl struct {
+	ar     repositories.AccountRepository
+	tr     repositories.TransactionRepository
+	sr     repositories.BalanceSnapshotRepository
+	tran   transactional.Transactional
+	logger *slog.Logger
+}
+
+func CreateNewBalanceSnapshotServiceImpl(
+	ar repositories.AccountRepository,
+	tr repositories.TransactionRepository,
+	sr repositories.BalanceSnapshotRepository,
+	tran transactional.Transactional,
+	logger *slog.Logger,
+) *BalanceSnapshotServiceImpl {
+	return &BalanceSnapshotServiceImpl{ar: ar, tr: tr, sr: sr, tran: tran, logger: logger}
+}
+
+// TakeBalanceSnapshots snapshots every bank account for the days that ended since its latest snapshot, or only for
+// the last day that ended when it has none yet. Bank accounts that cannot be snapshotted are logged and left for the
+// next run.
+func (s *BalanceSnapshotServiceImpl) TakeBalanceSnapshots(now time.Time, ctx context.Context) (int, error) {
+	lastDay := lastSnapshotDay(now)
+	return s.snapshotBankAccounts(func(bankAccountId string) (time.Time, error) {
+		latest, err := s.sr.GetLatestBalanceSnapshot(bankAccountId, ctx)
+		if errors.Is(err, model.ErrNoMatchingBalanceSnapshot) {
+			return lastDay, nil
+		}
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error when getting latest balance snapshot: %w", err)
+		}
+		return latest.Day.AddDate(0, 0, 1), nil
+	}, lastDay, ctx)
+}
+
+// BackfillBalanceSnapshots snapshots every bank account for the days from the one holding fromTime to the last day
+// that ended, skipping the days snapshotted already. A zero fromTime starts every bank account from the day of its
+// first transaction.
+func (s *BalanceSnapshotServiceImpl) BackfillBalanceSnapshots(
+	fromTime time.Time,
+	now time.Time,
+	ctx context.Context,
+) (int, error) {
+	firstDay := time.Time{}
+	if !fromTime.IsZero() {
+		firstDay = utcDay(fromTime)
+	}
+	return s.snapshotBankAccounts(func(string) (time.Time, error) {
+		return firstDay, nil
+	}, lastSnapshotDay(now), ctx)
+}
+
+// snapshotBankAccounts snapshots every bank account for the days from the one firstDay returns for it to lastDay,
+// and returns how many snapshots were stored
+func (s *BalanceSnapshotServiceImpl) snapshotBankAccounts(
+	firstDay func(bankAccountId string) (time.Time, error),
+	lastDay time.Time,
+	ctx context.Context,
+) (int, error) {
+	listCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+	bankAccounts, err := s.ar.GetAllBankAccountBalances(listCtx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Unable to get bank accounts to snapshot", logging.Err(err))
+		return 0, fmt.Errorf("error when getting bank accounts to snapshot: %w", err)
+	}
+
+	added := 0
+	for _, bankAccount := range bankAccounts {
+		first, err := firstDay(bankAccount.BankAccountId)
+		if err == nil && first.After(lastDay) {
+			continue
+		}
+		var snapshots []model.BalanceSnapshot
+		if err == nil {
+			snapshots, err = s.computeBalanceSnapshots(bankAccount.BankAccountId, first, lastDay, ctx)
+		}
+		var stored int
+		if err == nil {
+			stored, err = s.sr.AddBalanceSnapshots(snapshots, ctx)
+		}
+		if err != nil {
+			s.logger.ErrorContext(ctx, "Unable to snapshot bank account",
+				logging.BankAccountId(bankAccount.BankAccountId), logging.Err(err))
+			continue
+		}
+		added += stored
+	}
+	return added, nil
+}
+
+// computeBalanceSnapshots finds the balances of the bank account at the end of every day from firstDay to lastDay,
+// along with what happened during the day, by undoing the transactions made since from the current balances, read
+// along with the transactions from the snapshot of a single database transaction. A zero firstDay starts from the day
+// of the first transaction of the bank account, or from lastDay when it made none.
+func (s *BalanceSnapshotServiceImpl) computeBalanceSnapshots(
+	bankAccountId string,
+	firstDay time.Time,
+	lastDay time.Time,
+	ctx context.Context,
+) ([]model.BalanceSnapshot, error) {
+	getCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	txnCtx, err := s.tran.BeginTransaction(getCtx, transactional.IsolationHigh, transactional.DurabilityHigh)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction with error: %w", err)
+	}
+	defer func() {
+		if rollErr := s.tran.Rollback(txnCtx); rollErr != nil {
+			s.logger.ErrorContext(ctx, "Unable to roll back transaction", logging.Err(rollErr))
+		}
+	}()
+
+	now := time.Now()
+	availableBalance, pendingBalance, err := s.ar.GetAccountBalance(bankAccountId, txnCtx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get account balance with error: %w", err)
+	}
+	fromTime := firstDay
+	if fromTime.IsZero() {
+		fromTime = time.Unix(0, 0)
+	}
+	transactions, err := s.tr.GetTransactionsFromBankAccountId(&model.TransactionsForBankAccountInput{
+		BankAccountId: bankAccountId,
+		FromTime:      fromTime,
+		ToTime:        now,
+	}, txnCtx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get account history with error: %w", err)
+	}
+	sortNewestFirst(transactions)
+	if firstDay.IsZero() {
+		firstDay = lastDay
+		if len(transactions) > 0 {
+			firstDay = earliestTime(firstDay, utcDay(transactions[len(transactions)-1].CreatedAt))
+		}
+	}
+
+	var days []model.AccountBalanceBucket
+	for day := firstDay; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+		days = append(days, model.AccountBalanceBucket{StartTime: day, EndTime: day.AddDate(0, 0, 1)})
+	}
+	_, openingPendingBalance := fillBalanceBuckets(days, transactions, availableBalance, pendingBalance)
+	snapshots := make([]model.BalanceSnapshot, len(days))
+	for i, day := range days {
+		// Every day opens with the pending balance the day before it closes with
+		if i > 0 {
+			openingPendingBalance = days[i-1].ClosingPendingBalance
+		}
+		snapshots[len(days)-1-i] = model.BalanceSnapshot{
+			BankAccountId:           bankAccountId,
+			Day:                     day.StartTime,
+			AvailableBalance:        day.ClosingAvailableBalance,
+			PendingBalance:          day.ClosingPendingBalance,
+			OpeningAvailableBalance: day.OpeningAvailableBalance,
+			OpeningPendingBalance:   openingPendingBalance,
+			MinAvailableBalance:     day.MinAvailableBalance,
+			MaxAvailableBalance:     day.MaxAvailableBalance,
+			TotalDebits:             day.TotalDebits,
+			TotalCredits:            day.TotalCredits,
+		}
+	}
+	return snapshots, nil
+}
+
+// lastSnapshotDay is the last day that ended balanceSnapshotDelay before now, in UTC
+func lastSnapshotDay(now time.Time) time.Time {
+	return utcDay(now.Add(-balanceSnapshotDelay)).AddDate(0, 0, -1)
+}
+
+func earliestTime(first time.Time, second time.Time) time.Time {
+	if second.Before(first) {
+		return second
+	}
+	return first
+}
+
+// utcDay is the midnight UTC that starts the day holding tm
+func utcDay(tm time.Time) time.Time {
+	year, month, day := tm.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
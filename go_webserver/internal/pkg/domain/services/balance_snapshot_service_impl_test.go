@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/logging"
+	"webserver/test/mocks"
+)
+
+func TestTakeBalanceSnapshots(t *testing.T) {
+	now := time.Date(2024, time.April, 3, 10, 0, 0, 0, time.UTC)
+	transactions := []model.BankAccountTransactionOutput{
+		snapshotTransaction("older", model.Credit, "30", time.Date(2024, time.April, 1, 12, 0, 0, 0, time.UTC)),
+		snapshotTransaction("newer", model.Debit, "10", time.Date(2024, time.April, 2, 9, 0, 0, 0, time.UTC)),
+		snapshotTransaction("today", model.Debit, "20", time.Date(2024, time.April, 3, 8, 0, 0, 0, time.UTC)),
+	}
+
+	t.Run("Snapshots the days that ended since the latest snapshot", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockSnapshotRepo, mockTran, service, ctx, cancel := initializeSnapshotMocks()
+		defer cancel()
+		mockAccRepo.On("GetAllBankAccountBalances", mock.Anything).
+			Return([]model.BankAccountBalance{{BankAccountId: "bankAccountId"}}, nil)
+		mockSnapshotRepo.On("GetLatestBalanceSnapshot", "bankAccountId", mock.Anything).
+			Return(&model.BalanceSnapshot{Day: time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)}, nil)
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountBalance", "bankAccountId", mock.Anything).
+			Return(decimal.RequireFromString("100"), decimal.RequireFromString("100"), nil)
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.MatchedBy(
+			func(input *model.TransactionsForBankAccountInput) bool {
+				return input.FromTime.Equal(time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC))
+			}), mock.Anything).Return(transactions, nil)
+		mockSnapshotRepo.On("AddBalanceSnapshots", mock.MatchedBy(func(snapshots []model.BalanceSnapshot) bool {
+			return assert.Equal(t, []string{"2024-04-02 80 80", "2024-04-01 70 70"}, snapshotBalances(snapshots)) &&
+				assert.Equal(t, []string{"2024-04-02 70 70 70 80 10 0", "2024-04-01 100 100 70 100 0 30"},
+					snapshotDayTotals(snapshots))
+		}), mock.Anything).Return(2, nil)
+
+		added, err := service.TakeBalanceSnapshots(now, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, added)
+		mockTran.AssertNumberOfCalls(t, "Rollback", 1)
+	})
+
+	t.Run("Only snapshots the last day that ended for bank accounts without snapshots", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockSnapshotRepo, mockTran, service, ctx, cancel := initializeSnapshotMocks()
+		defer cancel()
+		mockAccRepo.On("GetAllBankAccountBalances", mock.Anything).
+			Return([]model.BankAccountBalance{{BankAccountId: "bankAccountId"}}, nil)
+		mockSnapshotRepo.On("GetLatestBalanceSnapshot", "bankAccountId", mock.Anything).
+			Return(nil, model.ErrNoMatchingBalanceSnapshot)
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountBalance", "bankAccountId", mock.Anything).
+			Return(decimal.RequireFromString("100"), decimal.RequireFromString("100"), nil)
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.Anything, mock.Anything).
+			Return([]model.BankAccountTransactionOutput{
+				snapshotTransaction("today", model.Debit, "20", time.Date(2024, time.April, 3, 8, 0, 0, 0, time.UTC)),
+			}, nil)
+		mockSnapshotRepo.On("AddBalanceSnapshots", mock.MatchedBy(func(snapshots []model.BalanceSnapshot) bool {
+			return assert.Equal(t, []string{"2024-04-02 80 80"}, snapshotBalances(snapshots))
+		}), mock.Anything).Return(1, nil)
+
+		added, err := service.TakeBalanceSnapshots(now, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, added)
+	})
+
+	t.Run("Waits for the transfers started before midnight before snapshotting a day", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockSnapshotRepo, _, service, ctx, cancel := initializeSnapshotMocks()
+		defer cancel()
+		mockAccRepo.On("GetAllBankAccountBalances", mock.Anything).
+			Return([]model.BankAccountBalance{{BankAccountId: "bankAccountId"}}, nil)
+		mockSnapshotRepo.On("GetLatestBalanceSnapshot", "bankAccountId", mock.Anything).
+			Return(&model.BalanceSnapshot{Day: time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)}, nil)
+
+		added, err := service.TakeBalanceSnapshots(time.Date(2024, time.April, 3, 0, 1, 0, 0, time.UTC), ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, added)
+		mockTranRepo.AssertNotCalled(t, "GetTransactionsFromBankAccountId", mock.Anything, mock.Anything)
+		mockSnapshotRepo.AssertNotCalled(t, "AddBalanceSnapshots", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Keeps snapshotting the other bank accounts when one fails", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockSnapshotRepo, mockTran, service, ctx, cancel := initializeSnapshotMocks()
+		defer cancel()
+		mockAccRepo.On("GetAllBankAccountBalances", mock.Anything).Return([]model.BankAccountBalance{
+			{BankAccountId: "failingBankAccountId"},
+			{BankAccountId: "bankAccountId"},
+		}, nil)
+		mockSnapshotRepo.On("GetLatestBalanceSnapshot", mock.Anything, mock.Anything).
+			Return(nil, model.ErrNoMatchingBalanceSnapshot)
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountBalance", "failingBankAccountId", mock.Anything).
+			Return(decimal.Zero, decimal.Zero, assert.AnError)
+		mockAccRepo.On("GetAccountBalance", "bankAccountId", mock.Anything).
+			Return(decimal.RequireFromString("100"), decimal.RequireFromString("100"), nil)
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.Anything, mock.Anything).Return(nil, nil)
+		mockSnapshotRepo.On("AddBalanceSnapshots", mock.Anything, mock.Anything).Return(1, nil)
+
+		added, err := service.TakeBalanceSnapshots(now, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, added)
+		mockSnapshotRepo.AssertNumberOfCalls(t, "AddBalanceSnapshots", 1)
+	})
+
+	t.Run("Returns error if the bank accounts cannot be listed", func(t *testing.T) {
+		_, mockAccRepo, _, _, service, ctx, cancel := initializeSnapshotMocks()
+		defer cancel()
+		mockAccRepo.On("GetAllBankAccountBalances", mock.Anything).Return(nil, assert.AnError)
+
+		_, err := service.TakeBalanceSnapshots(now, ctx)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func TestBackfillBalanceSnapshots(t *testing.T) {
+	now := time.Date(2024, time.April, 3, 10, 0, 0, 0, time.UTC)
+	transactions := []model.BankAccountTransactionOutput{
+		snapshotTransaction("older", model.Credit, "30", time.Date(2024, time.April, 1, 12, 0, 0, 0, time.UTC)),
+		snapshotTransaction("newer", model.Debit, "10", time.Date(2024, time.April, 2, 9, 0, 0, 0, time.UTC)),
+	}
+
+	t.Run("Starts from the day of the first transaction without a start time", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockSnapshotRepo, mockTran, service, ctx, cancel := initializeSnapshotMocks()
+		defer cancel()
+		mockAccRepo.On("GetAllBankAccountBalances", mock.Anything).
+			Return([]model.BankAccountBalance{{BankAccountId: "bankAccountId"}}, nil)
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountBalance", "bankAccountId", mock.Anything).
+			Return(decimal.RequireFromString("80"), decimal.RequireFromString("80"), nil)
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.MatchedBy(
+			func(input *model.TransactionsForBankAccountInput) bool {
+				return input.FromTime.Equal(time.Unix(0, 0))
+			}), mock.Anything).Return(transactions, nil)
+		mockSnapshotRepo.On("AddBalanceSnapshots", mock.MatchedBy(func(snapshots []model.BalanceSnapshot) bool {
+			return assert.Equal(t, []string{"2024-04-02 80 80", "2024-04-01 70 70"}, snapshotBalances(snapshots))
+		}), mock.Anything).Return(2, nil)
+
+		added, err := service.BackfillBalanceSnapshots(time.Time{}, now, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, added)
+	})
+
+	t.Run("Snapshots every day from the start time", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockSnapshotRepo, mockTran, service, ctx, cancel := initializeSnapshotMocks()
+		defer cancel()
+		mockAccRepo.On("GetAllBankAccountBalances", mock.Anything).
+			Return([]model.BankAccountBalance{{BankAccountId: "bankAccountId"}}, nil)
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountBalance", "bankAccountId", mock.Anything).
+			Return(decimal.RequireFromString("80"), decimal.RequireFromString("80"), nil)
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.Anything, mock.Anything).Return(transactions, nil)
+		mockSnapshotRepo.On("AddBalanceSnapshots", mock.MatchedBy(func(snapshots []model.BalanceSnapshot) bool {
+			return assert.Equal(t, []string{"2024-04-02 80 80", "2024-04-01 70 70", "2024-03-31 100 100"},
+				snapshotBalances(snapshots))
+		}), mock.Anything).Return(3, nil)
+
+		added, err := service.BackfillBalanceSnapshots(time.Date(2024, time.March, 31, 18, 0, 0, 0, time.UTC), now,
+			ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, added)
+		mockSnapshotRepo.AssertNotCalled(t, "GetLatestBalanceSnapshot", mock.Anything, mock.Anything)
+	})
+}
+
+func snapshotTransaction(
+	id string,
+	nature model.TransactionNature,
+	amount string,
+	createdAt time.Time,
+) model.BankAccountTransactionOutput {
+	return model.BankAccountTransactionOutput{Id: id, TransactionNature: nature, TransactionType: model.Realized,
+		Amount: decimal.RequireFromString(amount), CreatedAt: createdAt}
+}
+
+// snapshotBalances describes every snapshot by its day and balances
+func snapshotBalances(snapshots []model.BalanceSnapshot) []string {
+	res := make([]string, len(snapshots))
+	for i, snapshot := range snapshots {
+		res[i] = snapshot.Day.Format(time.DateOnly) + " " + snapshot.AvailableBalance.String() + " " +
+			snapshot.PendingBalance.String()
+	}
+	return res
+}
+
+// snapshotDayTotals describes every snapshot by its day, opening balances, extremes and totals
+func snapshotDayTotals(snapshots []model.BalanceSnapshot) []string {
+	res := make([]string, len(snapshots))
+	for i, snapshot := range snapshots {
+		res[i] = strings.Join([]string{snapshot.Day.Format(time.DateOnly), snapshot.OpeningAvailableBalance.String(),
+			snapshot.OpeningPendingBalance.String(), snapshot.MinAvailableBalance.String(),
+			snapshot.MaxAvailableBalance.String(), snapshot.TotalDebits.String(), snapshot.TotalCredits.String()}, " ")
+	}
+	return res
+}
+
+func initializeSnapshotMocks() (
+	*mocks.MockTransactionRepository,
+	*mocks.MockAccountRepository,
+	*mocks.MockBalanceSnapshotRepository,
+	*mocks.MockTransactional,
+	*BalanceSnapshotServiceImpl,
+	context.Context,
+	context.CancelFunc,
+) {
+	mockTranRepo := new(mocks.MockTransactionRepository)
+	mockAccRepo := &mocks.MockAccountRepository{}
+	mockSnapshotRepo := &mocks.MockBalanceSnapshotRepository{}
+	mockTran := &mocks.MockTransactional{}
+
+	service := CreateNewBalanceSnapshotServiceImpl(mockAccRepo, mockTranRepo, mockSnapshotRepo, mockTran,
+		logging.Discard())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return mockTranRepo, mockAccRepo, mockSnapshotRepo, mockTran, service, ctx, cancel
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+	"webserver/internal/pkg/tracing"
+)
+
+// BalanceSnapshotServiceTraced records a span for every call to the balance snapshot service it wraps
+type BalanceSnapshotServiceTraced struct {
+	next   BalanceSnapshotService
+	tracer trace.Tracer
+}
+
+func CreateNewBalanceSnapshotServiceTraced(
+	next BalanceSnapshotService,
+	tracer trace.Tracer,
+) *BalanceSnapshotServiceTraced {
+	return &BalanceSnapshotServiceTraced{next: next, tracer: tracer}
+}
+
+func (s *BalanceSnapshotServiceTraced) TakeBalanceSnapshots(now time.Time, ctx context.Context) (_ int, err error) {
+	ctx, span := s.tracer.Start(ctx, "BalanceSnapshotService.TakeBalanceSnapshots")
+	defer func() { tracing.End(span, err) }()
+	return s.next.TakeBalanceSnapshots(now, ctx)
+}
+
+func (s *BalanceSnapshotServiceTraced) BackfillBalanceSnapshots(
+	fromTime time.Time,
+	now time.Time,
+	ctx context.Context,
+) (_ int, err error) {
+	ctx, span := s.tracer.Start(ctx, "BalanceSnapshotService.BackfillBalanceSnapshots")
+	defer func() { tracing.End(span, err) }()
+	return s.next.BackfillBalanceSnapshots(fromTime, now, ctx)
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"regexp"
+	"strings"
+	"webserver/internal/pkg/domain/model"
+	repositories2 "webserver/internal/pkg/domain/repositories"
+)
+
+// categorySide is a transfer as listed in the transactions of one of its bank accounts, which is what category rules
+// match against
+type categorySide struct {
+	CounterpartyBankAccountId string
+	TransactionNature         model.TransactionNature
+	Amount                    decimal.Decimal
+	Memo                      string
+}
+
+// transferSides returns the sides of the transfer as seen by the bank account it is taken from and by the one it is
+// given to
+func transferSides(input *model.TransactionDetailsInput) (categorySide, categorySide) {
+	from := categorySide{
+		CounterpartyBankAccountId: input.ToBankAccountId,
+		TransactionNature:         model.Credit,
+		Amount:                    input.Amount,
+		Memo:                      input.Memo,
+	}
+	to := categorySide{
+		CounterpartyBankAccountId: input.FromBankAccountId,
+		TransactionNature:         model.Debit,
+		Amount:                    input.Amount,
+		Memo:                      input.Memo,
+	}
+	return from, to
+}
+
+// accountTransactionSide returns the side of a transaction listed in the transactions of a bank account
+func accountTransactionSide(transaction *model.BankAccountTransactionOutput) categorySide {
+	return categorySide{
+		CounterpartyBankAccountId: transaction.OtherBankAccountId,
+		TransactionNature:         transaction.TransactionNature,
+		Amount:                    transaction.Amount,
+		Memo:                      transaction.Memo,
+	}
+}
+
+// compiledCategoryRule is a category rule along with its compiled memo pattern, which is nil when the rule has none
+type compiledCategoryRule struct {
+	*model.CategoryRule
+	memoPattern *regexp.Regexp
+}
+
+// compileCategoryRules compiles the memo pattern of every rule once, so that the rules can be matched against many
+// sides. Memo patterns were validated when the rule was added, so a rule whose pattern no longer compiles matches
+// nothing and is left out.
+func compileCategoryRules(rules []model.CategoryRule) []compiledCategoryRule {
+	compiled := make([]compiledCategoryRule, 0, len(rules))
+	for i := range rules {
+		rule := compiledCategoryRule{CategoryRule: &rules[i]}
+		if rules[i].MemoPattern != "" {
+			pattern, err := regexp.Compile(rules[i].MemoPattern)
+			if err != nil {
+				continue
+			}
+			rule.memoPattern = pattern
+		}
+		compiled = append(compiled, rule)
+	}
+	return compiled
+}
+
+// matchCategory returns the category of the first rule matching the side, or no category when none does
+func matchCategory(rules []compiledCategoryRule, side categorySide) model.TransactionCategory {
+	for i := range rules {
+		if ruleMatches(&rules[i], side) {
+			return model.TransactionCategory{Name: rules[i].Category, Source: model.RuleCategory}
+		}
+	}
+	return model.TransactionCategory{}
+}
+
+// ruleMatches reports whether every condition of the rule holds for the side
+func ruleMatches(rule *compiledCategoryRule, side categorySide) bool {
+	if rule.CounterpartyBankAccountId != "" && rule.CounterpartyBankAccountId != side.CounterpartyBankAccountId {
+		return false
+	}
+	if rule.TransactionNature != "" && rule.TransactionNature != side.TransactionNature {
+		return false
+	}
+	if rule.MinAmount.Valid && side.Amount.LessThan(rule.MinAmount.Decimal) {
+		return false
+	}
+	if rule.MaxAmount.Valid && side.Amount.GreaterThan(rule.MaxAmount.Decimal) {
+		return false
+	}
+	return rule.memoPattern == nil || rule.memoPattern.MatchString(side.Memo)
+}
+
+// ruleCategory returns the category the rules of the holder of the bank account give the side
+func ruleCategory(
+	ar repositories2.AccountRepository,
+	cr repositories2.CategoryRuleRepository,
+	bankAccountId string,
+	side categorySide,
+	ctx context.Context,
+) (model.TransactionCategory, error) {
+	accountId, err := ar.GetAccountIdFromBankAccountId(bankAccountId, ctx)
+	if err != nil {
+		return model.TransactionCategory{}, fmt.Errorf("error when getting account of BankAccount %s: %w",
+			bankAccountId, err)
+	}
+	rules, err := cr.GetCategoryRules(accountId, ctx)
+	if err != nil {
+		return model.TransactionCategory{}, fmt.Errorf("error when getting category rules of Account %s: %w",
+			accountId, err)
+	}
+	return matchCategory(compileCategoryRules(rules), side), nil
+}
+
+// validateMemo trims the memo and checks its length
+func validateMemo(memo string) (string, error) {
+	memo = strings.TrimSpace(memo)
+	if len(memo) > maxMemoLength {
+		return "", fmt.Errorf("%w: memo must be at most %d characters long", model.ErrInvalidMemo, maxMemoLength)
+	}
+	return memo, nil
+}
+
+// validateCategory trims the category and checks its length. An empty category is only valid where leaving a
+// transaction uncategorized is.
+func validateCategory(category string) (string, error) {
+	category = strings.TrimSpace(category)
+	if len(category) > maxCategoryLength {
+		return "", fmt.Errorf("%w: category must be at most %d characters long", model.ErrInvalidCategory,
+			maxCategoryLength)
+	}
+	return category, nil
+}
+
+// validateCategoryRule normalizes the rule and checks that its category is set and that its conditions can match
+// a transaction
+func validateCategoryRule(input *model.CategoryRuleInput) (*model.CategoryRuleInput, error) {
+	rule := *input
+	category, err := validateCategory(rule.Category)
+	if err != nil {
+		return nil, err
+	}
+	if category == "" {
+		return nil, fmt.Errorf("%w: category is required", model.ErrInvalidCategoryRule)
+	}
+	rule.Category = category
+	rule.CounterpartyBankAccountId = strings.TrimSpace(rule.CounterpartyBankAccountId)
+	switch rule.TransactionNature {
+	case "", model.Debit, model.Credit:
+	default:
+		return nil, fmt.Errorf("%w: transactionNature must be debit or credit", model.ErrInvalidCategoryRule)
+	}
+	if rule.MinAmount.Valid && rule.MinAmount.Decimal.IsNegative() ||
+		rule.MaxAmount.Valid && rule.MaxAmount.Decimal.IsNegative() {
+		return nil, fmt.Errorf("%w: amounts must not be negative", model.ErrInvalidCategoryRule)
+	}
+	if rule.MinAmount.Valid && rule.MaxAmount.Valid && rule.MinAmount.Decimal.GreaterThan(rule.MaxAmount.Decimal) {
+		return nil, fmt.Errorf("%w: minAmount must not exceed maxAmount", model.ErrInvalidCategoryRule)
+	}
+	if len(rule.MemoPattern) > maxMemoPatternLength {
+		return nil, fmt.Errorf("%w: memoPattern must be at most %d characters long", model.ErrInvalidCategoryRule,
+			maxMemoPatternLength)
+	}
+	if _, err = regexp.Compile(rule.MemoPattern); err != nil {
+		return nil, fmt.Errorf("%w: memoPattern is not a valid regular expression", model.ErrInvalidCategoryRule)
+	}
+	return &rule, nil
+}
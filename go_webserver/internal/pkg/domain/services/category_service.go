@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+	"webserver/internal/pkg/domain/model"
+)
+
+type CategoryService interface {
+	GetCategoryRules(accountId string, ctx context.Context) ([]model.CategoryRule, error)
+	AddCategoryRule(accountId string, input *model.CategoryRuleInput, ctx context.Context) (*model.CategoryRule, error)
+	RemoveCategoryRule(accountId string, ruleId string, ctx context.Context) error
+	ApplyCategoryRules(
+		bankAccountId string,
+		after *model.TransactionCursor,
+		ctx context.Context,
+	) (*model.ApplyCategoryRulesOutput, error)
+	CategorizeTransaction(
+		transactionId string,
+		bankAccountId string,
+		category string,
+		ctx context.Context,
+	) (model.TransactionCategory, error)
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+)
+
+type CategoryServiceImpl struct {
+	ar     repositories.AccountRepository
+	tr     repositories.TransactionRepository
+	cr     repositories.CategoryRuleRepository
+	tran   transactional.Transactional
+	logger *slog.Logger
+}
+
+func CreateNewCategoryServiceImpl(
+	ar repositories.AccountRepository,
+	tr repositories.TransactionRepository,
+	cr repositories.CategoryRuleRepository,
+	tran transactional.Transactional,
+	logger *slog.Logger,
+) *CategoryServiceImpl {
+	return &CategoryServiceImpl{ar: ar, tr: tr, cr: cr, tran: tran, logger: logger}
+}
+
+func (c *CategoryServiceImpl) GetCategoryRules(accountId string, ctx context.Context) ([]model.CategoryRule, error) {
+	getCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+	rules, err := c.cr.GetCategoryRules(accountId, getCtx)
+	if err != nil {
+		c.logger.ErrorContext(ctx, "Unable to get category rules", logging.AccountId(accountId), logging.Err(err))
+		return nil, fmt.Errorf("unable to get category rules with error: %w", err)
+	}
+	return rules, nil
+}
+
+// AddCategoryRule appends the rule to the rules of the account, which only categorize the transactions made from then
+// on until they are applied again. The number of rules is checked before the rule is added, so concurrent requests
+// may exceed maxCategoryRules by a few rules.
+func (c *CategoryServiceImpl) AddCategoryRule(
+	accountId string,
+	input *model.CategoryRuleInput,
+	ctx context.Context,
+) (*model.CategoryRule, error) {
+	rule, err := validateCategoryRule(input)
+	if err != nil {
+		return nil, err
+	}
+	addCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	rules, err := c.cr.GetCategoryRules(accountId, addCtx)
+	if err != nil {
+		c.logger.ErrorContext(ctx, "Unable to get category rules", logging.AccountId(accountId), logging.Err(err))
+		return nil, fmt.Errorf("unable to get category rules with error: %w", err)
+	}
+	if len(rules) >= maxCategoryRules {
+		c.logger.InfoContext(ctx, "Rejecting category rule over the limit", logging.AccountId(accountId))
+		return nil, fmt.Errorf("%w: an account has at most %d category rules", model.ErrTooManyCategoryRules,
+			maxCategoryRules)
+	}
+
+	added, err := c.cr.AddCategoryRule(accountId, rule, addCtx)
+	if err != nil {
+		c.logger.ErrorContext(ctx, "Unable to add category rule", logging.AccountId(accountId), logging.Err(err))
+		return nil, fmt.Errorf("unable to add category rule with error: %w", err)
+	}
+	c.logger.InfoContext(ctx, "Added category rule", logging.AccountId(accountId),
+		slog.String(logging.CategoryRuleIdKey, added.Id))
+	return added, nil
+}
+
+func (c *CategoryServiceImpl) RemoveCategoryRule(accountId string, ruleId string, ctx context.Context) error {
+	removeCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+	if err := c.cr.RemoveCategoryRule(accountId, ruleId, removeCtx); err != nil {
+		c.logger.WarnContext(ctx, "Unable to remove category rule", logging.AccountId(accountId),
+			slog.String(logging.CategoryRuleIdKey, ruleId), logging.Err(err))
+		if errors.Is(err, model.ErrNoMatchingCategoryRule) {
+			return err
+		}
+		return fmt.Errorf("unable to remove category rule with error: %w", err)
+	}
+	c.logger.InfoContext(ctx, "Removed category rule", logging.AccountId(accountId),
+		slog.String(logging.CategoryRuleIdKey, ruleId))
+	return nil
+}
+
+// ApplyCategoryRules categorizes the transactions of the bank account that follow the after cursor, oldest first,
+// again with the current rules of its account holder, leaving alone the ones the account holder categorized.
+// Transactions no rule matches any longer lose their category. Every page of transactions is categorized in its own
+// database transaction, so that a failure leaves the pages before it categorized. All pages share the deadline of
+// the request, which is why at most maxCategorizedPages pages are categorized before the cursor to resume from is
+// returned.
+func (c *CategoryServiceImpl) ApplyCategoryRules(
+	bankAccountId string,
+	after *model.TransactionCursor,
+	ctx context.Context,
+) (*model.ApplyCategoryRulesOutput, error) {
+	rules, err := c.bankAccountRules(bankAccountId, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &model.ApplyCategoryRulesOutput{Next: after}
+	for pages := 0; pages < maxCategorizedPages; pages++ {
+		changed, next, err := c.applyCategoryRulesPage(bankAccountId, rules, res.Next, ctx)
+		if err != nil {
+			return nil, err
+		}
+		res.Changed += changed
+		res.Next = next
+		if next == nil {
+			break
+		}
+	}
+	c.logger.InfoContext(ctx, "Applied category rules", logging.BankAccountId(bankAccountId),
+		slog.Int("changed", res.Changed), slog.Bool("done", res.Next == nil))
+	return res, nil
+}
+
+// bankAccountRules returns the compiled rules of the holder of the bank account
+func (c *CategoryServiceImpl) bankAccountRules(
+	bankAccountId string,
+	ctx context.Context,
+) ([]compiledCategoryRule, error) {
+	getCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	accountId, err := c.ar.GetAccountIdFromBankAccountId(bankAccountId, getCtx)
+	if err != nil {
+		c.logger.WarnContext(ctx, "Unable to get account of bank account", logging.BankAccountId(bankAccountId),
+			logging.Err(err))
+		if errors.Is(err, model.ErrNoMatchingBankAccount) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unable to get account of bank account with error: %w", err)
+	}
+	rules, err := c.cr.GetCategoryRules(accountId, getCtx)
+	if err != nil {
+		c.logger.ErrorContext(ctx, "Unable to get category rules", logging.AccountId(accountId), logging.Err(err))
+		return nil, fmt.Errorf("unable to get category rules with error: %w", err)
+	}
+	return compileCategoryRules(rules), nil
+}
+
+// applyCategoryRulesPage categorizes the page of transactions of the bank account that follows the after cursor in a
+// single database transaction, and returns how many transactions changed category along with the cursor of the next
+// page
+func (c *CategoryServiceImpl) applyCategoryRulesPage(
+	bankAccountId string,
+	rules []compiledCategoryRule,
+	after *model.TransactionCursor,
+	ctx context.Context,
+) (int, *model.TransactionCursor, error) {
+	pageCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	var changed int
+	var next *model.TransactionCursor
+	err := c.tran.WithTransaction(pageCtx, transferTxnOptions, func(txnCtx transactional.TransactionContext) error {
+		changed = 0
+		page, err := c.tr.GetTransactionsPageFromBankAccountId(&model.TransactionPageInput{
+			BankAccountId: bankAccountId,
+			Order:         model.Ascending,
+			PageSize:      maxTransactionPageSize,
+			After:         after,
+		}, txnCtx)
+		if err != nil {
+			c.logger.ErrorContext(ctx, "Unable to get transactions page", logging.BankAccountId(bankAccountId),
+				logging.Err(err))
+			return fmt.Errorf("unable to get transactions page with error: %w", err)
+		}
+		for i := range page.Transactions {
+			transaction := &page.Transactions[i]
+			if transaction.Category.Source == model.ManualCategory {
+				continue
+			}
+			category := matchCategory(rules, accountTransactionSide(transaction))
+			if category == transaction.Category {
+				continue
+			}
+			err = c.tr.UpdateTransactionCategory(transaction.Id, bankAccountId, category, txnCtx)
+			if err != nil {
+				c.logger.ErrorContext(ctx, "Unable to categorize transaction", logging.TransactionId(transaction.Id),
+					logging.BankAccountId(bankAccountId), logging.Err(err))
+				return fmt.Errorf("unable to categorize transaction %s with error: %w", transaction.Id, err)
+			}
+			changed++
+		}
+		next = page.Next
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return changed, next, nil
+}
+
+// CategorizeTransaction sets the category the bank account gives the transaction, which rules no longer change. An
+// empty category hands the transaction back to the rules, which categorize it right away. It returns the category
+// the transaction ends up with.
+func (c *CategoryServiceImpl) CategorizeTransaction(
+	transactionId string,
+	bankAccountId string,
+	category string,
+	ctx context.Context,
+) (model.TransactionCategory, error) {
+	name, err := validateCategory(category)
+	if err != nil {
+		return model.TransactionCategory{}, err
+	}
+	categorizeCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	result := model.TransactionCategory{Name: name, Source: model.ManualCategory}
+	if name == "" {
+		result, err = c.ruleCategory(transactionId, bankAccountId, categorizeCtx)
+		if err != nil {
+			c.logger.WarnContext(ctx, "Unable to categorize transaction by rules", logging.TransactionId(transactionId),
+				logging.BankAccountId(bankAccountId), logging.Err(err))
+			if errors.Is(err, model.ErrNoMatchingTransaction) {
+				return model.TransactionCategory{}, err
+			}
+			return model.TransactionCategory{}, fmt.Errorf("unable to categorize transaction with error: %w", err)
+		}
+	}
+
+	if err = c.tr.UpdateTransactionCategory(transactionId, bankAccountId, result, categorizeCtx); err != nil {
+		c.logger.WarnContext(ctx, "Unable to categorize transaction", logging.TransactionId(transactionId),
+			logging.BankAccountId(bankAccountId), logging.Err(err))
+		if errors.Is(err, model.ErrNoMatchingTransaction) {
+			return model.TransactionCategory{}, err
+		}
+		return model.TransactionCategory{}, fmt.Errorf("unable to categorize transaction with error: %w", err)
+	}
+	c.logger.InfoContext(ctx, "Categorized transaction", logging.TransactionId(transactionId),
+		logging.BankAccountId(bankAccountId), slog.String("source", string(result.Source)))
+	return result, nil
+}
+
+// ruleCategory returns the category the rules of the holder of the bank account give its side of the transaction
+func (c *CategoryServiceImpl) ruleCategory(
+	transactionId string,
+	bankAccountId string,
+	ctx context.Context,
+) (model.TransactionCategory, error) {
+	transaction, err := c.tr.GetTransactionFromId(transactionId, ctx)
+	if err != nil {
+		return model.TransactionCategory{}, fmt.Errorf("error when getting transaction %s: %w", transactionId, err)
+	}
+	input := model.TransactionDetailsInput{
+		FromBankAccountId: transaction.FromBankAccountId,
+		ToBankAccountId:   transaction.ToBankAccountId,
+		Amount:            transaction.Amount,
+		Memo:              transaction.Memo,
+	}
+	fromSide, toSide := transferSides(&input)
+	switch bankAccountId {
+	case transaction.FromBankAccountId:
+		return ruleCategory(c.ar, c.cr, bankAccountId, fromSide, ctx)
+	case transaction.ToBankAccountId:
+		return ruleCategory(c.ar, c.cr, bankAccountId, toSide, ctx)
+	default:
+		return model.TransactionCategory{}, fmt.Errorf("%w for bankAccountId %s", model.ErrNoMatchingTransaction,
+			bankAccountId)
+	}
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/logging"
+	"webserver/test/mocks"
+)
+
+func TestAddCategoryRule(t *testing.T) {
+	t.Run("Adds the normalized rule", func(t *testing.T) {
+		_, _, mockCatRepo, _, service, ctx, cancel := initializeCategoryMocks()
+		defer cancel()
+		mockCatRepo.On("GetCategoryRules", "accountId", mock.Anything).Return(nil, nil)
+		mockCatRepo.On("AddCategoryRule", "accountId", mock.MatchedBy(func(input *model.CategoryRuleInput) bool {
+			return input.Category == "Coffee" && input.MemoPattern == "(?i)coffee"
+		}), mock.Anything).Return(&model.CategoryRule{Id: "ruleId", Category: "Coffee"}, nil)
+
+		rule, err := service.AddCategoryRule("accountId", &model.CategoryRuleInput{
+			Category:    "  Coffee ",
+			MemoPattern: "(?i)coffee",
+		}, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "ruleId", rule.Id)
+	})
+
+	invalidRules := map[string]model.CategoryRuleInput{
+		"without a category":       {Category: "  "},
+		"with a too long category": {Category: strings.Repeat("c", maxCategoryLength+1)},
+		"with an unknown nature":   {Category: "Coffee", TransactionNature: "sideways"},
+		"with a negative amount": {
+			Category:  "Coffee",
+			MinAmount: decimal.NewNullDecimal(decimal.NewFromInt(-1)),
+		},
+		"with amounts the wrong way round": {
+			Category:  "Coffee",
+			MinAmount: decimal.NewNullDecimal(decimal.NewFromInt(10)),
+			MaxAmount: decimal.NewNullDecimal(decimal.NewFromInt(5)),
+		},
+		"with an invalid memo pattern": {Category: "Coffee", MemoPattern: "(coffee"},
+	}
+	for name, input := range invalidRules {
+		t.Run("Rejects rules "+name, func(t *testing.T) {
+			_, _, mockCatRepo, _, service, ctx, cancel := initializeCategoryMocks()
+			defer cancel()
+
+			_, err := service.AddCategoryRule("accountId", &input, ctx)
+			assert.ErrorIs(t, err, model.ErrValidation)
+			mockCatRepo.AssertNumberOfCalls(t, "AddCategoryRule", 0)
+		})
+	}
+
+	t.Run("Returns error if the account has too many rules", func(t *testing.T) {
+		_, _, mockCatRepo, _, service, ctx, cancel := initializeCategoryMocks()
+		defer cancel()
+		mockCatRepo.On("GetCategoryRules", "accountId", mock.Anything).
+			Return(make([]model.CategoryRule, maxCategoryRules), nil)
+
+		_, err := service.AddCategoryRule("accountId", &model.CategoryRuleInput{Category: "Coffee"}, ctx)
+		assert.ErrorIs(t, err, model.ErrTooManyCategoryRules)
+		mockCatRepo.AssertNumberOfCalls(t, "AddCategoryRule", 0)
+	})
+}
+
+func TestApplyCategoryRules(t *testing.T) {
+	t.Run("Categorizes every page again and leaves the transactions categorized by hand", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockCatRepo, mockTran, service, ctx, cancel := initializeCategoryMocks()
+		defer cancel()
+		mockAccRepo.On("GetAccountIdFromBankAccountId", "bankAccountId", mock.Anything).Return("accountId", nil)
+		mockCatRepo.On("GetCategoryRules", "accountId", mock.Anything).Return([]model.CategoryRule{
+			{Category: "Coffee", MemoPattern: "coffee"},
+		}, nil)
+		cursor := &model.TransactionCursor{CreatedAt: time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), Id: "2"}
+		mockTranRepo.On("GetTransactionsPageFromBankAccountId", mock.MatchedBy(
+			func(input *model.TransactionPageInput) bool {
+				return input.After == nil && input.Order == model.Ascending
+			}), mock.Anything).Return(&model.TransactionPageOutput{
+			Transactions: []model.BankAccountTransactionOutput{
+				{Id: "first", Memo: "coffee"},
+				{Id: "second", Memo: "coffee beans", Category: model.TransactionCategory{
+					Name:   "Gifts",
+					Source: model.ManualCategory,
+				}},
+			},
+			Next: cursor,
+		}, nil).Once()
+		mockTranRepo.On("GetTransactionsPageFromBankAccountId", mock.MatchedBy(
+			func(input *model.TransactionPageInput) bool {
+				return input.After == cursor
+			}), mock.Anything).Return(&model.TransactionPageOutput{
+			Transactions: []model.BankAccountTransactionOutput{
+				{Id: "third", Memo: "coffee", Category: model.TransactionCategory{
+					Name:   "Coffee",
+					Source: model.RuleCategory,
+				}},
+				{Id: "fourth", Memo: "books", Category: model.TransactionCategory{
+					Name:   "Coffee",
+					Source: model.RuleCategory,
+				}},
+			},
+		}, nil).Once()
+		mockTranRepo.On("UpdateTransactionCategory", "first", "bankAccountId",
+			model.TransactionCategory{Name: "Coffee", Source: model.RuleCategory}, mock.Anything).Return(nil)
+		mockTranRepo.On("UpdateTransactionCategory", "fourth", "bankAccountId", model.TransactionCategory{},
+			mock.Anything).Return(nil)
+
+		applied, err := service.ApplyCategoryRules("bankAccountId", nil, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, applied.Changed)
+		assert.Nil(t, applied.Next)
+		mockTranRepo.AssertNumberOfCalls(t, "UpdateTransactionCategory", 2)
+		// Every page is categorized in its own transaction
+		mockTran.AssertNumberOfCalls(t, "Commit", 2)
+	})
+
+	t.Run("Resumes from the cursor and stops after the last page it categorizes", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockCatRepo, mockTran, service, ctx, cancel := initializeCategoryMocks()
+		defer cancel()
+		mockAccRepo.On("GetAccountIdFromBankAccountId", "bankAccountId", mock.Anything).Return("accountId", nil)
+		mockCatRepo.On("GetCategoryRules", "accountId", mock.Anything).Return([]model.CategoryRule{
+			{Category: "Coffee"},
+		}, nil)
+		after := &model.TransactionCursor{CreatedAt: time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), Id: "1"}
+		next := &model.TransactionCursor{CreatedAt: time.Date(2024, time.April, 2, 0, 0, 0, 0, time.UTC), Id: "2"}
+		mockTranRepo.On("GetTransactionsPageFromBankAccountId", mock.MatchedBy(
+			func(input *model.TransactionPageInput) bool {
+				return input.After == after
+			}), mock.Anything).Return(&model.TransactionPageOutput{
+			Transactions: []model.BankAccountTransactionOutput{{Id: "first"}},
+			Next:         next,
+		}, nil).Once()
+		mockTranRepo.On("GetTransactionsPageFromBankAccountId", mock.MatchedBy(
+			func(input *model.TransactionPageInput) bool {
+				return input.After == next
+			}), mock.Anything).Return(&model.TransactionPageOutput{
+			Transactions: []model.BankAccountTransactionOutput{{Id: "next"}},
+			Next:         next,
+		}, nil)
+		mockTranRepo.On("UpdateTransactionCategory", mock.Anything, "bankAccountId",
+			model.TransactionCategory{Name: "Coffee", Source: model.RuleCategory}, mock.Anything).Return(nil)
+
+		applied, err := service.ApplyCategoryRules("bankAccountId", after, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, maxCategorizedPages, applied.Changed)
+		assert.Equal(t, next, applied.Next)
+		mockTranRepo.AssertNumberOfCalls(t, "GetTransactionsPageFromBankAccountId", maxCategorizedPages)
+		mockTran.AssertNumberOfCalls(t, "Commit", maxCategorizedPages)
+	})
+
+	t.Run("Keeps the pages categorized before a failure", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockCatRepo, mockTran, service, ctx, cancel := initializeCategoryMocks()
+		defer cancel()
+		mockAccRepo.On("GetAccountIdFromBankAccountId", "bankAccountId", mock.Anything).Return("accountId", nil)
+		mockCatRepo.On("GetCategoryRules", "accountId", mock.Anything).Return([]model.CategoryRule{
+			{Category: "Coffee"},
+		}, nil)
+		cursor := &model.TransactionCursor{CreatedAt: time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), Id: "1"}
+		mockTranRepo.On("GetTransactionsPageFromBankAccountId", mock.MatchedBy(
+			func(input *model.TransactionPageInput) bool {
+				return input.After == nil
+			}), mock.Anything).Return(&model.TransactionPageOutput{
+			Transactions: []model.BankAccountTransactionOutput{{Id: "first"}},
+			Next:         cursor,
+		}, nil)
+		mockTranRepo.On("GetTransactionsPageFromBankAccountId", mock.MatchedBy(
+			func(input *model.TransactionPageInput) bool {
+				return input.After == cursor
+			}), mock.Anything).Return(nil, errors.New("connection lost"))
+		mockTranRepo.On("UpdateTransactionCategory", "first", "bankAccountId", mock.Anything, mock.Anything).
+			Return(nil)
+
+		_, err := service.ApplyCategoryRules("bankAccountId", nil, ctx)
+		assert.NotNil(t, err)
+		mockTran.AssertNumberOfCalls(t, "Commit", 1)
+		mockTran.AssertNumberOfCalls(t, "Rollback", 1)
+	})
+
+	t.Run("Skips rules whose memo pattern does not compile", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockCatRepo, _, service, ctx, cancel := initializeCategoryMocks()
+		defer cancel()
+		mockAccRepo.On("GetAccountIdFromBankAccountId", "bankAccountId", mock.Anything).Return("accountId", nil)
+		mockCatRepo.On("GetCategoryRules", "accountId", mock.Anything).Return([]model.CategoryRule{
+			{Category: "Broken", MemoPattern: "(coffee"},
+			{Category: "Coffee", MemoPattern: "coffee"},
+		}, nil)
+		mockTranRepo.On("GetTransactionsPageFromBankAccountId", mock.Anything, mock.Anything).
+			Return(&model.TransactionPageOutput{
+				Transactions: []model.BankAccountTransactionOutput{{Id: "first", Memo: "coffee"}},
+			}, nil)
+		mockTranRepo.On("UpdateTransactionCategory", "first", "bankAccountId",
+			model.TransactionCategory{Name: "Coffee", Source: model.RuleCategory}, mock.Anything).Return(nil)
+
+		applied, err := service.ApplyCategoryRules("bankAccountId", nil, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, applied.Changed)
+	})
+
+	t.Run("Returns error if the bank account does not exist", func(t *testing.T) {
+		_, mockAccRepo, _, _, service, ctx, cancel := initializeCategoryMocks()
+		defer cancel()
+		mockAccRepo.On("GetAccountIdFromBankAccountId", "bankAccountId", mock.Anything).
+			Return("", model.ErrNoMatchingBankAccount)
+
+		_, err := service.ApplyCategoryRules("bankAccountId", nil, ctx)
+		assert.ErrorIs(t, err, model.ErrNoMatchingBankAccount)
+	})
+}
+
+func TestCategorizeTransaction(t *testing.T) {
+	transaction := &model.TransactionDetailsOutput{
+		Id:                "transactionId",
+		FromBankAccountId: "fromAccountID",
+		ToBankAccountId:   "toAccountID",
+		Amount:            decimal.RequireFromString("3.50"),
+		Memo:              "coffee",
+	}
+
+	t.Run("Sets the category by hand", func(t *testing.T) {
+		mockTranRepo, _, _, _, service, ctx, cancel := initializeCategoryMocks()
+		defer cancel()
+		manual := model.TransactionCategory{Name: "Gifts", Source: model.ManualCategory}
+		mockTranRepo.On("UpdateTransactionCategory", "transactionId", "toAccountID", manual, mock.Anything).
+			Return(nil)
+
+		category, err := service.CategorizeTransaction("transactionId", "toAccountID", " Gifts ", ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, manual, category)
+	})
+
+	t.Run("Hands the transaction back to the rules of the side's account holder", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockCatRepo, _, service, ctx, cancel := initializeCategoryMocks()
+		defer cancel()
+		mockTranRepo.On("GetTransactionFromId", "transactionId", mock.Anything).Return(transaction, nil)
+		mockAccRepo.On("GetAccountIdFromBankAccountId", "toAccountID", mock.Anything).Return("recipient", nil)
+		mockCatRepo.On("GetCategoryRules", "recipient", mock.Anything).Return([]model.CategoryRule{
+			{Category: "Spent", TransactionNature: model.Credit},
+			{Category: "Sales", TransactionNature: model.Debit, CounterpartyBankAccountId: "fromAccountID"},
+		}, nil)
+		rule := model.TransactionCategory{Name: "Sales", Source: model.RuleCategory}
+		mockTranRepo.On("UpdateTransactionCategory", "transactionId", "toAccountID", rule, mock.Anything).Return(nil)
+
+		category, err := service.CategorizeTransaction("transactionId", "toAccountID", "", ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, rule, category)
+	})
+
+	t.Run("Returns error if the bank account is not a side of the transaction", func(t *testing.T) {
+		mockTranRepo, _, _, _, service, ctx, cancel := initializeCategoryMocks()
+		defer cancel()
+		mockTranRepo.On("GetTransactionFromId", "transactionId", mock.Anything).Return(transaction, nil)
+
+		_, err := service.CategorizeTransaction("transactionId", "otherAccountID", "", ctx)
+		assert.ErrorIs(t, err, model.ErrNoMatchingTransaction)
+		mockTranRepo.AssertNumberOfCalls(t, "UpdateTransactionCategory", 0)
+	})
+
+	t.Run("Rejects too long categories", func(t *testing.T) {
+		mockTranRepo, _, _, _, service, ctx, cancel := initializeCategoryMocks()
+		defer cancel()
+
+		_, err := service.CategorizeTransaction("transactionId", "toAccountID",
+			strings.Repeat("c", maxCategoryLength+1), ctx)
+		assert.ErrorIs(t, err, model.ErrInvalidCategory)
+		mockTranRepo.AssertNumberOfCalls(t, "UpdateTransactionCategory", 0)
+	})
+}
+
+func initializeCategoryMocks() (
+	*mocks.MockTransactionRepository,
+	*mocks.MockAccountRepository,
+	*mocks.MockCategoryRuleRepository,
+	*mocks.MockTransactional,
+	*CategoryServiceImpl,
+	context.Context,
+	context.CancelFunc,
+) {
+	mockTranRepo := new(mocks.MockTransactionRepository)
+	mockAccRepo := &mocks.MockAccountRepository{}
+	mockCatRepo := &mocks.MockCategoryRuleRepository{}
+	mockTran := &mocks.MockTransactional{}
+
+	service := CreateNewCategoryServiceImpl(mockAccRepo, mockTranRepo, mockCatRepo, mockTran, logging.Discard())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+	mockTran.On("Commit", mock.Anything).Return(nil)
+	mockTran.On("Rollback", mock.Anything).Return(nil)
+	return mockTranRepo, mockAccRepo, mockCatRepo, mockTran, service, ctx, cancel
+}
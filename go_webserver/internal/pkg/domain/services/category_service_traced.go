@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/tracing"
+)
+
+// CategoryServiceTraced records a span for every call to the category service it wraps
+type CategoryServiceTraced struct {
+	next   CategoryService
+	tracer trace.Tracer
+}
+
+func CreateNewCategoryServiceTraced(next CategoryService, tracer trace.Tracer) *CategoryServiceTraced {
+	return &CategoryServiceTraced{next: next, tracer: tracer}
+}
+
+func (c *CategoryServiceTraced) GetCategoryRules(
+	accountId string,
+	ctx context.Context,
+) (_ []model.CategoryRule, err error) {
+	ctx, span := c.tracer.Start(ctx, "CategoryService.GetCategoryRules")
+	defer func() { tracing.End(span, err) }()
+	return c.next.GetCategoryRules(accountId, ctx)
+}
+
+func (c *CategoryServiceTraced) AddCategoryRule(
+	accountId string,
+	input *model.CategoryRuleInput,
+	ctx context.Context,
+) (_ *model.CategoryRule, err error) {
+	ctx, span := c.tracer.Start(ctx, "CategoryService.AddCategoryRule")
+	defer func() { tracing.End(span, err) }()
+	return c.next.AddCategoryRule(accountId, input, ctx)
+}
+
+func (c *CategoryServiceTraced) RemoveCategoryRule(accountId string, ruleId string, ctx context.Context) (err error) {
+	ctx, span := c.tracer.Start(ctx, "CategoryService.RemoveCategoryRule")
+	defer func() { tracing.End(span, err) }()
+	return c.next.RemoveCategoryRule(accountId, ruleId, ctx)
+}
+
+func (c *CategoryServiceTraced) ApplyCategoryRules(
+	bankAccountId string,
+	after *model.TransactionCursor,
+	ctx context.Context,
+) (_ *model.ApplyCategoryRulesOutput, err error) {
+	ctx, span := c.tracer.Start(ctx, "CategoryService.ApplyCategoryRules")
+	defer func() { tracing.End(span, err) }()
+	return c.next.ApplyCategoryRules(bankAccountId, after, ctx)
+}
+
+func (c *CategoryServiceTraced) CategorizeTransaction(
+	transactionId string,
+	bankAccountId string,
+	category string,
+	ctx context.Context,
+) (_ model.TransactionCategory, err error) {
+	ctx, span := c.tracer.Start(ctx, "CategoryService.CategorizeTransaction")
+	defer func() { tracing.End(span, err) }()
+	return c.next.CategorizeTransaction(transactionId, bankAccountId, category, ctx)
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"github.com/shopspring/decimal"
+	"webserver/internal/pkg/domain/model"
+)
+
+// transferJournalEntry builds the postings of a transfer. A realized transfer moves the amount on both balances of
+// either bank account, while a pending transfer only holds it on their pending balances.
+func transferJournalEntry(transactionId string, input *model.TransactionDetailsInput) *model.JournalEntryInput {
+	balances := []model.BalanceKind{model.AvailableBalance, model.PendingBalance}
+	kind := model.TransferEntry
+	if input.Type == model.Pending {
+		balances = []model.BalanceKind{model.PendingBalance}
+		kind = model.PendingHoldEntry
+	}
+	postings := make([]model.Posting, 0, 2*len(balances))
+	for _, balance := range balances {
+		postings = append(postings,
+			model.Posting{BankAccountId: input.FromBankAccountId, Balance: balance, Amount: input.Amount.Neg()},
+			model.Posting{BankAccountId: input.ToBankAccountId, Balance: balance, Amount: input.Amount},
+		)
+	}
+	return &model.JournalEntryInput{TransactionId: transactionId, Kind: kind, Postings: postings}
+}
+
+// pendingReleaseJournalEntry builds the postings that reverse the hold of a pending transaction on the pending
+// balances of both bank accounts
+func pendingReleaseJournalEntry(transaction *model.TransactionDetailsOutput) *model.JournalEntryInput {
+	return &model.JournalEntryInput{
+		TransactionId: transaction.Id,
+		Kind:          model.PendingReleaseEntry,
+		Postings: []model.Posting{
+			{BankAccountId: transaction.FromBankAccountId, Balance: model.PendingBalance, Amount: transaction.Amount},
+			{
+				BankAccountId: transaction.ToBankAccountId,
+				Balance:       model.PendingBalance,
+				Amount:        transaction.Amount.Neg(),
+			},
+		},
+	}
+}
+
+// isBalanced reports whether the postings to each kind of balance of the entry sum to zero
+func isBalanced(entry *model.JournalEntryInput) bool {
+	sums := make(map[model.BalanceKind]decimal.Decimal)
+	for _, posting := range entry.Postings {
+		sums[posting.Balance] = sums[posting.Balance].Add(posting.Amount)
+	}
+	for _, sum := range sums {
+		if !sum.IsZero() {
+			return false
+		}
+	}
+	return len(entry.Postings) > 0
+}
@@ -0,0 +1,10 @@
+package services
+
+import (
+	"context"
+	"webserver/internal/pkg/domain/model"
+)
+
+type LedgerService interface {
+	FindBalanceDiscrepancies(ctx context.Context) ([]model.BalanceDiscrepancy, error)
+}
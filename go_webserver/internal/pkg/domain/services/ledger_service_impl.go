@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"webserver/internal/pkg/domain/model"
+	repositories2 "webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/logging"
+)
+
+type LedgerServiceImpl struct {
+	ar     repositories2.AccountRepository
+	jr     repositories2.JournalRepository
+	logger *slog.Logger
+}
+
+func CreateNewLedgerServiceImpl(
+	ar repositories2.AccountRepository,
+	jr repositories2.JournalRepository,
+	logger *slog.Logger,
+) *LedgerServiceImpl {
+	return &LedgerServiceImpl{ar, jr, logger}
+}
+
+// FindBalanceDiscrepancies compares the stored balances of every bank account against the totals of their postings
+// in the journal. Postings to bank accounts that no longer exist are reported as well, except for the opening
+// balance equity account, which only ever holds the counterpart of opening balances.
+func (l *LedgerServiceImpl) FindBalanceDiscrepancies(ctx context.Context) ([]model.BalanceDiscrepancy, error) {
+	checkCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	balances, err := l.ar.GetAllBankAccountBalances(checkCtx)
+	if err != nil {
+		l.logger.ErrorContext(ctx, "Unable to get stored bank account balances", logging.Err(err))
+		return nil, fmt.Errorf("error when getting stored bank account balances: %w", err)
+	}
+	totals, err := l.jr.GetPostingTotals(checkCtx)
+	if err != nil {
+		l.logger.ErrorContext(ctx, "Unable to get posting totals", logging.Err(err))
+		return nil, fmt.Errorf("error when getting posting totals: %w", err)
+	}
+
+	posted := make(map[string]*model.BankAccountBalance)
+	for _, total := range totals {
+		if total.BankAccountId == model.OpeningBalanceEquityAccountId {
+			continue
+		}
+		balance, ok := posted[total.BankAccountId]
+		if !ok {
+			balance = &model.BankAccountBalance{BankAccountId: total.BankAccountId}
+			posted[total.BankAccountId] = balance
+		}
+		switch total.Balance {
+		case model.AvailableBalance:
+			balance.AvailableBalance = balance.AvailableBalance.Add(total.Amount)
+		case model.PendingBalance:
+			balance.PendingBalance = balance.PendingBalance.Add(total.Amount)
+		}
+	}
+
+	discrepancies := make([]model.BalanceDiscrepancy, 0)
+	for _, stored := range balances {
+		postedBalance, ok := posted[stored.BankAccountId]
+		if !ok {
+			postedBalance = &model.BankAccountBalance{BankAccountId: stored.BankAccountId}
+		}
+		delete(posted, stored.BankAccountId)
+		if !stored.AvailableBalance.Equal(postedBalance.AvailableBalance) ||
+			!stored.PendingBalance.Equal(postedBalance.PendingBalance) {
+			discrepancies = append(discrepancies, newBalanceDiscrepancy(stored, *postedBalance))
+		}
+	}
+	for _, postedBalance := range posted {
+		unknown := model.BankAccountBalance{BankAccountId: postedBalance.BankAccountId}
+		if !postedBalance.AvailableBalance.IsZero() || !postedBalance.PendingBalance.IsZero() {
+			discrepancies = append(discrepancies, newBalanceDiscrepancy(unknown, *postedBalance))
+		}
+	}
+	sort.Slice(discrepancies, func(i, j int) bool {
+		return discrepancies[i].BankAccountId < discrepancies[j].BankAccountId
+	})
+
+	l.logger.InfoContext(ctx, "Compared balances against the journal", slog.Int("discrepancies", len(discrepancies)))
+	return discrepancies, nil
+}
+
+func newBalanceDiscrepancy(stored model.BankAccountBalance, posted model.BankAccountBalance) model.BalanceDiscrepancy {
+	return model.BalanceDiscrepancy{
+		BankAccountId:   stored.BankAccountId,
+		AccountNumber:   stored.AccountNumber,
+		StoredAvailable: stored.AvailableBalance,
+		PostedAvailable: posted.AvailableBalance,
+		StoredPending:   stored.PendingBalance,
+		PostedPending:   posted.PendingBalance,
+	}
+}
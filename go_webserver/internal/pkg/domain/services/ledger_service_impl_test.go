@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/logging"
+	"webserver/test/mocks"
+)
+
+func TestFindBalanceDiscrepancies(t *testing.T) {
+	balances := []model.BankAccountBalance{
+		{
+			BankAccountId:    "bankAccountId1",
+			AccountNumber:    "1234567890",
+			AvailableBalance: decimal.RequireFromString("900.00"),
+			PendingBalance:   decimal.RequireFromString("800.00"),
+		},
+		{
+			BankAccountId:    "bankAccountId2",
+			AccountNumber:    "0987654321",
+			AvailableBalance: decimal.RequireFromString("100.00"),
+			PendingBalance:   decimal.RequireFromString("200.00"),
+		},
+	}
+	totals := []model.PostingTotal{
+		{BankAccountId: "bankAccountId1", Balance: model.AvailableBalance, Amount: decimal.RequireFromString("900")},
+		{BankAccountId: "bankAccountId1", Balance: model.PendingBalance, Amount: decimal.RequireFromString("800")},
+		{BankAccountId: "bankAccountId2", Balance: model.AvailableBalance, Amount: decimal.RequireFromString("100")},
+		{BankAccountId: "bankAccountId2", Balance: model.PendingBalance, Amount: decimal.RequireFromString("200")},
+		{
+			BankAccountId: model.OpeningBalanceEquityAccountId,
+			Balance:       model.AvailableBalance,
+			Amount:        decimal.RequireFromString("-1000"),
+		},
+	}
+
+	t.Run("Reports nothing when every stored balance matches its postings", func(t *testing.T) {
+		mockAccRepo, mockJournalRepo, service, ctx, cancel := initializeLedgerMocks()
+		defer cancel()
+		mockAccRepo.On("GetAllBankAccountBalances", mock.Anything).Return(balances, nil)
+		mockJournalRepo.On("GetPostingTotals", mock.Anything).Return(totals, nil)
+
+		discrepancies, err := service.FindBalanceDiscrepancies(ctx)
+		assert.Nil(t, err)
+		assert.Empty(t, discrepancies)
+	})
+
+	t.Run("Reports bank accounts whose stored balances diverge from their postings", func(t *testing.T) {
+		mockAccRepo, mockJournalRepo, service, ctx, cancel := initializeLedgerMocks()
+		defer cancel()
+		tampered := append([]model.BankAccountBalance{}, balances...)
+		tampered[1].PendingBalance = decimal.RequireFromString("250.00")
+		mockAccRepo.On("GetAllBankAccountBalances", mock.Anything).Return(tampered, nil)
+		mockJournalRepo.On("GetPostingTotals", mock.Anything).Return(totals, nil)
+
+		discrepancies, err := service.FindBalanceDiscrepancies(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, discrepancies, 1)
+		assert.Equal(t, "bankAccountId2", discrepancies[0].BankAccountId)
+		assert.Equal(t, "0987654321", discrepancies[0].AccountNumber)
+		assert.True(t, decimal.RequireFromString("250").Equal(discrepancies[0].StoredPending))
+		assert.True(t, decimal.RequireFromString("200").Equal(discrepancies[0].PostedPending))
+	})
+
+	t.Run("Reports bank accounts without postings and postings without bank accounts", func(t *testing.T) {
+		mockAccRepo, mockJournalRepo, service, ctx, cancel := initializeLedgerMocks()
+		defer cancel()
+		mockAccRepo.On("GetAllBankAccountBalances", mock.Anything).Return(balances[:1], nil)
+		mockJournalRepo.On("GetPostingTotals", mock.Anything).Return(totals[2:], nil)
+
+		discrepancies, err := service.FindBalanceDiscrepancies(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, discrepancies, 2)
+		assert.Equal(t, "bankAccountId1", discrepancies[0].BankAccountId)
+		assert.True(t, discrepancies[0].PostedAvailable.IsZero())
+		assert.Equal(t, "bankAccountId2", discrepancies[1].BankAccountId)
+		assert.True(t, discrepancies[1].StoredAvailable.IsZero())
+	})
+
+	t.Run("Returns error if the posting totals cannot be read", func(t *testing.T) {
+		mockAccRepo, mockJournalRepo, service, ctx, cancel := initializeLedgerMocks()
+		defer cancel()
+		mockAccRepo.On("GetAllBankAccountBalances", mock.Anything).Return(balances, nil)
+		mockJournalRepo.On("GetPostingTotals", mock.Anything).Return(nil, assert.AnError)
+
+		_, err := service.FindBalanceDiscrepancies(ctx)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func initializeLedgerMocks() (
+	*mocks.MockAccountRepository,
+	*mocks.MockJournalRepository,
+	*LedgerServiceImpl,
+	context.Context,
+	context.CancelFunc,
+) {
+	mockAccRepo := &mocks.MockAccountRepository{}
+	mockJournalRepo := &mocks.MockJournalRepository{}
+	service := CreateNewLedgerServiceImpl(mockAccRepo, mockJournalRepo, logging.Discard())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return mockAccRepo, mockJournalRepo, service, ctx, cancel
+}
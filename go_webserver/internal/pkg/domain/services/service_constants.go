@@ -1,5 +1,68 @@
 package services
 
-import "time"
+import (
+	"context"
+	"regexp"
+	"time"
+	"webserver/internal/pkg/infrastructure/transactional"
+)
 
+// addTimeout bounds service operations whose context carries no deadline, such as those run by background jobs
 const addTimeout = 3 * time.Second
+
+// transferTxnOptions are used by every database transaction that moves money between bank accounts
+var transferTxnOptions = transactional.TransactionOptions{
+	Isolation:  transactional.IsolationLow,
+	Durability: transactional.DurabilityHigh,
+}
+
+// dummyPasswordHash is verified against when a login names an unknown username
+const dummyPasswordHash = "$argon2id$v=19$m=65536,t=3,p=2$c29tZXNhbHRzb21lc2FsdA$" +
+	"tkLzMODWu6tKSmJrGzzyi2YCzjkORCwvI3xCnBW+nyo"
+
+// minPasswordLength is the shortest password accepted when registering an account
+const minPasswordLength = 8
+
+// maxAccountNumberAttempts bounds how many account numbers are drawn when opening a bank account
+const maxAccountNumberAttempts = 5
+
+// maxPayeeNicknameLength bounds the nickname given to a payee
+const maxPayeeNicknameLength = 64
+
+// maxMemoLength bounds the memo the sender gives a transfer
+const maxMemoLength = 140
+
+// maxCategoryLength, maxMemoPatternLength and maxCategoryRules bound the categories of transactions and the rules
+// an account categorizes them with
+const (
+	maxCategoryLength    = 64
+	maxMemoPatternLength = 256
+	maxCategoryRules     = 100
+)
+
+// defaultTransactionPageSize and maxTransactionPageSize bound the number of transactions listed per page
+const (
+	defaultTransactionPageSize = 50
+	maxTransactionPageSize     = 200
+)
+
+// maxCategorizedPages bounds the number of pages of transactions a single request applying category rules categorizes
+const maxCategorizedPages = 10
+
+// maxBalanceHistoryBuckets bounds the number of buckets of a balance history, which is enough for five years of days
+const maxBalanceHistoryBuckets = 2000
+
+// balanceSnapshotDelay is how long after midnight UTC a day is snapshotted, which lets the transfers that started
+// before midnight be committed first
+const balanceSnapshotDelay = 5 * time.Minute
+
+var usernameRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{3,32}$`)
+
+// withOperationDeadline bounds an operation by the deadline of the request it serves, which is set from the budget
+// of the request's route, falling back to addTimeout when there is none
+func withOperationDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, hasDeadline := ctx.Deadline(); hasDeadline {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, addTimeout)
+}
@@ -0,0 +1,11 @@
+package services
+
+import (
+	"context"
+	"webserver/internal/pkg/domain/model"
+)
+
+type StatementService interface {
+	GetStatement(input *model.StatementInput, ctx context.Context) (*model.StatementOutput, error)
+	GetMonthlyStatement(input *model.MonthlyStatementInput, ctx context.Context) (*model.MonthlyStatementOutput, error)
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+)
+
+type StatementServiceImpl struct {
+	ar     repositories.AccountRepository
+	tr     repositories.TransactionRepository
+	tran   transactional.Transactional
+	logger *slog.Logger
+}
+
+func CreateNewStatementServiceImpl(
+	ar repositories.AccountRepository,
+	tr repositories.TransactionRepository,
+	tran transactional.Transactional,
+	logger *slog.Logger,
+) *StatementServiceImpl {
+	return &StatementServiceImpl{ar: ar, tr: tr, tran: tran, logger: logger}
+}
+
+// statementPeriod holds a bank account along with its balances around a period and its realized transactions in the
+// period, oldest first
+type statementPeriod struct {
+	accountDetails          *model.AccountDetailsOutput
+	bankAccount             *model.BankAccount
+	openingAvailableBalance decimal.Decimal
+	closingAvailableBalance decimal.Decimal
+	closingPendingBalance   decimal.Decimal
+	transactions            []model.BankAccountTransactionOutput
+	createdAt               time.Time
+}
+
+// GetStatement lists the realized transactions of the bank account over the period of the input along with its
+// available balances at the start and at the end of the period
+func (s *StatementServiceImpl) GetStatement(
+	input *model.StatementInput,
+	ctx context.Context,
+) (*model.StatementOutput, error) {
+	getCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	txnCtx, err := s.tran.BeginTransaction(getCtx, transactional.IsolationHigh, transactional.DurabilityHigh)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Unable to begin transaction for statement",
+			logging.BankAccountId(input.BankAccountId), logging.Err(err))
+		return nil, fmt.Errorf("error when beginning transaction: %w", err)
+	}
+	defer func() {
+		if rollErr := s.tran.Rollback(txnCtx); rollErr != nil {
+			s.logger.ErrorContext(ctx, "Unable to roll back transaction", logging.Err(rollErr))
+		}
+	}()
+
+	period, err := s.getPeriod(input.BankAccountId, input.FromTime, input.ToTime, txnCtx)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]model.StatementEntry, len(period.transactions))
+	counterparties := make(map[string]*model.KnownBankAccount)
+	for i, transaction := range period.transactions {
+		counterparty, err := s.getCounterparty(transaction.OtherBankAccountId, counterparties, txnCtx)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = model.StatementEntry{
+			BankAccountTransactionOutput: transaction,
+			CounterpartyAccountNumber:    counterparty.AccountNumber,
+			CounterpartyHolder:           counterparty.AccountHolder,
+		}
+	}
+
+	s.logger.DebugContext(ctx, "Created statement", logging.BankAccountId(input.BankAccountId))
+	return &model.StatementOutput{
+		BankAccountId:           input.BankAccountId,
+		AccountNumber:           period.bankAccount.AccountNumber,
+		AccountType:             period.bankAccount.AccountType,
+		AccountHolder:           holderName(period.accountDetails.Person),
+		FromTime:                input.FromTime,
+		ToTime:                  input.ToTime,
+		OpeningAvailableBalance: period.openingAvailableBalance,
+		ClosingAvailableBalance: period.closingAvailableBalance,
+		Entries:                 entries,
+		CreatedAt:               period.createdAt,
+	}, nil
+}
+
+// GetMonthlyStatement lists the realized transactions of the bank account over the calendar month of the input, in
+// UTC, along with its balances at the end of the month
+func (s *StatementServiceImpl) GetMonthlyStatement(
+	input *model.MonthlyStatementInput,
+	ctx context.Context,
+) (*model.MonthlyStatementOutput, error) {
+	getCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	txnCtx, err := s.tran.BeginTransaction(getCtx, transactional.IsolationHigh, transactional.DurabilityHigh)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Unable to begin transaction for monthly statement",
+			logging.BankAccountId(input.BankAccountId), logging.Err(err))
+		return nil, fmt.Errorf("error when beginning transaction: %w", err)
+	}
+	defer func() {
+		if rollErr := s.tran.Rollback(txnCtx); rollErr != nil {
+			s.logger.ErrorContext(ctx, "Unable to roll back transaction", logging.Err(rollErr))
+		}
+	}()
+
+	// Transactions are stored to the second, so the last second of the month is the last one they can be made in
+	fromTime := time.Date(input.Year, input.Month, 1, 0, 0, 0, 0, time.UTC)
+	toTime := fromTime.AddDate(0, 1, 0).Add(-time.Second)
+	period, err := s.getPeriod(input.BankAccountId, fromTime, toTime, txnCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.DebugContext(ctx, "Created monthly statement", logging.BankAccountId(input.BankAccountId))
+	return &model.MonthlyStatementOutput{
+		Account:     *period.accountDetails,
+		BankAccount: *period.bankAccount,
+		Month: model.AccountBalanceMonth{
+			Month:            input.Month,
+			Year:             input.Year,
+			AvailableBalance: period.closingAvailableBalance,
+			PendingBalance:   period.closingPendingBalance,
+		},
+		Transactions: period.transactions,
+		CreatedAt:    period.createdAt,
+	}, nil
+}
+
+// getPeriod finds the balances of the bank account around the period by undoing the transactions made since its
+// start from the current balances, read along with the transactions from the snapshot of the transaction context
+func (s *StatementServiceImpl) getPeriod(
+	bankAccountId string,
+	fromTime time.Time,
+	toTime time.Time,
+	txnCtx context.Context,
+) (*statementPeriod, error) {
+	accountDetails, err := s.ar.GetAccountDetailsFromBankAccountId(bankAccountId, txnCtx)
+	if err != nil {
+		return nil, fmt.Errorf("error when getting account details of BankAccount %s: %w", bankAccountId, err)
+	}
+	bankAccount, ok := findBankAccount(accountDetails, bankAccountId)
+	if !ok {
+		return nil, fmt.Errorf("%w for bankAccountId %s", model.ErrNoMatchingBankAccount, bankAccountId)
+	}
+	now := time.Now()
+	availableBalance, pendingBalance, err := s.ar.GetAccountBalance(bankAccountId, txnCtx)
+	if err != nil {
+		return nil, fmt.Errorf("error when getting balance of BankAccount %s: %w", bankAccountId, err)
+	}
+	transactions, err := s.tr.GetTransactionsFromBankAccountId(&model.TransactionsForBankAccountInput{
+		BankAccountId: bankAccountId,
+		FromTime:      fromTime,
+		ToTime:        now,
+	}, txnCtx)
+	if err != nil {
+		return nil, fmt.Errorf("error when getting transactions of BankAccount %s: %w", bankAccountId, err)
+	}
+
+	// Undo the transactions newest first, down to the end and then down to the start of the period
+	sortNewestFirst(transactions)
+	period := &statementPeriod{
+		accountDetails:          accountDetails,
+		bankAccount:             bankAccount,
+		openingAvailableBalance: availableBalance,
+		closingAvailableBalance: availableBalance,
+		closingPendingBalance:   pendingBalance,
+		createdAt:               now,
+	}
+	var inPeriod []model.BankAccountTransactionOutput
+	for _, transaction := range transactions {
+		period.openingAvailableBalance, pendingBalance = undoTransaction(transaction, period.openingAvailableBalance,
+			pendingBalance)
+		if transaction.CreatedAt.After(toTime) {
+			period.closingAvailableBalance, period.closingPendingBalance = period.openingAvailableBalance,
+				pendingBalance
+		} else if transaction.TransactionType == model.Realized {
+			inPeriod = append(inPeriod, transaction)
+		}
+	}
+	period.transactions = make([]model.BankAccountTransactionOutput, len(inPeriod))
+	for i := range inPeriod {
+		period.transactions[i] = inPeriod[len(inPeriod)-1-i]
+	}
+	return period, nil
+}
+
+// getCounterparty resolves the account number and holder of a bank account once per statement, leaving both empty
+// for bank accounts that no longer exist
+func (s *StatementServiceImpl) getCounterparty(
+	bankAccountId string,
+	counterparties map[string]*model.KnownBankAccount,
+	ctx context.Context,
+) (*model.KnownBankAccount, error) {
+	if counterparty, ok := counterparties[bankAccountId]; ok {
+		return counterparty, nil
+	}
+	counterparty := &model.KnownBankAccount{Id: bankAccountId}
+	accountDetails, err := s.ar.GetAccountDetailsFromBankAccountId(bankAccountId, ctx)
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("error when getting account details of counterparty %s: %w", bankAccountId, err)
+	default:
+		if bankAccount, ok := findBankAccount(accountDetails, bankAccountId); ok {
+			counterparty.AccountNumber = bankAccount.AccountNumber
+			counterparty.AccountType = bankAccount.AccountType
+			counterparty.AccountHolder = holderName(accountDetails.Person)
+		}
+	}
+	counterparties[bankAccountId] = counterparty
+	return counterparty, nil
+}
+
+func findBankAccount(accountDetails *model.AccountDetailsOutput, bankAccountId string) (*model.BankAccount, bool) {
+	for i := range accountDetails.BankAccounts {
+		if accountDetails.BankAccounts[i].Id == bankAccountId {
+			return &accountDetails.BankAccounts[i], true
+		}
+	}
+	return nil, false
+}
+
+func holderName(person model.Person) string {
+	return person.FirstName + " " + person.LastName
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/logging"
+	"webserver/test/mocks"
+)
+
+func TestGetStatement(t *testing.T) {
+	fromTime := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	toTime := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+	statementInput := model.StatementInput{BankAccountId: "tomBankAccountId", FromTime: fromTime, ToTime: toTime}
+	tomDetails := &model.AccountDetailsOutput{
+		Person: model.Person{FirstName: "Tom", LastName: "Smith"},
+		BankAccounts: []model.BankAccount{
+			{Id: "tomBankAccountId", AccountNumber: "123-45678-9", AccountType: model.Checking},
+		},
+	}
+	samDetails := &model.AccountDetailsOutput{
+		Person: model.Person{FirstName: "Sam", LastName: "Jones"},
+		BankAccounts: []model.BankAccount{
+			{Id: "samBankAccountId", AccountNumber: "987-65432-1", AccountType: model.Savings},
+		},
+	}
+	transaction := func(
+		id string,
+		nature model.TransactionNature,
+		transactionType model.TransactionType,
+		amount string,
+		createdAt time.Time,
+	) model.BankAccountTransactionOutput {
+		return model.BankAccountTransactionOutput{
+			Id:                 id,
+			BankAccountId:      "tomBankAccountId",
+			OtherBankAccountId: "samBankAccountId",
+			TransactionNature:  nature,
+			TransactionType:    transactionType,
+			Status:             model.Active,
+			Amount:             decimal.RequireFromString(amount),
+			CreatedAt:          createdAt,
+		}
+	}
+	// Tom receives 50.00 and sends 20.00 during March and receives 30.00 after it, with a transfer of 5.00 still
+	// pending. Transactions are listed in no particular order.
+	received := transaction("received", model.Debit, model.Realized, "50.00", fromTime.Add(24*time.Hour))
+	sent := transaction("sent", model.Credit, model.Realized, "20.00", fromTime.Add(48*time.Hour))
+	pending := transaction("pending", model.Credit, model.Pending, "5.00", fromTime.Add(72*time.Hour))
+	later := transaction("later", model.Debit, model.Realized, "30.00", toTime.Add(24*time.Hour))
+	transactions := []model.BankAccountTransactionOutput{sent, later, pending, received}
+
+	t.Run("Reconstructs the opening and closing balances and lists realized transactions oldest first",
+		func(t *testing.T) {
+			mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeStatementMocks()
+			defer cancel()
+			mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+			mockTran.On("Rollback", mock.Anything).Return(nil)
+			mockAccRepo.On("GetAccountDetailsFromBankAccountId", "tomBankAccountId", mock.Anything).
+				Return(tomDetails, nil)
+			mockAccRepo.On("GetAccountDetailsFromBankAccountId", "samBankAccountId", mock.Anything).
+				Return(samDetails, nil)
+			mockAccRepo.On("GetAccountBalance", "tomBankAccountId", mock.Anything).
+				Return(decimal.RequireFromString("100.00"), decimal.RequireFromString("100.00"), nil)
+			mockTranRepo.On("GetTransactionsFromBankAccountId", mock.MatchedBy(
+				func(input *model.TransactionsForBankAccountInput) bool {
+					return input.FromTime.Equal(fromTime) && input.ToTime.After(toTime)
+				}), mock.Anything).Return(transactions, nil)
+
+			statement, err := service.GetStatement(&statementInput, ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, "123-45678-9", statement.AccountNumber)
+			assert.Equal(t, model.Checking, statement.AccountType)
+			assert.Equal(t, "Tom Smith", statement.AccountHolder)
+			assert.True(t, decimal.RequireFromString("40.00").Equal(statement.OpeningAvailableBalance),
+				statement.OpeningAvailableBalance.String())
+			assert.True(t, decimal.RequireFromString("70.00").Equal(statement.ClosingAvailableBalance),
+				statement.ClosingAvailableBalance.String())
+			assert.Len(t, statement.Entries, 2)
+			assert.Equal(t, "received", statement.Entries[0].Id)
+			assert.Equal(t, "sent", statement.Entries[1].Id)
+			for _, entry := range statement.Entries {
+				assert.Equal(t, "987-65432-1", entry.CounterpartyAccountNumber)
+				assert.Equal(t, "Sam Jones", entry.CounterpartyHolder)
+			}
+			mockAccRepo.AssertNumberOfCalls(t, "GetAccountDetailsFromBankAccountId", 2)
+			mockTran.AssertNumberOfCalls(t, "Rollback", 1)
+		})
+
+	t.Run("Leaves counterparties that no longer exist unnamed", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeStatementMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountDetailsFromBankAccountId", "tomBankAccountId", mock.Anything).
+			Return(tomDetails, nil)
+		mockAccRepo.On("GetAccountDetailsFromBankAccountId", "samBankAccountId", mock.Anything).
+			Return(nil, model.ErrNoMatchingBankAccount)
+		mockAccRepo.On("GetAccountBalance", "tomBankAccountId", mock.Anything).
+			Return(decimal.RequireFromString("100.00"), decimal.RequireFromString("100.00"), nil)
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.Anything, mock.Anything).
+			Return([]model.BankAccountTransactionOutput{received}, nil)
+
+		statement, err := service.GetStatement(&statementInput, ctx)
+		assert.Nil(t, err)
+		assert.Len(t, statement.Entries, 1)
+		assert.Equal(t, "samBankAccountId", statement.Entries[0].OtherBankAccountId)
+		assert.Empty(t, statement.Entries[0].CounterpartyAccountNumber)
+		assert.Empty(t, statement.Entries[0].CounterpartyHolder)
+	})
+
+	t.Run("Returns error if the counterparty cannot be read", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeStatementMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountDetailsFromBankAccountId", "tomBankAccountId", mock.Anything).
+			Return(tomDetails, nil)
+		mockAccRepo.On("GetAccountDetailsFromBankAccountId", "samBankAccountId", mock.Anything).
+			Return(nil, model.ErrStorageUnavailable)
+		mockAccRepo.On("GetAccountBalance", "tomBankAccountId", mock.Anything).
+			Return(decimal.RequireFromString("100.00"), decimal.RequireFromString("100.00"), nil)
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.Anything, mock.Anything).
+			Return([]model.BankAccountTransactionOutput{received}, nil)
+
+		_, err := service.GetStatement(&statementInput, ctx)
+		assert.ErrorIs(t, err, model.ErrStorageUnavailable)
+	})
+
+	t.Run("Returns error if the bank account does not exist", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeStatementMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountDetailsFromBankAccountId", "tomBankAccountId", mock.Anything).
+			Return(samDetails, nil)
+
+		_, err := service.GetStatement(&statementInput, ctx)
+		assert.ErrorIs(t, err, model.ErrNoMatchingBankAccount)
+		mockTranRepo.AssertNumberOfCalls(t, "GetTransactionsFromBankAccountId", 0)
+		mockTran.AssertNumberOfCalls(t, "Rollback", 1)
+	})
+
+	t.Run("Returns error if the transaction cannot begin", func(t *testing.T) {
+		_, mockAccRepo, mockTran, service, ctx, cancel := initializeStatementMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).
+			Return(ctx, errors.New("can't start transaction"))
+
+		_, err := service.GetStatement(&statementInput, ctx)
+		assert.Error(t, err, "can't start transaction")
+		mockTran.AssertNumberOfCalls(t, "Rollback", 0)
+		mockAccRepo.AssertNumberOfCalls(t, "GetAccountDetailsFromBankAccountId", 0)
+	})
+}
+
+func TestGetMonthlyStatement(t *testing.T) {
+	tomDetails := &model.AccountDetailsOutput{
+		Person: model.Person{FirstName: "Tom", LastName: "Smith"},
+		BankAccounts: []model.BankAccount{
+			{Id: "tomBankAccountId", AccountNumber: "123-45678-9", AccountType: model.Checking},
+		},
+	}
+	transaction := func(
+		id string,
+		transactionType model.TransactionType,
+		amount string,
+		createdAt time.Time,
+	) model.BankAccountTransactionOutput {
+		return model.BankAccountTransactionOutput{
+			Id:                 id,
+			BankAccountId:      "tomBankAccountId",
+			OtherBankAccountId: "samBankAccountId",
+			TransactionNature:  model.Debit,
+			TransactionType:    transactionType,
+			Status:             model.Active,
+			Amount:             decimal.RequireFromString(amount),
+			CreatedAt:          createdAt,
+		}
+	}
+	// Tom receives 50.00 at the end of February and 30.00 in March, and has 5.00 pending since March
+	lastSecond := time.Date(2024, time.February, 29, 23, 59, 59, 0, time.UTC)
+	received := transaction("received", model.Realized, "50.00", lastSecond)
+	later := transaction("later", model.Realized, "30.00", lastSecond.Add(time.Second))
+	pending := transaction("pending", model.Pending, "5.00", lastSecond.Add(time.Hour))
+
+	t.Run("Reconstructs the balances at the end of the month and lists its realized transactions", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeStatementMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountDetailsFromBankAccountId", "tomBankAccountId", mock.Anything).
+			Return(tomDetails, nil)
+		mockAccRepo.On("GetAccountBalance", "tomBankAccountId", mock.Anything).
+			Return(decimal.RequireFromString("100.00"), decimal.RequireFromString("105.00"), nil)
+		mockTranRepo.On("GetTransactionsFromBankAccountId", mock.MatchedBy(
+			func(input *model.TransactionsForBankAccountInput) bool {
+				return input.FromTime.Equal(time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC))
+			}), mock.Anything).Return([]model.BankAccountTransactionOutput{pending, received, later}, nil)
+
+		statement, err := service.GetMonthlyStatement(&model.MonthlyStatementInput{
+			BankAccountId: "tomBankAccountId",
+			Year:          2024,
+			Month:         time.February,
+		}, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "Tom", statement.Account.Person.FirstName)
+		assert.Equal(t, "123-45678-9", statement.BankAccount.AccountNumber)
+		assert.Equal(t, time.February, statement.Month.Month)
+		assert.Equal(t, 2024, statement.Month.Year)
+		assert.True(t, decimal.RequireFromString("70.00").Equal(statement.Month.AvailableBalance),
+			statement.Month.AvailableBalance.String())
+		assert.True(t, decimal.RequireFromString("70.00").Equal(statement.Month.PendingBalance),
+			statement.Month.PendingBalance.String())
+		assert.Equal(t, []model.BankAccountTransactionOutput{received}, statement.Transactions)
+		mockTran.AssertNumberOfCalls(t, "Rollback", 1)
+	})
+
+	t.Run("Returns error if the bank account does not exist", func(t *testing.T) {
+		_, mockAccRepo, mockTran, service, ctx, cancel := initializeStatementMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockAccRepo.On("GetAccountDetailsFromBankAccountId", "samBankAccountId", mock.Anything).
+			Return(nil, model.ErrNoMatchingBankAccount)
+
+		_, err := service.GetMonthlyStatement(&model.MonthlyStatementInput{
+			BankAccountId: "samBankAccountId",
+			Year:          2024,
+			Month:         time.February,
+		}, ctx)
+		assert.ErrorIs(t, err, model.ErrNoMatchingBankAccount)
+	})
+}
+
+func initializeStatementMocks() (
+	*mocks.MockTransactionRepository,
+	*mocks.MockAccountRepository,
+	*mocks.MockTransactional,
+	*StatementServiceImpl,
+	context.Context,
+	context.CancelFunc,
+) {
+	mockTranRepo := &mocks.MockTransactionRepository{}
+	mockAccRepo := &mocks.MockAccountRepository{}
+	mockTran := &mocks.MockTransactional{}
+	service := CreateNewStatementServiceImpl(mockAccRepo, mockTranRepo, mockTran, logging.Discard())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel
+}
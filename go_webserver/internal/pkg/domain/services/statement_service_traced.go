@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/tracing"
+)
+
+// StatementServiceTraced records a span for every call to the statement service it wraps
+type StatementServiceTraced struct {
+	next   StatementService
+	tracer trace.Tracer
+}
+
+func CreateNewStatementServiceTraced(next StatementService, tracer trace.Tracer) *StatementServiceTraced {
+	return &StatementServiceTraced{next: next, tracer: tracer}
+}
+
+func (s *StatementServiceTraced) GetStatement(
+	input *model.StatementInput,
+	ctx context.Context,
+) (_ *model.StatementOutput, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatementService.GetStatement")
+	defer func() { tracing.End(span, err) }()
+	return s.next.GetStatement(input, ctx)
+}
+
+func (s *StatementServiceTraced) GetMonthlyStatement(
+	input *model.MonthlyStatementInput,
+	ctx context.Context,
+) (_ *model.MonthlyStatementOutput, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatementService.GetMonthlyStatement")
+	defer func() { tracing.End(span, err) }()
+	return s.next.GetMonthlyStatement(input, ctx)
+}
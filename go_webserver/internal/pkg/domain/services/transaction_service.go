@@ -2,12 +2,23 @@ package services
 
 import (
 	"context"
+	"time"
 	"webserver/internal/pkg/domain/model"
 )
 
 type TransactionService interface {
 	AddTransaction(
 		input model.TransactionDetailsInput,
+		idempotencyKey *model.IdempotencyKeyInput,
 		ctx context.Context,
-	) error
+	) (model.TransactionCreatedOutput, error)
+	AddPendingTransaction(
+		input model.TransactionDetailsInput,
+		ctx context.Context,
+	) (string, error)
+	GetTransactionDetails(transactionId string, ctx context.Context) (*model.TransactionDetailsOutput, error)
+	ApplyPendingTransaction(transactionId string, ctx context.Context) error
+	RevokePendingTransaction(transactionId string, ctx context.Context) error
+	RevokeExpiredPendingTransactions(expiredBy time.Time, ctx context.Context) (int, error)
+	DeleteExpiredIdempotencyKeys(now time.Time, ctx context.Context) (int, error)
 }
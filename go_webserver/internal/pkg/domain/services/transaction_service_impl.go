@@ -2,86 +2,490 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 	"webserver/internal/pkg/domain/model"
 	repositories2 "webserver/internal/pkg/domain/repositories"
 	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
 )
 
 type TransactionServiceImpl struct {
-	tr   repositories2.TransactionRepository
-	ar   repositories2.AccountRepository
-	tran transactional.Transactional
+	tr          repositories2.TransactionRepository
+	ar          repositories2.AccountRepository
+	ir          repositories2.IdempotencyRepository
+	jr          repositories2.JournalRepository
+	cr          repositories2.CategoryRuleRepository
+	tran        transactional.Transactional
+	payeePolicy model.UnknownPayeePolicy
+	metrics     *metrics.Metrics
+	logger      *slog.Logger
 }
 
 func CreateNewTransactionServiceImpl(
 	tr repositories2.TransactionRepository,
 	ar repositories2.AccountRepository,
+	ir repositories2.IdempotencyRepository,
+	jr repositories2.JournalRepository,
+	cr repositories2.CategoryRuleRepository,
 	transactional transactional.Transactional,
+	payeePolicy model.UnknownPayeePolicy,
+	metrics *metrics.Metrics,
+	logger *slog.Logger,
 ) *TransactionServiceImpl {
-	return &TransactionServiceImpl{tr, ar, transactional}
+	return &TransactionServiceImpl{tr, ar, ir, jr, cr, transactional, payeePolicy, metrics, logger}
 }
 
-func (t *TransactionServiceImpl) AddTransaction(input model.TransactionDetailsInput, ctx context.Context) error {
-	addCtx, cancel := context.WithTimeout(ctx, addTimeout)
+// AddTransaction transfers the amount between the two bank accounts. When an idempotency key is given, it is stored
+// in the same database transaction as the transfer so that retrying the request returns the original transaction
+// instead of transferring the amount again. Transfers to destinations that are neither bank accounts of the sender
+// nor known payees are subject to the unknown payee policy of the service.
+func (t *TransactionServiceImpl) AddTransaction(
+	input model.TransactionDetailsInput,
+	idempotencyKey *model.IdempotencyKeyInput,
+	ctx context.Context,
+) (model.TransactionCreatedOutput, error) {
+	memo, err := validateMemo(input.Memo)
+	if err != nil {
+		return model.TransactionCreatedOutput{}, err
+	}
+	input.Memo = memo
+
+	addCtx, cancel := withOperationDeadline(ctx)
 	defer cancel()
 
-	txnCtx, err := t.tran.BeginTransaction(addCtx, transactional.IsolationLow, transactional.DurabilityHigh)
+	requestHash := hashTransactionRequest(&input)
+	var res model.TransactionCreatedOutput
+	err = t.tran.WithTransaction(addCtx, transferTxnOptions, func(txnCtx transactional.TransactionContext) error {
+		if idempotencyKey != nil {
+			record, err := t.ir.GetIdempotencyRecord(idempotencyKey, txnCtx)
+			if err == nil {
+				if record.RequestHash != requestHash {
+					t.logger.WarnContext(ctx, "Rejecting reuse of idempotency key for a different request",
+						slog.String(logging.IdempotencyKeyKey,
+							idempotencyKey.Key), logging.AccountId(idempotencyKey.AccountId))
+					return model.ErrIdempotencyKeyReused
+				}
+				t.logger.InfoContext(ctx, "Replaying transaction for idempotency key",
+					logging.TransactionId(record.TransactionId), slog.String(logging.IdempotencyKeyKey,
+						idempotencyKey.Key))
+				res = model.TransactionCreatedOutput{Id: record.TransactionId, Replayed: true}
+				return nil
+			}
+			if !errors.Is(err, model.ErrNoMatchingIdempotencyKey) {
+				t.logger.ErrorContext(ctx, "Unable to get idempotency key",
+					slog.String(logging.IdempotencyKeyKey, idempotencyKey.Key), logging.Err(err))
+				return fmt.Errorf("error when getting idempotency key: %w", err)
+			}
+		}
+
+		if err := t.checkPayee(&input, txnCtx); err != nil {
+			return err
+		}
+
+		transactionId, err := t.transfer(&input, txnCtx)
+		if err != nil {
+			return err
+		}
+
+		if idempotencyKey != nil {
+			record := model.IdempotencyRecord{
+				Key:           idempotencyKey.Key,
+				AccountId:     idempotencyKey.AccountId,
+				RequestHash:   requestHash,
+				TransactionId: transactionId,
+				CreatedAt:     time.Now(),
+			}
+			if err = t.ir.AddIdempotencyRecord(&record, txnCtx); err != nil {
+				t.logger.WarnContext(ctx, "Unable to store idempotency key",
+					slog.String(logging.IdempotencyKeyKey, idempotencyKey.Key), logging.Err(err))
+				if errors.Is(err, model.ErrIdempotencyKeyInUse) {
+					return err
+				}
+				return fmt.Errorf("error when storing idempotency key: %w", err)
+			}
+		}
+		res = model.TransactionCreatedOutput{Id: transactionId}
+		return nil
+	})
 	if err != nil {
-		log.Printf("Error encountered when starting Add Transaction database transaction from "+
-			"BankAccount %s to BankAccount %s: %v", input.FromBankAccountId, input.ToBankAccountId, err)
-		return fmt.Errorf("error when starting Add Transaction database transaction: %w", err)
+		t.metrics.CountTransfer(transferOutcome(err))
+		t.logger.WarnContext(ctx, "Transfer failed", logging.FromBankAccountId(input.FromBankAccountId),
+			logging.ToBankAccountId(input.ToBankAccountId), logging.Err(err))
+		return model.TransactionCreatedOutput{}, err
+	}
+
+	if res.Replayed {
+		t.metrics.CountTransfer(metrics.TransferReplayed)
+	} else {
+		t.metrics.CountTransfer(metrics.TransferCommitted)
+		t.logger.InfoContext(ctx, "Committed transfer", logging.TransactionId(res.Id),
+			logging.FromBankAccountId(input.FromBankAccountId), logging.ToBankAccountId(input.ToBankAccountId))
 	}
 
-	var shouldRollback = true
+	return res, nil
+}
 
-	defer func() {
-		if shouldRollback {
-			if rollErr := t.tran.Rollback(txnCtx); rollErr != nil {
-				log.Printf("Error rolling back transaction: %v", rollErr)
-			}
-			return
+// transferOutcome tells apart the transfers that failed for lack of funds and the ones whose transaction failed to
+// commit from every other failure, which rolled the transaction back
+func transferOutcome(err error) string {
+	switch {
+	case errors.Is(err, model.ErrInsufficientBalance):
+		return metrics.TransferInsufficientBalance
+	case errors.Is(err, transactional.ErrCommitFailed):
+		return metrics.TransferCommitError
+	default:
+		return metrics.TransferRollback
+	}
+}
+
+// checkPayee enforces the unknown payee policy on the destination of the transfer
+func (t *TransactionServiceImpl) checkPayee(input *model.TransactionDetailsInput, ctx context.Context) error {
+	if t.payeePolicy == model.AllowUnknownPayees || t.payeePolicy == "" {
+		return nil
+	}
+	if t.payeePolicy == model.ConfirmUnknownPayees && input.ConfirmUnknownPayee {
+		return nil
+	}
+	senderDetails, err := t.ar.GetAccountDetailsFromBankAccountId(input.FromBankAccountId, ctx)
+	if err != nil {
+		t.logger.ErrorContext(ctx, "Unable to get account details of sender",
+			logging.BankAccountId(input.FromBankAccountId), logging.Err(err))
+		return fmt.Errorf("error when getting account details of sender: %w", err)
+	}
+	for _, bankAccount := range senderDetails.BankAccounts {
+		if bankAccount.Id == input.ToBankAccountId {
+			return nil
+		}
+	}
+	for _, payee := range senderDetails.KnownBankAccounts {
+		if payee.Id == input.ToBankAccountId {
+			return nil
+		}
+	}
+	t.logger.InfoContext(ctx, "Transfer to unknown payee held by policy",
+		logging.FromBankAccountId(input.FromBankAccountId), logging.ToBankAccountId(input.ToBankAccountId),
+		slog.String("policy", string(t.payeePolicy)))
+	if t.payeePolicy == model.ConfirmUnknownPayees {
+		return model.ErrUnknownPayeeNotConfirmed
+	}
+	return model.ErrUnknownPayee
+}
+
+func (t *TransactionServiceImpl) AddPendingTransaction(
+	input model.TransactionDetailsInput,
+	ctx context.Context,
+) (string, error) {
+	if !input.ExpirationDate.After(time.Now()) {
+		t.logger.InfoContext(ctx, "Rejecting pending transaction that has already expired",
+			logging.FromBankAccountId(input.FromBankAccountId), logging.ToBankAccountId(input.ToBankAccountId),
+			slog.Time("expiration_date", input.ExpirationDate))
+		return "", model.ErrInvalidExpirationDate
+	}
+	memo, err := validateMemo(input.Memo)
+	if err != nil {
+		return "", err
+	}
+	input.Memo = memo
+	input.Type = model.Pending
+	input.Status = model.Active
+
+	addCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	var transactionId string
+	err = t.tran.WithTransaction(addCtx, transferTxnOptions, func(txnCtx transactional.TransactionContext) error {
+		if err := t.checkPayee(&input, txnCtx); err != nil {
+			return err
+		}
+		var err error
+		transactionId, err = t.transfer(&input, txnCtx)
+		return err
+	})
+	if err != nil {
+		t.logger.WarnContext(ctx, "Pending transfer failed", logging.FromBankAccountId(input.FromBankAccountId),
+			logging.ToBankAccountId(input.ToBankAccountId), logging.Err(err))
+		return "", err
+	}
+
+	t.logger.InfoContext(ctx, "Committed pending transfer", logging.TransactionId(transactionId),
+		logging.FromBankAccountId(input.FromBankAccountId), logging.ToBankAccountId(input.ToBankAccountId))
+
+	return transactionId, nil
+}
+
+func (t *TransactionServiceImpl) ApplyPendingTransaction(transactionId string, ctx context.Context) error {
+	applyCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	err := t.tran.WithTransaction(applyCtx, transferTxnOptions, func(txnCtx transactional.TransactionContext) error {
+		pendingTransaction, err := t.closePendingTransaction(transactionId, model.Applied, txnCtx)
+		if err != nil {
+			return err
+		}
+
+		realizedInput := model.TransactionDetailsInput{
+			FromBankAccountId: pendingTransaction.FromBankAccountId,
+			ToBankAccountId:   pendingTransaction.ToBankAccountId,
+			Amount:            pendingTransaction.Amount,
+			Type:              model.Realized,
+			Memo:              pendingTransaction.Memo,
+			FromCategory:      manualCategory(pendingTransaction.FromCategory),
+			ToCategory:        manualCategory(pendingTransaction.ToCategory),
 		}
-	}()
+		_, err = t.transfer(&realizedInput, txnCtx)
+		return err
+	})
+	if err != nil {
+		t.logger.WarnContext(ctx, "Unable to apply pending transaction", logging.TransactionId(transactionId),
+			logging.Err(err))
+		return err
+	}
+
+	t.logger.InfoContext(ctx, "Applied pending transaction", logging.TransactionId(transactionId))
+
+	return nil
+}
+
+func (t *TransactionServiceImpl) RevokePendingTransaction(transactionId string, ctx context.Context) error {
+	revokeCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	err := t.tran.WithTransaction(revokeCtx, transferTxnOptions, func(txnCtx transactional.TransactionContext) error {
+		_, err := t.closePendingTransaction(transactionId, model.Revoked, txnCtx)
+		return err
+	})
+	if err != nil {
+		t.logger.WarnContext(ctx, "Unable to revoke pending transaction", logging.TransactionId(transactionId),
+			logging.Err(err))
+		return err
+	}
 
+	t.logger.InfoContext(ctx, "Revoked pending transaction", logging.TransactionId(transactionId))
+
+	return nil
+}
+
+func (t *TransactionServiceImpl) GetTransactionDetails(
+	transactionId string,
+	ctx context.Context,
+) (*model.TransactionDetailsOutput, error) {
+	getCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	transaction, err := t.tr.GetTransactionFromId(transactionId, getCtx)
+	if err != nil {
+		t.logger.WarnContext(ctx, "Unable to get transaction", logging.TransactionId(transactionId), logging.Err(err))
+		return nil, fmt.Errorf("error when getting transaction %s: %w", transactionId, err)
+	}
+	return transaction, nil
+}
+
+func (t *TransactionServiceImpl) RevokeExpiredPendingTransactions(
+	expiredBy time.Time,
+	ctx context.Context,
+) (int, error) {
+	getCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	expiredTransactions, err := t.tr.GetExpiredPendingTransactions(expiredBy, getCtx)
+	if err != nil {
+		t.logger.ErrorContext(ctx, "Unable to get expired pending transactions", slog.Time("expired_by", expiredBy),
+			logging.Err(err))
+		return 0, fmt.Errorf("error when getting expired pending transactions: %w", err)
+	}
+
+	revoked := 0
+	for _, expiredTransaction := range expiredTransactions {
+		err = t.RevokePendingTransaction(expiredTransaction.Id, ctx)
+		if errors.Is(err, model.ErrPendingTransactionNotActive) {
+			// The transaction was applied or revoked after it was listed
+			continue
+		}
+		if err != nil {
+			t.logger.ErrorContext(ctx, "Unable to revoke expired pending transaction",
+				logging.TransactionId(expiredTransaction.Id), logging.Err(err))
+			continue
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// DeleteExpiredIdempotencyKeys deletes the idempotency keys older than IdempotencyKeyTTL, which requests already
+// treat as never used, so that they do not pile up on backends without TTL indexes
+func (t *TransactionServiceImpl) DeleteExpiredIdempotencyKeys(now time.Time, ctx context.Context) (int, error) {
+	deleteCtx, cancel := withOperationDeadline(ctx)
+	defer cancel()
+
+	deleted, err := t.ir.DeleteExpiredIdempotencyRecords(now, deleteCtx)
+	if err != nil {
+		t.logger.ErrorContext(ctx, "Unable to delete expired idempotency keys", logging.Err(err))
+		return 0, fmt.Errorf("error when deleting expired idempotency keys: %w", err)
+	}
+	return deleted, nil
+}
+
+// transfer moves the amount between the two bank accounts and records the transaction along with its journal entry,
+// all within the given database transaction context. Pending transactions only affect the pending balances of either
+// bank account. Either side of the transaction that is not given a category is categorized by the rules of the
+// holder of its bank account.
+func (t *TransactionServiceImpl) transfer(
+	input *model.TransactionDetailsInput,
+	txnCtx context.Context,
+) (string, error) {
 	toPending := input.Type == model.Pending
 	newBalance, pendingBalance, err := t.ar.DeductBalance(input.FromBankAccountId, input.Amount, toPending, txnCtx)
 	if err != nil {
-		log.Printf("Error deducting balance from BankAccount %s: %v", input.FromBankAccountId, err)
-		return fmt.Errorf("error when deducting balance from BankAccount %s: %w", input.FromBankAccountId, err)
+		t.logger.ErrorContext(txnCtx, "Unable to deduct balance", logging.BankAccountId(input.FromBankAccountId),
+			logging.Err(err))
+		return "", fmt.Errorf("error when deducting balance from BankAccount %s: %w", input.FromBankAccountId, err)
 	}
 
 	if newBalance.IsNegative() || pendingBalance.IsNegative() {
-		log.Printf("Insufficient balance in BankAccount %s", input.FromBankAccountId)
-		return fmt.Errorf("insufficient balance in BankAccount %s", input.FromBankAccountId)
+		t.logger.InfoContext(txnCtx, "Insufficient balance", logging.BankAccountId(input.FromBankAccountId))
+		return "", fmt.Errorf("%w in BankAccount %s", model.ErrInsufficientBalance, input.FromBankAccountId)
 	}
 
-	log.Printf("Successfully deducted balance from BankAccount %s", input.FromBankAccountId)
+	t.logger.DebugContext(txnCtx, "Deducted balance", logging.BankAccountId(input.FromBankAccountId))
 
 	if err = t.ar.AddBalance(input.ToBankAccountId, input.Amount, toPending, txnCtx); err != nil {
-		log.Printf("Error adding balance to BankAccount %s: %v", input.ToBankAccountId, err)
-		return fmt.Errorf("error when adding balance to BankAccount %s: %w", input.ToBankAccountId, err)
+		t.logger.ErrorContext(txnCtx, "Unable to add balance", logging.BankAccountId(input.ToBankAccountId),
+			logging.Err(err))
+		return "", fmt.Errorf("error when adding balance to BankAccount %s: %w", input.ToBankAccountId, err)
 	}
 
-	log.Printf("Successfully added balance to BankAccount %s", input.ToBankAccountId)
+	t.logger.DebugContext(txnCtx, "Added balance", logging.BankAccountId(input.ToBankAccountId))
 
-	if err = t.tr.AddTransaction(&input, txnCtx); err != nil {
-		log.Printf("Error adding transaction to the database from BankAccount %s to "+
-			"BankAccount %s: %v", input.FromBankAccountId, input.ToBankAccountId, err)
-		return fmt.Errorf("error when adding transaction to the database: %w", err)
+	if err = t.categorize(input, txnCtx); err != nil {
+		return "", err
 	}
 
-	if commitErr := t.tran.Commit(txnCtx); commitErr != nil {
-		log.Printf("Error committing Add Transaction database transaction: %v", commitErr)
-		return fmt.Errorf("error when committing Add Transaction database transaction: %w", commitErr)
+	transactionId, err := t.tr.AddTransaction(input, txnCtx)
+	if err != nil {
+		t.logger.ErrorContext(txnCtx, "Unable to add transaction", logging.FromBankAccountId(input.FromBankAccountId),
+			logging.ToBankAccountId(input.ToBankAccountId), logging.Err(err))
+		return "", fmt.Errorf("error when adding transaction to the database: %w", err)
 	}
 
-	log.Printf(
-		"Successfully committed Add Transaction database transaction from "+
-			"BankAccount %s to BankAccount %s", input.FromBankAccountId, input.ToBankAccountId,
-	)
+	if err = t.postJournalEntry(transferJournalEntry(transactionId, input), txnCtx); err != nil {
+		return "", err
+	}
+	return transactionId, nil
+}
 
-	shouldRollback = false
+// categorize gives the sides of the transfer that have no category the one the rules of their account holder give
+// them
+func (t *TransactionServiceImpl) categorize(input *model.TransactionDetailsInput, txnCtx context.Context) error {
+	fromSide, toSide := transferSides(input)
+	if input.FromCategory.Name == "" {
+		category, err := ruleCategory(t.ar, t.cr, input.FromBankAccountId, fromSide, txnCtx)
+		if err != nil {
+			t.logger.ErrorContext(txnCtx, "Unable to categorize transaction",
+				logging.BankAccountId(input.FromBankAccountId), logging.Err(err))
+			return fmt.Errorf("error when categorizing transaction: %w", err)
+		}
+		input.FromCategory = category
+	}
+	if input.ToCategory.Name == "" {
+		category, err := ruleCategory(t.ar, t.cr, input.ToBankAccountId, toSide, txnCtx)
+		if err != nil {
+			t.logger.ErrorContext(txnCtx, "Unable to categorize transaction",
+				logging.BankAccountId(input.ToBankAccountId), logging.Err(err))
+			return fmt.Errorf("error when categorizing transaction: %w", err)
+		}
+		input.ToCategory = category
+	}
 	return nil
 }
+
+// manualCategory keeps the category only when the account holder set it, so that rules categorize the transaction
+// again
+func manualCategory(category model.TransactionCategory) model.TransactionCategory {
+	if category.Source != model.ManualCategory {
+		return model.TransactionCategory{}
+	}
+	return category
+}
+
+// closePendingTransaction moves an active pending transaction to the given status and releases its amount from
+// the pending balances of both bank accounts, all within the given database transaction context. The release is
+// journaled as the reverse of the hold, so that a subsequently applied transaction is journaled as a new transfer.
+func (t *TransactionServiceImpl) closePendingTransaction(
+	transactionId string,
+	status model.PendingTransactionStatus,
+	txnCtx context.Context,
+) (*model.TransactionDetailsOutput, error) {
+	pendingTransaction, err := t.tr.GetTransactionFromId(transactionId, txnCtx)
+	if err != nil {
+		t.logger.WarnContext(txnCtx, "Unable to get pending transaction", logging.TransactionId(transactionId),
+			logging.Err(err))
+		return nil, fmt.Errorf("error when getting pending transaction %s: %w", transactionId, err)
+	}
+	if pendingTransaction.Type != model.Pending || pendingTransaction.Status != model.Active {
+		t.logger.InfoContext(txnCtx, "Transaction is not an active pending transaction",
+			logging.TransactionId(transactionId))
+		return nil, model.ErrPendingTransactionNotActive
+	}
+
+	if err = t.tr.UpdatePendingTransactionStatus(transactionId, status, txnCtx); err != nil {
+		t.logger.ErrorContext(txnCtx, "Unable to set status of pending transaction",
+			logging.TransactionId(transactionId), slog.String("status", string(status)), logging.Err(err))
+		return nil, fmt.Errorf("error when setting status of pending transaction %s: %w", transactionId, err)
+	}
+
+	err = t.ar.AddBalance(pendingTransaction.FromBankAccountId, pendingTransaction.Amount, true, txnCtx)
+	if err != nil {
+		t.logger.ErrorContext(txnCtx, "Unable to restore pending balance",
+			logging.BankAccountId(pendingTransaction.FromBankAccountId), logging.Err(err))
+		return nil, fmt.Errorf("error when restoring pending balance to BankAccount %s: %w",
+			pendingTransaction.FromBankAccountId, err)
+	}
+
+	_, _, err = t.ar.DeductBalance(pendingTransaction.ToBankAccountId, pendingTransaction.Amount, true, txnCtx)
+	if err != nil {
+		t.logger.ErrorContext(txnCtx, "Unable to release pending balance",
+			logging.BankAccountId(pendingTransaction.ToBankAccountId), logging.Err(err))
+		return nil, fmt.Errorf("error when releasing pending balance from BankAccount %s: %w",
+			pendingTransaction.ToBankAccountId, err)
+	}
+
+	if err = t.postJournalEntry(pendingReleaseJournalEntry(pendingTransaction), txnCtx); err != nil {
+		return nil, err
+	}
+	return pendingTransaction, nil
+}
+
+// postJournalEntry appends the entry to the journal, refusing entries whose postings do not sum to zero
+func (t *TransactionServiceImpl) postJournalEntry(entry *model.JournalEntryInput, txnCtx context.Context) error {
+	if !isBalanced(entry) {
+		t.logger.ErrorContext(txnCtx, "Refusing unbalanced journal entry", logging.TransactionId(entry.TransactionId),
+			slog.String("kind", string(entry.Kind)))
+		return fmt.Errorf("error when posting %s journal entry for transaction %s: %w", entry.Kind,
+			entry.TransactionId, model.ErrUnbalancedJournalEntry)
+	}
+	if _, err := t.jr.AddJournalEntry(entry, txnCtx); err != nil {
+		t.logger.ErrorContext(txnCtx, "Unable to post journal entry", logging.TransactionId(entry.TransactionId),
+			slog.String("kind", string(entry.Kind)), logging.Err(err))
+		return fmt.Errorf("error when posting journal entry for transaction %s: %w", entry.TransactionId, err)
+	}
+	return nil
+}
+
+// hashTransactionRequest fingerprints the parts of the request that determine its effect, so that an idempotency
+// key reused for a different transfer can be told apart from a retry. The memo is only part of the fingerprint when
+// it is set, which keeps the keys stored before transfers had memos valid.
+func hashTransactionRequest(input *model.TransactionDetailsInput) string {
+	fingerprint := fmt.Sprintf("%s|%s|%s|%s",
+		input.FromBankAccountId, input.ToBankAccountId, input.Amount.String(), input.Type)
+	if input.Memo != "" {
+		fingerprint += "|" + input.Memo
+	}
+	hash := sha256.Sum256([]byte(fingerprint))
+	return hex.EncodeToString(hash[:])
+}
@@ -3,12 +3,18 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"strings"
 	"testing"
 	"time"
 	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
 	"webserver/test/mocks"
 )
 
@@ -24,14 +30,14 @@ func TestAddTransaction(t *testing.T) {
 		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
 		defer cancel()
 		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
-		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return(nil)
-		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything).
-			Return(nil)
-		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything).
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("transactionId", nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 			Return(nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
 		mockTran.On("Commit", mock.Anything).Return(nil)
 
-		err := service.AddTransaction(input, ctx)
+		_, err := service.AddTransaction(input, nil, ctx)
 		assert.Nil(t, err)
 	})
 
@@ -41,20 +47,24 @@ func TestAddTransaction(t *testing.T) {
 		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).
 			Return(ctx, errors.New("can't start transaction"))
 
-		err := service.AddTransaction(input, ctx)
+		_, err := service.AddTransaction(input, nil, ctx)
 		assert.ErrorContains(t, err, "can't start transaction")
 		mockTran.AssertNumberOfCalls(t, "Rollback", 0)
 		mockTranRepo.AssertNumberOfCalls(t, "AddTransaction", 0)
 	})
 
 	t.Run("Returns error if repository Add Transaction is unsuccessful", func(t *testing.T) {
-		mockTranRepo, _, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
 		defer cancel()
 		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
-		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return(assert.AnError)
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("", assert.AnError)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
 		mockTran.On("Rollback", mock.Anything).Return(nil)
 
-		err := service.AddTransaction(input, ctx)
+		_, err := service.AddTransaction(input, nil, ctx)
 		assert.NotNil(t, err)
 	})
 
@@ -62,12 +72,14 @@ func TestAddTransaction(t *testing.T) {
 		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
 		defer cancel()
 		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
-		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return(nil)
-		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything).
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("transactionId", nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 			Return(assert.AnError)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
 		mockTran.On("Rollback", mock.Anything).Return(nil)
 
-		err := service.AddTransaction(input, ctx)
+		_, err := service.AddTransaction(input, nil, ctx)
 		assert.NotNil(t, err)
 	})
 
@@ -75,14 +87,14 @@ func TestAddTransaction(t *testing.T) {
 		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
 		defer cancel()
 		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
-		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return(nil)
-		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything).
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("transactionId", nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 			Return(nil)
-		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything).
-			Return(assert.AnError)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, assert.AnError)
 		mockTran.On("Rollback", mock.Anything).Return(nil)
 
-		err := service.AddTransaction(input, ctx)
+		_, err := service.AddTransaction(input, nil, ctx)
 		assert.NotNil(t, err)
 	})
 
@@ -90,26 +102,30 @@ func TestAddTransaction(t *testing.T) {
 		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
 		defer cancel()
 		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
-		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return(nil)
-		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything).
-			Return(nil)
-		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything).
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("transactionId", nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 			Return(nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
 		mockTran.On("Commit", mock.Anything).Return(nil)
 
-		service.AddTransaction(input, ctx)
+		service.AddTransaction(input, nil, ctx)
 		mockTran.AssertNumberOfCalls(t, "Commit", 1)
 		mockTran.AssertNumberOfCalls(t, "Rollback", 0)
 	})
 
 	t.Run("Rollback if errors are encountered", func(t *testing.T) {
-		mockTranRepo, _, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
 		defer cancel()
 		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
-		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return(assert.AnError)
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("", assert.AnError)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
 		mockTran.On("Rollback", mock.Anything).Return(nil)
 
-		service.AddTransaction(input, ctx)
+		service.AddTransaction(input, nil, ctx)
 		mockTran.AssertNumberOfCalls(t, "Commit", 0)
 		mockTran.AssertNumberOfCalls(t, "Rollback", 1)
 	})
@@ -118,14 +134,14 @@ func TestAddTransaction(t *testing.T) {
 		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
 		defer cancel()
 		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
-		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return(nil)
-		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything).
-			Return(nil)
-		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything).
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("transactionId", nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 			Return(nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
 		mockTran.On("Commit", mock.Anything).Return(errors.New("commit error"))
 
-		err := service.AddTransaction(input, ctx)
+		_, err := service.AddTransaction(input, nil, ctx)
 		assert.Error(t, err)
 		assert.ErrorContains(t, err, "commit error")
 		mockTran.AssertNumberOfCalls(t, "Commit", 1)
@@ -133,9 +149,708 @@ func TestAddTransaction(t *testing.T) {
 	})
 }
 
-func initializeTransactionMocks() (
+func TestAddTransactionOutcomeMetrics(t *testing.T) {
+	input := model.TransactionDetailsInput{
+		ToBankAccountId:   "toAccountID",
+		FromBankAccountId: "fromAccountID",
+		Amount:            decimal.RequireFromString("100.00"),
+	}
+	assertTransfersCounted := func(t *testing.T, service *TransactionServiceImpl, outcome string) {
+		expected := fmt.Sprintf(`# HELP wallet_transfers_total Transfers attempted, by outcome.
+# TYPE wallet_transfers_total counter
+wallet_transfers_total{outcome="%s"} 1
+`, outcome)
+		err := testutil.GatherAndCompare(service.metrics.Registry(), strings.NewReader(expected),
+			"wallet_transfers_total")
+		assert.Nil(t, err)
+	}
+
+	t.Run("Counts committed transfers", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("transactionId", nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockTran.On("Commit", mock.Anything).Return(nil)
+
+		_, err := service.AddTransaction(input, nil, ctx)
+		assert.Nil(t, err)
+		assertTransfersCounted(t, service, metrics.TransferCommitted)
+	})
+
+	t.Run("Counts transfers refused for insufficient balance", func(t *testing.T) {
+		_, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.NewFromInt(-1), decimal.Zero, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+
+		_, err := service.AddTransaction(input, nil, ctx)
+		assert.ErrorIs(t, err, model.ErrInsufficientBalance)
+		assertTransfersCounted(t, service, metrics.TransferInsufficientBalance)
+	})
+
+	t.Run("Counts transfers rolled back on other errors", func(t *testing.T) {
+		_, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, assert.AnError)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+
+		_, err := service.AddTransaction(input, nil, ctx)
+		assert.ErrorIs(t, err, assert.AnError)
+		assertTransfersCounted(t, service, metrics.TransferRollback)
+	})
+
+	t.Run("Counts transfers that failed to commit", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("transactionId", nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockTran.On("Commit", mock.Anything).Return(assert.AnError)
+
+		_, err := service.AddTransaction(input, nil, ctx)
+		assert.ErrorIs(t, err, transactional.ErrCommitFailed)
+		assertTransfersCounted(t, service, metrics.TransferCommitError)
+	})
+}
+
+func TestAddTransactionWithIdempotencyKey(t *testing.T) {
+	testAmt, _ := decimal.NewFromString("100.00")
+	input := model.TransactionDetailsInput{
+		ToBankAccountId:   "toAccountID",
+		FromBankAccountId: "fromAccountID",
+		Amount:            testAmt,
+		Type:              model.Realized,
+	}
+	key := &model.IdempotencyKeyInput{Key: "key", AccountId: "accountId"}
+
+	t.Run("Stores the key along with the created transaction", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockIdemRepo, mockTran, service, ctx, cancel :=
+			initializeIdempotentTransactionMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockIdemRepo.On("GetIdempotencyRecord", key, mock.Anything).Return(nil, model.ErrNoMatchingIdempotencyKey)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("transactionId", nil)
+		mockIdemRepo.On("AddIdempotencyRecord", mock.MatchedBy(func(record *model.IdempotencyRecord) bool {
+			return record.Key == "key" && record.AccountId == "accountId" && record.TransactionId == "transactionId"
+		}), mock.Anything).Return(nil)
+		mockTran.On("Commit", mock.Anything).Return(nil)
+
+		res, err := service.AddTransaction(input, key, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, model.TransactionCreatedOutput{Id: "transactionId"}, res)
+		mockIdemRepo.AssertExpectations(t)
+	})
+
+	t.Run("Replays the original transaction for a retried request", func(t *testing.T) {
+		_, mockAccRepo, mockIdemRepo, mockTran, service, ctx, cancel := initializeIdempotentTransactionMocks()
+		defer cancel()
+		record := &model.IdempotencyRecord{
+			Key: "key", AccountId: "accountId", RequestHash: hashTransactionRequest(&input), TransactionId: "original",
+		}
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockIdemRepo.On("GetIdempotencyRecord", key, mock.Anything).Return(record, nil)
+		mockTran.On("Commit", mock.Anything).Return(nil)
+
+		retried := input
+		retried.Amount, _ = decimal.NewFromString("100")
+		res, err := service.AddTransaction(retried, key, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, model.TransactionCreatedOutput{Id: "original", Replayed: true}, res)
+		mockAccRepo.AssertNotCalled(t, "DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockIdemRepo.AssertNotCalled(t, "AddIdempotencyRecord", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects a key reused for a different request", func(t *testing.T) {
+		_, mockAccRepo, mockIdemRepo, mockTran, service, ctx, cancel := initializeIdempotentTransactionMocks()
+		defer cancel()
+		record := &model.IdempotencyRecord{
+			Key: "key", AccountId: "accountId", RequestHash: hashTransactionRequest(&input), TransactionId: "original",
+		}
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockIdemRepo.On("GetIdempotencyRecord", key, mock.Anything).Return(record, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+
+		different := input
+		different.Amount, _ = decimal.NewFromString("200.00")
+		_, err := service.AddTransaction(different, key, ctx)
+		assert.ErrorIs(t, err, model.ErrIdempotencyKeyReused)
+		mockAccRepo.AssertNotCalled(t, "DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rolls back the transfer if the key is stored by a concurrent request", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockIdemRepo, mockTran, service, ctx, cancel :=
+			initializeIdempotentTransactionMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockIdemRepo.On("GetIdempotencyRecord", key, mock.Anything).Return(nil, model.ErrNoMatchingIdempotencyKey)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("transactionId", nil)
+		mockIdemRepo.On("AddIdempotencyRecord", mock.Anything, mock.Anything).Return(model.ErrIdempotencyKeyInUse)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+
+		_, err := service.AddTransaction(input, key, ctx)
+		assert.ErrorIs(t, err, model.ErrIdempotencyKeyInUse)
+		mockTran.AssertCalled(t, "Rollback", mock.Anything)
+		mockTran.AssertNotCalled(t, "Commit", mock.Anything)
+	})
+}
+
+func TestAddTransactionUnknownPayeePolicy(t *testing.T) {
+	testAmt, _ := decimal.NewFromString("100.00")
+	senderDetails := &model.AccountDetailsOutput{
+		Id:                "senderId",
+		BankAccounts:      []model.BankAccount{{Id: "fromAccountID"}, {Id: "ownAccountID"}},
+		KnownBankAccounts: []model.KnownBankAccount{{Id: "payeeAccountID"}},
+	}
+	inputTo := func(toBankAccountId string) model.TransactionDetailsInput {
+		return model.TransactionDetailsInput{
+			ToBankAccountId:   toBankAccountId,
+			FromBankAccountId: "fromAccountID",
+			Amount:            testAmt,
+			Type:              model.Realized,
+		}
+	}
+
+	t.Run("Transfers to own bank accounts and known payees", func(t *testing.T) {
+		for _, toBankAccountId := range []string{"ownAccountID", "payeeAccountID"} {
+			mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+			service.payeePolicy = model.RejectUnknownPayees
+			mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+			mockAccRepo.On("GetAccountDetailsFromBankAccountId", "fromAccountID", mock.Anything).
+				Return(senderDetails, nil)
+			mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(decimal.Zero, decimal.Zero, nil)
+			mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("transactionId", nil)
+			mockTran.On("Commit", mock.Anything).Return(nil)
+
+			_, err := service.AddTransaction(inputTo(toBankAccountId), nil, ctx)
+			assert.Nil(t, err)
+			cancel()
+		}
+	})
+
+	t.Run("Rejects transfers to unknown payees", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		service.payeePolicy = model.RejectUnknownPayees
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockAccRepo.On("GetAccountDetailsFromBankAccountId", "fromAccountID", mock.Anything).Return(senderDetails, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+
+		input := inputTo("strangerAccountID")
+		input.ConfirmUnknownPayee = true
+		_, err := service.AddTransaction(input, nil, ctx)
+		assert.ErrorIs(t, err, model.ErrUnknownPayee)
+		mockTranRepo.AssertNotCalled(t, "AddTransaction", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Requires confirmation of transfers to unknown payees", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		service.payeePolicy = model.ConfirmUnknownPayees
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockAccRepo.On("GetAccountDetailsFromBankAccountId", "fromAccountID", mock.Anything).Return(senderDetails, nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("transactionId", nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+		mockTran.On("Commit", mock.Anything).Return(nil)
+
+		input := inputTo("strangerAccountID")
+		_, err := service.AddTransaction(input, nil, ctx)
+		assert.ErrorIs(t, err, model.ErrUnknownPayeeNotConfirmed)
+		mockTranRepo.AssertNotCalled(t, "AddTransaction", mock.Anything, mock.Anything)
+
+		input.ConfirmUnknownPayee = true
+		_, err = service.AddTransaction(input, nil, ctx)
+		assert.Nil(t, err)
+		mockTranRepo.AssertNumberOfCalls(t, "AddTransaction", 1)
+	})
+	t.Run("Rejects pending transfers to unknown payees", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		service.payeePolicy = model.RejectUnknownPayees
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockAccRepo.On("GetAccountDetailsFromBankAccountId", "fromAccountID", mock.Anything).Return(senderDetails, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+
+		input := inputTo("strangerAccountID")
+		input.ExpirationDate = time.Now().Add(time.Hour)
+		_, err := service.AddPendingTransaction(input, ctx)
+		assert.ErrorIs(t, err, model.ErrUnknownPayee)
+		mockAccRepo.AssertNotCalled(t, "DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockTranRepo.AssertNotCalled(t, "AddTransaction", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAddPendingTransaction(t *testing.T) {
+	testAmt, _ := decimal.NewFromString("100.00")
+	input := model.TransactionDetailsInput{
+		ToBankAccountId:   "toAccountID",
+		FromBankAccountId: "fromAccountID",
+		Amount:            testAmt,
+		ExpirationDate:    time.Now().Add(time.Hour),
+	}
+
+	t.Run("Returns the ID of the created active pending transaction", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockAccRepo.On("DeductBalance", "fromAccountID", testAmt, true, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockAccRepo.On("AddBalance", "toAccountID", testAmt, true, mock.Anything).Return(nil)
+		mockTranRepo.On("AddTransaction", mock.MatchedBy(func(details *model.TransactionDetailsInput) bool {
+			return details.Type == model.Pending && details.Status == model.Active
+		}), mock.Anything).Return("transactionId", nil)
+		mockTran.On("Commit", mock.Anything).Return(nil)
+
+		transactionId, err := service.AddPendingTransaction(input, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "transactionId", transactionId)
+		mockTran.AssertNumberOfCalls(t, "Commit", 1)
+	})
+
+	t.Run("Returns error without starting a transaction if the expiration date has passed", func(t *testing.T) {
+		_, _, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		expiredInput := input
+		expiredInput.ExpirationDate = time.Now().Add(-time.Hour)
+
+		_, err := service.AddPendingTransaction(expiredInput, ctx)
+		assert.ErrorIs(t, err, model.ErrInvalidExpirationDate)
+		mockTran.AssertNumberOfCalls(t, "BeginTransaction", 0)
+	})
+
+	t.Run("Returns error and rolls back if the pending balance is insufficient", func(t *testing.T) {
+		_, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.NewFromInt(-1), nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+
+		_, err := service.AddPendingTransaction(input, ctx)
+		assert.ErrorContains(t, err, "insufficient balance")
+		mockTran.AssertNumberOfCalls(t, "Rollback", 1)
+	})
+}
+
+func TestApplyPendingTransaction(t *testing.T) {
+	testAmt, _ := decimal.NewFromString("100.00")
+	pendingTransaction := &model.TransactionDetailsOutput{
+		Id:                "transactionId",
+		FromBankAccountId: "fromAccountID",
+		ToBankAccountId:   "toAccountID",
+		Amount:            testAmt,
+		Type:              model.Pending,
+		Status:            model.Active,
+	}
+
+	t.Run("Releases the pending amount and posts a realized transfer", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTranRepo.On("GetTransactionFromId", "transactionId", mock.Anything).Return(pendingTransaction, nil)
+		mockTranRepo.On("UpdatePendingTransactionStatus", "transactionId", model.Applied, mock.Anything).
+			Return(nil)
+		mockAccRepo.On("AddBalance", "fromAccountID", testAmt, true, mock.Anything).Return(nil)
+		mockAccRepo.On("DeductBalance", "toAccountID", testAmt, true, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockAccRepo.On("DeductBalance", "fromAccountID", testAmt, false, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockAccRepo.On("AddBalance", "toAccountID", testAmt, false, mock.Anything).Return(nil)
+		mockTranRepo.On("AddTransaction", mock.MatchedBy(func(details *model.TransactionDetailsInput) bool {
+			return details.Type == model.Realized
+		}), mock.Anything).Return("realizedTransactionId", nil)
+		mockTran.On("Commit", mock.Anything).Return(nil)
+
+		err := service.ApplyPendingTransaction("transactionId", ctx)
+		assert.Nil(t, err)
+		mockTranRepo.AssertNumberOfCalls(t, "AddTransaction", 1)
+		mockTran.AssertNumberOfCalls(t, "Commit", 1)
+	})
+
+	t.Run("Returns error and rolls back if the transaction is no longer active", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		appliedTransaction := *pendingTransaction
+		appliedTransaction.Status = model.Applied
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTranRepo.On("GetTransactionFromId", "transactionId", mock.Anything).Return(&appliedTransaction, nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+
+		err := service.ApplyPendingTransaction("transactionId", ctx)
+		assert.ErrorIs(t, err, model.ErrPendingTransactionNotActive)
+		mockAccRepo.AssertNumberOfCalls(t, "AddBalance", 0)
+		mockTran.AssertNumberOfCalls(t, "Rollback", 1)
+	})
+
+	t.Run("Returns error if the transaction does not exist", func(t *testing.T) {
+		mockTranRepo, _, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTranRepo.On("GetTransactionFromId", "transactionId", mock.Anything).
+			Return(nil, model.ErrNoMatchingTransaction)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+
+		err := service.ApplyPendingTransaction("transactionId", ctx)
+		assert.ErrorIs(t, err, model.ErrNoMatchingTransaction)
+	})
+}
+
+func TestRevokePendingTransaction(t *testing.T) {
+	testAmt, _ := decimal.NewFromString("100.00")
+	pendingTransaction := &model.TransactionDetailsOutput{
+		Id:                "transactionId",
+		FromBankAccountId: "fromAccountID",
+		ToBankAccountId:   "toAccountID",
+		Amount:            testAmt,
+		Type:              model.Pending,
+		Status:            model.Active,
+	}
+
+	t.Run("Restores the pending balances without posting a transfer", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTranRepo.On("GetTransactionFromId", "transactionId", mock.Anything).Return(pendingTransaction, nil)
+		mockTranRepo.On("UpdatePendingTransactionStatus", "transactionId", model.Revoked, mock.Anything).
+			Return(nil)
+		mockAccRepo.On("AddBalance", "fromAccountID", testAmt, true, mock.Anything).Return(nil)
+		mockAccRepo.On("DeductBalance", "toAccountID", testAmt, true, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockTran.On("Commit", mock.Anything).Return(nil)
+
+		err := service.RevokePendingTransaction("transactionId", ctx)
+		assert.Nil(t, err)
+		mockTranRepo.AssertNumberOfCalls(t, "AddTransaction", 0)
+		mockTran.AssertNumberOfCalls(t, "Commit", 1)
+	})
+
+	t.Run("Returns error and rolls back if the status update loses a race", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTranRepo.On("GetTransactionFromId", "transactionId", mock.Anything).Return(pendingTransaction, nil)
+		mockTranRepo.On("UpdatePendingTransactionStatus", "transactionId", model.Revoked, mock.Anything).
+			Return(model.ErrPendingTransactionNotActive)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+
+		err := service.RevokePendingTransaction("transactionId", ctx)
+		assert.ErrorIs(t, err, model.ErrPendingTransactionNotActive)
+		mockAccRepo.AssertNumberOfCalls(t, "AddBalance", 0)
+		mockTran.AssertNumberOfCalls(t, "Rollback", 1)
+	})
+}
+
+func TestTransferJournalEntries(t *testing.T) {
+	testAmt, _ := decimal.NewFromString("100.00")
+	input := model.TransactionDetailsInput{
+		ToBankAccountId:   "toAccountID",
+		FromBankAccountId: "fromAccountID",
+		Amount:            testAmt,
+		Type:              model.Realized,
+	}
+
+	t.Run("Posts a balanced transfer entry on both balances of either bank account", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockJournalRepo := &mocks.MockJournalRepository{}
+		service.jr = mockJournalRepo
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("transactionId", nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockJournalRepo.On("AddJournalEntry", mock.MatchedBy(func(entry *model.JournalEntryInput) bool {
+			return entry.TransactionId == "transactionId" && entry.Kind == model.TransferEntry &&
+				len(entry.Postings) == 4 && isBalanced(entry)
+		}), mock.Anything).Return("journalEntryId", nil).Once()
+		mockTran.On("Commit", mock.Anything).Return(nil)
+
+		_, err := service.AddTransaction(input, nil, ctx)
+		assert.Nil(t, err)
+		mockJournalRepo.AssertExpectations(t)
+	})
+
+	t.Run("Returns error and rolls back if the journal entry cannot be posted", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockJournalRepo := &mocks.MockJournalRepository{}
+		service.jr = mockJournalRepo
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTranRepo.On("AddTransaction", mock.Anything, mock.Anything).Return("transactionId", nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockJournalRepo.On("AddJournalEntry", mock.Anything, mock.Anything).Return("", assert.AnError)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+
+		_, err := service.AddTransaction(input, nil, ctx)
+		assert.ErrorIs(t, err, assert.AnError)
+		mockTran.AssertNumberOfCalls(t, "Rollback", 1)
+		mockTran.AssertNumberOfCalls(t, "Commit", 0)
+	})
+
+	t.Run("Posts a hold on the pending balances only for pending transactions", func(t *testing.T) {
+		entry := transferJournalEntry("transactionId", &model.TransactionDetailsInput{
+			FromBankAccountId: "fromAccountID",
+			ToBankAccountId:   "toAccountID",
+			Amount:            testAmt,
+			Type:              model.Pending,
+		})
+		assert.Equal(t, model.PendingHoldEntry, entry.Kind)
+		assert.Len(t, entry.Postings, 2)
+		for _, posting := range entry.Postings {
+			assert.Equal(t, model.PendingBalance, posting.Balance)
+		}
+		assert.True(t, isBalanced(entry))
+	})
+
+	t.Run("Releases the hold with the reverse postings", func(t *testing.T) {
+		hold := transferJournalEntry("transactionId", &model.TransactionDetailsInput{
+			FromBankAccountId: "fromAccountID",
+			ToBankAccountId:   "toAccountID",
+			Amount:            testAmt,
+			Type:              model.Pending,
+		})
+		release := pendingReleaseJournalEntry(&model.TransactionDetailsOutput{
+			Id:                "transactionId",
+			FromBankAccountId: "fromAccountID",
+			ToBankAccountId:   "toAccountID",
+			Amount:            testAmt,
+			Type:              model.Pending,
+		})
+		assert.Equal(t, model.PendingReleaseEntry, release.Kind)
+		assert.True(t, isBalanced(release))
+		net := make(map[string]decimal.Decimal)
+		for _, posting := range append(hold.Postings, release.Postings...) {
+			net[posting.BankAccountId] = net[posting.BankAccountId].Add(posting.Amount)
+		}
+		for bankAccountId, amount := range net {
+			assert.True(t, amount.IsZero(), "net pending postings of %s should be zero", bankAccountId)
+		}
+	})
+
+	t.Run("Refuses to post an unbalanced entry", func(t *testing.T) {
+		_, _, _, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockJournalRepo := &mocks.MockJournalRepository{}
+		service.jr = mockJournalRepo
+		entry := &model.JournalEntryInput{
+			TransactionId: "transactionId",
+			Kind:          model.TransferEntry,
+			Postings: []model.Posting{
+				{BankAccountId: "fromAccountID", Balance: model.AvailableBalance, Amount: testAmt.Neg()},
+				{BankAccountId: "toAccountID", Balance: model.PendingBalance, Amount: testAmt},
+			},
+		}
+
+		err := service.postJournalEntry(entry, ctx)
+		assert.ErrorIs(t, err, model.ErrUnbalancedJournalEntry)
+		mockJournalRepo.AssertNotCalled(t, "AddJournalEntry", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRevokeExpiredPendingTransactions(t *testing.T) {
+	testAmt, _ := decimal.NewFromString("100.00")
+	expiredBy := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Revokes every expired transaction that is still active", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		expiredTransactions := []model.TransactionDetailsOutput{
+			{Id: "first", FromBankAccountId: "from", ToBankAccountId: "to", Amount: testAmt,
+				Type: model.Pending, Status: model.Active},
+			{Id: "second", FromBankAccountId: "from", ToBankAccountId: "to", Amount: testAmt,
+				Type: model.Pending, Status: model.Active},
+		}
+		mockTranRepo.On("GetExpiredPendingTransactions", expiredBy, mock.Anything).Return(expiredTransactions, nil)
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTranRepo.On("GetTransactionFromId", "first", mock.Anything).Return(&expiredTransactions[0], nil)
+		mockTranRepo.On("GetTransactionFromId", "second", mock.Anything).Return(&expiredTransactions[1], nil)
+		mockTranRepo.On("UpdatePendingTransactionStatus", "first", model.Revoked, mock.Anything).Return(nil)
+		mockTranRepo.On("UpdatePendingTransactionStatus", "second", model.Revoked, mock.Anything).
+			Return(model.ErrPendingTransactionNotActive)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockTran.On("Commit", mock.Anything).Return(nil)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+
+		revoked, err := service.RevokeExpiredPendingTransactions(expiredBy, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, revoked)
+	})
+
+	t.Run("Returns error if expired transactions cannot be listed", func(t *testing.T) {
+		mockTranRepo, _, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockTranRepo.On("GetExpiredPendingTransactions", expiredBy, mock.Anything).Return(nil, assert.AnError)
+
+		_, err := service.RevokeExpiredPendingTransactions(expiredBy, ctx)
+		assert.Error(t, err)
+		mockTran.AssertNumberOfCalls(t, "BeginTransaction", 0)
+	})
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Returns the number of deleted keys", func(t *testing.T) {
+		_, _, mockIdemRepo, _, service, ctx, cancel := initializeIdempotentTransactionMocks()
+		defer cancel()
+		mockIdemRepo.On("DeleteExpiredIdempotencyRecords", now, mock.Anything).Return(3, nil)
+
+		deleted, err := service.DeleteExpiredIdempotencyKeys(now, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, deleted)
+	})
+
+	t.Run("Returns error if the keys cannot be deleted", func(t *testing.T) {
+		_, _, mockIdemRepo, _, service, ctx, cancel := initializeIdempotentTransactionMocks()
+		defer cancel()
+		mockIdemRepo.On("DeleteExpiredIdempotencyRecords", now, mock.Anything).Return(0, assert.AnError)
+
+		_, err := service.DeleteExpiredIdempotencyKeys(now, ctx)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func TestTransferCategorization(t *testing.T) {
+	testAmt, _ := decimal.NewFromString("100.00")
+	input := model.TransactionDetailsInput{
+		ToBankAccountId:   "toAccountID",
+		FromBankAccountId: "fromAccountID",
+		Amount:            testAmt,
+		Type:              model.Realized,
+		Memo:              "  Rent for May ",
+	}
+	expectTransfer := func(mockAccRepo *mocks.MockAccountRepository, mockTran *mocks.MockTransactional,
+		ctx context.Context) {
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	}
+
+	t.Run("Categorizes each side by the first matching rule of its account holder", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockCatRepo, mockTran, service, ctx, cancel :=
+			initializeCategorizedTransactionMocks()
+		defer cancel()
+		expectTransfer(mockAccRepo, mockTran, ctx)
+		mockAccRepo.On("GetAccountIdFromBankAccountId", "fromAccountID", mock.Anything).Return("sender", nil)
+		mockAccRepo.On("GetAccountIdFromBankAccountId", "toAccountID", mock.Anything).Return("recipient", nil)
+		mockCatRepo.On("GetCategoryRules", "sender", mock.Anything).Return([]model.CategoryRule{
+			{Category: "Income", TransactionNature: model.Debit},
+			{Category: "Too large", MinAmount: decimal.NewNullDecimal(decimal.NewFromInt(500))},
+			{Category: "Housing", CounterpartyBankAccountId: "toAccountID", MemoPattern: "(?i)^rent"},
+			{Category: "Anything"},
+		}, nil)
+		mockCatRepo.On("GetCategoryRules", "recipient", mock.Anything).Return([]model.CategoryRule{
+			{Category: "Other memo", MemoPattern: "deposit"},
+		}, nil)
+		mockTranRepo.On("AddTransaction", mock.MatchedBy(func(details *model.TransactionDetailsInput) bool {
+			return details.Memo == "Rent for May" &&
+				details.FromCategory == model.TransactionCategory{Name: "Housing", Source: model.RuleCategory} &&
+				details.ToCategory == model.TransactionCategory{}
+		}), mock.Anything).Return("transactionId", nil)
+		mockTran.On("Commit", mock.Anything).Return(nil)
+
+		_, err := service.AddTransaction(input, nil, ctx)
+		assert.Nil(t, err)
+		mockTranRepo.AssertNumberOfCalls(t, "AddTransaction", 1)
+	})
+
+	t.Run("Returns error and rolls back if the rules cannot be read", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockCatRepo, mockTran, service, ctx, cancel :=
+			initializeCategorizedTransactionMocks()
+		defer cancel()
+		expectTransfer(mockAccRepo, mockTran, ctx)
+		mockAccRepo.On("GetAccountIdFromBankAccountId", mock.Anything, mock.Anything).Return("accountId", nil)
+		mockCatRepo.On("GetCategoryRules", mock.Anything, mock.Anything).Return(nil, assert.AnError)
+		mockTran.On("Rollback", mock.Anything).Return(nil)
+
+		_, err := service.AddTransaction(input, nil, ctx)
+		assert.ErrorIs(t, err, assert.AnError)
+		mockTranRepo.AssertNumberOfCalls(t, "AddTransaction", 0)
+		mockTran.AssertNumberOfCalls(t, "Rollback", 1)
+	})
+
+	t.Run("Returns error without starting a transaction if the memo is too long", func(t *testing.T) {
+		_, _, _, mockTran, service, ctx, cancel := initializeCategorizedTransactionMocks()
+		defer cancel()
+		longInput := input
+		longInput.Memo = strings.Repeat("m", maxMemoLength+1)
+
+		_, err := service.AddTransaction(longInput, nil, ctx)
+		assert.ErrorIs(t, err, model.ErrInvalidMemo)
+		mockTran.AssertNumberOfCalls(t, "BeginTransaction", 0)
+	})
+
+	t.Run("Applied pending transactions keep their memo and the categories set by hand", func(t *testing.T) {
+		mockTranRepo, mockAccRepo, mockTran, service, ctx, cancel := initializeTransactionMocks()
+		defer cancel()
+		mockTran.On("BeginTransaction", mock.Anything, mock.Anything, mock.Anything).Return(ctx, nil)
+		mockTranRepo.On("GetTransactionFromId", "transactionId", mock.Anything).Return(&model.TransactionDetailsOutput{
+			Id:                "transactionId",
+			FromBankAccountId: "fromAccountID",
+			ToBankAccountId:   "toAccountID",
+			Amount:            testAmt,
+			Type:              model.Pending,
+			Status:            model.Active,
+			Memo:              "deposit",
+			FromCategory:      model.TransactionCategory{Name: "Housing", Source: model.ManualCategory},
+			ToCategory:        model.TransactionCategory{Name: "Deposits", Source: model.RuleCategory},
+		}, nil)
+		mockTranRepo.On("UpdatePendingTransactionStatus", "transactionId", model.Applied, mock.Anything).
+			Return(nil)
+		mockAccRepo.On("AddBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockAccRepo.On("DeductBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(decimal.Zero, decimal.Zero, nil)
+		mockTranRepo.On("AddTransaction", mock.MatchedBy(func(details *model.TransactionDetailsInput) bool {
+			return details.Memo == "deposit" &&
+				details.FromCategory == model.TransactionCategory{Name: "Housing", Source: model.ManualCategory} &&
+				details.ToCategory == model.TransactionCategory{}
+		}), mock.Anything).Return("realizedTransactionId", nil)
+		mockTran.On("Commit", mock.Anything).Return(nil)
+
+		err := service.ApplyPendingTransaction("transactionId", ctx)
+		assert.Nil(t, err)
+		mockTranRepo.AssertNumberOfCalls(t, "AddTransaction", 1)
+	})
+
+	t.Run("Fingerprints the memo only when it is set", func(t *testing.T) {
+		withoutMemo := input
+		withoutMemo.Memo = ""
+		// The fingerprint of fromAccountID|toAccountID|100|realized, as stored before transfers had memos
+		assert.Equal(t, "7f41cc64e42c8ef31a9abfb2ac34b2744d8c38f26c1c93cabb391eb797381608",
+			hashTransactionRequest(&withoutMemo))
+		assert.NotEqual(t, hashTransactionRequest(&withoutMemo), hashTransactionRequest(&input))
+	})
+}
+
+// initializeCategorizedTransactionMocks leaves the category rules of the account holders for the test to mock
+func initializeCategorizedTransactionMocks() (
 	*mocks.MockTransactionRepository,
 	*mocks.MockAccountRepository,
+	*mocks.MockCategoryRuleRepository,
 	*mocks.MockTransactional,
 	*TransactionServiceImpl,
 	context.Context,
@@ -143,9 +858,55 @@ func initializeTransactionMocks() (
 ) {
 	mockTranRepo := new(mocks.MockTransactionRepository)
 	mockAccRepo := &mocks.MockAccountRepository{}
+	mockCatRepo := &mocks.MockCategoryRuleRepository{}
 	mockTran := &mocks.MockTransactional{}
+	mockJournalRepo := &mocks.MockJournalRepository{}
+	mockJournalRepo.On("AddJournalEntry", mock.Anything, mock.Anything).Return("journalEntryId", nil).Maybe()
 
-	service := CreateNewTransactionServiceImpl(mockTranRepo, mockAccRepo, mockTran)
+	service := CreateNewTransactionServiceImpl(
+		mockTranRepo, mockAccRepo, &mocks.MockIdempotencyRepository{}, mockJournalRepo, mockCatRepo, mockTran,
+		model.AllowUnknownPayees, metrics.New(), logging.Discard(),
+	)
 	addCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return mockTranRepo, mockAccRepo, mockCatRepo, mockTran, service, addCtx, cancel
+}
+
+func initializeTransactionMocks() (
+	*mocks.MockTransactionRepository,
+	*mocks.MockAccountRepository,
+	*mocks.MockTransactional,
+	*TransactionServiceImpl,
+	context.Context,
+	context.CancelFunc,
+) {
+	mockTranRepo, mockAccRepo, _, mockTran, service, addCtx, cancel := initializeIdempotentTransactionMocks()
 	return mockTranRepo, mockAccRepo, mockTran, service, addCtx, cancel
 }
+
+func initializeIdempotentTransactionMocks() (
+	*mocks.MockTransactionRepository,
+	*mocks.MockAccountRepository,
+	*mocks.MockIdempotencyRepository,
+	*mocks.MockTransactional,
+	*TransactionServiceImpl,
+	context.Context,
+	context.CancelFunc,
+) {
+	mockTranRepo := new(mocks.MockTransactionRepository)
+	mockAccRepo := &mocks.MockAccountRepository{}
+	mockIdemRepo := &mocks.MockIdempotencyRepository{}
+	mockTran := &mocks.MockTransactional{}
+	mockJournalRepo := &mocks.MockJournalRepository{}
+	mockJournalRepo.On("AddJournalEntry", mock.Anything, mock.Anything).Return("journalEntryId", nil).Maybe()
+	// Transfers are categorized by the rules of either account holder, which have none unless a test says otherwise
+	mockAccRepo.On("GetAccountIdFromBankAccountId", mock.Anything, mock.Anything).Return("accountId", nil).Maybe()
+	mockCatRepo := &mocks.MockCategoryRuleRepository{}
+	mockCatRepo.On("GetCategoryRules", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+
+	service := CreateNewTransactionServiceImpl(
+		mockTranRepo, mockAccRepo, mockIdemRepo, mockJournalRepo, mockCatRepo, mockTran, model.AllowUnknownPayees,
+		metrics.New(), logging.Discard(),
+	)
+	addCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return mockTranRepo, mockAccRepo, mockIdemRepo, mockTran, service, addCtx, cancel
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/tracing"
+)
+
+// TransactionServiceTraced records a span for every call to the transaction service it wraps
+type TransactionServiceTraced struct {
+	next   TransactionService
+	tracer trace.Tracer
+}
+
+func CreateNewTransactionServiceTraced(next TransactionService, tracer trace.Tracer) *TransactionServiceTraced {
+	return &TransactionServiceTraced{next: next, tracer: tracer}
+}
+
+func (t *TransactionServiceTraced) AddTransaction(
+	input model.TransactionDetailsInput,
+	idempotencyKey *model.IdempotencyKeyInput,
+	ctx context.Context,
+) (_ model.TransactionCreatedOutput, err error) {
+	ctx, span := t.tracer.Start(ctx, "TransactionService.AddTransaction")
+	defer func() { tracing.End(span, err) }()
+	return t.next.AddTransaction(input, idempotencyKey, ctx)
+}
+
+func (t *TransactionServiceTraced) AddPendingTransaction(
+	input model.TransactionDetailsInput,
+	ctx context.Context,
+) (_ string, err error) {
+	ctx, span := t.tracer.Start(ctx, "TransactionService.AddPendingTransaction")
+	defer func() { tracing.End(span, err) }()
+	return t.next.AddPendingTransaction(input, ctx)
+}
+
+func (t *TransactionServiceTraced) GetTransactionDetails(
+	transactionId string,
+	ctx context.Context,
+) (_ *model.TransactionDetailsOutput, err error) {
+	ctx, span := t.tracer.Start(ctx, "TransactionService.GetTransactionDetails")
+	defer func() { tracing.End(span, err) }()
+	return t.next.GetTransactionDetails(transactionId, ctx)
+}
+
+func (t *TransactionServiceTraced) ApplyPendingTransaction(transactionId string, ctx context.Context) (err error) {
+	ctx, span := t.tracer.Start(ctx, "TransactionService.ApplyPendingTransaction")
+	defer func() { tracing.End(span, err) }()
+	return t.next.ApplyPendingTransaction(transactionId, ctx)
+}
+
+func (t *TransactionServiceTraced) RevokePendingTransaction(transactionId string, ctx context.Context) (err error) {
+	ctx, span := t.tracer.Start(ctx, "TransactionService.RevokePendingTransaction")
+	defer func() { tracing.End(span, err) }()
+	return t.next.RevokePendingTransaction(transactionId, ctx)
+}
+
+func (t *TransactionServiceTraced) RevokeExpiredPendingTransactions(
+	expiredBy time.Time,
+	ctx context.Context,
+) (_ int, err error) {
+	ctx, span := t.tracer.Start(ctx, "TransactionService.RevokeExpiredPendingTransactions")
+	defer func() { tracing.End(span, err) }()
+	return t.next.RevokeExpiredPendingTransactions(expiredBy, ctx)
+}
+
+func (t *TransactionServiceTraced) DeleteExpiredIdempotencyKeys(
+	now time.Time,
+	ctx context.Context,
+) (_ int, err error) {
+	ctx, span := t.tracer.Start(ctx, "TransactionService.DeleteExpiredIdempotencyKeys")
+	defer func() { tracing.End(span, err) }()
+	return t.next.DeleteExpiredIdempotencyKeys(now, ctx)
+}
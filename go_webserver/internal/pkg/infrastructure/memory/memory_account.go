@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type AccountRecord struct {
+	Id                string
+	Username          string
+	Password          string
+	Person            Person
+	BankAccounts      []BankAccount
+	KnownBankAccounts []KnownBankAccount
+	CreatedAt         time.Time
+}
+
+func (a *AccountRecord) Copy() Document {
+	copied := *a
+	copied.BankAccounts = append([]BankAccount{}, a.BankAccounts...)
+	copied.KnownBankAccounts = append([]KnownBankAccount{}, a.KnownBankAccounts...)
+	return &copied
+}
+
+type Person struct {
+	FirstName string
+	LastName  string
+}
+
+type BankAccount struct {
+	Id               string
+	AccountNumber    string
+	AccountType      string
+	PendingBalance   decimal.Decimal
+	AvailableBalance decimal.Decimal
+}
+
+type KnownBankAccount struct {
+	Id            string
+	AccountNumber string
+	AccountHolder string
+	AccountType   string
+	Nickname      string
+}
@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type BalanceSnapshotRecord struct {
+	Id                      string
+	BankAccountId           string
+	Day                     time.Time
+	AvailableBalance        decimal.Decimal
+	PendingBalance          decimal.Decimal
+	OpeningAvailableBalance decimal.Decimal
+	OpeningPendingBalance   decimal.Decimal
+	MinAvailableBalance     decimal.Decimal
+	MaxAvailableBalance     decimal.Decimal
+	TotalDebits             decimal.Decimal
+	TotalCredits            decimal.Decimal
+	CreatedAt               time.Time
+}
+
+// BalanceSnapshotId identifies the snapshot of a bank account for a day, so that every day is only snapshotted once
+// like the unique index of the MongoDB collection ensures
+func BalanceSnapshotId(bankAccountId string, day time.Time) string {
+	return bankAccountId + "/" + day.UTC().Format(time.DateOnly)
+}
+
+func (b *BalanceSnapshotRecord) Copy() Document {
+	copied := *b
+	return &copied
+}
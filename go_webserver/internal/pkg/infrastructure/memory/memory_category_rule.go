@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type CategoryRuleRecord struct {
+	Id                        string
+	AccountId                 string
+	Category                  string
+	CounterpartyBankAccountId string
+	TransactionNature         string
+	MinAmount                 decimal.NullDecimal
+	MaxAmount                 decimal.NullDecimal
+	MemoPattern               string
+	CreatedAt                 time.Time
+}
+
+func (c *CategoryRuleRecord) Copy() Document {
+	copied := *c
+	return &copied
+}
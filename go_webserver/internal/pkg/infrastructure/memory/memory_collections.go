@@ -0,0 +1,42 @@
+package memory
+
+// The collections of the wallet, named like their MongoDB counterparts
+const (
+	AccountCollectionName        = "account"
+	TransactionCollectionName    = "transaction"
+	IdempotencyKeyCollectionName = "idempotency_key"
+	JournalEntryCollectionName   = "journal_entry"
+	// BalanceSnapshotCollectionName holds the snapshots of every bank account by the ID BalanceSnapshotId gives them
+	BalanceSnapshotCollectionName = "balance_snapshot"
+	CategoryRuleCollectionName    = "category_rule"
+)
+
+// NewWalletStore returns an empty store with the unique indexes the schema migrations create in MongoDB
+func NewWalletStore() *Store {
+	s := NewStore()
+	s.AddUniqueIndex(AccountCollectionName, UniqueIndex{
+		Name: "unique_username",
+		Keys: func(doc Document) []string {
+			return []string{doc.(*AccountRecord).Username}
+		},
+	})
+	s.AddUniqueIndex(AccountCollectionName, UniqueIndex{
+		Name: "unique_bank_account_number",
+		Keys: func(doc Document) []string {
+			bankAccounts := doc.(*AccountRecord).BankAccounts
+			keys := make([]string, len(bankAccounts))
+			for i, bankAccount := range bankAccounts {
+				keys[i] = bankAccount.AccountNumber
+			}
+			return keys
+		},
+	})
+	s.AddUniqueIndex(IdempotencyKeyCollectionName, UniqueIndex{
+		Name: "account_idempotency_key",
+		Keys: func(doc Document) []string {
+			record := doc.(*IdempotencyRecord)
+			return []string{record.AccountId + "/" + record.Key}
+		},
+	})
+	return s
+}
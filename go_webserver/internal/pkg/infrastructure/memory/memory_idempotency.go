@@ -0,0 +1,19 @@
+package memory
+
+import (
+	"time"
+)
+
+type IdempotencyRecord struct {
+	Id            string
+	AccountId     string
+	Key           string
+	RequestHash   string
+	TransactionId string
+	CreatedAt     time.Time
+}
+
+func (i *IdempotencyRecord) Copy() Document {
+	copied := *i
+	return &copied
+}
@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type JournalEntryRecord struct {
+	Id            string
+	TransactionId string
+	Kind          string
+	Postings      []Posting
+	CreatedAt     time.Time
+}
+
+func (j *JournalEntryRecord) Copy() Document {
+	copied := *j
+	copied.Postings = append([]Posting{}, j.Postings...)
+	return &copied
+}
+
+type Posting struct {
+	BankAccountId string
+	Balance       string
+	Amount        decimal.Decimal
+}
@@ -0,0 +1,443 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrWriteConflict is returned when committing a transaction that wrote a document another transaction committed
+	// a change to since it was read. Like a MongoDB write conflict it is transient, so the transaction can be retried.
+	ErrWriteConflict = errors.New("write conflict with a concurrent transaction")
+	ErrDuplicateKey  = errors.New("duplicate key")
+	ErrTxDone        = errors.New("transaction has already been committed or rolled back")
+	// maxAutoCommitAttempts bounds the retries of writes made outside of a transaction
+	maxAutoCommitAttempts = 10
+)
+
+// Document is a record of a collection. Documents are copied in and out of the store, so that changes to a document
+// only take effect once it is written and its transaction committed.
+type Document interface {
+	Copy() Document
+}
+
+// UniqueIndex rejects documents of a collection sharing any of the keys returned for them
+type UniqueIndex struct {
+	Name string
+	Keys func(doc Document) []string
+}
+
+type storedDocument struct {
+	doc     Document
+	version uint64
+	// order keeps collections in insertion order, like the natural order of MongoDB collections
+	order   uint64
+	deleted bool
+}
+
+type collection map[string]*storedDocument
+
+// Store is an in-memory database of collections of documents, which are read and written in transactions. Committed
+// documents are never changed in place, so a transaction can keep reading a snapshot of them.
+type Store struct {
+	mu          sync.Mutex
+	collections map[string]collection
+	indexes     map[string][]UniqueIndex
+	// seq is the number of commits so far, which versions every committed document
+	seq   uint64
+	order uint64
+}
+
+func NewStore() *Store {
+	return &Store{
+		collections: make(map[string]collection),
+		indexes:     make(map[string][]UniqueIndex),
+	}
+}
+
+// AddUniqueIndex makes the store reject documents of the collection that share a key of the index
+func (s *Store) AddUniqueIndex(collectionName string, index UniqueIndex) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.indexes[collectionName] = append(s.indexes[collectionName], index)
+}
+
+type pendingWrite struct {
+	doc     Document
+	deleted bool
+	// baseVersion is the version of the document the write was based on, which must still be the committed one
+	baseVersion uint64
+	order       uint64
+}
+
+// Tx is a transaction on the store. Writes are kept in the transaction until it commits. Reads see the writes of the
+// transaction on top of either the latest committed documents, or a snapshot of the documents committed when the
+// transaction began.
+type Tx struct {
+	store    *Store
+	snapshot map[string]collection
+	writes   map[string]map[string]*pendingWrite
+	reads    map[string]map[string]uint64
+	done     bool
+	mu       sync.Mutex
+}
+
+// Begin starts a transaction. Snapshot transactions read the documents committed when they began, while the others
+// read the latest committed documents.
+func (s *Store) Begin(snapshot bool) *Tx {
+	tx := &Tx{
+		store:  s,
+		writes: make(map[string]map[string]*pendingWrite),
+		reads:  make(map[string]map[string]uint64),
+	}
+	if snapshot {
+		s.mu.Lock()
+		tx.snapshot = make(map[string]collection, len(s.collections))
+		for name, docs := range s.collections {
+			copied := make(collection, len(docs))
+			for id, stored := range docs {
+				copied[id] = stored
+			}
+			tx.snapshot[name] = copied
+		}
+		s.mu.Unlock()
+	}
+	return tx
+}
+
+// Get returns a copy of the document with the given ID as seen by the transaction
+func (tx *Tx) Get(collectionName string, id string) (Document, bool, error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return nil, false, ErrTxDone
+	}
+	if write, ok := tx.writes[collectionName][id]; ok {
+		if write.deleted {
+			return nil, false, nil
+		}
+		return write.doc.Copy(), true, nil
+	}
+	stored := tx.committed(collectionName, id)
+	tx.recordRead(collectionName, id, stored)
+	if stored == nil || stored.deleted {
+		return nil, false, nil
+	}
+	return stored.doc.Copy(), true, nil
+}
+
+// Find returns copies of the documents of the collection matching the predicate in insertion order, as seen by the
+// transaction
+func (tx *Tx) Find(collectionName string, match func(doc Document) bool) ([]Document, error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return nil, ErrTxDone
+	}
+	type ordered struct {
+		doc   Document
+		order uint64
+	}
+	visible := make(map[string]ordered)
+	for id, stored := range tx.committedCollection(collectionName) {
+		tx.recordRead(collectionName, id, stored)
+		if !stored.deleted {
+			visible[id] = ordered{stored.doc, stored.order}
+		}
+	}
+	for id, write := range tx.writes[collectionName] {
+		if write.deleted {
+			delete(visible, id)
+		} else {
+			visible[id] = ordered{write.doc, write.order}
+		}
+	}
+	matches := make([]ordered, 0)
+	for _, elem := range visible {
+		if match == nil || match(elem.doc) {
+			matches = append(matches, elem)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].order < matches[j].order })
+	res := make([]Document, len(matches))
+	for i, elem := range matches {
+		res[i] = elem.doc.Copy()
+	}
+	return res, nil
+}
+
+// Put inserts the document or replaces the document with the same ID. Documents sharing a key of a unique index
+// with another document visible to the transaction are rejected with ErrDuplicateKey.
+func (tx *Tx) Put(collectionName string, id string, doc Document) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return ErrTxDone
+	}
+	if err := tx.checkUniqueIndexes(collectionName, id, doc); err != nil {
+		return err
+	}
+	tx.write(collectionName, id, &pendingWrite{doc: doc.Copy()})
+	return nil
+}
+
+// Delete removes the document with the given ID
+func (tx *Tx) Delete(collectionName string, id string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.write(collectionName, id, &pendingWrite{deleted: true})
+	return nil
+}
+
+// Commit applies the writes of the transaction. It fails with ErrWriteConflict when a document it wrote has been
+// changed by another transaction since the transaction read it, or when a concurrent transaction took one of its
+// unique keys, in which case none of the writes are applied.
+func (tx *Tx) Commit() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	s := tx.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for collectionName, writes := range tx.writes {
+		for id, write := range writes {
+			var committedVersion uint64
+			if stored, ok := s.collections[collectionName][id]; ok {
+				committedVersion = stored.version
+			}
+			if committedVersion != write.baseVersion {
+				return fmt.Errorf("%w on document %s of %s", ErrWriteConflict, id, collectionName)
+			}
+			if write.deleted {
+				continue
+			}
+			if err := s.checkCommittedUniqueIndexes(collectionName, id, write.doc, writes); err != nil {
+				return fmt.Errorf("%w: %w", ErrWriteConflict, err)
+			}
+		}
+	}
+
+	s.seq++
+	for collectionName, writes := range tx.writes {
+		docs, ok := s.collections[collectionName]
+		if !ok {
+			docs = make(collection)
+			s.collections[collectionName] = docs
+		}
+		for id, write := range writes {
+			order := write.order
+			if stored, ok := docs[id]; ok {
+				order = stored.order
+			}
+			docs[id] = &storedDocument{doc: write.doc, version: s.seq, order: order, deleted: write.deleted}
+		}
+	}
+	return nil
+}
+
+// Rollback discards the writes of the transaction
+func (tx *Tx) Rollback() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.writes = nil
+	return nil
+}
+
+// committed returns the committed document visible to the transaction, which is nil when there is none
+func (tx *Tx) committed(collectionName string, id string) *storedDocument {
+	if tx.snapshot != nil {
+		return tx.snapshot[collectionName][id]
+	}
+	tx.store.mu.Lock()
+	defer tx.store.mu.Unlock()
+	return tx.store.collections[collectionName][id]
+}
+
+func (tx *Tx) committedCollection(collectionName string) collection {
+	if tx.snapshot != nil {
+		return tx.snapshot[collectionName]
+	}
+	tx.store.mu.Lock()
+	defer tx.store.mu.Unlock()
+	docs := tx.store.collections[collectionName]
+	copied := make(collection, len(docs))
+	for id, stored := range docs {
+		copied[id] = stored
+	}
+	return copied
+}
+
+// recordRead remembers the version of the first read of a document, which later writes of the document are based on
+func (tx *Tx) recordRead(collectionName string, id string, stored *storedDocument) {
+	reads, ok := tx.reads[collectionName]
+	if !ok {
+		reads = make(map[string]uint64)
+		tx.reads[collectionName] = reads
+	}
+	if _, ok = reads[id]; ok {
+		return
+	}
+	var version uint64
+	if stored != nil {
+		version = stored.version
+	}
+	reads[id] = version
+}
+
+func (tx *Tx) write(collectionName string, id string, write *pendingWrite) {
+	writes, ok := tx.writes[collectionName]
+	if !ok {
+		writes = make(map[string]*pendingWrite)
+		tx.writes[collectionName] = writes
+	}
+	if previous, ok := writes[id]; ok {
+		write.baseVersion = previous.baseVersion
+		write.order = previous.order
+	} else {
+		if _, read := tx.reads[collectionName][id]; !read {
+			tx.recordRead(collectionName, id, tx.committed(collectionName, id))
+		}
+		write.baseVersion = tx.reads[collectionName][id]
+		tx.store.mu.Lock()
+		tx.store.order++
+		write.order = tx.store.order
+		tx.store.mu.Unlock()
+	}
+	writes[id] = write
+}
+
+func (tx *Tx) checkUniqueIndexes(collectionName string, id string, doc Document) error {
+	tx.store.mu.Lock()
+	indexes := tx.store.indexes[collectionName]
+	tx.store.mu.Unlock()
+	for _, index := range indexes {
+		keys := index.Keys(doc)
+		if len(keys) == 0 {
+			continue
+		}
+		for otherId, stored := range tx.committedCollection(collectionName) {
+			if otherId == id || stored.deleted {
+				continue
+			}
+			// Documents written by the transaction are checked as written below
+			if _, ok := tx.writes[collectionName][otherId]; ok {
+				continue
+			}
+			if sharesKey(keys, index.Keys(stored.doc)) {
+				return fmt.Errorf("%w on index %s of %s", ErrDuplicateKey, index.Name, collectionName)
+			}
+		}
+		for otherId, write := range tx.writes[collectionName] {
+			if otherId == id || write.deleted {
+				continue
+			}
+			if sharesKey(keys, index.Keys(write.doc)) {
+				return fmt.Errorf("%w on index %s of %s", ErrDuplicateKey, index.Name, collectionName)
+			}
+		}
+		if hasDuplicates(keys) {
+			return fmt.Errorf("%w on index %s of %s", ErrDuplicateKey, index.Name, collectionName)
+		}
+	}
+	return nil
+}
+
+// checkCommittedUniqueIndexes checks a written document against the latest committed documents, which may have
+// changed since the transaction checked it on write. The store must be locked.
+func (s *Store) checkCommittedUniqueIndexes(
+	collectionName string,
+	id string,
+	doc Document,
+	writes map[string]*pendingWrite,
+) error {
+	for _, index := range s.indexes[collectionName] {
+		keys := index.Keys(doc)
+		if len(keys) == 0 {
+			continue
+		}
+		for otherId, stored := range s.collections[collectionName] {
+			if otherId == id || stored.deleted {
+				continue
+			}
+			if _, overwritten := writes[otherId]; overwritten {
+				continue
+			}
+			if sharesKey(keys, index.Keys(stored.doc)) {
+				return fmt.Errorf("%w on index %s of %s", ErrDuplicateKey, index.Name, collectionName)
+			}
+		}
+	}
+	return nil
+}
+
+func sharesKey(keys []string, otherKeys []string) bool {
+	for _, key := range keys {
+		for _, otherKey := range otherKeys {
+			if key == otherKey {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasDuplicates(keys []string) bool {
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			return true
+		}
+		seen[key] = struct{}{}
+	}
+	return false
+}
+
+type txKey struct{}
+
+// ContextWithTx returns a copy of the context carrying the transaction, which repositories then read and write in
+func ContextWithTx(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction carried by the context, or nil outside of transactions
+func TxFromContext(ctx context.Context) *Tx {
+	tx, _ := ctx.Value(txKey{}).(*Tx)
+	return tx
+}
+
+// Run runs fn in the transaction carried by the context. Outside of transactions fn runs in a transaction of its own
+// that is committed when fn succeeds and retried on write conflicts, like a single write to MongoDB.
+func (s *Store) Run(ctx context.Context, fn func(tx *Tx) error) error {
+	if tx := TxFromContext(ctx); tx != nil {
+		return fn(tx)
+	}
+	var err error
+	for attempt := 1; attempt <= maxAutoCommitAttempts; attempt++ {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		tx := s.Begin(false)
+		if err = fn(tx); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); !errors.Is(err, ErrWriteConflict) {
+			return err
+		}
+	}
+	return err
+}
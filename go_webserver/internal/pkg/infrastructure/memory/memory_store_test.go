@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type counter struct {
+	Name  string
+	Value int
+}
+
+func (c *counter) Copy() Document {
+	copied := *c
+	return &copied
+}
+
+const counters = "counters"
+
+func newCounterStore(t *testing.T) *Store {
+	s := NewStore()
+	s.AddUniqueIndex(counters, UniqueIndex{
+		Name: "unique_name",
+		Keys: func(doc Document) []string { return []string{doc.(*counter).Name} },
+	})
+	tx := s.Begin(false)
+	assert.Nil(t, tx.Put(counters, "a", &counter{Name: "a", Value: 1}))
+	assert.Nil(t, tx.Commit())
+	return s
+}
+
+func getCounter(t *testing.T, tx *Tx, id string) *counter {
+	doc, found, err := tx.Get(counters, id)
+	assert.Nil(t, err)
+	if !found {
+		return nil
+	}
+	return doc.(*counter)
+}
+
+func TestStore(t *testing.T) {
+	t.Run("Writes are only visible to other transactions once committed", func(t *testing.T) {
+		s := newCounterStore(t)
+		writer, reader := s.Begin(false), s.Begin(false)
+		assert.Nil(t, writer.Put(counters, "a", &counter{Name: "a", Value: 2}))
+		assert.Equal(t, 2, getCounter(t, writer, "a").Value)
+		assert.Equal(t, 1, getCounter(t, reader, "a").Value)
+		assert.Nil(t, writer.Commit())
+		assert.Equal(t, 2, getCounter(t, reader, "a").Value)
+	})
+
+	t.Run("Rolled back writes are discarded", func(t *testing.T) {
+		s := newCounterStore(t)
+		tx := s.Begin(false)
+		assert.Nil(t, tx.Put(counters, "b", &counter{Name: "b"}))
+		assert.Nil(t, tx.Delete(counters, "a"))
+		assert.Nil(t, tx.Rollback())
+		assert.ErrorIs(t, tx.Commit(), ErrTxDone)
+
+		check := s.Begin(false)
+		assert.Nil(t, getCounter(t, check, "b"))
+		assert.Equal(t, 1, getCounter(t, check, "a").Value)
+	})
+
+	t.Run("Snapshot transactions keep reading the documents committed when they began", func(t *testing.T) {
+		s := newCounterStore(t)
+		snapshot := s.Begin(true)
+		writer := s.Begin(false)
+		assert.Nil(t, writer.Put(counters, "a", &counter{Name: "a", Value: 2}))
+		assert.Nil(t, writer.Put(counters, "b", &counter{Name: "b", Value: 3}))
+		assert.Nil(t, writer.Commit())
+
+		assert.Equal(t, 1, getCounter(t, snapshot, "a").Value)
+		found, err := snapshot.Find(counters, nil)
+		assert.Nil(t, err)
+		assert.Len(t, found, 1)
+	})
+
+	t.Run("Concurrent writes to the same document conflict on commit", func(t *testing.T) {
+		s := newCounterStore(t)
+		first, second := s.Begin(false), s.Begin(true)
+		for _, tx := range []*Tx{first, second} {
+			c := getCounter(t, tx, "a")
+			c.Value++
+			assert.Nil(t, tx.Put(counters, "a", c))
+		}
+		assert.Nil(t, first.Commit())
+		assert.ErrorIs(t, second.Commit(), ErrWriteConflict)
+		assert.Equal(t, 2, getCounter(t, s.Begin(false), "a").Value)
+	})
+
+	t.Run("Unique keys are checked on write and on commit", func(t *testing.T) {
+		s := newCounterStore(t)
+		tx := s.Begin(false)
+		assert.ErrorIs(t, tx.Put(counters, "b", &counter{Name: "a"}), ErrDuplicateKey)
+
+		first, second := s.Begin(false), s.Begin(false)
+		assert.Nil(t, first.Put(counters, "b", &counter{Name: "b"}))
+		assert.Nil(t, second.Put(counters, "c", &counter{Name: "b"}))
+		assert.Nil(t, first.Commit())
+		assert.ErrorIs(t, second.Commit(), ErrWriteConflict)
+	})
+
+	t.Run("Documents are copied in and out of the store", func(t *testing.T) {
+		s := newCounterStore(t)
+		tx := s.Begin(false)
+		c := getCounter(t, tx, "a")
+		c.Value = 5
+		assert.Equal(t, 1, getCounter(t, tx, "a").Value)
+	})
+
+	t.Run("Writes outside of transactions are committed and retried on conflicts", func(t *testing.T) {
+		s := newCounterStore(t)
+		attempts := 0
+		err := s.Run(context.Background(), func(tx *Tx) error {
+			attempts++
+			c := getCounter(t, tx, "a")
+			if attempts == 1 {
+				concurrent := s.Begin(false)
+				assert.Nil(t, concurrent.Put(counters, "a", &counter{Name: "a", Value: 10}))
+				assert.Nil(t, concurrent.Commit())
+			}
+			c.Value++
+			return tx.Put(counters, "a", c)
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, 11, getCounter(t, s.Begin(false), "a").Value)
+
+		errFailed := errors.New("failed")
+		err = s.Run(context.Background(), func(tx *Tx) error {
+			assert.Nil(t, tx.Put(counters, "b", &counter{Name: "b"}))
+			return errFailed
+		})
+		assert.ErrorIs(t, err, errFailed)
+		assert.Nil(t, getCounter(t, s.Begin(false), "b"))
+	})
+}
@@ -0,0 +1,27 @@
+package memory
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type TransactionRecord struct {
+	Id                 string
+	FromBankAccountId  string
+	ToBankAccountId    string
+	Amount             decimal.Decimal
+	Type               string
+	ExpirationDate     time.Time
+	Status             string
+	Memo               string
+	FromCategory       string
+	FromCategorySource string
+	ToCategory         string
+	ToCategorySource   string
+	CreatedAt          time.Time
+}
+
+func (t *TransactionRecord) Copy() Document {
+	copied := *t
+	return &copied
+}
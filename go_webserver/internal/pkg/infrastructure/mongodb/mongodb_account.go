@@ -23,6 +23,12 @@ type MongoAccountInput struct {
 	CreatedAt         primitive.Timestamp `bson:"_createdAt"`
 }
 
+type MongoAccountCredentialsOutput struct {
+	Id       primitive.ObjectID `bson:"_id"`
+	Username string             `bson:"username"`
+	Password string             `bson:"password"`
+}
+
 type Person struct {
 	FirstName string `bson:"firstName"`
 	LastName  string `bson:"lastName"`
@@ -41,6 +47,7 @@ type KnownBankAccount struct {
 	AccountNumber string             `bson:"accountNumber"`
 	AccountHolder string             `bson:"accountHolder"`
 	AccountType   string             `bson:"accountType"`
+	Nickname      string             `bson:"nickname,omitempty"`
 }
 
 type MongoAccountTransactionOutput struct {
@@ -52,5 +59,8 @@ type MongoAccountTransactionOutput struct {
 	ExpirationDate     primitive.Timestamp  `bson:"expirationDate,omitempty"`
 	Status             string               `bson:"status,omitempty"`
 	Amount             primitive.Decimal128 `bson:"amount"`
+	Memo               string               `bson:"memo,omitempty"`
+	Category           string               `bson:"category,omitempty"`
+	CategorySource     string               `bson:"categorySource,omitempty"`
 	CreatedAt          primitive.Timestamp  `bson:"_createdAt"`
 }
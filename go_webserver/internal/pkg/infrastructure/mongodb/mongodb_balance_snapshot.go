@@ -0,0 +1,20 @@
+package mongodb
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type MongoBalanceSnapshot struct {
+	BankAccountId           primitive.ObjectID   `bson:"bankAccountId"`
+	Day                     time.Time            `bson:"day"`
+	AvailableBalance        primitive.Decimal128 `bson:"availableBalance"`
+	PendingBalance          primitive.Decimal128 `bson:"pendingBalance"`
+	OpeningAvailableBalance primitive.Decimal128 `bson:"openingAvailableBalance"`
+	OpeningPendingBalance   primitive.Decimal128 `bson:"openingPendingBalance"`
+	MinAvailableBalance     primitive.Decimal128 `bson:"minAvailableBalance"`
+	MaxAvailableBalance     primitive.Decimal128 `bson:"maxAvailableBalance"`
+	TotalDebits             primitive.Decimal128 `bson:"totalDebits"`
+	TotalCredits            primitive.Decimal128 `bson:"totalCredits"`
+	CreatedAt               primitive.Timestamp  `bson:"_createdAt"`
+}
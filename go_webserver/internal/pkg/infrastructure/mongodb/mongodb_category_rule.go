@@ -0,0 +1,18 @@
+package mongodb
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MongoCategoryRule leaves out the conditions the rule does not have
+type MongoCategoryRule struct {
+	Id                        primitive.ObjectID    `bson:"_id"`
+	AccountId                 primitive.ObjectID    `bson:"accountId"`
+	Category                  string                `bson:"category"`
+	CounterpartyBankAccountId *primitive.ObjectID   `bson:"counterpartyBankAccountId,omitempty"`
+	TransactionNature         string                `bson:"transactionNature,omitempty"`
+	MinAmount                 *primitive.Decimal128 `bson:"minAmount,omitempty"`
+	MaxAmount                 *primitive.Decimal128 `bson:"maxAmount,omitempty"`
+	MemoPattern               string                `bson:"memoPattern,omitempty"`
+	CreatedAt                 primitive.Timestamp   `bson:"_createdAt"`
+}
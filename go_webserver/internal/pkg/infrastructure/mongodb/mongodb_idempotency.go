@@ -0,0 +1,15 @@
+package mongodb
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type MongoIdempotencyRecord struct {
+	AccountId     primitive.ObjectID `bson:"accountId"`
+	Key           string             `bson:"key"`
+	RequestHash   string             `bson:"requestHash"`
+	TransactionId primitive.ObjectID `bson:"transactionId"`
+	// Stored as a date rather than a timestamp since TTL indexes only expire documents by date fields
+	CreatedAt time.Time `bson:"createdAt"`
+}
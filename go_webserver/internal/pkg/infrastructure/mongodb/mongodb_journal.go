@@ -0,0 +1,33 @@
+package mongodb
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MongoJournalEntryInput struct {
+	TransactionId primitive.ObjectID  `bson:"transactionId,omitempty"`
+	Kind          string              `bson:"kind"`
+	Postings      []MongoPosting      `bson:"postings"`
+	CreatedAt     primitive.Timestamp `bson:"_createdAt"`
+}
+
+type MongoPosting struct {
+	BankAccountId primitive.ObjectID   `bson:"bankAccountId"`
+	Balance       string               `bson:"balance"`
+	Amount        primitive.Decimal128 `bson:"amount"`
+}
+
+type MongoPostingTotal struct {
+	Id struct {
+		BankAccountId primitive.ObjectID `bson:"bankAccountId"`
+		Balance       string             `bson:"balance"`
+	} `bson:"_id"`
+	Amount primitive.Decimal128 `bson:"amount"`
+}
+
+type MongoBankAccountBalance struct {
+	Id               primitive.ObjectID   `bson:"_id"`
+	AccountNumber    string               `bson:"accountNumber"`
+	PendingBalance   primitive.Decimal128 `bson:"pendingBalance"`
+	AvailableBalance primitive.Decimal128 `bson:"availableBalance"`
+}
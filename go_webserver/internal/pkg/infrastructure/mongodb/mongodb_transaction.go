@@ -5,24 +5,34 @@ import (
 )
 
 type MongoTransactionInput struct {
-	FromBankAccountId primitive.ObjectID   `bson:"fromBankAccountId"`
-	ToBankAccountId   primitive.ObjectID   `bson:"toBankAccountId"`
-	Amount            primitive.Decimal128 `bson:"amount"`
-	Type              string               `bson:"type"`
-	ExpirationDate    primitive.Timestamp  `bson:"expirationDate,omitempty"`
-	Status            string               `bson:"status,omitempty"`
-	CreatedAt         primitive.Timestamp  `bson:"_createdAt"`
+	FromBankAccountId  primitive.ObjectID   `bson:"fromBankAccountId"`
+	ToBankAccountId    primitive.ObjectID   `bson:"toBankAccountId"`
+	Amount             primitive.Decimal128 `bson:"amount"`
+	Type               string               `bson:"type"`
+	ExpirationDate     primitive.Timestamp  `bson:"expirationDate,omitempty"`
+	Status             string               `bson:"status,omitempty"`
+	Memo               string               `bson:"memo,omitempty"`
+	FromCategory       string               `bson:"fromCategory,omitempty"`
+	FromCategorySource string               `bson:"fromCategorySource,omitempty"`
+	ToCategory         string               `bson:"toCategory,omitempty"`
+	ToCategorySource   string               `bson:"toCategorySource,omitempty"`
+	CreatedAt          primitive.Timestamp  `bson:"_createdAt"`
 }
 
 type MongoTransactionOutput struct {
-	Id                primitive.ObjectID   `bson:"_id"`
-	FromBankAccountId primitive.ObjectID   `bson:"fromBankAccountId"`
-	ToBankAccountId   primitive.ObjectID   `bson:"toBankAccountId"`
-	Amount            primitive.Decimal128 `bson:"amount"`
-	Type              string               `bson:"type"`
-	ExpirationDate    primitive.Timestamp  `bson:"expirationDate,omitempty"`
-	Status            string               `bson:"status,omitempty"`
-	CreatedAt         primitive.Timestamp  `bson:"_createdAt"`
+	Id                 primitive.ObjectID   `bson:"_id"`
+	FromBankAccountId  primitive.ObjectID   `bson:"fromBankAccountId"`
+	ToBankAccountId    primitive.ObjectID   `bson:"toBankAccountId"`
+	Amount             primitive.Decimal128 `bson:"amount"`
+	Type               string               `bson:"type"`
+	ExpirationDate     primitive.Timestamp  `bson:"expirationDate,omitempty"`
+	Status             string               `bson:"status,omitempty"`
+	Memo               string               `bson:"memo,omitempty"`
+	FromCategory       string               `bson:"fromCategory,omitempty"`
+	FromCategorySource string               `bson:"fromCategorySource,omitempty"`
+	ToCategory         string               `bson:"toCategory,omitempty"`
+	ToCategorySource   string               `bson:"toCategorySource,omitempty"`
+	CreatedAt          primitive.Timestamp  `bson:"_createdAt"`
 }
 
 type MongoTransactionForBankAccountInput struct {
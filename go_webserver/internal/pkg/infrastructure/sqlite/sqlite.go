@@ -0,0 +1,266 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"net/url"
+)
+
+const (
+	DriverName = "sqlite"
+	// busyTimeoutMs is how long a connection waits for the write lock held by another connection before failing
+	busyTimeoutMs = 5000
+)
+
+// TxMode is the locking mode a transaction begins in, see https://www.sqlite.org/lang_transaction.html
+type TxMode string
+
+const (
+	// Deferred transactions take the write lock on their first write. A deferred transaction that read data changed
+	// by another transaction since fails to take the lock, and must be retried.
+	Deferred TxMode = "DEFERRED"
+	// Immediate transactions take the write lock when they begin, so that they never conflict with other writers
+	Immediate TxMode = "IMMEDIATE"
+)
+
+// TxOptions are the options a transaction begins with
+type TxOptions struct {
+	Mode TxMode
+	// Snapshot starts the read snapshot of the transaction when it begins rather than on its first read, so that it
+	// reads the data as of its beginning
+	Snapshot bool
+	// Synchronous is the synchronous setting of the transaction's connection, FULL syncs every commit to disk while
+	// NORMAL may lose the latest commits to a power loss, see https://www.sqlite.org/pragma.html#pragma_synchronous
+	Synchronous Synchronous
+}
+
+type Synchronous string
+
+const (
+	SynchronousNormal Synchronous = "NORMAL"
+	SynchronousFull   Synchronous = "FULL"
+)
+
+// Amounts are stored as decimal strings, which SQLite would otherwise compare as text and add up as inexact floating
+// point numbers. decimal_cmp and decimal_add compare and add them exactly.
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("decimal_cmp", 2, decimalFunction(
+		func(a decimal.Decimal, b decimal.Decimal) driver.Value {
+			return int64(a.Cmp(b))
+		}))
+	sqlite.MustRegisterDeterministicScalarFunction("decimal_add", 2, decimalFunction(
+		func(a decimal.Decimal, b decimal.Decimal) driver.Value {
+			return a.Add(b).String()
+		}))
+}
+
+func decimalFunction(
+	fn func(a decimal.Decimal, b decimal.Decimal) driver.Value,
+) func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	return func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		a, err := decimalFromValue(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := decimalFromValue(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return fn(a, b), nil
+	}
+}
+
+func decimalFromValue(value driver.Value) (decimal.Decimal, error) {
+	switch v := value.(type) {
+	case string:
+		return decimal.NewFromString(v)
+	case []byte:
+		return decimal.NewFromString(string(v))
+	case int64:
+		return decimal.NewFromInt(v), nil
+	default:
+		return decimal.Zero, fmt.Errorf("expected a decimal string, got %T", value)
+	}
+}
+
+// Open opens the SQLite database at the given path, creating it if it does not exist. The database is put in WAL
+// mode so that readers do not block the writer, and foreign keys are enforced.
+func Open(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeoutMs))
+	params.Add("_pragma", "journal_mode(WAL)")
+	db, err := sql.Open(DriverName, "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("error when opening SQLite database %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Querier runs statements, either directly on the database or within a transaction
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Tx is a transaction on a connection of its own. Unlike sql.Tx, it begins in the given locking mode.
+type Tx struct {
+	conn *sql.Conn
+	done bool
+}
+
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+func Begin(ctx context.Context, db *sql.DB, opts TxOptions) (*Tx, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error when getting a connection: %w", err)
+	}
+	tx := &Tx{conn: conn}
+	if err = tx.begin(ctx, opts); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("error when beginning %s transaction: %w", opts.Mode, err)
+	}
+	return tx, nil
+}
+
+func (tx *Tx) begin(ctx context.Context, opts TxOptions) error {
+	synchronous := opts.Synchronous
+	if synchronous == "" {
+		synchronous = SynchronousFull
+	}
+	if _, err := tx.conn.ExecContext(ctx, "PRAGMA synchronous = "+string(synchronous)); err != nil {
+		return err
+	}
+	if _, err := tx.conn.ExecContext(ctx, "BEGIN "+string(opts.Mode)); err != nil {
+		return err
+	}
+	if opts.Snapshot {
+		var ignored int
+		err := tx.conn.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_schema").Scan(&ignored)
+		if err != nil {
+			_, _ = tx.conn.ExecContext(context.Background(), "ROLLBACK")
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.conn.ExecContext(ctx, query, args...)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tx.conn.QueryContext(ctx, query, args...)
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tx.conn.QueryRowContext(ctx, query, args...)
+}
+
+// Commit commits the transaction and returns its connection to the pool. A commit that fails because another
+// connection holds a lock is rolled back, so that the transaction can be retried from the start.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	if _, err := tx.conn.ExecContext(context.Background(), "COMMIT"); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, ErrTxDone) {
+			return fmt.Errorf("error when committing transaction: %w, and rolling it back: %w", err, rollbackErr)
+		}
+		return fmt.Errorf("error when committing transaction: %w", err)
+	}
+	tx.done = true
+	return tx.conn.Close()
+}
+
+// Rollback rolls back the transaction and returns its connection to the pool. The rollback goes through even if the
+// context of the transaction has been cancelled, or the connection would hold on to its locks.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	_, err := tx.conn.ExecContext(context.Background(), "ROLLBACK")
+	closeErr := tx.conn.Close()
+	if err != nil {
+		return fmt.Errorf("error when rolling back transaction: %w", err)
+	}
+	return closeErr
+}
+
+type txKey struct{}
+
+// ContextWithTx returns a copy of the context carrying the transaction, which repositories then run statements in
+func ContextWithTx(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction carried by the context, or nil outside of transactions
+func TxFromContext(ctx context.Context) *Tx {
+	tx, _ := ctx.Value(txKey{}).(*Tx)
+	return tx
+}
+
+// Executor returns the transaction carried by the context, or the database outside of transactions
+func Executor(ctx context.Context, db *sql.DB) Querier {
+	if tx := TxFromContext(ctx); tx != nil {
+		return tx
+	}
+	return db
+}
+
+// Run runs fn in the transaction carried by the context. Outside of transactions fn runs in an immediate
+// transaction of its own, so that statements reading and then writing rows see no concurrent change in between.
+func Run(ctx context.Context, db *sql.DB, fn func(q Querier) error) error {
+	return runIn(ctx, db, TxOptions{Mode: Immediate}, fn)
+}
+
+// Read runs fn in the transaction carried by the context. Outside of transactions fn runs in a deferred transaction
+// of its own, so that all its statements read the same snapshot of the database.
+func Read(ctx context.Context, db *sql.DB, fn func(q Querier) error) error {
+	return runIn(ctx, db, TxOptions{Mode: Deferred}, fn)
+}
+
+func runIn(ctx context.Context, db *sql.DB, opts TxOptions, fn func(q Querier) error) error {
+	if tx := TxFromContext(ctx); tx != nil {
+		return fn(tx)
+	}
+	tx, err := Begin(ctx, db, opts)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// IsBusy reports whether the error is caused by a lock held by another connection, which makes it worth retrying the
+// transaction it failed
+func IsBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	primaryCode := sqliteErr.Code() & 0xff
+	return primaryCode == sqlite3.SQLITE_BUSY || primaryCode == sqlite3.SQLITE_LOCKED
+}
+
+// IsUniqueViolation reports whether the error is caused by a row duplicating the key of a unique index
+func IsUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE ||
+		sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
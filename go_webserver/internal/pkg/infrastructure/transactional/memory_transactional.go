@@ -0,0 +1,91 @@
+package transactional
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"webserver/internal/pkg/infrastructure/memory"
+	"webserver/internal/pkg/logging"
+)
+
+// MemoryTransactional runs transactions on an in-memory store. IsolationHigh transactions read a snapshot of the
+// store taken when they begin, like MongoDB snapshot reads. The store holds a single copy of the data, so the local
+// and majority read concerns of the lower isolation levels both read the latest committed documents. Durability
+// levels have no meaning for a store that is not persisted.
+type MemoryTransactional struct {
+	store  *memory.Store
+	logger *slog.Logger
+}
+
+func NewMemoryTransactional(store *memory.Store, logger *slog.Logger) *MemoryTransactional {
+	return &MemoryTransactional{
+		store:  store,
+		logger: logger,
+	}
+}
+
+func (m *MemoryTransactional) BeginTransaction(
+	ctx context.Context,
+	isolationLevel int,
+	durabilityLevel int,
+) (TransactionContext, error) {
+	tx := m.store.Begin(isolationLevel == IsolationHigh)
+	return memory.ContextWithTx(ctx, tx), nil
+}
+
+func (m *MemoryTransactional) Commit(ctx context.Context) error {
+	tx := memory.TxFromContext(ctx)
+	if tx == nil {
+		return errNoSession
+	}
+	return tx.Commit()
+}
+
+func (m *MemoryTransactional) Rollback(ctx context.Context) error {
+	tx := memory.TxFromContext(ctx)
+	if tx == nil {
+		return errNoSession
+	}
+	return tx.Rollback()
+}
+
+// WithTransaction retries the whole transaction when its commit conflicts with a concurrent transaction, like
+// MongoTransactional does on transient transaction errors. Retries stop once the context is done.
+func (m *MemoryTransactional) WithTransaction(
+	ctx context.Context,
+	opts TransactionOptions,
+	fn func(txnCtx TransactionContext) error,
+) error {
+	var err error
+	for attempt := 1; attempt <= maxTransactionAttempts; attempt++ {
+		err = m.runTransaction(ctx, opts, fn)
+		if err == nil || !errors.Is(err, memory.ErrWriteConflict) {
+			return err
+		}
+		m.logger.WarnContext(ctx, "Retrying in-memory transaction after write conflict", slog.Int("attempt", attempt),
+			logging.Err(err))
+		if waitErr := waitForRetry(ctx, attempt); waitErr != nil {
+			return fmt.Errorf("%w, last error: %w", waitErr, err)
+		}
+	}
+	return fmt.Errorf("in-memory transaction failed after %d attempts: %w", maxTransactionAttempts, err)
+}
+
+func (m *MemoryTransactional) runTransaction(
+	ctx context.Context,
+	opts TransactionOptions,
+	fn func(txnCtx TransactionContext) error,
+) error {
+	tx := m.store.Begin(opts.Isolation == IsolationHigh)
+	if err := fn(memory.ContextWithTx(ctx, tx)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			m.logger.ErrorContext(ctx, "Unable to roll back transaction", logging.Err(rollbackErr))
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error when committing in-memory transaction: %w", commitError{err})
+	}
+	return nil
+}
@@ -3,23 +3,47 @@ package transactional
 import (
 	"context"
 	"errors"
+	"fmt"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readconcern"
 	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"log/slog"
+	"math/rand"
+	"time"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
 )
 
+const (
+	transientTransactionLabel = "TransientTransactionError"
+	unknownCommitResultLabel  = "UnknownTransactionCommitResult"
+	// maxTransactionAttempts bounds the retries of callers whose context carries no deadline
+	maxTransactionAttempts = 10
+	maxCommitAttempts      = 3
+	retryBackoff           = 10 * time.Millisecond
+)
+
+var errNoSession = errors.New("no session found, please start a transaction before committing or rolling back")
+
+// MongoTransactional counts the transactions it begins, commits and aborts. Transactions whose commit fails are
+// counted as aborted, since the server does not keep them.
 type MongoTransactional struct {
 	client  *mongo.Client
-	session mongo.Session
+	metrics *metrics.Metrics
+	logger  *slog.Logger
 }
 
-func NewMongoTransactional(client *mongo.Client) *MongoTransactional {
+func NewMongoTransactional(client *mongo.Client, metrics *metrics.Metrics, logger *slog.Logger) *MongoTransactional {
 	return &MongoTransactional{
-		client: client,
+		client:  client,
+		metrics: metrics,
+		logger:  logger,
 	}
 }
 
+// BeginTransaction starts a session with a transaction and returns a context bound to it. The context must be passed
+// to Commit or Rollback, which end the session.
 func (m *MongoTransactional) BeginTransaction(
 	ctx context.Context,
 	readConcern int,
@@ -29,15 +53,16 @@ func (m *MongoTransactional) BeginTransaction(
 	if err != nil {
 		return nil, err
 	}
-	m.session = session
 
 	determinedReadConcern := determineReadConcern(readConcern)
 	determinedWriteConcern := determineWriteConcern(writeConcern)
 	txnOpts := options.Transaction().SetReadConcern(determinedReadConcern).SetWriteConcern(determinedWriteConcern)
 	err = session.StartTransaction(txnOpts)
 	if err != nil {
+		session.EndSession(ctx)
 		return nil, err
 	}
+	m.metrics.CountMongoTransaction(metrics.TransactionBegin)
 
 	txnCtx := mongo.NewSessionContext(ctx, session)
 	return txnCtx, nil
@@ -68,19 +93,116 @@ func determineWriteConcern(writeConcern int) *writeconcern.WriteConcern {
 }
 
 func (m *MongoTransactional) Commit(ctx context.Context) error {
-	if m.session != nil {
-		err := m.session.CommitTransaction(ctx)
-		m.session.EndSession(ctx)
-		return err
+	session := mongo.SessionFromContext(ctx)
+	if session == nil {
+		return errNoSession
 	}
-	return errors.New("no session found, please start a transaction before committing")
+	err := session.CommitTransaction(ctx)
+	session.EndSession(ctx)
+	m.countCommit(err)
+	return err
 }
 
 func (m *MongoTransactional) Rollback(ctx context.Context) error {
-	if m.session != nil {
-		err := m.session.AbortTransaction(ctx)
-		m.session.EndSession(ctx)
+	session := mongo.SessionFromContext(ctx)
+	if session == nil {
+		return errNoSession
+	}
+	err := session.AbortTransaction(ctx)
+	session.EndSession(ctx)
+	m.metrics.CountMongoTransaction(metrics.TransactionAbort)
+	return err
+}
+
+// WithTransaction follows the retry guidance of the MongoDB drivers: the whole transaction is retried when it fails
+// with a TransientTransactionError, such as a write conflict with a concurrent transaction, and the commit alone is
+// retried when its result is unknown. Retries stop once the context is done.
+func (m *MongoTransactional) WithTransaction(
+	ctx context.Context,
+	opts TransactionOptions,
+	fn func(txnCtx TransactionContext) error,
+) error {
+	var err error
+	for attempt := 1; attempt <= maxTransactionAttempts; attempt++ {
+		err = m.runTransaction(ctx, opts, fn)
+		if err == nil || !hasErrorLabel(err, transientTransactionLabel) {
+			return err
+		}
+		m.logger.WarnContext(ctx, "Retrying database transaction after transient error", slog.Int("attempt", attempt),
+			logging.Err(err))
+		if waitErr := waitForRetry(ctx, attempt); waitErr != nil {
+			return fmt.Errorf("%w, last error: %w", waitErr, err)
+		}
+	}
+	return fmt.Errorf("database transaction failed after %d attempts: %w", maxTransactionAttempts, err)
+}
+
+func (m *MongoTransactional) runTransaction(
+	ctx context.Context,
+	opts TransactionOptions,
+	fn func(txnCtx TransactionContext) error,
+) error {
+	session, err := m.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("error when starting database session: %w", err)
+	}
+	defer session.EndSession(context.WithoutCancel(ctx))
+
+	txnOpts := options.Transaction().
+		SetReadConcern(determineReadConcern(opts.Isolation)).
+		SetWriteConcern(determineWriteConcern(opts.Durability))
+	if err = session.StartTransaction(txnOpts); err != nil {
+		return fmt.Errorf("error when starting database transaction: %w", err)
+	}
+	m.metrics.CountMongoTransaction(metrics.TransactionBegin)
+	txnCtx := mongo.NewSessionContext(ctx, session)
+
+	if err = fn(txnCtx); err != nil {
+		// The abort must go through even if the context has been cancelled, or the transaction holds its locks
+		// until it times out on the server
+		if abortErr := session.AbortTransaction(context.WithoutCancel(ctx)); abortErr != nil {
+			m.logger.ErrorContext(ctx, "Unable to roll back transaction", logging.Err(abortErr))
+		}
+		m.metrics.CountMongoTransaction(metrics.TransactionAbort)
 		return err
 	}
-	return errors.New("no session found, please start a transaction before rolling back")
+
+	for commitAttempt := 1; ; commitAttempt++ {
+		err = session.CommitTransaction(txnCtx)
+		if err == nil {
+			m.countCommit(nil)
+			return nil
+		}
+		if !hasErrorLabel(err, unknownCommitResultLabel) || commitAttempt == maxCommitAttempts || ctx.Err() != nil {
+			m.countCommit(err)
+			return fmt.Errorf("error when committing database transaction: %w", commitError{err})
+		}
+		m.logger.WarnContext(ctx, "Retrying commit of database transaction with unknown result", logging.Err(err))
+	}
+}
+
+func (m *MongoTransactional) countCommit(err error) {
+	if err != nil {
+		m.metrics.CountMongoTransaction(metrics.TransactionAbort)
+		return
+	}
+	m.metrics.CountMongoTransaction(metrics.TransactionCommit)
+}
+
+func hasErrorLabel(err error, label string) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorLabel(label)
+}
+
+// waitForRetry backs off with jitter so that conflicting transactions do not keep colliding
+func waitForRetry(ctx context.Context, attempt int) error {
+	backoff := time.Duration(attempt)*retryBackoff + time.Duration(rand.Int63n(int64(retryBackoff)))
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
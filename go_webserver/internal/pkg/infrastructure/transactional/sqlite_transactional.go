@@ -0,0 +1,114 @@
+package transactional
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/logging"
+)
+
+// SQLiteTransactional runs transactions on a SQLite database in WAL mode, where every transaction reads a single
+// snapshot of the database and writers take turns. IsolationLow transactions begin deferred, taking the write lock on
+// their first write. IsolationMedium transactions begin immediate, taking the write lock when they begin so that
+// their reads are never made stale by another writer. IsolationHigh transactions begin deferred with their snapshot
+// taken when they begin, like MongoDB snapshot reads. DurabilityLow commits are not synced to disk right away, and
+// may be lost to a power loss but not to a crash of the webserver.
+type SQLiteTransactional struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewSQLiteTransactional(db *sql.DB, logger *slog.Logger) *SQLiteTransactional {
+	return &SQLiteTransactional{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (s *SQLiteTransactional) BeginTransaction(
+	ctx context.Context,
+	isolationLevel int,
+	durabilityLevel int,
+) (TransactionContext, error) {
+	tx, err := sqlite.Begin(ctx, s.db, determineSQLiteTxOptions(isolationLevel, durabilityLevel))
+	if err != nil {
+		return nil, err
+	}
+	return sqlite.ContextWithTx(ctx, tx), nil
+}
+
+func determineSQLiteTxOptions(isolationLevel int, durabilityLevel int) sqlite.TxOptions {
+	opts := sqlite.TxOptions{Mode: sqlite.Deferred, Synchronous: sqlite.SynchronousFull}
+	switch isolationLevel {
+	case IsolationMedium:
+		opts.Mode = sqlite.Immediate
+	case IsolationHigh:
+		opts.Snapshot = true
+	}
+	if durabilityLevel == DurabilityLow {
+		opts.Synchronous = sqlite.SynchronousNormal
+	}
+	return opts
+}
+
+func (s *SQLiteTransactional) Commit(ctx context.Context) error {
+	tx := sqlite.TxFromContext(ctx)
+	if tx == nil {
+		return errNoSession
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteTransactional) Rollback(ctx context.Context) error {
+	tx := sqlite.TxFromContext(ctx)
+	if tx == nil {
+		return errNoSession
+	}
+	return tx.Rollback()
+}
+
+// WithTransaction retries the whole transaction when it fails on a lock held by another connection, which happens
+// when a deferred transaction writes after another writer committed changes to the snapshot it read, like
+// MongoTransactional does on transient transaction errors. Retries stop once the context is done.
+func (s *SQLiteTransactional) WithTransaction(
+	ctx context.Context,
+	opts TransactionOptions,
+	fn func(txnCtx TransactionContext) error,
+) error {
+	var err error
+	for attempt := 1; attempt <= maxTransactionAttempts; attempt++ {
+		err = s.runTransaction(ctx, opts, fn)
+		if err == nil || !sqlite.IsBusy(err) {
+			return err
+		}
+		s.logger.WarnContext(ctx, "Retrying SQLite transaction after lock conflict", slog.Int("attempt", attempt),
+			logging.Err(err))
+		if waitErr := waitForRetry(ctx, attempt); waitErr != nil {
+			return fmt.Errorf("%w, last error: %w", waitErr, err)
+		}
+	}
+	return fmt.Errorf("SQLite transaction failed after %d attempts: %w", maxTransactionAttempts, err)
+}
+
+func (s *SQLiteTransactional) runTransaction(
+	ctx context.Context,
+	opts TransactionOptions,
+	fn func(txnCtx TransactionContext) error,
+) error {
+	tx, err := sqlite.Begin(ctx, s.db, determineSQLiteTxOptions(opts.Isolation, opts.Durability))
+	if err != nil {
+		return err
+	}
+	if err = fn(sqlite.ContextWithTx(ctx, tx)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			s.logger.ErrorContext(ctx, "Unable to roll back transaction", logging.Err(rollbackErr))
+		}
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error when committing SQLite transaction: %w", commitError{err})
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package transactional
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"webserver/internal/pkg/tracing"
+)
+
+// TracedTransactional records a span for every transaction it begins, commits or rolls back. WithTransaction records
+// a single span around all of its attempts, with a begin event when an attempt starts and a commit or rollback event
+// when fn returns, so that the spans of the repository calls made by fn are its children.
+type TracedTransactional struct {
+	next   Transactional
+	tracer trace.Tracer
+}
+
+func NewTracedTransactional(next Transactional, tracer trace.Tracer) *TracedTransactional {
+	return &TracedTransactional{next: next, tracer: tracer}
+}
+
+// BeginTransaction returns the transaction context of the wrapped Transactional as is, so that the spans of the calls
+// made in the transaction are not children of the ended begin span
+func (t *TracedTransactional) BeginTransaction(
+	ctx context.Context,
+	isolationLevel int,
+	durabilityLevel int,
+) (_ TransactionContext, err error) {
+	_, span := t.tracer.Start(ctx, "Transactional.BeginTransaction", trace.WithAttributes(
+		attribute.Int("transaction.isolation", isolationLevel),
+		attribute.Int("transaction.durability", durabilityLevel),
+	))
+	defer func() { tracing.End(span, err) }()
+	return t.next.BeginTransaction(ctx, isolationLevel, durabilityLevel)
+}
+
+func (t *TracedTransactional) Commit(ctx context.Context) (err error) {
+	ctx, span := t.tracer.Start(ctx, "Transactional.Commit")
+	defer func() { tracing.End(span, err) }()
+	return t.next.Commit(ctx)
+}
+
+func (t *TracedTransactional) Rollback(ctx context.Context) (err error) {
+	ctx, span := t.tracer.Start(ctx, "Transactional.Rollback")
+	defer func() { tracing.End(span, err) }()
+	return t.next.Rollback(ctx)
+}
+
+func (t *TracedTransactional) WithTransaction(
+	ctx context.Context,
+	opts TransactionOptions,
+	fn func(txnCtx TransactionContext) error,
+) (err error) {
+	ctx, span := t.tracer.Start(ctx, "Transactional.WithTransaction", trace.WithAttributes(
+		attribute.Int("transaction.isolation", opts.Isolation),
+		attribute.Int("transaction.durability", opts.Durability),
+	))
+	defer func() { tracing.End(span, err) }()
+	attempt := 0
+	return t.next.WithTransaction(ctx, opts, func(txnCtx TransactionContext) error {
+		attempt++
+		span.AddEvent("begin", trace.WithAttributes(attribute.Int("transaction.attempt", attempt)))
+		if err := fn(txnCtx); err != nil {
+			span.AddEvent("rollback", trace.WithAttributes(attribute.Int("transaction.attempt", attempt)))
+			return err
+		}
+		span.AddEvent("commit", trace.WithAttributes(attribute.Int("transaction.attempt", attempt)))
+		return nil
+	})
+}
@@ -1,6 +1,9 @@
 package transactional
 
-import "context"
+import (
+	"context"
+	"errors"
+)
 
 const (
 	IsolationLow int = iota
@@ -13,12 +16,40 @@ const (
 	DurabilityHigh
 )
 
+type TransactionOptions struct {
+	Isolation  int
+	Durability int
+}
+
 type Transactional interface {
 	BeginTransaction(ctx context.Context, isolationLevel int, durabilityLevel int) (TransactionContext, error)
 	Commit(ctx context.Context) error
 	Rollback(ctx context.Context) error
+	// WithTransaction runs fn in a database transaction that is committed if fn succeeds and rolled back otherwise.
+	// fn may be run more than once when the transaction fails for transient reasons, so it must not have side
+	// effects outside the transaction.
+	WithTransaction(ctx context.Context, opts TransactionOptions, fn func(txnCtx TransactionContext) error) error
+}
+
+// ErrCommitFailed is matched by the errors of WithTransaction when fn succeeded but its transaction could not be
+// committed, as opposed to fn failing and its transaction being rolled back
+var ErrCommitFailed = errors.New("transaction could not be committed")
+
+// commitError marks the error of a failed commit as ErrCommitFailed while keeping its message
+type commitError struct {
+	error
+}
+
+func (e commitError) Is(target error) bool {
+	return target == ErrCommitFailed
+}
+
+func (e commitError) Unwrap() error {
+	return e.error
 }
 
+// TransactionContext carries the database transaction it was returned for, so that concurrent callers each commit
+// and roll back their own transaction
 type TransactionContext interface {
 	context.Context
 }
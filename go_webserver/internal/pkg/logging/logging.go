@@ -0,0 +1,196 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log/slog"
+	"strings"
+	"webserver/internal/pkg/requestcontext"
+)
+
+// Log formats
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// Keys used across the webserver, so that records about the same entities can be queried the same way
+const (
+	RequestIdKey         = "request_id"
+	TraceIdKey           = "trace_id"
+	SpanIdKey            = "span_id"
+	AccountIdKey         = "account_id"
+	BankAccountIdKey     = "bank_account_id"
+	FromBankAccountIdKey = "from_bank_account_id"
+	ToBankAccountIdKey   = "to_bank_account_id"
+	TransactionIdKey     = "transaction_id"
+	PayeeIdKey           = "payee_id"
+	CategoryRuleIdKey    = "category_rule_id"
+	IdempotencyKeyKey    = "idempotency_key"
+	UsernameKey          = "username"
+	AccountNumberKey     = "account_number"
+	AmountKey            = "amount"
+	RouteKey             = "route"
+	MethodKey            = "method"
+	PathKey              = "path"
+	StatusKey            = "status"
+	LatencyKey           = "latency"
+	ErrorKey             = "error"
+)
+
+const redacted = "[REDACTED]"
+
+// secretKeys are never logged, whatever their value
+var secretKeys = map[string]bool{
+	"password":      true,
+	"new_password":  true,
+	"secret":        true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"authorization": true,
+}
+
+type Options struct {
+	Level  slog.Level
+	Format string
+}
+
+// New creates a logger writing records in the given format, with the ID of the request a record is logged for taken
+// from its context and sensitive attributes redacted
+func New(w io.Writer, opts Options) (*slog.Logger, error) {
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level, ReplaceAttr: redact}
+	var handler slog.Handler
+	switch opts.Format {
+	case TextFormat:
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case JSONFormat:
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected text or json", opts.Format)
+	}
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// ParseLevel parses one of debug, info, warn or error, optionally offset like info+2
+func ParseLevel(raw string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(raw)); err != nil {
+		return 0, err
+	}
+	return level, nil
+}
+
+// FromEnv creates a logger for the command line tools, which take the level and format from LOG_LEVEL and LOG_FORMAT
+// like the webserver does and default to info and text
+func FromEnv(w io.Writer, lookupEnv func(string) (string, bool)) (*slog.Logger, error) {
+	opts := Options{Level: slog.LevelInfo, Format: TextFormat}
+	if raw, present := lookupEnv("LOG_LEVEL"); present && raw != "" {
+		level, err := ParseLevel(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LOG_LEVEL %q: %w", raw, err)
+		}
+		opts.Level = level
+	}
+	if raw, present := lookupEnv("LOG_FORMAT"); present && raw != "" {
+		opts.Format = raw
+	}
+	return New(w, opts)
+}
+
+// Discard returns a logger dropping every record, for code that has nothing to log to
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+type loggerKey struct{}
+
+// ContextWithLogger returns a copy of the context carrying the logger of the request it serves
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger of the request the context serves, or the default logger outside of requests
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+func Err(err error) slog.Attr {
+	return slog.Any(ErrorKey, err)
+}
+
+func AccountId(accountId string) slog.Attr {
+	return slog.String(AccountIdKey, accountId)
+}
+
+func BankAccountId(bankAccountId string) slog.Attr {
+	return slog.String(BankAccountIdKey, bankAccountId)
+}
+
+func FromBankAccountId(bankAccountId string) slog.Attr {
+	return slog.String(FromBankAccountIdKey, bankAccountId)
+}
+
+func ToBankAccountId(bankAccountId string) slog.Attr {
+	return slog.String(ToBankAccountIdKey, bankAccountId)
+}
+
+func TransactionId(transactionId string) slog.Attr {
+	return slog.String(TransactionIdKey, transactionId)
+}
+
+// AccountNumber is masked down to its last four digits when logged
+func AccountNumber(accountNumber string) slog.Attr {
+	return slog.String(AccountNumberKey, accountNumber)
+}
+
+// MaskAccountNumber keeps the last four characters of an account number, which is enough to tell accounts apart
+func MaskAccountNumber(accountNumber string) string {
+	if len(accountNumber) <= 4 {
+		return strings.Repeat("*", len(accountNumber))
+	}
+	return strings.Repeat("*", len(accountNumber)-4) + accountNumber[len(accountNumber)-4:]
+}
+
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+	switch {
+	case secretKeys[key]:
+		return slog.String(attr.Key, redacted)
+	case key == AccountNumberKey && attr.Value.Kind() == slog.KindString:
+		return slog.String(attr.Key, MaskAccountNumber(attr.Value.String()))
+	}
+	return attr
+}
+
+// contextHandler adds the ID of the request a record is logged for, so that every record of a request can be found,
+// and the IDs of the trace and span it is logged in, so that records can be found from traces
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if requestId := requestcontext.RequestIdFromContext(ctx); requestId != "" {
+			record.AddAttrs(slog.String(RequestIdKey, requestId))
+		}
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			record.AddAttrs(slog.String(TraceIdKey, spanContext.TraceID().String()),
+				slog.String(SpanIdKey, spanContext.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"testing"
+	"webserver/internal/pkg/requestcontext"
+)
+
+func newTestLogger(t *testing.T, format string) (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Options{Level: slog.LevelInfo, Format: format})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logger, &buf
+}
+
+func decodeRecord(t *testing.T, buf *bytes.Buffer) map[string]any {
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+func TestNew(t *testing.T) {
+	t.Run("Writes records in the chosen format", func(t *testing.T) {
+		logger, buf := newTestLogger(t, JSONFormat)
+		logger.Info("Created transaction", TransactionId("transactionId1"))
+		record := decodeRecord(t, buf)
+		assert.Equal(t, "Created transaction", record["msg"])
+		assert.Equal(t, "transactionId1", record[TransactionIdKey])
+
+		logger, buf = newTestLogger(t, TextFormat)
+		logger.Info("Created transaction", TransactionId("transactionId1"))
+		assert.Contains(t, buf.String(), `msg="Created transaction" transaction_id=transactionId1`)
+	})
+
+	t.Run("Rejects unknown formats", func(t *testing.T) {
+		_, err := New(&bytes.Buffer{}, Options{Format: "xml"})
+		assert.ErrorContains(t, err, `unknown log format "xml"`)
+	})
+
+	t.Run("Drops records below the level", func(t *testing.T) {
+		logger, buf := newTestLogger(t, JSONFormat)
+		logger.Debug("Read bank account")
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("Adds the request ID from the context", func(t *testing.T) {
+		logger, buf := newTestLogger(t, JSONFormat)
+		ctx := requestcontext.WithRequestId(context.Background(), "requestId1")
+		logger.With(BankAccountId("bankAccountId1")).InfoContext(ctx, "Deducted balance")
+		record := decodeRecord(t, buf)
+		assert.Equal(t, "requestId1", record[RequestIdKey])
+		assert.Equal(t, "bankAccountId1", record[BankAccountIdKey])
+	})
+
+	t.Run("Adds the trace and span IDs from the context", func(t *testing.T) {
+		logger, buf := newTestLogger(t, JSONFormat)
+		traceId, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		spanId, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+		spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceId, SpanID: spanId})
+		logger.InfoContext(trace.ContextWithSpanContext(context.Background(), spanContext), "Deducted balance")
+		record := decodeRecord(t, buf)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", record[TraceIdKey])
+		assert.Equal(t, "00f067aa0ba902b7", record[SpanIdKey])
+	})
+
+	t.Run("Redacts secrets and masks account numbers", func(t *testing.T) {
+		logger, buf := newTestLogger(t, JSONFormat)
+		logger.Info("Registered account",
+			slog.String("password", "hunter2"),
+			slog.Group("request", slog.String("Authorization", "Bearer token1")),
+			AccountNumber("1234567890"))
+		record := decodeRecord(t, buf)
+		assert.Equal(t, "[REDACTED]", record["password"])
+		assert.Equal(t, map[string]any{"Authorization": "[REDACTED]"}, record["request"])
+		assert.Equal(t, "******7890", record[AccountNumberKey])
+		assert.NotContains(t, buf.String(), "hunter2")
+	})
+}
+
+func TestFromEnv(t *testing.T) {
+	lookupEnv := func(values map[string]string) func(string) (string, bool) {
+		return func(name string) (string, bool) {
+			value, present := values[name]
+			return value, present
+		}
+	}
+
+	t.Run("Reads the level and format", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, err := FromEnv(&buf, lookupEnv(map[string]string{"LOG_LEVEL": "debug", "LOG_FORMAT": "json"}))
+		assert.Nil(t, err)
+		logger.Debug("Applied migration")
+		assert.Equal(t, "Applied migration", decodeRecord(t, &buf)["msg"])
+	})
+
+	t.Run("Rejects unknown levels", func(t *testing.T) {
+		_, err := FromEnv(&bytes.Buffer{}, lookupEnv(map[string]string{"LOG_LEVEL": "loud"}))
+		assert.ErrorContains(t, err, "LOG_LEVEL")
+	})
+}
+
+func TestFromContext(t *testing.T) {
+	logger := Discard()
+	assert.Same(t, logger, FromContext(ContextWithLogger(context.Background(), logger)))
+	assert.Same(t, slog.Default(), FromContext(context.Background()))
+}
+
+func TestMaskAccountNumber(t *testing.T) {
+	assert.Equal(t, "******7890", MaskAccountNumber("1234567890"))
+	assert.Equal(t, "****", MaskAccountNumber("1234"))
+	assert.Equal(t, "", MaskAccountNumber(""))
+}
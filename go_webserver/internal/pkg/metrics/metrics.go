@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "wallet"
+
+// Outcomes of transfers made through AddTransaction
+const (
+	TransferCommitted           = "committed"
+	TransferReplayed            = "replayed"
+	TransferInsufficientBalance = "insufficient_balance"
+	TransferRollback            = "rollback"
+	TransferCommitError         = "commit_error"
+)
+
+// Events in the life of a MongoDB transaction
+const (
+	TransactionBegin  = "begin"
+	TransactionCommit = "commit"
+	TransactionAbort  = "abort"
+)
+
+// Metrics holds the collectors of the webserver, registered on a registry of their own so that separate instances,
+// like the ones of tests, do not collide
+type Metrics struct {
+	registry            *prometheus.Registry
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+	transfers           *prometheus.CounterVec
+	mongoOperations     *prometheus.HistogramVec
+	mongoTransactions   *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Requests served, by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve requests, by route and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		transfers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfers_total",
+			Help:      "Transfers attempted, by outcome.",
+		}, []string{"outcome"}),
+		mongoOperations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "mongo_operation_duration_seconds",
+			Help:      "Time taken by repository operations on MongoDB, by collection and operation.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"collection", "operation"}),
+		mongoTransactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "mongo_transactions_total",
+			Help:      "MongoDB transactions begun, committed and aborted.",
+		}, []string{"event"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.transfers,
+		m.mongoOperations,
+		m.mongoTransactions,
+	)
+	return m
+}
+
+// Handler serves the collected metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Registry lets collectors outside of this package be registered next to the ones of the webserver
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// ObserveRequest records a request served on the named route. Requests that match no route are not observed, so
+// that the routes label only holds the routes of the API.
+func (m *Metrics) ObserveRequest(route string, method string, status int, duration time.Duration) {
+	m.httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	m.httpRequestDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+func (m *Metrics) CountTransfer(outcome string) {
+	m.transfers.WithLabelValues(outcome).Inc()
+}
+
+// ObserveMongoOperation records the time since the operation started, and is meant to be deferred at its start:
+//
+//	defer ar.metrics.ObserveMongoOperation(ar.col.Name(), "AddBalance", time.Now())
+func (m *Metrics) ObserveMongoOperation(collection string, operation string, start time.Time) {
+	m.mongoOperations.WithLabelValues(collection, operation).Observe(time.Since(start).Seconds())
+}
+
+func (m *Metrics) CountMongoTransaction(event string) {
+	m.mongoTransactions.WithLabelValues(event).Inc()
+}
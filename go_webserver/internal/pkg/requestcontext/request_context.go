@@ -0,0 +1,16 @@
+package requestcontext
+
+import "context"
+
+type requestIdKey struct{}
+
+// WithRequestId returns a copy of the context carrying the ID identifying the request it serves
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+// RequestIdFromContext returns the ID of the request the context serves, or an empty string outside of requests
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
+}
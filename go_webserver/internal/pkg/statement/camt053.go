@@ -0,0 +1,177 @@
+package statement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// Elements of an ISO 20022 camt.053.001.02 bank to customer statement, in the order the schema requires
+type (
+	camtDocument struct {
+		XMLName   xml.Name          `xml:"Document"`
+		Namespace string            `xml:"xmlns,attr"`
+		Statement camtBkToCstmrStmt `xml:"BkToCstmrStmt"`
+	}
+	camtBkToCstmrStmt struct {
+		GrpHdr camtGrpHdr `xml:"GrpHdr"`
+		Stmt   camtStmt   `xml:"Stmt"`
+	}
+	camtGrpHdr struct {
+		MsgId   string `xml:"MsgId"`
+		CreDtTm string `xml:"CreDtTm"`
+	}
+	camtStmt struct {
+		Id      string     `xml:"Id"`
+		CreDtTm string     `xml:"CreDtTm"`
+		FrToDt  camtFrToDt `xml:"FrToDt"`
+		Acct    camtAcct   `xml:"Acct"`
+		Bal     []camtBal  `xml:"Bal"`
+		Ntry    []camtNtry `xml:"Ntry"`
+	}
+	camtFrToDt struct {
+		FrDtTm string `xml:"FrDtTm"`
+		ToDtTm string `xml:"ToDtTm"`
+	}
+	camtAcct struct {
+		Id   camtAcctId `xml:"Id"`
+		Ccy  string     `xml:"Ccy"`
+		Ownr camtParty  `xml:"Ownr"`
+		Svcr string     `xml:"Svcr>FinInstnId>Othr>Id"`
+	}
+	camtAcctId struct {
+		Othr string `xml:"Othr>Id"`
+	}
+	camtParty struct {
+		Nm string `xml:"Nm"`
+	}
+	camtAmt struct {
+		Ccy   string `xml:"Ccy,attr"`
+		Value string `xml:",chardata"`
+	}
+	camtBal struct {
+		Tp        string  `xml:"Tp>CdOrPrtry>Cd"`
+		Amt       camtAmt `xml:"Amt"`
+		CdtDbtInd string  `xml:"CdtDbtInd"`
+		Dt        string  `xml:"Dt>DtTm"`
+	}
+	camtNtry struct {
+		NtryRef     string     `xml:"NtryRef"`
+		Amt         camtAmt    `xml:"Amt"`
+		CdtDbtInd   string     `xml:"CdtDbtInd"`
+		Sts         string     `xml:"Sts"`
+		BookgDt     string     `xml:"BookgDt>DtTm"`
+		ValDt       string     `xml:"ValDt>DtTm"`
+		AcctSvcrRef string     `xml:"AcctSvcrRef"`
+		BkTxCd      string     `xml:"BkTxCd>Prtry>Cd"`
+		TxDtls      camtTxDtls `xml:"NtryDtls>TxDtls"`
+	}
+	camtTxDtls struct {
+		AcctSvcrRef string         `xml:"Refs>AcctSvcrRef"`
+		RltdPties   *camtRltdPties `xml:"RltdPties,omitempty"`
+		RmtInf      string         `xml:"RmtInf>Ustrd"`
+	}
+	camtRltdPties struct {
+		Dbtr     *camtParty  `xml:"Dbtr,omitempty"`
+		DbtrAcct *camtAcctId `xml:"DbtrAcct>Id,omitempty"`
+		Cdtr     *camtParty  `xml:"Cdtr,omitempty"`
+		CdtrAcct *camtAcctId `xml:"CdtrAcct>Id,omitempty"`
+	}
+)
+
+// writeCamt053 writes an ISO 20022 camt.053.001.02 statement, with the opening and closing available balances as
+// its OPBD and CLBD balances and one booked entry per transaction
+func writeCamt053(w io.Writer, s *model.StatementOutput, opts Options) error {
+	id := fmt.Sprintf("%s-%s-%s", s.AccountNumber, s.FromTime.UTC().Format("20060102"),
+		s.ToTime.UTC().Format("20060102"))
+	entries := make([]camtNtry, len(s.Entries))
+	for i := range s.Entries {
+		entry := &s.Entries[i]
+		entries[i] = camtNtry{
+			NtryRef:     entry.Id,
+			Amt:         camtAmt{Ccy: opts.Currency, Value: entry.Amount.StringFixed(2)},
+			CdtDbtInd:   "CRDT",
+			Sts:         "BOOK",
+			BookgDt:     camtTime(entry.CreatedAt),
+			ValDt:       camtTime(entry.CreatedAt),
+			AcctSvcrRef: entry.Id,
+			BkTxCd:      "TRANSFER",
+			TxDtls: camtTxDtls{
+				AcctSvcrRef: entry.Id,
+				RltdPties:   relatedParties(entry),
+				RmtInf:      description(entry),
+			},
+		}
+		if outgoing(&entry.BankAccountTransactionOutput) {
+			entries[i].CdtDbtInd = "DBIT"
+		}
+	}
+	document := camtDocument{
+		Namespace: camt053Namespace,
+		Statement: camtBkToCstmrStmt{
+			GrpHdr: camtGrpHdr{MsgId: id, CreDtTm: camtTime(s.CreatedAt)},
+			Stmt: camtStmt{
+				Id:      id,
+				CreDtTm: camtTime(s.CreatedAt),
+				FrToDt:  camtFrToDt{FrDtTm: camtTime(s.FromTime), ToDtTm: camtTime(s.ToTime)},
+				Acct: camtAcct{
+					Id:   camtAcctId{Othr: s.AccountNumber},
+					Ccy:  opts.Currency,
+					Ownr: camtParty{Nm: s.AccountHolder},
+					Svcr: opts.BankId,
+				},
+				Bal: []camtBal{
+					camtBalance("OPBD", s.OpeningAvailableBalance, s.FromTime, opts),
+					camtBalance("CLBD", s.ClosingAvailableBalance, s.ToTime, opts),
+				},
+				Ntry: entries,
+			},
+		},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("error when writing camt.053 header: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("error when writing camt.053 statement: %w", err)
+	}
+	return nil
+}
+
+// relatedParties names the counterparty of the entry as its creditor when money left the bank account of the
+// statement, and as its debtor otherwise
+func relatedParties(entry *model.StatementEntry) *camtRltdPties {
+	if entry.CounterpartyAccountNumber == "" {
+		return nil
+	}
+	party := &camtParty{Nm: entry.CounterpartyHolder}
+	account := &camtAcctId{Othr: entry.CounterpartyAccountNumber}
+	if outgoing(&entry.BankAccountTransactionOutput) {
+		return &camtRltdPties{Cdtr: party, CdtrAcct: account}
+	}
+	return &camtRltdPties{Dbtr: party, DbtrAcct: account}
+}
+
+func camtBalance(code string, balance decimal.Decimal, asOf time.Time, opts Options) camtBal {
+	indicator := "CRDT"
+	if balance.IsNegative() {
+		indicator = "DBIT"
+	}
+	return camtBal{
+		Tp:        code,
+		Amt:       camtAmt{Ccy: opts.Currency, Value: balance.Abs().StringFixed(2)},
+		CdtDbtInd: indicator,
+		Dt:        camtTime(asOf),
+	}
+}
+
+func camtTime(t time.Time) string {
+	return utc(t).Format("2006-01-02T15:04:05Z")
+}
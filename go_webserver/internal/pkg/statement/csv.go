@@ -0,0 +1,52 @@
+package statement
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+var csvHeader = []string{
+	"date", "description", "transactionId", "counterpartyAccountNumber", "counterpartyName", "amount", "balance",
+}
+
+// writeCSV writes one row per entry with the available balance after it, between rows holding the opening and the
+// closing balance, so that spreadsheets can check the running balance
+func writeCSV(w io.Writer, s *model.StatementOutput, _ Options) error {
+	writer := csv.NewWriter(w)
+	rows := [][]string{csvHeader, {
+		utc(s.FromTime).Format(time.RFC3339), "Opening balance", "", "", "", "", s.OpeningAvailableBalance.StringFixed(2),
+	}}
+	balance := s.OpeningAvailableBalance
+	for i := range s.Entries {
+		entry := &s.Entries[i]
+		balance = balance.Add(change(&entry.BankAccountTransactionOutput))
+		rows = append(rows, []string{
+			utc(entry.CreatedAt).Format(time.RFC3339),
+			description(entry),
+			entry.Id,
+			entry.CounterpartyAccountNumber,
+			spreadsheetSafe(entry.CounterpartyHolder),
+			signedAmount(entry),
+			balance.StringFixed(2),
+		})
+	}
+	rows = append(rows, []string{
+		utc(s.ToTime).Format(time.RFC3339), "Closing balance", "", "", "", "", s.ClosingAvailableBalance.StringFixed(2),
+	})
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("error when writing CSV statement: %w", err)
+	}
+	return nil
+}
+
+// spreadsheetSafe keeps spreadsheets from evaluating text chosen by account holders, like their names, as formulas
+func spreadsheetSafe(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
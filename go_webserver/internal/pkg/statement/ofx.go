@@ -0,0 +1,154 @@
+package statement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+const (
+	ofxHeader = `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+	// ofxMaxNameLength bounds the NAME of a transaction, which OFX limits to 32 characters
+	ofxMaxNameLength = 32
+)
+
+// Elements of an OFX 2.2 bank statement response, in the order the specification requires
+type (
+	ofxDocument struct {
+		XMLName xml.Name     `xml:"OFX"`
+		SignOn  ofxSignOn    `xml:"SIGNONMSGSRSV1>SONRS"`
+		Bank    ofxStmtTrnRs `xml:"BANKMSGSRSV1>STMTTRNRS"`
+	}
+	ofxStatus struct {
+		Code     int    `xml:"CODE"`
+		Severity string `xml:"SEVERITY"`
+	}
+	ofxSignOn struct {
+		Status   ofxStatus `xml:"STATUS"`
+		DtServer string    `xml:"DTSERVER"`
+		Language string    `xml:"LANGUAGE"`
+	}
+	ofxStmtTrnRs struct {
+		TrnUid string    `xml:"TRNUID"`
+		Status ofxStatus `xml:"STATUS"`
+		StmtRs ofxStmtRs `xml:"STMTRS"`
+	}
+	ofxStmtRs struct {
+		CurDef       string          `xml:"CURDEF"`
+		BankAcctFrom ofxBankAcct     `xml:"BANKACCTFROM"`
+		BankTranList ofxBankTranList `xml:"BANKTRANLIST"`
+		LedgerBal    ofxBalance      `xml:"LEDGERBAL"`
+		AvailBal     ofxBalance      `xml:"AVAILBAL"`
+		BalList      []ofxBal        `xml:"BALLIST>BAL"`
+	}
+	ofxBankAcct struct {
+		BankId   string `xml:"BANKID"`
+		AcctId   string `xml:"ACCTID"`
+		AcctType string `xml:"ACCTTYPE"`
+	}
+	ofxBankTranList struct {
+		DtStart string       `xml:"DTSTART"`
+		DtEnd   string       `xml:"DTEND"`
+		StmtTrn []ofxStmtTrn `xml:"STMTTRN"`
+	}
+	ofxStmtTrn struct {
+		TrnType  string `xml:"TRNTYPE"`
+		DtPosted string `xml:"DTPOSTED"`
+		TrnAmt   string `xml:"TRNAMT"`
+		FitId    string `xml:"FITID"`
+		Name     string `xml:"NAME,omitempty"`
+		Memo     string `xml:"MEMO"`
+	}
+	ofxBalance struct {
+		BalAmt string `xml:"BALAMT"`
+		DtAsOf string `xml:"DTASOF"`
+	}
+	ofxBal struct {
+		Name    string `xml:"NAME"`
+		Desc    string `xml:"DESC"`
+		BalType string `xml:"BALTYPE"`
+		Value   string `xml:"VALUE"`
+		DtAsOf  string `xml:"DTASOF"`
+	}
+)
+
+var ofxAccountTypes = map[model.BankAccountType]string{
+	model.Savings:    "SAVINGS",
+	model.Checking:   "CHECKING",
+	model.Investment: "MONEYMRKT",
+}
+
+// writeOFX writes an OFX 2.2 bank statement. OFX statements only carry the balance at their end, so the opening
+// balance is listed among the additional balances.
+func writeOFX(w io.Writer, s *model.StatementOutput, opts Options) error {
+	ok := ofxStatus{Code: 0, Severity: "INFO"}
+	transactions := make([]ofxStmtTrn, len(s.Entries))
+	for i := range s.Entries {
+		entry := &s.Entries[i]
+		transactions[i] = ofxStmtTrn{
+			TrnType:  "CREDIT",
+			DtPosted: ofxTime(entry.CreatedAt),
+			TrnAmt:   signedAmount(entry),
+			FitId:    entry.Id,
+			Name:     truncate(entry.CounterpartyHolder, ofxMaxNameLength),
+			Memo:     description(entry),
+		}
+		if outgoing(&entry.BankAccountTransactionOutput) {
+			transactions[i].TrnType = "DEBIT"
+		}
+	}
+	closing := ofxBalance{BalAmt: s.ClosingAvailableBalance.StringFixed(2), DtAsOf: ofxTime(s.ToTime)}
+	document := ofxDocument{
+		SignOn: ofxSignOn{Status: ok, DtServer: ofxTime(s.CreatedAt), Language: "ENG"},
+		Bank: ofxStmtTrnRs{
+			TrnUid: "0",
+			Status: ok,
+			StmtRs: ofxStmtRs{
+				CurDef: opts.Currency,
+				BankAcctFrom: ofxBankAcct{
+					BankId:   opts.BankId,
+					AcctId:   s.AccountNumber,
+					AcctType: ofxAccountTypes[s.AccountType],
+				},
+				BankTranList: ofxBankTranList{
+					DtStart: ofxTime(s.FromTime),
+					DtEnd:   ofxTime(s.ToTime),
+					StmtTrn: transactions,
+				},
+				LedgerBal: closing,
+				AvailBal:  closing,
+				BalList: []ofxBal{{
+					Name:    "Opening balance",
+					Desc:    "Available balance at the start of the statement",
+					BalType: "DOLLAR",
+					Value:   s.OpeningAvailableBalance.StringFixed(2),
+					DtAsOf:  ofxTime(s.FromTime),
+				}},
+			},
+		},
+	}
+
+	if _, err := io.WriteString(w, xml.Header+ofxHeader); err != nil {
+		return fmt.Errorf("error when writing OFX header: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("error when writing OFX statement: %w", err)
+	}
+	return nil
+}
+
+func ofxTime(t time.Time) string {
+	return utc(t).Format("20060102150405") + "[0:GMT]"
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length])
+}
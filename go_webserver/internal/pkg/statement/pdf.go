@@ -0,0 +1,246 @@
+package statement
+
+import (
+	"fmt"
+	"github.com/go-pdf/fpdf"
+	"github.com/shopspring/decimal"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+	"webserver/internal/pkg/domain/model"
+)
+
+// PDFContentType is the media type of monthly statements, which are printable rather than meant for other software
+const PDFContentType = "application/pdf"
+
+// Sizes of the PDF layout in millimetres, on an A4 page
+const (
+	pdfMargin     = 15.0
+	pdfWidth      = 180.0
+	pdfLineHeight = 6.0
+	pdfFont       = "Helvetica"
+	// pdfVisibleDigits is how many of the last digits of account numbers are printed, the others being masked
+	pdfVisibleDigits = 4
+)
+
+type pdfColumn struct {
+	title string
+	width float64
+	align string
+}
+
+// pdfColumns are the columns of the transactions table, which spans the width of the page between its margins
+var pdfColumns = []pdfColumn{
+	{title: "Date", width: 20, align: "L"},
+	{title: "Description", width: 70, align: "L"},
+	{title: "Reference", width: 46, align: "L"},
+	{title: "Amount", width: 22, align: "R"},
+	{title: "Balance", width: 22, align: "R"},
+}
+
+// pdfWriter lays out a monthly statement, translating its UTF-8 text into the encoding of the standard PDF fonts
+type pdfWriter struct {
+	doc  *fpdf.Fpdf
+	text func(string) string
+}
+
+// WritePDF writes the printable statement of a bank account over a month. Every page repeats the header with the
+// name of the account holder, the masked account number and the month. The transactions table starts with the
+// available balance at the start of the month, found by undoing the transactions of the month from the balance at its
+// end, lists the balance after every transaction and ends with the totals of the money that came in and went out.
+// Transactions that are not realized are left out, as they did not change the available balance.
+func WritePDF(w io.Writer, s *model.MonthlyStatementOutput, opts Options) error {
+	doc := fpdf.New("P", "mm", "A4", "")
+	p := &pdfWriter{doc: doc, text: doc.UnicodeTranslatorFromDescriptor("")}
+	start := time.Date(s.Month.Year, s.Month.Month, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, -1)
+	title := fmt.Sprintf("%s statement %s", opts.BankId, start.Format("January 2006"))
+
+	doc.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	doc.SetAutoPageBreak(true, pdfMargin+pdfLineHeight)
+	doc.SetTitle(title, true)
+	doc.SetCreator(opts.BankId, true)
+	doc.SetCreationDate(s.CreatedAt)
+	doc.AliasNbPages("")
+	doc.SetHeaderFunc(func() { p.header(s, opts, start) })
+	doc.SetFooterFunc(func() { p.footer(s) })
+	doc.AddPage()
+
+	var transactions []*model.BankAccountTransactionOutput
+	closing := s.Month.AvailableBalance
+	opening := closing
+	for i := range s.Transactions {
+		if transaction := &s.Transactions[i]; transaction.TransactionType == model.Realized {
+			transactions = append(transactions, transaction)
+			opening = opening.Sub(change(transaction))
+		}
+	}
+
+	p.row("", start, "Opening balance", "", decimal.NullDecimal{}, opening)
+	balance := opening
+	moneyIn, moneyOut := decimal.Zero, decimal.Zero
+	var transfersIn, transfersOut int
+	for _, transaction := range transactions {
+		amount := change(transaction)
+		balance = balance.Add(amount)
+		if outgoing(transaction) {
+			moneyOut, transfersOut = moneyOut.Add(amount), transfersOut+1
+		} else {
+			moneyIn, transfersIn = moneyIn.Add(amount), transfersIn+1
+		}
+		p.row("", transaction.CreatedAt, pdfDescription(transaction, &s.Account), transaction.Id,
+			decimal.NewNullDecimal(amount), balance)
+	}
+	p.row("T", end, "Closing balance", "", decimal.NullDecimal{}, closing)
+
+	doc.Ln(pdfLineHeight)
+	doc.SetFont(pdfFont, "B", 9)
+	doc.CellFormat(pdfWidth, pdfLineHeight, "Totals", "B", 1, "L", false, 0, "")
+	doc.SetFont(pdfFont, "", 9)
+	p.total(fmt.Sprintf("Money in (%s)", transfers(transfersIn)), moneyIn)
+	p.total(fmt.Sprintf("Money out (%s)", transfers(transfersOut)), moneyOut)
+	p.total("Net change", moneyIn.Add(moneyOut))
+
+	if err := doc.Output(w); err != nil {
+		return fmt.Errorf("error when writing PDF statement: %w", err)
+	}
+	return nil
+}
+
+// MonthlyFileName names the file of a monthly statement after its bank account and month
+func MonthlyFileName(s *model.MonthlyStatementOutput) string {
+	return fmt.Sprintf("statement-%s-%04d-%02d.pdf", s.BankAccount.AccountNumber, s.Month.Year, s.Month.Month)
+}
+
+func (p *pdfWriter) header(s *model.MonthlyStatementOutput, opts Options, start time.Time) {
+	doc := p.doc
+	doc.SetFont(pdfFont, "B", 14)
+	doc.CellFormat(pdfWidth/2, 8, p.text(opts.BankId+" monthly statement"), "", 0, "L", false, 0, "")
+	doc.CellFormat(pdfWidth/2, 8, start.Format("January 2006"), "", 1, "R", false, 0, "")
+	doc.SetFont(pdfFont, "", 10)
+	holder := s.Account.Person.FirstName + " " + s.Account.Person.LastName
+	doc.CellFormat(pdfWidth/2, pdfLineHeight, p.text(holder), "", 0, "L", false, 0, "")
+	account := fmt.Sprintf("%s account %s", titleCase(string(s.BankAccount.AccountType)),
+		maskAccountNumber(s.BankAccount.AccountNumber))
+	doc.CellFormat(pdfWidth/2, pdfLineHeight, p.text(account), "", 1, "R", false, 0, "")
+	doc.CellFormat(pdfWidth, pdfLineHeight, "Amounts in "+opts.Currency, "", 1, "R", false, 0, "")
+	doc.Ln(2)
+	doc.SetFont(pdfFont, "B", 9)
+	for _, column := range pdfColumns {
+		doc.CellFormat(column.width, pdfLineHeight, column.title, "B", 0, column.align, false, 0, "")
+	}
+	doc.Ln(-1)
+	doc.SetFont(pdfFont, "", 9)
+}
+
+func (p *pdfWriter) footer(s *model.MonthlyStatementOutput) {
+	doc := p.doc
+	doc.SetY(-pdfMargin)
+	doc.SetFont(pdfFont, "", 8)
+	created := "Created " + utc(s.CreatedAt).Format("2006-01-02 15:04 UTC")
+	doc.CellFormat(pdfWidth/2, 5, created, "", 0, "L", false, 0, "")
+	doc.CellFormat(pdfWidth/2, 5, fmt.Sprintf("Page %d of {nb}", doc.PageNo()), "", 0, "R", false, 0, "")
+}
+
+// row writes a row of the transactions table, leaving the amount empty for the rows of the opening and closing
+// balances
+func (p *pdfWriter) row(
+	border string,
+	date time.Time,
+	description string,
+	reference string,
+	amount decimal.NullDecimal,
+	balance decimal.Decimal,
+) {
+	values := []string{utc(date).Format("2006-01-02"), description, reference, "", balance.StringFixed(2)}
+	if amount.Valid {
+		values[3] = amount.Decimal.StringFixed(2)
+	}
+	for i, column := range pdfColumns {
+		p.doc.CellFormat(column.width, pdfLineHeight, p.fit(values[i], column.width), border, 0, column.align, false,
+			0, "")
+	}
+	p.doc.Ln(-1)
+}
+
+func (p *pdfWriter) total(label string, amount decimal.Decimal) {
+	p.doc.CellFormat(pdfWidth-pdfColumns[len(pdfColumns)-1].width, pdfLineHeight, label, "", 0, "L", false, 0, "")
+	p.doc.CellFormat(pdfColumns[len(pdfColumns)-1].width, pdfLineHeight, amount.StringFixed(2), "", 1, "R", false,
+		0, "")
+}
+
+// fit translates the text and shortens it with an ellipsis until it fits in a cell of the given width
+func (p *pdfWriter) fit(text string, width float64) string {
+	translated := p.text(text)
+	// Cells are padded by the cell margin on either side
+	available := width - 2*p.doc.GetCellMargin()
+	if p.doc.GetStringWidth(translated) <= available {
+		return translated
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		translated = p.text(strings.TrimSpace(string(runes)) + "...")
+		if p.doc.GetStringWidth(translated) <= available {
+			break
+		}
+	}
+	return translated
+}
+
+// pdfDescription names the other side of the transaction after the bank accounts and the payees of the account
+// holder, which are all the statement knows of
+func pdfDescription(transaction *model.BankAccountTransactionOutput, account *model.AccountDetailsOutput) string {
+	direction := "Transfer from "
+	if outgoing(transaction) {
+		direction = "Transfer to "
+	}
+	for _, bankAccount := range account.BankAccounts {
+		if bankAccount.Id == transaction.OtherBankAccountId {
+			return fmt.Sprintf("%sown %s account %s", direction, bankAccount.AccountType,
+				maskAccountNumber(bankAccount.AccountNumber))
+		}
+	}
+	for _, payee := range account.KnownBankAccounts {
+		if payee.Id == transaction.OtherBankAccountId {
+			name := payee.Nickname
+			if name == "" {
+				name = payee.AccountHolder
+			}
+			return fmt.Sprintf("%s%s %s", direction, name, maskAccountNumber(payee.AccountNumber))
+		}
+	}
+	return direction + "another bank account"
+}
+
+// maskAccountNumber replaces all but the last digits of an account number with asterisks, keeping its separators
+func maskAccountNumber(accountNumber string) string {
+	masked := []rune(accountNumber)
+	visible := pdfVisibleDigits
+	for i := len(masked) - 1; i >= 0; i-- {
+		if !unicode.IsDigit(masked[i]) {
+			continue
+		}
+		if visible > 0 {
+			visible--
+		} else {
+			masked[i] = '*'
+		}
+	}
+	return string(masked)
+}
+
+func titleCase(text string) string {
+	if text == "" {
+		return text
+	}
+	return strings.ToUpper(text[:1]) + text[1:]
+}
+
+func transfers(count int) string {
+	if count == 1 {
+		return "1 transfer"
+	}
+	return fmt.Sprintf("%d transfers", count)
+}
@@ -0,0 +1,126 @@
+package statement
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/ledongthuc/pdf"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+func sampleMonthlyStatement() *model.MonthlyStatementOutput {
+	s := &model.MonthlyStatementOutput{
+		Account: model.AccountDetailsOutput{
+			Person: model.Person{FirstName: "Zoë", LastName: "Müller"},
+			BankAccounts: []model.BankAccount{
+				{Id: "65f1c0e4b4a1f0a9c8d7e6f5", AccountNumber: "123-45678-9", AccountType: model.Checking},
+				{Id: "65f1c0e4b4a1f0a9c8d7e6f6", AccountNumber: "123-45679-1", AccountType: model.Savings},
+			},
+			KnownBankAccounts: []model.KnownBankAccount{
+				{Id: "65f1c0e4b4a1f0a9c8d7e6f7", AccountNumber: "987-65432-1", AccountHolder: "Sam Jones",
+					Nickname: "Sam"},
+				{Id: "65f1c0e4b4a1f0a9c8d7e6f8", AccountNumber: "555-12345-6", AccountHolder: "Ada Lovelace"},
+				{Id: "65f1c0e4b4a1f0a9c8d7e6f9", AccountNumber: "555-98765-4",
+					Nickname: "The landlord who owns the flat on the corner of the street"},
+			},
+		},
+		BankAccount: model.BankAccount{
+			Id:            "65f1c0e4b4a1f0a9c8d7e6f5",
+			AccountNumber: "123-45678-9",
+			AccountType:   model.Checking,
+		},
+		Month: model.AccountBalanceMonth{
+			Year:             2024,
+			Month:            time.February,
+			AvailableBalance: decimal.RequireFromString("1250.75"),
+			PendingBalance:   decimal.RequireFromString("1200.75"),
+		},
+		CreatedAt: time.Date(2024, time.March, 1, 8, 30, 0, 0, time.UTC),
+	}
+	add := func(day int, other string, nature model.TransactionNature, transactionType model.TransactionType,
+		amount string) {
+		s.Transactions = append(s.Transactions, model.BankAccountTransactionOutput{
+			Id:                 fmt.Sprintf("65f1c0e4b4a1f0a9c8d7%04d", len(s.Transactions)),
+			BankAccountId:      s.BankAccount.Id,
+			OtherBankAccountId: other,
+			TransactionNature:  nature,
+			TransactionType:    transactionType,
+			Status:             model.Active,
+			Amount:             decimal.RequireFromString(amount),
+			CreatedAt:          time.Date(2024, time.February, day, 12, 0, 0, 0, time.UTC),
+		})
+	}
+	add(1, "65f1c0e4b4a1f0a9c8d7e6f7", model.Debit, model.Realized, "2000.00")
+	add(2, "65f1c0e4b4a1f0a9c8d7e6f9", model.Credit, model.Realized, "950.00")
+	add(3, "65f1c0e4b4a1f0a9c8d7e6f8", model.Credit, model.Pending, "50.00")
+	add(5, "65f1c0e4b4a1f0a9c8d7e6f6", model.Credit, model.Realized, "300.00")
+	add(6, "65f1c0e4b4a1f0a9c8d7e6fa", model.Debit, model.Realized, "12.34")
+	// Enough small transfers to carry the table over to a second page
+	for i := 0; i < 36; i++ {
+		add(7+i/2, "65f1c0e4b4a1f0a9c8d7e6f8", model.Credit, model.Realized, "1.50")
+	}
+	return s
+}
+
+func TestWritePDF(t *testing.T) {
+	var out bytes.Buffer
+	assert.Nil(t, WritePDF(&out, sampleMonthlyStatement(), options))
+	text := extractText(t, out.Bytes())
+
+	golden := filepath.Join("testdata", "monthly_statement.golden")
+	if *update {
+		assert.Nil(t, os.WriteFile(golden, []byte(text), 0o644))
+	}
+	expected, err := os.ReadFile(golden)
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), text)
+}
+
+func TestMaskAccountNumber(t *testing.T) {
+	assert.Equal(t, "***-**678-9", maskAccountNumber("123-45678-9"))
+	assert.Equal(t, "123", maskAccountNumber("123"))
+	assert.Equal(t, "", maskAccountNumber(""))
+}
+
+func TestMonthlyFileName(t *testing.T) {
+	assert.Equal(t, "statement-123-45678-9-2024-02.pdf", MonthlyFileName(sampleMonthlyStatement()))
+}
+
+// extractText returns the text of every page of a PDF, writing a line per row of text and separating the cells of a
+// row, which are drawn from different positions, with " | "
+func extractText(t *testing.T, document []byte) string {
+	t.Helper()
+	reader, err := pdf.NewReader(bytes.NewReader(document), int64(len(document)))
+	if err != nil {
+		t.Fatalf("Failed to read PDF: %v", err)
+	}
+	var out strings.Builder
+	for i := 1; i <= reader.NumPage(); i++ {
+		fmt.Fprintf(&out, "=== page %d ===\n", i)
+		var row []string
+		var x, y float64
+		for j, text := range reader.Page(i).Content().Text {
+			switch {
+			case j > 0 && text.Y != y:
+				out.WriteString(strings.Join(row, " | ") + "\n")
+				row = []string{text.S}
+			case j > 0 && text.X == x:
+				row[len(row)-1] += text.S
+			default:
+				row = append(row, text.S)
+			}
+			x, y = text.X, text.Y
+		}
+		out.WriteString(strings.Join(row, " | ") + "\n")
+	}
+	return out.String()
+}
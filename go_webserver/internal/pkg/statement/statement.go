@@ -0,0 +1,159 @@
+package statement
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+// Options are the details of the wallet that statements carry but bank accounts do not
+type Options struct {
+	// Currency is the ISO 4217 code of the currency amounts are in
+	Currency string
+	// BankId identifies the wallet as the servicer of the bank accounts in OFX and camt.053 statements
+	BankId string
+}
+
+// Format is a file format statements can be written in
+type Format struct {
+	Name        string
+	ContentType string
+	Extension   string
+	mediaTypes  []string
+	write       func(w io.Writer, s *model.StatementOutput, opts Options) error
+}
+
+func (f Format) Write(w io.Writer, s *model.StatementOutput, opts Options) error {
+	return f.write(w, s, opts)
+}
+
+var (
+	CSV = Format{
+		Name:        "csv",
+		ContentType: "text/csv; charset=utf-8",
+		Extension:   "csv",
+		mediaTypes:  []string{"text/csv"},
+		write:       writeCSV,
+	}
+	OFX = Format{
+		Name:        "ofx",
+		ContentType: "application/x-ofx",
+		Extension:   "ofx",
+		mediaTypes:  []string{"application/x-ofx", "application/ofx"},
+		write:       writeOFX,
+	}
+	Camt053 = Format{
+		Name:        "camt053",
+		ContentType: "application/xml; charset=utf-8",
+		Extension:   "xml",
+		mediaTypes:  []string{"application/xml", "text/xml"},
+		write:       writeCamt053,
+	}
+)
+
+// Formats are the supported formats, the first of which is written when the client accepts any
+var Formats = []Format{CSV, OFX, Camt053}
+
+func FormatByName(name string) (Format, bool) {
+	for _, format := range Formats {
+		if format.Name == name {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// Negotiate picks the format of highest preference in the value of an Accept header, failing when none of the
+// accepted media types is supported
+func Negotiate(accept string) (Format, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return Formats[0], true
+	}
+	type accepted struct {
+		mediaType string
+		quality   float64
+	}
+	var ranges []accepted
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, accepted{mediaType: mediaType, quality: quality})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].quality > ranges[j].quality })
+	for _, r := range ranges {
+		for _, format := range Formats {
+			if format.matches(r.mediaType) {
+				return format, true
+			}
+		}
+	}
+	return Format{}, false
+}
+
+func (f Format) matches(mediaRange string) bool {
+	if mediaRange == "*/*" {
+		return true
+	}
+	for _, mediaType := range f.mediaTypes {
+		if mediaType == mediaRange || strings.HasSuffix(mediaRange, "/*") &&
+			strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// FileName names the file of a statement after its bank account and period
+func FileName(s *model.StatementOutput, format Format) string {
+	return fmt.Sprintf("statement-%s-%s-%s.%s", s.AccountNumber, s.FromTime.UTC().Format("20060102"),
+		s.ToTime.UTC().Format("20060102"), format.Extension)
+}
+
+// outgoing tells whether money left the bank account of the statement. Transactions take their nature from the books
+// of the wallet, which credit the bank accounts money leaves and debit those it reaches.
+func outgoing(transaction *model.BankAccountTransactionOutput) bool {
+	return transaction.TransactionNature == model.Credit
+}
+
+// change is the change the transaction made to the available balance of the bank account of the statement
+func change(transaction *model.BankAccountTransactionOutput) decimal.Decimal {
+	if outgoing(transaction) {
+		return transaction.Amount.Neg()
+	}
+	return transaction.Amount
+}
+
+func signedAmount(entry *model.StatementEntry) string {
+	return change(&entry.BankAccountTransactionOutput).StringFixed(2)
+}
+
+func description(entry *model.StatementEntry) string {
+	counterparty := entry.CounterpartyAccountNumber
+	if counterparty == "" {
+		counterparty = "closed BankAccount"
+	}
+	if outgoing(&entry.BankAccountTransactionOutput) {
+		return "Transfer to " + counterparty
+	}
+	return "Transfer from " + counterparty
+}
+
+func utc(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
@@ -0,0 +1,147 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+var options = Options{Currency: "EUR", BankId: "WALLET"}
+
+func sampleStatement() *model.StatementOutput {
+	fromTime := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(
+		id string,
+		nature model.TransactionNature,
+		amount string,
+		createdAt time.Time,
+		accountNumber string,
+		holder string,
+	) model.StatementEntry {
+		return model.StatementEntry{
+			BankAccountTransactionOutput: model.BankAccountTransactionOutput{
+				Id:                id,
+				BankAccountId:     "65f1c0e4b4a1f0a9c8d7e6f5",
+				TransactionNature: nature,
+				TransactionType:   model.Realized,
+				Amount:            decimal.RequireFromString(amount),
+				CreatedAt:         createdAt,
+			},
+			CounterpartyAccountNumber: accountNumber,
+			CounterpartyHolder:        holder,
+		}
+	}
+	return &model.StatementOutput{
+		BankAccountId:           "65f1c0e4b4a1f0a9c8d7e6f5",
+		AccountNumber:           "123-45678-9",
+		AccountType:             model.Checking,
+		AccountHolder:           "Tom Smith",
+		FromTime:                fromTime,
+		ToTime:                  time.Date(2024, time.March, 31, 23, 59, 59, 0, time.UTC),
+		OpeningAvailableBalance: decimal.RequireFromString("40"),
+		ClosingAvailableBalance: decimal.RequireFromString("70"),
+		Entries: []model.StatementEntry{
+			entry("received", model.Debit, "50", fromTime.Add(24*time.Hour), "987-65432-1", "=Sam Jones"),
+			entry("sent", model.Credit, "20", fromTime.Add(48*time.Hour), "", ""),
+		},
+		CreatedAt: time.Date(2024, time.April, 2, 8, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var out bytes.Buffer
+	assert.Nil(t, CSV.Write(&out, sampleStatement(), options))
+	assert.Equal(t, strings.Join([]string{
+		"date,description,transactionId,counterpartyAccountNumber,counterpartyName,amount,balance",
+		"2024-03-01T00:00:00Z,Opening balance,,,,,40.00",
+		"2024-03-02T00:00:00Z,Transfer from 987-65432-1,received,987-65432-1,'=Sam Jones,50.00,90.00",
+		"2024-03-03T00:00:00Z,Transfer to closed BankAccount,sent,,,-20.00,70.00",
+		"2024-03-31T23:59:59Z,Closing balance,,,,,70.00",
+	}, "\n")+"\n", out.String())
+}
+
+func TestOFX(t *testing.T) {
+	var out bytes.Buffer
+	assert.Nil(t, OFX.Write(&out, sampleStatement(), options))
+	assert.True(t, strings.HasPrefix(out.String(), xml.Header+`<?OFX OFXHEADER="200" VERSION="220"`))
+
+	var document ofxDocument
+	assert.Nil(t, xml.Unmarshal(out.Bytes(), &document))
+	statement := document.Bank.StmtRs
+	assert.Equal(t, "EUR", statement.CurDef)
+	assert.Equal(t, ofxBankAcct{BankId: "WALLET", AcctId: "123-45678-9", AcctType: "CHECKING"},
+		statement.BankAcctFrom)
+	assert.Equal(t, "20240301000000[0:GMT]", statement.BankTranList.DtStart)
+	assert.Equal(t, []ofxStmtTrn{
+		{
+			TrnType:  "CREDIT",
+			DtPosted: "20240302000000[0:GMT]",
+			TrnAmt:   "50.00",
+			FitId:    "received",
+			Name:     "=Sam Jones",
+			Memo:     "Transfer from 987-65432-1",
+		},
+		{
+			TrnType:  "DEBIT",
+			DtPosted: "20240303000000[0:GMT]",
+			TrnAmt:   "-20.00",
+			FitId:    "sent",
+			Memo:     "Transfer to closed BankAccount",
+		},
+	}, statement.BankTranList.StmtTrn)
+	assert.Equal(t, "70.00", statement.AvailBal.BalAmt)
+	assert.Equal(t, "40.00", statement.BalList[0].Value)
+}
+
+func TestCamt053(t *testing.T) {
+	var out bytes.Buffer
+	s := sampleStatement()
+	s.OpeningAvailableBalance = decimal.RequireFromString("-12.5")
+	assert.Nil(t, Camt053.Write(&out, s, options))
+	assert.Contains(t, out.String(), `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">`)
+
+	var document camtDocument
+	assert.Nil(t, xml.Unmarshal(out.Bytes(), &document))
+	statement := document.Statement.Stmt
+	assert.Equal(t, "123-45678-9-20240301-20240331", statement.Id)
+	assert.Equal(t, "WALLET", statement.Acct.Svcr)
+	assert.Equal(t, []camtBal{
+		{Tp: "OPBD", Amt: camtAmt{Ccy: "EUR", Value: "12.50"}, CdtDbtInd: "DBIT", Dt: "2024-03-01T00:00:00Z"},
+		{Tp: "CLBD", Amt: camtAmt{Ccy: "EUR", Value: "70.00"}, CdtDbtInd: "CRDT", Dt: "2024-03-31T23:59:59Z"},
+	}, statement.Bal)
+	assert.Len(t, statement.Ntry, 2)
+	assert.Equal(t, "CRDT", statement.Ntry[0].CdtDbtInd)
+	assert.Equal(t, &camtRltdPties{
+		Dbtr:     &camtParty{Nm: "=Sam Jones"},
+		DbtrAcct: &camtAcctId{Othr: "987-65432-1"},
+	}, statement.Ntry[0].TxDtls.RltdPties)
+	assert.Equal(t, "DBIT", statement.Ntry[1].CdtDbtInd)
+	assert.Equal(t, camtAmt{Ccy: "EUR", Value: "20.00"}, statement.Ntry[1].Amt)
+	assert.Nil(t, statement.Ntry[1].TxDtls.RltdPties)
+}
+
+func TestNegotiate(t *testing.T) {
+	for accept, expected := range map[string]string{
+		"":                                      "csv",
+		"*/*":                                   "csv",
+		"application/x-ofx":                     "ofx",
+		"text/*;q=0.5, application/xml":         "camt053",
+		"application/json, application/*;q=0.2": "ofx",
+		"text/csv;q=0, text/xml":                "camt053",
+	} {
+		format, ok := Negotiate(accept)
+		assert.True(t, ok, accept)
+		assert.Equal(t, expected, format.Name, accept)
+	}
+	_, ok := Negotiate("application/json, text/csv;q=0")
+	assert.False(t, ok)
+}
+
+func TestFileName(t *testing.T) {
+	assert.Equal(t, "statement-123-45678-9-20240301-20240331.xml", FileName(sampleStatement(), Camt053))
+}
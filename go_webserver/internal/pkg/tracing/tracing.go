@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+)
+
+// Exporters spans can be sent to
+const (
+	NoExporter     = "none"
+	StdoutExporter = "stdout"
+	OTLPExporter   = "otlp"
+)
+
+// TracerName names the tracer the spans of the webserver are started with
+const TracerName = "webserver"
+
+const serviceName = "wallet-webserver"
+
+type Options struct {
+	Exporter string
+	// OTLPEndpoint is the URL of the OTLP/HTTP collector, the OTEL_EXPORTER_OTLP_ENDPOINT environment variable or
+	// localhost:4318 when it is empty
+	OTLPEndpoint string
+	// SampleRatio is the fraction of traces that are recorded, unless the caller of the webserver sampled them
+	SampleRatio float64
+	// Output is where the stdout exporter writes spans
+	Output io.Writer
+}
+
+// NewProvider returns a tracer provider exporting spans as the options say. Spans are still created when there is no
+// exporter, so that trace IDs keep correlating logs, but they are dropped once they end. The provider must be shut
+// down to flush the spans that have not been exported yet.
+func NewProvider(opts Options, ctx context.Context) (*sdktrace.TracerProvider, error) {
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("error when describing the traced service: %w", err)
+	}
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	}
+
+	switch opts.Exporter {
+	case NoExporter:
+	case StdoutExporter:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(opts.Output))
+		if err != nil {
+			return nil, fmt.Errorf("error when creating stdout span exporter: %w", err)
+		}
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	case OTLPExporter:
+		var clientOpts []otlptracehttp.Option
+		if opts.OTLPEndpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpointURL(opts.OTLPEndpoint))
+		}
+		exporter, err := otlptracehttp.New(ctx, clientOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("error when creating OTLP span exporter: %w", err)
+		}
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	default:
+		return nil, fmt.Errorf("unknown span exporter %q", opts.Exporter)
+	}
+	return sdktrace.NewTracerProvider(providerOpts...), nil
+}
+
+// Tracer returns the tracer of the webserver from the provider
+func Tracer(provider trace.TracerProvider) trace.Tracer {
+	return provider.Tracer(TracerName)
+}
+
+// End ends the span, marking it as failed when err is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
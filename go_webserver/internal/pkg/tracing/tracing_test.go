@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestNewProvider(t *testing.T) {
+	t.Run("Writes spans to the output with the stdout exporter once shut down", func(t *testing.T) {
+		var buf bytes.Buffer
+		tp, err := NewProvider(Options{Exporter: StdoutExporter, SampleRatio: 1, Output: &buf}, context.Background())
+		assert.Nil(t, err)
+		_, span := Tracer(tp).Start(context.Background(), "TransactionService.AddTransaction")
+		span.End()
+		assert.Nil(t, tp.Shutdown(context.Background()))
+		assert.Contains(t, buf.String(), `"Name":"TransactionService.AddTransaction"`)
+		assert.Contains(t, buf.String(), serviceName)
+	})
+
+	t.Run("Still creates spans without an exporter", func(t *testing.T) {
+		tp, err := NewProvider(Options{Exporter: NoExporter, SampleRatio: 1}, context.Background())
+		assert.Nil(t, err)
+		_, span := Tracer(tp).Start(context.Background(), "TransactionService.AddTransaction")
+		assert.True(t, span.SpanContext().IsValid())
+		span.End()
+	})
+
+	t.Run("Rejects unknown exporters", func(t *testing.T) {
+		_, err := NewProvider(Options{Exporter: "jaeger"}, context.Background())
+		assert.EqualError(t, err, `unknown span exporter "jaeger"`)
+	})
+}
+
+func TestEnd(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := Tracer(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	_, succeeded := tracer.Start(context.Background(), "AccountRepository.AddBalance")
+	End(succeeded, nil)
+	_, failed := tracer.Start(context.Background(), "AccountRepository.DeductBalance")
+	End(failed, errors.New("insufficient balance"))
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	assert.Equal(t, "insufficient balance", spans[1].Status.Description)
+}
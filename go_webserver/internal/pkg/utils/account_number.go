@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// GenerateAccountNumber returns a random account number in the XXX-XXXXX-X format, where the last digit is the Luhn
+// check digit of the preceding eight
+func GenerateAccountNumber() (string, error) {
+	upperBound := big.NewInt(100_000_000)
+	n, err := rand.Int(rand.Reader, upperBound)
+	if err != nil {
+		return "", fmt.Errorf("error when generating account number: %w", err)
+	}
+	digits := fmt.Sprintf("%08d", n.Int64())
+	return fmt.Sprintf("%s-%s-%d", digits[:3], digits[3:], luhnCheckDigit(digits)), nil
+}
+
+// HasValidCheckDigit reports whether the last digit of an account number in the XXX-XXXXX-X format is the Luhn check
+// digit of the preceding eight
+func HasValidCheckDigit(accountNumber string) bool {
+	digits := strings.ReplaceAll(accountNumber, "-", "")
+	if len(digits) != 9 {
+		return false
+	}
+	for _, digit := range digits {
+		if digit < '0' || digit > '9' {
+			return false
+		}
+	}
+	return luhnCheckDigit(digits[:8]) == int(digits[8]-'0')
+}
+
+func luhnCheckDigit(digits string) int {
+	sum := 0
+	// Doubling starts from the rightmost digit, as the check digit is appended to its right
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return (10 - sum%10) % 10
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+)
+
+func TestGenerateAccountNumber(t *testing.T) {
+	t.Run("Generates account numbers in the XXX-XXXXX-X format with a valid check digit", func(t *testing.T) {
+		pattern := regexp.MustCompile(`^\d{3}-\d{5}-\d$`)
+		for i := 0; i < 100; i++ {
+			accountNumber, err := GenerateAccountNumber()
+			assert.Nil(t, err)
+			assert.Regexp(t, pattern, accountNumber)
+			assert.True(t, HasValidCheckDigit(accountNumber), accountNumber)
+		}
+	})
+}
+
+func TestHasValidCheckDigit(t *testing.T) {
+	t.Run("Accepts account numbers whose last digit is the Luhn check digit", func(t *testing.T) {
+		assert.True(t, HasValidCheckDigit("123-45678-2"))
+		assert.True(t, HasValidCheckDigit("000-00000-0"))
+	})
+
+	t.Run("Rejects account numbers with a wrong check digit or format", func(t *testing.T) {
+		assert.False(t, HasValidCheckDigit("123-45678-9"))
+		assert.False(t, HasValidCheckDigit("12a-45678-2"))
+		assert.False(t, HasValidCheckDigit("123-45678"))
+	})
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+type encodedTransactionCursor struct {
+	CreatedAt int64  `json:"t"`
+	Id        string `json:"id"`
+}
+
+// EncodeTransactionCursor turns the cursor into an opaque token that clients hand back to fetch the next page
+func EncodeTransactionCursor(cursor *model.TransactionCursor) string {
+	raw, _ := json.Marshal(encodedTransactionCursor{CreatedAt: cursor.CreatedAt.Unix(), Id: cursor.Id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeTransactionCursor reverses EncodeTransactionCursor, returning model.ErrInvalidTransactionCursor for tokens
+// it did not produce
+func DecodeTransactionCursor(token string) (*model.TransactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, model.ErrInvalidTransactionCursor
+	}
+	var decoded encodedTransactionCursor
+	if err = json.Unmarshal(raw, &decoded); err != nil || decoded.Id == "" {
+		return nil, model.ErrInvalidTransactionCursor
+	}
+	return &model.TransactionCursor{CreatedAt: time.Unix(decoded.CreatedAt, 0), Id: decoded.Id}, nil
+}
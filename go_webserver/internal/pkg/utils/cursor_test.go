@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+func TestTransactionCursor(t *testing.T) {
+	t.Run("Decodes an encoded cursor", func(t *testing.T) {
+		cursor := &model.TransactionCursor{
+			CreatedAt: time.Unix(1717171717, 0),
+			Id:        "665a1b2c3d4e5f6a7b8c9d0e",
+		}
+		decoded, err := DecodeTransactionCursor(EncodeTransactionCursor(cursor))
+		assert.Nil(t, err)
+		assert.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+		assert.Equal(t, cursor.Id, decoded.Id)
+	})
+
+	t.Run("Rejects tokens that are not cursors", func(t *testing.T) {
+		for _, token := range []string{"not a cursor", "bm90IGpzb24", "e30"} {
+			_, err := DecodeTransactionCursor(token)
+			assert.ErrorIs(t, err, model.ErrInvalidTransactionCursor, token)
+		}
+	})
+}
@@ -2,9 +2,13 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 	"github.com/shopspring/decimal"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
 	"time"
+	"webserver/internal/pkg/domain/model"
 )
 
 func ObjectIdToString(insertedId interface{}) (string, error) {
@@ -16,14 +20,26 @@ func ObjectIdToString(insertedId interface{}) (string, error) {
 	return "", err
 }
 
+// StringToObjectId parses the hex form of an ObjectID, failing with model.ErrInvalidId for malformed IDs, which are
+// usually given by clients
 func StringToObjectId(stringId string) (primitive.ObjectID, error) {
 	objId, err := primitive.ObjectIDFromHex(stringId)
 	if err != nil {
-		return primitive.ObjectID{}, err
+		return primitive.ObjectID{}, fmt.Errorf("%w: %w", model.ErrInvalidId, err)
 	}
 	return objId, nil
 }
 
+// ClassifyMongoError marks the errors of MongoDB deployments that cannot be reached or did not answer in time with
+// model.ErrStorageUnavailable, and returns every other error as is
+func ClassifyMongoError(err error) error {
+	var selectionErr topology.ServerSelectionError
+	if err != nil && (mongo.IsNetworkError(err) || mongo.IsTimeout(err) || errors.As(err, &selectionErr)) {
+		return fmt.Errorf("%w: %w", model.ErrStorageUnavailable, err)
+	}
+	return err
+}
+
 func GetCurrentTimestamp() primitive.Timestamp {
 	return TimeToTimestamp(time.Now())
 }
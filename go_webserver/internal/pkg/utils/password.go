@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// PasswordParams are the argon2id parameters used when hashing a password
+type PasswordParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultPasswordParams are the parameters new password hashes are created with. Hashes created with any other
+// parameters are reported as needing a rehash when verified.
+var DefaultPasswordParams = PasswordParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var ErrUnsupportedPasswordHash = errors.New("unsupported password hash format")
+
+const argon2idPrefix = "$argon2id$"
+
+var bcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+// HashPassword hashes the password with argon2id and a random salt, and encodes the result along with the algorithm
+// and its parameters in the PHC string format, e.g. $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func HashPassword(password string) (string, error) {
+	return hashPasswordWithParams(password, DefaultPasswordParams)
+}
+
+func hashPasswordWithParams(password string, params PasswordParams) (string, error) {
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("error when generating password salt: %w", err)
+	}
+	key := argon2.IDKey(
+		[]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength,
+	)
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		params.Memory,
+		params.Iterations,
+		params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// IsPasswordHash reports whether the stored value is an encoded hash produced by a supported algorithm
+func IsPasswordHash(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix) || isBcryptHash(encoded)
+}
+
+// VerifyPassword checks the password against the encoded hash in constant time. needsRehash is true when the
+// password matches but the hash was not created with the current algorithm and DefaultPasswordParams.
+func VerifyPassword(encoded string, password string) (matches bool, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, argon2idPrefix):
+		return verifyArgon2id(encoded, password)
+	case isBcryptHash(encoded):
+		err = bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, fmt.Errorf("error when comparing bcrypt hash: %w", err)
+		}
+		return true, true, nil
+	default:
+		return false, false, ErrUnsupportedPasswordHash
+	}
+}
+
+func verifyArgon2id(encoded string, password string) (bool, bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, false, err
+	}
+	otherKey := argon2.IDKey(
+		[]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength,
+	)
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return false, false, nil
+	}
+	return true, params != DefaultPasswordParams, nil
+}
+
+func decodeArgon2id(encoded string) (PasswordParams, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=65536,t=3,p=2", "<salt>", "<hash>"
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return PasswordParams{}, nil, nil, ErrUnsupportedPasswordHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return PasswordParams{}, nil, nil, fmt.Errorf("error when parsing argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return PasswordParams{}, nil, nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+	var params PasswordParams
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return PasswordParams{}, nil, nil, fmt.Errorf("error when parsing argon2id parameters: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return PasswordParams{}, nil, nil, fmt.Errorf("error when decoding argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return PasswordParams{}, nil, nil, fmt.Errorf("error when decoding argon2id hash: %w", err)
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+func isBcryptHash(encoded string) bool {
+	for _, prefix := range bcryptPrefixes {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	t.Run("Encodes the algorithm and parameters alongside the hash", func(t *testing.T) {
+		encoded, err := HashPassword("password")
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=65536,t=3,p=2$"))
+		assert.True(t, IsPasswordHash(encoded))
+	})
+
+	t.Run("Salts every hash of the same password differently", func(t *testing.T) {
+		first, _ := HashPassword("password")
+		second, _ := HashPassword("password")
+		assert.NotEqual(t, first, second)
+	})
+}
+
+func TestVerifyPassword(t *testing.T) {
+	t.Run("Matches the password the hash was created from", func(t *testing.T) {
+		encoded, _ := HashPassword("password")
+		matches, needsRehash, err := VerifyPassword(encoded, "password")
+		assert.Nil(t, err)
+		assert.True(t, matches)
+		assert.False(t, needsRehash)
+	})
+
+	t.Run("Does not match a different password", func(t *testing.T) {
+		encoded, _ := HashPassword("password")
+		matches, _, err := VerifyPassword(encoded, "wrongpassword")
+		assert.Nil(t, err)
+		assert.False(t, matches)
+	})
+
+	t.Run("Requests a rehash if the hash was created with outdated parameters", func(t *testing.T) {
+		outdatedParams := DefaultPasswordParams
+		outdatedParams.Iterations = 1
+		encoded, _ := hashPasswordWithParams("password", outdatedParams)
+		matches, needsRehash, err := VerifyPassword(encoded, "password")
+		assert.Nil(t, err)
+		assert.True(t, matches)
+		assert.True(t, needsRehash)
+	})
+
+	t.Run("Verifies bcrypt hashes and requests a rehash", func(t *testing.T) {
+		encoded, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+		matches, needsRehash, err := VerifyPassword(string(encoded), "password")
+		assert.Nil(t, err)
+		assert.True(t, matches)
+		assert.True(t, needsRehash)
+	})
+
+	t.Run("Rejects plaintext passwords", func(t *testing.T) {
+		matches, _, err := VerifyPassword("password", "password")
+		assert.ErrorIs(t, err, ErrUnsupportedPasswordHash)
+		assert.False(t, matches)
+		assert.False(t, IsPasswordHash("password"))
+	})
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+	"webserver/internal/pkg/logging"
+)
+
+const (
+	LockCollectionName = "migration_lock"
+	// LockLease is how long the lock is held without being renewed, after which another migrator may take it over
+	LockLease = 30 * time.Second
+	// LockWaitTimeout is how long a migrator waits for another one to finish before giving up
+	LockWaitTimeout   = 10 * time.Minute
+	lockId            = "migrator"
+	lockRenewInterval = LockLease / 3
+	lockRetryInterval = time.Second
+)
+
+var (
+	ErrMigrationLocked   = errors.New("migrations are locked by another migrator")
+	ErrMigrationLockLost = errors.New("the lease of the migration lock was lost")
+)
+
+// MigrationLock is a lease on the lock document of the migrations database, which is renewed in the background until
+// it is released
+type MigrationLock struct {
+	ms    *MigrationServiceImpl
+	owner string
+	lost  atomic.Bool
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// AcquireLock takes the migration lock, waiting up to the given duration for a migrator holding it to release it or
+// for its lease to expire
+func (ms *MigrationServiceImpl) AcquireLock(wait time.Duration) (*MigrationLock, error) {
+	owner, err := newLockOwner()
+	if err != nil {
+		return nil, fmt.Errorf("error when generating migration lock owner: %w", err)
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		acquired, err := ms.tryLock(owner)
+		if err != nil {
+			return nil, fmt.Errorf("error when taking migration lock: %w", err)
+		}
+		if acquired {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			return nil, ErrMigrationLocked
+		}
+		ms.logger.Info("Waiting for another migrator to release the migration lock")
+		select {
+		case <-ms.ctx.Done():
+			return nil, ms.ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	lock := &MigrationLock{ms: ms, owner: owner, stop: make(chan struct{}), done: make(chan struct{})}
+	go lock.renew()
+	ms.logger.Info("Took migration lock", slog.String("owner", owner))
+	return lock, nil
+}
+
+// Lost reports whether the lease could not be renewed, in which case another migrator may have taken the lock
+func (l *MigrationLock) Lost() bool {
+	return l.lost.Load()
+}
+
+// Release stops renewing the lease and removes the lock document if it is still held
+func (l *MigrationLock) Release() error {
+	close(l.stop)
+	<-l.done
+	collection := l.ms.client.Database(l.ms.migrationDatabaseName).Collection(LockCollectionName)
+	mongoCtx, cancel := context.WithTimeout(l.ms.ctx, MigrationTimeout)
+	defer cancel()
+	_, err := collection.DeleteOne(mongoCtx, bson.M{"_id": lockId, "owner": l.owner})
+	if err != nil {
+		return err
+	}
+	l.ms.logger.Info("Released migration lock", slog.String("owner", l.owner))
+	return nil
+}
+
+func (l *MigrationLock) renew() {
+	defer close(l.done)
+	ticker := time.NewTicker(lockRenewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			renewed, err := l.ms.tryLock(l.owner)
+			if err != nil || !renewed {
+				l.ms.logger.Error("Unable to renew the migration lock", slog.String("owner", l.owner), logging.Err(err))
+				l.lost.Store(true)
+				return
+			}
+		}
+	}
+}
+
+// tryLock takes the lock, or extends its lease, when it is free, expired or already held by the owner. A lock held by
+// another owner makes the upsert collide with the existing lock document.
+func (ms *MigrationServiceImpl) tryLock(owner string) (bool, error) {
+	collection := ms.client.Database(ms.migrationDatabaseName).Collection(LockCollectionName)
+	mongoCtx, cancel := context.WithTimeout(ms.ctx, MigrationTimeout)
+	defer cancel()
+	now := time.Now().UTC()
+	filter := bson.M{
+		"_id": lockId,
+		"$or": bson.A{bson.M{"owner": owner}, bson.M{"expiresAt": bson.M{"$lt": now}}},
+	}
+	update := bson.M{"$set": bson.M{"owner": owner, "expiresAt": now.Add(LockLease)}}
+	_, err := collection.UpdateOne(mongoCtx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func newLockOwner() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	suffix := make([]byte, 4)
+	if _, err = rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix)), nil
+}
@@ -6,7 +6,17 @@ import (
 
 type MigrationService interface {
 	// ApplyMigration /**
-	// * ApplyMigration applies a migration to the database
+	// * ApplyMigration applies a migration of the track to the database
 	// * Returns an error if the migration fails, and a boolean indicating if the migration was applied
-	ApplyMigration(databaseName string, migration versions.Migration) (error, bool)
+	ApplyMigration(databaseName string, track string, migration versions.Migration) (error, bool)
+	// RollbackMigration /**
+	// * RollbackMigration reverts an applied migration of the track and unmarks it as applied
+	// * Returns an error if the rollback fails, and a boolean indicating if the migration was rolled back
+	RollbackMigration(databaseName string, track string, migration versions.Migration) (error, bool)
+	// MigrateUp applies the migrations of the track up to and including the target number that have not been applied
+	MigrateUp(databaseName string, track versions.Track, target int) error
+	// MigrateDown rolls back the applied migrations of the track numbered above the target, latest first
+	MigrateDown(databaseName string, track versions.Track, target int) error
+	// Status lists every migration of the track along with when it was applied
+	Status(track versions.Track) ([]MigrationStatus, error)
 }
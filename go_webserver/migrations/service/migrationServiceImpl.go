@@ -3,20 +3,52 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"log"
+	"log/slog"
 	"time"
+	"webserver/internal/pkg/logging"
 	"webserver/migrations/versions"
 )
 
 const MigrationTimeout = time.Minute * 1
 
+// ErrMigrationChanged is returned when the source of an applied migration no longer matches the checksum recorded
+// when it was applied
+var ErrMigrationChanged = errors.New("applied migration has changed since it was applied")
+
+// MigrationRecord is the document stored for every applied migration. Records written before tracks, timings and
+// checksums were kept only have a version.
+type MigrationRecord struct {
+	Version     string    `bson:"version"`
+	Track       string    `bson:"track,omitempty"`
+	Description string    `bson:"description,omitempty"`
+	Checksum    string    `bson:"checksum,omitempty"`
+	AppliedAt   time.Time `bson:"appliedAt,omitempty"`
+	DurationMs  int64     `bson:"durationMs,omitempty"`
+}
+
+type MigrationStatus struct {
+	Track       string
+	Number      int
+	Version     string
+	Description string
+	Applied     bool
+	AppliedAt   time.Time
+	Duration    time.Duration
+	// Changed is set when the migration no longer matches the checksum recorded when it was applied
+	Changed bool
+}
+
 type MigrationServiceImpl struct {
 	client                  *mongo.Client
 	ctx                     context.Context
 	migrationDatabaseName   string
 	migrationCollectionName string
+	// warnOnChanged only logs applied migrations whose checksum changed instead of refusing to run
+	warnOnChanged bool
+	logger        *slog.Logger
 }
 
 func NewMigrationService(
@@ -24,23 +56,29 @@ func NewMigrationService(
 	ctx context.Context,
 	migrationDatabaseName string,
 	migrationCollectionName string,
+	logger *slog.Logger,
 ) *MigrationServiceImpl {
 	return &MigrationServiceImpl{
 		client:                  client,
 		ctx:                     ctx,
 		migrationDatabaseName:   migrationDatabaseName,
 		migrationCollectionName: migrationCollectionName,
+		logger:                  logger,
 	}
 }
 
+// SetWarnOnChangedMigrations makes the service log applied migrations that changed instead of refusing to migrate
+func (ms *MigrationServiceImpl) SetWarnOnChangedMigrations(warn bool) {
+	ms.warnOnChanged = warn
+}
+
 // CheckIfApplied TODO: Set this function to private after finding out a way to test them
 func (ms *MigrationServiceImpl) CheckIfApplied(version string) (bool, error) {
-	db := ms.client.Database(ms.migrationDatabaseName)
-	collection := db.Collection(ms.migrationCollectionName)
-	mongoCtx, cancel := context.WithTimeout(ms.ctx, MigrationTimeout)
-	defer cancel()
-	filter := bson.M{"version": version}
-	err := collection.FindOne(mongoCtx, filter).Err()
+	return ms.checkIfApplied(ms.ctx, version)
+}
+
+func (ms *MigrationServiceImpl) checkIfApplied(ctx context.Context, version string) (bool, error) {
+	_, err := ms.getRecord(ctx, version)
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return false, nil
 	}
@@ -51,37 +89,339 @@ func (ms *MigrationServiceImpl) CheckIfApplied(version string) (bool, error) {
 }
 
 // MarkAsApplied TODO: Set this function to private after finding out a way to test them
-func (ms *MigrationServiceImpl) MarkAsApplied(version string) error {
+func (ms *MigrationServiceImpl) MarkAsApplied(record MigrationRecord) error {
+	return ms.markAsApplied(ms.ctx, record)
+}
+
+func (ms *MigrationServiceImpl) markAsApplied(ctx context.Context, record MigrationRecord) error {
 	db := ms.client.Database(ms.migrationDatabaseName)
 	collection := db.Collection(ms.migrationCollectionName)
-	mongoCtx, cancel := context.WithTimeout(ms.ctx, MigrationTimeout)
+	mongoCtx, cancel := context.WithTimeout(ctx, MigrationTimeout)
 	defer cancel()
-	_, err := collection.InsertOne(mongoCtx, bson.M{"version": version})
+	_, err := collection.InsertOne(mongoCtx, record)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (ms *MigrationServiceImpl) ApplyMigration(databaseName string, migration versions.Migration) (error, bool) {
-	hasBeenApplied, err := ms.CheckIfApplied(migration.Version)
+// UnmarkAsApplied TODO: Set this function to private after finding out a way to test them
+func (ms *MigrationServiceImpl) UnmarkAsApplied(version string) error {
+	return ms.unmarkAsApplied(ms.ctx, version)
+}
+
+func (ms *MigrationServiceImpl) unmarkAsApplied(ctx context.Context, version string) error {
+	db := ms.client.Database(ms.migrationDatabaseName)
+	collection := db.Collection(ms.migrationCollectionName)
+	mongoCtx, cancel := context.WithTimeout(ctx, MigrationTimeout)
+	defer cancel()
+	_, err := collection.DeleteOne(mongoCtx, bson.M{"version": version})
 	if err != nil {
-		log.Printf("Error when checking if migration %s has been applied: %v", migration.Version, err)
-		return err, false
+		return err
+	}
+	return nil
+}
+
+// ApplyMigration runs the migration and marks it as applied. Transactional migrations are marked as applied in the
+// same database transaction, so that a failure never leaves a migration half applied or applied but unmarked.
+func (ms *MigrationServiceImpl) ApplyMigration(
+	databaseName string,
+	track string,
+	migration versions.Migration,
+) (error, bool) {
+	hasBeenApplied := false
+	err := ms.inTransaction(migration, func(ctx context.Context) error {
+		hasBeenApplied = false
+		applied, err := ms.checkIfApplied(ctx, migration.Version)
+		if err != nil {
+			ms.logger.Error("Unable to check if migration has been applied", slog.String("version", migration.Version),
+				logging.Err(err))
+			return err
+		}
+		if applied {
+			ms.logger.Info("Migration has already been applied", slog.String("version", migration.Version))
+			return nil
+		}
+		startedAt := time.Now()
+		err = migration.Up(ms.client, ctx, databaseName)
+		if err != nil {
+			ms.logger.Error("Unable to apply migration", slog.String("version", migration.Version), logging.Err(err))
+			return err
+		}
+		// A non-transactional migration has been applied from here on, even if marking it fails
+		hasBeenApplied = !migration.Transactional
+		record := MigrationRecord{
+			Version:     migration.Version,
+			Track:       track,
+			Description: migration.Description,
+			Checksum:    migration.Checksum,
+			AppliedAt:   startedAt.UTC(),
+			DurationMs:  time.Since(startedAt).Milliseconds(),
+		}
+		err = ms.markAsApplied(ctx, record)
+		if err != nil {
+			ms.logger.Error("Unable to mark migration as applied", slog.String("version", migration.Version),
+				logging.Err(err))
+			return err
+		}
+		hasBeenApplied = true
+		return nil
+	})
+	if err != nil && migration.Transactional {
+		hasBeenApplied = false
 	}
-	if hasBeenApplied {
-		log.Printf("Migration %s has already been applied", migration.Version)
-		return nil, false
+	return err, hasBeenApplied
+}
+
+// RollbackMigration runs the Down of an applied migration and unmarks it, in the same database transaction for
+// transactional migrations
+func (ms *MigrationServiceImpl) RollbackMigration(
+	databaseName string,
+	track string,
+	migration versions.Migration,
+) (error, bool) {
+	hasBeenRolledBack := false
+	err := ms.inTransaction(migration, func(ctx context.Context) error {
+		hasBeenRolledBack = false
+		applied, err := ms.checkIfApplied(ctx, migration.Version)
+		if err != nil {
+			ms.logger.Error("Unable to check if migration has been applied", slog.String("version", migration.Version),
+				logging.Err(err))
+			return err
+		}
+		if !applied {
+			ms.logger.Info("Migration has not been applied", slog.String("version", migration.Version),
+				slog.String("track", track))
+			return nil
+		}
+		startedAt := time.Now()
+		err = migration.Down(ms.client, ctx, databaseName)
+		if err != nil {
+			ms.logger.Error("Unable to roll back migration", slog.String("version", migration.Version),
+				logging.Err(err))
+			return err
+		}
+		hasBeenRolledBack = !migration.Transactional
+		err = ms.unmarkAsApplied(ctx, migration.Version)
+		if err != nil {
+			ms.logger.Error("Unable to unmark migration as applied", slog.String("version", migration.Version),
+				logging.Err(err))
+			return err
+		}
+		hasBeenRolledBack = true
+		ms.logger.Info("Rolled back migration", slog.String("version", migration.Version),
+			slog.Duration("duration", time.Since(startedAt)))
+		return nil
+	})
+	if err != nil && migration.Transactional {
+		hasBeenRolledBack = false
+	}
+	return err, hasBeenRolledBack
+}
+
+// inTransaction runs fn in a database transaction when the migration allows it, and directly otherwise. The driver
+// retries the whole transaction on transient errors, so fn may run more than once.
+func (ms *MigrationServiceImpl) inTransaction(migration versions.Migration, fn func(ctx context.Context) error) error {
+	if !migration.Transactional {
+		return fn(ms.ctx)
 	}
-	err = migration.Up(ms.client, ms.ctx, databaseName)
+	session, err := ms.client.StartSession()
 	if err != nil {
-		log.Printf("Error when applying migration %s: %v", migration.Version, err)
-		return err, false
+		return fmt.Errorf("error when starting session for migration %s: %w", migration.Version, err)
 	}
-	err = ms.MarkAsApplied(migration.Version)
+	defer session.EndSession(ms.ctx)
+	_, err = session.WithTransaction(ms.ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+	return err
+}
+
+// VerifyMigrations checks the applied migrations of the track against the checksums recorded when they were applied.
+// Records from before checksums were kept, or of the legacy checksum of the current source, are given the checksum
+// and description of the current source.
+func (ms *MigrationServiceImpl) VerifyMigrations(track versions.Track) error {
+	changed := make([]string, 0)
+	for _, elem := range track.Migrations {
+		record, err := ms.getRecord(ms.ctx, elem.Version)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error when getting record of migration %s: %w", elem.Version, err)
+		}
+		if elem.Checksum == "" {
+			continue
+		}
+		if record.Checksum == "" || record.Checksum == elem.LegacyChecksum {
+			if err = ms.recordIdentity(elem, track.Name); err != nil {
+				return fmt.Errorf("error when recording checksum of migration %s: %w", elem.Version, err)
+			}
+			continue
+		}
+		if record.Checksum != elem.Checksum {
+			ms.logger.Warn("Migration has changed since it was applied", slog.String("version", elem.Version),
+				slog.String("description", elem.Description), slog.String("applied_description", record.Description))
+			changed = append(changed, elem.Version)
+		}
+	}
+	if len(changed) == 0 || ms.warnOnChanged {
+		return nil
+	}
+	return fmt.Errorf("%w: %v", ErrMigrationChanged, changed)
+}
+
+func (ms *MigrationServiceImpl) recordIdentity(migration versions.Migration, track string) error {
+	db := ms.client.Database(ms.migrationDatabaseName)
+	collection := db.Collection(ms.migrationCollectionName)
+	mongoCtx, cancel := context.WithTimeout(ms.ctx, MigrationTimeout)
+	defer cancel()
+	update := bson.M{"$set": bson.M{
+		"track":       track,
+		"description": migration.Description,
+		"checksum":    migration.Checksum,
+	}}
+	_, err := collection.UpdateOne(mongoCtx, bson.M{"version": migration.Version}, update)
+	return err
+}
+
+// withLock runs fn while holding the migration lock, so that concurrent migrators never run the same migration twice
+func (ms *MigrationServiceImpl) withLock(track versions.Track, fn func(lock *MigrationLock) error) error {
+	lock, err := ms.AcquireLock(LockWaitTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			ms.logger.Error("Unable to release the migration lock", logging.Err(err))
+		}
+	}()
+	if err = ms.VerifyMigrations(track); err != nil {
+		return err
+	}
+	return fn(lock)
+}
+
+func (ms *MigrationServiceImpl) MigrateUp(databaseName string, track versions.Track, target int) error {
+	if err := validateTarget(track, target); err != nil {
+		return err
+	}
+	return ms.withLock(track, func(lock *MigrationLock) error {
+		return ms.migrateUp(databaseName, track, target, lock)
+	})
+}
+
+func (ms *MigrationServiceImpl) migrateUp(
+	databaseName string,
+	track versions.Track,
+	target int,
+	lock *MigrationLock,
+) error {
+	for _, elem := range track.Migrations[:target] {
+		if lock.Lost() {
+			return ErrMigrationLockLost
+		}
+		ms.logger.Info("Applying migration", slog.String("version", elem.Version))
+		err, hasBeenApplied := ms.ApplyMigration(databaseName, track.Name, elem)
+		if err != nil {
+			return fmt.Errorf("error when applying migration %s: %w", elem.Version, err)
+		}
+		if hasBeenApplied {
+			ms.logger.Info("Migration has been applied", slog.String("version", elem.Version))
+		} else {
+			ms.logger.Info("Migration has not been applied by this run", slog.String("version", elem.Version))
+		}
+	}
+	return nil
+}
+
+func (ms *MigrationServiceImpl) MigrateDown(databaseName string, track versions.Track, target int) error {
+	if err := validateTarget(track, target); err != nil {
+		return err
+	}
+	return ms.withLock(track, func(lock *MigrationLock) error {
+		return ms.migrateDown(databaseName, track, target, lock)
+	})
+}
+
+func (ms *MigrationServiceImpl) migrateDown(
+	databaseName string,
+	track versions.Track,
+	target int,
+	lock *MigrationLock,
+) error {
+	for i := len(track.Migrations) - 1; i >= target; i-- {
+		if lock.Lost() {
+			return ErrMigrationLockLost
+		}
+		elem := track.Migrations[i]
+		err, hasBeenRolledBack := ms.RollbackMigration(databaseName, track.Name, elem)
+		if err != nil {
+			return fmt.Errorf("error when rolling back migration %s: %w", elem.Version, err)
+		}
+		if hasBeenRolledBack {
+			ms.logger.Info("Migration has been rolled back", slog.String("version", elem.Version))
+		}
+	}
+	return nil
+}
+
+func (ms *MigrationServiceImpl) Status(track versions.Track) ([]MigrationStatus, error) {
+	statuses := make([]MigrationStatus, len(track.Migrations))
+	for i, elem := range track.Migrations {
+		statuses[i] = MigrationStatus{
+			Track:       track.Name,
+			Number:      i + 1,
+			Version:     elem.Version,
+			Description: elem.Description,
+		}
+		record, err := ms.getRecord(ms.ctx, elem.Version)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error when getting record of migration %s: %w", elem.Version, err)
+		}
+		statuses[i].Applied = true
+		statuses[i].AppliedAt = record.AppliedAt
+		statuses[i].Duration = time.Duration(record.DurationMs) * time.Millisecond
+		statuses[i].Changed = record.Checksum != "" && elem.Checksum != "" && record.Checksum != elem.Checksum &&
+			record.Checksum != elem.LegacyChecksum
+	}
+	return statuses, nil
+}
+
+// LatestApplied returns the number of the latest applied migration of the track, or 0 when none has been applied
+func (ms *MigrationServiceImpl) LatestApplied(track versions.Track) (int, error) {
+	statuses, err := ms.Status(track)
+	if err != nil {
+		return 0, err
+	}
+	latest := 0
+	for _, elem := range statuses {
+		if elem.Applied {
+			latest = elem.Number
+		}
+	}
+	return latest, nil
+}
+
+func (ms *MigrationServiceImpl) getRecord(ctx context.Context, version string) (*MigrationRecord, error) {
+	db := ms.client.Database(ms.migrationDatabaseName)
+	collection := db.Collection(ms.migrationCollectionName)
+	mongoCtx, cancel := context.WithTimeout(ctx, MigrationTimeout)
+	defer cancel()
+	var record MigrationRecord
+	err := collection.FindOne(mongoCtx, bson.M{"version": version}).Decode(&record)
 	if err != nil {
-		log.Printf("Error when marking migration %s as applied: %v", migration.Version, err)
-		return err, true
+		return nil, err
 	}
-	return nil, true
+	return &record, nil
+}
+
+func validateTarget(track versions.Track, target int) error {
+	if target < 0 || target > len(track.Migrations) {
+		return fmt.Errorf("target version %d of track %s must be between 0 and %d", target, track.Name,
+			len(track.Migrations))
+	}
+	return nil
 }
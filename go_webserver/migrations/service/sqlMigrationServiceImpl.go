@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/logging"
+	"webserver/migrations/versions"
+)
+
+// SQLMigrationTableName is the table applied SQL migrations are recorded in, in the database they were applied to
+const SQLMigrationTableName = "schema_migrations"
+
+// SQLMigrationServiceImpl applies the migrations of SQL tracks. Every migration is applied or rolled back in an
+// immediate transaction that also records it, which holds the write lock of the database from its beginning. Concurrent
+// migrators thus take turns, and each finds the migrations applied by the others already recorded.
+type SQLMigrationServiceImpl struct {
+	db  *sql.DB
+	ctx context.Context
+	// warnOnChanged only logs applied migrations whose checksum changed instead of refusing to run
+	warnOnChanged bool
+	logger        *slog.Logger
+}
+
+func NewSQLMigrationService(db *sql.DB, ctx context.Context, logger *slog.Logger) *SQLMigrationServiceImpl {
+	return &SQLMigrationServiceImpl{
+		db:     db,
+		ctx:    ctx,
+		logger: logger,
+	}
+}
+
+// SetWarnOnChangedMigrations makes the service log applied migrations that changed instead of refusing to migrate
+func (ms *SQLMigrationServiceImpl) SetWarnOnChangedMigrations(warn bool) {
+	ms.warnOnChanged = warn
+}
+
+func (ms *SQLMigrationServiceImpl) ensureMigrationTable() error {
+	ctx, cancel := context.WithTimeout(ms.ctx, MigrationTimeout)
+	defer cancel()
+	_, err := ms.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+SQLMigrationTableName+` (
+		version TEXT PRIMARY KEY,
+		track TEXT NOT NULL,
+		description TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at INTEGER NOT NULL,
+		duration_ms INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("error when creating table %s: %w", SQLMigrationTableName, err)
+	}
+	return nil
+}
+
+// ApplyMigration runs the migration and records it as applied in the same database transaction
+func (ms *SQLMigrationServiceImpl) ApplyMigration(track string, migration versions.SQLMigration) (error, bool) {
+	hasBeenApplied := false
+	err := ms.inTransaction(func(tx *sqlite.Tx, ctx context.Context) error {
+		record, err := ms.getRecord(tx, ctx, migration.Version)
+		if err != nil {
+			ms.logger.Error("Unable to check if migration has been applied", slog.String("version", migration.Version),
+				logging.Err(err))
+			return err
+		}
+		if record != nil {
+			ms.logger.Info("Migration has already been applied", slog.String("version", migration.Version))
+			return nil
+		}
+		startedAt := time.Now()
+		if err = migration.Up(tx, ctx); err != nil {
+			ms.logger.Error("Unable to apply migration", slog.String("version", migration.Version), logging.Err(err))
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO `+SQLMigrationTableName+
+			` (version, track, description, checksum, applied_at, duration_ms) VALUES (?, ?, ?, ?, ?, ?)`,
+			migration.Version, track, migration.Description, migration.Checksum, startedAt.UnixMilli(),
+			time.Since(startedAt).Milliseconds())
+		if err != nil {
+			ms.logger.Error("Unable to mark migration as applied", slog.String("version", migration.Version),
+				logging.Err(err))
+			return err
+		}
+		hasBeenApplied = true
+		return nil
+	})
+	if err != nil {
+		hasBeenApplied = false
+	}
+	return err, hasBeenApplied
+}
+
+// RollbackMigration runs the Down of an applied migration and removes its record in the same database transaction
+func (ms *SQLMigrationServiceImpl) RollbackMigration(track string, migration versions.SQLMigration) (error, bool) {
+	hasBeenRolledBack := false
+	err := ms.inTransaction(func(tx *sqlite.Tx, ctx context.Context) error {
+		record, err := ms.getRecord(tx, ctx, migration.Version)
+		if err != nil {
+			ms.logger.Error("Unable to check if migration has been applied", slog.String("version", migration.Version),
+				logging.Err(err))
+			return err
+		}
+		if record == nil {
+			ms.logger.Info("Migration has not been applied", slog.String("version", migration.Version),
+				slog.String("track", track))
+			return nil
+		}
+		startedAt := time.Now()
+		if err = migration.Down(tx, ctx); err != nil {
+			ms.logger.Error("Unable to roll back migration", slog.String("version", migration.Version),
+				logging.Err(err))
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM `+SQLMigrationTableName+` WHERE version = ?`, migration.Version)
+		if err != nil {
+			ms.logger.Error("Unable to unmark migration as applied", slog.String("version", migration.Version),
+				logging.Err(err))
+			return err
+		}
+		hasBeenRolledBack = true
+		ms.logger.Info("Rolled back migration", slog.String("version", migration.Version),
+			slog.Duration("duration", time.Since(startedAt)))
+		return nil
+	})
+	if err != nil {
+		hasBeenRolledBack = false
+	}
+	return err, hasBeenRolledBack
+}
+
+func (ms *SQLMigrationServiceImpl) inTransaction(fn func(tx *sqlite.Tx, ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ms.ctx, MigrationTimeout)
+	defer cancel()
+	tx, err := sqlite.Begin(ctx, ms.db, sqlite.TxOptions{Mode: sqlite.Immediate})
+	if err != nil {
+		return err
+	}
+	if err = fn(tx, ctx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			ms.logger.Error("Unable to roll back migration transaction", logging.Err(rollbackErr))
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// VerifyMigrations checks the applied migrations of the track against the checksums recorded when they were applied.
+// Records of the legacy checksum of the current source are given its checksum and description.
+func (ms *SQLMigrationServiceImpl) VerifyMigrations(track versions.SQLTrack) error {
+	changed := make([]string, 0)
+	for _, elem := range track.Migrations {
+		record, err := ms.getRecord(ms.db, ms.ctx, elem.Version)
+		if err != nil {
+			return fmt.Errorf("error when getting record of migration %s: %w", elem.Version, err)
+		}
+		if record != nil && elem.LegacyChecksum != "" && record.Checksum == elem.LegacyChecksum {
+			_, err = ms.db.ExecContext(ms.ctx, `UPDATE `+SQLMigrationTableName+
+				` SET description = ?, checksum = ? WHERE version = ?`, elem.Description, elem.Checksum, elem.Version)
+			if err != nil {
+				return fmt.Errorf("error when recording checksum of migration %s: %w", elem.Version, err)
+			}
+			continue
+		}
+		if record != nil && record.Checksum != elem.Checksum {
+			ms.logger.Warn("Migration has changed since it was applied", slog.String("version", elem.Version),
+				slog.String("description", elem.Description), slog.String("applied_description", record.Description))
+			changed = append(changed, elem.Version)
+		}
+	}
+	if len(changed) == 0 || ms.warnOnChanged {
+		return nil
+	}
+	return fmt.Errorf("%w: %v", ErrMigrationChanged, changed)
+}
+
+func (ms *SQLMigrationServiceImpl) MigrateUp(track versions.SQLTrack, target int) error {
+	if err := validateSQLTarget(track, target); err != nil {
+		return err
+	}
+	if err := ms.prepare(track); err != nil {
+		return err
+	}
+	for _, elem := range track.Migrations[:target] {
+		ms.logger.Info("Applying migration", slog.String("version", elem.Version))
+		err, hasBeenApplied := ms.ApplyMigration(track.Name, elem)
+		if err != nil {
+			return fmt.Errorf("error when applying migration %s: %w", elem.Version, err)
+		}
+		if hasBeenApplied {
+			ms.logger.Info("Migration has been applied", slog.String("version", elem.Version))
+		} else {
+			ms.logger.Info("Migration has not been applied by this run", slog.String("version", elem.Version))
+		}
+	}
+	return nil
+}
+
+func (ms *SQLMigrationServiceImpl) MigrateDown(track versions.SQLTrack, target int) error {
+	if err := validateSQLTarget(track, target); err != nil {
+		return err
+	}
+	if err := ms.prepare(track); err != nil {
+		return err
+	}
+	for i := len(track.Migrations) - 1; i >= target; i-- {
+		elem := track.Migrations[i]
+		err, hasBeenRolledBack := ms.RollbackMigration(track.Name, elem)
+		if err != nil {
+			return fmt.Errorf("error when rolling back migration %s: %w", elem.Version, err)
+		}
+		if hasBeenRolledBack {
+			ms.logger.Info("Migration has been rolled back", slog.String("version", elem.Version))
+		}
+	}
+	return nil
+}
+
+// prepare creates the table migrations are recorded in and checks that no applied migration has changed
+func (ms *SQLMigrationServiceImpl) prepare(track versions.SQLTrack) error {
+	if err := ms.ensureMigrationTable(); err != nil {
+		return err
+	}
+	return ms.VerifyMigrations(track)
+}
+
+func (ms *SQLMigrationServiceImpl) Status(track versions.SQLTrack) ([]MigrationStatus, error) {
+	if err := ms.ensureMigrationTable(); err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, len(track.Migrations))
+	for i, elem := range track.Migrations {
+		statuses[i] = MigrationStatus{
+			Track:       track.Name,
+			Number:      i + 1,
+			Version:     elem.Version,
+			Description: elem.Description,
+		}
+		record, err := ms.getRecord(ms.db, ms.ctx, elem.Version)
+		if err != nil {
+			return nil, fmt.Errorf("error when getting record of migration %s: %w", elem.Version, err)
+		}
+		if record == nil {
+			continue
+		}
+		statuses[i].Applied = true
+		statuses[i].AppliedAt = record.AppliedAt
+		statuses[i].Duration = time.Duration(record.DurationMs) * time.Millisecond
+		statuses[i].Changed = record.Checksum != elem.Checksum && record.Checksum != elem.LegacyChecksum
+	}
+	return statuses, nil
+}
+
+// LatestApplied returns the number of the latest applied migration of the track, or 0 when none has been applied
+func (ms *SQLMigrationServiceImpl) LatestApplied(track versions.SQLTrack) (int, error) {
+	statuses, err := ms.Status(track)
+	if err != nil {
+		return 0, err
+	}
+	latest := 0
+	for _, elem := range statuses {
+		if elem.Applied {
+			latest = elem.Number
+		}
+	}
+	return latest, nil
+}
+
+// getRecord returns the record of the migration, or nil when it has not been applied
+func (ms *SQLMigrationServiceImpl) getRecord(
+	q sqlite.Querier,
+	ctx context.Context,
+	version string,
+) (*MigrationRecord, error) {
+	var record MigrationRecord
+	var appliedAt int64
+	err := q.QueryRowContext(ctx, `SELECT version, track, description, checksum, applied_at, duration_ms FROM `+
+		SQLMigrationTableName+` WHERE version = ?`, version).Scan(&record.Version, &record.Track,
+		&record.Description, &record.Checksum, &appliedAt, &record.DurationMs)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	record.AppliedAt = time.UnixMilli(appliedAt).UTC()
+	return &record, nil
+}
+
+func validateSQLTarget(track versions.SQLTrack, target int) error {
+	if target < 0 || target > len(track.Migrations) {
+		return fmt.Errorf("target version %d of track %s must be between 0 and %d", target, track.Name,
+			len(track.Migrations))
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/logging"
+	"webserver/migrations/versions"
+	"webserver/migrations/versions/sqlschema"
+)
+
+func TestSQLMigrationService(t *testing.T) {
+	newService := func(t *testing.T) *SQLMigrationServiceImpl {
+		db, err := sqlite.Open(filepath.Join(t.TempDir(), "migrations.db"))
+		if err != nil {
+			t.Fatalf("Failed to open SQLite database: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = db.Close()
+		})
+		return NewSQLMigrationService(db, context.Background(), logging.Discard())
+	}
+	tableExists := func(t *testing.T, ms *SQLMigrationServiceImpl, table string) bool {
+		var count int
+		err := ms.db.QueryRow(`SELECT count(*) FROM sqlite_schema WHERE type = 'table' AND name = ?`,
+			table).Scan(&count)
+		assert.Nil(t, err)
+		return count == 1
+	}
+	track := sqlschema.Track
+
+	t.Run("Migrates up and down to the target", func(t *testing.T) {
+		ms := newService(t)
+		assert.Nil(t, ms.MigrateUp(track, 2))
+		assert.True(t, tableExists(t, ms, sqlschema.TransactionTableName))
+		assert.False(t, tableExists(t, ms, sqlschema.IdempotencyKeyTableName))
+		latest, err := ms.LatestApplied(track)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, latest)
+
+		assert.Nil(t, ms.MigrateUp(track, len(track.Migrations)))
+		statuses, err := ms.Status(track)
+		assert.Nil(t, err)
+		for _, status := range statuses {
+			assert.True(t, status.Applied)
+			assert.False(t, status.Changed)
+			assert.False(t, status.AppliedAt.IsZero())
+		}
+
+		assert.Nil(t, ms.MigrateDown(track, 1))
+		assert.True(t, tableExists(t, ms, sqlschema.AccountTableName))
+		assert.False(t, tableExists(t, ms, sqlschema.TransactionTableName))
+		latest, err = ms.LatestApplied(track)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, latest)
+	})
+
+	t.Run("Failed migrations leave nothing behind", func(t *testing.T) {
+		ms := newService(t)
+		failing := versions.SQLTrack{Name: "schema", Migrations: []versions.SQLMigration{{
+			Version:  "1__Schema",
+			Checksum: "checksum",
+			Up: func(tx versions.SQLExecutor, ctx context.Context) error {
+				if _, err := tx.ExecContext(ctx, `CREATE TABLE partial (id TEXT)`); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `CREATE TABLE partial (id TEXT)`)
+				return err
+			},
+		}}}
+		assert.NotNil(t, ms.MigrateUp(failing, 1))
+		assert.False(t, tableExists(t, ms, "partial"))
+		latest, err := ms.LatestApplied(failing)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, latest)
+	})
+
+	t.Run("Refuses to migrate when an applied migration changed", func(t *testing.T) {
+		ms := newService(t)
+		assert.Nil(t, ms.MigrateUp(track, 1))
+		changed := versions.SQLTrack{Name: track.Name, Migrations: append([]versions.SQLMigration{},
+			track.Migrations...)}
+		changed.Migrations[0].Checksum = "changed"
+		assert.ErrorIs(t, ms.MigrateUp(changed, 2), ErrMigrationChanged)
+		statuses, err := ms.Status(changed)
+		assert.Nil(t, err)
+		assert.True(t, statuses[0].Changed)
+
+		ms.SetWarnOnChangedMigrations(true)
+		assert.Nil(t, ms.MigrateUp(changed, 2))
+	})
+
+	t.Run("Upgrades records of the legacy checksum of unchanged migrations", func(t *testing.T) {
+		ms := newService(t)
+		assert.Nil(t, ms.MigrateUp(track, 1))
+		_, err := ms.db.Exec(`UPDATE `+SQLMigrationTableName+` SET checksum = ? WHERE version = ?`,
+			track.Migrations[0].LegacyChecksum, track.Migrations[0].Version)
+		assert.Nil(t, err)
+
+		statuses, err := ms.Status(track)
+		assert.Nil(t, err)
+		assert.False(t, statuses[0].Changed)
+		assert.Nil(t, ms.MigrateUp(track, 2))
+		record, err := ms.getRecord(ms.db, ms.ctx, track.Migrations[0].Version)
+		assert.Nil(t, err)
+		assert.Equal(t, track.Migrations[0].Checksum, record.Checksum)
+	})
+}
@@ -1,13 +1,21 @@
 package data
 
 import (
+	"embed"
 	"time"
 	"webserver/migrations/versions"
 )
 
 const timeout = time.Minute * 1
 
+//go:embed v*.go
+var sources embed.FS
+
 var Migrations = []versions.Migration{
 	MigrationData1,
 	MigrationData2,
+	MigrationData3,
+	MigrationData4,
 }
+
+var Track = versions.NewTrack("data", Migrations, sources)
@@ -117,7 +117,8 @@ var accounts = []interface{}{
 }
 
 var MigrationData1 = versions.Migration{
-	Version: "1__Data",
+	Version:       "1__Data",
+	Transactional: true,
 	Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
 		db := client.Database(databaseName)
 		mongoCtx, cancel := context.WithTimeout(ctx, timeout)
@@ -110,7 +110,8 @@ func createRandomTransactions() []interface{} {
 }
 
 var MigrationData2 = versions.Migration{
-	Version: "2__Data",
+	Version:       "2__Data",
+	Transactional: true,
 	Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
 		db := client.Database(databaseName)
 		mongoCtx, cancel := context.WithTimeout(ctx, timeout)
@@ -0,0 +1,61 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"webserver/internal/pkg/infrastructure/mongodb"
+	"webserver/internal/pkg/utils"
+	"webserver/migrations/versions"
+	"webserver/migrations/versions/schema"
+)
+
+var MigrationData3 = versions.Migration{
+	Version:       "3__Data",
+	Transactional: true,
+	Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		coll := db.Collection(schema.AccountCollectionName)
+
+		projection := bson.M{"_id": 1, "username": 1, "password": 1}
+		cursor, err := coll.Find(mongoCtx, bson.M{}, options.Find().SetProjection(projection))
+		if err != nil {
+			return err
+		}
+		var credentials []mongodb.MongoAccountCredentialsOutput
+		if err = cursor.All(mongoCtx, &credentials); err != nil {
+			return err
+		}
+
+		hashed := 0
+		for _, elem := range credentials {
+			// Hashes that are already encoded are left alone so that the migration can be safely re-run
+			if utils.IsPasswordHash(elem.Password) {
+				continue
+			}
+			passwordHash, err := utils.HashPassword(elem.Password)
+			if err != nil {
+				return fmt.Errorf("error when hashing password for account %s: %w", elem.Username, err)
+			}
+			filter := bson.M{"_id": elem.Id, "password": elem.Password}
+			_, err = coll.UpdateOne(mongoCtx, filter, bson.M{"$set": bson.M{"password": passwordHash}})
+			if err != nil {
+				return fmt.Errorf("error when storing password hash for account %s: %w", elem.Username, err)
+			}
+			hashed++
+		}
+
+		log.Printf("%d plaintext account passwords successfully hashed", hashed)
+		return nil
+	},
+	Down: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		// Hashing is one-way, so the plaintext passwords cannot be restored. Logins keep working against the hashes.
+		log.Printf("account password hashes are kept since the plaintext passwords cannot be restored")
+		return nil
+	},
+}
@@ -0,0 +1,122 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/mongodb"
+	"webserver/internal/pkg/utils"
+	"webserver/migrations/versions"
+	"webserver/migrations/versions/schema"
+)
+
+// MigrationData4 posts an opening journal entry for the balances every bank account held before transfers were
+// journaled, balanced against the opening balance equity account
+var MigrationData4 = versions.Migration{
+	Version:       "4__Data",
+	Transactional: true,
+	Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		journalColl := db.Collection(schema.JournalEntryCollectionName)
+
+		// Opening entries are only posted once so that the migration can be safely re-run
+		opened, err := journalColl.CountDocuments(mongoCtx, bson.M{"kind": string(model.OpeningBalanceEntry)})
+		if err != nil {
+			return err
+		}
+		if opened > 0 {
+			log.Printf("Opening journal entries already posted")
+			return nil
+		}
+
+		pipeline := mongo.Pipeline{
+			{{"$unwind", "$bankAccounts"}},
+			{{"$replaceRoot", bson.D{{"newRoot", "$bankAccounts"}}}},
+		}
+		cursor, err := db.Collection(schema.AccountCollectionName).Aggregate(mongoCtx, pipeline)
+		if err != nil {
+			return err
+		}
+		var balances []mongodb.MongoBankAccountBalance
+		if err = cursor.All(mongoCtx, &balances); err != nil {
+			return err
+		}
+
+		equityAccountId, err := utils.StringToObjectId(model.OpeningBalanceEquityAccountId)
+		if err != nil {
+			return err
+		}
+		posted := 0
+		for _, elem := range balances {
+			postings, err := openingPostings(elem, equityAccountId)
+			if err != nil {
+				return fmt.Errorf("error when building opening postings for BankAccount %s: %w", elem.Id.Hex(), err)
+			}
+			if len(postings) == 0 {
+				continue
+			}
+			entry := mongodb.MongoJournalEntryInput{
+				Kind:      string(model.OpeningBalanceEntry),
+				Postings:  postings,
+				CreatedAt: utils.GetCurrentTimestamp(),
+			}
+			if _, err = journalColl.InsertOne(mongoCtx, entry); err != nil {
+				return fmt.Errorf("error when posting opening entry for BankAccount %s: %w", elem.Id.Hex(), err)
+			}
+			posted++
+		}
+
+		log.Printf("%d opening journal entries successfully posted", posted)
+		return nil
+	},
+	Down: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		_, err := db.Collection(schema.JournalEntryCollectionName).
+			DeleteMany(mongoCtx, bson.M{"kind": string(model.OpeningBalanceEntry)})
+		if err != nil {
+			return err
+		}
+		return nil
+	},
+}
+
+// openingPostings moves each non-zero balance of the bank account out of the opening balance equity account
+func openingPostings(
+	balance mongodb.MongoBankAccountBalance,
+	equityAccountId primitive.ObjectID,
+) ([]mongodb.MongoPosting, error) {
+	amounts := []struct {
+		kind   model.BalanceKind
+		amount primitive.Decimal128
+	}{
+		{model.AvailableBalance, balance.AvailableBalance},
+		{model.PendingBalance, balance.PendingBalance},
+	}
+	postings := make([]mongodb.MongoPosting, 0, 4)
+	for _, elem := range amounts {
+		amount, err := utils.FromPrimitiveDecimal128ToDecimal(elem.amount)
+		if err != nil {
+			return nil, err
+		}
+		if amount.IsZero() {
+			continue
+		}
+		negativeAmount, err := utils.FromDecimalToPrimitiveDecimal128(amount.Neg())
+		if err != nil {
+			return nil, err
+		}
+		postings = append(postings,
+			mongodb.MongoPosting{BankAccountId: balance.Id, Balance: string(elem.kind), Amount: elem.amount},
+			mongodb.MongoPosting{BankAccountId: equityAccountId, Balance: string(elem.kind), Amount: negativeAmount},
+		)
+	}
+	return postings, nil
+}
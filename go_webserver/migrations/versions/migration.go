@@ -2,11 +2,38 @@ package versions
 
 import (
 	"context"
+	"database/sql"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Migration struct {
 	Version string
-	Up      func(db *mongo.Client, ctx context.Context, databaseName string) error
-	Down    func(db *mongo.Client, ctx context.Context, databaseName string) error
+	// Description and Checksum identify what an applied version did, and are filled in from the source of the
+	// migration by NewTrack unless given
+	Description string
+	Checksum    string
+	// LegacyChecksum is the SHA-256 of the whole source file, which checksums were until they ignored comments,
+	// formatting and imports. Records of it are upgraded to Checksum.
+	LegacyChecksum string
+	// Transactional migrations only read and write documents, so they run in the same database transaction that
+	// marks them as applied. Migrations that create collections or indexes cannot.
+	Transactional bool
+	Up            func(db *mongo.Client, ctx context.Context, databaseName string) error
+	Down          func(db *mongo.Client, ctx context.Context, databaseName string) error
+}
+
+// SQLMigration is a migration of a SQL database. SQLite creates and drops tables within transactions, so every SQL
+// migration runs in the same database transaction that marks it as applied.
+type SQLMigration struct {
+	Version        string
+	Description    string
+	Checksum       string
+	LegacyChecksum string
+	Up             func(tx SQLExecutor, ctx context.Context) error
+	Down           func(tx SQLExecutor, ctx context.Context) error
+}
+
+// SQLExecutor runs the statements of a SQL migration within its transaction
+type SQLExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 }
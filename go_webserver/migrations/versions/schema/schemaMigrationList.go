@@ -1,8 +1,24 @@
 package schema
 
-import "webserver/migrations/versions"
+import (
+	"embed"
+	"webserver/migrations/versions"
+)
+
+//go:embed v*.go
+var sources embed.FS
 
 var SchemaMigrations = []versions.Migration{
 	MigrationSchema1,
 	MigrationSchema2,
+	MigrationSchema3,
+	MigrationSchema4,
+	MigrationSchema5,
+	MigrationSchema6,
+	MigrationSchema7,
+	MigrationSchema8,
+	MigrationSchema9,
+	MigrationSchema10,
 }
+
+var Track = versions.NewTrack("schema", SchemaMigrations, sources)
@@ -0,0 +1,35 @@
+package schema
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"webserver/migrations/service"
+	"webserver/migrations/versions"
+)
+
+// MigrationSchema10 deletes the balance snapshots taken before snapshots held the opening balances, extremes and
+// totals of their day, which balance histories read instead of replaying the transactions of the day. Snapshots only
+// derive from transactions, so the snapshotter takes them again from the last day that ended on, and the
+// snapshotbackfill command takes them again for earlier days. The new fields need no validation change, since the
+// validator of the balance snapshot collection allows fields it does not list.
+var MigrationSchema10 = versions.Migration{
+	Version: "10__Schema",
+	Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+
+		res, err := db.Collection(BalanceSnapshotCollectionName).DeleteMany(mongoCtx, bson.M{})
+		if err != nil {
+			return err
+		}
+		log.Printf("Deleted %d documents of collection %s", res.DeletedCount, BalanceSnapshotCollectionName)
+		return nil
+	},
+	Down: func(*mongo.Client, context.Context, string) error {
+		// Earlier versions read the snapshots taken since and ignore the fields they do not know
+		return nil
+	},
+}
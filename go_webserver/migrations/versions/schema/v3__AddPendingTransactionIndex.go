@@ -0,0 +1,49 @@
+package schema
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"webserver/migrations/service"
+	"webserver/migrations/versions"
+)
+
+const PendingTransactionExpiryIndexName = "pending_transaction_expiry"
+
+var MigrationSchema3 = versions.Migration{
+	Version: "3__Schema",
+	Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+
+		// Supports the lookup of active pending transactions past their expiration date
+		index := mongo.IndexModel{
+			Keys: bson.D{{"status", 1}, {"expirationDate", 1}},
+			Options: options.Index().
+				SetName(PendingTransactionExpiryIndexName).
+				SetPartialFilterExpression(bson.M{"type": "pending"}),
+		}
+
+		_, err := db.Collection(TransactionCollectionName).Indexes().CreateOne(mongoCtx, index)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Index %s created on collection %s", PendingTransactionExpiryIndexName, TransactionCollectionName)
+		return nil
+	},
+	Down: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+		indexes := db.Collection(TransactionCollectionName).Indexes()
+		_, err := indexes.DropOne(mongoCtx, PendingTransactionExpiryIndexName)
+		if err != nil {
+			return err
+		}
+		return nil
+	},
+}
@@ -0,0 +1,91 @@
+package schema
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+	"webserver/migrations/service"
+	"webserver/migrations/versions"
+)
+
+const IdempotencyKeyCollectionName = "idempotency_key"
+
+// IdempotencyKeyTTL is how long a client can safely retry a request with the same idempotency key
+const IdempotencyKeyTTL = 24 * time.Hour
+
+var MigrationSchema4 = versions.Migration{
+	Version: "4__Schema",
+	Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+
+		validation := bson.M{
+			"$jsonSchema": bson.M{
+				"bsonType": "object",
+				"required": []string{"accountId", "key", "requestHash", "transactionId", "createdAt"},
+				"properties": bson.M{
+					"accountId": bson.M{
+						"bsonType":    "objectId",
+						"description": "the account that sent the request [required]",
+					},
+					"key": bson.M{
+						"bsonType":    "string",
+						"description": "the idempotency key given by the client [required]",
+					},
+					"requestHash": bson.M{
+						"bsonType":    "string",
+						"description": "the hash of the request the key was first used for [required]",
+					},
+					"transactionId": bson.M{
+						"bsonType":    "objectId",
+						"description": "the transaction created by the request [required]",
+					},
+					"createdAt": bson.M{
+						"bsonType":    "date",
+						"description": "the time the key was first used, after which it expires [required]",
+					},
+				},
+			},
+		}
+
+		opts := options.CreateCollection().SetValidator(validation).SetValidationLevel("strict")
+		err := db.CreateCollection(mongoCtx, IdempotencyKeyCollectionName, opts)
+		if err != nil {
+			return err
+		}
+
+		indexes := []mongo.IndexModel{
+			{
+				Keys:    bson.D{{"accountId", 1}, {"key", 1}},
+				Options: options.Index().SetName("account_idempotency_key").SetUnique(true),
+			},
+			{
+				Keys: bson.D{{"createdAt", 1}},
+				Options: options.Index().
+					SetName("idempotency_key_expiry").
+					SetExpireAfterSeconds(int32(IdempotencyKeyTTL.Seconds())),
+			},
+		}
+		_, err = db.Collection(IdempotencyKeyCollectionName).Indexes().CreateMany(mongoCtx, indexes)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Collection %s created with validation rules and indexes", IdempotencyKeyCollectionName)
+		return nil
+	},
+	Down: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+		err := db.Collection(IdempotencyKeyCollectionName).Drop(mongoCtx)
+		if err != nil {
+			return err
+		}
+		return nil
+	},
+}
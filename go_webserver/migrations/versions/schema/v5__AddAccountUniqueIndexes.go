@@ -0,0 +1,62 @@
+package schema
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"webserver/migrations/service"
+	"webserver/migrations/versions"
+)
+
+const (
+	UsernameIndexName      = "unique_username"
+	AccountNumberIndexName = "unique_bank_account_number"
+)
+
+var MigrationSchema5 = versions.Migration{
+	Version: "5__Schema",
+	Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+
+		// Registration and bank account opening rely on these indexes to reject duplicates. Accounts without bank
+		// accounts are left out of the account number index, as they would otherwise collide on a missing value.
+		indexes := []mongo.IndexModel{
+			{
+				Keys:    bson.D{{"username", 1}},
+				Options: options.Index().SetName(UsernameIndexName).SetUnique(true),
+			},
+			{
+				Keys: bson.D{{"bankAccounts.accountNumber", 1}},
+				Options: options.Index().
+					SetName(AccountNumberIndexName).
+					SetUnique(true).
+					SetPartialFilterExpression(bson.M{"bankAccounts.accountNumber": bson.M{"$exists": true}}),
+			},
+		}
+
+		_, err := db.Collection(AccountCollectionName).Indexes().CreateMany(mongoCtx, indexes)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Indexes %s and %s created on collection %s", UsernameIndexName, AccountNumberIndexName,
+			AccountCollectionName)
+		return nil
+	},
+	Down: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+		indexes := db.Collection(AccountCollectionName).Indexes()
+		for _, name := range []string{UsernameIndexName, AccountNumberIndexName} {
+			if _, err := indexes.DropOne(mongoCtx, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,58 @@
+package schema
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"webserver/migrations/service"
+	"webserver/migrations/versions"
+)
+
+const (
+	FromBankAccountListingIndexName = "from_bank_account_listing"
+	ToBankAccountListingIndexName   = "to_bank_account_listing"
+)
+
+var MigrationSchema6 = versions.Migration{
+	Version: "6__Schema",
+	Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+
+		// Support listing the transactions of a bank account in creation order, with each side of the transfer
+		// served by its own index. The remaining filters are applied to the index ranges of the bank account.
+		indexes := []mongo.IndexModel{
+			{
+				Keys:    bson.D{{"fromBankAccountId", 1}, {"_createdAt", 1}, {"_id", 1}},
+				Options: options.Index().SetName(FromBankAccountListingIndexName),
+			},
+			{
+				Keys:    bson.D{{"toBankAccountId", 1}, {"_createdAt", 1}, {"_id", 1}},
+				Options: options.Index().SetName(ToBankAccountListingIndexName),
+			},
+		}
+		_, err := db.Collection(TransactionCollectionName).Indexes().CreateMany(mongoCtx, indexes)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Indexes %s and %s created on collection %s", FromBankAccountListingIndexName,
+			ToBankAccountListingIndexName, TransactionCollectionName)
+		return nil
+	},
+	Down: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+		indexes := db.Collection(TransactionCollectionName).Indexes()
+		for _, name := range []string{FromBankAccountListingIndexName, ToBankAccountListingIndexName} {
+			if _, err := indexes.DropOne(mongoCtx, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
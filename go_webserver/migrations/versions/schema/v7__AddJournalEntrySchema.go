@@ -0,0 +1,100 @@
+package schema
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"webserver/migrations/service"
+	"webserver/migrations/versions"
+)
+
+const JournalEntryCollectionName = "journal_entry"
+
+var MigrationSchema7 = versions.Migration{
+	Version: "7__Schema",
+	Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+
+		validation := bson.M{
+			"$jsonSchema": bson.M{
+				"bsonType": "object",
+				"required": []string{"kind", "postings", "_createdAt"},
+				"properties": bson.M{
+					"transactionId": bson.M{
+						"bsonType":    "objectId",
+						"description": "the transaction the entry was posted for",
+					},
+					"kind": bson.M{
+						"enum":        []string{"transfer", "pendingHold", "pendingRelease", "openingBalance"},
+						"description": "the kind of balance movement the entry records [required]",
+					},
+					"postings": bson.M{
+						"bsonType":    "array",
+						"minItems":    2,
+						"description": "the postings of the entry, which sum to zero per balance [required]",
+						"items": bson.M{
+							"bsonType": "object",
+							"required": []string{"bankAccountId", "balance", "amount"},
+							"properties": bson.M{
+								"bankAccountId": bson.M{
+									"bsonType":    "objectId",
+									"description": "the bank account the posting applies to [required]",
+								},
+								"balance": bson.M{
+									"enum":        []string{"available", "pending"},
+									"description": "the balance of the bank account the posting applies to [required]",
+								},
+								"amount": bson.M{
+									"bsonType":    "decimal",
+									"description": "the signed amount of the posting [required]",
+								},
+							},
+						},
+					},
+					"_createdAt": bson.M{
+						"bsonType":    "timestamp",
+						"description": "the time the entry was posted [required]",
+					},
+				},
+			},
+		}
+
+		opts := options.CreateCollection().SetValidator(validation).SetValidationLevel("strict")
+		err := db.CreateCollection(mongoCtx, JournalEntryCollectionName, opts)
+		if err != nil {
+			return err
+		}
+
+		indexes := []mongo.IndexModel{
+			{
+				Keys:    bson.D{{"postings.bankAccountId", 1}},
+				Options: options.Index().SetName("journal_posting_bank_account"),
+			},
+			{
+				Keys:    bson.D{{"transactionId", 1}},
+				Options: options.Index().SetName("journal_transaction"),
+			},
+		}
+		_, err = db.Collection(JournalEntryCollectionName).Indexes().CreateMany(mongoCtx, indexes)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Collection %s created with validation rules and indexes", JournalEntryCollectionName)
+		return nil
+	},
+	Down: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+		err := db.Collection(JournalEntryCollectionName).Drop(mongoCtx)
+		if err != nil {
+			return err
+		}
+		return nil
+	},
+}
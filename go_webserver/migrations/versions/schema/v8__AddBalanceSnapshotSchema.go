@@ -0,0 +1,80 @@
+package schema
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"webserver/migrations/service"
+	"webserver/migrations/versions"
+)
+
+const BalanceSnapshotCollectionName = "balance_snapshot"
+
+var MigrationSchema8 = versions.Migration{
+	Version: "8__Schema",
+	Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+
+		validation := bson.M{
+			"$jsonSchema": bson.M{
+				"bsonType": "object",
+				"required": []string{"bankAccountId", "day", "availableBalance", "pendingBalance", "_createdAt"},
+				"properties": bson.M{
+					"bankAccountId": bson.M{
+						"bsonType":    "objectId",
+						"description": "the bank account the snapshot was taken of [required]",
+					},
+					"day": bson.M{
+						"bsonType":    "date",
+						"description": "the midnight UTC that starts the day the snapshot closes [required]",
+					},
+					"availableBalance": bson.M{
+						"bsonType":    "decimal",
+						"description": "the available balance at the end of the day [required]",
+					},
+					"pendingBalance": bson.M{
+						"bsonType":    "decimal",
+						"description": "the pending balance at the end of the day [required]",
+					},
+					"_createdAt": bson.M{
+						"bsonType":    "timestamp",
+						"description": "the time the snapshot was taken [required]",
+					},
+				},
+			},
+		}
+
+		opts := options.CreateCollection().SetValidator(validation).SetValidationLevel("strict")
+		err := db.CreateCollection(mongoCtx, BalanceSnapshotCollectionName, opts)
+		if err != nil {
+			return err
+		}
+
+		// The index keeps a single snapshot per day when several replicas take snapshots at the same time
+		indexModel := mongo.IndexModel{
+			Keys:    bson.D{{"bankAccountId", 1}, {"day", 1}},
+			Options: options.Index().SetUnique(true).SetName("unique_bank_account_day"),
+		}
+		_, err = db.Collection(BalanceSnapshotCollectionName).Indexes().CreateOne(mongoCtx, indexModel)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Collection %s created with validation rules and indexes", BalanceSnapshotCollectionName)
+		return nil
+	},
+	Down: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+		err := db.Collection(BalanceSnapshotCollectionName).Drop(mongoCtx)
+		if err != nil {
+			return err
+		}
+		return nil
+	},
+}
@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"webserver/migrations/service"
+	"webserver/migrations/versions"
+)
+
+const CategoryRuleCollectionName = "category_rule"
+
+// MigrationSchema9 creates the collection of the rules categorizing transactions. The memo and the categories of
+// transactions need no migration, since the validator of the transaction collection allows fields it does not list.
+var MigrationSchema9 = versions.Migration{
+	Version: "9__Schema",
+	Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+
+		validation := bson.M{
+			"$jsonSchema": bson.M{
+				"bsonType": "object",
+				"required": []string{"_id", "accountId", "category", "_createdAt"},
+				"properties": bson.M{
+					"_id": bson.M{
+						"bsonType":    "objectId",
+						"description": "the unique identifier for the rule [required]",
+					},
+					"accountId": bson.M{
+						"bsonType":    "objectId",
+						"description": "the account whose transactions the rule categorizes [required]",
+					},
+					"category": bson.M{
+						"bsonType":    "string",
+						"description": "the category given to matching transactions [required]",
+					},
+					"counterpartyBankAccountId": bson.M{
+						"bsonType":    "objectId",
+						"description": "the bank account on the other side of matching transactions",
+					},
+					"transactionNature": bson.M{
+						"bsonType":    "string",
+						"description": "the nature of matching transactions",
+						"enum":        []string{"debit", "credit"},
+					},
+					"minAmount": bson.M{
+						"bsonType":    "decimal",
+						"description": "the lowest amount of matching transactions",
+					},
+					"maxAmount": bson.M{
+						"bsonType":    "decimal",
+						"description": "the highest amount of matching transactions",
+					},
+					"memoPattern": bson.M{
+						"bsonType":    "string",
+						"description": "the regular expression the memo of matching transactions matches",
+					},
+					"_createdAt": bson.M{
+						"bsonType":    "timestamp",
+						"description": "the time the rule has been created [required]",
+					},
+				},
+			},
+		}
+
+		opts := options.CreateCollection().SetValidator(validation).SetValidationLevel("strict")
+		err := db.CreateCollection(mongoCtx, CategoryRuleCollectionName, opts)
+		if err != nil {
+			return err
+		}
+
+		// The rules of an account are read on every transfer, in the order they were created
+		indexModel := mongo.IndexModel{
+			Keys:    bson.D{{"accountId", 1}, {"_id", 1}},
+			Options: options.Index().SetName("account_category_rules"),
+		}
+		_, err = db.Collection(CategoryRuleCollectionName).Indexes().CreateOne(mongoCtx, indexModel)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Collection %s created with validation rules and indexes", CategoryRuleCollectionName)
+		return nil
+	},
+	Down: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+		db := client.Database(databaseName)
+		mongoCtx, cancel := context.WithTimeout(ctx, service.MigrationTimeout)
+		defer cancel()
+		err := db.Collection(CategoryRuleCollectionName).Drop(mongoCtx)
+		if err != nil {
+			return err
+		}
+		return nil
+	},
+}
@@ -0,0 +1,38 @@
+package sqlschema
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"webserver/migrations/versions"
+)
+
+//go:embed v*.go
+var sources embed.FS
+
+// SQLSchemaMigrations create the same tables and indexes for the SQLite backend as SchemaMigrations create
+// collections and indexes for MongoDB, version for version
+var SQLSchemaMigrations = []versions.SQLMigration{
+	MigrationSQLSchema1,
+	MigrationSQLSchema2,
+	MigrationSQLSchema3,
+	MigrationSQLSchema4,
+	MigrationSQLSchema5,
+	MigrationSQLSchema6,
+	MigrationSQLSchema7,
+	MigrationSQLSchema8,
+	MigrationSQLSchema9,
+	MigrationSQLSchema10,
+}
+
+var Track = versions.NewSQLTrack("schema", SQLSchemaMigrations, sources)
+
+// execAll runs the statements in order, stopping at the first that fails
+func execAll(tx versions.SQLExecutor, ctx context.Context, statements ...string) error {
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("error when executing %q: %w", statement, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package sqlschema
+
+import (
+	"context"
+	"log"
+	"webserver/migrations/versions"
+)
+
+// MigrationSQLSchema10 stores the opening balances, extremes and totals of the day of every balance snapshot, and
+// deletes the snapshots taken without them like MigrationSchema10 does
+var MigrationSQLSchema10 = versions.SQLMigration{
+	Version: "10__Schema",
+	Up: func(tx versions.SQLExecutor, ctx context.Context) error {
+		err := execAll(tx, ctx,
+			`DELETE FROM balance_snapshot`,
+			`ALTER TABLE balance_snapshot ADD COLUMN opening_available_balance TEXT NOT NULL DEFAULT '0'`,
+			`ALTER TABLE balance_snapshot ADD COLUMN opening_pending_balance TEXT NOT NULL DEFAULT '0'`,
+			`ALTER TABLE balance_snapshot ADD COLUMN min_available_balance TEXT NOT NULL DEFAULT '0'`,
+			`ALTER TABLE balance_snapshot ADD COLUMN max_available_balance TEXT NOT NULL DEFAULT '0'`,
+			`ALTER TABLE balance_snapshot ADD COLUMN total_debits TEXT NOT NULL DEFAULT '0'`,
+			`ALTER TABLE balance_snapshot ADD COLUMN total_credits TEXT NOT NULL DEFAULT '0'`,
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("Table %s altered", BalanceSnapshotTableName)
+		return nil
+	},
+	Down: func(tx versions.SQLExecutor, ctx context.Context) error {
+		return execAll(tx, ctx,
+			`ALTER TABLE balance_snapshot DROP COLUMN opening_available_balance`,
+			`ALTER TABLE balance_snapshot DROP COLUMN opening_pending_balance`,
+			`ALTER TABLE balance_snapshot DROP COLUMN min_available_balance`,
+			`ALTER TABLE balance_snapshot DROP COLUMN max_available_balance`,
+			`ALTER TABLE balance_snapshot DROP COLUMN total_debits`,
+			`ALTER TABLE balance_snapshot DROP COLUMN total_credits`,
+		)
+	},
+}
@@ -0,0 +1,61 @@
+package sqlschema
+
+import (
+	"context"
+	"log"
+	"webserver/migrations/versions"
+)
+
+const (
+	AccountTableName          = "account"
+	BankAccountTableName      = "bank_account"
+	KnownBankAccountTableName = "known_bank_account"
+)
+
+// MigrationSQLSchema1 splits the account documents into a table of accounts and tables of the bank accounts and
+// known bank accounts they hold. Balances are stored as decimal strings so that they are kept exactly.
+var MigrationSQLSchema1 = versions.SQLMigration{
+	Version: "1__Schema",
+	Up: func(tx versions.SQLExecutor, ctx context.Context) error {
+		err := execAll(tx, ctx,
+			`CREATE TABLE account (
+				id TEXT PRIMARY KEY,
+				username TEXT NOT NULL,
+				password TEXT NOT NULL,
+				first_name TEXT NOT NULL,
+				last_name TEXT NOT NULL,
+				created_at INTEGER NOT NULL
+			)`,
+			`CREATE TABLE bank_account (
+				id TEXT PRIMARY KEY,
+				account_id TEXT NOT NULL REFERENCES account (id) ON DELETE CASCADE,
+				account_number TEXT NOT NULL,
+				account_type TEXT NOT NULL,
+				available_balance TEXT NOT NULL,
+				pending_balance TEXT NOT NULL
+			)`,
+			`CREATE INDEX bank_account_account ON bank_account (account_id)`,
+			`CREATE TABLE known_bank_account (
+				account_id TEXT NOT NULL REFERENCES account (id) ON DELETE CASCADE,
+				id TEXT NOT NULL,
+				account_number TEXT NOT NULL,
+				account_holder TEXT NOT NULL,
+				account_type TEXT NOT NULL,
+				nickname TEXT NOT NULL DEFAULT '',
+				PRIMARY KEY (account_id, id)
+			)`,
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("Tables %s, %s and %s created", AccountTableName, BankAccountTableName, KnownBankAccountTableName)
+		return nil
+	},
+	Down: func(tx versions.SQLExecutor, ctx context.Context) error {
+		return execAll(tx, ctx,
+			`DROP TABLE known_bank_account`,
+			`DROP TABLE bank_account`,
+			`DROP TABLE account`,
+		)
+	},
+}
@@ -0,0 +1,38 @@
+package sqlschema
+
+import (
+	"context"
+	"log"
+	"webserver/migrations/versions"
+)
+
+// TransactionTableName is not transaction, which is a keyword of SQL
+const TransactionTableName = "bank_transaction"
+
+// MigrationSQLSchema2 stores transactions with their times as Unix seconds, the precision of MongoDB timestamps.
+// Status and expiration date are only set on pending transactions.
+var MigrationSQLSchema2 = versions.SQLMigration{
+	Version: "2__Schema",
+	Up: func(tx versions.SQLExecutor, ctx context.Context) error {
+		err := execAll(tx, ctx,
+			`CREATE TABLE bank_transaction (
+				id TEXT PRIMARY KEY,
+				from_bank_account_id TEXT NOT NULL,
+				to_bank_account_id TEXT NOT NULL,
+				amount TEXT NOT NULL,
+				type TEXT NOT NULL CHECK (type IN ('realized', 'pending')),
+				status TEXT NOT NULL DEFAULT '',
+				expiration_date INTEGER NOT NULL DEFAULT 0,
+				created_at INTEGER NOT NULL
+			)`,
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("Table %s created", TransactionTableName)
+		return nil
+	},
+	Down: func(tx versions.SQLExecutor, ctx context.Context) error {
+		return execAll(tx, ctx, `DROP TABLE bank_transaction`)
+	},
+}
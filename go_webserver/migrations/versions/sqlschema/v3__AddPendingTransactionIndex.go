@@ -0,0 +1,28 @@
+package sqlschema
+
+import (
+	"context"
+	"log"
+	"webserver/migrations/versions"
+)
+
+const PendingTransactionExpiryIndexName = "pending_transaction_expiry"
+
+var MigrationSQLSchema3 = versions.SQLMigration{
+	Version: "3__Schema",
+	Up: func(tx versions.SQLExecutor, ctx context.Context) error {
+		// Supports the lookup of active pending transactions past their expiration date
+		err := execAll(tx, ctx,
+			`CREATE INDEX pending_transaction_expiry ON bank_transaction (status, expiration_date)
+				WHERE type = 'pending'`,
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("Index %s created on table %s", PendingTransactionExpiryIndexName, TransactionTableName)
+		return nil
+	},
+	Down: func(tx versions.SQLExecutor, ctx context.Context) error {
+		return execAll(tx, ctx, `DROP INDEX pending_transaction_expiry`)
+	},
+}
@@ -0,0 +1,36 @@
+package sqlschema
+
+import (
+	"context"
+	"log"
+	"webserver/migrations/versions"
+)
+
+const IdempotencyKeyTableName = "idempotency_key"
+
+var MigrationSQLSchema4 = versions.SQLMigration{
+	Version: "4__Schema",
+	Up: func(tx versions.SQLExecutor, ctx context.Context) error {
+		// SQLite has no TTL indexes, so expired idempotency keys are deleted in the background like on the in-memory
+		// backend
+		err := execAll(tx, ctx,
+			`CREATE TABLE idempotency_key (
+				id TEXT PRIMARY KEY,
+				account_id TEXT NOT NULL,
+				key TEXT NOT NULL,
+				request_hash TEXT NOT NULL,
+				transaction_id TEXT NOT NULL,
+				created_at INTEGER NOT NULL
+			)`,
+			`CREATE UNIQUE INDEX account_idempotency_key ON idempotency_key (account_id, key)`,
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("Table %s created with indexes", IdempotencyKeyTableName)
+		return nil
+	},
+	Down: func(tx versions.SQLExecutor, ctx context.Context) error {
+		return execAll(tx, ctx, `DROP TABLE idempotency_key`)
+	},
+}
@@ -0,0 +1,33 @@
+package sqlschema
+
+import (
+	"context"
+	"log"
+	"webserver/migrations/versions"
+)
+
+const (
+	UsernameIndexName      = "unique_username"
+	AccountNumberIndexName = "unique_bank_account_number"
+)
+
+var MigrationSQLSchema5 = versions.SQLMigration{
+	Version: "5__Schema",
+	Up: func(tx versions.SQLExecutor, ctx context.Context) error {
+		err := execAll(tx, ctx,
+			`CREATE UNIQUE INDEX unique_username ON account (username)`,
+			`CREATE UNIQUE INDEX unique_bank_account_number ON bank_account (account_number)`,
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("Indexes %s and %s created", UsernameIndexName, AccountNumberIndexName)
+		return nil
+	},
+	Down: func(tx versions.SQLExecutor, ctx context.Context) error {
+		return execAll(tx, ctx,
+			`DROP INDEX unique_username`,
+			`DROP INDEX unique_bank_account_number`,
+		)
+	},
+}
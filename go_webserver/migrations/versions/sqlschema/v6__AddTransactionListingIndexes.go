@@ -0,0 +1,35 @@
+package sqlschema
+
+import (
+	"context"
+	"log"
+	"webserver/migrations/versions"
+)
+
+const (
+	FromBankAccountListingIndexName = "from_bank_account_listing"
+	ToBankAccountListingIndexName   = "to_bank_account_listing"
+)
+
+var MigrationSQLSchema6 = versions.SQLMigration{
+	Version: "6__Schema",
+	Up: func(tx versions.SQLExecutor, ctx context.Context) error {
+		// Transactions of a bank account are listed by creation time and then by ID
+		err := execAll(tx, ctx,
+			`CREATE INDEX from_bank_account_listing ON bank_transaction (from_bank_account_id, created_at, id)`,
+			`CREATE INDEX to_bank_account_listing ON bank_transaction (to_bank_account_id, created_at, id)`,
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("Indexes %s and %s created on table %s", FromBankAccountListingIndexName,
+			ToBankAccountListingIndexName, TransactionTableName)
+		return nil
+	},
+	Down: func(tx versions.SQLExecutor, ctx context.Context) error {
+		return execAll(tx, ctx,
+			`DROP INDEX from_bank_account_listing`,
+			`DROP INDEX to_bank_account_listing`,
+		)
+	},
+}
@@ -0,0 +1,49 @@
+package sqlschema
+
+import (
+	"context"
+	"log"
+	"webserver/migrations/versions"
+)
+
+const (
+	JournalEntryTableName = "journal_entry"
+	PostingTableName      = "posting"
+)
+
+// MigrationSQLSchema7 stores the postings of journal entries in a table of their own. Like journal entries, postings
+// are never updated or deleted.
+var MigrationSQLSchema7 = versions.SQLMigration{
+	Version: "7__Schema",
+	Up: func(tx versions.SQLExecutor, ctx context.Context) error {
+		err := execAll(tx, ctx,
+			`CREATE TABLE journal_entry (
+				id TEXT PRIMARY KEY,
+				transaction_id TEXT NOT NULL DEFAULT '',
+				kind TEXT NOT NULL,
+				created_at INTEGER NOT NULL
+			)`,
+			`CREATE INDEX journal_transaction ON journal_entry (transaction_id)`,
+			`CREATE TABLE posting (
+				journal_entry_id TEXT NOT NULL REFERENCES journal_entry (id),
+				position INTEGER NOT NULL,
+				bank_account_id TEXT NOT NULL,
+				balance TEXT NOT NULL CHECK (balance IN ('available', 'pending')),
+				amount TEXT NOT NULL,
+				PRIMARY KEY (journal_entry_id, position)
+			)`,
+			`CREATE INDEX journal_posting_bank_account ON posting (bank_account_id)`,
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("Tables %s and %s created with indexes", JournalEntryTableName, PostingTableName)
+		return nil
+	},
+	Down: func(tx versions.SQLExecutor, ctx context.Context) error {
+		return execAll(tx, ctx,
+			`DROP TABLE posting`,
+			`DROP TABLE journal_entry`,
+		)
+	},
+}
@@ -0,0 +1,33 @@
+package sqlschema
+
+import (
+	"context"
+	"log"
+	"webserver/migrations/versions"
+)
+
+const BalanceSnapshotTableName = "balance_snapshot"
+
+var MigrationSQLSchema8 = versions.SQLMigration{
+	Version: "8__Schema",
+	Up: func(tx versions.SQLExecutor, ctx context.Context) error {
+		err := execAll(tx, ctx,
+			`CREATE TABLE balance_snapshot (
+				bank_account_id TEXT NOT NULL,
+				day INTEGER NOT NULL,
+				available_balance TEXT NOT NULL,
+				pending_balance TEXT NOT NULL,
+				created_at INTEGER NOT NULL,
+				PRIMARY KEY (bank_account_id, day)
+			)`,
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("Table %s created", BalanceSnapshotTableName)
+		return nil
+	},
+	Down: func(tx versions.SQLExecutor, ctx context.Context) error {
+		return execAll(tx, ctx, `DROP TABLE balance_snapshot`)
+	},
+}
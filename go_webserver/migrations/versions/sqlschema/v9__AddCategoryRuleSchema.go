@@ -0,0 +1,51 @@
+package sqlschema
+
+import (
+	"context"
+	"log"
+	"webserver/migrations/versions"
+)
+
+const CategoryRuleTableName = "category_rule"
+
+// MigrationSQLSchema9 stores the memo and the category each side gives a transaction, and the rules categorizing
+// the transactions of an account. Amount bounds of rules are NULL when the rule does not bound the amount.
+var MigrationSQLSchema9 = versions.SQLMigration{
+	Version: "9__Schema",
+	Up: func(tx versions.SQLExecutor, ctx context.Context) error {
+		err := execAll(tx, ctx,
+			`ALTER TABLE bank_transaction ADD COLUMN memo TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE bank_transaction ADD COLUMN from_category TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE bank_transaction ADD COLUMN from_category_source TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE bank_transaction ADD COLUMN to_category TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE bank_transaction ADD COLUMN to_category_source TEXT NOT NULL DEFAULT ''`,
+			`CREATE TABLE category_rule (
+				id TEXT PRIMARY KEY,
+				account_id TEXT NOT NULL,
+				category TEXT NOT NULL,
+				counterparty_bank_account_id TEXT NOT NULL DEFAULT '',
+				transaction_nature TEXT NOT NULL DEFAULT '' CHECK (transaction_nature IN ('', 'debit', 'credit')),
+				min_amount TEXT,
+				max_amount TEXT,
+				memo_pattern TEXT NOT NULL DEFAULT '',
+				created_at INTEGER NOT NULL
+			)`,
+			`CREATE INDEX account_category_rules ON category_rule (account_id, id)`,
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("Table %s created and columns added to table %s", CategoryRuleTableName, TransactionTableName)
+		return nil
+	},
+	Down: func(tx versions.SQLExecutor, ctx context.Context) error {
+		return execAll(tx, ctx,
+			`DROP TABLE category_rule`,
+			`ALTER TABLE bank_transaction DROP COLUMN memo`,
+			`ALTER TABLE bank_transaction DROP COLUMN from_category`,
+			`ALTER TABLE bank_transaction DROP COLUMN from_category_source`,
+			`ALTER TABLE bank_transaction DROP COLUMN to_category`,
+			`ALTER TABLE bank_transaction DROP COLUMN to_category_source`,
+		)
+	},
+}
@@ -0,0 +1,132 @@
+package versions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io/fs"
+	"strings"
+)
+
+// Track is an ordered list of migrations that is applied and rolled back independently of other tracks. The number
+// of a migration is its position in the list, starting from 1.
+type Track struct {
+	Name       string
+	Migrations []Migration
+}
+
+// NewTrack identifies every migration of the track by the source file it is defined in, which is named after the
+// number of its version, e.g. v7__AddJournalEntrySchema.go for version 7__Schema. The description of the migration is
+// the rest of the file name, and its checksum covers the version, the description and the code of that file, which
+// includes the statements of SQL migrations, but not its comments, formatting or imports. It panics when a source
+// file is missing, since the track is built when its package is initialized.
+func NewTrack(name string, migrations []Migration, sources fs.FS) Track {
+	identified := make([]Migration, len(migrations))
+	for i, elem := range migrations {
+		description, checksum, legacyChecksum := identify(name, elem.Version, sources)
+		if elem.Description == "" {
+			elem.Description = description
+		}
+		if elem.Checksum == "" {
+			elem.Checksum, elem.LegacyChecksum = checksum, legacyChecksum
+		}
+		identified[i] = elem
+	}
+	return Track{Name: name, Migrations: identified}
+}
+
+// SQLTrack is an ordered list of migrations of a SQL database, numbered like the migrations of a Track
+type SQLTrack struct {
+	Name       string
+	Migrations []SQLMigration
+}
+
+// NewSQLTrack identifies every migration of the track by the source file it is defined in, like NewTrack
+func NewSQLTrack(name string, migrations []SQLMigration, sources fs.FS) SQLTrack {
+	identified := make([]SQLMigration, len(migrations))
+	for i, elem := range migrations {
+		description, checksum, legacyChecksum := identify(name, elem.Version, sources)
+		if elem.Description == "" {
+			elem.Description = description
+		}
+		if elem.Checksum == "" {
+			elem.Checksum, elem.LegacyChecksum = checksum, legacyChecksum
+		}
+		identified[i] = elem
+	}
+	return SQLTrack{Name: name, Migrations: identified}
+}
+
+// identify returns the description, checksum and legacy checksum of the migration of the given version from its
+// source file
+func identify(track string, version string, sources fs.FS) (string, string, string) {
+	number, _, _ := strings.Cut(version, "__")
+	matches, err := fs.Glob(sources, "v"+number+"__*.go")
+	if err != nil || len(matches) != 1 {
+		panic(fmt.Sprintf("expected one source file for migration %s of track %s, found %d", version,
+			track, len(matches)))
+	}
+	source, err := fs.ReadFile(sources, matches[0])
+	if err != nil {
+		panic(fmt.Sprintf("error when reading source file of migration %s: %v", version, err))
+	}
+	_, description, _ := strings.Cut(strings.TrimSuffix(matches[0], ".go"), "__")
+	checksum, err := codeChecksum(version, description, source)
+	if err != nil {
+		panic(fmt.Sprintf("error when parsing source file of migration %s: %v", version, err))
+	}
+	legacyChecksum := sha256.Sum256(source)
+	return description, checksum, hex.EncodeToString(legacyChecksum[:])
+}
+
+// codeChecksum returns the SHA-256 of the version and description of a migration followed by the tokens of its source
+// outside of import declarations. Comments and whitespace are not tokens, so only changes to the code change it.
+func codeChecksum(version string, description string, source []byte) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", source, parser.ImportsOnly)
+	if err != nil {
+		return "", err
+	}
+	var imports []*ast.GenDecl
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			imports = append(imports, gen)
+		}
+	}
+	inImports := func(offset int) bool {
+		for _, gen := range imports {
+			if offset >= fset.Position(gen.Pos()).Offset && offset < fset.Position(gen.End()).Offset {
+				return true
+			}
+		}
+		return false
+	}
+
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%s\n%s\n", version, description)
+	var s scanner.Scanner
+	scanned := fset.AddFile("", fset.Base(), len(source))
+	s.Init(scanned, source, nil, 0)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if inImports(scanned.Offset(pos)) {
+			continue
+		}
+		// Semicolons are mostly inserted at line ends, which formatting may move
+		if tok == token.SEMICOLON {
+			lit = ""
+		}
+		_, _ = fmt.Fprintf(hash, "%s %s\n", tok, lit)
+	}
+	if s.ErrorCount > 0 {
+		return "", fmt.Errorf("found %d syntax errors", s.ErrorCount)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
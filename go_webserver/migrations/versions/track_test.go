@@ -0,0 +1,128 @@
+package versions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const firstSource = `package schema
+
+import "log"
+
+// MigrationSchema1 creates the first collection
+var MigrationSchema1 = up("CREATE first")
+
+func up(statement string) string {
+	log.Print(statement)
+	return statement
+}
+`
+
+func TestNewTrack(t *testing.T) {
+	sources := fstest.MapFS{
+		"v1__AddFirstSchema.go":  {Data: []byte(firstSource)},
+		"v2__AddSecondSchema.go": {Data: []byte("package schema\n\nvar MigrationSchema2 = 2\n")},
+	}
+	migrations := []Migration{{Version: "1__Schema"}, {Version: "2__Schema", Description: "Given description"}}
+
+	t.Run("Identifies migrations by their source file", func(t *testing.T) {
+		track := NewTrack("schema", migrations, sources)
+		assert.Equal(t, "schema", track.Name)
+		assert.Len(t, track.Migrations, 2)
+		assert.Equal(t, "AddFirstSchema", track.Migrations[0].Description)
+		assert.Equal(t, "Given description", track.Migrations[1].Description)
+		assert.Len(t, track.Migrations[0].Checksum, 64)
+		assert.NotEqual(t, track.Migrations[0].Checksum, track.Migrations[1].Checksum)
+		assert.Empty(t, migrations[0].Checksum)
+		legacyChecksum := sha256.Sum256([]byte(firstSource))
+		assert.Equal(t, hex.EncodeToString(legacyChecksum[:]), track.Migrations[0].LegacyChecksum)
+	})
+
+	t.Run("Keeps the checksum when only comments, formatting or imports change", func(t *testing.T) {
+		before := NewTrack("schema", migrations, sources)
+		for _, source := range []string{
+			`package schema
+import (
+	"fmt"
+	"log"
+)
+var MigrationSchema1 = up( "CREATE first" ) // creates the first collection
+func up(statement string) string {
+        log.Print(statement)
+        return statement
+}
+`,
+			`package schema
+
+import (
+	"log"
+)
+
+var MigrationSchema1 = up("CREATE first")
+
+/* up logs and returns the statement */
+func up(statement string) string {
+	log.Print(statement)
+
+	return statement
+}
+`,
+		} {
+			after := NewTrack("schema", migrations, fstest.MapFS{
+				"v1__AddFirstSchema.go":  {Data: []byte(source)},
+				"v2__AddSecondSchema.go": sources["v2__AddSecondSchema.go"],
+			})
+			assert.Equal(t, before.Migrations[0].Checksum, after.Migrations[0].Checksum, source)
+			assert.NotEqual(t, before.Migrations[0].LegacyChecksum, after.Migrations[0].LegacyChecksum)
+		}
+	})
+
+	t.Run("Changes the checksum when the code, version or description changes", func(t *testing.T) {
+		before := NewTrack("schema", migrations, sources)
+		for name, source := range map[string]string{
+			"v1__AddFirstSchema.go":   strings.Replace(firstSource, "CREATE first", "CREATE changed", 1),
+			"v1__AddRenamedSchema.go": firstSource,
+		} {
+			after := NewTrack("schema", migrations, fstest.MapFS{
+				name:                     {Data: []byte(source)},
+				"v2__AddSecondSchema.go": sources["v2__AddSecondSchema.go"],
+			})
+			assert.NotEqual(t, before.Migrations[0].Checksum, after.Migrations[0].Checksum, name)
+			assert.Equal(t, before.Migrations[1].Checksum, after.Migrations[1].Checksum)
+		}
+		renumbered := NewTrack("schema", []Migration{{Version: "2__Schema"}}, fstest.MapFS{
+			"v2__AddFirstSchema.go": sources["v1__AddFirstSchema.go"],
+		})
+		assert.NotEqual(t, before.Migrations[0].Checksum, renumbered.Migrations[0].Checksum)
+	})
+
+	t.Run("Leaves the legacy checksum empty when the checksum is given", func(t *testing.T) {
+		track := NewTrack("schema", []Migration{{Version: "1__Schema", Checksum: "given"}}, sources)
+		assert.Equal(t, "given", track.Migrations[0].Checksum)
+		assert.Empty(t, track.Migrations[0].LegacyChecksum)
+	})
+
+	t.Run("Panics when the source of a migration is missing", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewTrack("schema", append(migrations, Migration{Version: "3__Schema"}), sources)
+		})
+	})
+}
+
+func TestNewSQLTrack(t *testing.T) {
+	sources := fstest.MapFS{
+		"v1__AddFirstSchema.go": {Data: []byte(firstSource)},
+	}
+	track := NewSQLTrack("schema", []SQLMigration{{Version: "1__Schema"}}, sources)
+	assert.Equal(t, "schema", track.Name)
+	assert.Equal(t, "AddFirstSchema", track.Migrations[0].Description)
+	assert.Equal(t, NewTrack("schema", []Migration{{Version: "1__Schema"}}, sources).Migrations[0].Checksum,
+		track.Migrations[0].Checksum)
+	assert.Panics(t, func() {
+		NewSQLTrack("schema", []SQLMigration{{Version: "2__Schema"}}, sources)
+	})
+}
@@ -41,7 +41,6 @@ func TestGetAccountDetails(t *testing.T) {
 		)
 		assert.Equal(t, utils.TomAccountDetails.Username, accountDetails.Username)
 		assert.Equal(t, pkgutils.TimestampToTime(utils.TomAccountDetails.CreatedAt), accountDetails.CreatedAt)
-		assert.Equal(t, utils.TomAccountDetails.Password, accountDetails.Password)
 		assert.Equal(t, knownAccountId, accountDetails.KnownBankAccounts[0].Id)
 		assert.Equal(t, tomAccountId, accountDetails.BankAccounts[0].Id)
 	})
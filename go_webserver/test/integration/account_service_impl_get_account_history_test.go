@@ -45,15 +45,17 @@ func TestGetAccountHistory(t *testing.T) {
 
 			accountService := setupAccountService(mongoClient, tranCollection, accCollection)
 
-			input := model.TransactionsForBankAccountInput{
+			input := model.AccountBalanceHistoryInput{
 				BankAccountId: tomAccountName,
 				ToTime:        time.Date(2021, time.March, 30, 0, 0, 0, 0, time.UTC),
 				FromTime:      time.Date(2020, time.December, 1, 0, 0, 0, 0, time.UTC),
+				BucketSize:    model.MonthlyBuckets,
 			}
-			res, _ := accountService.GetAccountBalanceHistoryInMonths(&input, ctx)
+			res, err := accountService.GetAccountBalanceHistory(&input, ctx)
+			assert.Nil(t, err)
 			monthVals := make([]string, 4)
-			for i, month := range res.Months {
-				monthVals[i] = month.AvailableBalance.String()
+			for i, month := range res.Buckets {
+				monthVals[i] = month.ClosingAvailableBalance.String()
 			}
 			expectedPendingMarchBalance, _ :=
 				pkgutils.FromPrimitiveDecimal128ToDecimal(utils.TomAccountDetails.BankAccounts[0].PendingBalance)
@@ -108,7 +110,7 @@ func TestGetAccountHistory(t *testing.T) {
 				expectedAvailableDecemberBalance,
 				expectedPendingDecemberBalance,
 			)
-			assert.Equal(t, 4, len(res.Months))
+			assert.Equal(t, 4, len(res.Buckets))
 			assert.Equal(t, expectedAvailableMarchBalance.String(), monthVals[0])
 			assert.Equal(t, expectedAvailableFebBalance.String(), monthVals[1])
 			assert.Equal(t, expectedAvailableJanBalance.String(), monthVals[2])
@@ -0,0 +1,128 @@
+package integration
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/mongodb"
+	pkgutils "webserver/internal/pkg/utils"
+	"webserver/migrations/versions/schema"
+	"webserver/test/utils"
+)
+
+func TestGetAccountTransactionsPage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+	tranCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.TransactionCollectionName)
+	accCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.AccountCollectionName)
+
+	tomAccountName, _ := pkgutils.ObjectIdToString(utils.TomAccountDetails.BankAccounts[0].Id)
+	samAccountName, _ := pkgutils.ObjectIdToString(utils.SamAccountDetails.BankAccounts[0].Id)
+	tomObjectId, _ := pkgutils.StringToObjectId(tomAccountName)
+	samObjectId, _ := pkgutils.StringToObjectId(samAccountName)
+
+	// Five transactions share a creation time so that pages have to be split on the transaction ID
+	sharedTime := pkgutils.TimeToTimestamp(time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC))
+	transactionsInput := make([]interface{}, 0, 8)
+	for i := 1; i <= 8; i++ {
+		amount, _ := pkgutils.FromDecimalToPrimitiveDecimal128(decimal.NewFromInt(int64(i * 10)))
+		from, to := tomObjectId, samObjectId
+		if i%2 == 0 {
+			from, to = samObjectId, tomObjectId
+		}
+		createdAt := sharedTime
+		if i > 5 {
+			createdAt = pkgutils.TimeToTimestamp(time.Date(2021, time.March, i, 0, 0, 0, 0, time.UTC))
+		}
+		transactionsInput = append(transactionsInput, mongodb.MongoTransactionInput{
+			FromBankAccountId: from,
+			ToBankAccountId:   to,
+			Amount:            amount,
+			Type:              "realized",
+			CreatedAt:         createdAt,
+		})
+	}
+
+	listAll := func(t *testing.T, input model.TransactionPageInput) []model.BankAccountTransactionOutput {
+		accountService := setupAccountService(mongoClient, tranCollection, accCollection)
+		var transactions []model.BankAccountTransactionOutput
+		for {
+			page, err := accountService.GetBankAccountTransactionsPage(&input, ctx)
+			if err != nil {
+				t.Fatalf("Error listing transactions: %v", err)
+			}
+			assert.LessOrEqual(t, len(page.Transactions), input.PageSize)
+			transactions = append(transactions, page.Transactions...)
+			if page.Next == nil {
+				return transactions
+			}
+			input.After = page.Next
+		}
+	}
+
+	t.Run("Lists every transaction exactly once across pages in both orders", func(t *testing.T) {
+		setupGetAccountTransactionsTestCase(accCollection, tranCollection, ctx, t)
+		if _, err := tranCollection.InsertMany(ctx, transactionsInput); err != nil {
+			t.Fatalf("Error inserting transactions: %v", err)
+		}
+
+		for _, order := range []model.SortOrder{model.Ascending, model.Descending} {
+			transactions := listAll(t, model.TransactionPageInput{
+				BankAccountId: tomAccountName,
+				Order:         order,
+				PageSize:      2,
+			})
+			assert.Len(t, transactions, 8)
+			seen := make(map[string]bool)
+			for i, transaction := range transactions {
+				assert.False(t, seen[transaction.Id], "transaction %s listed twice", transaction.Id)
+				seen[transaction.Id] = true
+				if i == 0 {
+					continue
+				}
+				previous := transactions[i-1]
+				if order == model.Ascending {
+					assert.False(t, transaction.CreatedAt.Before(previous.CreatedAt))
+				} else {
+					assert.False(t, transaction.CreatedAt.After(previous.CreatedAt))
+				}
+			}
+		}
+	})
+
+	t.Run("Filters by nature, amount range and counterparty", func(t *testing.T) {
+		setupGetAccountTransactionsTestCase(accCollection, tranCollection, ctx, t)
+		if _, err := tranCollection.InsertMany(ctx, transactionsInput); err != nil {
+			t.Fatalf("Error inserting transactions: %v", err)
+		}
+
+		transactions := listAll(t, model.TransactionPageInput{
+			BankAccountId: tomAccountName,
+			PageSize:      3,
+			Filter: model.TransactionFilter{
+				TransactionNature:         model.Debit,
+				MinAmount:                 decimal.NewNullDecimal(decimal.NewFromInt(30)),
+				MaxAmount:                 decimal.NewNullDecimal(decimal.NewFromInt(70)),
+				CounterpartyBankAccountId: samAccountName,
+			},
+		})
+		amounts := make([]string, len(transactions))
+		for i, transaction := range transactions {
+			assert.Equal(t, model.Debit, transaction.TransactionNature)
+			assert.Equal(t, samAccountName, transaction.OtherBankAccountId)
+			amounts[i] = transaction.Amount.String()
+		}
+		assert.ElementsMatch(t, []string{"40", "60"}, amounts)
+
+		transactions = listAll(t, model.TransactionPageInput{
+			BankAccountId: tomAccountName,
+			PageSize:      3,
+			Filter:        model.TransactionFilter{CounterpartyBankAccountId: primitive.NewObjectID().Hex()},
+		})
+		assert.Empty(t, transactions)
+	})
+}
@@ -3,9 +3,14 @@ package integration
 import (
 	"context"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
 	"testing"
 	"time"
+	"webserver/internal/pkg/infrastructure/mongodb"
+	"webserver/migrations/versions/data"
 	"webserver/migrations/versions/schema"
 	"webserver/test/utils"
 )
@@ -19,7 +24,7 @@ func TestLogins(t *testing.T) {
 	t.Run("Allows the login of a user with the correct password", func(t *testing.T) {
 		setupLoginTestCase(accCollection, tranCollection, ctx, t)
 		service := setupAccountService(mongoClient, tranCollection, accCollection)
-		accountDetails, err := service.Login(utils.TomAccountDetails.Username, utils.TomAccountDetails.Password, ctx)
+		accountDetails, err := service.Login(utils.TomAccountDetails.Username, utils.TomPassword, ctx)
 		if err != nil {
 			t.Fatalf("Error logging in: %v", err)
 		}
@@ -39,11 +44,49 @@ func TestLogins(t *testing.T) {
 
 	t.Run("Does not allow the login of a user with the incorrect username", func(t *testing.T) {
 		service := setupAccountService(mongoClient, tranCollection, accCollection)
-		_, err := service.Login("wrongusername", utils.TomAccountDetails.Password, ctx)
+		_, err := service.Login("wrongusername", utils.TomPassword, ctx)
 		assert.NotNil(t, err)
 		assert.EqualError(t, err, "invalid username or password")
 	})
 
+	t.Run("Rehashes a password stored with an outdated algorithm on login", func(t *testing.T) {
+		setupLoginTestCase(accCollection, tranCollection, ctx, t)
+		bcryptHash, _ := bcrypt.GenerateFromPassword([]byte(utils.TomPassword), bcrypt.MinCost)
+		_, err := accCollection.UpdateOne(
+			ctx,
+			bson.M{"username": utils.TomAccountDetails.Username},
+			bson.M{"$set": bson.M{"password": string(bcryptHash)}},
+		)
+		assert.Nil(t, err)
+		service := setupAccountService(mongoClient, tranCollection, accCollection)
+
+		_, err = service.Login(utils.TomAccountDetails.Username, utils.TomPassword, ctx)
+		assert.Nil(t, err)
+
+		var credentials mongodb.MongoAccountCredentialsOutput
+		err = accCollection.FindOne(ctx, bson.M{"username": utils.TomAccountDetails.Username}).Decode(&credentials)
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(credentials.Password, "$argon2id$"))
+	})
+
+	t.Run("Allows the login of a user whose plaintext password was migrated", func(t *testing.T) {
+		setupLoginTestCase(accCollection, tranCollection, ctx, t)
+		_, err := accCollection.UpdateOne(
+			ctx,
+			bson.M{"username": utils.TomAccountDetails.Username},
+			bson.M{"$set": bson.M{"password": utils.TomPassword}},
+		)
+		assert.Nil(t, err)
+		service := setupAccountService(mongoClient, tranCollection, accCollection)
+		_, err = service.Login(utils.TomAccountDetails.Username, utils.TomPassword, ctx)
+		assert.EqualError(t, err, "invalid username or password")
+
+		err = data.MigrationData3.Up(mongoClient, ctx, utils.TestDatabaseName)
+		assert.Nil(t, err)
+
+		_, err = service.Login(utils.TomAccountDetails.Username, utils.TomPassword, ctx)
+		assert.Nil(t, err)
+	})
 }
 
 func setupLoginTestCase(
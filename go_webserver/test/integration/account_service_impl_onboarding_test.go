@@ -0,0 +1,71 @@
+package integration
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	pkgutils "webserver/internal/pkg/utils"
+	"webserver/migrations/versions/schema"
+	"webserver/test/utils"
+)
+
+func TestOnboarding(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+	tranCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.TransactionCollectionName)
+	accCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.AccountCollectionName)
+	registerInput := model.RegisterAccountInput{
+		Username: "Jerry",
+		Password: "cheese-lover",
+		Person:   model.Person{FirstName: "Jerry", LastName: "Mouse"},
+	}
+
+	t.Run("Registers an account that can log in and open bank accounts", func(t *testing.T) {
+		setupLoginTestCase(accCollection, tranCollection, ctx, t)
+		service := setupAccountService(mongoClient, tranCollection, accCollection)
+
+		registered, err := service.Register(&registerInput, ctx)
+		if err != nil {
+			t.Fatalf("Error registering account: %v", err)
+		}
+		assert.Equal(t, "Jerry", registered.Username)
+		assert.Empty(t, registered.BankAccounts)
+
+		loggedIn, err := service.Login(registerInput.Username, registerInput.Password, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, registered.Id, loggedIn.Id)
+
+		savings, err := service.OpenBankAccount(registered.Id, model.Savings, ctx)
+		assert.Nil(t, err)
+		checking, err := service.OpenBankAccount(registered.Id, model.Checking, ctx)
+		assert.Nil(t, err)
+		assert.True(t, pkgutils.HasValidCheckDigit(savings.AccountNumber))
+		assert.NotEqual(t, savings.AccountNumber, checking.AccountNumber)
+
+		owned, err := service.IsBankAccountOwner(registered.Id, savings.Id, ctx)
+		assert.Nil(t, err)
+		assert.True(t, owned)
+		details, err := service.GetAccountDetailsFromBankAccountId(checking.Id, ctx)
+		assert.Nil(t, err)
+		assert.Len(t, details.BankAccounts, 2)
+		assert.True(t, details.BankAccounts[1].AvailableBalance.IsZero())
+	})
+
+	t.Run("Does not register a username twice", func(t *testing.T) {
+		setupLoginTestCase(accCollection, tranCollection, ctx, t)
+		service := setupAccountService(mongoClient, tranCollection, accCollection)
+		takenInput := registerInput
+		takenInput.Username = utils.TomAccountDetails.Username
+
+		_, err := service.Register(&takenInput, ctx)
+		assert.ErrorIs(t, err, model.ErrUsernameTaken)
+	})
+
+	t.Run("Does not open a bank account for an unknown account", func(t *testing.T) {
+		service := setupAccountService(mongoClient, tranCollection, accCollection)
+		_, err := service.OpenBankAccount("000000000000000000000000", model.Savings, ctx)
+		assert.ErrorIs(t, err, model.ErrNoMatchingAccount)
+	})
+}
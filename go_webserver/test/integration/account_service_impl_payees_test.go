@@ -0,0 +1,73 @@
+package integration
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/migrations/versions/schema"
+	"webserver/test/utils"
+)
+
+func TestPayees(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+	tranCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.TransactionCollectionName)
+	accCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.AccountCollectionName)
+	samAccountNumber := utils.SamAccountDetails.BankAccounts[0].AccountNumber
+
+	t.Run("Adds, renames and removes a payee", func(t *testing.T) {
+		setupLoginTestCase(accCollection, tranCollection, ctx, t)
+		service := setupAccountService(mongoClient, tranCollection, accCollection)
+		sam, err := service.Login(utils.SamAccountDetails.Username, utils.SamPassword, ctx)
+		if err != nil {
+			t.Fatalf("Error logging in: %v", err)
+		}
+		tom, err := service.Login(utils.TomAccountDetails.Username, utils.TomPassword, ctx)
+		if err != nil {
+			t.Fatalf("Error logging in: %v", err)
+		}
+
+		payee, err := service.AddPayee(sam.Id, &model.PayeeInput{
+			AccountNumber: utils.TomAccountDetails.BankAccounts[0].AccountNumber,
+			Nickname:      "Tom",
+		}, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, tom.BankAccounts[0].Id, payee.Id)
+		assert.Equal(t, "Tom Smith", payee.AccountHolder)
+		_, err = service.AddPayee(sam.Id, &model.PayeeInput{AccountNumber: payee.AccountNumber}, ctx)
+		assert.ErrorIs(t, err, model.ErrPayeeAlreadyKnown)
+
+		assert.Nil(t, service.RenamePayee(sam.Id, payee.Id, "Tommy", ctx))
+		payees, err := service.GetPayees(sam.Id, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, []model.KnownBankAccount{{
+			Id:            tom.BankAccounts[0].Id,
+			AccountNumber: tom.BankAccounts[0].AccountNumber,
+			AccountHolder: "Tom Smith",
+			AccountType:   model.Savings,
+			Nickname:      "Tommy",
+		}}, payees)
+
+		assert.Nil(t, service.RemovePayee(sam.Id, payee.Id, ctx))
+		assert.ErrorIs(t, service.RemovePayee(sam.Id, payee.Id, ctx), model.ErrNoMatchingPayee)
+		payees, err = service.GetPayees(sam.Id, ctx)
+		assert.Nil(t, err)
+		assert.Empty(t, payees)
+	})
+
+	t.Run("Does not add own bank accounts or unknown account numbers as payees", func(t *testing.T) {
+		setupLoginTestCase(accCollection, tranCollection, ctx, t)
+		service := setupAccountService(mongoClient, tranCollection, accCollection)
+		sam, err := service.Login(utils.SamAccountDetails.Username, utils.SamPassword, ctx)
+		if err != nil {
+			t.Fatalf("Error logging in: %v", err)
+		}
+
+		_, err = service.AddPayee(sam.Id, &model.PayeeInput{AccountNumber: samAccountNumber}, ctx)
+		assert.ErrorIs(t, err, model.ErrPayeeIsOwnBankAccount)
+		_, err = service.AddPayee(sam.Id, &model.PayeeInput{AccountNumber: "000-00000-0"}, ctx)
+		assert.ErrorIs(t, err, model.ErrNoMatchingBankAccount)
+	})
+}
@@ -5,6 +5,9 @@ import (
 	"webserver/internal/pkg/domain/repositories"
 	"webserver/internal/pkg/domain/services"
 	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+	"webserver/migrations/versions/schema"
 )
 
 func setupAccountService(
@@ -12,9 +15,13 @@ func setupAccountService(
 	tranCollection *mongo.Collection,
 	accCollection *mongo.Collection,
 ) *services.AccountServiceImpl {
-	tr := repositories.CreateNewTransactionRepositoryMongodb(tranCollection)
-	ar := repositories.CreateNewAccountRepositoryMongodb(accCollection)
-	tran := transactional.NewMongoTransactional(mongoClient)
-	service := services.CreateNewAccountServiceImpl(ar, tr, tran)
+	m := metrics.New()
+	tr := repositories.CreateNewTransactionRepositoryMongodb(tranCollection, m, logging.Discard())
+	ar := repositories.CreateNewAccountRepositoryMongodb(accCollection, m, logging.Discard())
+	// Snapshots belong to the bank accounts they were taken of, so the collection is shared across test cases
+	snapshotCollection := accCollection.Database().Collection(schema.BalanceSnapshotCollectionName)
+	sr := repositories.CreateNewBalanceSnapshotRepositoryMongodb(snapshotCollection, m, logging.Discard())
+	tran := transactional.NewMongoTransactional(mongoClient, m, logging.Discard())
+	service := services.CreateNewAccountServiceImpl(ar, tr, sr, tran, logging.Discard())
 	return service
 }
@@ -0,0 +1,109 @@
+package integration
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/domain/services"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+	pkgutils "webserver/internal/pkg/utils"
+	"webserver/migrations/versions/data"
+	"webserver/migrations/versions/schema"
+	"webserver/test/utils"
+)
+
+func TestFindBalanceDiscrepancies(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+	tranCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.TransactionCollectionName)
+	accCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.AccountCollectionName)
+	journalCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.JournalEntryCollectionName)
+
+	tomBankAccountId, _ := pkgutils.ObjectIdToString(utils.TomAccountDetails.BankAccounts[0].Id)
+	samBankAccountId, _ := pkgutils.ObjectIdToString(utils.SamAccountDetails.BankAccounts[0].Id)
+	transferAmount, _ := decimal.NewFromString("100.00")
+
+	t.Run("Balances match the journal after transfers and pending transactions", func(t *testing.T) {
+		setupLedgerTestCase(tranCollection, accCollection, journalCollection, ctx, t)
+		transactionService := setupTransactionService(mongoClient, tranCollection, accCollection)
+		ledgerService := setupLedgerService(accCollection, journalCollection)
+
+		_, err := transactionService.AddTransaction(model.TransactionDetailsInput{
+			FromBankAccountId: tomBankAccountId,
+			ToBankAccountId:   samBankAccountId,
+			Amount:            transferAmount,
+			Type:              model.Realized,
+		}, nil, ctx)
+		assert.Nil(t, err)
+		pendingTransactionId, err := transactionService.AddPendingTransaction(model.TransactionDetailsInput{
+			FromBankAccountId: samBankAccountId,
+			ToBankAccountId:   tomBankAccountId,
+			Amount:            transferAmount,
+			Type:              model.Pending,
+			ExpirationDate:    time.Now().Add(time.Hour),
+			Status:            model.Active,
+		}, ctx)
+		assert.Nil(t, err)
+
+		discrepancies, err := ledgerService.FindBalanceDiscrepancies(ctx)
+		assert.Nil(t, err)
+		assert.Empty(t, discrepancies)
+
+		assert.Nil(t, transactionService.ApplyPendingTransaction(pendingTransactionId, ctx))
+		discrepancies, err = ledgerService.FindBalanceDiscrepancies(ctx)
+		assert.Nil(t, err)
+		assert.Empty(t, discrepancies)
+	})
+
+	t.Run("Reports a bank account whose stored balance was changed outside of a transfer", func(t *testing.T) {
+		setupLedgerTestCase(tranCollection, accCollection, journalCollection, ctx, t)
+		ledgerService := setupLedgerService(accCollection, journalCollection)
+		tamperedAmount, _ := pkgutils.FromDecimalToPrimitiveDecimal128(transferAmount)
+		_, err := accCollection.UpdateOne(ctx,
+			bson.M{"bankAccounts._id": utils.SamAccountDetails.BankAccounts[0].Id},
+			bson.M{"$inc": bson.M{"bankAccounts.$.availableBalance": tamperedAmount}},
+		)
+		if err != nil {
+			t.Fatalf("Error changing Sam's balance: %v", err)
+		}
+
+		discrepancies, err := ledgerService.FindBalanceDiscrepancies(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, discrepancies, 1)
+		assert.Equal(t, samBankAccountId, discrepancies[0].BankAccountId)
+		assert.True(t, discrepancies[0].StoredAvailable.Sub(discrepancies[0].PostedAvailable).Equal(transferAmount))
+		assert.True(t, discrepancies[0].StoredPending.Equal(discrepancies[0].PostedPending))
+	})
+}
+
+// setupLedgerTestCase seeds the accounts and posts their opening journal entries
+func setupLedgerTestCase(
+	tranCollection *mongo.Collection,
+	accCollection *mongo.Collection,
+	journalCollection *mongo.Collection,
+	ctx context.Context,
+	t *testing.T,
+) {
+	utils.CleanupCollection(journalCollection, ctx)
+	setupAddTransactionTestCase(tranCollection, accCollection, ctx, t)
+	if err := data.MigrationData4.Up(mongoClient, ctx, utils.TestDatabaseName); err != nil {
+		t.Fatalf("Error posting opening journal entries: %v", err)
+	}
+}
+
+func setupLedgerService(
+	accCollection *mongo.Collection,
+	journalCollection *mongo.Collection,
+) *services.LedgerServiceImpl {
+	m := metrics.New()
+	ar := repositories.CreateNewAccountRepositoryMongodb(accCollection, m, logging.Discard())
+	jr := repositories.CreateNewJournalRepositoryMongodb(journalCollection, m, logging.Discard())
+	return services.CreateNewLedgerServiceImpl(ar, jr, logging.Discard())
+}
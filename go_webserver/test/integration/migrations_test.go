@@ -5,11 +5,14 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"testing"
 	"time"
 	"webserver/internal/pkg/infrastructure/mongodb"
+	"webserver/internal/pkg/logging"
 	pkgutils "webserver/internal/pkg/utils"
 	"webserver/migrations/service"
+	"webserver/migrations/versions"
 	"webserver/migrations/versions/schema"
 	"webserver/test/utils"
 )
@@ -25,7 +28,8 @@ func TestMigrationService(t *testing.T) {
 	defer cancel()
 	db := mongoClient.Database(TestMigrationDatabaseName)
 	collection := db.Collection(TestMigrationCollectionName)
-	ms := service.NewMigrationService(mongoClient, ctx, TestMigrationDatabaseName, TestMigrationCollectionName)
+	ms := service.NewMigrationService(mongoClient, ctx, TestMigrationDatabaseName, TestMigrationCollectionName,
+		logging.Discard())
 
 	t.Run("checkIfApplied should return true if a migration has already been applied", func(t *testing.T) {
 		version := "1"
@@ -54,7 +58,7 @@ func TestMigrationService(t *testing.T) {
 
 	t.Run("markAsApplied should insert the migrated version into the database", func(t *testing.T) {
 		version := "20"
-		err := ms.MarkAsApplied(version)
+		err := ms.MarkAsApplied(service.MigrationRecord{Version: version})
 		assert.Nil(t, err)
 		err = collection.FindOne(ctx, bson.M{"version": version}).Err()
 		assert.Nil(t, err)
@@ -63,7 +67,7 @@ func TestMigrationService(t *testing.T) {
 
 	t.Run("checkIfApplied should return false after calling markAsApplied", func(t *testing.T) {
 		version := "20"
-		err := ms.MarkAsApplied(version)
+		err := ms.MarkAsApplied(service.MigrationRecord{Version: version})
 		assert.Nil(t, err)
 		res, err := ms.CheckIfApplied(version)
 		assert.Equal(t, true, res)
@@ -72,6 +76,239 @@ func TestMigrationService(t *testing.T) {
 	})
 }
 
+func TestMigrationTracks(t *testing.T) {
+	if mongoClient == nil {
+		t.Error("mongoClient is uninitialized or otherwise nil")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+	db := mongoClient.Database(TestMigrationDatabaseName)
+	collection := db.Collection(TestMigrationCollectionName)
+	ms := service.NewMigrationService(mongoClient, ctx, TestMigrationDatabaseName, TestMigrationCollectionName,
+		logging.Discard())
+	track := versions.Track{
+		Name: "test",
+		Migrations: []versions.Migration{
+			createCollectionMigration("1__Test", "track_test_first"),
+			createCollectionMigration("2__Test", "track_test_second"),
+			createCollectionMigration("3__Test", "track_test_third"),
+		},
+	}
+
+	t.Run("up applies migrations up to the target and records when they were applied", func(t *testing.T) {
+		defer utils.CleanupCollection(collection, ctx)
+		err := ms.MigrateUp(TestMigrationDatabaseName, track, 2)
+		assert.Nil(t, err)
+
+		statuses, err := ms.Status(track)
+		assert.Nil(t, err)
+		assert.Len(t, statuses, 3)
+		assert.True(t, statuses[0].Applied)
+		assert.True(t, statuses[1].Applied)
+		assert.False(t, statuses[2].Applied)
+		assert.False(t, statuses[1].AppliedAt.IsZero())
+		assert.Equal(t, "test", statuses[1].Track)
+
+		var record service.MigrationRecord
+		err = collection.FindOne(ctx, bson.M{"version": "2__Test"}).Decode(&record)
+		assert.Nil(t, err)
+		assert.Equal(t, "test", record.Track)
+		assert.Nil(t, ms.MigrateDown(TestMigrationDatabaseName, track, 0))
+	})
+
+	t.Run("down rolls back migrations above the target in reverse order", func(t *testing.T) {
+		defer utils.CleanupCollection(collection, ctx)
+		err := ms.MigrateUp(TestMigrationDatabaseName, track, 3)
+		assert.Nil(t, err)
+
+		err = ms.MigrateDown(TestMigrationDatabaseName, track, 1)
+		assert.Nil(t, err)
+		latest, err := ms.LatestApplied(track)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, latest)
+		names, err := db.ListCollectionNames(ctx, bson.M{"name": bson.M{"$regex": "^track_test_"}})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"track_test_first"}, names)
+		assert.Nil(t, ms.MigrateDown(TestMigrationDatabaseName, track, 0))
+	})
+
+	t.Run("Rejects targets outside of the track", func(t *testing.T) {
+		assert.NotNil(t, ms.MigrateUp(TestMigrationDatabaseName, track, 4))
+		assert.NotNil(t, ms.MigrateDown(TestMigrationDatabaseName, track, -1))
+	})
+}
+
+func TestMigrationLock(t *testing.T) {
+	if mongoClient == nil {
+		t.Error("mongoClient is uninitialized or otherwise nil")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+	lockCollection := mongoClient.Database(TestMigrationDatabaseName).Collection(service.LockCollectionName)
+	first := service.NewMigrationService(mongoClient, ctx, TestMigrationDatabaseName, TestMigrationCollectionName,
+		logging.Discard())
+	second := service.NewMigrationService(mongoClient, ctx, TestMigrationDatabaseName, TestMigrationCollectionName,
+		logging.Discard())
+
+	t.Run("Only one migrator holds the lock at a time", func(t *testing.T) {
+		defer utils.CleanupCollection(lockCollection, ctx)
+		lock, err := first.AcquireLock(0)
+		assert.Nil(t, err)
+
+		_, err = second.AcquireLock(0)
+		assert.ErrorIs(t, err, service.ErrMigrationLocked)
+
+		assert.Nil(t, lock.Release())
+		lock, err = second.AcquireLock(0)
+		assert.Nil(t, err)
+		assert.Nil(t, lock.Release())
+	})
+
+	t.Run("Takes over a lock whose lease has expired", func(t *testing.T) {
+		defer utils.CleanupCollection(lockCollection, ctx)
+		_, err := lockCollection.InsertOne(ctx, bson.M{
+			"_id":       "migrator",
+			"owner":     "crashed-migrator",
+			"expiresAt": time.Now().Add(-time.Second),
+		})
+		assert.Nil(t, err)
+
+		lock, err := first.AcquireLock(0)
+		assert.Nil(t, err)
+		assert.False(t, lock.Lost())
+		assert.Nil(t, lock.Release())
+	})
+}
+
+func TestMigrationChecksums(t *testing.T) {
+	if mongoClient == nil {
+		t.Error("mongoClient is uninitialized or otherwise nil")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+	collection := mongoClient.Database(TestMigrationDatabaseName).Collection(TestMigrationCollectionName)
+	ms := service.NewMigrationService(mongoClient, ctx, TestMigrationDatabaseName, TestMigrationCollectionName,
+		logging.Discard())
+	migration := createCollectionMigration("1__Test", "checksum_test")
+	migration.Description = "CreateChecksumTest"
+	migration.Checksum = "original"
+	track := versions.Track{Name: "test", Migrations: []versions.Migration{migration}}
+	changed := migration
+	changed.Checksum = "changed"
+	changedTrack := versions.Track{Name: "test", Migrations: []versions.Migration{changed}}
+
+	t.Run("Records the checksum and description of applied migrations", func(t *testing.T) {
+		defer utils.CleanupCollection(collection, ctx)
+		assert.Nil(t, ms.MigrateUp(TestMigrationDatabaseName, track, 1))
+
+		var record service.MigrationRecord
+		err := collection.FindOne(ctx, bson.M{"version": "1__Test"}).Decode(&record)
+		assert.Nil(t, err)
+		assert.Equal(t, "original", record.Checksum)
+		assert.Equal(t, "CreateChecksumTest", record.Description)
+		assert.Nil(t, ms.MigrateDown(TestMigrationDatabaseName, track, 0))
+	})
+
+	t.Run("Refuses to migrate when an applied migration has changed", func(t *testing.T) {
+		defer utils.CleanupCollection(collection, ctx)
+		assert.Nil(t, ms.MigrateUp(TestMigrationDatabaseName, track, 1))
+
+		err := ms.MigrateUp(TestMigrationDatabaseName, changedTrack, 1)
+		assert.ErrorIs(t, err, service.ErrMigrationChanged)
+		statuses, err := ms.Status(changedTrack)
+		assert.Nil(t, err)
+		assert.True(t, statuses[0].Changed)
+
+		ms.SetWarnOnChangedMigrations(true)
+		defer ms.SetWarnOnChangedMigrations(false)
+		assert.Nil(t, ms.MigrateUp(TestMigrationDatabaseName, changedTrack, 1))
+		assert.Nil(t, ms.MigrateDown(TestMigrationDatabaseName, changedTrack, 0))
+	})
+
+	t.Run("Adopts the checksum of migrations applied before checksums were recorded", func(t *testing.T) {
+		defer utils.CleanupCollection(collection, ctx)
+		assert.Nil(t, ms.MarkAsApplied(service.MigrationRecord{Version: "1__Test"}))
+
+		assert.Nil(t, ms.VerifyMigrations(track))
+		var record service.MigrationRecord
+		err := collection.FindOne(ctx, bson.M{"version": "1__Test"}).Decode(&record)
+		assert.Nil(t, err)
+		assert.Equal(t, "original", record.Checksum)
+	})
+
+	t.Run("Upgrades records of the legacy checksum of unchanged migrations", func(t *testing.T) {
+		defer utils.CleanupCollection(collection, ctx)
+		assert.Nil(t, ms.MarkAsApplied(service.MigrationRecord{Version: "1__Test", Checksum: "legacy"}))
+		upgraded := migration
+		upgraded.LegacyChecksum = "legacy"
+		upgradedTrack := versions.Track{Name: "test", Migrations: []versions.Migration{upgraded}}
+
+		statuses, err := ms.Status(upgradedTrack)
+		assert.Nil(t, err)
+		assert.False(t, statuses[0].Changed)
+		assert.Nil(t, ms.VerifyMigrations(upgradedTrack))
+		var record service.MigrationRecord
+		err = collection.FindOne(ctx, bson.M{"version": "1__Test"}).Decode(&record)
+		assert.Nil(t, err)
+		assert.Equal(t, "original", record.Checksum)
+	})
+}
+
+func TestTransactionalMigration(t *testing.T) {
+	if mongoClient == nil {
+		t.Error("mongoClient is uninitialized or otherwise nil")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+	db := mongoClient.Database(TestMigrationDatabaseName)
+	collection := db.Collection(TestMigrationCollectionName)
+	documents := db.Collection("transactional_test")
+	ms := service.NewMigrationService(mongoClient, ctx, TestMigrationDatabaseName, TestMigrationCollectionName,
+		logging.Discard())
+	if err := db.CreateCollection(ctx, "transactional_test"); err != nil {
+		t.Logf("Collection transactional_test already exists: %v", err)
+	}
+
+	t.Run("Leaves neither the documents nor the record behind when the migration fails", func(t *testing.T) {
+		defer utils.CleanupCollection(collection, ctx)
+		defer utils.CleanupCollection(documents, ctx)
+		migration := versions.Migration{
+			Version:       "1__Test",
+			Transactional: true,
+			Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+				_, err := client.Database(databaseName).Collection("transactional_test").
+					InsertOne(ctx, bson.M{"name": "half applied"})
+				if err != nil {
+					return err
+				}
+				return assert.AnError
+			},
+		}
+
+		err, hasBeenApplied := ms.ApplyMigration(TestMigrationDatabaseName, "test", migration)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.False(t, hasBeenApplied)
+		count, err := documents.CountDocuments(ctx, bson.M{})
+		assert.Nil(t, err)
+		assert.Zero(t, count)
+		applied, err := ms.CheckIfApplied("1__Test")
+		assert.Nil(t, err)
+		assert.False(t, applied)
+	})
+}
+
+func createCollectionMigration(version string, collectionName string) versions.Migration {
+	return versions.Migration{
+		Version: version,
+		Up: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+			return client.Database(databaseName).CreateCollection(ctx, collectionName)
+		},
+		Down: func(client *mongo.Client, ctx context.Context, databaseName string) error {
+			return client.Database(databaseName).Collection(collectionName).Drop(ctx)
+		},
+	}
+}
+
 func TestV1SchemaMigration(t *testing.T) {
 	if mongoClient == nil {
 		t.Error("mongoClient is uninitialized or otherwise nil")
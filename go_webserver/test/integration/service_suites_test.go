@@ -0,0 +1,61 @@
+package integration
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+	"webserver/migrations/versions/schema"
+	"webserver/test/suites"
+	"webserver/test/utils"
+)
+
+// newMongodbBackend empties the collections of the test database and returns repositories on them
+func newMongodbBackend(t *testing.T) *suites.Backend {
+	if mongoClient == nil {
+		t.Fatal("mongoClient is uninitialized or otherwise nil")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	database := mongoClient.Database(utils.TestDatabaseName)
+	accCollection := database.Collection(schema.AccountCollectionName)
+	tranCollection := database.Collection(schema.TransactionCollectionName)
+	idempotencyCollection := database.Collection(schema.IdempotencyKeyCollectionName)
+	journalCollection := database.Collection(schema.JournalEntryCollectionName)
+	snapshotCollection := database.Collection(schema.BalanceSnapshotCollectionName)
+	categoryRuleCollection := database.Collection(schema.CategoryRuleCollectionName)
+	for _, collection := range []*mongo.Collection{accCollection, tranCollection, idempotencyCollection,
+		journalCollection, snapshotCollection, categoryRuleCollection} {
+		utils.CleanupCollection(collection, ctx)
+	}
+	m, logger := metrics.New(), logging.Discard()
+	return &suites.Backend{
+		Accounts:         repositories.CreateNewAccountRepositoryMongodb(accCollection, m, logger),
+		Transactions:     repositories.CreateNewTransactionRepositoryMongodb(tranCollection, m, logger),
+		IdempotencyKeys:  repositories.CreateNewIdempotencyRepositoryMongodb(idempotencyCollection, m, logger),
+		Journal:          repositories.CreateNewJournalRepositoryMongodb(journalCollection, m, logger),
+		BalanceSnapshots: repositories.CreateNewBalanceSnapshotRepositoryMongodb(snapshotCollection, m, logger),
+		CategoryRules:    repositories.CreateNewCategoryRuleRepositoryMongodb(categoryRuleCollection, m, logger),
+		Transactional:    transactional.NewMongoTransactional(mongoClient, m, logger),
+	}
+}
+
+func TestAccountServiceSuite(t *testing.T) {
+	suites.RunAccountServiceSuite(t, newMongodbBackend)
+}
+
+func TestTransactionServiceSuite(t *testing.T) {
+	suites.RunTransactionServiceSuite(t, newMongodbBackend)
+}
+
+func TestCategoryServiceSuite(t *testing.T) {
+	suites.RunCategoryServiceSuite(t, newMongodbBackend)
+}
+
+func TestTracingSuite(t *testing.T) {
+	suites.RunTracingSuite(t, newMongodbBackend)
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"testing"
 	"time"
+	"webserver/internal/pkg/logging"
 	"webserver/migrations/service"
 	"webserver/migrations/versions/schema"
 	"webserver/test/utils"
@@ -31,10 +32,11 @@ func TestMain(m *testing.M) {
 	if mongoClient == nil {
 		log.Fatalf("mongoClient is uninitialized or otherwise nil")
 	}
-	ms := service.NewMigrationService(mongoClient, ctx, migrationDatabaseName, migrationCollectionName)
-	migrations := schema.SchemaMigrations
+	ms := service.NewMigrationService(mongoClient, ctx, migrationDatabaseName, migrationCollectionName,
+		logging.Discard())
+	migrations := schema.Track.Migrations
 	for _, elem := range migrations {
-		_, hasBeenApplied := ms.ApplyMigration(mainDatabaseName, elem)
+		_, hasBeenApplied := ms.ApplyMigration(mainDatabaseName, schema.Track.Name, elem)
 		if !hasBeenApplied {
 			log.Fatalf("Unable to apply migration %s", elem.Version)
 		}
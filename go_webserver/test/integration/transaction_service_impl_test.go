@@ -5,6 +5,7 @@ import (
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"log"
 	"testing"
@@ -14,6 +15,8 @@ import (
 	"webserver/internal/pkg/domain/services"
 	"webserver/internal/pkg/infrastructure/mongodb"
 	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
 	pkgutils "webserver/internal/pkg/utils"
 	"webserver/migrations/versions/schema"
 	"webserver/test/utils"
@@ -42,7 +45,7 @@ func TestAddTransaction(t *testing.T) {
 
 	t.Run("Should be able to insert transactions", func(t *testing.T) {
 		setupAddTransactionTestCase(tranCollection, accCollection, ctx, t)
-		err := service.AddTransaction(input, ctx)
+		_, err := service.AddTransaction(input, nil, ctx)
 		assert.Nil(t, err)
 		samFind, tomFind := mongodb.MongoAccountOutput{}, mongodb.MongoAccountOutput{}
 		err = accCollection.FindOne(
@@ -104,7 +107,7 @@ func TestAddTransaction(t *testing.T) {
 			Amount:            decimal.RequireFromString(reallyHighAmount),
 			Type:              model.Realized,
 		}
-		err := service.AddTransaction(reallyHighInput, ctx)
+		_, err := service.AddTransaction(reallyHighInput, nil, ctx)
 		assert.NotNil(t, err)
 		assert.EqualError(t, err, "insufficient balance in BankAccount "+tomAccountName)
 	})
@@ -117,7 +120,7 @@ func TestAddTransaction(t *testing.T) {
 			FromBankAccountId: tomAccountName,
 			Amount:            decimal.RequireFromString(reallyHighAmount),
 		}
-		err := service.AddTransaction(reallyHighInput, ctx)
+		_, err := service.AddTransaction(reallyHighInput, nil, ctx)
 		assert.NotNil(t, err)
 		samDetails, tomDetails := mongodb.MongoAccountOutput{}, mongodb.MongoAccountOutput{}
 
@@ -157,7 +160,7 @@ func TestAddTransaction(t *testing.T) {
 			Amount:            transferAmount,
 			Type:              model.Pending,
 		}
-		err := service.AddTransaction(pendingInput, ctx)
+		_, err := service.AddTransaction(pendingInput, nil, ctx)
 		assert.Nil(t, err)
 		samFind, tomFind := mongodb.MongoAccountOutput{}, mongodb.MongoAccountOutput{}
 		err = accCollection.FindOne(
@@ -211,6 +214,201 @@ func TestAddTransaction(t *testing.T) {
 	})
 }
 
+func TestPendingTransactionLifecycle(t *testing.T) {
+	if mongoClient == nil {
+		t.Error("mongoClient is uninitialized or otherwise nil")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+	tranCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.TransactionCollectionName)
+	accCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.AccountCollectionName)
+
+	tomAccountName, _ := pkgutils.ObjectIdToString(utils.TomAccountDetails.BankAccounts[0].Id)
+	samAccountName, _ := pkgutils.ObjectIdToString(utils.SamAccountDetails.BankAccounts[0].Id)
+
+	service := setupTransactionService(mongoClient, tranCollection, accCollection)
+	transferAmount, _ := decimal.NewFromString("100.00")
+	pendingInput := model.TransactionDetailsInput{
+		ToBankAccountId:   samAccountName,
+		FromBankAccountId: tomAccountName,
+		Amount:            transferAmount,
+		ExpirationDate:    time.Now().Add(time.Hour),
+	}
+	tomBankAccount, samBankAccount := utils.TomAccountDetails.BankAccounts[0], utils.SamAccountDetails.BankAccounts[0]
+	tomAvailable, _ := pkgutils.FromPrimitiveDecimal128ToDecimal(tomBankAccount.AvailableBalance)
+	tomPending, _ := pkgutils.FromPrimitiveDecimal128ToDecimal(tomBankAccount.PendingBalance)
+	samAvailable, _ := pkgutils.FromPrimitiveDecimal128ToDecimal(samBankAccount.AvailableBalance)
+	samPending, _ := pkgutils.FromPrimitiveDecimal128ToDecimal(samBankAccount.PendingBalance)
+
+	t.Run("Applying a pending transaction realizes the transfer", func(t *testing.T) {
+		setupAddTransactionTestCase(tranCollection, accCollection, ctx, t)
+		transactionId, err := service.AddPendingTransaction(pendingInput, ctx)
+		assert.Nil(t, err)
+
+		err = service.ApplyPendingTransaction(transactionId, ctx)
+		assert.Nil(t, err)
+
+		tomFind, samFind := findAccountBalances(accCollection, ctx, t)
+		assert.Equal(
+			t, tomAvailable.Sub(transferAmount).String(), formatDecimalString(tomFind.AvailableBalance.String()),
+		)
+		assert.Equal(t, tomPending.Sub(transferAmount).String(), formatDecimalString(tomFind.PendingBalance.String()))
+		assert.Equal(
+			t, samAvailable.Add(transferAmount).String(), formatDecimalString(samFind.AvailableBalance.String()),
+		)
+		assert.Equal(t, samPending.Add(transferAmount).String(), formatDecimalString(samFind.PendingBalance.String()))
+
+		var pendingRes = mongodb.MongoTransactionOutput{}
+		objectId, _ := pkgutils.StringToObjectId(transactionId)
+		err = tranCollection.FindOne(ctx, bson.M{"_id": objectId}).Decode(&pendingRes)
+		assert.Nil(t, err)
+		assert.Equal(t, string(model.Applied), pendingRes.Status)
+		realizedCount, err := tranCollection.CountDocuments(ctx, bson.M{"type": string(model.Realized)})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), realizedCount)
+	})
+
+	t.Run("Revoking a pending transaction restores the pending balances", func(t *testing.T) {
+		setupAddTransactionTestCase(tranCollection, accCollection, ctx, t)
+		transactionId, err := service.AddPendingTransaction(pendingInput, ctx)
+		assert.Nil(t, err)
+
+		err = service.RevokePendingTransaction(transactionId, ctx)
+		assert.Nil(t, err)
+
+		tomFind, samFind := findAccountBalances(accCollection, ctx, t)
+		assert.Equal(t, tomAvailable.String(), formatDecimalString(tomFind.AvailableBalance.String()))
+		assert.Equal(t, tomPending.String(), formatDecimalString(tomFind.PendingBalance.String()))
+		assert.Equal(t, samAvailable.String(), formatDecimalString(samFind.AvailableBalance.String()))
+		assert.Equal(t, samPending.String(), formatDecimalString(samFind.PendingBalance.String()))
+
+		err = service.ApplyPendingTransaction(transactionId, ctx)
+		assert.ErrorIs(t, err, model.ErrPendingTransactionNotActive)
+	})
+
+	t.Run("Only expired pending transactions are revoked", func(t *testing.T) {
+		setupAddTransactionTestCase(tranCollection, accCollection, ctx, t)
+		expiringId, err := service.AddPendingTransaction(pendingInput, ctx)
+		assert.Nil(t, err)
+		laterInput := pendingInput
+		laterInput.ExpirationDate = time.Now().Add(3 * time.Hour)
+		_, err = service.AddPendingTransaction(laterInput, ctx)
+		assert.Nil(t, err)
+
+		revoked, err := service.RevokeExpiredPendingTransactions(time.Now().Add(2*time.Hour), ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, revoked)
+
+		var pendingRes = mongodb.MongoTransactionOutput{}
+		objectId, _ := pkgutils.StringToObjectId(expiringId)
+		err = tranCollection.FindOne(ctx, bson.M{"_id": objectId}).Decode(&pendingRes)
+		assert.Nil(t, err)
+		assert.Equal(t, string(model.Revoked), pendingRes.Status)
+		activeCount, err := tranCollection.CountDocuments(ctx, bson.M{"status": string(model.Active)})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), activeCount)
+	})
+}
+
+func TestAddTransactionIdempotency(t *testing.T) {
+	if mongoClient == nil {
+		t.Error("mongoClient is uninitialized or otherwise nil")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+	tranCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.TransactionCollectionName)
+	accCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.AccountCollectionName)
+	idemCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.IdempotencyKeyCollectionName)
+
+	tomAccountName, _ := pkgutils.ObjectIdToString(utils.TomAccountDetails.BankAccounts[0].Id)
+	samAccountName, _ := pkgutils.ObjectIdToString(utils.SamAccountDetails.BankAccounts[0].Id)
+
+	service := setupTransactionService(mongoClient, tranCollection, accCollection)
+	transferAmount, _ := decimal.NewFromString("100.00")
+	input := model.TransactionDetailsInput{
+		ToBankAccountId:   samAccountName,
+		FromBankAccountId: tomAccountName,
+		Amount:            transferAmount,
+		Type:              model.Realized,
+	}
+	key := &model.IdempotencyKeyInput{Key: "retried-transfer", AccountId: primitive.NewObjectID().Hex()}
+	tomAvailable, _ := pkgutils.FromPrimitiveDecimal128ToDecimal(
+		utils.TomAccountDetails.BankAccounts[0].AvailableBalance,
+	)
+
+	t.Run("Retrying a request with the same key transfers the amount once", func(t *testing.T) {
+		setupAddTransactionTestCase(tranCollection, accCollection, ctx, t)
+		utils.CleanupCollection(idemCollection, ctx)
+
+		first, err := service.AddTransaction(input, key, ctx)
+		assert.Nil(t, err)
+		assert.False(t, first.Replayed)
+		retried, err := service.AddTransaction(input, key, ctx)
+		assert.Nil(t, err)
+		assert.True(t, retried.Replayed)
+		assert.Equal(t, first.Id, retried.Id)
+
+		tomFind, _ := findAccountBalances(accCollection, ctx, t)
+		assert.Equal(
+			t, tomAvailable.Sub(transferAmount).String(), formatDecimalString(tomFind.AvailableBalance.String()),
+		)
+		count, err := tranCollection.CountDocuments(ctx, bson.M{})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Reusing a key for a different request is rejected", func(t *testing.T) {
+		setupAddTransactionTestCase(tranCollection, accCollection, ctx, t)
+		utils.CleanupCollection(idemCollection, ctx)
+
+		_, err := service.AddTransaction(input, key, ctx)
+		assert.Nil(t, err)
+		different := input
+		different.Amount, _ = decimal.NewFromString("50.00")
+		_, err = service.AddTransaction(different, key, ctx)
+		assert.ErrorIs(t, err, model.ErrIdempotencyKeyReused)
+
+		tomFind, _ := findAccountBalances(accCollection, ctx, t)
+		assert.Equal(
+			t, tomAvailable.Sub(transferAmount).String(), formatDecimalString(tomFind.AvailableBalance.String()),
+		)
+	})
+
+	t.Run("A failed transfer does not store the key", func(t *testing.T) {
+		setupAddTransactionTestCase(tranCollection, accCollection, ctx, t)
+		utils.CleanupCollection(idemCollection, ctx)
+
+		tooHigh := input
+		tooHigh.Amount, _ = decimal.NewFromString("1000000000.00")
+		_, err := service.AddTransaction(tooHigh, key, ctx)
+		assert.NotNil(t, err)
+		count, err := idemCollection.CountDocuments(ctx, bson.M{})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+func findAccountBalances(
+	accCollection *mongo.Collection,
+	ctx context.Context,
+	t *testing.T,
+) (mongodb.BankAccount, mongodb.BankAccount) {
+	samFind, tomFind := mongodb.MongoAccountOutput{}, mongodb.MongoAccountOutput{}
+	err := accCollection.FindOne(
+		ctx, bson.M{"bankAccounts._id": utils.SamAccountDetails.BankAccounts[0].Id},
+	).Decode(&samFind)
+	if err != nil {
+		t.Errorf("Error in finding Sam's account details: %v", err)
+	}
+	err = accCollection.FindOne(
+		ctx, bson.M{"bankAccounts._id": utils.TomAccountDetails.BankAccounts[0].Id},
+	).Decode(&tomFind)
+	if err != nil {
+		t.Errorf("Error in finding Tom's account details: %v", err)
+	}
+	return tomFind.BankAccounts[0], samFind.BankAccounts[0]
+}
+
 func setupAddTransactionTestCase(
 	tranCollection *mongo.Collection,
 	accCollection *mongo.Collection,
@@ -236,10 +434,21 @@ func setupTransactionService(
 	tranCollection *mongo.Collection,
 	accCollection *mongo.Collection,
 ) *services.TransactionServiceImpl {
-	tr := repositories.CreateNewTransactionRepositoryMongodb(tranCollection)
-	ar := repositories.CreateNewAccountRepositoryMongodb(accCollection)
-	tran := transactional.NewMongoTransactional(mongoClient)
-	service := services.CreateNewTransactionServiceImpl(tr, ar, tran)
+	m, logger := metrics.New(), logging.Discard()
+	tr := repositories.CreateNewTransactionRepositoryMongodb(tranCollection, m, logger)
+	ar := repositories.CreateNewAccountRepositoryMongodb(accCollection, m, logger)
+	ir := repositories.CreateNewIdempotencyRepositoryMongodb(
+		mongoClient.Database(utils.TestDatabaseName).Collection(schema.IdempotencyKeyCollectionName), m, logger,
+	)
+	jr := repositories.CreateNewJournalRepositoryMongodb(
+		mongoClient.Database(utils.TestDatabaseName).Collection(schema.JournalEntryCollectionName), m, logger,
+	)
+	cr := repositories.CreateNewCategoryRuleRepositoryMongodb(
+		mongoClient.Database(utils.TestDatabaseName).Collection(schema.CategoryRuleCollectionName), m, logger,
+	)
+	tran := transactional.NewMongoTransactional(mongoClient, m, logger)
+	service := services.CreateNewTransactionServiceImpl(tr, ar, ir, jr, cr, tran, model.AllowUnknownPayees, m,
+		logger)
 	return service
 }
 
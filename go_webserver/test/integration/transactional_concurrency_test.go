@@ -0,0 +1,152 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"sync"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+	pkgutils "webserver/internal/pkg/utils"
+	"webserver/migrations/versions/schema"
+	"webserver/test/utils"
+)
+
+func TestConcurrentTransactions(t *testing.T) {
+	if mongoClient == nil {
+		t.Error("mongoClient is uninitialized or otherwise nil")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+	tranCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.TransactionCollectionName)
+	accCollection := mongoClient.Database(utils.TestDatabaseName).Collection(schema.AccountCollectionName)
+
+	tomAccountName, _ := pkgutils.ObjectIdToString(utils.TomAccountDetails.BankAccounts[0].Id)
+	samAccountName, _ := pkgutils.ObjectIdToString(utils.SamAccountDetails.BankAccounts[0].Id)
+	tomBankAccount, samBankAccount := utils.TomAccountDetails.BankAccounts[0], utils.SamAccountDetails.BankAccounts[0]
+	tomAvailable, _ := pkgutils.FromPrimitiveDecimal128ToDecimal(tomBankAccount.AvailableBalance)
+	samAvailable, _ := pkgutils.FromPrimitiveDecimal128ToDecimal(samBankAccount.AvailableBalance)
+
+	t.Run("Parallel transfers between the same bank accounts are all applied exactly once", func(t *testing.T) {
+		setupAddTransactionTestCase(tranCollection, accCollection, ctx, t)
+		service := setupTransactionService(mongoClient, tranCollection, accCollection)
+		const transfers = 20
+		transferAmount, _ := decimal.NewFromString("1.25")
+
+		var wg sync.WaitGroup
+		errs := make(chan error, transfers)
+		for i := 0; i < transfers; i++ {
+			input := model.TransactionDetailsInput{
+				FromBankAccountId: tomAccountName,
+				ToBankAccountId:   samAccountName,
+				Amount:            transferAmount,
+				Type:              model.Realized,
+			}
+			// Alternate directions so that both bank accounts are contended for by either side of the transfer
+			if i%2 == 1 {
+				input.FromBankAccountId, input.ToBankAccountId = samAccountName, tomAccountName
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				requestCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+				defer cancel()
+				_, err := service.AddTransaction(input, nil, requestCtx)
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			assert.Nil(t, err)
+		}
+
+		tomFind, samFind := findAccountBalances(accCollection, ctx, t)
+		assert.Equal(t, tomAvailable.String(), formatDecimalString(tomFind.AvailableBalance.String()))
+		assert.Equal(t, samAvailable.String(), formatDecimalString(samFind.AvailableBalance.String()))
+		count, err := tranCollection.CountDocuments(ctx, bson.M{})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(transfers), count)
+	})
+
+	t.Run("Concurrent transactions commit and roll back their own sessions", func(t *testing.T) {
+		utils.CleanupCollection(tranCollection, ctx)
+		tran := transactional.NewMongoTransactional(mongoClient, metrics.New(), logging.Discard())
+		const transactions = 20
+
+		var wg sync.WaitGroup
+		errs := make(chan error, transactions)
+		for i := 0; i < transactions; i++ {
+			wg.Add(1)
+			go func(shouldCommit bool) {
+				defer wg.Done()
+				txnCtx, err := tran.BeginTransaction(ctx, transactional.IsolationLow, transactional.DurabilityHigh)
+				if err != nil {
+					errs <- err
+					return
+				}
+				_, err = tranCollection.InsertOne(txnCtx, bson.M{
+					"fromBankAccountId": utils.TomAccountDetails.BankAccounts[0].Id,
+					"toBankAccountId":   utils.SamAccountDetails.BankAccounts[0].Id,
+					"amount":            utils.TomAccountDetails.BankAccounts[0].AvailableBalance,
+					"type":              "realized",
+					"_createdAt":        pkgutils.GetCurrentTimestamp(),
+				})
+				if err != nil {
+					_ = tran.Rollback(txnCtx)
+					errs <- err
+					return
+				}
+				if shouldCommit {
+					errs <- tran.Commit(txnCtx)
+				} else {
+					errs <- tran.Rollback(txnCtx)
+				}
+			}(i%2 == 0)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			assert.Nil(t, err)
+		}
+
+		count, err := tranCollection.CountDocuments(ctx, bson.M{})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(transactions/2), count)
+	})
+
+	t.Run("WithTransaction rolls back when the function fails", func(t *testing.T) {
+		utils.CleanupCollection(tranCollection, ctx)
+		tran := transactional.NewMongoTransactional(mongoClient, metrics.New(), logging.Discard())
+		errFailed := errors.New("failed")
+		opts := transactional.TransactionOptions{
+			Isolation:  transactional.IsolationLow,
+			Durability: transactional.DurabilityHigh,
+		}
+
+		err := tran.WithTransaction(ctx, opts, func(txnCtx transactional.TransactionContext) error {
+			_, err := tranCollection.InsertOne(txnCtx, bson.M{
+				"fromBankAccountId": utils.TomAccountDetails.BankAccounts[0].Id,
+				"toBankAccountId":   utils.SamAccountDetails.BankAccounts[0].Id,
+				"amount":            utils.TomAccountDetails.BankAccounts[0].AvailableBalance,
+				"type":              "realized",
+				"_createdAt":        pkgutils.GetCurrentTimestamp(),
+			})
+			if err != nil {
+				return err
+			}
+			return errFailed
+		})
+		assert.ErrorIs(t, err, errFailed)
+
+		count, err := tranCollection.CountDocuments(ctx, bson.M{})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+}
@@ -23,6 +23,14 @@ func (m *MockAccountRepository) GetAccountDetailsFromBankAccountId(
 	return accountDetails, args.Error(1)
 }
 
+func (m *MockAccountRepository) GetAccountIdFromBankAccountId(
+	bankAccountId string,
+	ctx context.Context,
+) (string, error) {
+	args := m.Called(bankAccountId, ctx)
+	return args.String(0), args.Error(1)
+}
+
 func (m *MockAccountRepository) AddBalance(
 	bankAccountID string,
 	amount decimal.Decimal,
@@ -68,5 +76,97 @@ func (m *MockAccountRepository) GetAccountBalance(bankAccountId string, ctx cont
 	if args.Get(1) != nil {
 		pendingBalance = args.Get(1).(decimal.Decimal)
 	}
-	return balance, pendingBalance, args.Error(1)
+	return balance, pendingBalance, args.Error(2)
+}
+
+func (m *MockAccountRepository) GetAccountCredentialsFromUsername(
+	username string,
+	ctx context.Context,
+) (*model.AccountCredentialsOutput, error) {
+	args := m.Called(username, ctx)
+	var credentials *model.AccountCredentialsOutput
+	if args.Get(0) != nil {
+		credentials = args.Get(0).(*model.AccountCredentialsOutput)
+	}
+	return credentials, args.Error(1)
+}
+
+func (m *MockAccountRepository) UpdatePasswordHash(accountId string, passwordHash string, ctx context.Context) error {
+	args := m.Called(accountId, passwordHash, ctx)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) AddAccount(input *model.AccountInput, ctx context.Context) (string, error) {
+	args := m.Called(input, ctx)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAccountRepository) AddBankAccount(
+	accountId string,
+	input *model.BankAccountInput,
+	ctx context.Context,
+) (string, error) {
+	args := m.Called(accountId, input, ctx)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAccountRepository) GetAccountDetailsFromAccountNumber(
+	accountNumber string,
+	ctx context.Context,
+) (*model.AccountDetailsOutput, error) {
+	args := m.Called(accountNumber, ctx)
+	var accountDetails *model.AccountDetailsOutput
+	if args.Get(0) != nil {
+		accountDetails = args.Get(0).(*model.AccountDetailsOutput)
+	}
+	return accountDetails, args.Error(1)
+}
+
+func (m *MockAccountRepository) GetKnownBankAccounts(
+	accountId string,
+	ctx context.Context,
+) ([]model.KnownBankAccount, error) {
+	args := m.Called(accountId, ctx)
+	var knownBankAccounts []model.KnownBankAccount
+	if args.Get(0) != nil {
+		knownBankAccounts = args.Get(0).([]model.KnownBankAccount)
+	}
+	return knownBankAccounts, args.Error(1)
+}
+
+func (m *MockAccountRepository) AddKnownBankAccount(
+	accountId string,
+	knownBankAccount *model.KnownBankAccount,
+	ctx context.Context,
+) error {
+	args := m.Called(accountId, knownBankAccount, ctx)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) UpdateKnownBankAccountNickname(
+	accountId string,
+	knownBankAccountId string,
+	nickname string,
+	ctx context.Context,
+) error {
+	args := m.Called(accountId, knownBankAccountId, nickname, ctx)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) RemoveKnownBankAccount(
+	accountId string,
+	knownBankAccountId string,
+	ctx context.Context,
+) error {
+	args := m.Called(accountId, knownBankAccountId, ctx)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) GetAllBankAccountBalances(ctx context.Context) ([]model.BankAccountBalance, error) {
+	args := m.Called(ctx)
+	var balances = make([]model.BankAccountBalance, 0)
+	if args.Get(0) != nil {
+		balances = args.Get(0).([]model.BankAccountBalance)
+	}
+	return balances, args.Error(1)
 }
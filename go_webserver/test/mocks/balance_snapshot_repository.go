@@ -0,0 +1,59 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+type MockBalanceSnapshotRepository struct {
+	mock.Mock
+}
+
+func (m *MockBalanceSnapshotRepository) AddBalanceSnapshots(
+	snapshots []model.BalanceSnapshot,
+	ctx context.Context,
+) (int, error) {
+	args := m.Called(snapshots, ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBalanceSnapshotRepository) GetEarliestBalanceSnapshot(
+	bankAccountId string,
+	fromDay time.Time,
+	ctx context.Context,
+) (*model.BalanceSnapshot, error) {
+	args := m.Called(bankAccountId, fromDay, ctx)
+	var snapshot *model.BalanceSnapshot
+	if args.Get(0) != nil {
+		snapshot = args.Get(0).(*model.BalanceSnapshot)
+	}
+	return snapshot, args.Error(1)
+}
+
+func (m *MockBalanceSnapshotRepository) GetBalanceSnapshots(
+	bankAccountId string,
+	fromDay time.Time,
+	toDay time.Time,
+	ctx context.Context,
+) ([]model.BalanceSnapshot, error) {
+	args := m.Called(bankAccountId, fromDay, toDay, ctx)
+	var snapshots []model.BalanceSnapshot
+	if args.Get(0) != nil {
+		snapshots = args.Get(0).([]model.BalanceSnapshot)
+	}
+	return snapshots, args.Error(1)
+}
+
+func (m *MockBalanceSnapshotRepository) GetLatestBalanceSnapshot(
+	bankAccountId string,
+	ctx context.Context,
+) (*model.BalanceSnapshot, error) {
+	args := m.Called(bankAccountId, ctx)
+	var snapshot *model.BalanceSnapshot
+	if args.Get(0) != nil {
+		snapshot = args.Get(0).(*model.BalanceSnapshot)
+	}
+	return snapshot, args.Error(1)
+}
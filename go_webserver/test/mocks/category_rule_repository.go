@@ -0,0 +1,41 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"webserver/internal/pkg/domain/model"
+)
+
+type MockCategoryRuleRepository struct {
+	mock.Mock
+}
+
+func (m *MockCategoryRuleRepository) AddCategoryRule(
+	accountId string,
+	input *model.CategoryRuleInput,
+	ctx context.Context,
+) (*model.CategoryRule, error) {
+	args := m.Called(accountId, input, ctx)
+	var rule *model.CategoryRule
+	if args.Get(0) != nil {
+		rule = args.Get(0).(*model.CategoryRule)
+	}
+	return rule, args.Error(1)
+}
+
+func (m *MockCategoryRuleRepository) GetCategoryRules(
+	accountId string,
+	ctx context.Context,
+) ([]model.CategoryRule, error) {
+	args := m.Called(accountId, ctx)
+	var rules []model.CategoryRule
+	if args.Get(0) != nil {
+		rules = args.Get(0).([]model.CategoryRule)
+	}
+	return rules, args.Error(1)
+}
+
+func (m *MockCategoryRuleRepository) RemoveCategoryRule(accountId string, ruleId string, ctx context.Context) error {
+	args := m.Called(accountId, ruleId, ctx)
+	return args.Error(0)
+}
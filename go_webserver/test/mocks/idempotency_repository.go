@@ -0,0 +1,34 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+type MockIdempotencyRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyRepository) GetIdempotencyRecord(
+	key *model.IdempotencyKeyInput,
+	ctx context.Context,
+) (*model.IdempotencyRecord, error) {
+	args := m.Called(key, ctx)
+	var record *model.IdempotencyRecord
+	if args.Get(0) != nil {
+		record = args.Get(0).(*model.IdempotencyRecord)
+	}
+	return record, args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) AddIdempotencyRecord(record *model.IdempotencyRecord, ctx context.Context) error {
+	args := m.Called(record, ctx)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) DeleteExpiredIdempotencyRecords(now time.Time, ctx context.Context) (int, error) {
+	args := m.Called(now, ctx)
+	return args.Int(0), args.Error(1)
+}
@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"webserver/internal/pkg/domain/model"
+)
+
+type MockJournalRepository struct {
+	mock.Mock
+}
+
+func (m *MockJournalRepository) AddJournalEntry(entry *model.JournalEntryInput, ctx context.Context) (string, error) {
+	args := m.Called(entry, ctx)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockJournalRepository) GetPostingTotals(ctx context.Context) ([]model.PostingTotal, error) {
+	args := m.Called(ctx)
+	var totals = make([]model.PostingTotal, 0)
+	if args.Get(0) != nil {
+		totals = args.Get(0).([]model.PostingTotal)
+	}
+	return totals, args.Error(1)
+}
@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 	"github.com/stretchr/testify/mock"
+	"time"
 	"webserver/internal/pkg/domain/model"
 )
 
@@ -10,9 +11,12 @@ type MockTransactionRepository struct {
 	mock.Mock
 }
 
-func (m *MockTransactionRepository) AddTransaction(details *model.TransactionDetailsInput, ctx context.Context) error {
+func (m *MockTransactionRepository) AddTransaction(
+	details *model.TransactionDetailsInput,
+	ctx context.Context,
+) (string, error) {
 	args := m.Called(details, ctx)
-	return args.Error(0)
+	return args.String(0), args.Error(1)
 }
 
 func (m *MockTransactionRepository) GetTransactionsFromBankAccountId(
@@ -26,3 +30,58 @@ func (m *MockTransactionRepository) GetTransactionsFromBankAccountId(
 	}
 	return accountTransactions, args.Error(1)
 }
+
+func (m *MockTransactionRepository) GetTransactionsPageFromBankAccountId(
+	input *model.TransactionPageInput,
+	ctx context.Context,
+) (*model.TransactionPageOutput, error) {
+	args := m.Called(input, ctx)
+	var page *model.TransactionPageOutput
+	if args.Get(0) != nil {
+		page = args.Get(0).(*model.TransactionPageOutput)
+	}
+	return page, args.Error(1)
+}
+
+func (m *MockTransactionRepository) GetTransactionFromId(
+	transactionId string,
+	ctx context.Context,
+) (*model.TransactionDetailsOutput, error) {
+	args := m.Called(transactionId, ctx)
+	var transactionDetails *model.TransactionDetailsOutput
+	if args.Get(0) != nil {
+		transactionDetails = args.Get(0).(*model.TransactionDetailsOutput)
+	}
+	return transactionDetails, args.Error(1)
+}
+
+func (m *MockTransactionRepository) UpdatePendingTransactionStatus(
+	transactionId string,
+	status model.PendingTransactionStatus,
+	ctx context.Context,
+) error {
+	args := m.Called(transactionId, status, ctx)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRepository) GetExpiredPendingTransactions(
+	expiredBy time.Time,
+	ctx context.Context,
+) ([]model.TransactionDetailsOutput, error) {
+	args := m.Called(expiredBy, ctx)
+	var transactions = make([]model.TransactionDetailsOutput, 0)
+	if args.Get(0) != nil {
+		transactions = args.Get(0).([]model.TransactionDetailsOutput)
+	}
+	return transactions, args.Error(1)
+}
+
+func (m *MockTransactionRepository) UpdateTransactionCategory(
+	transactionId string,
+	bankAccountId string,
+	category model.TransactionCategory,
+	ctx context.Context,
+) error {
+	args := m.Called(transactionId, bankAccountId, category, ctx)
+	return args.Error(0)
+}
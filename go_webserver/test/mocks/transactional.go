@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"fmt"
 	"github.com/stretchr/testify/mock"
 	"webserver/internal/pkg/infrastructure/transactional"
 )
@@ -28,3 +29,24 @@ func (m *MockTransactional) Rollback(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
 }
+
+// WithTransaction runs fn once between the mocked BeginTransaction and Commit or Rollback, so that tests set
+// expectations on those calls as they would for a service managing its transaction by hand
+func (m *MockTransactional) WithTransaction(
+	ctx context.Context,
+	opts transactional.TransactionOptions,
+	fn func(txnCtx transactional.TransactionContext) error,
+) error {
+	txnCtx, err := m.BeginTransaction(ctx, opts.Isolation, opts.Durability)
+	if err != nil {
+		return err
+	}
+	if err = fn(txnCtx); err != nil {
+		_ = m.Rollback(txnCtx)
+		return err
+	}
+	if err = m.Commit(txnCtx); err != nil {
+		return fmt.Errorf("%w: %w", transactional.ErrCommitFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package suites
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+)
+
+// RunAccountServiceSuite checks the account service against the storage returned by newBackend
+func RunAccountServiceSuite(t *testing.T, newBackend NewBackend) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+
+	t.Run("Registered accounts can log in", func(t *testing.T) {
+		b := newBackend(t)
+		tom := seedAccount(t, b, "tom", "10.00", ctx)
+		as := b.accountService()
+
+		details, err := as.Login(tom.Username, tom.Password, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, tom.AccountId, details.Id)
+		_, err = as.Login(tom.Username, "wrong-password", ctx)
+		assert.ErrorIs(t, err, model.ErrInvalidCredentials)
+		_, err = as.Login("nobody", tom.Password, ctx)
+		assert.ErrorIs(t, err, model.ErrInvalidCredentials)
+	})
+
+	t.Run("Usernames can only be registered once", func(t *testing.T) {
+		b := newBackend(t)
+		seedAccount(t, b, "tom", "10.00", ctx)
+		_, err := b.accountService().Register(&model.RegisterAccountInput{
+			Username: "tom",
+			Password: "another-password",
+			Person:   model.Person{FirstName: "Tom", LastName: "Other"},
+		}, ctx)
+		assert.ErrorIs(t, err, model.ErrUsernameTaken)
+	})
+
+	t.Run("Opened bank accounts belong to their account", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "10.00", ctx), seedAccount(t, b, "sam", "10.00", ctx)
+		as := b.accountService()
+
+		bankAccount, err := as.OpenBankAccount(tom.AccountId, model.Checking, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "0", bankAccount.AvailableBalance.String())
+		details, err := as.GetAccountDetailsFromBankAccountId(bankAccount.Id, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, tom.AccountId, details.Id)
+		assert.Len(t, details.BankAccounts, 2)
+
+		owner, err := as.IsBankAccountOwner(tom.AccountId, bankAccount.Id, ctx)
+		assert.Nil(t, err)
+		assert.True(t, owner)
+		owner, err = as.IsBankAccountOwner(sam.AccountId, bankAccount.Id, ctx)
+		assert.Nil(t, err)
+		assert.False(t, owner)
+	})
+
+	t.Run("Payees can be added, renamed and removed", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "10.00", ctx), seedAccount(t, b, "sam", "10.00", ctx)
+		as := b.accountService()
+
+		payee, err := as.AddPayee(tom.AccountId, &model.PayeeInput{AccountNumber: sam.AccountNumber}, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, sam.BankAccountId, payee.Id)
+		_, err = as.AddPayee(tom.AccountId, &model.PayeeInput{AccountNumber: sam.AccountNumber}, ctx)
+		assert.ErrorIs(t, err, model.ErrPayeeAlreadyKnown)
+		_, err = as.AddPayee(tom.AccountId, &model.PayeeInput{AccountNumber: tom.AccountNumber}, ctx)
+		assert.ErrorIs(t, err, model.ErrPayeeIsOwnBankAccount)
+
+		assert.Nil(t, as.RenamePayee(tom.AccountId, payee.Id, "Sam", ctx))
+		payees, err := as.GetPayees(tom.AccountId, ctx)
+		assert.Nil(t, err)
+		assert.Len(t, payees, 1)
+		assert.Equal(t, "Sam", payees[0].Nickname)
+
+		assert.Nil(t, as.RemovePayee(tom.AccountId, payee.Id, ctx))
+		assert.ErrorIs(t, as.RemovePayee(tom.AccountId, payee.Id, ctx), model.ErrNoMatchingPayee)
+		payees, err = as.GetPayees(tom.AccountId, ctx)
+		assert.Nil(t, err)
+		assert.Empty(t, payees)
+	})
+
+	t.Run("Transaction pages list every transaction once", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "100.00", ctx), seedAccount(t, b, "sam", "100.00", ctx)
+		ts := b.transactionService(model.AllowUnknownPayees)
+		const transfers = 5
+		created := make(map[string]bool)
+		for i := 0; i < transfers; i++ {
+			input := model.TransactionDetailsInput{
+				FromBankAccountId: tom.BankAccountId,
+				ToBankAccountId:   sam.BankAccountId,
+				Amount:            decimal.NewFromInt(int64(i + 1)),
+				Type:              model.Realized,
+			}
+			if i%2 == 1 {
+				input.FromBankAccountId, input.ToBankAccountId = sam.BankAccountId, tom.BankAccountId
+			}
+			res, err := ts.AddTransaction(input, nil, ctx)
+			assert.Nil(t, err)
+			created[res.Id] = true
+		}
+
+		as := b.accountService()
+		for _, order := range []model.SortOrder{model.Ascending, model.Descending} {
+			listed := make(map[string]bool)
+			input := &model.TransactionPageInput{BankAccountId: tom.BankAccountId, Order: order, PageSize: 2}
+			for pages := 0; pages < transfers; pages++ {
+				page, err := as.GetBankAccountTransactionsPage(input, ctx)
+				assert.Nil(t, err)
+				for _, transaction := range page.Transactions {
+					assert.False(t, listed[transaction.Id], "transaction %s is listed twice", transaction.Id)
+					listed[transaction.Id] = true
+				}
+				if page.Next == nil {
+					break
+				}
+				input.After = page.Next
+			}
+			assert.Equal(t, created, listed)
+		}
+
+		page, err := as.GetBankAccountTransactionsPage(&model.TransactionPageInput{
+			BankAccountId: tom.BankAccountId,
+			Filter:        model.TransactionFilter{TransactionNature: model.Credit},
+		}, ctx)
+		assert.Nil(t, err)
+		assert.Len(t, page.Transactions, 3)
+		for _, transaction := range page.Transactions {
+			assert.Equal(t, model.Credit, transaction.TransactionNature)
+			assert.Equal(t, sam.BankAccountId, transaction.OtherBankAccountId)
+		}
+
+		_, err = as.GetBankAccountTransactionsPage(&model.TransactionPageInput{
+			BankAccountId: tom.BankAccountId,
+			Filter:        model.TransactionFilter{CounterpartyBankAccountId: "not-an-id"},
+		}, ctx)
+		assert.ErrorIs(t, err, model.ErrInvalidTransactionQuery)
+	})
+
+	t.Run("Balance histories track the balances and totals of every bucket", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "100.00", ctx), seedAccount(t, b, "sam", "50.00", ctx)
+		ts := b.transactionService(model.AllowUnknownPayees)
+		for _, input := range []model.TransactionDetailsInput{
+			{FromBankAccountId: tom.BankAccountId, ToBankAccountId: sam.BankAccountId, Type: model.Realized,
+				Amount: decimal.RequireFromString("30.00")},
+			{FromBankAccountId: sam.BankAccountId, ToBankAccountId: tom.BankAccountId, Type: model.Realized,
+				Amount: decimal.RequireFromString("5.50")},
+		} {
+			_, err := ts.AddTransaction(input, nil, ctx)
+			assert.Nil(t, err)
+		}
+
+		now := time.Now()
+		history, err := b.accountService().GetAccountBalanceHistory(&model.AccountBalanceHistoryInput{
+			BankAccountId: tom.BankAccountId,
+			FromTime:      now,
+			ToTime:        now,
+			BucketSize:    model.QuarterlyBuckets,
+		}, ctx)
+		assert.Nil(t, err)
+		assert.Len(t, history.Buckets, 1)
+		bucket := history.Buckets[0]
+		assert.Equal(t, "100", bucket.OpeningAvailableBalance.String())
+		assert.Equal(t, "75.5", bucket.ClosingAvailableBalance.String())
+		assert.Equal(t, "70", bucket.MinAvailableBalance.String())
+		assert.Equal(t, "100", bucket.MaxAvailableBalance.String())
+		assert.Equal(t, "5.5", bucket.TotalDebits.String())
+		assert.Equal(t, "30", bucket.TotalCredits.String())
+	})
+
+	t.Run("Balance snapshots are taken once for every day that ended", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "100.00", ctx), seedAccount(t, b, "sam", "50.00", ctx)
+		_, err := b.transactionService(model.AllowUnknownPayees).AddTransaction(model.TransactionDetailsInput{
+			FromBankAccountId: tom.BankAccountId,
+			ToBankAccountId:   sam.BankAccountId,
+			Type:              model.Realized,
+			Amount:            decimal.RequireFromString("30.00"),
+		}, nil, ctx)
+		assert.Nil(t, err)
+		year, month, day := time.Now().UTC().Date()
+		today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		// Snapshots are taken as if it were the day after tomorrow, so that today and tomorrow have ended
+		later := today.AddDate(0, 0, 2).Add(12 * time.Hour)
+		ss := b.balanceSnapshotService()
+
+		added, err := ss.TakeBalanceSnapshots(later, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, added)
+		latest, err := b.BalanceSnapshots.GetLatestBalanceSnapshot(tom.BankAccountId, ctx)
+		assert.Nil(t, err)
+		assert.True(t, today.AddDate(0, 0, 1).Equal(latest.Day))
+		assert.Equal(t, "70.00", latest.AvailableBalance.StringFixed(2))
+		added, err = ss.TakeBalanceSnapshots(later, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, added)
+
+		added, err = ss.BackfillBalanceSnapshots(today.AddDate(0, 0, -1), later, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 4, added)
+		earliest, err := b.BalanceSnapshots.GetEarliestBalanceSnapshot(tom.BankAccountId, today.AddDate(0, 0, -7), ctx)
+		assert.Nil(t, err)
+		assert.True(t, today.AddDate(0, 0, -1).Equal(earliest.Day))
+		assert.Equal(t, "100.00", earliest.AvailableBalance.StringFixed(2))
+		earliest, err = b.BalanceSnapshots.GetEarliestBalanceSnapshot(sam.BankAccountId, today, ctx)
+		assert.Nil(t, err)
+		assert.True(t, today.Equal(earliest.Day))
+		assert.Equal(t, "80.00", earliest.AvailableBalance.StringFixed(2))
+		_, err = b.BalanceSnapshots.GetEarliestBalanceSnapshot(sam.BankAccountId, later, ctx)
+		assert.ErrorIs(t, err, model.ErrNoMatchingBalanceSnapshot)
+	})
+
+	t.Run("Balance histories read the days that were snapshotted from their snapshot", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "100.00", ctx), seedAccount(t, b, "sam", "50.00", ctx)
+		ts := b.transactionService(model.AllowUnknownPayees)
+		transfer := model.TransactionDetailsInput{
+			FromBankAccountId: tom.BankAccountId,
+			ToBankAccountId:   sam.BankAccountId,
+			Type:              model.Realized,
+			Amount:            decimal.RequireFromString("30.00"),
+		}
+		_, err := ts.AddTransaction(transfer, nil, ctx)
+		assert.Nil(t, err)
+		year, month, day := time.Now().UTC().Date()
+		today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		// Snapshotted as if it were the day after tomorrow, so that today and tomorrow have ended
+		later := today.AddDate(0, 0, 2).Add(12 * time.Hour)
+		_, err = b.balanceSnapshotService().BackfillBalanceSnapshots(today, later, ctx)
+		assert.Nil(t, err)
+		// A transaction made after its day was snapshotted is only seen by histories that replay the day
+		transfer.Amount = decimal.RequireFromString("10.00")
+		_, err = ts.AddTransaction(transfer, nil, ctx)
+		assert.Nil(t, err)
+
+		history, err := b.accountService().GetAccountBalanceHistory(&model.AccountBalanceHistoryInput{
+			BankAccountId: tom.BankAccountId,
+			FromTime:      today,
+			ToTime:        today.AddDate(0, 0, 1),
+			BucketSize:    model.DailyBuckets,
+		}, ctx)
+		assert.Nil(t, err)
+		if assert.Len(t, history.Buckets, 2) {
+			bucket := history.Buckets[1]
+			assert.True(t, today.Equal(bucket.StartTime))
+			assert.Equal(t, "100", bucket.OpeningAvailableBalance.String())
+			assert.Equal(t, "70", bucket.ClosingAvailableBalance.String())
+			assert.Equal(t, "70", bucket.MinAvailableBalance.String())
+			assert.Equal(t, "100", bucket.MaxAvailableBalance.String())
+			assert.Equal(t, "0", bucket.TotalDebits.String())
+			assert.Equal(t, "30", bucket.TotalCredits.String())
+			assert.Equal(t, "70", history.Buckets[0].OpeningAvailableBalance.String())
+			assert.Equal(t, "70", history.Buckets[0].ClosingAvailableBalance.String())
+		}
+	})
+
+	t.Run("Balance histories start from the nearest snapshot after them", func(t *testing.T) {
+		b := newBackend(t)
+		tom := seedAccount(t, b, "tom", "100.00", ctx)
+		year, month, day := time.Now().UTC().Date()
+		yesterday := time.Date(year, month, day-1, 0, 0, 0, 0, time.UTC)
+		// Backfilled as if it were early today, when yesterday has just ended
+		earlyToday := yesterday.AddDate(0, 0, 1).Add(time.Hour)
+		_, err := b.balanceSnapshotService().BackfillBalanceSnapshots(yesterday, earlyToday, ctx)
+		assert.Nil(t, err)
+		// Money that appears without a transaction is only seen by the histories that start from the balances
+		err = b.Accounts.AddBalance(tom.BankAccountId, decimal.RequireFromString("1000.00"), false, ctx)
+		assert.Nil(t, err)
+
+		as := b.accountService()
+		history, err := as.GetAccountBalanceHistory(&model.AccountBalanceHistoryInput{
+			BankAccountId: tom.BankAccountId,
+			FromTime:      yesterday,
+			ToTime:        yesterday,
+			BucketSize:    model.DailyBuckets,
+		}, ctx)
+		assert.Nil(t, err)
+		assert.Len(t, history.Buckets, 1)
+		assert.Equal(t, "100", history.Buckets[0].ClosingAvailableBalance.String())
+		history, err = as.GetAccountBalanceHistory(&model.AccountBalanceHistoryInput{
+			BankAccountId: tom.BankAccountId,
+			FromTime:      time.Now(),
+			ToTime:        time.Now(),
+			BucketSize:    model.DailyBuckets,
+		}, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "1100", history.Buckets[0].ClosingAvailableBalance.String())
+	})
+}
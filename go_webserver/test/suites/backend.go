@@ -0,0 +1,130 @@
+package suites
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"testing"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/domain/services"
+	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+	"webserver/internal/pkg/metrics"
+)
+
+// Backend is the storage the service suites run against
+type Backend struct {
+	Accounts         repositories.AccountRepository
+	Transactions     repositories.TransactionRepository
+	IdempotencyKeys  repositories.IdempotencyRepository
+	Journal          repositories.JournalRepository
+	BalanceSnapshots repositories.BalanceSnapshotRepository
+	CategoryRules    repositories.CategoryRuleRepository
+	Transactional    transactional.Transactional
+}
+
+// NewBackend returns a backend holding no data, so that every test case starts from a clean slate
+type NewBackend func(t *testing.T) *Backend
+
+func (b *Backend) accountService() *services.AccountServiceImpl {
+	return services.CreateNewAccountServiceImpl(b.Accounts, b.Transactions, b.BalanceSnapshots, b.Transactional,
+		logging.Discard())
+}
+
+func (b *Backend) transactionService(payeePolicy model.UnknownPayeePolicy) *services.TransactionServiceImpl {
+	return services.CreateNewTransactionServiceImpl(b.Transactions, b.Accounts, b.IdempotencyKeys, b.Journal,
+		b.CategoryRules, b.Transactional, payeePolicy, metrics.New(), logging.Discard())
+}
+
+func (b *Backend) categoryService() *services.CategoryServiceImpl {
+	return services.CreateNewCategoryServiceImpl(b.Accounts, b.Transactions, b.CategoryRules, b.Transactional,
+		logging.Discard())
+}
+
+func (b *Backend) statementService() *services.StatementServiceImpl {
+	return services.CreateNewStatementServiceImpl(b.Accounts, b.Transactions, b.Transactional, logging.Discard())
+}
+
+func (b *Backend) balanceSnapshotService() *services.BalanceSnapshotServiceImpl {
+	return services.CreateNewBalanceSnapshotServiceImpl(b.Accounts, b.Transactions, b.BalanceSnapshots,
+		b.Transactional, logging.Discard())
+}
+
+func (b *Backend) ledgerService() *services.LedgerServiceImpl {
+	return services.CreateNewLedgerServiceImpl(b.Accounts, b.Journal, logging.Discard())
+}
+
+// seededAccount is an account registered through the account service, holding a single funded bank account
+type seededAccount struct {
+	Username      string
+	Password      string
+	AccountId     string
+	BankAccountId string
+	AccountNumber string
+}
+
+// seedAccount registers an account with a bank account holding the given balance. The balance is journaled as an
+// opening balance, so that the ledger of a freshly seeded backend has no discrepancies.
+func seedAccount(t *testing.T, b *Backend, username string, balance string, ctx context.Context) seededAccount {
+	t.Helper()
+	const password = "correct-horse-battery"
+	as := b.accountService()
+	account, err := as.Register(&model.RegisterAccountInput{
+		Username: username,
+		Password: password,
+		Person:   model.Person{FirstName: username, LastName: "Suite"},
+	}, ctx)
+	if err != nil {
+		t.Fatalf("Failed to register account %s: %v", username, err)
+	}
+	bankAccount, err := as.OpenBankAccount(account.Id, model.Savings, ctx)
+	if err != nil {
+		t.Fatalf("Failed to open bank account for %s: %v", username, err)
+	}
+	amount := decimal.RequireFromString(balance)
+	if err = b.Accounts.AddBalance(bankAccount.Id, amount, false, ctx); err != nil {
+		t.Fatalf("Failed to fund bank account of %s: %v", username, err)
+	}
+	_, err = b.Journal.AddJournalEntry(&model.JournalEntryInput{
+		Kind: model.OpeningBalanceEntry,
+		Postings: []model.Posting{
+			{BankAccountId: bankAccount.Id, Balance: model.AvailableBalance, Amount: amount},
+			{BankAccountId: model.OpeningBalanceEquityAccountId, Balance: model.AvailableBalance, Amount: amount.Neg()},
+			{BankAccountId: bankAccount.Id, Balance: model.PendingBalance, Amount: amount},
+			{BankAccountId: model.OpeningBalanceEquityAccountId, Balance: model.PendingBalance, Amount: amount.Neg()},
+		},
+	}, ctx)
+	if err != nil {
+		t.Fatalf("Failed to journal opening balance of %s: %v", username, err)
+	}
+	return seededAccount{
+		Username:      username,
+		Password:      password,
+		AccountId:     account.Id,
+		BankAccountId: bankAccount.Id,
+		AccountNumber: bankAccount.AccountNumber,
+	}
+}
+
+// balances returns the available and pending balances of the bank account as strings, which compare equal
+// regardless of how either backend stores decimals
+func balances(t *testing.T, b *Backend, bankAccountId string, ctx context.Context) (string, string) {
+	t.Helper()
+	available, pending, err := b.Accounts.GetAccountBalance(bankAccountId, ctx)
+	if err != nil {
+		t.Fatalf("Failed to get balance of BankAccount %s: %v", bankAccountId, err)
+	}
+	return available.StringFixed(2), pending.StringFixed(2)
+}
+
+// assertLedgerBalanced fails the test when a stored balance differs from the totals of its postings
+func assertLedgerBalanced(t *testing.T, b *Backend, ctx context.Context) {
+	t.Helper()
+	discrepancies, err := b.ledgerService().FindBalanceDiscrepancies(ctx)
+	if err != nil {
+		t.Fatalf("Failed to check the ledger: %v", err)
+	}
+	if len(discrepancies) > 0 {
+		t.Errorf("Expected no balance discrepancies, found %+v", discrepancies)
+	}
+}
@@ -0,0 +1,240 @@
+package suites
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/domain/services"
+)
+
+// RunCategoryServiceSuite checks that category rules and memos are stored and applied by the storage returned by
+// newBackend
+func RunCategoryServiceSuite(t *testing.T, newBackend NewBackend) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+
+	t.Run("Category rules are listed in the order they were added and can be removed", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "0", ctx), seedAccount(t, b, "sam", "0", ctx)
+		cs := b.categoryService()
+
+		first, err := cs.AddCategoryRule(tom.AccountId, &model.CategoryRuleInput{
+			Category:          " Groceries ",
+			TransactionNature: model.Credit,
+			MinAmount:         decimal.NewNullDecimal(decimal.RequireFromString("5.00")),
+			MemoPattern:       "(?i)market",
+		}, ctx)
+		assert.Nil(t, err)
+		second, err := cs.AddCategoryRule(tom.AccountId, &model.CategoryRuleInput{
+			Category:                  "Family",
+			CounterpartyBankAccountId: sam.BankAccountId,
+		}, ctx)
+		assert.Nil(t, err)
+
+		rules, err := cs.GetCategoryRules(tom.AccountId, ctx)
+		assert.Nil(t, err)
+		if assert.Len(t, rules, 2) {
+			assert.Equal(t, first.Id, rules[0].Id)
+			assert.Equal(t, "Groceries", rules[0].Category)
+			assert.Equal(t, model.Credit, rules[0].TransactionNature)
+			assert.Equal(t, "5", rules[0].MinAmount.Decimal.String())
+			assert.False(t, rules[0].MaxAmount.Valid)
+			assert.Equal(t, "(?i)market", rules[0].MemoPattern)
+			assert.Equal(t, second.Id, rules[1].Id)
+			assert.Equal(t, sam.BankAccountId, rules[1].CounterpartyBankAccountId)
+		}
+		rules, err = cs.GetCategoryRules(sam.AccountId, ctx)
+		assert.Nil(t, err)
+		assert.Empty(t, rules)
+
+		err = cs.RemoveCategoryRule(sam.AccountId, first.Id, ctx)
+		assert.True(t, errors.Is(err, model.ErrNoMatchingCategoryRule))
+		assert.Nil(t, cs.RemoveCategoryRule(tom.AccountId, first.Id, ctx))
+		rules, err = cs.GetCategoryRules(tom.AccountId, ctx)
+		assert.Nil(t, err)
+		if assert.Len(t, rules, 1) {
+			assert.Equal(t, second.Id, rules[0].Id)
+		}
+	})
+
+	t.Run("Transfers are categorized by the first matching rule of either account holder", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "231.95", ctx), seedAccount(t, b, "sam", "56.18", ctx)
+		cs := b.categoryService()
+		addCategoryRule(t, cs, tom.AccountId, model.CategoryRuleInput{Category: "Rent", MemoPattern: "^rent"}, ctx)
+		addCategoryRule(t, cs, tom.AccountId, model.CategoryRuleInput{
+			Category:                  "Family",
+			CounterpartyBankAccountId: sam.BankAccountId,
+		}, ctx)
+		addCategoryRule(t, cs, sam.AccountId, model.CategoryRuleInput{
+			Category:          "Income",
+			TransactionNature: model.Debit,
+			MaxAmount:         decimal.NewNullDecimal(decimal.RequireFromString("50.00")),
+		}, ctx)
+		ts := b.transactionService(model.AllowUnknownPayees)
+
+		rent, err := ts.AddTransaction(model.TransactionDetailsInput{
+			FromBankAccountId: tom.BankAccountId,
+			ToBankAccountId:   sam.BankAccountId,
+			Amount:            decimal.RequireFromString("100.00"),
+			Type:              model.Realized,
+			Memo:              " rent for May ",
+		}, nil, ctx)
+		assert.Nil(t, err)
+		gift, err := ts.AddTransaction(model.TransactionDetailsInput{
+			FromBankAccountId: tom.BankAccountId,
+			ToBankAccountId:   sam.BankAccountId,
+			Amount:            decimal.RequireFromString("20.00"),
+			Type:              model.Realized,
+		}, nil, ctx)
+		assert.Nil(t, err)
+
+		details, err := ts.GetTransactionDetails(rent.Id, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "rent for May", details.Memo)
+		assert.Equal(t, model.TransactionCategory{Name: "Rent", Source: model.RuleCategory}, details.FromCategory)
+		assert.Equal(t, model.TransactionCategory{}, details.ToCategory)
+
+		tomTransactions := transactionsById(t, b, tom.BankAccountId, ctx)
+		assert.Equal(t, "rent for May", tomTransactions[rent.Id].Memo)
+		assert.Equal(t, "Rent", tomTransactions[rent.Id].Category.Name)
+		assert.Equal(t, "Family", tomTransactions[gift.Id].Category.Name)
+		samTransactions := transactionsById(t, b, sam.BankAccountId, ctx)
+		assert.Equal(t, "rent for May", samTransactions[rent.Id].Memo)
+		assert.Equal(t, model.TransactionCategory{}, samTransactions[rent.Id].Category)
+		assert.Equal(t, model.TransactionCategory{Name: "Income", Source: model.RuleCategory},
+			samTransactions[gift.Id].Category)
+	})
+
+	t.Run("Applying the rules again categorizes all but the transactions categorized by hand", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "231.95", ctx), seedAccount(t, b, "sam", "56.18", ctx)
+		cs := b.categoryService()
+		ts := b.transactionService(model.AllowUnknownPayees)
+		var ids []string
+		for _, memo := range []string{"coffee", "coffee beans", "books"} {
+			res, err := ts.AddTransaction(model.TransactionDetailsInput{
+				FromBankAccountId: tom.BankAccountId,
+				ToBankAccountId:   sam.BankAccountId,
+				Amount:            decimal.RequireFromString("3.50"),
+				Type:              model.Realized,
+				Memo:              memo,
+			}, nil, ctx)
+			assert.Nil(t, err)
+			ids = append(ids, res.Id)
+		}
+		category, err := cs.CategorizeTransaction(ids[1], tom.BankAccountId, "Gifts", ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, model.TransactionCategory{Name: "Gifts", Source: model.ManualCategory}, category)
+		addCategoryRule(t, cs, tom.AccountId, model.CategoryRuleInput{Category: "Coffee", MemoPattern: "coffee"}, ctx)
+
+		applied, err := cs.ApplyCategoryRules(tom.BankAccountId, nil, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, applied.Changed)
+		assert.Nil(t, applied.Next)
+		transactions := transactionsById(t, b, tom.BankAccountId, ctx)
+		assert.Equal(t, model.TransactionCategory{Name: "Coffee", Source: model.RuleCategory},
+			transactions[ids[0]].Category)
+		assert.Equal(t, model.TransactionCategory{Name: "Gifts", Source: model.ManualCategory},
+			transactions[ids[1]].Category)
+		assert.Equal(t, model.TransactionCategory{}, transactions[ids[2]].Category)
+
+		applied, err = cs.ApplyCategoryRules(tom.BankAccountId, nil, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, applied.Changed)
+
+		category, err = cs.CategorizeTransaction(ids[1], tom.BankAccountId, "", ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, model.TransactionCategory{Name: "Coffee", Source: model.RuleCategory}, category)
+		transactions = transactionsById(t, b, sam.BankAccountId, ctx)
+		assert.Equal(t, model.TransactionCategory{}, transactions[ids[1]].Category)
+	})
+
+	t.Run("Only the bank accounts of a transaction can categorize it", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "231.95", ctx), seedAccount(t, b, "sam", "56.18", ctx)
+		kim := seedAccount(t, b, "kim", "0", ctx)
+		res, err := b.transactionService(model.AllowUnknownPayees).AddTransaction(model.TransactionDetailsInput{
+			FromBankAccountId: tom.BankAccountId,
+			ToBankAccountId:   sam.BankAccountId,
+			Amount:            decimal.RequireFromString("1.00"),
+			Type:              model.Realized,
+		}, nil, ctx)
+		assert.Nil(t, err)
+
+		_, err = b.categoryService().CategorizeTransaction(res.Id, kim.BankAccountId, "Stolen", ctx)
+		assert.True(t, errors.Is(err, model.ErrNoMatchingTransaction))
+		_, err = b.categoryService().CategorizeTransaction(res.Id, kim.BankAccountId, "", ctx)
+		assert.True(t, errors.Is(err, model.ErrNoMatchingTransaction))
+	})
+
+	t.Run("Applied pending transactions keep their memo and the categories set by hand", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "231.95", ctx), seedAccount(t, b, "sam", "56.18", ctx)
+		cs := b.categoryService()
+		ts := b.transactionService(model.AllowUnknownPayees)
+		addCategoryRule(t, cs, sam.AccountId, model.CategoryRuleInput{Category: "Deposits"}, ctx)
+
+		pendingId, err := ts.AddPendingTransaction(model.TransactionDetailsInput{
+			FromBankAccountId: tom.BankAccountId,
+			ToBankAccountId:   sam.BankAccountId,
+			Amount:            decimal.RequireFromString("30.00"),
+			ExpirationDate:    time.Now().Add(time.Hour),
+			Memo:              "deposit",
+		}, ctx)
+		assert.Nil(t, err)
+		_, err = cs.CategorizeTransaction(pendingId, tom.BankAccountId, "Housing", ctx)
+		assert.Nil(t, err)
+		addCategoryRule(t, cs, tom.AccountId, model.CategoryRuleInput{Category: "Outgoing"}, ctx)
+		assert.Nil(t, ts.ApplyPendingTransaction(pendingId, ctx))
+
+		for _, transaction := range transactionsById(t, b, tom.BankAccountId, ctx) {
+			assert.Equal(t, "deposit", transaction.Memo)
+			assert.Equal(t, model.TransactionCategory{Name: "Housing", Source: model.ManualCategory},
+				transaction.Category)
+		}
+		for _, transaction := range transactionsById(t, b, sam.BankAccountId, ctx) {
+			assert.Equal(t, model.TransactionCategory{Name: "Deposits", Source: model.RuleCategory},
+				transaction.Category)
+		}
+	})
+}
+
+// addCategoryRule adds a rule to the rules of the account, failing the test when it is refused
+func addCategoryRule(
+	t *testing.T,
+	cs services.CategoryService,
+	accountId string,
+	input model.CategoryRuleInput,
+	ctx context.Context,
+) {
+	t.Helper()
+	if _, err := cs.AddCategoryRule(accountId, &input, ctx); err != nil {
+		t.Fatalf("Failed to add category rule %s: %v", input.Category, err)
+	}
+}
+
+// transactionsById lists every transaction of the bank account by ID
+func transactionsById(
+	t *testing.T,
+	b *Backend,
+	bankAccountId string,
+	ctx context.Context,
+) map[string]model.BankAccountTransactionOutput {
+	t.Helper()
+	page, err := b.accountService().GetBankAccountTransactionsPage(
+		&model.TransactionPageInput{BankAccountId: bankAccountId, PageSize: 200}, ctx,
+	)
+	if err != nil {
+		t.Fatalf("Failed to list transactions of BankAccount %s: %v", bankAccountId, err)
+	}
+	transactions := make(map[string]model.BankAccountTransactionOutput, len(page.Transactions))
+	for _, transaction := range page.Transactions {
+		transactions[transaction.Id] = transaction
+	}
+	return transactions
+}
@@ -0,0 +1,38 @@
+package suites
+
+import (
+	"testing"
+	"webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/infrastructure/memory"
+	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+)
+
+func newMemoryBackend(t *testing.T) *Backend {
+	store := memory.NewWalletStore()
+	return &Backend{
+		Accounts:         repositories.CreateNewAccountRepositoryMemory(store, logging.Discard()),
+		Transactions:     repositories.CreateNewTransactionRepositoryMemory(store, logging.Discard()),
+		IdempotencyKeys:  repositories.CreateNewIdempotencyRepositoryMemory(store, logging.Discard()),
+		Journal:          repositories.CreateNewJournalRepositoryMemory(store, logging.Discard()),
+		BalanceSnapshots: repositories.CreateNewBalanceSnapshotRepositoryMemory(store, logging.Discard()),
+		CategoryRules:    repositories.CreateNewCategoryRuleRepositoryMemory(store, logging.Discard()),
+		Transactional:    transactional.NewMemoryTransactional(store, logging.Discard()),
+	}
+}
+
+func TestAccountServiceOnMemory(t *testing.T) {
+	RunAccountServiceSuite(t, newMemoryBackend)
+}
+
+func TestTransactionServiceOnMemory(t *testing.T) {
+	RunTransactionServiceSuite(t, newMemoryBackend)
+}
+
+func TestCategoryServiceOnMemory(t *testing.T) {
+	RunCategoryServiceSuite(t, newMemoryBackend)
+}
+
+func TestTracingOnMemory(t *testing.T) {
+	RunTracingSuite(t, newMemoryBackend)
+}
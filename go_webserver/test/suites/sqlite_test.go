@@ -0,0 +1,53 @@
+package suites
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/infrastructure/sqlite"
+	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/logging"
+	"webserver/migrations/service"
+	"webserver/migrations/versions/sqlschema"
+)
+
+// newSQLiteBackend migrates a database file of its own in the temporary directory of the test
+func newSQLiteBackend(t *testing.T) *Backend {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatalf("Failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	ms := service.NewSQLMigrationService(db, context.Background(), logging.Discard())
+	if err = ms.MigrateUp(sqlschema.Track, len(sqlschema.Track.Migrations)); err != nil {
+		t.Fatalf("Failed to migrate SQLite database: %v", err)
+	}
+	return &Backend{
+		Accounts:         repositories.CreateNewAccountRepositorySQLite(db, logging.Discard()),
+		Transactions:     repositories.CreateNewTransactionRepositorySQLite(db, logging.Discard()),
+		IdempotencyKeys:  repositories.CreateNewIdempotencyRepositorySQLite(db, logging.Discard()),
+		Journal:          repositories.CreateNewJournalRepositorySQLite(db, logging.Discard()),
+		BalanceSnapshots: repositories.CreateNewBalanceSnapshotRepositorySQLite(db, logging.Discard()),
+		CategoryRules:    repositories.CreateNewCategoryRuleRepositorySQLite(db, logging.Discard()),
+		Transactional:    transactional.NewSQLiteTransactional(db, logging.Discard()),
+	}
+}
+
+func TestAccountServiceOnSQLite(t *testing.T) {
+	RunAccountServiceSuite(t, newSQLiteBackend)
+}
+
+func TestTransactionServiceOnSQLite(t *testing.T) {
+	RunTransactionServiceSuite(t, newSQLiteBackend)
+}
+
+func TestCategoryServiceOnSQLite(t *testing.T) {
+	RunCategoryServiceSuite(t, newSQLiteBackend)
+}
+
+func TestTracingOnSQLite(t *testing.T) {
+	RunTracingSuite(t, newSQLiteBackend)
+}
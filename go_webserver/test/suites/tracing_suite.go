@@ -0,0 +1,131 @@
+package suites
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"sort"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/domain/repositories"
+	"webserver/internal/pkg/domain/services"
+	"webserver/internal/pkg/infrastructure/transactional"
+	"webserver/internal/pkg/tracing"
+)
+
+// RunTracingSuite checks the spans recorded for the transaction service and the storage returned by newBackend
+func RunTracingSuite(t *testing.T, newBackend NewBackend) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+
+	transfer := func(t *testing.T, amount string) (tracetest.SpanStubs, error) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "231.95", ctx), seedAccount(t, b, "sam", "56.18", ctx)
+		exporter := tracetest.NewInMemoryExporter()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+		tracer := tracing.Tracer(tp)
+		ts := services.CreateNewTransactionServiceTraced(b.traced(tracer).transactionService(model.AllowUnknownPayees),
+			tracer)
+
+		_, err := ts.AddTransaction(model.TransactionDetailsInput{
+			FromBankAccountId: tom.BankAccountId,
+			ToBankAccountId:   sam.BankAccountId,
+			Amount:            decimal.RequireFromString(amount),
+			Type:              model.Realized,
+		}, nil, ctx)
+		return exporter.GetSpans(), err
+	}
+
+	t.Run("Transfers record a span tree from the service down to the repositories", func(t *testing.T) {
+		spans, err := transfer(t, "100.10")
+		assert.Nil(t, err)
+
+		assert.Equal(t, map[string][]string{
+			"TransactionService.AddTransaction": {"Transactional.WithTransaction"},
+			"Transactional.WithTransaction": {
+				"AccountRepository.DeductBalance",
+				"AccountRepository.AddBalance",
+				"AccountRepository.GetAccountIdFromBankAccountId",
+				"CategoryRuleRepository.GetCategoryRules",
+				"AccountRepository.GetAccountIdFromBankAccountId",
+				"CategoryRuleRepository.GetCategoryRules",
+				"TransactionRepository.AddTransaction",
+				"JournalRepository.AddJournalEntry",
+			},
+		}, spanTree(spans))
+		withTransaction := findSpan(t, spans, "Transactional.WithTransaction")
+		assert.Equal(t, []string{"begin", "commit"}, eventNames(withTransaction))
+		for _, span := range spans {
+			assert.Equal(t, codes.Unset, span.Status.Code, span.Name)
+		}
+	})
+
+	t.Run("Failed transfers mark the spans they failed in", func(t *testing.T) {
+		spans, err := transfer(t, "99999999.99")
+		assert.ErrorIs(t, err, model.ErrInsufficientBalance)
+
+		assert.Equal(t, map[string][]string{
+			"TransactionService.AddTransaction": {"Transactional.WithTransaction"},
+			"Transactional.WithTransaction":     {"AccountRepository.DeductBalance"},
+		}, spanTree(spans))
+		withTransaction := findSpan(t, spans, "Transactional.WithTransaction")
+		assert.Equal(t, []string{"begin", "rollback", "exception"}, eventNames(withTransaction))
+		assert.Equal(t, codes.Error, withTransaction.Status.Code)
+		assert.Equal(t, codes.Error, findSpan(t, spans, "TransactionService.AddTransaction").Status.Code)
+	})
+}
+
+// traced returns the backend with a span recorded for every call to its repositories and transactions
+func (b *Backend) traced(tracer trace.Tracer) *Backend {
+	return &Backend{
+		Accounts:         repositories.CreateNewAccountRepositoryTraced(b.Accounts, tracer),
+		Transactions:     repositories.CreateNewTransactionRepositoryTraced(b.Transactions, tracer),
+		IdempotencyKeys:  repositories.CreateNewIdempotencyRepositoryTraced(b.IdempotencyKeys, tracer),
+		Journal:          repositories.CreateNewJournalRepositoryTraced(b.Journal, tracer),
+		BalanceSnapshots: repositories.CreateNewBalanceSnapshotRepositoryTraced(b.BalanceSnapshots, tracer),
+		CategoryRules:    repositories.CreateNewCategoryRuleRepositoryTraced(b.CategoryRules, tracer),
+		Transactional:    transactional.NewTracedTransactional(b.Transactional, tracer),
+	}
+}
+
+// spanTree maps the name of every span with children to the names of its children, in the order they started
+func spanTree(spans tracetest.SpanStubs) map[string][]string {
+	names := make(map[trace.SpanID]string, len(spans))
+	for _, span := range spans {
+		names[span.SpanContext.SpanID()] = span.Name
+	}
+	// Spans are exported as they end, so children come before their parents and siblings in the order they ended
+	ordered := append(tracetest.SpanStubs(nil), spans...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].StartTime.Before(ordered[j].StartTime) })
+	tree := make(map[string][]string)
+	for _, span := range ordered {
+		if parent, ok := names[span.Parent.SpanID()]; ok {
+			tree[parent] = append(tree[parent], span.Name)
+		}
+	}
+	return tree
+}
+
+func findSpan(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("Expected a span named %s", name)
+	return tracetest.SpanStub{}
+}
+
+func eventNames(span tracetest.SpanStub) []string {
+	names := make([]string, len(span.Events))
+	for i, event := range span.Events {
+		names[i] = event.Name
+	}
+	return names
+}
@@ -0,0 +1,325 @@
+package suites
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+	"webserver/internal/pkg/domain/model"
+	"webserver/internal/pkg/infrastructure/transactional"
+)
+
+// RunTransactionServiceSuite checks the transaction service and the transactions it runs in against the storage
+// returned by newBackend
+func RunTransactionServiceSuite(t *testing.T, newBackend NewBackend) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+
+	t.Run("Transfers move the amount between bank accounts", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "231.95", ctx), seedAccount(t, b, "sam", "56.18", ctx)
+		ts := b.transactionService(model.AllowUnknownPayees)
+
+		res, err := ts.AddTransaction(model.TransactionDetailsInput{
+			FromBankAccountId: tom.BankAccountId,
+			ToBankAccountId:   sam.BankAccountId,
+			Amount:            decimal.RequireFromString("100.10"),
+			Type:              model.Realized,
+		}, nil, ctx)
+		assert.Nil(t, err)
+
+		available, pending := balances(t, b, tom.BankAccountId, ctx)
+		assert.Equal(t, "131.85", available)
+		assert.Equal(t, "131.85", pending)
+		available, pending = balances(t, b, sam.BankAccountId, ctx)
+		assert.Equal(t, "156.28", available)
+		assert.Equal(t, "156.28", pending)
+
+		details, err := ts.GetTransactionDetails(res.Id, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, tom.BankAccountId, details.FromBankAccountId)
+		assert.Equal(t, "100.1", details.Amount.String())
+		assertLedgerBalanced(t, b, ctx)
+	})
+
+	t.Run("Failed transfers leave no trace", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "231.95", ctx), seedAccount(t, b, "sam", "56.18", ctx)
+		ts := b.transactionService(model.AllowUnknownPayees)
+
+		_, err := ts.AddTransaction(model.TransactionDetailsInput{
+			FromBankAccountId: tom.BankAccountId,
+			ToBankAccountId:   sam.BankAccountId,
+			Amount:            decimal.RequireFromString("99999999.99"),
+			Type:              model.Realized,
+		}, nil, ctx)
+		assert.EqualError(t, err, "insufficient balance in BankAccount "+tom.BankAccountId)
+
+		available, _ := balances(t, b, tom.BankAccountId, ctx)
+		assert.Equal(t, "231.95", available)
+		page, err := b.accountService().GetBankAccountTransactionsPage(
+			&model.TransactionPageInput{BankAccountId: tom.BankAccountId}, ctx,
+		)
+		assert.Nil(t, err)
+		assert.Empty(t, page.Transactions)
+		assertLedgerBalanced(t, b, ctx)
+	})
+
+	t.Run("Retried transfers with the same idempotency key are replayed", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "231.95", ctx), seedAccount(t, b, "sam", "56.18", ctx)
+		ts := b.transactionService(model.AllowUnknownPayees)
+		key := &model.IdempotencyKeyInput{Key: "transfer-1", AccountId: tom.AccountId}
+		input := model.TransactionDetailsInput{
+			FromBankAccountId: tom.BankAccountId,
+			ToBankAccountId:   sam.BankAccountId,
+			Amount:            decimal.RequireFromString("10.00"),
+			Type:              model.Realized,
+		}
+
+		first, err := ts.AddTransaction(input, key, ctx)
+		assert.Nil(t, err)
+		assert.False(t, first.Replayed)
+		retry, err := ts.AddTransaction(input, key, ctx)
+		assert.Nil(t, err)
+		assert.True(t, retry.Replayed)
+		assert.Equal(t, first.Id, retry.Id)
+
+		input.Amount = decimal.RequireFromString("20.00")
+		_, err = ts.AddTransaction(input, key, ctx)
+		assert.ErrorIs(t, err, model.ErrIdempotencyKeyReused)
+
+		available, _ := balances(t, b, tom.BankAccountId, ctx)
+		assert.Equal(t, "221.95", available)
+	})
+
+	t.Run("Idempotency keys are treated as never used once they expired", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "231.95", ctx), seedAccount(t, b, "sam", "56.18", ctx)
+		ts := b.transactionService(model.AllowUnknownPayees)
+		input := model.TransactionDetailsInput{
+			FromBankAccountId: tom.BankAccountId,
+			ToBankAccountId:   sam.BankAccountId,
+			Amount:            decimal.RequireFromString("10.00"),
+			Type:              model.Realized,
+		}
+		first, err := ts.AddTransaction(input, &model.IdempotencyKeyInput{Key: "fresh", AccountId: tom.AccountId}, ctx)
+		assert.Nil(t, err)
+		err = b.IdempotencyKeys.AddIdempotencyRecord(&model.IdempotencyRecord{
+			Key:           "stale",
+			AccountId:     tom.AccountId,
+			RequestHash:   "hash of another request",
+			TransactionId: first.Id,
+			CreatedAt:     time.Now().Add(-model.IdempotencyKeyTTL - time.Minute),
+		}, ctx)
+		assert.Nil(t, err)
+
+		stale, err := ts.AddTransaction(input, &model.IdempotencyKeyInput{Key: "stale", AccountId: tom.AccountId}, ctx)
+		assert.Nil(t, err)
+		assert.False(t, stale.Replayed)
+		assert.NotEqual(t, first.Id, stale.Id)
+
+		deleted, err := ts.DeleteExpiredIdempotencyKeys(time.Now(), ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, deleted)
+		deleted, err = ts.DeleteExpiredIdempotencyKeys(time.Now().Add(model.IdempotencyKeyTTL+time.Minute), ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, deleted)
+		retry, err := ts.AddTransaction(input, &model.IdempotencyKeyInput{Key: "fresh", AccountId: tom.AccountId}, ctx)
+		assert.Nil(t, err)
+		assert.False(t, retry.Replayed)
+
+		available, _ := balances(t, b, tom.BankAccountId, ctx)
+		assert.Equal(t, "201.95", available)
+	})
+
+	t.Run("Pending transactions hold the amount until applied or revoked", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "231.95", ctx), seedAccount(t, b, "sam", "56.18", ctx)
+		ts := b.transactionService(model.AllowUnknownPayees)
+		input := model.TransactionDetailsInput{
+			FromBankAccountId: tom.BankAccountId,
+			ToBankAccountId:   sam.BankAccountId,
+			Amount:            decimal.RequireFromString("30.00"),
+			ExpirationDate:    time.Now().Add(time.Hour),
+		}
+
+		applied, err := ts.AddPendingTransaction(input, ctx)
+		assert.Nil(t, err)
+		available, pending := balances(t, b, tom.BankAccountId, ctx)
+		assert.Equal(t, "231.95", available)
+		assert.Equal(t, "201.95", pending)
+
+		assert.Nil(t, ts.ApplyPendingTransaction(applied, ctx))
+		available, pending = balances(t, b, tom.BankAccountId, ctx)
+		assert.Equal(t, "201.95", available)
+		assert.Equal(t, "201.95", pending)
+		assert.ErrorIs(t, ts.RevokePendingTransaction(applied, ctx), model.ErrPendingTransactionNotActive)
+
+		revoked, err := ts.AddPendingTransaction(input, ctx)
+		assert.Nil(t, err)
+		assert.Nil(t, ts.RevokePendingTransaction(revoked, ctx))
+		available, pending = balances(t, b, sam.BankAccountId, ctx)
+		assert.Equal(t, "86.18", available)
+		assert.Equal(t, "86.18", pending)
+		assert.ErrorIs(t, ts.ApplyPendingTransaction(revoked, ctx), model.ErrPendingTransactionNotActive)
+
+		details, err := ts.GetTransactionDetails(revoked, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, model.Revoked, details.Status)
+		assertLedgerBalanced(t, b, ctx)
+	})
+
+	t.Run("Expired pending transactions are revoked", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "231.95", ctx), seedAccount(t, b, "sam", "56.18", ctx)
+		ts := b.transactionService(model.AllowUnknownPayees)
+		expiration := time.Now().Add(time.Hour)
+		_, err := ts.AddPendingTransaction(model.TransactionDetailsInput{
+			FromBankAccountId: tom.BankAccountId,
+			ToBankAccountId:   sam.BankAccountId,
+			Amount:            decimal.RequireFromString("30.00"),
+			ExpirationDate:    expiration,
+		}, ctx)
+		assert.Nil(t, err)
+
+		revoked, err := ts.RevokeExpiredPendingTransactions(time.Now(), ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, revoked)
+		revoked, err = ts.RevokeExpiredPendingTransactions(expiration.Add(time.Second), ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, revoked)
+
+		_, pending := balances(t, b, tom.BankAccountId, ctx)
+		assert.Equal(t, "231.95", pending)
+		assertLedgerBalanced(t, b, ctx)
+	})
+
+	t.Run("Parallel transfers between the same bank accounts are all applied exactly once", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "231.95", ctx), seedAccount(t, b, "sam", "56.18", ctx)
+		ts := b.transactionService(model.AllowUnknownPayees)
+		const transfers = 20
+
+		var wg sync.WaitGroup
+		errs := make(chan error, transfers)
+		for i := 0; i < transfers; i++ {
+			input := model.TransactionDetailsInput{
+				FromBankAccountId: tom.BankAccountId,
+				ToBankAccountId:   sam.BankAccountId,
+				Amount:            decimal.RequireFromString("1.25"),
+				Type:              model.Realized,
+			}
+			// Alternate directions so that both bank accounts are contended for by either side of the transfer
+			if i%2 == 1 {
+				input.FromBankAccountId, input.ToBankAccountId = sam.BankAccountId, tom.BankAccountId
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				requestCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+				defer cancel()
+				_, err := ts.AddTransaction(input, nil, requestCtx)
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			assert.Nil(t, err)
+		}
+
+		available, _ := balances(t, b, tom.BankAccountId, ctx)
+		assert.Equal(t, "231.95", available)
+		available, _ = balances(t, b, sam.BankAccountId, ctx)
+		assert.Equal(t, "56.18", available)
+		page, err := b.accountService().GetBankAccountTransactionsPage(
+			&model.TransactionPageInput{BankAccountId: tom.BankAccountId}, ctx,
+		)
+		assert.Nil(t, err)
+		assert.Len(t, page.Transactions, transfers)
+		assertLedgerBalanced(t, b, ctx)
+	})
+
+	t.Run("Transactions roll back when the function fails", func(t *testing.T) {
+		b := newBackend(t)
+		tom := seedAccount(t, b, "tom", "231.95", ctx)
+		errFailed := errors.New("failed")
+		opts := transactional.TransactionOptions{
+			Isolation:  transactional.IsolationLow,
+			Durability: transactional.DurabilityHigh,
+		}
+
+		err := b.Transactional.WithTransaction(ctx, opts, func(txnCtx transactional.TransactionContext) error {
+			if err := b.Accounts.AddBalance(tom.BankAccountId, decimal.NewFromInt(10), false, txnCtx); err != nil {
+				return err
+			}
+			return errFailed
+		})
+		assert.ErrorIs(t, err, errFailed)
+		available, _ := balances(t, b, tom.BankAccountId, ctx)
+		assert.Equal(t, "231.95", available)
+	})
+
+	t.Run("Snapshot transactions do not see changes committed after they began", func(t *testing.T) {
+		b := newBackend(t)
+		tom := seedAccount(t, b, "tom", "231.95", ctx)
+
+		txnCtx, err := b.Transactional.BeginTransaction(ctx, transactional.IsolationHigh, transactional.DurabilityHigh)
+		assert.Nil(t, err)
+		before, _, err := b.Accounts.GetAccountBalance(tom.BankAccountId, txnCtx)
+		assert.Nil(t, err)
+		assert.Nil(t, b.Accounts.AddBalance(tom.BankAccountId, decimal.NewFromInt(10), false, ctx))
+		after, _, err := b.Accounts.GetAccountBalance(tom.BankAccountId, txnCtx)
+		assert.Nil(t, err)
+		assert.Equal(t, before.StringFixed(2), after.StringFixed(2))
+		assert.Nil(t, b.Transactional.Rollback(txnCtx))
+
+		available, _ := balances(t, b, tom.BankAccountId, ctx)
+		assert.Equal(t, "241.95", available)
+	})
+
+	t.Run("Statements list the transfers of their period between the balances around it", func(t *testing.T) {
+		b := newBackend(t)
+		tom, sam := seedAccount(t, b, "tom", "100.00", ctx), seedAccount(t, b, "sam", "50.00", ctx)
+		ts := b.transactionService(model.AllowUnknownPayees)
+		transfer := func(from seededAccount, to seededAccount, amount string) {
+			_, err := ts.AddTransaction(model.TransactionDetailsInput{
+				FromBankAccountId: from.BankAccountId,
+				ToBankAccountId:   to.BankAccountId,
+				Amount:            decimal.RequireFromString(amount),
+				Type:              model.Realized,
+			}, nil, ctx)
+			assert.Nil(t, err)
+		}
+
+		fromTime := time.Now().Add(-time.Hour)
+		transfer(tom, sam, "30.00")
+		transfer(sam, tom, "5.50")
+		// Transactions are stored to the second, so the transfer after the period has to be made in the next second
+		toTime := time.Now()
+		time.Sleep(time.Until(toTime.Truncate(time.Second).Add(time.Second)))
+		transfer(tom, sam, "10.00")
+
+		statement, err := b.statementService().GetStatement(&model.StatementInput{
+			BankAccountId: tom.BankAccountId,
+			FromTime:      fromTime,
+			ToTime:        toTime,
+		}, ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, tom.AccountNumber, statement.AccountNumber)
+		assert.Equal(t, "100", statement.OpeningAvailableBalance.String())
+		assert.Equal(t, "75.5", statement.ClosingAvailableBalance.String())
+		assert.Len(t, statement.Entries, 2)
+		assert.Equal(t, model.Credit, statement.Entries[0].TransactionNature)
+		assert.Equal(t, "30", statement.Entries[0].Amount.String())
+		assert.Equal(t, model.Debit, statement.Entries[1].TransactionNature)
+		for _, entry := range statement.Entries {
+			assert.Equal(t, sam.AccountNumber, entry.CounterpartyAccountNumber)
+			assert.Equal(t, "sam Suite", entry.CounterpartyHolder)
+		}
+	})
+}
@@ -13,13 +13,19 @@ var tomBalanceDecimal128, _ = primitive.ParseDecimal128("231.95")
 var samBalanceDecimal128, _ = primitive.ParseDecimal128("56.18")
 var tomBalanceDecimal, _ = decimal.NewFromString("231.95")
 
+const TomPassword = "pass"
+const SamPassword = "word"
+
+var tomPasswordHash, _ = pkgutils.HashPassword(TomPassword)
+var samPasswordHash, _ = pkgutils.HashPassword(SamPassword)
+
 var TomAccountDetails = mongodb.MongoAccountInput{
 	Username: "Tom",
-	Password: "pass",
+	Password: tomPasswordHash,
 	BankAccounts: []mongodb.BankAccount{
 		{
 			Id:               primitive.NewObjectID(),
-			AccountNumber:    "123-45678-9",
+			AccountNumber:    "123-45678-2",
 			AccountType:      "savings",
 			AvailableBalance: tomBalanceDecimal128,
 			PendingBalance:   tomBalanceDecimal128,
@@ -32,7 +38,7 @@ var TomAccountDetails = mongodb.MongoAccountInput{
 	KnownBankAccounts: []mongodb.KnownBankAccount{
 		{
 			Id:            primitive.NewObjectID(),
-			AccountNumber: "987-65432-1",
+			AccountNumber: "987-65432-4",
 			AccountHolder: "Sam Jones",
 			AccountType:   "checking",
 		},
@@ -42,11 +48,11 @@ var TomAccountDetails = mongodb.MongoAccountInput{
 
 var SamAccountDetails = mongodb.MongoAccountInput{
 	Username: "Sam",
-	Password: "word",
+	Password: samPasswordHash,
 	BankAccounts: []mongodb.BankAccount{
 		{
 			Id:               primitive.NewObjectID(),
-			AccountNumber:    "987-65432-1",
+			AccountNumber:    "987-65432-4",
 			AccountType:      "checking",
 			AvailableBalance: samBalanceDecimal128,
 			PendingBalance:   samBalanceDecimal128,
@@ -65,7 +71,7 @@ var TomAccountDetailsModel = model.AccountDetailsOutput{
 	BankAccounts: []model.BankAccount{
 		{
 			Id:               "UUID",
-			AccountNumber:    "123-45678-9",
+			AccountNumber:    "123-45678-2",
 			AvailableBalance: tomBalanceDecimal,
 			AccountType:      model.Checking,
 		},
@@ -77,7 +83,7 @@ var TomAccountDetailsModel = model.AccountDetailsOutput{
 	KnownBankAccounts: []model.KnownBankAccount{
 		{
 			Id:            "UUID",
-			AccountNumber: "987-65432-1",
+			AccountNumber: "987-65432-4",
 			AccountHolder: "Sam Jones",
 			AccountType:   model.Savings,
 		},